@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Flags of commands that talk to a running server
+var (
+	serverFlag string
+	apiKeyFlag string
+)
+
+// addServerFlag registers the --server and --api-key flags on a command and its subcommands
+// Both fall back to the GOVIRTUAL_URL and GOVIRTUAL_API_KEY environment variables
+func addServerFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&serverFlag, "server", "s", "", "URL of a running server (default: $GOVIRTUAL_URL or http://localhost:<server.port>)")
+	cmd.PersistentFlags().StringVar(&apiKeyFlag, "api-key", "", "API key sent as a bearer token, e.g. for an authenticating proxy (default: $GOVIRTUAL_API_KEY)")
+}
+
+// adminClient calls the admin API of a running server
+type adminClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// newAdminClient creates a client for the server given by --server or the configured port
+func newAdminClient() *adminClient {
+	baseURL := serverFlag
+	if baseURL == "" {
+		baseURL = os.Getenv("GOVIRTUAL_URL")
+	}
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://localhost:%d", viper.GetInt("server.port"))
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+
+	apiKey := apiKeyFlag
+	if apiKey == "" {
+		apiKey = os.Getenv("GOVIRTUAL_API_KEY")
+	}
+
+	return &adminClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request to an admin API path such as "/specs" and decodes the JSON response into out
+// Error responses are returned as errors carrying the server's message
+func (c *adminClient) do(method, path string, body interface{}, out interface{}) error {
+	resp, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// download sends a GET request and returns the raw response body, which the caller must close
+func (c *adminClient) download(path string) (io.ReadCloser, error) {
+	resp, err := c.send(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// send performs a request, turning error statuses into errors
+func (c *adminClient) send(method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+"/_api"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req.Header)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach server at %s: %w", c.baseURL, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// authorize adds the API key, if any, to request headers
+func (c *adminClient) authorize(header http.Header) {
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/prasenjit/go-virtual/internal/backup"
+	"github.com/prasenjit/go-virtual/internal/storage"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export specs, operations and response configs to a tar.gz archive",
+	Long: `Exports all specs, or a single spec with --spec, to a tar.gz archive.
+
+By default the archive is downloaded from a running server through the admin API
+(--server, default http://localhost:<server.port>). With --data-dir it is built
+directly from a file storage directory instead.`,
+	Example: `  go-virtual export --out backup.tar.gz
+  go-virtual export --spec 3f2c... --out users.tar.gz --server http://mock:8080
+  go-virtual export --data-dir ./data --out - > backup.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+var (
+	exportOut     string
+	exportSpecID  string
+	exportDataDir string
+)
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Archive file to write, or - for stdout (default: go-virtual-export-<timestamp>.tar.gz)")
+	exportCmd.Flags().StringVar(&exportSpecID, "spec", "", "Export only this spec ID")
+	exportCmd.Flags().StringVar(&exportDataDir, "data-dir", "", "Read directly from this storage directory instead of a running server")
+	addServerFlag(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	out := exportOut
+	if out == "" {
+		out = "go-virtual-export-" + time.Now().Format("20060102-150405") + ".tar.gz"
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := writeExport(w); err != nil {
+		if out != "-" {
+			os.Remove(out)
+		}
+		return err
+	}
+
+	if out != "-" {
+		fmt.Fprintf(os.Stderr, "Exported to %s\n", out)
+	}
+	return nil
+}
+
+// writeExport writes the archive from the data directory or the running server
+func writeExport(w io.Writer) error {
+	var specIDs []string
+	if exportSpecID != "" {
+		specIDs = []string{exportSpecID}
+	}
+
+	if exportDataDir != "" {
+		store, err := storage.NewFileStorage(exportDataDir)
+		if err != nil {
+			return fmt.Errorf("failed to open storage: %w", err)
+		}
+		return backup.Write(w, store, specIDs)
+	}
+
+	path := "/export"
+	if exportSpecID != "" {
+		path = "/specs/" + exportSpecID + "/export"
+	}
+
+	body, err := newAdminClient().download(path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(w, body)
+	return err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/prasenjit/go-virtual/internal/condition"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate configuration skeletons",
+}
+
+var genResponseCmd = &cobra.Command{
+	Use:   "response",
+	Short: "Generate a response config skeleton",
+	Long: `Generates a response config document for an operation, in the format used by
+the response config export and import endpoints
+(/_api/operations/:id/responses/export and /import).
+
+With --spec, the body and headers are filled from the spec's example or schema
+for the chosen status code.
+
+Conditions use the form "source[:key] operator [value]", for example
+"header:X-Tier eq premium", "query:page exists" or "rawBody contains error".`,
+	Example: `  go-virtual gen response --operation GET:/users/{id} --status 404
+  go-virtual gen response --operation POST:/orders --status 201 --spec openapi.yaml --format json
+  go-virtual gen response --operation GET:/users/{id} --condition "path:id eq 0" --out users.responses.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runGenResponse,
+}
+
+var (
+	genOperation  string
+	genStatus     int
+	genName       string
+	genSpecFile   string
+	genConditions []string
+	genDelay      int
+	genFormat     string
+	genOut        string
+)
+
+func init() {
+	genResponseCmd.Flags().StringVar(&genOperation, "operation", "", "Operation as METHOD:PATH, e.g. GET:/users/{id} (required)")
+	genResponseCmd.Flags().IntVar(&genStatus, "status", http.StatusOK, "Response status code")
+	genResponseCmd.Flags().StringVar(&genName, "name", "", "Response config name (default: derived from the status)")
+	genResponseCmd.Flags().StringVar(&genSpecFile, "spec", "", "OpenAPI spec to take the example body and headers from")
+	genResponseCmd.Flags().StringArrayVar(&genConditions, "condition", nil, `Condition as "source[:key] operator [value]" (repeatable)`)
+	genResponseCmd.Flags().IntVar(&genDelay, "delay", 0, "Response delay in milliseconds")
+	genResponseCmd.Flags().StringVarP(&genFormat, "format", "f", "yaml", "Output format: yaml or json")
+	genResponseCmd.Flags().StringVarP(&genOut, "out", "o", "-", "File to write, or - for stdout")
+	genResponseCmd.MarkFlagRequired("operation")
+
+	genCmd.AddCommand(genResponseCmd)
+}
+
+func runGenResponse(cmd *cobra.Command, args []string) error {
+	method, path, ok := strings.Cut(genOperation, ":")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("--operation must look like METHOD:PATH, e.g. GET:/users/{id}")
+	}
+	method = strings.ToUpper(method)
+
+	if genStatus < 100 || genStatus > 599 {
+		return fmt.Errorf("--status must be between 100 and 599")
+	}
+
+	conditions := make([]models.Condition, 0, len(genConditions))
+	for _, expr := range genConditions {
+		cond, err := parseConditionFlag(expr)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, cond)
+	}
+	if err := condition.ValidateAll(conditions); err != nil {
+		return err
+	}
+
+	name := genName
+	if name == "" {
+		name = fmt.Sprintf("%d %s", genStatus, http.StatusText(genStatus))
+	}
+
+	response := models.ResponseConfigInput{
+		Name:       name,
+		Priority:   0,
+		Conditions: conditions,
+		StatusCode: genStatus,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       "{}",
+		Delay:      genDelay,
+		Enabled:    true,
+	}
+
+	doc := models.ResponseConfigExport{
+		Version: models.ResponseConfigExportVersion,
+		Operation: models.ExportedOperation{
+			Method:      method,
+			Path:        path,
+			OperationID: parser.DefaultOperationID(method, path),
+		},
+	}
+
+	if genSpecFile != "" {
+		if err := fillFromSpec(&doc.Operation, &response); err != nil {
+			return err
+		}
+	}
+	doc.Responses = []models.ResponseConfigInput{response}
+
+	var data []byte
+	var err error
+	switch genFormat {
+	case "yaml":
+		data, err = yaml.Marshal(doc)
+	case "json":
+		data, err = json.MarshalIndent(doc, "", "  ")
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("--format must be yaml or json")
+	}
+	if err != nil {
+		return err
+	}
+
+	if genOut == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(genOut, data, 0644)
+}
+
+// fillFromSpec copies the operation ID and the example for the chosen status from the spec file
+func fillFromSpec(op *models.ExportedOperation, response *models.ResponseConfigInput) error {
+	content, err := readSource(genSpecFile)
+	if err != nil {
+		return err
+	}
+
+	// The spec ID only scopes operation IDs, so any fixed value works here
+	const specID = "gen"
+
+	p := parser.NewParser()
+	parsed, err := p.ParseForSpec(content, specID, "")
+	if err != nil {
+		return err
+	}
+
+	opID := parser.GenerateOperationID(specID, op.Method, op.Path)
+	found := false
+	for _, parsedOp := range parsed.Operations {
+		if parsedOp.ID == opID {
+			op.OperationID = parsedOp.OperationID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("operation %s %s not found in %s", op.Method, op.Path, genSpecFile)
+	}
+
+	examples, err := p.ExtractResponseExamples(content, specID)
+	if err != nil {
+		return err
+	}
+	for _, example := range examples[opID] {
+		if example.StatusCode != response.StatusCode {
+			continue
+		}
+		response.Description = example.Description
+		response.Headers = example.Headers
+		response.Body = example.Body
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %s %s documents no %d response; using an empty JSON body\n", op.Method, op.Path, response.StatusCode)
+	return nil
+}
+
+// parseConditionFlag parses a condition written as "source[:key] operator [value]"
+func parseConditionFlag(expr string) (models.Condition, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 2 {
+		return models.Condition{}, fmt.Errorf(`invalid condition %q: expected "source[:key] operator [value]"`, expr)
+	}
+
+	source, key, _ := strings.Cut(fields[0], ":")
+	cond := models.Condition{
+		Source:   source,
+		Key:      key,
+		Operator: fields[1],
+	}
+	if len(fields) > 2 {
+		// Keep the value verbatim, including inner spaces
+		rest := strings.TrimSpace(expr)
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+		cond.Value = strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+	}
+	return cond, nil
+}
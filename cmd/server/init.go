@@ -18,6 +18,7 @@ This command will:
   - Create config.yaml with default settings
   - Create data/ directory for file storage
   - Create data/specs/ directory for OpenAPI specs
+  - Create data/operations/ directory for custom operations
   - Create data/responses/ directory for response configurations
 
 If config.yaml already exists, it will not be overwritten unless --force is used.`,
@@ -53,6 +54,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 	dirs := []string{
 		dataDir,
 		filepath.Join(dataDir, "specs"),
+		filepath.Join(dataDir, "operations"),
 		filepath.Join(dataDir, "responses"),
 	}
 
@@ -75,18 +77,95 @@ func runInit(cmd *cobra.Command, args []string) error {
 				"autoGenerate": true,
 				"storePath":    "",
 			},
+			"maxConcurrent":    0,
+			"responseTimeout":  "0s",
+			"basePathPrefixes": []string{},
+			"readOnly":         false,
+			"utilities": map[string]interface{}{
+				"enabled": false,
+				"prefix":  "/_util",
+			},
+			"drain": map[string]interface{}{
+				"gracePeriod": "0s",
+				"timeout":     "30s",
+			},
 		},
 		"storage": map[string]interface{}{
-			"type": "file",
-			"path": "./data",
+			"type":  "file",
+			"path":  "./data",
+			"fsync": false,
+			"memory": map[string]interface{}{
+				"maxSpecs":           0,
+				"maxResponseConfigs": 0,
+				"maxBodyBytes":       0,
+				"eviction":           "none",
+			},
+		},
+		"cluster": map[string]interface{}{
+			"enabled":      false,
+			"nodeId":       "",
+			"pollInterval": "2s",
+			"redis": map[string]interface{}{
+				"url":     "",
+				"channel": "go-virtual:changes",
+			},
 		},
 		"tracing": map[string]interface{}{
-			"maxTraces": 1000,
-			"retention": "24h",
+			"maxTraces":     1000,
+			"maxBodySize":   65536,
+			"redactHeaders": []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"},
+			"maskRules":     []map[string]interface{}{},
+			"retention":     "24h",
+			"redis": map[string]interface{}{
+				"url":     "",
+				"channel": "go-virtual:traces",
+			},
+		},
+		"conditions": map[string]interface{}{
+			"jwt": map[string]interface{}{
+				"verify":   false,
+				"keyFiles": []string{},
+				"secrets":  []string{},
+			},
+		},
+		"oauth": map[string]interface{}{
+			"enabled":         false,
+			"issuer":          "",
+			"tokenTTL":        "1h",
+			"refreshTokenTTL": "24h",
+			"clients":         []interface{}{},
+		},
+		"events": map[string]interface{}{
+			"kafka": map[string]interface{}{
+				"enabled":  false,
+				"brokers":  []string{},
+				"clientId": "go-virtual",
+				"acks":     "one",
+				"timeout":  "10s",
+			},
+			"mqtt": map[string]interface{}{
+				"broker":   "",
+				"clientId": "go-virtual",
+				"timeout":  "10s",
+			},
+			"amqp": map[string]interface{}{
+				"enabled": false,
+				"url":     "",
+				"timeout": "10s",
+			},
 		},
 		"logging": map[string]interface{}{
-			"level":  "info",
-			"format": "json",
+			"level":      "info",
+			"format":     "json",
+			"file":       "",
+			"maxSize":    100,
+			"maxAge":     28,
+			"maxBackups": 5,
+			"accessLog": map[string]interface{}{
+				"enabled": false,
+				"format":  "combined",
+				"file":    "",
+			},
 		},
 	}
 
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/prasenjit/go-virtual/internal/storage"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy all data from one storage directory to another",
+	Long: `Copies all specs with their content, operations, response configs and files from
+one storage to another, then reopens the target and verifies the counts match.
+
+Storages are given as <type>:<location>. File storage ("file:<dir>") is the only
+persistent backend, so this moves data between storage directories; there is no
+database backend to migrate to. The target must be empty. Stop servers using
+either storage before migrating.`,
+	Example: `  go-virtual migrate --from file:./data --to file:/var/lib/go-virtual`,
+	Args:    cobra.NoArgs,
+	RunE:    runMigrate,
+}
+
+var (
+	migrateFrom string
+	migrateTo   string
+)
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Source storage, e.g. file:./data")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "Target storage, e.g. file:./new-data")
+	migrateCmd.MarkFlagRequired("from")
+	migrateCmd.MarkFlagRequired("to")
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	if migrateFrom == migrateTo {
+		return fmt.Errorf("--from and --to must be different storages")
+	}
+
+	from, err := storage.Open(migrateFrom)
+	if err != nil {
+		return fmt.Errorf("failed to open source storage: %w", err)
+	}
+	defer from.Close()
+
+	expected, err := storage.CountAll(from)
+	if err != nil {
+		return fmt.Errorf("failed to read source storage: %w", err)
+	}
+
+	to, err := storage.Open(migrateTo)
+	if err != nil {
+		return fmt.Errorf("failed to open target storage: %w", err)
+	}
+	_, err = storage.Migrate(from, to)
+	to.Close()
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	// Reopen the target so the check covers what was persisted
+	to, err = storage.Open(migrateTo)
+	if err != nil {
+		return fmt.Errorf("failed to reopen target storage: %w", err)
+	}
+	defer to.Close()
+
+	actual, err := storage.CountAll(to)
+	if err != nil {
+		return fmt.Errorf("failed to read target storage: %w", err)
+	}
+	if actual != expected {
+		return fmt.Errorf("verification failed: source has %d specs, %d operations, %d response configs and %d files, target has %d, %d, %d and %d",
+			expected.Specs, expected.Operations, expected.ResponseConfigs, expected.Files, actual.Specs, actual.Operations, actual.ResponseConfigs, actual.Files)
+	}
+
+	fmt.Printf("Migrated %d specs, %d operations, %d response configs and %d files from %s to %s\n",
+		actual.Specs, actual.Operations, actual.ResponseConfigs, actual.Files, migrateFrom, migrateTo)
+	return nil
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/prasenjit/go-virtual/internal/accesslog"
+	"github.com/prasenjit/go-virtual/internal/api"
+	"github.com/prasenjit/go-virtual/internal/condition"
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/oauth"
+	"github.com/prasenjit/go-virtual/internal/proxy"
+	"github.com/prasenjit/go-virtual/internal/tlsutil"
+	"github.com/prasenjit/go-virtual/internal/tracing"
+)
+
+// restartKeys are settings that only take effect when the server restarts
+var restartKeys = []string{
+	"server.host",
+	"server.port",
+	"server.tls.enabled",
+	"server.readOnly",
+	"logging.format",
+	"logging.file",
+	"storage.type",
+	"storage.path",
+	"storage.fsync",
+	"cluster.enabled",
+	"cluster.nodeId",
+	"cluster.pollInterval",
+	"cluster.redis.url",
+	"cluster.redis.channel",
+	"tracing.redis.url",
+	"tracing.redis.channel",
+	"storage.memory.maxSpecs",
+	"storage.memory.maxResponseConfigs",
+	"storage.memory.maxBodyBytes",
+	"storage.memory.eviction",
+}
+
+// configReloader applies configuration changes to a running server
+// Changes arrive from the config file watcher or a SIGHUP
+type configReloader struct {
+	mu             sync.Mutex
+	proxyEngine    *proxy.Engine
+	tracingService *tracing.Service
+	oauthServer    *oauth.Server
+	certs          *certReloader         // nil without TLS
+	logFile        *logging.RotatingFile // nil when logging to stderr
+	startup        map[string]interface{}
+	accessLog      *accesslog.Logger
+	accessLogKey   string // settings accessLog was opened with
+	kafkaKey       string // settings the Kafka publisher was created with
+	mqttKey        string // settings the MQTT publisher was created with
+	amqpKey        string // settings the AMQP publisher was created with
+}
+
+// newConfigReloader creates a reloader, remembering the settings that need a restart
+func newConfigReloader(proxyEngine *proxy.Engine, tracingService *tracing.Service, oauthServer *oauth.Server, certs *certReloader, logFile *logging.RotatingFile) *configReloader {
+	startup := make(map[string]interface{}, len(restartKeys))
+	for _, key := range restartKeys {
+		startup[key] = viper.Get(key)
+	}
+
+	return &configReloader{
+		proxyEngine:    proxyEngine,
+		tracingService: tracingService,
+		oauthServer:    oauthServer,
+		certs:          certs,
+		logFile:        logFile,
+		startup:        startup,
+	}
+}
+
+// apply applies the runtime settings from the current configuration
+// Invalid settings are reported and the previous value is kept
+func (r *configReloader) apply() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var fallbacks models.FallbackResponses
+	if err := unmarshalKey("fallback", &fallbacks); err != nil {
+		return fmt.Errorf("invalid fallback configuration: %w", err)
+	}
+	if errMsg := api.ValidateFallbacks(&fallbacks); errMsg != "" {
+		return fmt.Errorf("invalid fallback configuration: %s", errMsg)
+	}
+
+	var oauthConfig oauth.Config
+	if err := unmarshalKey("oauth", &oauthConfig); err != nil {
+		return fmt.Errorf("invalid oauth configuration: %w", err)
+	}
+	if err := r.oauthServer.Configure(oauthConfig); err != nil {
+		return fmt.Errorf("invalid oauth configuration: %w", err)
+	}
+
+	jwtOptions, err := r.jwtOptions()
+	if err != nil {
+		return fmt.Errorf("invalid conditions.jwt configuration: %w", err)
+	}
+
+	var maskRules []tracing.MaskRule
+	if err := unmarshalKey("tracing.maskRules", &maskRules); err != nil {
+		return fmt.Errorf("invalid tracing.maskRules configuration: %w", err)
+	}
+	if err := r.tracingService.SetMaskRules(maskRules); err != nil {
+		return fmt.Errorf("invalid tracing.maskRules configuration: %w", err)
+	}
+
+	// Only the level can change at runtime; the format is fixed at startup
+	if err := logging.SetLevel(viper.GetString("logging.level")); err != nil {
+		return fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	if r.logFile != nil {
+		r.logFile.SetOptions(rotateOptions())
+	}
+
+	if err := r.applyAccessLog(); err != nil {
+		return err
+	}
+	if err := r.applyEvents(); err != nil {
+		return err
+	}
+
+	r.proxyEngine.SetFallbackResponses(fallbacks)
+	r.proxyEngine.SetDrainGracePeriod(viper.GetDuration("server.drain.gracePeriod"))
+	r.proxyEngine.SetMaxConcurrent(viper.GetInt("server.maxConcurrent"))
+	r.proxyEngine.SetResponseTimeout(viper.GetDuration("server.responseTimeout"))
+	r.proxyEngine.SetBasePathPrefixes(viper.GetStringSlice("server.basePathPrefixes"))
+	r.proxyEngine.SetUtilities(viper.GetBool("server.utilities.enabled"), viper.GetString("server.utilities.prefix"))
+	r.proxyEngine.SetTemplateEnvAllowlist(viper.GetStringSlice("templates.envAllowlist"))
+	r.proxyEngine.SetSeedDir(viper.GetString("stateful.seedDir"))
+	r.proxyEngine.SetJWTOptions(jwtOptions)
+	r.tracingService.SetMaxTraces(viper.GetInt("tracing.maxTraces"))
+	r.tracingService.SetMaxBodySize(viper.GetInt("tracing.maxBodySize"))
+	r.tracingService.SetRedactHeaders(viper.GetStringSlice("tracing.redactHeaders"))
+
+	if r.certs != nil {
+		if err := r.certs.load(); err != nil {
+			return fmt.Errorf("failed to reload TLS certificate: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// jwtOptions loads the keys jwt conditions verify bearer tokens with
+// Tokens of the mock authorization server are trusted whenever it is enabled
+func (r *configReloader) jwtOptions() (condition.JWTOptions, error) {
+	opts := condition.JWTOptions{Verify: viper.GetBool("conditions.jwt.verify")}
+	for _, path := range viper.GetStringSlice("conditions.jwt.keyFiles") {
+		keys, err := oauth.LoadPublicKeys(path)
+		if err != nil {
+			return opts, err
+		}
+		opts.Keys = append(opts.Keys, keys...)
+	}
+	for _, secret := range viper.GetStringSlice("conditions.jwt.secrets") {
+		opts.Secrets = append(opts.Secrets, []byte(secret))
+	}
+	if r.oauthServer.Enabled() {
+		opts.Keys = append(opts.Keys, r.oauthServer.Key().Public())
+	}
+	return opts, nil
+}
+
+// applyAccessLog opens, reopens or closes the access log when its settings change
+func (r *configReloader) applyAccessLog() error {
+	enabled := viper.GetBool("logging.accessLog.enabled")
+	format := viper.GetString("logging.accessLog.format")
+	file := viper.GetString("logging.accessLog.file")
+
+	rotate := rotateOptions()
+
+	key := ""
+	if enabled {
+		key = fmt.Sprintf("%s|%s|%+v", format, file, rotate)
+	}
+	if key == r.accessLogKey {
+		return nil
+	}
+
+	var logger *accesslog.Logger
+	if enabled {
+		var err error
+		if logger, err = accesslog.Open(file, format, rotate); err != nil {
+			return fmt.Errorf("invalid access log configuration: %w", err)
+		}
+	}
+
+	r.proxyEngine.SetAccessLog(logger)
+	if r.accessLog != nil {
+		r.accessLog.Close()
+	}
+	r.accessLog, r.accessLogKey = logger, key
+	return nil
+}
+
+// applyEvents replaces the event publishers whose broker settings changed
+func (r *configReloader) applyEvents() error {
+	var kafkaConfig events.KafkaConfig
+	if err := unmarshalKey("events.kafka", &kafkaConfig); err != nil {
+		return fmt.Errorf("invalid events.kafka configuration: %w", err)
+	}
+
+	key := ""
+	if kafkaConfig.Enabled {
+		key = fmt.Sprintf("%+v", kafkaConfig)
+	}
+	if key != r.kafkaKey {
+		var publisher events.Publisher
+		if kafkaConfig.Enabled {
+			kafka, err := events.NewKafkaPublisher(kafkaConfig)
+			if err != nil {
+				return fmt.Errorf("invalid events.kafka configuration: %w", err)
+			}
+			publisher = kafka
+		}
+		r.proxyEngine.Events().SetPublisher(models.EventKafka, publisher)
+		r.kafkaKey = key
+	}
+
+	// MQTT actions can name their own broker, so the publisher is always available
+	var mqttConfig events.MQTTConfig
+	if err := unmarshalKey("events.mqtt", &mqttConfig); err != nil {
+		return fmt.Errorf("invalid events.mqtt configuration: %w", err)
+	}
+	if key := fmt.Sprintf("%+v", mqttConfig); key != r.mqttKey {
+		r.proxyEngine.Events().SetPublisher(models.EventMQTT, events.NewMQTTPublisher(mqttConfig))
+		r.mqttKey = key
+	}
+
+	var amqpConfig events.AMQPConfig
+	if err := unmarshalKey("events.amqp", &amqpConfig); err != nil {
+		return fmt.Errorf("invalid events.amqp configuration: %w", err)
+	}
+	key = ""
+	if amqpConfig.Enabled {
+		key = fmt.Sprintf("%+v", amqpConfig)
+	}
+	if key != r.amqpKey {
+		var publisher events.Publisher
+		if amqpConfig.Enabled {
+			amqp, err := events.NewAMQPPublisher(amqpConfig)
+			if err != nil {
+				return fmt.Errorf("invalid events.amqp configuration: %w", err)
+			}
+			publisher = amqp
+		}
+		r.proxyEngine.Events().SetPublisher(models.EventAMQP, publisher)
+		r.amqpKey = key
+	}
+	return nil
+}
+
+// unmarshalKey decodes a configuration section like viper.UnmarshalKey, but reads every field
+// on its own so GOVIRTUAL_* variables override nested keys, e.g. GOVIRTUAL_EVENTS_KAFKA_BROKERS
+// Lists of objects and maps, such as tracing.maskRules, are overridden with JSON
+func unmarshalKey(key string, out interface{}) error {
+	t := reflect.TypeOf(out).Elem()
+
+	var section interface{}
+	if t.Kind() == reflect.Struct {
+		fields := make(map[string]interface{})
+		if err := collectSettings(key, t, fields); err != nil {
+			return err
+		}
+		section = fields
+	} else {
+		value, err := settingValue(key, t)
+		if err != nil || value == nil {
+			return err
+		}
+		section = value
+	}
+
+	v := viper.New()
+	v.Set(key, section)
+	return v.UnmarshalKey(key, out)
+}
+
+// collectSettings adds the set fields of a struct type under key to settings
+func collectSettings(key string, t reflect.Type, settings map[string]interface{}) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			nested := make(map[string]interface{})
+			if err := collectSettings(key+"."+name, fieldType, nested); err != nil {
+				return err
+			}
+			if len(nested) > 0 {
+				settings[name] = nested
+			}
+			continue
+		}
+
+		value, err := settingValue(key+"."+name, fieldType)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			settings[name] = value
+		}
+	}
+	return nil
+}
+
+// settingValue returns the value of a key, or nil when it isn't set
+// Environment variables are strings, so lists of objects and maps are parsed as JSON
+func settingValue(key string, t reflect.Type) (interface{}, error) {
+	if !viper.IsSet(key) {
+		return nil, nil
+	}
+	value := viper.Get(key)
+	text, ok := value.(string)
+	if !ok || !(t.Kind() == reflect.Map || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.String) {
+		return value, nil
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+		return nil, fmt.Errorf("%s: expected JSON: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// rotateOptions returns the rotation limits shared by the log file and the access log
+func rotateOptions() logging.RotateOptions {
+	return logging.RotateOptions{
+		MaxSizeMB:  viper.GetInt("logging.maxSize"),
+		MaxAgeDays: viper.GetInt("logging.maxAge"),
+		MaxBackups: viper.GetInt("logging.maxBackups"),
+	}
+}
+
+// close releases resources held by the applied settings
+func (r *configReloader) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.accessLog != nil {
+		r.proxyEngine.SetAccessLog(nil)
+		r.accessLog.Close()
+		r.accessLog = nil
+	}
+	r.proxyEngine.Events().Close()
+}
+
+// reload re-reads the config file and applies it
+func (r *configReloader) reload(reason string) {
+	if viper.ConfigFileUsed() == "" {
+		slog.Warn("configuration reload skipped: no config file in use", "trigger", reason)
+		return
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		slog.Error("configuration reload failed, keeping the current settings", "trigger", reason, "error", err)
+		return
+	}
+	// Re-reading the file drops the merged profile
+	if err := applyProfile(); err != nil {
+		slog.Error("configuration reload failed", "trigger", reason, "error", err)
+		return
+	}
+	if err := r.apply(); err != nil {
+		slog.Error("configuration reload failed", "trigger", reason, "error", err)
+		return
+	}
+
+	for _, key := range restartKeys {
+		if fmt.Sprint(viper.Get(key)) != fmt.Sprint(r.startup[key]) {
+			slog.Warn("configuration change needs a restart", "key", key)
+		}
+	}
+	slog.Info("configuration reloaded", "trigger", reason)
+}
+
+// watch reloads the configuration on SIGHUP and, if enabled, when the config file changes
+func (r *configReloader) watch(watchFile bool) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			r.reload("SIGHUP")
+		}
+	}()
+
+	if watchFile && viper.ConfigFileUsed() != "" {
+		viper.OnConfigChange(func(e fsnotify.Event) {
+			r.reload(filepath.Base(e.Name) + " changed")
+		})
+		viper.WatchConfig()
+		slog.Info("watching config file for changes", "file", viper.ConfigFileUsed())
+	}
+}
+
+// certReloader serves the current TLS certificate and can swap it at runtime
+// New connections use the new certificate; established ones are unaffected
+type certReloader struct {
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	certPath string
+	keyPath  string
+}
+
+// load resolves the certificate from the TLS settings, generating one if allowed
+func (c *certReloader) load() error {
+	certFile := viper.GetString("server.tls.certFile")
+	keyFile := viper.GetString("server.tls.keyFile")
+	autoGenerate := viper.GetBool("server.tls.autoGenerate")
+	tlsStorePath := viper.GetString("server.tls.storePath")
+	storagePath := viper.GetString("storage.path")
+
+	// Resolve TLS store path - default to <storage.path>/certs if not configured
+	if tlsStorePath == "" {
+		// Resolve relative storage path
+		if storagePath != "" && !filepath.IsAbs(storagePath) {
+			cwd, _ := os.Getwd()
+			storagePath = filepath.Join(cwd, storagePath)
+		}
+		tlsStorePath = filepath.Join(storagePath, "certs")
+	}
+
+	// Get or generate TLS certificate
+	certManager := tlsutil.NewCertificateManager(certFile, keyFile, tlsStorePath)
+
+	cert, err := certManager.GetCertificate(autoGenerate)
+	if err != nil {
+		return err
+	}
+	certPath, keyPath := certManager.GetCertificatePaths()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cert == nil || certPath != c.certPath || keyPath != c.keyPath || !sameCertificate(c.cert, cert) {
+		slog.Info("using TLS certificate", "certFile", certPath, "keyFile", keyPath)
+	}
+	c.cert, c.certPath, c.keyPath = cert, certPath, keyPath
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// sameCertificate reports whether two certificates have the same leaf
+func sameCertificate(a, b *tls.Certificate) bool {
+	if len(a.Certificate) == 0 || len(b.Certificate) == 0 {
+		return false
+	}
+	return string(a.Certificate[0]) == string(b.Certificate[0])
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/tracing"
+)
+
+func TestUnmarshalKey_EnvOverridesNestedKeys(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetEnvPrefix("GOVIRTUAL")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+events:
+  kafka:
+    enabled: false
+    clientId: from-file
+    timeout: 5s
+fallback:
+  notFound:
+    statusCode: 404
+    body: from file
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GOVIRTUAL_EVENTS_KAFKA_ENABLED", "true")
+	t.Setenv("GOVIRTUAL_EVENTS_KAFKA_BROKERS", "a:9092,b:9092")
+	t.Setenv("GOVIRTUAL_FALLBACK_NOTFOUND_STATUSCODE", "410")
+	t.Setenv("GOVIRTUAL_TRACING_MASKRULES", `[{"path": "$.card", "keepLast": 4}]`)
+
+	var kafka events.KafkaConfig
+	if err := unmarshalKey("events.kafka", &kafka); err != nil {
+		t.Fatal(err)
+	}
+	if !kafka.Enabled || strings.Join(kafka.Brokers, " ") != "a:9092 b:9092" || kafka.ClientID != "from-file" || kafka.Timeout != 5*time.Second {
+		t.Errorf("Unexpected Kafka config: %+v", kafka)
+	}
+
+	var fallbacks models.FallbackResponses
+	if err := unmarshalKey("fallback", &fallbacks); err != nil {
+		t.Fatal(err)
+	}
+	if fallbacks.NotFound == nil || fallbacks.NotFound.StatusCode != 410 || fallbacks.NotFound.Body != "from file" || fallbacks.Error != nil {
+		t.Errorf("Unexpected fallbacks: %+v", fallbacks)
+	}
+
+	var maskRules []tracing.MaskRule
+	if err := unmarshalKey("tracing.maskRules", &maskRules); err != nil {
+		t.Fatal(err)
+	}
+	if len(maskRules) != 1 || maskRules[0].Path != "$.card" || maskRules[0].KeepLast != 4 {
+		t.Errorf("Unexpected mask rules: %+v", maskRules)
+	}
+}
+
+func TestApply_RejectsInvalidFallback(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader("fallback:\n  notFound:\n    statusCode: 700\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validation fails before any component is configured
+	var r configReloader
+	err := r.apply()
+	if err == nil || !strings.Contains(err.Error(), "Invalid status code for notFound fallback: 700") {
+		t.Errorf("Expected the invalid fallback to fail the reload, got %v", err)
+	}
+}
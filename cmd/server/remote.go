@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/tracing"
+)
+
+// Commands managing a running server through its admin API
+
+var specsCmd = &cobra.Command{
+	Use:   "specs",
+	Short: "Manage the specs of a running server",
+}
+
+var specsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List specs",
+	Args:  cobra.NoArgs,
+	RunE:  runSpecsList,
+}
+
+var specsEnableCmd = &cobra.Command{
+	Use:   "enable <spec>",
+	Short: "Enable a spec",
+	Long:  "Enables a spec, given by ID, unique ID prefix or name.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSpecAction(args[0], http.MethodPut, "/enable", "Enabled")
+	},
+}
+
+var specsDisableCmd = &cobra.Command{
+	Use:   "disable <spec>",
+	Short: "Disable a spec",
+	Long:  "Disables a spec, given by ID, unique ID prefix or name.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSpecAction(args[0], http.MethodPut, "/disable", "Disabled")
+	},
+}
+
+var specsDeleteCmd = &cobra.Command{
+	Use:   "delete <spec>",
+	Short: "Delete a spec with its operations and response configs",
+	Long:  "Deletes a spec, given by ID, unique ID prefix or name.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSpecAction(args[0], http.MethodDelete, "", "Deleted")
+	},
+}
+
+var tracesCmd = &cobra.Command{
+	Use:   "traces",
+	Short: "Inspect the request traces of a running server",
+}
+
+var tracesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent traces, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runTracesList,
+}
+
+var tracesTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Stream traces as requests arrive",
+	Long:  "Streams traces from the live trace WebSocket until interrupted.",
+	Args:  cobra.NoArgs,
+	RunE:  runTracesTail,
+}
+
+var tracesClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete recorded traces",
+	Args:  cobra.NoArgs,
+	RunE:  runTracesClear,
+}
+
+var (
+	remoteJSON   bool
+	tracesSpec   string
+	tracesMethod string
+	tracesStatus string
+	tracesLimit  int
+)
+
+func init() {
+	addServerFlag(specsCmd)
+	addServerFlag(tracesCmd)
+
+	specsListCmd.Flags().BoolVar(&remoteJSON, "json", false, "Print JSON")
+	specsCmd.AddCommand(specsListCmd, specsEnableCmd, specsDisableCmd, specsDeleteCmd)
+
+	tracesCmd.PersistentFlags().StringVar(&tracesSpec, "spec", "", "Only traces of this spec (ID, unique ID prefix or name)")
+	tracesCmd.PersistentFlags().BoolVar(&remoteJSON, "json", false, "Print one JSON trace per line")
+	for _, cmd := range []*cobra.Command{tracesListCmd, tracesTailCmd} {
+		cmd.Flags().StringVar(&tracesMethod, "method", "", "Only traces with this HTTP method")
+		cmd.Flags().StringVar(&tracesStatus, "status", "", "Only traces with this status code, or class like 5xx")
+	}
+	tracesListCmd.Flags().IntVarP(&tracesLimit, "limit", "n", 20, "Maximum number of traces")
+	tracesCmd.AddCommand(tracesListCmd, tracesTailCmd, tracesClearCmd)
+}
+
+// specSummary is an entry of the spec list endpoint
+type specSummary struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	BasePath       string `json:"basePath"`
+	Enabled        bool   `json:"enabled"`
+	Tracing        bool   `json:"tracing"`
+	OperationCount int    `json:"operationCount"`
+}
+
+func runSpecsList(cmd *cobra.Command, args []string) error {
+	var specs []specSummary
+	if err := newAdminClient().do(http.MethodGet, "/specs", nil, &specs); err != nil {
+		return err
+	}
+
+	if remoteJSON {
+		return printJSON(specs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tVERSION\tBASE PATH\tOPERATIONS\tENABLED\tTRACING")
+	for _, spec := range specs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\t%t\n",
+			spec.ID, spec.Name, spec.Version, spec.BasePath, spec.OperationCount, spec.Enabled, spec.Tracing)
+	}
+	return w.Flush()
+}
+
+// runSpecAction calls a spec endpoint such as PUT /specs/:id/enable
+func runSpecAction(ref, method, suffix, verb string) error {
+	client := newAdminClient()
+
+	spec, err := resolveSpec(client, ref)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(method, "/specs/"+spec.ID+suffix, nil, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s spec %q (%s)\n", verb, spec.Name, spec.ID)
+	return nil
+}
+
+// resolveSpec finds a spec by exact ID, exact name or unique ID prefix
+func resolveSpec(client *adminClient, ref string) (*specSummary, error) {
+	var specs []specSummary
+	if err := client.do(http.MethodGet, "/specs", nil, &specs); err != nil {
+		return nil, err
+	}
+
+	var byName, byPrefix []*specSummary
+	for i := range specs {
+		spec := &specs[i]
+		if spec.ID == ref {
+			return spec, nil
+		}
+		if spec.Name == ref {
+			byName = append(byName, spec)
+		}
+		if strings.HasPrefix(spec.ID, ref) {
+			byPrefix = append(byPrefix, spec)
+		}
+	}
+
+	for _, matches := range [][]*specSummary{byName, byPrefix} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			return nil, fmt.Errorf("%q matches %d specs; use the spec ID", ref, len(matches))
+		}
+	}
+	return nil, fmt.Errorf("spec %q not found", ref)
+}
+
+// traceSpecID resolves the --spec flag of the traces commands
+func traceSpecID(client *adminClient) (string, error) {
+	if tracesSpec == "" {
+		return "", nil
+	}
+	spec, err := resolveSpec(client, tracesSpec)
+	if err != nil {
+		return "", err
+	}
+	return spec.ID, nil
+}
+
+func runTracesList(cmd *cobra.Command, args []string) error {
+	client := newAdminClient()
+
+	specID, err := traceSpecID(client)
+	if err != nil {
+		return err
+	}
+
+	query := traceQuery(specID)
+	query.Set("limit", strconv.Itoa(tracesLimit))
+
+	var traces []*models.Trace
+	if err := client.do(http.MethodGet, "/traces?"+query.Encode(), nil, &traces); err != nil {
+		return err
+	}
+
+	for _, trace := range traces {
+		if err := printTrace(trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runTracesTail(cmd *cobra.Command, args []string) error {
+	client := newAdminClient()
+
+	specID, err := traceSpecID(client)
+	if err != nil {
+		return err
+	}
+
+	// The server filters the stream
+	wsURL := "ws" + strings.TrimPrefix(client.baseURL, "http") + "/_api/traces/stream?" + traceQuery(specID).Encode()
+	header := http.Header{}
+	client.authorize(header)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", wsURL, err)
+	}
+	defer conn.Close()
+
+	// Close the connection on Ctrl+C so the read loop ends cleanly
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		conn.Close()
+	}()
+
+	if !remoteJSON {
+		fmt.Fprintf(os.Stderr, "Streaming traces from %s (Ctrl+C to stop)\n", client.baseURL)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) || strings.Contains(err.Error(), "use of closed network connection") {
+				return nil
+			}
+			return fmt.Errorf("trace stream closed: %w", err)
+		}
+
+		var notice tracing.DroppedNotice
+		if json.Unmarshal(data, &notice) == nil && notice.Notice == "dropped" {
+			fmt.Fprintf(os.Stderr, "... %d traces dropped, the terminal didn't keep up\n", notice.Dropped)
+			continue
+		}
+		var trace models.Trace
+		if err := json.Unmarshal(data, &trace); err != nil {
+			return fmt.Errorf("invalid trace: %w", err)
+		}
+		if err := printTrace(&trace); err != nil {
+			return err
+		}
+	}
+}
+
+// traceQuery returns the query parameters selecting traces by the --spec, --method and --status flags
+func traceQuery(specID string) url.Values {
+	query := url.Values{}
+	if specID != "" {
+		query.Set("specId", specID)
+	}
+	if tracesMethod != "" {
+		query.Set("method", strings.ToUpper(tracesMethod))
+	}
+	if tracesStatus != "" {
+		query.Set("status", tracesStatus)
+	}
+	return query
+}
+
+func runTracesClear(cmd *cobra.Command, args []string) error {
+	client := newAdminClient()
+
+	specID, err := traceSpecID(client)
+	if err != nil {
+		return err
+	}
+
+	path := "/traces"
+	if specID != "" {
+		path += "?specId=" + url.QueryEscape(specID)
+	}
+	if err := client.do(http.MethodDelete, path, nil, nil); err != nil {
+		return err
+	}
+
+	fmt.Println("Traces cleared")
+	return nil
+}
+
+// printTrace prints a trace as a single line, or as JSON with --json
+func printTrace(trace *models.Trace) error {
+	if remoteJSON {
+		data, err := json.Marshal(trace)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	matched := trace.MatchedConfig
+	if matched == "" {
+		matched = "-"
+	}
+	fmt.Printf("%s  %-7s %s  %d  %s  %s\n",
+		trace.Timestamp.Local().Format("15:04:05.000"),
+		trace.Request.Method,
+		trace.Request.URL,
+		trace.Response.StatusCode,
+		time.Duration(trace.Duration).Round(time.Microsecond),
+		matched)
+	return nil
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -4,14 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	rootCmd = &cobra.Command{
+	cfgFile     string
+	profileFlag string
+	rootCmd     = &cobra.Command{
 		Use:   "go-virtual",
 		Short: "Go-Virtual - API proxy/mock service for OpenAPI 3 specs",
 		Long: `Go-Virtual is an API proxy/mock service that virtualizes OpenAPI 3 specifications.
@@ -33,10 +35,18 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default: ./config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "config profile to apply on top of the base settings (default: $GOVIRTUAL_PROFILE)")
 
 	// Add subcommands
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(genCmd)
+	rootCmd.AddCommand(specsCmd)
+	rootCmd.AddCommand(tracesCmd)
 }
 
 // initConfig reads in config file and ENV variables if set
@@ -59,6 +69,7 @@ func initConfig() {
 
 	// Read in environment variables that match
 	viper.SetEnvPrefix("GOVIRTUAL")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // e.g. GOVIRTUAL_STORAGE_TYPE
 	viper.AutomaticEnv()
 
 	// Set defaults
@@ -68,6 +79,47 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+
+	if err := applyProfile(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// activeProfile returns the profile selected with --profile or GOVIRTUAL_PROFILE
+func activeProfile() string {
+	if profileFlag != "" {
+		return profileFlag
+	}
+	return os.Getenv("GOVIRTUAL_PROFILE")
+}
+
+// applyProfile merges the selected profile from the "profiles" section of the config file
+// over the base settings. Environment variables and flags still take precedence
+func applyProfile() error {
+	profile := activeProfile()
+	if profile == "" {
+		return nil
+	}
+
+	key := "profiles." + profile
+	if !viper.IsSet(key) {
+		return fmt.Errorf("config profile %q not found in %s", profile, configFileName())
+	}
+
+	if err := viper.MergeConfigMap(viper.GetStringMap(key)); err != nil {
+		return fmt.Errorf("failed to apply config profile %q: %w", profile, err)
+	}
+	fmt.Fprintln(os.Stderr, "Using config profile:", profile)
+	return nil
+}
+
+// configFileName describes the config file in messages
+func configFileName() string {
+	if file := viper.ConfigFileUsed(); file != "" {
+		return file
+	}
+	return "the config file (none found)"
 }
 
 // setDefaults sets the default configuration values
@@ -87,16 +139,81 @@ func setDefaults() {
 	viper.SetDefault("server.tls.keyFile", "")
 	viper.SetDefault("server.tls.autoGenerate", true)
 	viper.SetDefault("server.tls.storePath", "")
+	viper.SetDefault("server.maxConcurrent", 0)
+	viper.SetDefault("server.responseTimeout", "0s")
+	viper.SetDefault("server.basePathPrefixes", []string{})
+	viper.SetDefault("server.readOnly", false)
+	viper.SetDefault("server.utilities.enabled", false)
+	viper.SetDefault("server.utilities.prefix", "/_util")
+	viper.SetDefault("server.drain.gracePeriod", "0s")
+	viper.SetDefault("server.drain.timeout", "30s")
 
 	// Storage defaults
 	viper.SetDefault("storage.type", "file")
 	viper.SetDefault("storage.path", defaultDataPath)
+	viper.SetDefault("storage.fsync", false)
+	viper.SetDefault("storage.memory.maxSpecs", 0)
+	viper.SetDefault("storage.memory.maxResponseConfigs", 0)
+	viper.SetDefault("storage.memory.maxBodyBytes", 0)
+	viper.SetDefault("storage.memory.eviction", "none")
+
+	// Cluster defaults
+	viper.SetDefault("cluster.enabled", false)
+	viper.SetDefault("cluster.nodeId", "")
+	viper.SetDefault("cluster.pollInterval", "2s")
+	viper.SetDefault("cluster.redis.url", "")
+	viper.SetDefault("cluster.redis.channel", "go-virtual:changes")
 
 	// Tracing defaults
 	viper.SetDefault("tracing.maxTraces", 1000)
+	viper.SetDefault("tracing.maxBodySize", 65536)
+	viper.SetDefault("tracing.redactHeaders", []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"})
 	viper.SetDefault("tracing.retention", "24h")
+	viper.SetDefault("tracing.redis.url", "")
+	viper.SetDefault("tracing.redis.channel", "go-virtual:traces")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
+	viper.SetDefault("logging.file", "")
+	viper.SetDefault("logging.maxSize", 100)
+	viper.SetDefault("logging.maxAge", 28)
+	viper.SetDefault("logging.maxBackups", 5)
+	viper.SetDefault("logging.accessLog.enabled", false)
+	viper.SetDefault("logging.accessLog.format", "combined")
+	viper.SetDefault("logging.accessLog.file", "")
+
+	// Template defaults
+	viper.SetDefault("templates.envAllowlist", []string{})
+
+	// Stateful spec defaults
+	viper.SetDefault("stateful.seedDir", "")
+
+	// Condition defaults
+	viper.SetDefault("conditions.jwt.verify", false)
+	viper.SetDefault("conditions.jwt.keyFiles", []string{})
+	viper.SetDefault("conditions.jwt.secrets", []string{})
+
+	// OAuth defaults
+	viper.SetDefault("oauth.enabled", false)
+	viper.SetDefault("oauth.issuer", "")
+	viper.SetDefault("oauth.audience", "")
+	viper.SetDefault("oauth.tokenTTL", "1h")
+	viper.SetDefault("oauth.refreshTokenTTL", "24h")
+	viper.SetDefault("oauth.keyFile", "")
+
+	// Event broker defaults
+	viper.SetDefault("events.kafka.enabled", false)
+	viper.SetDefault("events.kafka.brokers", []string{})
+	viper.SetDefault("events.kafka.clientId", "go-virtual")
+	viper.SetDefault("events.kafka.acks", "one")
+	viper.SetDefault("events.kafka.timeout", "10s")
+	viper.SetDefault("events.mqtt.broker", "")
+	viper.SetDefault("events.mqtt.clientId", "go-virtual")
+	viper.SetDefault("events.mqtt.username", "")
+	viper.SetDefault("events.mqtt.password", "")
+	viper.SetDefault("events.mqtt.timeout", "10s")
+	viper.SetDefault("events.amqp.enabled", false)
+	viper.SetDefault("events.amqp.url", "")
+	viper.SetDefault("events.amqp.timeout", "10s")
 }
@@ -4,8 +4,9 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -19,6 +20,9 @@ import (
 
 	govirtual "github.com/prasenjit/go-virtual"
 	"github.com/prasenjit/go-virtual/internal/api"
+	"github.com/prasenjit/go-virtual/internal/cluster"
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/oauth"
 	"github.com/prasenjit/go-virtual/internal/proxy"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
@@ -43,22 +47,41 @@ or specify a custom config file with the --config flag.`,
 }
 
 var (
-	devMode   bool
-	portFlag  int
-	tlsFlag   bool
+	devMode     bool
+	portFlag    int
+	tlsFlag     bool
+	watchConfig bool
 )
 
 func init() {
 	serveCmd.Flags().BoolVar(&devMode, "dev", false, "Enable development mode (serve UI from filesystem)")
 	serveCmd.Flags().IntVarP(&portFlag, "port", "p", 0, "Override server port")
 	serveCmd.Flags().BoolVar(&tlsFlag, "tls", false, "Enable TLS (overrides config)")
+	serveCmd.Flags().BoolVar(&watchConfig, "watch-config", true, "Apply config file changes without restarting (SIGHUP always reloads)")
+	serveCmd.Flags().Bool("read-only", false, "Reject admin API changes; mock traffic is served as usual")
 
 	// Bind flags to viper
 	viper.BindPFlag("server.port", serveCmd.Flags().Lookup("port"))
 	viper.BindPFlag("server.tls.enabled", serveCmd.Flags().Lookup("tls"))
+	viper.BindPFlag("server.readOnly", serveCmd.Flags().Lookup("read-only"))
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
+	// Log to stderr, or to a rotated file when logging.file is set
+	var logOutput io.Writer = os.Stderr
+	var logFile *logging.RotatingFile
+	if path := viper.GetString("logging.file"); path != "" {
+		var err error
+		if logFile, err = logging.OpenRotatingFile(path, rotateOptions()); err != nil {
+			return err
+		}
+		defer logFile.Close()
+		logOutput = logFile
+	}
+	if err := logging.Setup(logOutput, viper.GetString("logging.level"), viper.GetString("logging.format")); err != nil {
+		return fmt.Errorf("invalid logging configuration: %w", err)
+	}
+
 	// Get configuration values
 	port := viper.GetInt("server.port")
 	host := viper.GetString("server.host")
@@ -86,18 +109,31 @@ func runServe(cmd *cobra.Command, args []string) error {
 	}
 
 	// Log the data path being used
-	log.Printf("Using data directory: %s", storagePath)
+	slog.Info("using data directory", "path", storagePath)
 
 	// Initialize storage
 	var store storage.Storage
-	var err error
+	var fileStore *storage.FileStorage
+	var memStore *storage.MemoryStorage
 	if storageType == "file" {
-		store, err = storage.NewFileStorage(storagePath)
+		var err error
+		fileStore, err = storage.NewFileStorage(storagePath)
 		if err != nil {
 			return fmt.Errorf("failed to initialize file storage: %w", err)
 		}
+		fileStore.SetFsync(viper.GetBool("storage.fsync"))
+		store = fileStore
 	} else {
-		store = storage.NewMemoryStorage()
+		memStore = storage.NewMemoryStorage()
+		if err := memStore.SetLimits(storage.MemoryLimits{
+			MaxSpecs:           viper.GetInt("storage.memory.maxSpecs"),
+			MaxResponseConfigs: viper.GetInt("storage.memory.maxResponseConfigs"),
+			MaxBodyBytes:       viper.GetInt64("storage.memory.maxBodyBytes"),
+			Eviction:           viper.GetString("storage.memory.eviction"),
+		}); err != nil {
+			return fmt.Errorf("invalid storage.memory config: %w", err)
+		}
+		store = memStore
 	}
 
 	// Initialize statistics collector
@@ -109,19 +145,98 @@ func runServe(cmd *cobra.Command, args []string) error {
 	// Initialize proxy engine
 	proxyEngine := proxy.NewEngine(store, statsCollector, tracingService)
 
+	// Load the TLS certificate; it can be replaced at runtime by a config reload
+	var certs *certReloader
+	if tlsEnabled {
+		certs = &certReloader{}
+	}
+
+	// Mock authorization server; disabled unless oauth.enabled is set
+	oauthServer, err := oauth.NewServer()
+	if err != nil {
+		return err
+	}
+
+	// Apply runtime settings (fallback responses, template env allowlist, tracing limits, OAuth, certificate)
+	reloader := newConfigReloader(proxyEngine, tracingService, oauthServer, certs, logFile)
+	if err := reloader.apply(); err != nil {
+		return err
+	}
+
 	// Setup router
 	router := api.NewRouter(store, statsCollector, tracingService, proxyEngine)
+	if memStore != nil {
+		memStore.SetEvictHandler(router.SpecEvicted)
+	}
+	if viper.GetBool("server.readOnly") {
+		router.SetReadOnly(true)
+		slog.Info("read-only mode: admin API changes are rejected")
+	}
+	router.SetOAuth(oauthServer)
+
+	// Keep instances sharing the storage directory consistent
+	clusterCtx, stopCluster := context.WithCancel(context.Background())
+	defer stopCluster()
+	if viper.GetBool("cluster.enabled") {
+		if fileStore == nil {
+			return fmt.Errorf("cluster mode needs file storage shared by all nodes")
+		}
+		node, err := cluster.New(storagePath, nodeID(), viper.GetDuration("cluster.pollInterval"), func() error {
+			if err := fileStore.Reload(); err != nil {
+				return err
+			}
+			return proxyEngine.ReloadRoutes()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start cluster mode: %w", err)
+		}
+		node.SetStats(statsCollector)
+		if redisURL := viper.GetString("cluster.redis.url"); redisURL != "" {
+			channel := viper.GetString("cluster.redis.channel")
+			notifier, err := cluster.NewRedisNotifier(node, redisURL, channel)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Redis for cluster changes: %w", err)
+			}
+			defer notifier.Close()
+			node.SetNotifier(notifier)
+			go notifier.Run(clusterCtx)
+			slog.Info("cluster change notifications enabled", "channel", channel)
+		}
+		router.SetCluster(node)
+		go node.Run(clusterCtx)
+		slog.Info("cluster mode enabled", "node", node.ID(), "pollInterval", viper.GetDuration("cluster.pollInterval"))
+	}
+
+	// Evaluate the alert policies of specs
+	alertsCtx, stopAlerts := context.WithCancel(context.Background())
+	defer stopAlerts()
+	go proxyEngine.Alerts().Run(alertsCtx)
+
+	// Share live traces with other nodes
+	if redisURL := viper.GetString("tracing.redis.url"); redisURL != "" {
+		channel := viper.GetString("tracing.redis.channel")
+		fanout, err := tracing.NewRedisFanout(tracingService, redisURL, channel, nodeID())
+		if err != nil {
+			return fmt.Errorf("failed to connect to Redis for trace fan-out: %w", err)
+		}
+		defer fanout.Close()
+		tracingService.SetPublisher(fanout)
+		fanout.SetPurgeHandler(router.PurgeRemote)
+		router.SetPurgeBroadcaster(fanout)
+		go fanout.Run(clusterCtx)
+		slog.Info("trace fan-out enabled", "channel", channel)
+	}
 
 	// Setup UI serving
 	if devMode {
 		// In dev mode, serve UI from filesystem
-		log.Println("Development mode: Serving UI from ./ui/dist")
+		slog.Info("development mode: serving UI from ./ui/dist")
 		router.ServeUIFromFS("./ui/dist")
 	} else {
 		// In production, serve embedded UI
 		uiFS, err := fs.Sub(govirtual.EmbeddedUI, "ui/dist")
 		if err != nil {
-			log.Printf("Warning: Embedded UI not available: %v", err)
+			slog.Warn("embedded UI not available", "error", err)
 		} else {
 			router.ServeEmbeddedUI(uiFS)
 		}
@@ -136,20 +251,31 @@ func runServe(cmd *cobra.Command, args []string) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Event streams would keep connections open until the shutdown timeout
+	server.RegisterOnShutdown(router.CloseStreams)
+
 	// Start server
 	var cleanup func(context.Context) error
 	if tlsEnabled {
-		cleanup = startTLSServer(server, addr)
+		cleanup = startTLSServer(server, addr, certs)
 	} else {
 		startHTTPServer(server, addr)
 	}
 
+	// Storage is loaded and routes are built; report ready to probes
+	router.SetReady(true)
+
+	// Apply configuration changes without restarting
+	reloader.watch(watchConfig)
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
+	drain(proxyEngine, quit)
+	router.SetReady(false)
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -158,74 +284,97 @@ func runServe(cmd *cobra.Command, args []string) error {
 	// For TLS mode, close the mux listener first to unblock Accept() calls
 	if cleanup != nil {
 		if err := cleanup(ctx); err != nil {
-			log.Printf("Cleanup error: %v", err)
+			slog.Error("cleanup failed", "error", err)
 		}
 	}
 
 	// Shutdown main server
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		slog.Error("server shutdown failed", "error", err)
 	}
 
-	log.Println("Server stopped")
+	reloader.close()
+
+	slog.Info("server stopped")
 	return nil
 }
 
+// nodeID returns the name of this instance in a cluster: cluster.nodeId or the hostname
+func nodeID() string {
+	if id := viper.GetString("cluster.nodeId"); id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// drain fails readiness, keeps serving mock requests for the grace period, then rejects new
+// ones and waits for in-flight requests up to the drain timeout
+// A second signal skips the rest of the drain
+func drain(proxyEngine *proxy.Engine, quit <-chan os.Signal) {
+	grace := viper.GetDuration("server.drain.gracePeriod")
+	timeout := viper.GetDuration("server.drain.timeout")
+
+	// A drain started through the admin API may already be past its grace period
+	if proxyEngine.DrainStatus().Rejecting {
+		grace = 0
+	}
+	proxyEngine.StartDrain(grace)
+	slog.Info("draining", "gracePeriod", grace, "timeout", timeout, "inFlight", proxyEngine.DrainStatus().InFlight)
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-quit:
+		slog.Warn("drain interrupted")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-quit:
+			slog.Warn("drain interrupted")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := proxyEngine.WaitIdle(ctx); err != nil {
+		slog.Warn("drain ended with requests in flight", "inFlight", proxyEngine.DrainStatus().InFlight)
+	}
+}
+
 // startHTTPServer starts a plain HTTP server
 func startHTTPServer(server *http.Server, addr string) {
 	server.Addr = addr
 	go func() {
-		log.Printf("Starting Go-Virtual server on %s", addr)
-		log.Printf("Admin UI available at http://%s/_ui/", addr)
-		log.Printf("Admin API available at http://%s/_api/", addr)
+		slog.Info("starting Go-Virtual server", "addr", addr,
+			"adminUI", fmt.Sprintf("http://%s/_ui/", addr),
+			"adminAPI", fmt.Sprintf("http://%s/_api/", addr))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+			slog.Error("server failed", "error", err)
+			os.Exit(1)
 		}
 	}()
 }
 
 // startTLSServer starts a server that handles both HTTP and HTTPS on the same port
 // Returns a cleanup function that should be called during shutdown
-func startTLSServer(server *http.Server, addr string) func(context.Context) error {
-	// Get TLS configuration from viper
-	certFile := viper.GetString("server.tls.certFile")
-	keyFile := viper.GetString("server.tls.keyFile")
-	autoGenerate := viper.GetBool("server.tls.autoGenerate")
-	tlsStorePath := viper.GetString("server.tls.storePath")
-	storagePath := viper.GetString("storage.path")
-
-	// Resolve TLS store path - default to <storage.path>/certs if not configured
-	if tlsStorePath == "" {
-		// Resolve relative storage path
-		if storagePath != "" && !filepath.IsAbs(storagePath) {
-			cwd, _ := os.Getwd()
-			storagePath = filepath.Join(cwd, storagePath)
-		}
-		tlsStorePath = filepath.Join(storagePath, "certs")
-	}
-
-	// Get or generate TLS certificate
-	certManager := tlsutil.NewCertificateManager(certFile, keyFile, tlsStorePath)
-
-	cert, err := certManager.GetCertificate(autoGenerate)
-	if err != nil {
-		log.Fatalf("Failed to get TLS certificate: %v", err)
-	}
-
-	certPath, keyPath := certManager.GetCertificatePaths()
-	log.Printf("Using TLS certificate: %s", certPath)
-	log.Printf("Using TLS private key: %s", keyPath)
-
+func startTLSServer(server *http.Server, addr string, certs *certReloader) func(context.Context) error {
 	// Create TLS config
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{*cert},
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
 	}
 
 	// Create base listener
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("Failed to create listener: %v", err)
+		slog.Error("failed to create listener", "addr", addr, "error", err)
+		os.Exit(1)
 	}
 
 	// Create multiplexed listener for HTTP and HTTPS on same port
@@ -240,20 +389,20 @@ func startTLSServer(server *http.Server, addr string) func(context.Context) erro
 	}
 
 	go func() {
-		log.Printf("Starting Go-Virtual server on %s (HTTP & HTTPS)", addr)
-		log.Printf("Admin UI available at https://%s/_ui/ (or http://%s/_ui/)", addr, addr)
-		log.Printf("Admin API available at https://%s/_api/ (or http://%s/_api/)", addr, addr)
+		slog.Info("starting Go-Virtual server (HTTP & HTTPS)", "addr", addr,
+			"adminUI", fmt.Sprintf("https://%s/_ui/", addr),
+			"adminAPI", fmt.Sprintf("https://%s/_api/", addr))
 
 		// Serve HTTPS
 		go func() {
 			if err := server.Serve(muxListener.HTTPSListener()); err != nil && err != http.ErrServerClosed {
-				log.Printf("HTTPS server error: %v", err)
+				slog.Error("HTTPS server failed", "error", err)
 			}
 		}()
 
 		// Serve HTTP
 		if err := httpServer.Serve(muxListener.HTTPListener()); err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+			slog.Error("HTTP server failed", "error", err)
 		}
 	}()
 
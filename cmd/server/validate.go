@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate <file-or-url>...",
+	Short: "Validate OpenAPI specs offline",
+	Long: `Parses and validates OpenAPI 3 specs with the same pipeline the server uses,
+without a running server. For each spec the operations are listed with the
+example response go-virtual would serve, and operations without a usable
+example are reported.
+
+The command exits with a non-zero status if a spec is invalid, or with --strict
+if an operation has no example response, so it can gate commits and CI builds.`,
+	Example: `  go-virtual validate petstore.yaml
+  go-virtual validate specs/*.yaml --strict
+  go-virtual validate openapi.json --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runValidate,
+}
+
+var (
+	validateBasePath string
+	validateStrict   bool
+	validateJSON     bool
+)
+
+func init() {
+	validateCmd.Flags().StringVarP(&validateBasePath, "base-path", "b", "", "Base path used to show full operation paths")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Fail when an operation has no example response")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the results as JSON")
+}
+
+// validationResult is the outcome of validating one spec
+type validationResult struct {
+	Source     string               `json:"source"`
+	Valid      bool                 `json:"valid"`
+	Error      string               `json:"error,omitempty"`
+	Name       string               `json:"name,omitempty"`
+	Version    string               `json:"version,omitempty"`
+	Operations []validatedOperation `json:"operations,omitempty"`
+}
+
+// validatedOperation describes an operation of a valid spec
+type validatedOperation struct {
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	OperationID   string `json:"operationId"`
+	ExampleStatus int    `json:"exampleStatus,omitempty"`
+	Problem       string `json:"problem,omitempty"`
+}
+
+// noExampleProblem explains why the example fallback can't answer an operation
+const noExampleProblem = "no example response: document a 200, 201, 202 or 204 response with a JSON example or schema"
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	results := make([]validationResult, 0, len(args))
+	invalid, problems := 0, 0
+
+	for _, source := range args {
+		result := validateSource(source)
+		if !result.Valid {
+			invalid++
+		}
+		for _, op := range result.Operations {
+			if op.Problem != "" {
+				problems++
+			}
+		}
+		results = append(results, result)
+	}
+
+	if validateJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printValidationResults(results)
+	}
+
+	// Results are already printed; don't repeat usage on failure
+	cmd.SilenceUsage = true
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d specs are invalid", invalid, len(args))
+	}
+	if validateStrict && problems > 0 {
+		return fmt.Errorf("%d operations have no example response", problems)
+	}
+	return nil
+}
+
+// validateSource parses a spec and summarizes its operations
+func validateSource(source string) validationResult {
+	result := validationResult{Source: source}
+
+	content, err := readSource(source)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	parsed, err := parser.NewParser().Parse(content, validateBasePath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Valid = true
+	result.Name = parsed.Spec.Name
+	result.Version = parsed.Spec.Version
+
+	ops := parsed.Operations
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].FullPath != ops[j].FullPath {
+			return ops[i].FullPath < ops[j].FullPath
+		}
+		return ops[i].Method < ops[j].Method
+	})
+
+	for _, op := range ops {
+		validated := validatedOperation{
+			Method:      op.Method,
+			Path:        op.FullPath,
+			OperationID: op.OperationID,
+		}
+		if op.ExampleResponse != nil {
+			validated.ExampleStatus = op.ExampleResponse.StatusCode
+		} else {
+			validated.Problem = noExampleProblem
+		}
+		result.Operations = append(result.Operations, validated)
+	}
+
+	return result
+}
+
+// printValidationResults prints a human readable report
+func printValidationResults(results []validationResult) {
+	for i, result := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		if !result.Valid {
+			fmt.Printf("✗ %s: %s\n", result.Source, result.Error)
+			continue
+		}
+
+		fmt.Printf("✓ %s: %s %s (%d operations)\n", result.Source, result.Name, result.Version, len(result.Operations))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, op := range result.Operations {
+			example := "-"
+			if op.ExampleStatus != 0 {
+				example = fmt.Sprintf("example %d", op.ExampleStatus)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", op.Method, op.Path, op.OperationID, example)
+		}
+		w.Flush()
+
+		for _, op := range result.Operations {
+			if op.Problem != "" {
+				fmt.Printf("  ! %s %s: %s\n", op.Method, op.Path, op.Problem)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package govirtualtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// DefaultImage is the image started when ContainerOptions.Image is empty
+// Build it from the repository root with: docker build -t go-virtual .
+const DefaultImage = "go-virtual:latest"
+
+// containerPort is the port go-virtual listens on inside the container
+const containerPort = "8080/tcp"
+
+// ContainerOptions configures a go-virtual container
+type ContainerOptions struct {
+	Image          string            // Defaults to DefaultImage
+	SpecDir        string            // Directory of specs to load once the server is healthy
+	BasePath       string            // Base path for specs loaded from SpecDir
+	Stubs          []Stub            // Responses added once the specs are loaded, tried in order per operation
+	Env            map[string]string // Extra environment, e.g. GOVIRTUAL_LOGGING_LEVEL
+	StartupTimeout time.Duration     // Defaults to 30s
+}
+
+// Stub is a response for the operation with the given method and spec path, e.g. "/pets/{petId}"
+type Stub struct {
+	Method   string
+	Path     string
+	Response Response
+}
+
+// Container is a go-virtual server running in Docker
+// It is driven through the docker CLI, so no Docker SDK is needed
+type Container struct {
+	// ID is the Docker container ID
+	ID string
+	// URL is the base URL of the server on the host, e.g. http://127.0.0.1:49153
+	URL string
+
+	client  *http.Client
+	specIDs []string // Specs loaded through this container, searched by Stub
+}
+
+// StartContainer starts go-virtual in a container, waits for it to become healthy, loads the
+// specs from opts.SpecDir and adds opts.Stubs. Storage is in-memory, so the container starts empty
+func StartContainer(ctx context.Context, opts ContainerOptions) (*Container, error) {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	timeout := opts.StartupTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	args := []string{"run", "-d", "--rm", "-p", "127.0.0.1::8080", "-e", "GOVIRTUAL_STORAGE_TYPE=memory"}
+	for key, value := range opts.Env {
+		args = append(args, "-e", key+"="+value)
+	}
+	args = append(args, image)
+
+	out, err := docker(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+	c := &Container{
+		ID:     out,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if err := c.start(ctx, timeout); err != nil {
+		logs, _ := docker(context.Background(), "logs", c.ID)
+		c.Terminate(context.Background())
+		if logs != "" {
+			return nil, fmt.Errorf("%w\ncontainer logs:\n%s", err, logs)
+		}
+		return nil, err
+	}
+
+	if opts.SpecDir != "" {
+		if _, err := c.LoadDir(ctx, opts.SpecDir, opts.BasePath); err != nil {
+			c.Terminate(context.Background())
+			return nil, err
+		}
+	}
+	for _, stub := range opts.Stubs {
+		if err := c.Stub(ctx, stub.Method, stub.Path, stub.Response); err != nil {
+			c.Terminate(context.Background())
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// RunContainer starts a container for a test and terminates it when the test finishes
+// The test is skipped when the docker CLI is not installed
+func RunContainer(t testing.TB, opts ContainerOptions) *Container {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("govirtualtest: docker is not available")
+	}
+
+	c, err := StartContainer(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("govirtualtest: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Terminate(context.Background())
+	})
+	return c
+}
+
+// start resolves the mapped port and waits for the readiness endpoint
+func (c *Container) start(ctx context.Context, timeout time.Duration) error {
+	out, err := docker(ctx, "port", c.ID, containerPort)
+	if err != nil {
+		return fmt.Errorf("failed to resolve container port: %w", err)
+	}
+	// One line per host binding, e.g. "127.0.0.1:49153"
+	hostPort, _, _ := strings.Cut(out, "\n")
+	c.URL = "http://" + strings.TrimSpace(hostPort)
+
+	return c.waitHealthy(ctx, timeout)
+}
+
+// waitHealthy polls the readiness endpoint until it answers 200 OK
+func (c *Container) waitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+"/_api/health/ready", nil)
+		if resp, err := c.client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container did not become healthy within %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// LoadSpec creates a spec through the admin API and returns its ID
+func (c *Container) LoadSpec(ctx context.Context, content, basePath string) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	input := map[string]string{"content": content, "basePath": basePath}
+	if err := c.admin(ctx, http.MethodPost, "/specs", input, &created); err != nil {
+		return "", fmt.Errorf("failed to create spec: %w", err)
+	}
+	c.specIDs = append(c.specIDs, created.ID)
+	return created.ID, nil
+}
+
+// Stub adds a response for the operation with the given method and spec path, e.g. "/pets/{petId}"
+// Only specs loaded through this container are searched. Stubs are tried in the order they were
+// added; the first whose conditions match is returned
+func (c *Container) Stub(ctx context.Context, method, path string, resp Response) error {
+	for _, specID := range c.specIDs {
+		var ops []models.OperationSummary
+		if err := c.admin(ctx, http.MethodGet, "/specs/"+specID+"/operations", nil, &ops); err != nil {
+			return fmt.Errorf("failed to list operations: %w", err)
+		}
+		for _, op := range ops {
+			if !strings.EqualFold(op.Method, method) || (op.Path != path && op.FullPath != path) {
+				continue
+			}
+			if err := c.admin(ctx, http.MethodPost, "/operations/"+op.ID+"/responses", resp.input(op.ResponseCount), nil); err != nil {
+				return fmt.Errorf("failed to stub %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no operation %s %s in the loaded specs", strings.ToUpper(method), path)
+}
+
+// admin sends a JSON request to the admin API and decodes the response into out, if not nil
+func (c *Container) admin(ctx context.Context, method, path string, in, out interface{}) error {
+	var payload io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+"/_api"+path, payload)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// LoadDir loads every spec file (.yaml, .yml, .json) in a directory and returns their IDs
+func (c *Container) LoadDir(ctx context.Context, dir, basePath string) ([]string, error) {
+	files, err := specFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		id, err := c.LoadSpec(ctx, string(content), basePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Terminate stops and removes the container
+func (c *Container) Terminate(ctx context.Context) error {
+	_, err := docker(ctx, "rm", "-f", c.ID)
+	return err
+}
+
+// docker runs a docker CLI command and returns its trimmed output
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("docker %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,60 @@
+package govirtualtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContainer needs Docker and a go-virtual image, e.g.
+// docker build -t go-virtual . && GOVIRTUAL_TEST_IMAGE=go-virtual go test ./govirtualtest
+func TestContainer(t *testing.T) {
+	image := os.Getenv("GOVIRTUAL_TEST_IMAGE")
+	if image == "" {
+		t.Skip("GOVIRTUAL_TEST_IMAGE is not set")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte(ordersSpec), 0644)
+
+	c := RunContainer(t, ContainerOptions{Image: image, SpecDir: dir})
+
+	resp, err := http.Get(c.URL + "/orders/1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+// TestContainer_Stub drives the container's admin API calls against an embedded server
+func TestContainer_Stub(t *testing.T) {
+	srv := NewServer(t, "")
+	c := &Container{URL: srv.URL, client: http.DefaultClient}
+	ctx := context.Background()
+
+	if _, err := c.LoadSpec(ctx, ordersSpec, "/api"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stub(ctx, "GET", "/orders/{id}", Response{Body: `{"id": "{{path.id}}"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stub(ctx, "DELETE", "/orders/{id}", Response{}); err == nil {
+		t.Error("Expected an error for an operation that isn't in the loaded specs")
+	}
+
+	resp, err := http.Get(srv.URL + "/api/orders/7")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"id": "7"}` {
+		t.Errorf("Expected the stubbed response, got %s", body)
+	}
+}
@@ -0,0 +1,331 @@
+// Package govirtualtest starts in-memory go-virtual mock servers for Go tests
+//
+// A server is created from an OpenAPI spec and serves it on an httptest server:
+//
+//	srv := govirtualtest.NewServer(t, petstoreYAML)
+//	srv.Stub("GET", "/pets/{petId}", govirtualtest.Response{StatusCode: 200, Body: `{"id": "{{path.petId}}"}`})
+//
+//	client.Get(srv.URL + "/pets/1")
+//
+//	srv.RequireReceived("GET", "/pets/1")
+//
+// Every request is traced, so tests can assert on what the code under test sent.
+package govirtualtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prasenjit/go-virtual/internal/api"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
+	"github.com/prasenjit/go-virtual/internal/proxy"
+	"github.com/prasenjit/go-virtual/internal/stats"
+	"github.com/prasenjit/go-virtual/internal/storage"
+	"github.com/prasenjit/go-virtual/internal/tracing"
+)
+
+// maxTraces is the number of requests a test server remembers
+const maxTraces = 10000
+
+// Server is an in-memory go-virtual instance running on an httptest server
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:54321
+	// The admin API is available under URL + "/_api"
+	URL string
+
+	t              testing.TB
+	httpServer     *httptest.Server
+	store          storage.Storage
+	tracingService *tracing.Service
+	proxyEngine    *proxy.Engine
+	specs          []*models.Spec
+}
+
+// Option configures a test server
+type Option func(*options)
+
+type options struct {
+	basePath        string
+	exampleFallback bool
+}
+
+// WithBasePath mounts the spec under a path prefix
+func WithBasePath(basePath string) Option {
+	return func(o *options) {
+		o.basePath = basePath
+	}
+}
+
+// WithoutExampleFallback disables answering with spec examples when no stub matches
+func WithoutExampleFallback() Option {
+	return func(o *options) {
+		o.exampleFallback = false
+	}
+}
+
+// Response is a stubbed response for an operation
+// Headers and Body can use go-virtual template variables such as {{path.id}}
+type Response struct {
+	Name       string
+	StatusCode int // Defaults to 200
+	Headers    map[string]string
+	Body       string
+	Delay      int // Milliseconds
+	Conditions []Condition
+}
+
+// Condition restricts a stubbed response to matching requests
+type Condition struct {
+	Source   string // path, query, header, body, rawBody, ...
+	Key      string
+	Operator string // eq, ne, contains, regex, exists, ...
+	Value    string
+}
+
+// Request is a request received by the server
+type Request struct {
+	Method  string
+	Path    string
+	Query   url.Values
+	Headers http.Header
+	Body    string
+
+	StatusCode int // Status code the server answered with
+}
+
+// NewServer starts a server mocking the given OpenAPI spec (YAML or JSON)
+// An empty spec starts a server without operations; more specs can be added with AddSpec.
+// The server is closed when the test finishes
+func NewServer(t testing.TB, spec string, opts ...Option) *Server {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	statsCollector := stats.NewCollector()
+	tracingService := tracing.NewService(maxTraces)
+	proxyEngine := proxy.NewEngine(store, statsCollector, tracingService)
+	router := api.NewRouter(store, statsCollector, tracingService, proxyEngine)
+	router.SetReady(true)
+
+	s := &Server{
+		t:              t,
+		store:          store,
+		tracingService: tracingService,
+		proxyEngine:    proxyEngine,
+	}
+
+	adminHandler := router.Handler()
+	s.httpServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/_api/") {
+			adminHandler.ServeHTTP(w, r)
+			return
+		}
+		proxyEngine.ServeHTTP(w, r)
+	}))
+	s.URL = s.httpServer.URL
+	t.Cleanup(s.Close)
+
+	if spec != "" {
+		s.AddSpec(spec, opts...)
+	}
+
+	return s
+}
+
+// AddSpec loads another OpenAPI spec into the server and returns its ID
+func (s *Server) AddSpec(content string, opts ...Option) string {
+	s.t.Helper()
+
+	o := options{exampleFallback: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	result, err := parser.NewParser().Parse(content, o.basePath)
+	if err != nil {
+		s.t.Fatalf("govirtualtest: %v", err)
+	}
+
+	result.Spec.Tracing = true
+	result.Spec.UseExampleFallback = o.exampleFallback
+
+	if err := s.store.CreateSpec(result.Spec); err != nil {
+		s.t.Fatalf("govirtualtest: %v", err)
+	}
+	for _, op := range result.Operations {
+		if err := s.store.CreateOperation(op); err != nil {
+			s.t.Fatalf("govirtualtest: %v", err)
+		}
+	}
+
+	s.specs = append(s.specs, result.Spec)
+	s.proxyEngine.ReloadRoutes()
+
+	return result.Spec.ID
+}
+
+// Stub adds a response for the operation with the given method and spec path, e.g. "/pets/{petId}"
+// Stubs are tried in the order they were added; the first whose conditions match is returned
+func (s *Server) Stub(method, path string, resp Response) {
+	s.t.Helper()
+
+	op := s.findOperation(method, path)
+	if op == nil {
+		s.t.Fatalf("govirtualtest: no operation %s %s in the loaded specs", strings.ToUpper(method), path)
+	}
+
+	existing, _ := s.store.GetResponseConfigsByOperation(op.ID)
+	input := resp.input(len(existing))
+
+	cfg := &models.ResponseConfig{
+		ID:          uuid.New().String(),
+		OperationID: op.ID,
+		Name:        input.Name,
+		Priority:    input.Priority,
+		Conditions:  input.Conditions,
+		StatusCode:  input.StatusCode,
+		Headers:     input.Headers,
+		Body:        input.Body,
+		Delay:       input.Delay,
+		Enabled:     true,
+	}
+	if err := s.store.CreateResponseConfig(cfg); err != nil {
+		s.t.Fatalf("govirtualtest: %v", err)
+	}
+}
+
+// input returns the response config of a stub tried after the given number of earlier ones
+func (resp Response) input(priority int) models.ResponseConfigInput {
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	headers := resp.Headers
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	conditions := make([]models.Condition, len(resp.Conditions))
+	for i, c := range resp.Conditions {
+		conditions[i] = models.Condition{Source: c.Source, Key: c.Key, Operator: c.Operator, Value: c.Value}
+	}
+
+	name := resp.Name
+	if name == "" {
+		name = fmt.Sprintf("stub %d", priority+1)
+	}
+
+	return models.ResponseConfigInput{
+		Name:       name,
+		Priority:   priority,
+		Conditions: conditions,
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       resp.Body,
+		Delay:      resp.Delay,
+		Enabled:    true,
+	}
+}
+
+// findOperation returns the operation with the given method and spec path
+func (s *Server) findOperation(method, path string) *models.Operation {
+	for _, spec := range s.specs {
+		ops, _ := s.store.GetOperationsBySpec(spec.ID)
+		for _, op := range ops {
+			if strings.EqualFold(op.Method, method) && (op.Path == path || op.FullPath == path) {
+				return op
+			}
+		}
+	}
+	return nil
+}
+
+// Requests returns all requests received so far, oldest first
+func (s *Server) Requests() []Request {
+	traces := s.tracingService.GetTraces(nil)
+
+	requests := make([]Request, 0, len(traces))
+	for i := len(traces) - 1; i >= 0; i-- {
+		trace := traces[i]
+		requests = append(requests, Request{
+			Method:     trace.Request.Method,
+			Path:       trace.Request.Path,
+			Query:      url.Values(trace.Request.Query),
+			Headers:    http.Header(trace.Request.Headers),
+			Body:       trace.Request.Body,
+			StatusCode: trace.Response.StatusCode,
+		})
+	}
+	return requests
+}
+
+// Received returns the requests received with the given method and request path, oldest first
+func (s *Server) Received(method, path string) []Request {
+	var matched []Request
+	for _, req := range s.Requests() {
+		if strings.EqualFold(req.Method, method) && req.Path == path {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// RequireReceived fails the test unless a request with the given method and path was received
+// It returns the most recent matching request
+func (s *Server) RequireReceived(method, path string) Request {
+	s.t.Helper()
+
+	matched := s.Received(method, path)
+	if len(matched) == 0 {
+		s.t.Fatalf("govirtualtest: expected a %s %s request, got %s", strings.ToUpper(method), path, s.describeRequests())
+	}
+	return matched[len(matched)-1]
+}
+
+// RequireReceivedTimes fails the test unless exactly n matching requests were received
+func (s *Server) RequireReceivedTimes(method, path string, n int) []Request {
+	s.t.Helper()
+
+	matched := s.Received(method, path)
+	if len(matched) != n {
+		s.t.Fatalf("govirtualtest: expected %d %s %s requests, got %d", n, strings.ToUpper(method), path, len(matched))
+	}
+	return matched
+}
+
+// RequireNotReceived fails the test if a request with the given method and path was received
+func (s *Server) RequireNotReceived(method, path string) {
+	s.t.Helper()
+
+	if matched := s.Received(method, path); len(matched) > 0 {
+		s.t.Fatalf("govirtualtest: expected no %s %s request, got %d", strings.ToUpper(method), path, len(matched))
+	}
+}
+
+// Reset forgets all received requests
+func (s *Server) Reset() {
+	s.tracingService.ClearTraces()
+}
+
+// Close shuts the server down. It is called automatically when the test finishes
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// describeRequests summarizes received requests for failure messages
+func (s *Server) describeRequests() string {
+	requests := s.Requests()
+	if len(requests) == 0 {
+		return "no requests"
+	}
+
+	lines := make([]string, len(requests))
+	for i, req := range requests {
+		lines[i] = req.Method + " " + req.Path
+	}
+	return "[" + strings.Join(lines, ", ") + "]"
+}
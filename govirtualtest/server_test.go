@@ -0,0 +1,145 @@
+package govirtualtest
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ordersSpec = `
+openapi: 3.0.0
+info:
+  title: Orders
+  version: 1.0.0
+paths:
+  /orders:
+    post:
+      operationId: createOrder
+      responses:
+        "201":
+          description: Created
+  /orders/{id}:
+    get:
+      operationId: getOrder
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              example:
+                id: "example"
+`
+
+func TestServer_StubAndAssert(t *testing.T) {
+	srv := NewServer(t, ordersSpec)
+
+	srv.Stub("POST", "/orders", Response{
+		StatusCode: http.StatusCreated,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       `{"sku": "{{body.sku}}"}`,
+	})
+
+	resp, err := http.Post(srv.URL+"/orders", "application/json", strings.NewReader(`{"sku": "A-1"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if string(body) != `{"sku": "A-1"}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+
+	req := srv.RequireReceived("POST", "/orders")
+	if req.Body != `{"sku": "A-1"}` {
+		t.Errorf("Unexpected recorded body: %s", req.Body)
+	}
+	if req.StatusCode != http.StatusCreated {
+		t.Errorf("Expected recorded status 201, got %d", req.StatusCode)
+	}
+	srv.RequireNotReceived("GET", "/orders/1")
+
+	srv.Reset()
+	srv.RequireReceivedTimes("POST", "/orders", 0)
+}
+
+func TestServer_Conditions(t *testing.T) {
+	srv := NewServer(t, ordersSpec, WithBasePath("/api"))
+
+	srv.Stub("GET", "/orders/{id}", Response{
+		StatusCode: http.StatusNotFound,
+		Conditions: []Condition{{Source: "path", Key: "id", Operator: "eq", Value: "missing"}},
+	})
+	srv.Stub("GET", "/orders/{id}", Response{Body: `{"id": "{{path.id}}"}`})
+
+	for _, tt := range []struct {
+		id     string
+		status int
+	}{
+		{"missing", http.StatusNotFound},
+		{"42", http.StatusOK},
+	} {
+		resp, err := http.Get(srv.URL + "/api/orders/" + tt.id)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET /api/orders/%s: expected status %d, got %d", tt.id, tt.status, resp.StatusCode)
+		}
+	}
+
+	srv.RequireReceivedTimes("GET", "/api/orders/42", 1)
+	if got := len(srv.Requests()); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestServer_AdminAPI(t *testing.T) {
+	srv := NewServer(t, ordersSpec)
+
+	resp, err := http.Get(srv.URL + "/_api/specs")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	srv.RequireNotReceived("GET", "/_api/specs")
+}
+
+func TestServer_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte(ordersSpec), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a spec"), 0644)
+
+	srv := NewServer(t, "")
+	if ids := srv.LoadDir(dir); len(ids) != 1 {
+		t.Fatalf("Expected 1 spec, got %d", len(ids))
+	}
+
+	resp, err := http.Get(srv.URL + "/orders/1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
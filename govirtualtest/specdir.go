@@ -0,0 +1,56 @@
+package govirtualtest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// specExtensions are the file extensions loaded from a spec directory
+var specExtensions = []string{".yaml", ".yml", ".json"}
+
+// specFiles returns the spec files in a directory, sorted by name
+// Subdirectories and other files are ignored
+func specFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		for _, allowed := range specExtensions {
+			if ext == allowed {
+				files = append(files, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadDir loads every spec file (.yaml, .yml, .json) in a directory and returns their IDs
+func (s *Server) LoadDir(dir string, opts ...Option) []string {
+	s.t.Helper()
+
+	files, err := specFiles(dir)
+	if err != nil {
+		s.t.Fatalf("govirtualtest: %v", err)
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			s.t.Fatalf("govirtualtest: %v", err)
+		}
+		ids = append(ids, s.AddSpec(string(content), opts...))
+	}
+	return ids
+}
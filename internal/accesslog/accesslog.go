@@ -0,0 +1,223 @@
+// Package accesslog writes one line per request served by the proxy engine
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/requestid"
+)
+
+// Supported access log formats
+const (
+	FormatCombined = "combined" // Apache/NGINX combined log format
+	FormatJSON     = "json"     // One JSON object per line
+)
+
+// Logger writes access log lines to a writer
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	format string
+}
+
+// New creates a logger writing the given format to w
+func New(w io.Writer, format string) (*Logger, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "":
+		format = FormatCombined
+	case FormatCombined, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown access log format %q (use combined or json)", format)
+	}
+	return &Logger{w: w, format: format}, nil
+}
+
+// Open creates a logger appending to a rotated file; an empty path or "-" writes to stdout
+func Open(path, format string, rotate logging.RotateOptions) (*Logger, error) {
+	if path == "" || path == "-" {
+		return New(os.Stdout, format)
+	}
+
+	file, err := logging.OpenRotatingFile(path, rotate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+
+	logger, err := New(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	logger.closer = file
+	return logger, nil
+}
+
+// Close closes the underlying file, if the logger opened one
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closer.Close()
+}
+
+// Entry is a single served request
+type Entry struct {
+	Time        time.Time     `json:"time"`
+	RemoteAddr  string        `json:"remoteAddr"`
+	Method      string        `json:"method"`
+	URI         string        `json:"uri"`
+	Proto       string        `json:"proto"`
+	Status      int           `json:"status"`
+	Bytes       int64         `json:"bytes"`
+	Duration    time.Duration `json:"-"`
+	Referer     string        `json:"referer,omitempty"`
+	UserAgent   string        `json:"userAgent,omitempty"`
+	RequestID   string        `json:"requestId,omitempty"`
+	SpecID      string        `json:"specId,omitempty"`
+	OperationID string        `json:"operationId,omitempty"`
+}
+
+// Log writes an entry; write errors are ignored so logging never fails a request
+func (l *Logger) Log(entry *Entry) {
+	var line []byte
+	if l.format == FormatJSON {
+		line = formatJSON(entry)
+	} else {
+		line = formatCombined(entry)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
+
+// formatCombined formats an entry in the combined log format
+func formatCombined(entry *Entry) []byte {
+	size := "-"
+	if entry.Bytes > 0 {
+		size = fmt.Sprint(entry.Bytes)
+	}
+	return []byte(fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s %q %q\n",
+		host(entry.RemoteAddr),
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		entry.Method, entry.URI, entry.Proto,
+		entry.Status, size,
+		orDash(entry.Referer), orDash(entry.UserAgent)))
+}
+
+// formatJSON formats an entry as a JSON line, with the duration in milliseconds
+func formatJSON(entry *Entry) []byte {
+	type alias Entry
+	data, _ := json.Marshal(struct {
+		*alias
+		Duration float64 `json:"durationMs"`
+	}{
+		alias:    (*alias)(entry),
+		Duration: float64(entry.Duration.Microseconds()) / 1000,
+	})
+	return append(data, '\n')
+}
+
+// host strips the port from a remote address
+func host(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	if addr == "" {
+		return "-"
+	}
+	return addr
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Recorder is a response writer that captures the status and size of a response
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+
+	// SpecID and OperationID identify the matched operation, when there is one
+	SpecID      string
+	OperationID string
+}
+
+// NewRecorder wraps a response writer
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+// WriteHeader records the status code
+func (rec *Recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written
+func (rec *Recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the wrapped writer does
+func (rec *Recorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (rec *Recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Entry builds the access log entry of a finished request
+func (rec *Recorder) Entry(r *http.Request, start time.Time) *Entry {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	return &Entry{
+		Time:        start,
+		RemoteAddr:  r.RemoteAddr,
+		Method:      r.Method,
+		URI:         uri,
+		Proto:       r.Proto,
+		Status:      status,
+		Bytes:       rec.bytes,
+		Duration:    time.Since(start),
+		Referer:     r.Referer(),
+		UserAgent:   r.UserAgent(),
+		RequestID:   requestid.FromContext(r.Context()),
+		SpecID:      rec.SpecID,
+		OperationID: rec.OperationID,
+	}
+}
@@ -0,0 +1,116 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/logging"
+)
+
+func sampleEntry() *Entry {
+	return &Entry{
+		Time:        time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC),
+		RemoteAddr:  "10.0.0.1:51234",
+		Method:      "GET",
+		URI:         "/api/users/7?expand=true",
+		Proto:       "HTTP/1.1",
+		Status:      200,
+		Bytes:       42,
+		Duration:    1500 * time.Microsecond,
+		UserAgent:   "curl/8.0",
+		SpecID:      "spec-1",
+		OperationID: "getUser",
+	}
+}
+
+func TestLogCombined(t *testing.T) {
+	var buf strings.Builder
+	logger, err := New(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Log(sampleEntry())
+
+	expected := `10.0.0.1 - - [05/Mar/2024:14:07:09 +0000] "GET /api/users/7?expand=true HTTP/1.1" 200 42 "-" "curl/8.0"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestLogJSON(t *testing.T) {
+	var buf strings.Builder
+	logger, err := New(&buf, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Log(sampleEntry())
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+		t.Fatalf("Expected a JSON line: %v", err)
+	}
+	if entry["durationMs"] != 1.5 || entry["status"] != float64(200) || entry["operationId"] != "getUser" {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+	if _, ok := entry["referer"]; ok {
+		t.Error("Expected empty referer to be omitted")
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New(&strings.Builder{}, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestOpenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	for i := 0; i < 2; i++ {
+		logger, err := Open(path, FormatCombined, logging.RotateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.Log(sampleEntry())
+		logger.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("Expected 2 lines, got %d", n)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := NewRecorder(w)
+
+	rec.WriteHeader(http.StatusCreated)
+	rec.Write([]byte("hello"))
+	rec.Write([]byte(" world"))
+
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.Header.Set("Referer", "http://example.com/")
+	entry := rec.Entry(req, time.Now())
+
+	if entry.Status != http.StatusCreated || entry.Bytes != 11 {
+		t.Errorf("Expected 201 with 11 bytes, got %d with %d", entry.Status, entry.Bytes)
+	}
+	if entry.Referer != "http://example.com/" || entry.URI != "/orders" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "hello world" {
+		t.Error("Expected the response to reach the wrapped writer")
+	}
+}
@@ -0,0 +1,245 @@
+// Package alerts watches the error rate and latency of specs with an alert policy, so long-running
+// mock environments report when they degrade
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+const (
+	defaultWindow      = 300 * time.Second
+	defaultMinRequests = 10
+	maxSamples         = 10000 // Requests kept per spec; older ones drop out of the window early
+	evaluateInterval   = 5 * time.Second
+	webhookTimeout     = 5 * time.Second
+)
+
+// sample is one request of a spec
+type sample struct {
+	at       time.Time
+	duration time.Duration
+	isError  bool
+}
+
+// Monitor keeps the recent requests of specs with an alert policy and fires their alerts
+type Monitor struct {
+	mu      sync.Mutex
+	specs   map[string]*models.Spec  // spec ID -> spec with an enabled alert policy
+	samples map[string][]sample      // spec ID -> requests, oldest first
+	firing  map[string]*models.Alert // spec ID + metric -> alert
+	client  *http.Client             // Posts alerts to webhooks
+	now     func() time.Time
+}
+
+// NewMonitor creates a monitor without specs
+func NewMonitor() *Monitor {
+	return &Monitor{
+		specs:   make(map[string]*models.Spec),
+		samples: make(map[string][]sample),
+		firing:  make(map[string]*models.Alert),
+		client:  &http.Client{Timeout: webhookTimeout},
+		now:     time.Now,
+	}
+}
+
+// SetSpecs sets the specs to watch; those without an enabled alert policy are ignored
+// Specs that are no longer watched lose their requests and alerts
+func (m *Monitor) SetSpecs(specs []*models.Spec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	watched := make(map[string]*models.Spec)
+	for _, spec := range specs {
+		if spec.Alerts != nil && spec.Alerts.Enabled {
+			watched[spec.ID] = spec
+		}
+	}
+	for id := range m.samples {
+		if watched[id] == nil {
+			delete(m.samples, id)
+		}
+	}
+	for key, alert := range m.firing {
+		if watched[alert.SpecID] == nil {
+			delete(m.firing, key)
+		}
+	}
+	m.specs = watched
+}
+
+// Record adds a request of a spec
+func (m *Monitor) Record(specID string, duration time.Duration, isError bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.specs[specID] == nil {
+		return
+	}
+	samples := append(m.samples[specID], sample{at: m.now(), duration: duration, isError: isError})
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	m.samples[specID] = samples
+}
+
+// Run evaluates the thresholds periodically until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(evaluateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Evaluate()
+		}
+	}
+}
+
+// Evaluate checks the thresholds of all watched specs against their window, firing alerts that
+// started breaching and resolving those that stopped. Webhooks are posted before it returns
+func (m *Monitor) Evaluate() {
+	m.mu.Lock()
+	now := m.now()
+	var changed []models.Alert
+	var webhooks []string
+	for id, spec := range m.specs {
+		policy := spec.Alerts
+		window := time.Duration(policy.Window) * time.Second
+		if window <= 0 {
+			window = defaultWindow
+		}
+		minRequests := policy.MinRequests
+		if minRequests <= 0 {
+			minRequests = defaultMinRequests
+		}
+
+		samples := m.samples[id]
+		cutoff := now.Add(-window)
+		start := sort.Search(len(samples), func(i int) bool { return !samples[i].at.Before(cutoff) })
+		samples = samples[start:]
+		m.samples[id] = samples
+
+		errorRate, p95 := measure(samples)
+		checks := []struct {
+			metric    string
+			value     float64
+			threshold float64
+		}{
+			{models.AlertErrorRate, errorRate, policy.ErrorRate},
+			{models.AlertP95Latency, p95, float64(policy.P95Latency)},
+		}
+		for _, check := range checks {
+			key := id + "\x00" + check.metric
+			alert := m.firing[key]
+			breached := check.threshold > 0 && len(samples) >= minRequests && check.value > check.threshold
+
+			switch {
+			case breached && alert == nil:
+				alert = &models.Alert{
+					SpecID:    id,
+					SpecName:  spec.Name,
+					Metric:    check.metric,
+					Status:    models.AlertFiring,
+					Value:     check.value,
+					Threshold: check.threshold,
+					Requests:  len(samples),
+					Since:     now,
+				}
+				m.firing[key] = alert
+				changed = append(changed, *alert)
+				webhooks = append(webhooks, policy.Webhook)
+			case breached:
+				alert.Value, alert.Requests = check.value, len(samples)
+			case alert != nil:
+				delete(m.firing, key)
+				resolved := *alert
+				resolved.Status = models.AlertResolved
+				resolved.Value, resolved.Requests = check.value, len(samples)
+				resolved.ResolvedAt = &now
+				changed = append(changed, resolved)
+				webhooks = append(webhooks, policy.Webhook)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for i, alert := range changed {
+		m.notify(alert, webhooks[i])
+	}
+}
+
+// Alerts returns the alerts currently firing, of one spec or of all when specID is empty
+func (m *Monitor) Alerts(specID string) []models.Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alerts := make([]models.Alert, 0)
+	for _, alert := range m.firing {
+		if specID == "" || alert.SpecID == specID {
+			alerts = append(alerts, *alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].SpecID != alerts[j].SpecID {
+			return alerts[i].SpecID < alerts[j].SpecID
+		}
+		return alerts[i].Metric < alerts[j].Metric
+	})
+	return alerts
+}
+
+// notify logs an alert that fired or resolved and posts it to the webhook, if any
+func (m *Monitor) notify(alert models.Alert, webhook string) {
+	attrs := []interface{}{"specId", alert.SpecID, "metric", alert.Metric, "value", alert.Value, "threshold", alert.Threshold, "requests", alert.Requests}
+	if alert.Status == models.AlertFiring {
+		slog.Warn("alert fired", attrs...)
+	} else {
+		slog.Info("alert resolved", attrs...)
+	}
+	if webhook == "" {
+		return
+	}
+
+	// Posting in order keeps a resolution from overtaking the alert it resolves
+	body, _ := json.Marshal(alert)
+	resp, err := m.client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("failed to post alert to webhook", "specId", alert.SpecID, "webhook", webhook, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("alert webhook rejected the alert", "specId", alert.SpecID, "webhook", webhook, "status", resp.StatusCode)
+	}
+}
+
+// measure returns the error rate in percent and the 95th percentile latency in milliseconds
+func measure(samples []sample) (float64, float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	errors := 0
+	durations := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		if s.isError {
+			errors++
+		}
+		durations[i] = s.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	index := int(math.Ceil(0.95*float64(len(durations)))) - 1
+	p95 := float64(durations[index]) / float64(time.Millisecond)
+	return float64(errors) * 100 / float64(len(samples)), p95
+}
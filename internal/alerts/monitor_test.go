@@ -0,0 +1,111 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// newTestMonitor returns a monitor watching one spec, with a clock the test moves
+func newTestMonitor(policy *models.AlertPolicy) (*Monitor, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMonitor()
+	m.now = func() time.Time { return now }
+	m.SetSpecs([]*models.Spec{
+		{ID: "spec-1", Name: "API", Alerts: policy},
+		{ID: "spec-2", Name: "Quiet"},
+	})
+	return m, &now
+}
+
+func TestMonitor_ErrorRate(t *testing.T) {
+	m, now := newTestMonitor(&models.AlertPolicy{Enabled: true, ErrorRate: 20, Window: 60, MinRequests: 5})
+
+	for i := 0; i < 4; i++ {
+		m.Record("spec-1", time.Millisecond, true)
+	}
+	m.Evaluate()
+	if alerts := m.Alerts(""); len(alerts) != 0 {
+		t.Fatalf("Expected no alert below minRequests, got %+v", alerts)
+	}
+
+	m.Record("spec-1", time.Millisecond, false)
+	m.Record("spec-2", time.Millisecond, true)
+	m.Evaluate()
+	alerts := m.Alerts("")
+	if len(alerts) != 1 || alerts[0].Metric != models.AlertErrorRate || alerts[0].Value != 80 || alerts[0].Status != models.AlertFiring {
+		t.Fatalf("Expected an error rate alert at 80%%, got %+v", alerts)
+	}
+	if alerts := m.Alerts("spec-2"); len(alerts) != 0 {
+		t.Errorf("Expected no alerts for a spec without a policy, got %+v", alerts)
+	}
+
+	// The requests leave the window and the alert resolves
+	*now = now.Add(2 * time.Minute)
+	m.Evaluate()
+	if alerts := m.Alerts(""); len(alerts) != 0 {
+		t.Errorf("Expected the alert resolved, got %+v", alerts)
+	}
+}
+
+func TestMonitor_P95Latency(t *testing.T) {
+	m, _ := newTestMonitor(&models.AlertPolicy{Enabled: true, P95Latency: 100, MinRequests: 1})
+
+	for i := 0; i < 19; i++ {
+		m.Record("spec-1", 10*time.Millisecond, false)
+	}
+	m.Record("spec-1", time.Second, false)
+	m.Evaluate()
+	if alerts := m.Alerts(""); len(alerts) != 0 {
+		t.Fatalf("Expected the slowest 5%% to be ignored, got %+v", alerts)
+	}
+
+	m.Record("spec-1", time.Second, false)
+	m.Evaluate()
+	if alerts := m.Alerts("spec-1"); len(alerts) != 1 || alerts[0].Metric != models.AlertP95Latency || alerts[0].Value != 1000 {
+		t.Errorf("Expected a latency alert at 1000ms, got %+v", alerts)
+	}
+}
+
+func TestMonitor_Webhook(t *testing.T) {
+	received := make(chan models.Alert, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert models.Alert
+		json.NewDecoder(r.Body).Decode(&alert)
+		received <- alert
+	}))
+	defer server.Close()
+
+	m, now := newTestMonitor(&models.AlertPolicy{Enabled: true, ErrorRate: 50, MinRequests: 1, Webhook: server.URL})
+	m.Record("spec-1", time.Millisecond, true)
+	m.Evaluate()
+	*now = now.Add(10 * time.Minute)
+	m.Evaluate()
+
+	for _, want := range []string{models.AlertFiring, models.AlertResolved} {
+		select {
+		case alert := <-received:
+			if alert.Status != want || alert.SpecID != "spec-1" || alert.Metric != models.AlertErrorRate {
+				t.Errorf("Expected a %s alert, got %+v", want, alert)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a %s alert at the webhook", want)
+		}
+	}
+}
+
+func TestMonitor_SetSpecs(t *testing.T) {
+	m, _ := newTestMonitor(&models.AlertPolicy{Enabled: true, ErrorRate: 10, MinRequests: 1})
+	m.Record("spec-1", time.Millisecond, true)
+	m.Evaluate()
+
+	// Disabling the policy drops its requests and alerts
+	m.SetSpecs([]*models.Spec{{ID: "spec-1", Alerts: &models.AlertPolicy{Enabled: false, ErrorRate: 10}}})
+	if alerts := m.Alerts(""); len(alerts) != 0 || len(m.samples) != 0 {
+		t.Errorf("Expected no alerts or requests, got %+v %v", alerts, m.samples)
+	}
+}
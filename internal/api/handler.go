@@ -1,18 +1,47 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prasenjit/go-virtual/internal/backup"
+	"github.com/prasenjit/go-virtual/internal/cluster"
+	"github.com/prasenjit/go-virtual/internal/condition"
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/mockoon"
 	"github.com/prasenjit/go-virtual/internal/models"
 	"github.com/prasenjit/go-virtual/internal/parser"
 	"github.com/prasenjit/go-virtual/internal/proxy"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
+	"github.com/prasenjit/go-virtual/internal/template"
 	"github.com/prasenjit/go-virtual/internal/tracing"
+	"github.com/prasenjit/go-virtual/internal/wiremock"
+	"gopkg.in/yaml.v3"
 )
 
+// PurgeBroadcaster passes purges made on this node on to the other nodes
+type PurgeBroadcaster interface {
+	BroadcastPurge(req *models.PurgeRequest) error
+}
+
 // Handler handles API requests
 type Handler struct {
 	store          storage.Storage
@@ -20,6 +49,11 @@ type Handler struct {
 	tracingService *tracing.Service
 	proxyEngine    *proxy.Engine
 	parser         *parser.Parser
+	ready          atomic.Bool      // set once the server accepts mock traffic, cleared while draining
+	cluster        *cluster.Node    // nil unless cluster mode is enabled
+	purges         PurgeBroadcaster // nil unless traces are shared with other nodes
+	streamsDone    chan struct{}    // closed on shutdown to end the open event streams
+	closeStreams   sync.Once
 }
 
 // NewHandler creates a new API handler
@@ -30,6 +64,7 @@ func NewHandler(store storage.Storage, statsCollector *stats.Collector, tracingS
 		tracingService: tracingService,
 		proxyEngine:    proxyEngine,
 		parser:         parser.NewParser(),
+		streamsDone:    make(chan struct{}),
 	}
 }
 
@@ -37,10 +72,20 @@ func NewHandler(store storage.Storage, statsCollector *stats.Collector, tracingS
 func (h *Handler) ListSpecs(c *gin.Context) {
 	specs, err := h.store.GetAllSpecs()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
+	// ?group= lists the specs of one group
+	if group, ok := c.GetQuery("group"); ok {
+		specs = specsInGroup(specs, group)
+	}
+
+	// ?label=key:value (or key=value, or just key) lists the specs carrying every label given
+	if selectors := c.QueryArray("label"); len(selectors) > 0 {
+		specs = specsWithLabels(specs, selectors)
+	}
+
 	// Don't include full content in list
 	result := make([]map[string]interface{}, len(specs))
 	for i, spec := range specs {
@@ -51,6 +96,8 @@ func (h *Handler) ListSpecs(c *gin.Context) {
 			"version":            spec.Version,
 			"description":        spec.Description,
 			"basePath":           spec.BasePath,
+			"group":              spec.Group,
+			"labels":             spec.Labels,
 			"enabled":            spec.Enabled,
 			"tracing":            spec.Tracing,
 			"useExampleFallback": spec.UseExampleFallback,
@@ -77,6 +124,10 @@ func (h *Handler) CreateSpec(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OpenAPI spec: " + err.Error()})
 		return
 	}
+	if err := h.proxyEngine.CheckBasePath(parseResult.Spec.BasePath); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Override name if provided
 	if input.Name != "" {
@@ -86,22 +137,12 @@ func (h *Handler) CreateSpec(c *gin.Context) {
 		parseResult.Spec.Description = input.Description
 	}
 
-	// Save spec
-	if err := h.store.CreateSpec(parseResult.Spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Save spec and operations
+	if err := h.saveParsedSpec(parseResult); err != nil {
+		internalError(c, err)
 		return
 	}
 
-	// Save operations
-	for _, op := range parseResult.Operations {
-		if err := h.store.CreateOperation(op); err != nil {
-			// Rollback spec on error
-			h.store.DeleteSpec(parseResult.Spec.ID)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	}
-
 	// Reload routes
 	h.proxyEngine.ReloadRoutes()
 
@@ -113,6 +154,148 @@ func (h *Handler) CreateSpec(c *gin.Context) {
 	})
 }
 
+// ValidateSpec parses and validates a spec without persisting it
+// Returns the operations that would be created and any conflicts with registered routes
+func (h *Handler) ValidateSpec(c *gin.Context) {
+	var input models.SpecInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parseResult, err := h.parser.Parse(input.Content, input.BasePath)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"valid": false,
+			"error": "Invalid OpenAPI spec: " + err.Error(),
+		})
+		return
+	}
+	if err := h.proxyEngine.CheckBasePath(parseResult.Spec.BasePath); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	operations := make([]models.OperationSummary, len(parseResult.Operations))
+	for i, op := range parseResult.Operations {
+		operations[i] = toOperationSummary(op, 0)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":          true,
+		"name":           parseResult.Spec.Name,
+		"version":        parseResult.Spec.Version,
+		"basePath":       parseResult.Spec.BasePath,
+		"operationCount": len(operations),
+		"operations":     operations,
+		"conflicts":      h.proxyEngine.FindConflicts(parseResult.Operations),
+	})
+}
+
+// ImportMockoon creates a spec from a Mockoon environment file
+// Routes become the operations of a generated OpenAPI document and their responses become
+// response configs. ?name= and ?basePath= override the environment's name and endpoint prefix.
+func (h *Handler) ImportMockoon(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imp, err := mockoon.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Mockoon environment: " + err.Error()})
+		return
+	}
+
+	parseResult, err := h.parser.Parse(imp.Content, c.DefaultQuery("basePath", imp.BasePath))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Mockoon environment: " + err.Error()})
+		return
+	}
+	if err := h.proxyEngine.CheckBasePath(parseResult.Spec.BasePath); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := parseResult.Spec
+	if name := c.Query("name"); name != "" {
+		spec.Name = name
+	}
+	warnings := imp.Warnings
+	if errMsg := validateUpstream(imp.Upstream); errMsg != "" {
+		warnings = append(warnings, "Proxy host "+imp.Upstream+" is ignored: "+errMsg)
+	} else {
+		spec.Upstream = imp.Upstream
+	}
+
+	opsByRoute := make(map[string]*models.Operation, len(parseResult.Operations))
+	for _, op := range parseResult.Operations {
+		opsByRoute[op.Method+" "+op.Path] = op
+	}
+
+	var configs []*models.ResponseConfig
+	for _, route := range imp.Routes {
+		op, ok := opsByRoute[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+		for _, input := range route.Responses {
+			if err := condition.ValidateAll(input.Conditions); err != nil {
+				warnings = append(warnings, route.Method+" "+route.Path+": "+strconv.Quote(input.Name)+" is left out: "+err.Error())
+				continue
+			}
+			if input.BodyFile != "" && !storage.ValidSpecFileName(input.BodyFile) {
+				warnings = append(warnings, route.Method+" "+route.Path+": "+strconv.Quote(input.Name)+" has an invalid body file name "+strconv.Quote(input.BodyFile))
+				input.BodyFile = ""
+			}
+			configs = append(configs, newResponseConfig(op.ID, input))
+		}
+	}
+
+	if err := h.saveParsedSpec(parseResult); err != nil {
+		internalError(c, err)
+		return
+	}
+	for _, cfg := range configs {
+		if err := h.store.CreateResponseConfig(cfg); err != nil {
+			internalError(c, err)
+			return
+		}
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	if warnings == nil {
+		warnings = make([]string, 0)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":             spec.ID,
+		"name":           spec.Name,
+		"version":        spec.Version,
+		"operationCount": len(parseResult.Operations),
+		"responseCount":  len(configs),
+		"warnings":       warnings,
+	})
+}
+
+// saveParsedSpec stores a parsed spec with its operations, removing the spec again on failure
+func (h *Handler) saveParsedSpec(result *parser.ParseResult) error {
+	if err := h.store.CreateSpec(result.Spec); err != nil {
+		return err
+	}
+	for _, op := range result.Operations {
+		if err := h.store.CreateOperation(op); err != nil {
+			h.store.DeleteSpec(result.Spec.ID)
+			return err
+		}
+	}
+	return nil
+}
+
 // GetSpec returns a single spec
 func (h *Handler) GetSpec(c *gin.Context) {
 	id := c.Param("id")
@@ -123,7 +306,11 @@ func (h *Handler) GetSpec(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, spec)
+	// The upstream's health is runtime state, so it isn't part of the stored spec
+	c.JSON(http.StatusOK, struct {
+		*models.Spec
+		UpstreamHealth *models.UpstreamHealth `json:"upstreamHealth,omitempty"`
+	}{spec, h.proxyEngine.UpstreamHealth(id)})
 }
 
 // UpdateSpec updates a spec
@@ -142,6 +329,13 @@ func (h *Handler) UpdateSpec(c *gin.Context) {
 		return
 	}
 
+	if update.BasePath != nil {
+		if err := h.proxyEngine.CheckBasePath(*update.BasePath); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Apply updates
 	if update.Name != nil {
 		spec.Name = *update.Name
@@ -158,26 +352,239 @@ func (h *Handler) UpdateSpec(c *gin.Context) {
 	if update.Description != nil {
 		spec.Description = *update.Description
 	}
+	if update.Group != nil {
+		if errMsg := validateGroup(*update.Group); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Group = strings.TrimSpace(*update.Group)
+	}
+	if update.Labels != nil {
+		if errMsg := validateLabels(update.Labels); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Labels = update.Labels
+		if len(spec.Labels) == 0 {
+			spec.Labels = nil
+		}
+	}
 	if update.Enabled != nil {
 		spec.Enabled = *update.Enabled
 	}
 	if update.Tracing != nil {
 		spec.Tracing = *update.Tracing
 	}
+	if update.Fallbacks != nil {
+		if errMsg := ValidateFallbacks(update.Fallbacks); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Fallbacks = update.Fallbacks
+	}
+	if update.CORS != nil {
+		for i, m := range update.CORS.AllowedMethods {
+			update.CORS.AllowedMethods[i] = strings.ToUpper(strings.TrimSpace(m))
+		}
+		spec.CORS = update.CORS
+	}
+	if update.DisableAutoOptions != nil {
+		spec.DisableAutoOptions = *update.DisableAutoOptions
+	}
+	if update.MaxConcurrent != nil {
+		if *update.MaxConcurrent < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxConcurrent must not be negative"})
+			return
+		}
+		spec.MaxConcurrent = *update.MaxConcurrent
+	}
+	if update.Auth != nil {
+		if errMsg := validateAuth(update.Auth); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Auth = update.Auth
+	}
+	if update.Stateful != nil {
+		if errMsg := h.validateStateful(spec.ID, update.Stateful); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Stateful = update.Stateful
+	}
+	if update.Upstream != nil {
+		if errMsg := validateUpstream(*update.Upstream); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Upstream = *update.Upstream
+	}
+	if update.HealthCheck != nil {
+		if errMsg := validateHealthCheck(update.HealthCheck); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.HealthCheck = update.HealthCheck
+	}
+	if update.UpstreamRewrite != nil {
+		if errMsg := validateUpstreamRewrite(update.UpstreamRewrite); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.UpstreamRewrite = update.UpstreamRewrite
+		if update.UpstreamRewrite.Request == nil && update.UpstreamRewrite.Response == nil {
+			spec.UpstreamRewrite = nil
+		}
+	}
+	if update.Chaos != nil {
+		if errMsg := validateChaos(update.Chaos); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Chaos = update.Chaos
+	}
+	if update.Locale != nil {
+		if errMsg := validateLocale(*update.Locale); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Locale = *update.Locale
+	}
+	if update.ContractCheck != nil {
+		spec.ContractCheck = *update.ContractCheck
+	}
+	if update.Alerts != nil {
+		if errMsg := validateAlerts(update.Alerts); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		spec.Alerts = update.Alerts
+	}
 
 	spec.UpdatedAt = time.Now()
 
 	if err := h.store.UpdateSpec(spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
+	// Injections start over from the seed of a new chaos policy
+	if update.Chaos != nil {
+		h.proxyEngine.ResetChaos(id)
+	}
+
 	// Reload routes if base path or enabled changed
 	h.proxyEngine.ReloadRoutes()
 
 	c.JSON(http.StatusOK, spec)
 }
 
+// UpdateSpecContent replaces the OpenAPI content of a spec
+// Operations are reconciled by their deterministic IDs: response configs are kept for
+// operations that still exist and removed along with operations that no longer do.
+// Custom operations are left untouched.
+func (h *Handler) UpdateSpecContent(c *gin.Context) {
+	id := c.Param("id")
+
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	var input models.SpecContentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+		return
+	}
+
+	// Parse the new content using the existing spec ID so operation IDs stay stable
+	parseResult, err := h.parser.ParseForSpec(input.Content, spec.ID, spec.BasePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OpenAPI spec: " + err.Error()})
+		return
+	}
+
+	// Store the content first, on a copy, so a storage limit rejecting it changes nothing
+	updated := *spec
+	updated.Content = input.Content
+	updated.Version = parseResult.Spec.Version
+	updated.UpdatedAt = time.Now()
+	if err := h.store.UpdateSpec(&updated); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	existingOps, _ := h.store.GetOperationsBySpec(id)
+	existing := make(map[string]*models.Operation, len(existingOps))
+	for _, op := range existingOps {
+		existing[op.ID] = op
+	}
+
+	added := make([]models.OperationSummary, 0)
+	removed := make([]models.OperationSummary, 0)
+	kept := 0
+
+	for _, op := range parseResult.Operations {
+		if current, ok := existing[op.ID]; ok {
+			delete(existing, op.ID)
+			kept++
+			// Operations customized via the admin API take precedence over the spec
+			if current.Custom {
+				continue
+			}
+			op.Disabled = current.Disabled
+			op.Forward = current.Forward
+			op.Echo = current.Echo
+			op.Tracing = current.Tracing
+			op.RateLimit = current.RateLimit
+			if err := h.store.UpdateOperation(op); err != nil {
+				internalError(c, err)
+				return
+			}
+			continue
+		}
+
+		if err := h.store.CreateOperation(op); err != nil {
+			internalError(c, err)
+			return
+		}
+		added = append(added, toOperationSummary(op, 0))
+	}
+
+	// Whatever is left no longer exists in the new content, except custom operations
+	for _, op := range existingOps {
+		if _, ok := existing[op.ID]; !ok || op.Custom {
+			continue
+		}
+		responses, _ := h.store.GetResponseConfigsByOperation(op.ID)
+		if err := h.store.DeleteResponseConfigsByOperation(op.ID); err != nil {
+			internalError(c, err)
+			return
+		}
+		if err := h.store.DeleteOperation(op.ID); err != nil {
+			internalError(c, err)
+			return
+		}
+		removed = append(removed, toOperationSummary(op, len(responses)))
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":             updated.ID,
+		"version":        updated.Version,
+		"operationCount": len(parseResult.Operations),
+		"unchanged":      kept,
+		"added":          added,
+		"removed":        removed,
+	})
+}
+
 // DeleteSpec deletes a spec
 func (h *Handler) DeleteSpec(c *gin.Context) {
 	id := c.Param("id")
@@ -199,15 +606,21 @@ func (h *Handler) DeleteSpec(c *gin.Context) {
 		return
 	}
 
-	// Clear traces for this spec
-	h.tracingService.ClearTracesBySpec(id)
-
-	// Reload routes
-	h.proxyEngine.ReloadRoutes()
+	h.forgetSpec(id)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Spec deleted"})
 }
 
+// forgetSpec stops serving a deleted spec and clears its traces, statistics, shared variables
+// and contract violations
+func (h *Handler) forgetSpec(id string) {
+	h.tracingService.ClearTracesBySpec(id)
+	h.statsCollector.ResetSpec(id)
+	h.proxyEngine.Variables().Clear(id)
+	h.proxyEngine.ClearContractReport(id)
+	h.proxyEngine.ReloadRoutes()
+}
+
 // EnableSpec enables a spec
 func (h *Handler) EnableSpec(c *gin.Context) {
 	id := c.Param("id")
@@ -222,7 +635,7 @@ func (h *Handler) EnableSpec(c *gin.Context) {
 	spec.UpdatedAt = time.Now()
 
 	if err := h.store.UpdateSpec(spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
@@ -245,7 +658,7 @@ func (h *Handler) DisableSpec(c *gin.Context) {
 	spec.UpdatedAt = time.Now()
 
 	if err := h.store.UpdateSpec(spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
@@ -254,6 +667,142 @@ func (h *Handler) DisableSpec(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Spec disabled"})
 }
 
+// ListGroups returns the groups specs are listed under
+func (h *Handler) ListGroups(c *gin.Context) {
+	specs, err := h.store.GetAllSpecs()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	byGroup := make(map[string]*models.GroupSummary)
+	for _, spec := range specs {
+		if spec.Group == "" {
+			continue
+		}
+		summary, ok := byGroup[spec.Group]
+		if !ok {
+			summary = &models.GroupSummary{Group: spec.Group}
+			byGroup[spec.Group] = summary
+		}
+		summary.SpecCount++
+		if spec.Enabled {
+			summary.EnabledCount++
+		}
+	}
+
+	groups := make([]models.GroupSummary, 0, len(byGroup))
+	for _, summary := range byGroup {
+		groups = append(groups, *summary)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Group < groups[j].Group
+	})
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// EnableGroup enables all specs of a group
+func (h *Handler) EnableGroup(c *gin.Context) {
+	h.setGroupEnabled(c, true)
+}
+
+// DisableGroup disables all specs of a group
+func (h *Handler) DisableGroup(c *gin.Context) {
+	h.setGroupEnabled(c, false)
+}
+
+// setGroupEnabled enables or disables all specs of the group in the URL and reloads routes once
+func (h *Handler) setGroupEnabled(c *gin.Context, enabled bool) {
+	specs, ok := h.groupSpecs(c)
+	if !ok {
+		return
+	}
+
+	changed := 0
+	for _, spec := range specs {
+		if spec.Enabled == enabled {
+			continue
+		}
+		spec.Enabled = enabled
+		spec.UpdatedAt = time.Now()
+		if err := h.store.UpdateSpec(spec); err != nil {
+			internalError(c, err)
+			return
+		}
+		changed++
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{"group": c.Param("group"), "specs": len(specs), "changed": changed})
+}
+
+// GetGroupStats returns the statistics of the specs of a group and their totals
+func (h *Handler) GetGroupStats(c *gin.Context) {
+	specs, ok := h.groupSpecs(c)
+	if !ok {
+		return
+	}
+
+	collector, _ := h.scopedStats(c)
+	c.JSON(http.StatusOK, collector.GetGroupStats(c.Param("group"), specs))
+}
+
+// groupSpecs returns the specs of the group in the URL sorted by name, writing a 404 when it has none
+func (h *Handler) groupSpecs(c *gin.Context) ([]*models.Spec, bool) {
+	specs, err := h.store.GetAllSpecs()
+	if err != nil {
+		internalError(c, err)
+		return nil, false
+	}
+
+	specs = specsInGroup(specs, c.Param("group"))
+	if len(specs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return nil, false
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs, true
+}
+
+// specsInGroup returns the specs listed under a group; an empty group selects ungrouped specs
+func specsInGroup(specs []*models.Spec, group string) []*models.Spec {
+	var result []*models.Spec
+	for _, spec := range specs {
+		if spec.Group == group {
+			result = append(result, spec)
+		}
+	}
+	return result
+}
+
+// specsWithLabels returns the specs matching all label selectors, each a key with an optional
+// value after : or =
+func specsWithLabels(specs []*models.Spec, selectors []string) []*models.Spec {
+	var result []*models.Spec
+	for _, spec := range specs {
+		matches := true
+		for _, selector := range selectors {
+			key, value, hasValue := strings.Cut(selector, ":")
+			if !hasValue {
+				key, value, hasValue = strings.Cut(selector, "=")
+			}
+			actual, ok := spec.Labels[strings.TrimSpace(key)]
+			if !ok || (hasValue && actual != strings.TrimSpace(value)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result = append(result, spec)
+		}
+	}
+	return result
+}
+
 // ToggleTracing toggles tracing for a spec
 func (h *Handler) ToggleTracing(c *gin.Context) {
 	id := c.Param("id")
@@ -277,7 +826,7 @@ func (h *Handler) ToggleTracing(c *gin.Context) {
 	spec.UpdatedAt = time.Now()
 
 	if err := h.store.UpdateSpec(spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
@@ -307,7 +856,7 @@ func (h *Handler) ToggleExampleFallback(c *gin.Context) {
 	spec.UpdatedAt = time.Now()
 
 	if err := h.store.UpdateSpec(spec); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, err)
 		return
 	}
 
@@ -317,303 +866,1997 @@ func (h *Handler) ToggleExampleFallback(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"useExampleFallback": spec.UseExampleFallback})
 }
 
-// ListOperations returns all operations for a spec
-func (h *Handler) ListOperations(c *gin.Context) {
-	specID := c.Param("id")
+// snippetNamePattern restricts snippet names to characters usable in {{include "name"}}
+var snippetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 
-	ops, err := h.store.GetOperationsBySpec(specID)
+// ListSnippets returns the named snippets of a spec
+func (h *Handler) ListSnippets(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	// Convert to summaries with response counts
-	summaries := make([]models.OperationSummary, len(ops))
-	for i, op := range ops {
-		responses, _ := h.store.GetResponseConfigsByOperation(op.ID)
-		summaries[i] = models.OperationSummary{
-			ID:                 op.ID,
-			SpecID:             op.SpecID,
-			Method:             op.Method,
-			Path:               op.Path,
-			FullPath:           op.FullPath,
-			OperationID:        op.OperationID,
-			Summary:            op.Summary,
-			ResponseCount:      len(responses),
-			HasExampleResponse: op.ExampleResponse != nil,
-		}
+	snippets := spec.Snippets
+	if snippets == nil {
+		snippets = make(map[string]string)
 	}
 
-	c.JSON(http.StatusOK, summaries)
+	c.JSON(http.StatusOK, snippets)
 }
 
-// GetOperation returns a single operation
-func (h *Handler) GetOperation(c *gin.Context) {
-	id := c.Param("id")
-
-	op, err := h.store.GetOperation(id)
+// SetSnippet creates or replaces a named snippet of a spec
+func (h *Handler) SetSnippet(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	// Get response configs
-	responses, _ := h.store.GetResponseConfigsByOperation(id)
-	op.Responses = make([]models.ResponseConfig, len(responses))
-	for i, resp := range responses {
-		op.Responses[i] = *resp
+	name := c.Param("name")
+	if !snippetNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Snippet names may only contain letters, digits, '_', '.' and '-'"})
+		return
 	}
 
-	c.JSON(http.StatusOK, op)
-}
+	var input models.SnippetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-// ListResponseConfigs returns all response configs for an operation
-func (h *Handler) ListResponseConfigs(c *gin.Context) {
-	opID := c.Param("id")
+	if spec.Snippets == nil {
+		spec.Snippets = make(map[string]string)
+	}
+	spec.Snippets[name] = input.Content
+	spec.UpdatedAt = time.Now()
 
-	configs, err := h.store.GetResponseConfigsByOperation(opID)
+	if err := h.store.UpdateSpec(spec); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{"name": name, "content": input.Content})
+}
+
+// DeleteSnippet removes a named snippet from a spec
+func (h *Handler) DeleteSnippet(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, configs)
+	name := c.Param("name")
+	if _, ok := spec.Snippets[name]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return
+	}
+
+	delete(spec.Snippets, name)
+	spec.UpdatedAt = time.Now()
+
+	if err := h.store.UpdateSpec(spec); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snippet deleted"})
 }
 
-// CreateResponseConfig creates a new response config
-func (h *Handler) CreateResponseConfig(c *gin.Context) {
-	opID := c.Param("id")
+// ListSpecFiles returns the files stored with a spec
+func (h *Handler) ListSpecFiles(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
 
-	// Verify operation exists
-	_, err := h.store.GetOperation(opID)
+	files, err := h.store.GetSpecFiles(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		internalError(c, err)
 		return
 	}
 
-	var input models.ResponseConfigInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, files)
+}
+
+// UploadSpecFile stores the request body as a file of a spec, replacing a file of the same name
+func (h *Handler) UploadSpecFile(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	// Generate ID
-	cfg := &models.ResponseConfig{
-		ID:          generateID(),
-		OperationID: opID,
-		Name:        input.Name,
-		Description: input.Description,
-		Priority:    input.Priority,
-		Conditions:  input.Conditions,
-		StatusCode:  input.StatusCode,
-		Headers:     input.Headers,
-		Body:        input.Body,
-		Delay:       input.Delay,
-		Enabled:     input.Enabled,
+	name := c.Param("name")
+	if !storage.ValidSpecFileName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File names may only contain letters, digits, '_', '.' and '-', and can't start with '.'"})
+		return
 	}
 
-	// Set defaults
-	if cfg.StatusCode == 0 {
-		cfg.StatusCode = 200
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if cfg.Headers == nil {
-		cfg.Headers = make(map[string]string)
+
+	if err := h.store.SaveSpecFile(id, name, data); err != nil {
+		internalError(c, err)
+		return
 	}
-	if cfg.Conditions == nil {
-		cfg.Conditions = make([]models.Condition, 0)
+
+	file, info, err := h.store.OpenSpecFile(id, name)
+	if err != nil {
+		internalError(c, err)
+		return
 	}
+	file.Close()
 
-	if err := h.store.CreateResponseConfig(cfg); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, info)
+}
+
+// DownloadSpecFile returns a file stored with a spec
+func (h *Handler) DownloadSpecFile(c *gin.Context) {
+	file, info, err := h.store.OpenSpecFile(c.Param("id"), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
+	defer file.Close()
 
-	c.JSON(http.StatusCreated, cfg)
+	http.ServeContent(c.Writer, c.Request, info.Name, info.UpdatedAt, file)
 }
 
-// GetResponseConfig returns a single response config
-func (h *Handler) GetResponseConfig(c *gin.Context) {
-	id := c.Param("id")
+// DeleteSpecFile deletes a file stored with a spec, unless response configs use it as body
+func (h *Handler) DeleteSpecFile(c *gin.Context) {
+	id, name := c.Param("id"), c.Param("name")
 
-	cfg, err := h.store.GetResponseConfig(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+	ops, _ := h.store.GetOperationsBySpec(id)
+	for _, op := range ops {
+		configs, _ := h.store.GetResponseConfigsByOperation(op.ID)
+		for _, cfg := range configs {
+			if cfg.BodyFile == name {
+				c.JSON(http.StatusConflict, gin.H{"error": "File is the body of response config " + strconv.Quote(cfg.Name)})
+				return
+			}
+		}
+	}
+
+	if err := h.store.DeleteSpecFile(id, name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, cfg)
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted"})
 }
 
-// UpdateResponseConfig updates a response config
-func (h *Handler) UpdateResponseConfig(c *gin.Context) {
+// ListVariables returns the shared variables of a spec
+func (h *Handler) ListVariables(c *gin.Context) {
 	id := c.Param("id")
 
-	cfg, err := h.store.GetResponseConfig(id)
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.proxyEngine.Variables().All(id))
+}
+
+// SetAsyncAPI attaches an AsyncAPI document to a spec, registering its message channels
+func (h *Handler) SetAsyncAPI(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	var update models.ResponseConfigUpdate
-	if err := c.ShouldBindJSON(&update); err != nil {
+	var input models.SpecContentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+		return
+	}
 
-	// Apply updates
-	if update.Name != nil {
-		cfg.Name = *update.Name
+	channels, err := h.parser.ParseAsyncAPI(input.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid AsyncAPI document: " + err.Error()})
+		return
 	}
-	if update.Description != nil {
-		cfg.Description = *update.Description
+
+	spec.AsyncAPI = input.Content
+	spec.Channels = channels
+	spec.UpdatedAt = time.Now()
+	if err := h.store.UpdateSpec(spec); err != nil {
+		internalError(c, err)
+		return
 	}
-	if update.Priority != nil {
-		cfg.Priority = *update.Priority
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// DeleteAsyncAPI removes the AsyncAPI document and message channels of a spec
+func (h *Handler) DeleteAsyncAPI(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
 	}
-	if update.Conditions != nil {
-		cfg.Conditions = *update.Conditions
+
+	spec.AsyncAPI = ""
+	spec.Channels = nil
+	spec.UpdatedAt = time.Now()
+	if err := h.store.UpdateSpec(spec); err != nil {
+		internalError(c, err)
+		return
 	}
-	if update.StatusCode != nil {
-		cfg.StatusCode = *update.StatusCode
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{"message": "AsyncAPI document removed"})
+}
+
+// ListChannels returns the message channels of a spec
+func (h *Handler) ListChannels(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
 	}
-	if update.Headers != nil {
-		cfg.Headers = *update.Headers
+
+	channels := spec.Channels
+	if channels == nil {
+		channels = []models.Channel{}
 	}
-	if update.Body != nil {
-		cfg.Body = *update.Body
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetState returns the collections of a stateful spec
+func (h *Handler) GetState(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
 	}
-	if update.Delay != nil {
-		cfg.Delay = *update.Delay
+
+	state := h.proxyEngine.State(id)
+	if state == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Spec is not stateful"})
+		return
 	}
-	if update.Enabled != nil {
-		cfg.Enabled = *update.Enabled
+	c.JSON(http.StatusOK, state)
+}
+
+// ResetState restores the collections of a stateful spec to their seed
+func (h *Handler) ResetState(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
 	}
 
-	if err := h.store.UpdateResponseConfig(cfg); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !h.proxyEngine.ResetState(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Spec is not stateful"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "State reset"})
+}
 
-	c.JSON(http.StatusOK, cfg)
+// GetContractViolations returns the requests that violated a spec's OpenAPI document
+func (h *Handler) GetContractViolations(c *gin.Context) {
+	spec, err := h.store.GetSpec(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+	c.JSON(http.StatusOK, h.proxyEngine.ContractReport(spec))
 }
 
-// DeleteResponseConfig deletes a response config
-func (h *Handler) DeleteResponseConfig(c *gin.Context) {
+// ClearContractViolations removes the contract violations recorded for a spec
+func (h *Handler) ClearContractViolations(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.store.DeleteResponseConfig(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Response config deleted"})
+	h.proxyEngine.ClearContractReport(id)
+	c.JSON(http.StatusOK, gin.H{"message": "Contract violations cleared"})
 }
 
-// UpdateResponsePriority updates the priority of a response config
-func (h *Handler) UpdateResponsePriority(c *gin.Context) {
+// SetVariable sets a shared variable of a spec
+func (h *Handler) SetVariable(c *gin.Context) {
 	id := c.Param("id")
 
-	cfg, err := h.store.GetResponseConfig(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
 	var input struct {
-		Priority int `json:"priority"`
+		Value string `json:"value"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	cfg.Priority = input.Priority
+	key := c.Param("key")
+	h.proxyEngine.Variables().Set(id, key, input.Value)
 
-	if err := h.store.UpdateResponseConfig(cfg); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"key": key, "value": input.Value})
+}
+
+// DeleteVariable removes a shared variable of a spec
+func (h *Handler) DeleteVariable(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.store.GetSpec(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, cfg)
-}
-
-// GetGlobalStats returns global statistics
-func (h *Handler) GetGlobalStats(c *gin.Context) {
-	specs, _ := h.store.GetEnabledSpecs()
-	ops, _ := h.store.GetAllOperations()
+	if !h.proxyEngine.Variables().Delete(id, c.Param("key")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variable not found"})
+		return
+	}
 
-	stats := h.statsCollector.GetGlobalStats(len(specs), len(ops))
-	c.JSON(http.StatusOK, stats)
+	c.JSON(http.StatusOK, gin.H{"message": "Variable deleted"})
 }
 
-// GetSpecStats returns statistics for a spec
-func (h *Handler) GetSpecStats(c *gin.Context) {
+// ClearVariables removes all shared variables of a spec
+func (h *Handler) ClearVariables(c *gin.Context) {
 	id := c.Param("id")
 
-	spec, err := h.store.GetSpec(id)
-	if err != nil {
+	if _, err := h.store.GetSpec(id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	stats := h.statsCollector.GetSpecStats(id, spec.Name)
-	c.JSON(http.StatusOK, stats)
+	h.proxyEngine.Variables().Clear(id)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variables cleared"})
 }
 
-// GetOperationStats returns statistics for an operation
-func (h *Handler) GetOperationStats(c *gin.Context) {
+// GenerateResponses creates a response config for each documented status code of every
+// operation in a spec. Status codes that already have a response config are skipped, so
+// the endpoint can be called again after the spec content changes.
+func (h *Handler) GenerateResponses(c *gin.Context) {
 	id := c.Param("id")
 
-	stats := h.statsCollector.GetOperationStats(id)
-	if stats == nil {
-		c.JSON(http.StatusOK, gin.H{"message": "No statistics available"})
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
-}
+	ops, _ := h.store.GetOperationsBySpec(id)
 
-// ResetStats resets all statistics
-func (h *Handler) ResetStats(c *gin.Context) {
-	h.statsCollector.Reset()
-	c.JSON(http.StatusOK, gin.H{"message": "Statistics reset"})
+	created, skipped, err := h.generateResponses(spec, ops)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OpenAPI spec: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created":   len(created),
+		"skipped":   skipped,
+		"responses": created,
+	})
 }
 
-// ListTraces returns traces
-func (h *Handler) ListTraces(c *gin.Context) {
-	filter := &models.TraceFilter{
-		Limit: 100, // Default limit
+// generateResponses creates response configs from the spec for the given operations
+// Returns the created configs and the number of status codes skipped because they already had one
+func (h *Handler) generateResponses(spec *models.Spec, ops []*models.Operation) ([]*models.ResponseConfig, int, error) {
+	examples, err := h.parser.ExtractResponseExamples(spec.Content, spec.ID)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Parse query params
-	if specID := c.Query("specId"); specID != "" {
-		filter.SpecID = specID
-	}
-	if opID := c.Query("operationId"); opID != "" {
-		filter.OperationID = opID
-	}
-	if method := c.Query("method"); method != "" {
-		filter.Method = method
+	created := make([]*models.ResponseConfig, 0)
+	skipped := 0
+	for _, op := range ops {
+		existing, _ := h.store.GetResponseConfigsByOperation(op.ID)
+		covered := make(map[int]bool, len(existing))
+		for _, cfg := range existing {
+			covered[cfg.StatusCode] = true
+		}
+		priority := nextPriority(existing)
+
+		// Only the first success response is enabled; the rest are ready to be
+		// given conditions or switched on from the UI
+		enableNext := len(existing) == 0
+		for _, example := range examples[op.ID] {
+			if covered[example.StatusCode] {
+				skipped++
+				continue
+			}
+
+			name := strconv.Itoa(example.StatusCode)
+			if example.Description != "" {
+				name += " " + example.Description
+			}
+
+			cfg := &models.ResponseConfig{
+				ID:          generateID(),
+				OperationID: op.ID,
+				Name:        name,
+				Description: "Generated from the OpenAPI spec",
+				Priority:    priority,
+				Conditions:  make([]models.Condition, 0),
+				StatusCode:  example.StatusCode,
+				Headers:     example.Headers,
+				Body:        example.Body,
+				Enabled:     enableNext && example.StatusCode < 300,
+			}
+			if cfg.Enabled {
+				enableNext = false
+			}
+
+			if err := h.store.CreateResponseConfig(cfg); err != nil {
+				return created, skipped, err
+			}
+			created = append(created, cfg)
+			priority++
+		}
 	}
 
-	traces := h.tracingService.GetTraces(filter)
-	c.JSON(http.StatusOK, traces)
+	return created, skipped, nil
 }
 
-// GetTrace returns a single trace
-func (h *Handler) GetTrace(c *gin.Context) {
-	id := c.Param("id")
+// ListOperations returns all operations for a spec
+func (h *Handler) ListOperations(c *gin.Context) {
+	specID := c.Param("id")
 
-	trace := h.tracingService.GetTrace(id)
-	if trace == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Trace not found"})
+	ops, err := h.store.GetOperationsBySpec(specID)
+	if err != nil {
+		internalError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, trace)
-}
+	// Optionally narrow down to operations with a given tag
+	if tag := c.Query("tag"); tag != "" {
+		ops = filterOperationsByTag(ops, tag)
+	}
 
-// ClearTraces clears all traces
+	// Convert to summaries with response counts
+	summaries := make([]models.OperationSummary, len(ops))
+	for i, op := range ops {
+		responses, _ := h.store.GetResponseConfigsByOperation(op.ID)
+		summaries[i] = toOperationSummary(op, len(responses))
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetOperation returns a single operation
+func (h *Handler) GetOperation(c *gin.Context) {
+	id := c.Param("id")
+
+	op, err := h.store.GetOperation(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	// Get response configs
+	responses, _ := h.store.GetResponseConfigsByOperation(id)
+	op.Responses = make([]models.ResponseConfig, len(responses))
+	for i, resp := range responses {
+		op.Responses[i] = *resp
+	}
+
+	c.JSON(http.StatusOK, op)
+}
+
+// CreateOperation manually adds an operation to a spec
+func (h *Handler) CreateOperation(c *gin.Context) {
+	specID := c.Param("id")
+
+	spec, err := h.store.GetSpec(specID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	var input models.OperationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, opPath, errMsg := normalizeOperationRoute(input.Method, input.Path)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	if existing := h.findOperation(specID, method, opPath); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation already exists", "id": existing.ID})
+		return
+	}
+
+	op := &models.Operation{
+		ID:          parser.GenerateOperationID(specID, method, opPath),
+		SpecID:      specID,
+		Method:      method,
+		Path:        opPath,
+		FullPath:    path.Join(spec.BasePath, opPath),
+		OperationID: input.OperationID,
+		Summary:     input.Summary,
+		Description: input.Description,
+		Tags:        input.Tags,
+		Custom:      true,
+	}
+	if op.OperationID == "" {
+		op.OperationID = parser.DefaultOperationID(method, opPath)
+	}
+
+	if err := h.store.CreateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusCreated, op)
+}
+
+// UpdateOperation modifies an operation of a spec
+// A new method or path gives the operation the ID derived from them, and its response configs
+// move along, so reimporting the spec content matches it
+func (h *Handler) UpdateOperation(c *gin.Context) {
+	specID := c.Param("id")
+
+	spec, err := h.store.GetSpec(specID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	op, err := h.store.GetOperation(c.Param("opId"))
+	if err != nil || op.SpecID != specID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	var update models.OperationUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, opPath := op.Method, op.Path
+	if update.Method != nil {
+		method = *update.Method
+	}
+	if update.Path != nil {
+		opPath = *update.Path
+	}
+	method, opPath, errMsg := normalizeOperationRoute(method, opPath)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if existing := h.findOperation(specID, method, opPath); existing != nil && existing.ID != op.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation already exists", "id": existing.ID})
+		return
+	}
+
+	// Work on a copy so a failed update leaves the stored operation untouched
+	updated := *op
+	updated.Method = method
+	updated.Path = opPath
+	updated.FullPath = path.Join(spec.BasePath, opPath)
+	if update.OperationID != nil {
+		updated.OperationID = *update.OperationID
+	}
+	if update.Summary != nil {
+		updated.Summary = *update.Summary
+	}
+	if update.Description != nil {
+		updated.Description = *update.Description
+	}
+	if update.Tags != nil {
+		updated.Tags = *update.Tags
+	}
+	if update.Timeout != nil {
+		if *update.Timeout < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must not be negative"})
+			return
+		}
+		updated.Timeout = *update.Timeout
+	}
+	updated.Custom = true
+	updated.ID = parser.GenerateOperationID(specID, method, opPath)
+
+	if updated.ID == op.ID {
+		err = h.store.UpdateOperation(&updated)
+	} else {
+		err = h.moveOperation(op.ID, &updated)
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, &updated)
+}
+
+// moveOperation stores an operation under its new ID, moving the response configs of the old one
+func (h *Handler) moveOperation(oldID string, op *models.Operation) error {
+	if err := h.store.CreateOperation(op); err != nil {
+		return err
+	}
+	responses, err := h.store.GetResponseConfigsByOperation(oldID)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		moved := *resp
+		moved.OperationID = op.ID
+		if err := h.store.UpdateResponseConfig(&moved); err != nil {
+			return err
+		}
+	}
+	return h.store.DeleteOperation(oldID)
+}
+
+// DeleteOperation removes an operation and its response configs from a spec
+func (h *Handler) DeleteOperation(c *gin.Context) {
+	specID := c.Param("id")
+
+	op, err := h.store.GetOperation(c.Param("opId"))
+	if err != nil || op.SpecID != specID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	h.store.DeleteResponseConfigsByOperation(op.ID)
+
+	if err := h.store.DeleteOperation(op.ID); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Operation deleted"})
+}
+
+// EnableOperation enables routing for an operation
+func (h *Handler) EnableOperation(c *gin.Context) {
+	h.setOperationDisabled(c, false)
+}
+
+// DisableOperation stops routing requests to an operation without disabling its spec
+func (h *Handler) DisableOperation(c *gin.Context) {
+	h.setOperationDisabled(c, true)
+}
+
+// SetRateLimit attaches a simulated rate limit to an operation, replacing any previous one
+// Request counts start over
+func (h *Handler) SetRateLimit(c *gin.Context) {
+	op, err := h.store.GetOperation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	var policy models.RateLimitPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errMsg := validateRateLimit(&policy); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	op.RateLimit = &policy
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ResetRateLimit(op.ID)
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, op)
+}
+
+// DeleteRateLimit removes the simulated rate limit of an operation
+func (h *Handler) DeleteRateLimit(c *gin.Context) {
+	op, err := h.store.GetOperation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	op.RateLimit = nil
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ResetRateLimit(op.ID)
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, op)
+}
+
+// SetForward makes an operation forward requests to the upstream of its spec, replacing any
+// previous forwarding policy
+func (h *Handler) SetForward(c *gin.Context) {
+	op, err := h.store.GetOperation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	var policy models.ForwardPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if policy.Mode != models.ForwardUnmatched && policy.Mode != models.ForwardAlways {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be unmatched or always"})
+		return
+	}
+	spec, err := h.store.GetSpec(op.SpecID)
+	if err != nil || spec.Upstream == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The spec has no upstream to forward to"})
+		return
+	}
+
+	op.Forward = &policy
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, op)
+}
+
+// DeleteForward stops an operation forwarding requests, serving only the mock
+func (h *Handler) DeleteForward(c *gin.Context) {
+	op, err := h.store.GetOperation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	op.Forward = nil
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, op)
+}
+
+// EnableEcho makes an operation answer with a JSON description of each request
+func (h *Handler) EnableEcho(c *gin.Context) {
+	h.setOperationEcho(c, true)
+}
+
+// DisableEcho makes an echo operation serve its response configs again
+func (h *Handler) DisableEcho(c *gin.Context) {
+	h.setOperationEcho(c, false)
+}
+
+// setOperationEcho turns echoing of an operation on or off
+func (h *Handler) setOperationEcho(c *gin.Context, echo bool) {
+	op, err := h.store.GetOperation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	op.Echo = echo
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, op)
+}
+
+// setOperationDisabled updates the disabled flag of an operation and reloads routes
+func (h *Handler) setOperationDisabled(c *gin.Context, disabled bool) {
+	id := c.Param("id")
+
+	op, err := h.store.GetOperation(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	op.Disabled = disabled
+
+	if err := h.store.UpdateOperation(op); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	if disabled {
+		c.JSON(http.StatusOK, gin.H{"message": "Operation disabled"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Operation enabled"})
+	}
+}
+
+// findOperation returns the operation of a spec with the given method and path, if any
+func (h *Handler) findOperation(specID, method, opPath string) *models.Operation {
+	ops, _ := h.store.GetOperationsBySpec(specID)
+	for _, op := range ops {
+		if op.Method == method && op.Path == opPath {
+			return op
+		}
+	}
+	return nil
+}
+
+// normalizeOperationRoute validates and normalizes an operation method and path
+// Returns an error message if the route is invalid
+func normalizeOperationRoute(method, opPath string) (string, string, string) {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	switch method {
+	case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS":
+	default:
+		return "", "", "method must be one of GET, POST, PUT, DELETE, PATCH, HEAD, OPTIONS"
+	}
+
+	opPath = strings.TrimSpace(opPath)
+	if !strings.HasPrefix(opPath, "/") {
+		return "", "", "path must start with /"
+	}
+
+	return method, opPath, ""
+}
+
+// ListTags returns the tags used by the operations of a spec
+func (h *Handler) ListTags(c *gin.Context) {
+	specID := c.Param("id")
+
+	if _, err := h.store.GetSpec(specID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	ops, _ := h.store.GetOperationsBySpec(specID)
+
+	counts := make(map[string]int)
+	for _, op := range ops {
+		for _, tag := range op.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]models.TagSummary, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, models.TagSummary{Tag: tag, OperationCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// EnableTag enables all operations of a spec carrying a tag
+func (h *Handler) EnableTag(c *gin.Context) {
+	h.updateTaggedOperations(c, func(op *models.Operation) { op.Disabled = false })
+}
+
+// DisableTag disables all operations of a spec carrying a tag
+func (h *Handler) DisableTag(c *gin.Context) {
+	h.updateTaggedOperations(c, func(op *models.Operation) { op.Disabled = true })
+}
+
+// ToggleTagTracing sets tracing for all operations of a spec carrying a tag
+// Without a body, tracing is enabled unless every tagged operation already has it
+func (h *Handler) ToggleTagTracing(c *gin.Context) {
+	ops, ok := h.taggedOperations(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		// Toggle if no body
+		input.Enabled = false
+		for _, op := range ops {
+			if !op.Tracing {
+				input.Enabled = true
+				break
+			}
+		}
+	}
+
+	h.applyToOperations(c, ops, func(op *models.Operation) { op.Tracing = input.Enabled })
+}
+
+// GenerateTagResponses generates response configs for all operations of a spec carrying a tag
+func (h *Handler) GenerateTagResponses(c *gin.Context) {
+	ops, ok := h.taggedOperations(c)
+	if !ok {
+		return
+	}
+
+	spec, err := h.store.GetSpec(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	created, skipped, err := h.generateResponses(spec, ops)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OpenAPI spec: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created":   len(created),
+		"skipped":   skipped,
+		"responses": created,
+	})
+}
+
+// updateTaggedOperations applies a change to every operation of a spec carrying the tag in the URL
+func (h *Handler) updateTaggedOperations(c *gin.Context, apply func(op *models.Operation)) {
+	ops, ok := h.taggedOperations(c)
+	if !ok {
+		return
+	}
+
+	h.applyToOperations(c, ops, apply)
+}
+
+// applyToOperations applies a change to operations, persists them and reloads routes
+func (h *Handler) applyToOperations(c *gin.Context, ops []*models.Operation, apply func(op *models.Operation)) {
+	for _, op := range ops {
+		apply(op)
+		if err := h.store.UpdateOperation(op); err != nil {
+			internalError(c, err)
+			return
+		}
+	}
+
+	h.proxyEngine.ReloadRoutes()
+
+	c.JSON(http.StatusOK, gin.H{
+		"tag":            c.Param("tag"),
+		"operationCount": len(ops),
+	})
+}
+
+// taggedOperations returns the operations of the spec in the URL that carry the tag in the URL
+// Writes a 404 response and returns false if the spec or tag doesn't exist
+func (h *Handler) taggedOperations(c *gin.Context) ([]*models.Operation, bool) {
+	specID := c.Param("id")
+
+	if _, err := h.store.GetSpec(specID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return nil, false
+	}
+
+	ops, _ := h.store.GetOperationsBySpec(specID)
+	ops = filterOperationsByTag(ops, c.Param("tag"))
+	if len(ops) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No operations with this tag"})
+		return nil, false
+	}
+
+	return ops, true
+}
+
+// filterOperationsByTag returns the operations carrying the given tag
+func filterOperationsByTag(ops []*models.Operation, tag string) []*models.Operation {
+	result := make([]*models.Operation, 0)
+	for _, op := range ops {
+		for _, t := range op.Tags {
+			if t == tag {
+				result = append(result, op)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// ListResponseConfigs returns all response configs for an operation
+func (h *Handler) ListResponseConfigs(c *gin.Context) {
+	opID := c.Param("id")
+
+	configs, err := h.store.GetResponseConfigsByOperation(opID)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, configs)
+}
+
+// CreateResponseConfig creates a new response config
+func (h *Handler) CreateResponseConfig(c *gin.Context) {
+	opID := c.Param("id")
+
+	// Verify operation exists
+	op, err := h.store.GetOperation(opID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	var input models.ResponseConfigInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := condition.ValidateAll(input.Conditions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conditions: " + err.Error()})
+		return
+	}
+	if input.Pagination != nil {
+		if errMsg := validatePagination(input.Pagination); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if errMsg := validateEvents(input.Events); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if input.SOAPFault != nil {
+		if errMsg := validateSOAPFault(input.SOAPFault); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if input.Fault != nil {
+		if errMsg := validateFault(input.Fault); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if errMsg := h.validateBodyFile(op.SpecID, input.BodyFile); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	cfg := newResponseConfig(opID, input)
+
+	if err := h.store.CreateResponseConfig(cfg); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cfg)
+}
+
+// ExportResponseConfigs exports the response configs of an operation as a YAML or JSON document
+func (h *Handler) ExportResponseConfigs(c *gin.Context) {
+	opID := c.Param("id")
+
+	op, err := h.store.GetOperation(opID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	configs, _ := h.store.GetResponseConfigsByOperation(opID)
+
+	export := models.ResponseConfigExport{
+		Version: models.ResponseConfigExportVersion,
+		Operation: models.ExportedOperation{
+			Method:      op.Method,
+			Path:        op.Path,
+			OperationID: op.OperationID,
+		},
+		Responses: make([]models.ResponseConfigInput, len(configs)),
+	}
+	for i, cfg := range configs {
+		export.Responses[i] = cfg.ToInput()
+	}
+
+	filename := op.OperationID
+	if filename == "" {
+		filename = op.ID
+	}
+
+	switch c.DefaultQuery("format", "yaml") {
+	case "json":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.responses.json"`)
+		c.JSON(http.StatusOK, export)
+	case "yaml":
+		data, err := yaml.Marshal(export)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.responses.yaml"`)
+		c.Data(http.StatusOK, "application/x-yaml", data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+	}
+}
+
+// ImportResponseConfigs imports response configs exported from another operation
+// The body may be YAML or JSON. With ?mode=replace the existing configs are removed first,
+// otherwise the imported configs are placed after the existing ones.
+func (h *Handler) ImportResponseConfigs(c *gin.Context) {
+	opID := c.Param("id")
+
+	op, err := h.store.GetOperation(opID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "append")
+	if mode != "append" && mode != "replace" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be append or replace"})
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	var doc models.ResponseConfigExport
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export document: " + err.Error()})
+		return
+	}
+	if doc.Version > models.ResponseConfigExportVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported export version: " + strconv.Itoa(doc.Version)})
+		return
+	}
+	for _, input := range doc.Responses {
+		if err := condition.ValidateAll(input.Conditions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conditions in " + strconv.Quote(input.Name) + ": " + err.Error()})
+			return
+		}
+		if input.Pagination != nil {
+			if errMsg := validatePagination(input.Pagination); errMsg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination in " + strconv.Quote(input.Name) + ": " + errMsg})
+				return
+			}
+		}
+		if errMsg := validateEvents(input.Events); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid events in " + strconv.Quote(input.Name) + ": " + errMsg})
+			return
+		}
+		if input.SOAPFault != nil {
+			if errMsg := validateSOAPFault(input.SOAPFault); errMsg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SOAP fault in " + strconv.Quote(input.Name) + ": " + errMsg})
+				return
+			}
+		}
+		if input.Fault != nil {
+			if errMsg := validateFault(input.Fault); errMsg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fault in " + strconv.Quote(input.Name) + ": " + errMsg})
+				return
+			}
+		}
+		if errMsg := h.validateBodyFile(op.SpecID, input.BodyFile); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid body file in " + strconv.Quote(input.Name) + ": " + errMsg})
+			return
+		}
+	}
+
+	offset := 0
+	if mode == "replace" {
+		h.store.DeleteResponseConfigsByOperation(opID)
+	} else {
+		existing, _ := h.store.GetResponseConfigsByOperation(opID)
+		offset = nextPriority(existing)
+	}
+
+	created := make([]*models.ResponseConfig, 0, len(doc.Responses))
+	for _, input := range doc.Responses {
+		cfg := newResponseConfig(opID, input)
+		cfg.Priority += offset
+
+		if err := h.store.CreateResponseConfig(cfg); err != nil {
+			internalError(c, err)
+			return
+		}
+		created = append(created, cfg)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"imported":  len(created),
+		"responses": created,
+	})
+}
+
+// ImportWireMock converts WireMock stub mappings into response configs of a spec's operations
+// Stubs are matched to operations by method and path, and literal values of path parameters
+// become conditions. A stub for a path without an operation gets a new custom operation;
+// stubs matching URLs by regular expression are skipped.
+func (h *Handler) ImportWireMock(c *gin.Context) {
+	specID := c.Param("id")
+
+	spec, err := h.store.GetSpec(specID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stubs, err := wiremock.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid WireMock mappings: " + err.Error()})
+		return
+	}
+
+	ops, _ := h.store.GetOperationsBySpec(specID)
+	var newOps []*models.Operation
+	type plannedConfig struct {
+		op    *models.Operation
+		input models.ResponseConfigInput
+	}
+	var planned []plannedConfig
+	skipped := make([]gin.H, 0)
+	warnings := make([]gin.H, 0)
+
+	for _, stub := range stubs {
+		if stub.Pattern != "" {
+			skipped = append(skipped, gin.H{"name": stub.Name, "reason": "URL patterns can't be mapped onto operations"})
+			continue
+		}
+
+		stubPath := stub.Path
+		if spec.BasePath != "" && spec.BasePath != "/" {
+			if rest, ok := strings.CutPrefix(stubPath, strings.TrimSuffix(spec.BasePath, "/")); ok && strings.HasPrefix(rest, "/") {
+				stubPath = rest
+			}
+		}
+
+		targets := matchStubOperations(ops, stub.Method, stubPath)
+		if len(targets) == 0 {
+			method, opPath, errMsg := normalizeOperationRoute(stub.Method, stubPath)
+			if stub.Method == "ANY" {
+				errMsg = "No operation matches " + stubPath + " and ANY can't create one"
+			}
+			if errMsg != "" {
+				skipped = append(skipped, gin.H{"name": stub.Name, "reason": errMsg})
+				continue
+			}
+			op := &models.Operation{
+				ID:          parser.GenerateOperationID(specID, method, opPath),
+				SpecID:      specID,
+				Method:      method,
+				Path:        opPath,
+				FullPath:    path.Join(spec.BasePath, opPath),
+				OperationID: parser.DefaultOperationID(method, opPath),
+				Summary:     "Imported from WireMock",
+				Custom:      true,
+			}
+			ops = append(ops, op)
+			newOps = append(newOps, op)
+			targets = []stubTarget{{op: op}}
+		}
+
+		for _, target := range targets {
+			input := stub.Response
+			input.Conditions = target.conditions(input.Conditions)
+			if err := condition.ValidateAll(input.Conditions); err != nil {
+				skipped = append(skipped, gin.H{"name": stub.Name, "reason": "Invalid conditions: " + err.Error()})
+				continue
+			}
+			if errMsg := h.validateBodyFile(specID, input.BodyFile); errMsg != "" {
+				skipped = append(skipped, gin.H{"name": stub.Name, "reason": errMsg})
+				continue
+			}
+			planned = append(planned, plannedConfig{op: target.op, input: input})
+		}
+		for _, warning := range stub.Warnings {
+			warnings = append(warnings, gin.H{"name": stub.Name, "warning": warning})
+		}
+	}
+
+	for _, op := range newOps {
+		if err := h.store.CreateOperation(op); err != nil {
+			internalError(c, err)
+			return
+		}
+	}
+
+	// Stubs come in WireMock's match order, so each operation's configs follow it after any
+	// existing ones
+	priorities := make(map[string]int)
+	created := make([]*models.ResponseConfig, 0, len(planned))
+	for _, p := range planned {
+		priority, ok := priorities[p.op.ID]
+		if !ok {
+			existing, _ := h.store.GetResponseConfigsByOperation(p.op.ID)
+			priority = nextPriority(existing)
+		}
+		priorities[p.op.ID] = priority + 1
+
+		cfg := newResponseConfig(p.op.ID, p.input)
+		cfg.Priority = priority
+		if err := h.store.CreateResponseConfig(cfg); err != nil {
+			internalError(c, err)
+			return
+		}
+		created = append(created, cfg)
+	}
+
+	if len(newOps) > 0 {
+		h.proxyEngine.ReloadRoutes()
+	}
+
+	if newOps == nil {
+		newOps = make([]*models.Operation, 0)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"imported":   len(created),
+		"responses":  created,
+		"operations": newOps,
+		"skipped":    skipped,
+		"warnings":   warnings,
+	})
+}
+
+// stubTarget is an operation a WireMock stub applies to
+type stubTarget struct {
+	op     *models.Operation
+	params map[string]string // Values of the operation's path parameters given by the stub path
+}
+
+// conditions adds the stub's path parameter values to its conditions
+// Values in braces name a parameter of a urlPathTemplate, whose conditions are renamed
+func (t stubTarget) conditions(conditions []models.Condition) []models.Condition {
+	renames := make(map[string]string)
+	result := make([]models.Condition, 0, len(conditions)+len(t.params))
+	for _, name := range sortedKeys(t.params) {
+		value := t.params[name]
+		if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+			renames[strings.Trim(value, "{}")] = name
+			continue
+		}
+		result = append(result, models.Condition{Source: models.SourcePath, Key: name, Operator: models.OpEquals, Value: value})
+	}
+	for _, cond := range conditions {
+		if cond.Source == models.SourcePath {
+			if name, ok := renames[cond.Key]; ok {
+				cond.Key = name
+			}
+		}
+		result = append(result, cond)
+	}
+	return result
+}
+
+// matchStubOperations finds the operations a stub with the given method and path applies to
+// For each method the operation with the fewest path parameters wins, so /pets/mine prefers an
+// operation for /pets/mine over one for /pets/{id}
+func matchStubOperations(ops []*models.Operation, method, stubPath string) []stubTarget {
+	best := make(map[string]stubTarget)
+	var methods []string
+	for _, op := range ops {
+		if method != "ANY" && op.Method != method {
+			continue
+		}
+		params, ok := matchOperationPath(op.Path, stubPath)
+		if !ok {
+			continue
+		}
+		current, seen := best[op.Method]
+		if !seen {
+			methods = append(methods, op.Method)
+		}
+		if !seen || len(params) < len(current.params) {
+			best[op.Method] = stubTarget{op: op, params: params}
+		}
+	}
+
+	sort.Strings(methods)
+	targets := make([]stubTarget, 0, len(methods))
+	for _, m := range methods {
+		targets = append(targets, best[m])
+	}
+	return targets
+}
+
+// matchOperationPath matches a path against the path template of an operation, returning the
+// segments taken by each of its parameters
+func matchOperationPath(template, requestPath string) (map[string]string, bool) {
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// GetResponseConfig returns a single response config
+func (h *Handler) GetResponseConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	cfg, err := h.store.GetResponseConfig(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// UpdateResponseConfig updates a response config
+func (h *Handler) UpdateResponseConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	cfg, err := h.store.GetResponseConfig(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	var update models.ResponseConfigUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate everything before changing anything
+	if update.Conditions != nil {
+		if err := condition.ValidateAll(*update.Conditions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conditions: " + err.Error()})
+			return
+		}
+	}
+	if update.BodyFile != nil {
+		// An empty name removes the body file
+		if op, err := h.store.GetOperation(cfg.OperationID); err == nil {
+			if errMsg := h.validateBodyFile(op.SpecID, *update.BodyFile); errMsg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+				return
+			}
+		}
+	}
+	if update.Pagination != nil {
+		if errMsg := validatePagination(update.Pagination); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if update.Events != nil {
+		if errMsg := validateEvents(*update.Events); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if update.SOAPFault != nil {
+		if errMsg := validateSOAPFault(update.SOAPFault); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+	if update.Fault != nil && *update.Fault != (models.ResponseFault{}) {
+		if errMsg := validateFault(update.Fault); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+
+	// Work on a copy so a failed update leaves the stored config untouched
+	updated := *cfg
+	if update.Name != nil {
+		updated.Name = *update.Name
+	}
+	if update.Description != nil {
+		updated.Description = *update.Description
+	}
+	if update.Priority != nil {
+		updated.Priority = *update.Priority
+	}
+	if update.Conditions != nil {
+		updated.Conditions = *update.Conditions
+	}
+	if update.StatusCode != nil {
+		updated.StatusCode = *update.StatusCode
+	}
+	if update.Headers != nil {
+		updated.Headers = *update.Headers
+	}
+	if update.Body != nil {
+		updated.Body = *update.Body
+	}
+	if update.BodyFile != nil {
+		updated.BodyFile = *update.BodyFile
+	}
+	if update.NegateConditions != nil {
+		updated.NegateConditions = *update.NegateConditions
+	}
+	if update.Delay != nil {
+		updated.Delay = *update.Delay
+	}
+	if update.Seed != nil {
+		updated.Seed = *update.Seed
+	}
+	if update.Enabled != nil {
+		updated.Enabled = *update.Enabled
+	}
+	if update.Pagination != nil {
+		// An empty object removes the pagination
+		updated.Pagination = update.Pagination
+		if *update.Pagination == (models.Pagination{}) {
+			updated.Pagination = nil
+		}
+	}
+	if update.Events != nil {
+		// An empty list removes the events
+		updated.Events = *update.Events
+		if len(updated.Events) == 0 {
+			updated.Events = nil
+		}
+	}
+	if update.SOAPFault != nil {
+		// An empty object removes the fault
+		updated.SOAPFault = update.SOAPFault
+		if *update.SOAPFault == (models.SOAPFault{}) {
+			updated.SOAPFault = nil
+		}
+	}
+	if update.Fault != nil {
+		// An empty object removes the fault
+		updated.Fault = update.Fault
+		if *update.Fault == (models.ResponseFault{}) {
+			updated.Fault = nil
+		}
+	}
+
+	if err := h.store.UpdateResponseConfig(&updated); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &updated)
+}
+
+// DeleteResponseConfig deletes a response config
+func (h *Handler) DeleteResponseConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.store.DeleteResponseConfig(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Response config deleted"})
+}
+
+// EnableResponseConfig enables a response config
+func (h *Handler) EnableResponseConfig(c *gin.Context) {
+	h.setResponseConfigEnabled(c, true)
+}
+
+// DisableResponseConfig disables a response config without deleting it
+func (h *Handler) DisableResponseConfig(c *gin.Context) {
+	h.setResponseConfigEnabled(c, false)
+}
+
+// setResponseConfigEnabled updates the enabled flag of a single response config
+func (h *Handler) setResponseConfigEnabled(c *gin.Context, enabled bool) {
+	cfg, err := h.store.GetResponseConfig(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	cfg.Enabled = enabled
+
+	if err := h.store.UpdateResponseConfig(cfg); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// EnableOperationResponses enables the response configs of an operation
+// An optional body {"ids": [...]} limits the change to the listed configs
+func (h *Handler) EnableOperationResponses(c *gin.Context) {
+	h.setOperationResponsesEnabled(c, true)
+}
+
+// DisableOperationResponses disables the response configs of an operation
+// An optional body {"ids": [...]} limits the change to the listed configs
+func (h *Handler) DisableOperationResponses(c *gin.Context) {
+	h.setOperationResponsesEnabled(c, false)
+}
+
+// setOperationResponsesEnabled updates the enabled flag of some or all response configs of an operation
+func (h *Handler) setOperationResponsesEnabled(c *gin.Context, enabled bool) {
+	opID := c.Param("id")
+
+	if _, err := h.store.GetOperation(opID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	var input struct {
+		IDs []string `json:"ids"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	selected := make(map[string]bool, len(input.IDs))
+	for _, id := range input.IDs {
+		selected[id] = true
+	}
+
+	configs, _ := h.store.GetResponseConfigsByOperation(opID)
+
+	// Reject unknown IDs before changing anything
+	unknown := make(map[string]bool, len(selected))
+	for id := range selected {
+		unknown[id] = true
+	}
+	for _, cfg := range configs {
+		delete(unknown, cfg.ID)
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Response configs not found on this operation", "ids": sortedKeys(unknown)})
+		return
+	}
+
+	updated := 0
+	for _, cfg := range configs {
+		if (len(selected) > 0 && !selected[cfg.ID]) || cfg.Enabled == enabled {
+			continue
+		}
+
+		cfg.Enabled = enabled
+		if err := h.store.UpdateResponseConfig(cfg); err != nil {
+			internalError(c, err)
+			return
+		}
+		updated++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"operationId": opID, "enabled": enabled, "updated": updated})
+}
+
+// sortedKeys returns the keys of a set or map in sorted order
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CloneResponseConfig duplicates a response config, optionally onto another operation
+// The copy is placed after the existing configs of the target operation
+func (h *Handler) CloneResponseConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	source, err := h.store.GetResponseConfig(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	var input struct {
+		OperationID string `json:"operationId"`
+		Name        string `json:"name"`
+	}
+	// The body is optional; without one the config is cloned onto its own operation
+	c.ShouldBindJSON(&input)
+
+	targetOpID := source.OperationID
+	if input.OperationID != "" {
+		targetOpID = input.OperationID
+	}
+	if _, err := h.store.GetOperation(targetOpID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	existing, _ := h.store.GetResponseConfigsByOperation(targetOpID)
+
+	clone := *source
+	clone.ID = generateID()
+	clone.OperationID = targetOpID
+	clone.Priority = nextPriority(existing)
+	clone.Name = source.Name + " (copy)"
+	if input.Name != "" {
+		clone.Name = input.Name
+	}
+
+	// Copy conditions and headers so edits to the clone don't affect the source
+	clone.Conditions = append(make([]models.Condition, 0, len(source.Conditions)), source.Conditions...)
+	clone.Headers = make(map[string]string, len(source.Headers))
+	for k, v := range source.Headers {
+		clone.Headers[k] = v
+	}
+
+	if err := h.store.CreateResponseConfig(&clone); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, &clone)
+}
+
+// UpdateResponsePriority updates the priority of a response config
+func (h *Handler) UpdateResponsePriority(c *gin.Context) {
+	id := c.Param("id")
+
+	cfg, err := h.store.GetResponseConfig(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response config not found"})
+		return
+	}
+
+	var input struct {
+		Priority int `json:"priority"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg.Priority = input.Priority
+
+	if err := h.store.UpdateResponseConfig(cfg); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// scopedStats returns the statistics to report: merged across all nodes in cluster mode,
+// unless ?scope=node asks for this node only, along with the cluster nodes included
+func (h *Handler) scopedStats(c *gin.Context) (*stats.Collector, []string) {
+	if h.cluster == nil || c.Query("scope") == "node" {
+		return h.statsCollector, nil
+	}
+	return h.cluster.ClusterStats()
+}
+
+// GetGlobalStats returns global statistics
+func (h *Handler) GetGlobalStats(c *gin.Context) {
+	specs, _ := h.store.GetEnabledSpecs()
+	ops, _ := h.store.GetAllOperations()
+
+	collector, nodes := h.scopedStats(c)
+	stats := collector.GetGlobalStats(len(specs), len(ops))
+	stats.Nodes = nodes
+	stats.Alerts = h.proxyEngine.Alerts().Alerts("")
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetStatsSnapshot returns this node's raw statistics counters, which can be merged with other nodes'
+func (h *Handler) GetStatsSnapshot(c *gin.Context) {
+	snap := h.statsCollector.Snapshot()
+	if h.cluster != nil {
+		snap.NodeID = h.cluster.ID()
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+// StreamStats pushes the statistics of this node as Server-Sent Events: a stats event every
+// ?interval= seconds (default 2) with the totals and what changed since the previous one
+func (h *Handler) StreamStats(c *gin.Context) {
+	interval := 2 * time.Second
+	if seconds, err := strconv.Atoi(c.Query("interval")); err == nil && seconds > 0 {
+		interval = time.Duration(min(seconds, 60)) * time.Second
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(c.Writer)
+	rc.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	stream := h.statsCollector.NewStream()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := json.Marshal(stream.Next())
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: stats\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.streamsDone:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetSpecStats returns statistics for a spec
+func (h *Handler) GetSpecStats(c *gin.Context) {
+	id := c.Param("id")
+
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	collector, _ := h.scopedStats(c)
+	stats := collector.GetSpecStats(id, spec.Name)
+	stats.Alerts = h.proxyEngine.Alerts().Alerts(id)
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetCoverage reports which operations of a spec received requests and which response
+// configs never matched one
+func (h *Handler) GetCoverage(c *gin.Context) {
+	id := c.Param("id")
+
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	ops, err := h.store.GetOperationsBySpec(id)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+
+	configs := make(map[string][]*models.ResponseConfig, len(ops))
+	for _, op := range ops {
+		configs[op.ID], _ = h.store.GetResponseConfigsByOperation(op.ID)
+	}
+
+	collector, _ := h.scopedStats(c)
+	c.JSON(http.StatusOK, collector.GetCoverage(spec, ops, configs))
+}
+
+// ListAlerts returns the alerts currently firing on this node
+func (h *Handler) ListAlerts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.proxyEngine.Alerts().Alerts(c.Query("specId")))
+}
+
+// GetOperationStats returns statistics for an operation
+func (h *Handler) GetOperationStats(c *gin.Context) {
+	id := c.Param("id")
+
+	collector, _ := h.scopedStats(c)
+	stats := collector.GetOperationStats(id)
+	if stats == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "No statistics available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ResetStats resets all statistics
+// In cluster mode only this node's statistics are reset
+func (h *Handler) ResetStats(c *gin.Context) {
+	h.statsCollector.Reset()
+	c.JSON(http.StatusOK, gin.H{"message": "Statistics reset"})
+}
+
+// ListTraces returns traces
+func (h *Handler) ListTraces(c *gin.Context) {
+	filter := &models.TraceFilter{
+		Limit: 100, // Default limit
+	}
+
+	// Parse query params
+	if specID := c.Query("specId"); specID != "" {
+		filter.SpecID = specID
+	}
+	if opID := c.Query("operationId"); opID != "" {
+		filter.OperationID = opID
+	}
+	if method := c.Query("method"); method != "" {
+		filter.Method = method
+	}
+	if status := c.Query("status"); status != "" {
+		if err := tracing.ParseStatus(status, filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+
+	traces := h.tracingService.GetTraces(filter)
+	c.JSON(http.StatusOK, traces)
+}
+
+// GetTrace returns a single trace
+func (h *Handler) GetTrace(c *gin.Context) {
+	id := c.Param("id")
+
+	trace := h.tracingService.GetTrace(id)
+	if trace == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trace not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
+
+// ClearTraces clears all traces
 func (h *Handler) ClearTraces(c *gin.Context) {
 	specID := c.Query("specId")
 	if specID != "" {
@@ -621,7 +2864,230 @@ func (h *Handler) ClearTraces(c *gin.Context) {
 	} else {
 		h.tracingService.ClearTraces()
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Traces cleared"})
+	c.JSON(http.StatusOK, gin.H{"message": "Traces cleared"})
+}
+
+// Purge removes the traces, trace bodies or statistics captured, all of them or only those
+// captured in a time range. When traces are shared with other nodes, they are purged as well
+func (h *Handler) Purge(c *gin.Context) {
+	var input models.PurgeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validatePurge(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := h.purge(&input)
+	if h.purges != nil {
+		if err := h.purges.BroadcastPurge(&input); err != nil {
+			result.BroadcastError = err.Error()
+		} else {
+			result.Broadcast = true
+		}
+	}
+
+	logging.FromContext(c.Request.Context()).Info("purged captured data", "scopes", result.Scopes, "from", input.From, "to", input.To,
+		"traces", result.Traces, "bodies", result.Bodies, "errors", result.Errors, "statsReset", result.StatsReset,
+		"broadcast", result.Broadcast, "broadcastError", result.BroadcastError)
+	c.JSON(http.StatusOK, result)
+}
+
+// validatePurge checks the scopes and time range of a purge
+func validatePurge(input *models.PurgeRequest) error {
+	if len(input.Scopes) == 0 {
+		return errors.New("At least one scope is required")
+	}
+	for _, scope := range input.Scopes {
+		switch scope {
+		case models.PurgeTraces, models.PurgeBodies, models.PurgeStats, models.PurgeAll:
+		default:
+			return errors.New("Invalid scope " + strconv.Quote(scope) + ": use traces, bodies, stats or all")
+		}
+	}
+	if input.From != nil && input.To != nil && input.From.After(*input.To) {
+		return errors.New("from must not be after to")
+	}
+	return nil
+}
+
+// purge removes the data of a validated purge captured on this node
+func (h *Handler) purge(input *models.PurgeRequest) models.PurgeResult {
+	scopes := make(map[string]bool)
+	for _, scope := range input.Scopes {
+		if scope == models.PurgeAll {
+			scopes[models.PurgeTraces], scopes[models.PurgeStats] = true, true
+			continue
+		}
+		scopes[scope] = true
+	}
+	var from, to time.Time
+	if input.From != nil {
+		from = *input.From
+	}
+	if input.To != nil {
+		to = *input.To
+	}
+
+	result := models.PurgeResult{From: input.From, To: input.To, Scopes: make([]string, 0, len(scopes))}
+	// Removing whole traces leaves no bodies to remove
+	if scopes[models.PurgeTraces] {
+		result.Traces = h.tracingService.PurgeTraces(from, to)
+		result.Scopes = append(result.Scopes, models.PurgeTraces)
+	} else if scopes[models.PurgeBodies] {
+		result.Bodies = h.tracingService.PurgeBodies(from, to)
+		result.Scopes = append(result.Scopes, models.PurgeBodies)
+	}
+	if scopes[models.PurgeStats] {
+		result.Errors, result.HoursPurged = h.statsCollector.Purge(from, to)
+		result.StatsReset = from.IsZero() && to.IsZero()
+		result.Scopes = append(result.Scopes, models.PurgeStats)
+	}
+	return result
+}
+
+// Search finds specs, operations and response configs whose names, paths, summaries or bodies
+// contain the ?q= text, ignoring case. ?type= limits the hits to one type
+func (h *Handler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+	hitType := c.Query("type")
+	switch hitType {
+	case "", models.SearchSpec, models.SearchOperation, models.SearchResponse:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of spec, operation, response"})
+		return
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = min(l, 500)
+	}
+
+	specs, err := h.store.GetAllSpecs()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	var specHits, opHits, responseHits []models.SearchHit
+	for _, spec := range specs {
+		if field, snippet := searchFields(pattern, "name", spec.Name, "description", spec.Description); field != "" {
+			specHits = append(specHits, models.SearchHit{
+				Type:    models.SearchSpec,
+				ID:      spec.ID,
+				SpecID:  spec.ID,
+				Title:   spec.Name,
+				Field:   field,
+				Snippet: snippet,
+				Link:    "/_api/specs/" + spec.ID,
+				UILink:  "/_ui/specs/" + spec.ID,
+			})
+		}
+		if hitType == models.SearchSpec {
+			continue
+		}
+
+		ops, _ := h.store.GetOperationsBySpec(spec.ID)
+		sort.Slice(ops, func(i, j int) bool {
+			if ops[i].Path != ops[j].Path {
+				return ops[i].Path < ops[j].Path
+			}
+			return ops[i].Method < ops[j].Method
+		})
+		for _, op := range ops {
+			if field, snippet := searchFields(pattern, "path", op.Path, "summary", op.Summary, "operationId", op.OperationID); field != "" {
+				opHits = append(opHits, models.SearchHit{
+					Type:    models.SearchOperation,
+					ID:      op.ID,
+					SpecID:  spec.ID,
+					Title:   op.Method + " " + op.Path,
+					Field:   field,
+					Snippet: snippet,
+					Link:    "/_api/operations/" + op.ID,
+					UILink:  "/_ui/operations/" + op.ID,
+				})
+			}
+			if hitType == models.SearchOperation {
+				continue
+			}
+
+			configs, _ := h.store.GetResponseConfigsByOperation(op.ID)
+			for _, cfg := range configs {
+				if field, snippet := searchFields(pattern, "name", cfg.Name, "body", cfg.Body); field != "" {
+					responseHits = append(responseHits, models.SearchHit{
+						Type:        models.SearchResponse,
+						ID:          cfg.ID,
+						SpecID:      spec.ID,
+						OperationID: op.ID,
+						Title:       cfg.Name,
+						Field:       field,
+						Snippet:     snippet,
+						Link:        "/_api/responses/" + cfg.ID,
+						UILink:      "/_ui/operations/" + op.ID,
+					})
+				}
+			}
+		}
+	}
+
+	var hits []models.SearchHit
+	switch hitType {
+	case models.SearchSpec:
+		hits = specHits
+	case models.SearchOperation:
+		hits = opHits
+	case models.SearchResponse:
+		hits = responseHits
+	default:
+		hits = append(append(specHits, opHits...), responseHits...)
+	}
+
+	result := models.SearchResult{Query: query, Hits: make([]models.SearchHit, 0, min(len(hits), limit))}
+	if len(hits) > limit {
+		hits, result.Truncated = hits[:limit], true
+	}
+	result.Hits = append(result.Hits, hits...)
+	c.JSON(http.StatusOK, result)
+}
+
+// searchFields returns the name of the first field matching a pattern and the text around the
+// match, given alternating field names and values
+func searchFields(pattern *regexp.Regexp, fields ...string) (string, string) {
+	const context = 40 // Bytes of text kept on each side of the match
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fields[i+1]
+		loc := pattern.FindStringIndex(value)
+		if loc == nil {
+			continue
+		}
+
+		start, end := max(loc[0]-context, 0), min(loc[1]+context, len(value))
+		for start > 0 && !utf8.RuneStart(value[start]) {
+			start--
+		}
+		for end < len(value) && !utf8.RuneStart(value[end]) {
+			end++
+		}
+		snippet := strings.Join(strings.Fields(value[start:end]), " ")
+		if start > 0 {
+			snippet = "…" + snippet
+		}
+		if end < len(value) {
+			snippet += "…"
+		}
+		return fields[i], snippet
+	}
+	return "", ""
 }
 
 // GetRoutes returns registered routes
@@ -630,7 +3096,128 @@ func (h *Handler) GetRoutes(c *gin.Context) {
 	c.JSON(http.StatusOK, routes)
 }
 
+// ExportAll returns a tar.gz archive of all specs with their operations and response configs
+func (h *Handler) ExportAll(c *gin.Context) {
+	h.writeExport(c, nil, "go-virtual-export-"+time.Now().Format("20060102-150405"))
+}
+
+// ExportSpec returns a tar.gz archive of a single spec with its operations and response configs
+// With ?format=wiremock or ?format=mockoon the response configs are converted into WireMock
+// stub mappings or a Mockoon environment instead
+func (h *Handler) ExportSpec(c *gin.Context) {
+	id := c.Param("id")
+
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "archive")
+	if format == "archive" {
+		h.writeExport(c, []string{id}, "go-virtual-spec-"+id)
+		return
+	}
+
+	ops, _ := h.store.GetOperationsBySpec(id)
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	configs := make(map[string][]*models.ResponseConfig, len(ops))
+	for _, op := range ops {
+		configs[op.ID], _ = h.store.GetResponseConfigsByOperation(op.ID)
+	}
+
+	switch format {
+	case "wiremock":
+		c.Header("Content-Disposition", `attachment; filename="go-virtual-spec-`+id+`.wiremock.json"`)
+		c.JSON(http.StatusOK, wiremock.Export(ops, configs))
+	case "mockoon":
+		c.Header("Content-Disposition", `attachment; filename="go-virtual-spec-`+id+`.mockoon.json"`)
+		c.JSON(http.StatusOK, mockoon.Export(spec, ops, configs))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be archive, wiremock or mockoon"})
+	}
+}
+
+// ExportOpenAPI returns the spec's OpenAPI document enriched with its response configs
+// Configs become named examples and an x-mock extension per operation, so the document is a
+// contract of what the mock returns. Use ?format=json for JSON instead of YAML.
+func (h *Handler) ExportOpenAPI(c *gin.Context) {
+	id := c.Param("id")
+
+	spec, err := h.store.GetSpec(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spec not found"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+		return
+	}
+
+	ops, _ := h.store.GetOperationsBySpec(id)
+	configs := make(map[string][]*models.ResponseConfig, len(ops))
+	for _, op := range ops {
+		configs[op.ID], _ = h.store.GetResponseConfigsByOperation(op.ID)
+	}
+
+	doc, err := parser.Enrich(spec, ops, configs)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	if format == "json" {
+		data, err := parser.RenderJSON(doc)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="go-virtual-spec-`+id+`.openapi.json"`)
+		c.Data(http.StatusOK, "application/json", data)
+		return
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="go-virtual-spec-`+id+`.openapi.yaml"`)
+	c.Data(http.StatusOK, "application/x-yaml", data)
+}
+
+// writeExport builds an export archive in memory so failures are reported as JSON errors
+func (h *Handler) writeExport(c *gin.Context, specIDs []string, filename string) {
+	var buf bytes.Buffer
+	if err := backup.Write(&buf, h.store, specIDs); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.tar.gz"`)
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
+
+// internalError answers with 500 and records the error for the request log
+// A full storage answers 507 Insufficient Storage instead
+func internalError(c *gin.Context, err error) {
+	c.Error(err)
+	status := http.StatusInternalServerError
+	if errors.Is(err, storage.ErrCapacityExceeded) {
+		status = http.StatusInsufficientStorage
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
 // HealthCheck returns health status
+// It is the liveness check: it answers as long as the process serves HTTP
 func (h *Handler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
@@ -638,6 +3225,506 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 	})
 }
 
+// CloseStreams ends the open event streams, so they don't hold up a graceful shutdown
+func (h *Handler) CloseStreams() {
+	h.closeStreams.Do(func() { close(h.streamsDone) })
+}
+
+// SetReady marks the server as ready or not ready for traffic
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// ReadinessCheck answers 200 once storage is loaded and routes are built, and 503 otherwise
+// It also fails while the server is draining
+func (h *Handler) ReadinessCheck(c *gin.Context) {
+	status, code := "ready", http.StatusOK
+	if !h.ready.Load() {
+		status, code = "not ready", http.StatusServiceUnavailable
+	} else if h.proxyEngine.Draining() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status":    status,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// GetOrphans reports orphaned data in storage, such as response configs of deleted operations
+func (h *Handler) GetOrphans(c *gin.Context) {
+	h.orphans(c, false)
+}
+
+// DeleteOrphans deletes the orphaned data GetOrphans reports
+func (h *Handler) DeleteOrphans(c *gin.Context) {
+	h.orphans(c, true)
+}
+
+// orphans runs the storage maintenance routine
+func (h *Handler) orphans(c *gin.Context, remove bool) {
+	maintainer, ok := h.store.(storage.Maintainer)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Storage does not support maintenance"})
+		return
+	}
+
+	report, err := maintainer.Orphans(remove)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if remove && len(report.ResponseConfigs) > 0 {
+		h.proxyEngine.ReloadRoutes()
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetClusterStatus returns this node's view of the cluster
+func (h *Handler) GetClusterStatus(c *gin.Context) {
+	if h.cluster == nil {
+		c.JSON(http.StatusOK, cluster.Status{})
+		return
+	}
+	c.JSON(http.StatusOK, h.cluster.Status())
+}
+
+// GetDrainStatus returns the drain state and the number of in-flight mock requests
+func (h *Handler) GetDrainStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.proxyEngine.DrainStatus())
+}
+
+// StartDrain fails readiness and rejects new mock requests after the grace period
+// The grace period defaults to server.drain.gracePeriod and can be set with ?gracePeriod=10s
+func (h *Handler) StartDrain(c *gin.Context) {
+	grace := h.proxyEngine.DrainGracePeriod()
+	if value := c.Query("gracePeriod"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "gracePeriod must be a duration such as 10s"})
+			return
+		}
+		grace = d
+	}
+
+	h.proxyEngine.StartDrain(grace)
+	c.JSON(http.StatusOK, h.proxyEngine.DrainStatus())
+}
+
+// CancelDrain accepts mock requests again and restores readiness
+func (h *Handler) CancelDrain(c *gin.Context) {
+	h.proxyEngine.CancelDrain()
+	c.JSON(http.StatusOK, h.proxyEngine.DrainStatus())
+}
+
+// ValidateFallbacks checks the status codes of configured fallback responses, of a spec or the
+// server. A zero status code keeps the built-in default for that fallback
+func ValidateFallbacks(fallbacks *models.FallbackResponses) string {
+	named := []struct {
+		name string
+		fb   *models.FallbackResponse
+	}{
+		{"notFound", fallbacks.NotFound},
+		{"noMatch", fallbacks.NoMatch},
+		{"error", fallbacks.Error},
+		{"timeout", fallbacks.Timeout},
+	}
+	for _, n := range named {
+		name, fb := n.name, n.fb
+		if fb != nil && fb.StatusCode != 0 && (fb.StatusCode < 100 || fb.StatusCode > 599) {
+			return "Invalid status code for " + name + " fallback: " + strconv.Itoa(fb.StatusCode)
+		}
+	}
+	return ""
+}
+
+// validateAuth checks the scheme, pattern and response status codes of an auth policy
+// An empty scheme defaults to apiKey
+func validateAuth(policy *models.AuthPolicy) string {
+	switch policy.Type {
+	case "":
+		policy.Type = models.AuthAPIKey
+	case models.AuthAPIKey, models.AuthBearer:
+	default:
+		return "Invalid auth type: " + policy.Type + " (expected apiKey or bearer)"
+	}
+	if policy.Pattern != "" {
+		if _, err := regexp.Compile(policy.Pattern); err != nil {
+			return "Invalid auth pattern: " + err.Error()
+		}
+	}
+	named := []struct {
+		name     string
+		response *models.FallbackResponse
+	}{
+		{"unauthorized", policy.Unauthorized},
+		{"forbidden", policy.Forbidden},
+	}
+	for _, n := range named {
+		name, response := n.name, n.response
+		if response != nil && response.StatusCode != 0 && (response.StatusCode < 100 || response.StatusCode > 599) {
+			return "Invalid status code for " + name + " response: " + strconv.Itoa(response.StatusCode)
+		}
+	}
+	return ""
+}
+
+// validateStateful checks that the seed files of a stateful policy can be loaded and that its
+// relations are complete
+func (h *Handler) validateStateful(specID string, policy *models.StatefulPolicy) string {
+	for _, rel := range policy.Relations {
+		if rel.Collection == "" || rel.Field == "" || rel.References == "" {
+			return "Stateful relations need a collection, field and references"
+		}
+	}
+	for name, path := range policy.Seed {
+		if name == "" {
+			return "Stateful seed collection name is required"
+		}
+		if _, err := h.proxyEngine.LoadSeed(specID, path); err != nil {
+			return "Invalid seed for " + strconv.Quote(name) + ": " + err.Error()
+		}
+	}
+	return ""
+}
+
+// validateUpstream checks that an upstream is empty or an absolute http(s) URL
+func validateUpstream(upstream string) string {
+	if upstream == "" {
+		return ""
+	}
+	u, err := url.Parse(upstream)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "upstream must be an http or https URL, e.g. https://api.example.com"
+	}
+	return ""
+}
+
+// validateAlerts checks the thresholds, window and webhook of an alert policy
+func validateAlerts(policy *models.AlertPolicy) string {
+	if policy.ErrorRate < 0 || policy.ErrorRate > 100 {
+		return "Alert errorRate must be between 0 and 100"
+	}
+	if policy.P95Latency < 0 || policy.Window < 0 || policy.MinRequests < 0 {
+		return "Alert p95Latency, window and minRequests must not be negative"
+	}
+	if policy.Enabled && policy.ErrorRate == 0 && policy.P95Latency == 0 {
+		return "Alert policies need an errorRate or p95Latency threshold"
+	}
+	if policy.Webhook != "" {
+		u, err := url.Parse(policy.Webhook)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return "Alert webhook must be an http or https URL"
+		}
+	}
+	return ""
+}
+
+// validateHealthCheck checks the path and timings of an upstream health check
+func validateHealthCheck(check *models.HealthCheck) string {
+	if check.Interval < 0 || check.Timeout < 0 || check.FailureThreshold < 0 {
+		return "Health check interval, timeout and failureThreshold must not be negative"
+	}
+	if strings.Contains(check.Path, "://") {
+		return "Health check path must be relative to the upstream, e.g. /health"
+	}
+	return ""
+}
+
+// validateGroup checks that a group name fits in a URL path segment
+func validateGroup(group string) string {
+	group = strings.TrimSpace(group)
+	if len(group) > 100 {
+		return "Group names are limited to 100 characters"
+	}
+	if strings.ContainsAny(group, "/?#") {
+		return "Group names can't contain /, ? or #"
+	}
+	return ""
+}
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// validateLabels checks that label keys can be used in a ?label= filter
+func validateLabels(labels map[string]string) string {
+	for key, value := range labels {
+		if len(key) > 63 || !labelKeyPattern.MatchString(key) {
+			return "Invalid label key " + strconv.Quote(key) + ": use up to 63 letters, digits, '.', '_', '/' or '-'"
+		}
+		if len(value) > 256 {
+			return "Label values are limited to 256 characters"
+		}
+	}
+	return ""
+}
+
+// validateLocale checks that a locale is empty or has fake data
+func validateLocale(locale string) string {
+	if locale == "" || template.HasLocale(locale) {
+		return ""
+	}
+	return "Unknown locale: " + strconv.Quote(locale) + " (expected one of " + strings.Join(template.Locales(), ", ") + ")"
+}
+
+// validateUpstreamRewrite checks the request and response rewrites of a spec's upstream
+func validateUpstreamRewrite(rewrite *models.UpstreamRewrite) string {
+	if errMsg := validateMessageRewrite("request", rewrite.Request); errMsg != "" {
+		return errMsg
+	}
+	return validateMessageRewrite("response", rewrite.Response)
+}
+
+// validateMessageRewrite checks the header names and body field paths of a message rewrite
+// Body paths must name fields; wildcards, queries and modifiers can't be set
+func validateMessageRewrite(side string, rules *models.MessageRewrite) string {
+	if rules == nil {
+		return ""
+	}
+	for name := range rules.SetHeaders {
+		if strings.TrimSpace(name) == "" {
+			return side + " header names are required"
+		}
+	}
+	for _, name := range rules.RemoveHeaders {
+		if strings.TrimSpace(name) == "" {
+			return side + " header names are required"
+		}
+	}
+
+	paths := slices.Clone(rules.RemoveBody)
+	for path := range rules.SetBody {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if path == "" || strings.ContainsAny(path, "*?|#@()") {
+			return "Invalid " + side + " body path " + strconv.Quote(path) + " (expected field names like user.address.city or items.0.id)"
+		}
+	}
+	return ""
+}
+
+// validateChaos checks the shares, delays and error status code of a chaos policy
+func validateChaos(policy *models.ChaosPolicy) string {
+	if policy.ErrorPercent < 0 || policy.ErrorPercent > 100 || policy.DelayPercent < 0 || policy.DelayPercent > 100 {
+		return "errorPercent and delayPercent must be between 0 and 100"
+	}
+	if policy.MinDelay < 0 || policy.MaxDelay < 0 {
+		return "Chaos delays must not be negative"
+	}
+	if policy.MaxDelay != 0 && policy.MaxDelay < policy.MinDelay {
+		return "maxDelay must not be less than minDelay"
+	}
+	if r := policy.Response; r != nil && r.StatusCode != 0 && (r.StatusCode < 100 || r.StatusCode > 599) {
+		return "Invalid status code for chaos response: " + strconv.Itoa(r.StatusCode)
+	}
+	return ""
+}
+
+// validateRateLimit checks the limit, window, key and response status code of a rate limit policy
+func validateRateLimit(policy *models.RateLimitPolicy) string {
+	if policy.Limit < 1 {
+		return "limit must be at least 1"
+	}
+	if policy.Window < 1 {
+		return "window must be at least 1 second"
+	}
+	if policy.Key != "" && policy.Key != "ip" {
+		if name, ok := strings.CutPrefix(policy.Key, "header:"); !ok || name == "" {
+			return `key must be empty, "ip" or "header:<name>"`
+		}
+	}
+	if r := policy.Response; r != nil && r.StatusCode != 0 && (r.StatusCode < 100 || r.StatusCode > 599) {
+		return "Invalid status code for rate limit response: " + strconv.Itoa(r.StatusCode)
+	}
+	return ""
+}
+
+// validateEvents checks the broker kind, destination and delay of event actions
+func validateEvents(actions []models.EventAction) string {
+	for i, action := range actions {
+		prefix := "Event " + strconv.Itoa(i+1) + ": "
+		switch action.Type {
+		case models.EventKafka:
+		case models.EventMQTT:
+			if action.QoS < 0 || action.QoS > 2 {
+				return prefix + "qos must be 0, 1 or 2"
+			}
+			if strings.ContainsAny(action.Topic, "+#") {
+				return prefix + "MQTT topics to publish to can't contain wildcards"
+			}
+			if action.Broker != "" {
+				u, err := url.Parse(action.Broker)
+				if err != nil || u.Host == "" || !slices.Contains(events.MQTTBrokerSchemes, u.Scheme) {
+					return prefix + "invalid broker URL " + strconv.Quote(action.Broker) + " (expected e.g. tcp://host:1883)"
+				}
+			}
+		case models.EventAMQP:
+			if action.Topic != "" {
+				return prefix + "AMQP events use routingKey instead of topic"
+			}
+			if action.Exchange == "" && action.RoutingKey == "" && action.Channel == "" {
+				return prefix + "an exchange, routingKey or channel is required"
+			}
+		default:
+			return prefix + "invalid type " + strconv.Quote(action.Type) + " (expected kafka, mqtt or amqp)"
+		}
+		if action.Type != models.EventAMQP && action.Topic == "" && action.Channel == "" {
+			return prefix + "a topic or channel is required"
+		}
+		if action.Delay < 0 {
+			return prefix + "delay must not be negative"
+		}
+	}
+	return ""
+}
+
+// validateBodyFile checks that a response body file is stored with the operation's spec
+// An empty name is valid and means none
+func (h *Handler) validateBodyFile(specID, name string) string {
+	if name == "" {
+		return ""
+	}
+	if !storage.ValidSpecFileName(name) {
+		return "Invalid body file name: " + strconv.Quote(name)
+	}
+	file, _, err := h.store.OpenSpecFile(specID, name)
+	if err != nil {
+		return "Spec has no file " + strconv.Quote(name) + "; upload it to /_api/specs/" + specID + "/files/" + name + " first"
+	}
+	file.Close()
+	return ""
+}
+
+// validateSOAPFault checks the version and code of a SOAP fault
+func validateSOAPFault(f *models.SOAPFault) string {
+	switch f.Version {
+	case "", models.SOAP11, models.SOAP12:
+	default:
+		return "Invalid SOAP version: " + strconv.Quote(f.Version) + " (expected 1.1 or 1.2)"
+	}
+	switch f.Code {
+	case "", "Client", "Server", "Sender", "Receiver", "VersionMismatch", "MustUnderstand":
+	case "DataEncodingUnknown":
+		if f.Version != models.SOAP12 {
+			return "The DataEncodingUnknown fault code needs SOAP 1.2"
+		}
+	default:
+		return "Invalid SOAP fault code: " + strconv.Quote(f.Code) + " (expected Client, Server, Sender, Receiver, VersionMismatch, MustUnderstand or DataEncodingUnknown)"
+	}
+	return ""
+}
+
+// validateFault checks the type and parameters of a response fault
+func validateFault(f *models.ResponseFault) string {
+	switch f.Type {
+	case models.FaultAbort:
+		if f.Fraction < 0 || f.Fraction > 1 {
+			return "Abort fraction must be between 0 and 1"
+		}
+	case models.FaultTrickle:
+		if f.Interval < 0 || f.MaxBytes < 0 {
+			return "Trickle interval and maxBytes must not be negative"
+		}
+	default:
+		return "Invalid fault type: " + strconv.Quote(f.Type) + " (expected abort or trickle)"
+	}
+	return ""
+}
+
+// validatePagination checks the mode, sizes and dataset source of a pagination
+// An empty pagination is valid and means none
+func validatePagination(p *models.Pagination) string {
+	if *p == (models.Pagination{}) {
+		return ""
+	}
+	switch p.Mode {
+	case models.PaginationPage, models.PaginationOffset, models.PaginationCursor:
+	default:
+		return "Invalid pagination mode: " + strconv.Quote(p.Mode) + " (expected page, offset or cursor)"
+	}
+	if p.Generate < 0 || p.DefaultLimit < 0 || p.MaxLimit < 0 {
+		return "Pagination sizes must not be negative"
+	}
+	if p.Generate > 0 && p.ItemTemplate == "" {
+		return "Pagination needs an itemTemplate to generate items"
+	}
+	if p.Generate == 0 && p.Dataset == "" {
+		return "Pagination needs a dataset or items to generate"
+	}
+	return ""
+}
+
+// newResponseConfig builds a response config for an operation from input, applying defaults
+func newResponseConfig(opID string, input models.ResponseConfigInput) *models.ResponseConfig {
+	cfg := &models.ResponseConfig{
+		ID:               generateID(),
+		OperationID:      opID,
+		Name:             input.Name,
+		Description:      input.Description,
+		Priority:         input.Priority,
+		Conditions:       input.Conditions,
+		NegateConditions: input.NegateConditions,
+		StatusCode:       input.StatusCode,
+		Headers:          input.Headers,
+		Body:             input.Body,
+		BodyFile:         input.BodyFile,
+		Delay:            input.Delay,
+		Seed:             input.Seed,
+		Enabled:          input.Enabled,
+		Pagination:       input.Pagination,
+		Events:           input.Events,
+		SOAPFault:        input.SOAPFault,
+		Fault:            input.Fault,
+	}
+
+	// Set defaults
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = 200
+		if cfg.SOAPFault != nil {
+			cfg.StatusCode = cfg.SOAPFault.StatusCode()
+		}
+	}
+	if cfg.Headers == nil {
+		cfg.Headers = make(map[string]string)
+	}
+	if cfg.Conditions == nil {
+		cfg.Conditions = make([]models.Condition, 0)
+	}
+
+	return cfg
+}
+
+// nextPriority returns a priority that sorts after all of the given response configs
+func nextPriority(configs []*models.ResponseConfig) int {
+	priority := 0
+	for _, cfg := range configs {
+		if cfg.Priority >= priority {
+			priority = cfg.Priority + 1
+		}
+	}
+	return priority
+}
+
+// toOperationSummary converts an operation to its lightweight listing form
+func toOperationSummary(op *models.Operation, responseCount int) models.OperationSummary {
+	return models.OperationSummary{
+		ID:                 op.ID,
+		SpecID:             op.SpecID,
+		Method:             op.Method,
+		Path:               op.Path,
+		FullPath:           op.FullPath,
+		OperationID:        op.OperationID,
+		Summary:            op.Summary,
+		Tags:               op.Tags,
+		ResponseCount:      responseCount,
+		HasExampleResponse: op.ExampleResponse != nil,
+		Custom:             op.Custom,
+		Disabled:           op.Disabled,
+		Tracing:            op.Tracing,
+	}
+}
+
 // generateID generates a unique ID
 func generateID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
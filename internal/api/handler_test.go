@@ -1,14 +1,24 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prasenjit/go-virtual/internal/cluster"
 	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
 	"github.com/prasenjit/go-virtual/internal/proxy"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
@@ -136,6 +146,120 @@ paths:
 	}
 }
 
+func TestCreateSpec_StorageFull(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+	store.(*storage.MemoryStorage).SetLimits(storage.MemoryLimits{MaxSpecs: 1})
+	store.CreateSpec(&models.Spec{ID: "existing"})
+
+	r.POST("/specs", handler.CreateSpec)
+
+	specContent := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths: {}
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": specContent})
+
+	req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected status 507, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateResponseConfig_StorageFull(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+	store.(*storage.MemoryStorage).SetLimits(storage.MemoryLimits{MaxBodyBytes: 10})
+	store.CreateSpec(&models.Spec{ID: "spec-1"})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "config-1", OperationID: "op-1", Body: "small"})
+
+	r.PUT("/responses/:id", handler.UpdateResponseConfig)
+
+	req := httptest.NewRequest("PUT", "/responses/config-1", strings.NewReader(`{"body": "far too large"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected status 507, got %d: %s", w.Code, w.Body.String())
+	}
+	if cfg, _ := store.GetResponseConfig("config-1"); cfg.Body != "small" {
+		t.Errorf("Expected the rejected body not to be served, got %q", cfg.Body)
+	}
+}
+
+func TestSpecEvicted(t *testing.T) {
+	handler, store, _ := setupTestHandler(t)
+	memStore := store.(*storage.MemoryStorage)
+	memStore.SetLimits(storage.MemoryLimits{MaxSpecs: 1, Eviction: storage.EvictOldest})
+	memStore.SetEvictHandler(handler.forgetSpec)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", BasePath: "/old", Enabled: true, CreatedAt: time.Now()})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/old/users"})
+	handler.proxyEngine.ReloadRoutes()
+	handler.statsCollector.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	handler.tracingService.RecordTrace(&models.Trace{SpecID: "spec-1"})
+
+	store.CreateSpec(&models.Spec{ID: "spec-2", BasePath: "/new", Enabled: true, CreatedAt: time.Now().Add(time.Second)})
+
+	if op, _, _ := handler.proxyEngine.MatchRoute("GET", "/old/users"); op != nil {
+		t.Error("Expected the evicted spec no longer served")
+	}
+	if handler.statsCollector.GetOperationStats("op-1") != nil || len(handler.tracingService.GetTraces(nil)) != 0 {
+		t.Error("Expected the statistics and traces of the evicted spec cleared")
+	}
+}
+
+func TestCreateSpec_BasePathPrefix(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+	handler.proxyEngine.SetBasePathPrefixes([]string{"/team-a"})
+	store.CreateSpec(&models.Spec{ID: "spec-1", BasePath: "/team-a/users"})
+
+	r.POST("/specs", handler.CreateSpec)
+	r.PUT("/specs/:id", handler.UpdateSpec)
+
+	specContent := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths: {}
+`
+	create := func(basePath string) int {
+		jsonBody, _ := json.Marshal(map[string]string{"content": specContent, "basePath": basePath})
+		req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := create("/team-b/orders"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 outside the prefix, got %d", code)
+	}
+	if code := create("/team-a/orders"); code != http.StatusCreated {
+		t.Errorf("Expected status 201 under the prefix, got %d", code)
+	}
+
+	req := httptest.NewRequest("PUT", "/specs/spec-1", bytes.NewReader([]byte(`{"basePath": "/team-b"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 moving a spec outside the prefix, got %d", w.Code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.BasePath != "/team-a/users" {
+		t.Errorf("Expected base path to be unchanged, got %s", spec.BasePath)
+	}
+}
+
 func TestCreateSpec_InvalidSpec(t *testing.T) {
 	handler, _, r := setupTestHandler(t)
 
@@ -173,6 +297,94 @@ func TestCreateSpec_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestValidateSpec(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	// Existing spec already serving GET /api/v1/users
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Existing", BasePath: "/api/v1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/v1/users"})
+	handler.proxyEngine.ReloadRoutes()
+
+	r.POST("/specs/validate", handler.ValidateSpec)
+
+	specContent := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: Success
+    post:
+      responses:
+        "201":
+          description: Created
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": specContent, "basePath": "/api/v1"})
+	req := httptest.NewRequest("POST", "/specs/validate", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var result struct {
+		Valid      bool                      `json:"valid"`
+		Operations []models.OperationSummary `json:"operations"`
+		Conflicts  []map[string]interface{}  `json:"conflicts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if !result.Valid {
+		t.Error("Expected spec to be valid")
+	}
+	if len(result.Operations) != 2 {
+		t.Errorf("Expected 2 operations, got %d", len(result.Operations))
+	}
+	if len(result.Conflicts) != 1 {
+		t.Errorf("Expected 1 conflict, got %d", len(result.Conflicts))
+	}
+
+	// Nothing should have been persisted
+	specs, _ := store.GetAllSpecs()
+	if len(specs) != 1 {
+		t.Errorf("Expected only the existing spec, got %d specs", len(specs))
+	}
+}
+
+func TestValidateSpec_Invalid(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.POST("/specs/validate", handler.ValidateSpec)
+
+	jsonBody, _ := json.Marshal(map[string]string{"content": "invalid yaml content {{{"})
+	req := httptest.NewRequest("POST", "/specs/validate", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if result["valid"] != false {
+		t.Error("Expected spec to be reported invalid")
+	}
+	if result["error"] == nil {
+		t.Error("Expected an error message")
+	}
+}
+
 func TestGetSpec_Exists(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
@@ -248,158 +460,1975 @@ func TestUpdateSpec(t *testing.T) {
 	}
 }
 
-func TestUpdateSpec_NotFound(t *testing.T) {
-	handler, _, r := setupTestHandler(t)
+func TestUpdateSpec_MaxConcurrent(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
 
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
 	r.PUT("/specs/:id", handler.UpdateSpec)
 
-	update := map[string]interface{}{"name": "New Name"}
-	jsonBody, _ := json.Marshal(update)
-
-	req := httptest.NewRequest("PUT", "/specs/nonexistent", bytes.NewReader(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+	put := func(body string) int {
+		req := httptest.NewRequest("PUT", "/specs/spec-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
 
-	r.ServeHTTP(w, req)
+	if code := put(`{"maxConcurrent": 5}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.MaxConcurrent != 5 {
+		t.Errorf("Expected maxConcurrent 5, got %d", spec.MaxConcurrent)
+	}
 
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status 404, got %d", w.Code)
+	if code := put(`{"maxConcurrent": -1}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative limit, got %d", code)
 	}
 }
 
-func TestDeleteSpec(t *testing.T) {
+func TestUpdateSpec_Chaos(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
-	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
-	store.CreateSpec(spec)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+	r.PUT("/specs/:id", handler.UpdateSpec)
 
-	// Add an operation
-	op := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"}
-	store.CreateOperation(op)
+	put := func(body string) int {
+		req := httptest.NewRequest("PUT", "/specs/spec-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
 
-	// Add a response config
-	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default"}
-	store.CreateResponseConfig(config)
+	if code := put(`{"chaos": {"enabled": true, "errorPercent": 5, "delayPercent": 10, "minDelay": 100, "maxDelay": 2000, "seed": 7}}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.Chaos == nil || spec.Chaos.ErrorPercent != 5 || spec.Chaos.Seed != 7 {
+		t.Errorf("Expected the chaos policy to be stored, got %+v", spec.Chaos)
+	}
 
-	r.DELETE("/specs/:id", handler.DeleteSpec)
+	for _, chaos := range []string{
+		`{"enabled": true, "errorPercent": 101}`,
+		`{"enabled": true, "delayPercent": -1}`,
+		`{"enabled": true, "minDelay": 500, "maxDelay": 100}`,
+		`{"enabled": true, "response": {"statusCode": 42}}`,
+	} {
+		if code := put(`{"chaos": ` + chaos + `}`); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", chaos, code)
+		}
+	}
+}
 
-	req := httptest.NewRequest("DELETE", "/specs/spec-1", nil)
-	w := httptest.NewRecorder()
+func TestUpdateSpec_Locale(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
 
-	r.ServeHTTP(w, req)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+	r.PUT("/specs/:id", handler.UpdateSpec)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
+	put := func(body string) int {
+		req := httptest.NewRequest("PUT", "/specs/spec-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
 	}
 
-	// Verify deletion
-	_, err := store.GetSpec("spec-1")
-	if err == nil {
-		t.Error("Expected spec to be deleted")
+	if code := put(`{"locale": "de-DE"}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
 	}
-
-	// Verify operations deleted
-	ops, _ := store.GetOperationsBySpec("spec-1")
-	if len(ops) != 0 {
-		t.Errorf("Expected operations to be deleted, got %d", len(ops))
+	if spec, _ := store.GetSpec("spec-1"); spec.Locale != "de-DE" {
+		t.Errorf("Expected the locale to be stored, got %q", spec.Locale)
+	}
+	if code := put(`{"locale": "xx_XX"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown locale, got %d", code)
 	}
 }
 
-func TestDeleteSpec_NotFound(t *testing.T) {
-	handler, _, r := setupTestHandler(t)
+func TestUpdateSpec_Auth(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
 
-	r.DELETE("/specs/:id", handler.DeleteSpec)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+	r.PUT("/specs/:id", handler.UpdateSpec)
 
-	req := httptest.NewRequest("DELETE", "/specs/nonexistent", nil)
-	w := httptest.NewRecorder()
+	put := func(body string) int {
+		req := httptest.NewRequest("PUT", "/specs/spec-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
 
-	r.ServeHTTP(w, req)
+	if code := put(`{"auth": {"enabled": true, "keys": ["secret"]}}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.Auth == nil || spec.Auth.Type != models.AuthAPIKey {
+		t.Errorf("Expected an apiKey policy, got %+v", spec.Auth)
+	}
 
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status 404, got %d", w.Code)
+	for _, body := range []string{
+		`{"auth": {"enabled": true, "type": "digest"}}`,
+		`{"auth": {"enabled": true, "pattern": "["}}`,
+		`{"auth": {"enabled": true, "forbidden": {"statusCode": 1000}}}`,
+	} {
+		if code := put(body); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, code)
+		}
 	}
 }
 
-func TestListOperations(t *testing.T) {
+func TestUpdateSpec_Fallbacks(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
-	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
-	store.CreateSpec(spec)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
 
-	op1 := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"}
-	op2 := &models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/users"}
-	store.CreateOperation(op1)
-	store.CreateOperation(op2)
+	r.PUT("/specs/:id", handler.UpdateSpec)
 
-	r.GET("/specs/:id/operations", handler.ListOperations)
+	update := map[string]interface{}{
+		"fallbacks": map[string]interface{}{
+			"noMatch": map[string]interface{}{"statusCode": 501, "body": `{"error": "not mocked"}`},
+		},
+	}
+	jsonBody, _ := json.Marshal(update)
 
-	req := httptest.NewRequest("GET", "/specs/spec-1/operations", nil)
+	req := httptest.NewRequest("PUT", "/specs/spec-1", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
-
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
 	}
 
-	var result []models.Operation
-	json.Unmarshal(w.Body.Bytes(), &result)
+	spec, _ := store.GetSpec("spec-1")
+	if spec.Fallbacks == nil || spec.Fallbacks.NoMatch == nil || spec.Fallbacks.NoMatch.StatusCode != 501 {
+		t.Errorf("Expected noMatch fallback to be stored, got %+v", spec.Fallbacks)
+	}
 
-	if len(result) != 2 {
-		t.Errorf("Expected 2 operations, got %d", len(result))
+	// Invalid status code
+	update["fallbacks"] = map[string]interface{}{"error": map[string]interface{}{"statusCode": 999}}
+	jsonBody, _ = json.Marshal(update)
+
+	req = httptest.NewRequest("PUT", "/specs/spec-1", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
 	}
 }
 
-func TestGetOperation(t *testing.T) {
+func TestSpecFiles(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
-	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
-	store.CreateSpec(spec)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/report", FullPath: "/report"})
 
-	op := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", Summary: "List users"}
-	store.CreateOperation(op)
+	r.GET("/specs/:id/files", handler.ListSpecFiles)
+	r.PUT("/specs/:id/files/:name", handler.UploadSpecFile)
+	r.GET("/specs/:id/files/:name", handler.DownloadSpecFile)
+	r.DELETE("/specs/:id/files/:name", handler.DeleteSpecFile)
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+	r.PUT("/responses/:id", handler.UpdateResponseConfig)
 
-	r.GET("/operations/:id", handler.GetOperation)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
 
-	req := httptest.NewRequest("GET", "/operations/op-1", nil)
-	w := httptest.NewRecorder()
+	// A body file must be uploaded first
+	if w := do("POST", "/operations/op-1/responses", `{"name": "Report", "bodyFile": "report.pdf"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a missing body file, got %d", w.Code)
+	}
 
-	r.ServeHTTP(w, req)
+	w := do("PUT", "/specs/spec-1/files/report.pdf", "%PDF-1.4")
+	var info models.SpecFile
+	json.Unmarshal(w.Body.Bytes(), &info)
+	if w.Code != http.StatusOK || info.Size != 8 || info.ContentType != "application/pdf" {
+		t.Fatalf("Unexpected upload response: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/specs/spec-1/files/.env", "x"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a hidden file name, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/missing/files/report.pdf", "x"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing spec, got %d", w.Code)
+	}
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
+	if w := do("GET", "/specs/spec-1/files", ""); !strings.Contains(w.Body.String(), `"name":"report.pdf"`) {
+		t.Errorf("Expected the file in the list, got %s", w.Body.String())
+	}
+	if w := do("GET", "/specs/spec-1/files/report.pdf", ""); w.Body.String() != "%PDF-1.4" || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("Unexpected download: %q %s", w.Header().Get("Content-Type"), w.Body.String())
 	}
 
-	var result models.Operation
-	json.Unmarshal(w.Body.Bytes(), &result)
+	w = do("POST", "/operations/op-1/responses", `{"name": "Report", "bodyFile": "report.pdf"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var cfg models.ResponseConfig
+	json.Unmarshal(w.Body.Bytes(), &cfg)
 
-	if result.ID != "op-1" {
-		t.Errorf("Expected id 'op-1', got %q", result.ID)
+	// Files used as bodies can't be deleted
+	if w := do("DELETE", "/specs/spec-1/files/report.pdf", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a file in use, got %d", w.Code)
 	}
-	if result.Summary != "List users" {
-		t.Errorf("Expected summary 'List users', got %q", result.Summary)
+	if w := do("PUT", "/responses/"+cfg.ID, `{"bodyFile": "other.pdf"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a missing body file, got %d", w.Code)
+	}
+	if w := do("PUT", "/responses/"+cfg.ID, `{"bodyFile": ""}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w := do("DELETE", "/specs/spec-1/files/report.pdf", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w := do("GET", "/specs/spec-1/files/report.pdf", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deleting, got %d", w.Code)
 	}
 }
 
-func TestGetOperation_NotFound(t *testing.T) {
-	handler, _, r := setupTestHandler(t)
+func TestSnippets(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
 
-	r.GET("/operations/:id", handler.GetOperation)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
 
-	req := httptest.NewRequest("GET", "/operations/nonexistent", nil)
-	w := httptest.NewRecorder()
+	r.GET("/specs/:id/snippets", handler.ListSnippets)
+	r.PUT("/specs/:id/snippets/:name", handler.SetSnippet)
+	r.DELETE("/specs/:id/snippets/:name", handler.DeleteSnippet)
 
+	jsonBody, _ := json.Marshal(models.SnippetInput{Content: `{"city": "Springfield"}`})
+	req := httptest.NewRequest("PUT", "/specs/spec-1/snippets/address", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status 404, got %d", w.Code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
 	}
-}
 
-func TestListResponseConfigs(t *testing.T) {
-	handler, store, r := setupTestHandler(t)
+	req = httptest.NewRequest("GET", "/specs/spec-1/snippets", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	spec := &models.Spec{ID: "spec-1"}
+	var snippets map[string]string
+	json.Unmarshal(w.Body.Bytes(), &snippets)
+	if snippets["address"] != `{"city": "Springfield"}` {
+		t.Errorf("Unexpected snippets: %v", snippets)
+	}
+
+	// Invalid name
+	req = httptest.NewRequest("PUT", "/specs/spec-1/snippets/bad%20name", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/specs/spec-1/snippets/address", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	spec, _ := store.GetSpec("spec-1")
+	if len(spec.Snippets) != 0 {
+		t.Errorf("Expected snippet to be deleted, got %v", spec.Snippets)
+	}
+
+	req = httptest.NewRequest("DELETE", "/specs/spec-1/snippets/address", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAsyncAPI(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+
+	r.PUT("/specs/:id/asyncapi", handler.SetAsyncAPI)
+	r.DELETE("/specs/:id/asyncapi", handler.DeleteAsyncAPI)
+	r.GET("/specs/:id/channels", handler.ListChannels)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		path := "/specs/spec-1/asyncapi"
+		if method == "GET" {
+			path = "/specs/spec-1/channels"
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	content := `{"asyncapi": "3.0.0", "channels": {"userEvents": {"address": "users", "messages": {"created": {"payload": {"type": "object"}}}}}}`
+	body, _ := json.Marshal(models.SpecContentInput{Content: content})
+	if w := do("PUT", string(body)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.Channel("users") == nil || spec.AsyncAPI != content {
+		t.Errorf("Expected the channels to be stored, got %+v", spec.Channels)
+	}
+
+	var channels []models.Channel
+	json.Unmarshal(do("GET", "").Body.Bytes(), &channels)
+	if len(channels) != 1 || channels[0].Name != "userEvents" {
+		t.Errorf("Unexpected channels: %+v", channels)
+	}
+
+	if w := do("PUT", `{"content": "openapi: 3.0.0"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an OpenAPI document, got %d", w.Code)
+	}
+
+	do("DELETE", "")
+	if spec, _ := store.GetSpec("spec-1"); spec.Channels != nil || spec.AsyncAPI != "" {
+		t.Error("Expected the AsyncAPI document to be removed")
+	}
+}
+
+func TestContractViolations(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	content := "openapi: 3.0.0\ninfo: {title: API, version: '1'}\npaths:\n  /users/{id}:\n    get:\n      parameters:\n        - {name: id, in: path, required: true, schema: {type: integer}}\n      responses:\n        '200': {description: OK}\n"
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Content: content, Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.GET("/specs/:id/violations", handler.GetContractViolations)
+	r.DELETE("/specs/:id/violations", handler.ClearContractViolations)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/specs/missing/violations", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown spec, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-1", `{"contractCheck": true}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/api/users/1", "/api/users/ann", "/api/groups"} {
+		handler.proxyEngine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var report models.ContractReport
+	json.Unmarshal(do("GET", "/specs/spec-1/violations", "").Body.Bytes(), &report)
+	if !report.Enabled || report.Total != 2 || len(report.Violations) != 2 {
+		t.Fatalf("Expected two violations, got %+v", report)
+	}
+
+	if w := do("DELETE", "/specs/spec-1/violations", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	json.Unmarshal(do("GET", "/specs/spec-1/violations", "").Body.Bytes(), &report)
+	if report.Total != 0 || len(report.Violations) != 0 {
+		t.Errorf("Expected a cleared report, got %+v", report)
+	}
+}
+
+func TestGetCoverage(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/users", FullPath: "/api/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Name: "Gold", Priority: 1, StatusCode: 200, Enabled: true,
+		Conditions: []models.Condition{{Source: "header", Key: "X-Tier", Operator: "eq", Value: "gold"}}})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-2", OperationID: "op-1", Name: "Default", Priority: 2, StatusCode: 200, Enabled: true})
+	handler.proxyEngine.ReloadRoutes()
+
+	r.GET("/specs/:id/coverage", handler.GetCoverage)
+
+	handler.proxyEngine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/specs/spec-1/coverage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var coverage models.SpecCoverage
+	json.Unmarshal(w.Body.Bytes(), &coverage)
+	if coverage.CoveredOperations != 1 || coverage.TotalOperations != 2 || coverage.CoveredResponses != 1 {
+		t.Errorf("Expected one operation and response config covered, got %+v", coverage)
+	}
+	if len(coverage.UncoveredResponses) != 1 || coverage.UncoveredResponses[0].Name != "Gold" {
+		t.Errorf("Expected the gold config uncovered, got %+v", coverage.UncoveredResponses)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/specs/missing/coverage", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 500, Enabled: true})
+
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.GET("/stats", handler.GetGlobalStats)
+	r.GET("/stats/specs/:id", handler.GetSpecStats)
+	r.GET("/stats/alerts", handler.ListAlerts)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"alerts": {"enabled": true, "errorRate": 120}}`,
+		`{"alerts": {"enabled": true}}`,
+		`{"alerts": {"enabled": true, "p95Latency": 100, "webhook": "ftp://alerts"}}`,
+	} {
+		if w := do("PUT", "/specs/spec-1", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if w := do("PUT", "/specs/spec-1", `{"alerts": {"enabled": true, "errorRate": 50, "minRequests": 2}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for i := 0; i < 2; i++ {
+		handler.proxyEngine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+	}
+	handler.proxyEngine.Alerts().Evaluate()
+
+	var global models.GlobalStats
+	json.Unmarshal(do("GET", "/stats", "").Body.Bytes(), &global)
+	if len(global.Alerts) != 1 || global.Alerts[0].Metric != models.AlertErrorRate || global.Alerts[0].Value != 100 {
+		t.Errorf("Expected an error rate alert in the global stats, got %+v", global.Alerts)
+	}
+	var spec models.SpecStats
+	json.Unmarshal(do("GET", "/stats/specs/spec-1", "").Body.Bytes(), &spec)
+	if len(spec.Alerts) != 1 {
+		t.Errorf("Expected the alert in the spec stats, got %+v", spec.Alerts)
+	}
+	var alerts []models.Alert
+	json.Unmarshal(do("GET", "/stats/alerts?specId=other", "").Body.Bytes(), &alerts)
+	if len(alerts) != 0 {
+		t.Errorf("Expected no alerts of another spec, got %+v", alerts)
+	}
+}
+
+func TestUpstreamHealthCheck(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Upstream: upstream.URL})
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.GET("/specs/:id", handler.GetSpec)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PUT", "/specs/spec-1", `{"healthCheck": {"enabled": true, "path": "http://other/health"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an absolute path, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-1", `{"healthCheck": {"enabled": true, "interval": -1}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative interval, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-1", `{"healthCheck": {"enabled": true, "path": "/health", "interval": 3600, "fallback": true}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	defer do("PUT", "/specs/spec-1", `{"healthCheck": {"enabled": false}}`)
+
+	var spec struct {
+		Name           string                 `json:"name"`
+		HealthCheck    *models.HealthCheck    `json:"healthCheck"`
+		UpstreamHealth *models.UpstreamHealth `json:"upstreamHealth"`
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		json.Unmarshal(do("GET", "/specs/spec-1", "").Body.Bytes(), &spec)
+		if spec.UpstreamHealth != nil && spec.UpstreamHealth.Status == models.UpstreamUp {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if spec.Name != "API" || spec.HealthCheck == nil || spec.UpstreamHealth == nil || spec.UpstreamHealth.URL != upstream.URL+"/health" || spec.UpstreamHealth.Status != models.UpstreamUp {
+		t.Errorf("Expected the spec with its upstream up, got %+v %+v", spec, spec.UpstreamHealth)
+	}
+}
+
+func TestSpecGroups(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Cards", BasePath: "/cards", Enabled: true, Group: "payments"})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Accounts", BasePath: "/accounts", Enabled: true})
+	store.CreateSpec(&models.Spec{ID: "spec-3", Name: "Users", BasePath: "/users", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/", FullPath: "/cards/"})
+	handler.statsCollector.RecordRequest("spec-1", "op-1", "GET", "/", 10*time.Millisecond, false)
+
+	r.GET("/specs", handler.ListSpecs)
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.GET("/groups", handler.ListGroups)
+	r.PUT("/groups/:group/enable", handler.EnableGroup)
+	r.PUT("/groups/:group/disable", handler.DisableGroup)
+	r.GET("/stats/groups/:group", handler.GetGroupStats)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PUT", "/specs/spec-2", `{"group": "payments/eu"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a group with a slash, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-2", `{"group": " payments "}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var listed []map[string]interface{}
+	json.Unmarshal(do("GET", "/specs?group=payments", "").Body.Bytes(), &listed)
+	if len(listed) != 2 || listed[0]["group"] != "payments" {
+		t.Errorf("Expected the 2 specs of the group, got %v", listed)
+	}
+	json.Unmarshal(do("GET", "/specs?group=", "").Body.Bytes(), &listed)
+	if len(listed) != 1 || listed[0]["id"] != "spec-3" {
+		t.Errorf("Expected the ungrouped spec, got %v", listed)
+	}
+
+	w := do("PUT", "/groups/payments/disable", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"changed":2`) {
+		t.Fatalf("Expected 2 specs disabled, got %d: %s", w.Code, w.Body.String())
+	}
+	if spec, _ := store.GetSpec("spec-3"); !spec.Enabled {
+		t.Error("Expected the ungrouped spec to stay enabled")
+	}
+
+	var groups []models.GroupSummary
+	json.Unmarshal(do("GET", "/groups", "").Body.Bytes(), &groups)
+	if len(groups) != 1 || groups[0].Group != "payments" || groups[0].SpecCount != 2 || groups[0].EnabledCount != 0 {
+		t.Errorf("Expected the payments group with no enabled specs, got %+v", groups)
+	}
+
+	do("PUT", "/groups/payments/enable", "")
+	if spec, _ := store.GetSpec("spec-1"); !spec.Enabled {
+		t.Error("Expected the group's specs to be enabled again")
+	}
+
+	var groupStats models.GroupStats
+	json.Unmarshal(do("GET", "/stats/groups/payments", "").Body.Bytes(), &groupStats)
+	if groupStats.TotalRequests != 1 || len(groupStats.Specs) != 2 || groupStats.Specs[0].SpecName != "Accounts" {
+		t.Errorf("Expected the group's stats rolled up, got %+v", groupStats)
+	}
+
+	if w := do("GET", "/stats/groups/unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown group, got %d", w.Code)
+	}
+}
+
+func TestSpecLabels(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Cards", BasePath: "/cards", Labels: map[string]string{"team": "payments", "env": "staging"}})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Accounts", BasePath: "/accounts", Labels: map[string]string{"team": "payments", "env": "prod"}})
+	store.CreateSpec(&models.Spec{ID: "spec-3", Name: "Users", BasePath: "/users"})
+
+	r.GET("/specs", handler.ListSpecs)
+	r.PUT("/specs/:id", handler.UpdateSpec)
+
+	list := func(query string) []string {
+		req := httptest.NewRequest("GET", "/specs"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var specs []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &specs)
+		ids := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			ids = append(ids, spec["id"].(string))
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"?label=team:payments", "spec-1,spec-2"},
+		{"?label=team:payments&label=env=staging", "spec-1"},
+		{"?label=env", "spec-1,spec-2"},
+		{"?label=team:billing", ""},
+		{"", "spec-1,spec-2,spec-3"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(list(tt.query), ","); got != tt.expected {
+			t.Errorf("GET /specs%s: expected %q, got %q", tt.query, tt.expected, got)
+		}
+	}
+
+	update := func(body string) int {
+		req := httptest.NewRequest("PUT", "/specs/spec-3", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := update(`{"labels": {"team:x": "a"}}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid label key, got %d", code)
+	}
+	if code := update(`{"labels": {"env": "staging"}}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if got := strings.Join(list("?label=env:staging"), ","); got != "spec-1,spec-3" {
+		t.Errorf("Expected the relabeled spec to match, got %q", got)
+	}
+	update(`{"labels": {}}`)
+	if spec, _ := store.GetSpec("spec-3"); spec.Labels != nil {
+		t.Errorf("Expected the labels removed, got %v", spec.Labels)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Payments API", BasePath: "/payments"})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Users", Description: "Manages user payments preferences", BasePath: "/users"})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/payments/{id}/refund", Summary: "Refund a payment"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-2", Method: "GET", Path: "/users", Summary: "List users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-2", Name: "Default", Body: `{"users": [{"name": "Zoë", "note": "` + strings.Repeat("x", 60) + ` REFUND pending ` + strings.Repeat("y", 60) + `"}]}`})
+
+	r.GET("/search", handler.Search)
+
+	search := func(query string) (int, models.SearchResult) {
+		req := httptest.NewRequest("GET", "/search"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var result models.SearchResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	_, result := search("?q=refund")
+	if len(result.Hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %+v", result.Hits)
+	}
+	op, resp := result.Hits[0], result.Hits[1]
+	if op.Type != models.SearchOperation || op.ID != "op-1" || op.Field != "path" || op.Title != "POST /payments/{id}/refund" || op.Link != "/_api/operations/op-1" {
+		t.Errorf("Unexpected operation hit: %+v", op)
+	}
+	if resp.Type != models.SearchResponse || resp.OperationID != "op-2" || resp.Field != "body" || resp.UILink != "/_ui/operations/op-2" {
+		t.Errorf("Unexpected response hit: %+v", resp)
+	}
+	if !strings.HasPrefix(resp.Snippet, "…") || !strings.HasSuffix(resp.Snippet, "…") || !strings.Contains(resp.Snippet, "REFUND pending") {
+		t.Errorf("Expected a snippet around the match, got %q", resp.Snippet)
+	}
+
+	_, result = search("?q=payments&type=spec")
+	if len(result.Hits) != 2 || result.Hits[0].ID != "spec-1" || result.Hits[1].Field != "description" {
+		t.Errorf("Expected both specs, got %+v", result.Hits)
+	}
+
+	_, result = search("?q=u&limit=2")
+	if len(result.Hits) != 2 || !result.Truncated {
+		t.Errorf("Expected 2 hits truncated, got %+v", result)
+	}
+
+	if code, _ := search("?q=%20"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a query, got %d", code)
+	}
+	if code, _ := search("?q=a&type=trace"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown type, got %d", code)
+	}
+}
+
+func TestStreamStats(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+	r.GET("/stats/stream", handler.StreamStats)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stats/stream?interval=1")
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() models.StatsUpdate {
+		var update models.StatsUpdate
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read the stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				json.Unmarshal([]byte(data), &update)
+				return update
+			}
+		}
+	}
+
+	if first := next(); first.TotalRequests != 0 {
+		t.Errorf("Expected no requests yet, got %+v", first)
+	}
+	handler.statsCollector.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	if update := next(); update.TotalRequests != 1 || update.Requests != 1 {
+		t.Errorf("Expected the new request, got %+v", update)
+	}
+
+	// Shutting down ends the stream
+	handler.CloseStreams()
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("Expected the stream to end, got %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+	r.POST("/purge", handler.Purge)
+
+	purge := func(body string) (int, models.PurgeResult) {
+		req := httptest.NewRequest("POST", "/purge", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var result models.PurgeResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	old := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	handler.tracingService.RecordTrace(&models.Trace{ID: "old", Timestamp: old, Request: models.TraceRequest{Body: "card=4111"}})
+	handler.tracingService.RecordTrace(&models.Trace{ID: "recent", Timestamp: recent, Request: models.TraceRequest{Body: "card=5500"}, Response: models.TraceResponse{StatusCode: 201}})
+	handler.statsCollector.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, true)
+	handler.statsCollector.RecordError("spec-1", "op-1", "/users", "GET", 500, "boom", "req-1")
+
+	if code, _ := purge(`{"scopes": []}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without scopes, got %d", code)
+	}
+	if code, _ := purge(`{"scopes": ["recordings"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown scope, got %d", code)
+	}
+	if code, _ := purge(`{"scopes": ["traces"], "from": "2026-01-02T00:00:00Z", "to": "2026-01-01T00:00:00Z"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a reversed range, got %d", code)
+	}
+
+	// Bodies of recent traces go, the traces stay
+	code, result := purge(`{"scopes": ["bodies"], "from": "2026-01-02T00:00:00Z"}`)
+	if code != http.StatusOK || result.Bodies != 1 || result.Traces != 0 {
+		t.Errorf("Expected the body of one trace purged, got %d %+v", code, result)
+	}
+	if trace := handler.tracingService.GetTrace("recent"); trace.Request.Body != "" || trace.Response.StatusCode != 201 {
+		t.Errorf("Expected only the body removed, got %+v", trace)
+	}
+	if trace := handler.tracingService.GetTrace("old"); trace.Request.Body != "card=4111" {
+		t.Errorf("Expected the old trace kept whole, got %+v", trace)
+	}
+
+	// Traces in range go; a range keeps the statistics totals
+	_, result = purge(`{"scopes": ["traces", "stats"], "to": "2026-01-01T23:59:59Z"}`)
+	if result.Traces != 1 || result.StatsReset || handler.tracingService.GetTrace("old") != nil || handler.tracingService.GetTrace("recent") == nil {
+		t.Errorf("Expected the old trace purged, got %+v", result)
+	}
+	if stats := handler.statsCollector.GetGlobalStats(0, 0); stats.TotalRequests != 1 || len(stats.RecentErrors) != 1 {
+		t.Errorf("Expected the statistics kept, got %+v", stats)
+	}
+
+	_, result = purge(`{"scopes": ["all"]}`)
+	if result.Traces != 1 || !result.StatsReset || result.Errors != 1 || len(result.Scopes) != 2 {
+		t.Errorf("Expected everything purged, got %+v", result)
+	}
+	if stats := handler.statsCollector.GetGlobalStats(0, 0); stats.TotalRequests != 0 || len(handler.tracingService.GetTraces(nil)) != 0 {
+		t.Errorf("Expected nothing left, got %+v", stats)
+	}
+}
+
+func TestStatefulSpec(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/users", FullPath: "/api/users"})
+
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.GET("/specs/:id/state", handler.GetState)
+	r.POST("/specs/:id/state/reset", handler.ResetState)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/specs/spec-1/state/reset", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 before stateful mode is enabled, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-1", `{"stateful": {"enabled": true, "seed": {"users": "missing.json"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a missing seed file, got %d", w.Code)
+	}
+	if w := do("PUT", "/specs/spec-1", `{"stateful": {"enabled": true, "relations": [{"collection": "orders", "field": "userId"}]}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an incomplete relation, got %d", w.Code)
+	}
+
+	seed := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(seed, []byte(`[{"id": 1, "name": "Ann"}]`), 0644)
+	body, _ := json.Marshal(map[string]interface{}{"stateful": models.StatefulPolicy{Enabled: true, Seed: map[string]string{"users": seed}}})
+	if w := do("PUT", "/specs/spec-1", string(body)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a seed file outside the spec's files, got %d", w.Code)
+	}
+
+	store.SaveSpecFile("spec-1", "users.json", []byte(`[{"id": 1, "name": "Ann"}]`))
+	if w := do("PUT", "/specs/spec-1", `{"stateful": {"enabled": true, "seed": {"users": "users.json"}}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	handler.proxyEngine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name": "Bob"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var state map[string][]map[string]interface{}
+	json.Unmarshal(do("GET", "/specs/spec-1/state", "").Body.Bytes(), &state)
+	if len(state["users"]) != 2 {
+		t.Errorf("Expected two users, got %v", state)
+	}
+
+	if w := do("POST", "/specs/spec-1/state/reset", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	json.Unmarshal(do("GET", "/specs/spec-1/state", "").Body.Bytes(), &state)
+	if len(state["users"]) != 1 {
+		t.Errorf("Expected the seed after a reset, got %v", state)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", Enabled: true})
+
+	r.GET("/specs/:id/variables", handler.ListVariables)
+	r.PUT("/specs/:id/variables/:key", handler.SetVariable)
+	r.DELETE("/specs/:id/variables/:key", handler.DeleteVariable)
+	r.DELETE("/specs/:id/variables", handler.ClearVariables)
+
+	jsonBody, _ := json.Marshal(map[string]string{"value": "order-1"})
+	req := httptest.NewRequest("PUT", "/specs/spec-1/variables/orderId", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if value, _ := handler.proxyEngine.Variables().Get("spec-1", "orderId"); value != "order-1" {
+		t.Errorf("Expected variable to be set, got %q", value)
+	}
+
+	req = httptest.NewRequest("GET", "/specs/spec-1/variables", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var vars map[string]string
+	json.Unmarshal(w.Body.Bytes(), &vars)
+	if vars["orderId"] != "order-1" {
+		t.Errorf("Unexpected variables: %v", vars)
+	}
+
+	req = httptest.NewRequest("DELETE", "/specs/spec-1/variables/orderId", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	for _, tc := range []struct{ method, target string }{
+		{"GET", "/specs/missing/variables"},
+		{"DELETE", "/specs/missing/variables/orderId"},
+		{"DELETE", "/specs/missing/variables"},
+	} {
+		req = httptest.NewRequest(tc.method, tc.target, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "Spec not found") {
+			t.Errorf("%s %s: expected status 404 for a missing spec, got %d %s", tc.method, tc.target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestUpdateSpec_NotFound(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.PUT("/specs/:id", handler.UpdateSpec)
+
+	update := map[string]interface{}{"name": "New Name"}
+	jsonBody, _ := json.Marshal(update)
+
+	req := httptest.NewRequest("PUT", "/specs/nonexistent", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateSpecContent(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	r.POST("/specs", handler.CreateSpec)
+	r.PUT("/specs/:id/content", handler.UpdateSpecContent)
+
+	original := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: Success
+  /legacy:
+    get:
+      responses:
+        "200":
+          description: Success
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": original})
+	req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	specID := created["id"].(string)
+
+	// Attach response configs to both operations
+	ops, _ := store.GetOperationsBySpec(specID)
+	for _, op := range ops {
+		store.CreateResponseConfig(&models.ResponseConfig{ID: "resp" + op.Path, OperationID: op.ID, Enabled: true})
+	}
+
+	updated := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "2.0.0"
+paths:
+  /users:
+    get:
+      summary: List users
+      responses:
+        "200":
+          description: Success
+  /orders:
+    post:
+      responses:
+        "201":
+          description: Created
+`
+	jsonBody, _ = json.Marshal(map[string]string{"content": updated})
+	req = httptest.NewRequest("PUT", "/specs/"+specID+"/content", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result struct {
+		Version   string                    `json:"version"`
+		Unchanged int                       `json:"unchanged"`
+		Added     []models.OperationSummary `json:"added"`
+		Removed   []models.OperationSummary `json:"removed"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if result.Version != "2.0.0" {
+		t.Errorf("Expected version '2.0.0', got %q", result.Version)
+	}
+	if result.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged operation, got %d", result.Unchanged)
+	}
+	if len(result.Added) != 1 || result.Added[0].Path != "/orders" {
+		t.Errorf("Expected /orders to be added, got %+v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Path != "/legacy" {
+		t.Errorf("Expected /legacy to be removed, got %+v", result.Removed)
+	}
+
+	// Response config for the surviving operation is kept, the other one is gone
+	if _, err := store.GetResponseConfig("resp/users"); err != nil {
+		t.Error("Expected response config for /users to be preserved")
+	}
+	if _, err := store.GetResponseConfig("resp/legacy"); err == nil {
+		t.Error("Expected response config for /legacy to be deleted")
+	}
+
+	spec, _ := store.GetSpec(specID)
+	if spec.Content != updated {
+		t.Error("Expected spec content to be replaced")
+	}
+}
+
+func TestUpdateSpecContent_KeepsRateLimit(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	r.POST("/specs", handler.CreateSpec)
+	r.PUT("/specs/:id/content", handler.UpdateSpecContent)
+	r.PUT("/operations/:id/rate-limit", handler.SetRateLimit)
+
+	content := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: Success
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": content})
+	req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	specID := created["id"].(string)
+
+	ops, _ := store.GetOperationsBySpec(specID)
+	if len(ops) != 1 {
+		t.Fatalf("Expected 1 operation, got %d", len(ops))
+	}
+	opID := ops[0].ID
+
+	req = httptest.NewRequest("PUT", "/operations/"+opID+"/rate-limit", strings.NewReader(`{"limit": 5, "window": 60}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	jsonBody, _ = json.Marshal(map[string]string{"content": content})
+	req = httptest.NewRequest("PUT", "/specs/"+specID+"/content", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	op, err := store.GetOperation(opID)
+	if err != nil {
+		t.Fatalf("Expected operation to be kept: %v", err)
+	}
+	if op.RateLimit == nil || op.RateLimit.Limit != 5 || op.RateLimit.Window != 60 {
+		t.Errorf("Expected rate limit to survive the re-upload, got %+v", op.RateLimit)
+	}
+}
+
+func TestUpdateSpecContent_InvalidSpec(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1", Name: "API 1", Content: "original"}
+	store.CreateSpec(spec)
+
+	r.PUT("/specs/:id/content", handler.UpdateSpecContent)
+
+	jsonBody, _ := json.Marshal(map[string]string{"content": "not a valid spec"})
+	req := httptest.NewRequest("PUT", "/specs/spec-1/content", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	unchanged, _ := store.GetSpec("spec-1")
+	if unchanged.Content != "original" {
+		t.Error("Expected spec content to be left untouched")
+	}
+}
+
+func TestDeleteSpec(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
+	store.CreateSpec(spec)
+
+	// Add an operation
+	op := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"}
+	store.CreateOperation(op)
+
+	// Add a response config
+	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default"}
+	store.CreateResponseConfig(config)
+
+	r.DELETE("/specs/:id", handler.DeleteSpec)
+
+	req := httptest.NewRequest("DELETE", "/specs/spec-1", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Verify deletion
+	_, err := store.GetSpec("spec-1")
+	if err == nil {
+		t.Error("Expected spec to be deleted")
+	}
+
+	// Verify operations deleted
+	ops, _ := store.GetOperationsBySpec("spec-1")
+	if len(ops) != 0 {
+		t.Errorf("Expected operations to be deleted, got %d", len(ops))
+	}
+}
+
+func TestDeleteSpec_NotFound(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.DELETE("/specs/:id", handler.DeleteSpec)
+
+	req := httptest.NewRequest("DELETE", "/specs/nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGenerateResponses(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	r.POST("/specs", handler.CreateSpec)
+	r.POST("/specs/:id/generate-responses", handler.GenerateResponses)
+
+	specContent := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: Success
+          content:
+            application/json:
+              example:
+                users: []
+        "400":
+          description: Bad request
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": specContent})
+	req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	specID := created["id"].(string)
+
+	req = httptest.NewRequest("POST", "/specs/"+specID+"/generate-responses", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ops, _ := store.GetOperationsBySpec(specID)
+	configs, _ := store.GetResponseConfigsByOperation(ops[0].ID)
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 response configs, got %d", len(configs))
+	}
+	if configs[0].StatusCode != 200 || !configs[0].Enabled {
+		t.Errorf("Expected enabled 200 response first, got %d (enabled=%v)", configs[0].StatusCode, configs[0].Enabled)
+	}
+	if configs[0].Body != `{"users":[]}` {
+		t.Errorf("Expected example body, got %q", configs[0].Body)
+	}
+	if configs[1].StatusCode != 400 || configs[1].Enabled {
+		t.Errorf("Expected disabled 400 response second, got %d (enabled=%v)", configs[1].StatusCode, configs[1].Enabled)
+	}
+
+	// Running again must not duplicate configs
+	req = httptest.NewRequest("POST", "/specs/"+specID+"/generate-responses", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var result map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result["created"] != float64(0) || result["skipped"] != float64(2) {
+		t.Errorf("Expected 0 created and 2 skipped, got %v and %v", result["created"], result["skipped"])
+	}
+}
+
+func TestGenerateResponses_NotFound(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.POST("/specs/:id/generate-responses", handler.GenerateResponses)
+
+	req := httptest.NewRequest("POST", "/specs/nonexistent/generate-responses", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestListOperations(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
+	store.CreateSpec(spec)
+
+	op1 := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"}
+	op2 := &models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/users"}
+	store.CreateOperation(op1)
+	store.CreateOperation(op2)
+
+	r.GET("/specs/:id/operations", handler.ListOperations)
+
+	req := httptest.NewRequest("GET", "/specs/spec-1/operations", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var result []models.Operation
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if len(result) != 2 {
+		t.Errorf("Expected 2 operations, got %d", len(result))
+	}
+}
+
+func TestGetOperation(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1", Name: "API 1"}
+	store.CreateSpec(spec)
+
+	op := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", Summary: "List users"}
+	store.CreateOperation(op)
+
+	r.GET("/operations/:id", handler.GetOperation)
+
+	req := httptest.NewRequest("GET", "/operations/op-1", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var result models.Operation
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if result.ID != "op-1" {
+		t.Errorf("Expected id 'op-1', got %q", result.ID)
+	}
+	if result.Summary != "List users" {
+		t.Errorf("Expected summary 'List users', got %q", result.Summary)
+	}
+}
+
+func TestGetOperation_NotFound(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.GET("/operations/:id", handler.GetOperation)
+
+	req := httptest.NewRequest("GET", "/operations/nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestCreateOperation(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", BasePath: "/api", Enabled: true})
+
+	r.POST("/specs/:id/operations", handler.CreateOperation)
+
+	body := map[string]interface{}{"method": "post", "path": "/users/{id}/avatar", "summary": "Upload avatar"}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/specs/spec-1/operations", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var op models.Operation
+	json.Unmarshal(w.Body.Bytes(), &op)
+
+	if op.Method != "POST" {
+		t.Errorf("Expected method 'POST', got %q", op.Method)
+	}
+	if op.FullPath != "/api/users/{id}/avatar" {
+		t.Errorf("Expected full path '/api/users/{id}/avatar', got %q", op.FullPath)
+	}
+	if !op.Custom {
+		t.Error("Expected operation to be marked custom")
+	}
+
+	// The new route should be served
+	matched, _, _ := handler.proxyEngine.MatchRoute("POST", "/api/users/42/avatar")
+	if matched == nil || matched.ID != op.ID {
+		t.Error("Expected new operation to be routed")
+	}
+
+	// Adding the same route again conflicts
+	req = httptest.NewRequest("POST", "/specs/spec-1/operations", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+}
+
+func TestCreateOperation_InvalidMethod(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1"})
+
+	r.POST("/specs/:id/operations", handler.CreateOperation)
+
+	jsonBody, _ := json.Marshal(map[string]string{"method": "FETCH", "path": "/users"})
+	req := httptest.NewRequest("POST", "/specs/spec-1/operations", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestUpdateOperation(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, Enabled: true})
+
+	r.PUT("/specs/:id/operations/:opId", handler.UpdateOperation)
+
+	jsonBody, _ := json.Marshal(map[string]string{"method": "PUT", "path": "/members"})
+	req := httptest.NewRequest("PUT", "/specs/spec-1/operations/op-1", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The operation moves to the ID of its new method and path, taking its response configs along
+	if _, err := store.GetOperation("op-1"); err == nil {
+		t.Error("Expected the old operation ID to be gone")
+	}
+	id := parser.GenerateOperationID("spec-1", "PUT", "/members")
+	op, err := store.GetOperation(id)
+	if err != nil {
+		t.Fatalf("Expected the operation under %s: %v", id, err)
+	}
+	if op.Method != "PUT" || op.Path != "/members" || op.FullPath != "/members" {
+		t.Errorf("Expected PUT /members, got %s %s (%s)", op.Method, op.Path, op.FullPath)
+	}
+	if !op.Custom {
+		t.Error("Expected operation to be marked custom")
+	}
+	if responses, _ := store.GetResponseConfigsByOperation(id); len(responses) != 1 || responses[0].ID != "resp-1" {
+		t.Errorf("Expected the response config to move with the operation, got %v", responses)
+	}
+}
+
+func TestCreateResponseConfig_Pagination(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/users"})
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+
+	create := func(body string) int {
+		req := httptest.NewRequest("POST", "/operations/op-1/responses", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := create(`{"name": "List", "statusCode": 200, "pagination": {"mode": "page", "generate": 25, "itemTemplate": "{\"id\": {{item.number}}}"}}`); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+
+	for _, pagination := range []string{
+		`{"mode": "seek", "dataset": "[]"}`,
+		`{"mode": "page", "generate": 5}`,
+		`{"mode": "offset"}`,
+		`{"mode": "cursor", "dataset": "[]", "maxLimit": -1}`,
+	} {
+		if code := create(`{"name": "List", "statusCode": 200, "pagination": ` + pagination + `}`); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", pagination, code)
+		}
+	}
+}
+
+func TestCreateResponseConfig_SOAPFault(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/orders", FullPath: "/orders"})
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+
+	create := func(fault string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/operations/op-1/responses", strings.NewReader(`{"name": "Fault", "soapFault": `+fault+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without a status code, faults get the status of their SOAP binding
+	for fault, status := range map[string]int{
+		`{"string": "Internal error"}`:                      500,
+		`{"version": "1.2", "code": "Client"}`:              400,
+		`{"version": "1.2", "code": "DataEncodingUnknown"}`: 500,
+	} {
+		w := create(fault)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201 for %s, got %d", fault, w.Code)
+		}
+		var cfg models.ResponseConfig
+		json.Unmarshal(w.Body.Bytes(), &cfg)
+		if cfg.StatusCode != status || cfg.SOAPFault == nil {
+			t.Errorf("Expected status code %d for %s, got %d", status, fault, cfg.StatusCode)
+		}
+	}
+
+	for _, fault := range []string{
+		`{"version": "2.0"}`,
+		`{"code": "Timeout"}`,
+		`{"code": "DataEncodingUnknown"}`,
+	} {
+		if w := create(fault); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", fault, w.Code)
+		}
+	}
+}
+
+func TestResponseConfig_Fault(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/download", FullPath: "/download"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Fault: &models.ResponseFault{Type: models.FaultAbort}})
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+	r.PUT("/responses/:id", handler.UpdateResponseConfig)
+
+	do := func(method, target, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, fault := range []string{
+		`{"type": "abort", "fraction": 0.5}`,
+		`{"type": "trickle", "interval": 2000, "maxBytes": 10}`,
+	} {
+		if code := do("POST", "/operations/op-1/responses", `{"name": "Broken", "fault": `+fault+`}`); code != http.StatusCreated {
+			t.Errorf("Expected status 201 for %s, got %d", fault, code)
+		}
+	}
+	for _, fault := range []string{
+		`{"type": "explode"}`,
+		`{"type": "abort", "fraction": 1.5}`,
+		`{"type": "trickle", "interval": -1}`,
+	} {
+		if code := do("POST", "/operations/op-1/responses", `{"name": "Bad", "fault": `+fault+`}`); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", fault, code)
+		}
+	}
+
+	// An empty object removes the fault
+	if code := do("PUT", "/responses/resp-1", `{"fault": {}}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if cfg, _ := store.GetResponseConfig("resp-1"); cfg.Fault != nil {
+		t.Errorf("Expected the fault to be removed, got %+v", cfg.Fault)
+	}
+}
+
+func TestCreateResponseConfig_Events(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/orders", FullPath: "/orders"})
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+
+	create := func(events string) int {
+		req := httptest.NewRequest("POST", "/operations/op-1/responses", strings.NewReader(`{"name": "Created", "statusCode": 201, "events": `+events+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := create(`[{"type": "kafka", "topic": "orders", "payload": "{{body}}"}]`); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	if code := create(`[{"type": "mqtt", "topic": "devices/{{path.id}}/state", "broker": "tcp://localhost:1883", "qos": 1, "retain": true}]`); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	if code := create(`[{"type": "amqp", "exchange": "orders", "routingKey": "order.{{path.id}}.created", "headers": {"source": "mock"}}]`); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	for _, events := range []string{
+		`[{"type": "smtp", "topic": "orders"}]`,
+		`[{"type": "kafka"}]`,
+		`[{"type": "kafka", "channel": "orders", "delay": -1}]`,
+		`[{"type": "mqtt", "topic": "devices", "qos": 3}]`,
+		`[{"type": "mqtt", "topic": "devices/+/state"}]`,
+		`[{"type": "mqtt", "topic": "devices", "broker": "http://localhost:1883"}]`,
+		`[{"type": "amqp"}]`,
+		`[{"type": "amqp", "exchange": "orders", "topic": "orders"}]`,
+	} {
+		if code := create(events); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", events, code)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/users"})
+
+	r.PUT("/operations/:id/rate-limit", handler.SetRateLimit)
+	r.DELETE("/operations/:id/rate-limit", handler.DeleteRateLimit)
+
+	do := func(method, body string) int {
+		req := httptest.NewRequest(method, "/operations/op-1/rate-limit", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do("PUT", `{"limit": 10, "window": 60, "key": "header:X-API-Key"}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if op, _ := store.GetOperation("op-1"); op.RateLimit == nil || op.RateLimit.Limit != 10 {
+		t.Errorf("Expected the rate limit to be stored, got %+v", op.RateLimit)
+	}
+
+	for _, body := range []string{
+		`{"limit": 0, "window": 60}`,
+		`{"limit": 10, "window": 0}`,
+		`{"limit": 10, "window": 60, "key": "cookie"}`,
+	} {
+		if code := do("PUT", body); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, code)
+		}
+	}
+
+	if code := do("DELETE", ""); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if op, _ := store.GetOperation("op-1"); op.RateLimit != nil {
+		t.Error("Expected the rate limit to be removed")
+	}
+}
+
+func TestEcho(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/debug", FullPath: "/debug"})
+
+	r.PUT("/operations/:id/echo", handler.EnableEcho)
+	r.DELETE("/operations/:id/echo", handler.DisableEcho)
+
+	for _, tc := range []struct {
+		method string
+		echo   bool
+	}{{"PUT", true}, {"DELETE", false}} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, "/operations/op-1/echo", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if op, _ := store.GetOperation("op-1"); op.Echo != tc.echo {
+			t.Errorf("Expected echo %v after %s, got %v", tc.echo, tc.method, op.Echo)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/operations/missing/echo", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestForward(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/users"})
+
+	r.PUT("/specs/:id", handler.UpdateSpec)
+	r.PUT("/operations/:id/forward", handler.SetForward)
+	r.DELETE("/operations/:id/forward", handler.DeleteForward)
+
+	do := func(method, target, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Forwarding needs an upstream
+	if code := do("PUT", "/operations/op-1/forward", `{"mode": "unmatched"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an upstream, got %d", code)
+	}
+
+	for _, upstream := range []string{`"ftp://example.com"`, `"example.com"`, `"/api"`} {
+		if code := do("PUT", "/specs/spec-1", `{"upstream": `+upstream+`}`); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for upstream %s, got %d", upstream, code)
+		}
+	}
+	if code := do("PUT", "/specs/spec-1", `{"upstream": "https://api.example.com/v1"}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	rewrite := `{"upstreamRewrite": {"request": {"setHeaders": {"Authorization": "Bearer {{header.X-User}}"}, "removeBody": ["password"]}}}`
+	if code := do("PUT", "/specs/spec-1", rewrite); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.UpstreamRewrite == nil || spec.UpstreamRewrite.Request.RemoveBody[0] != "password" {
+		t.Errorf("Expected the rewrite to be stored, got %+v", spec.UpstreamRewrite)
+	}
+	for _, rewrite := range []string{
+		`{"request": {"setBody": {"items.#.id": "1"}}}`,
+		`{"response": {"removeBody": [""]}}`,
+		`{"response": {"removeHeaders": [" "]}}`,
+	} {
+		if code := do("PUT", "/specs/spec-1", `{"upstreamRewrite": `+rewrite+`}`); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", rewrite, code)
+		}
+	}
+	if code := do("PUT", "/specs/spec-1", `{"upstreamRewrite": {}}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.UpstreamRewrite != nil {
+		t.Errorf("Expected an empty rewrite to remove it, got %+v", spec.UpstreamRewrite)
+	}
+
+	if code := do("PUT", "/operations/op-1/forward", `{"mode": "always", "mockHeader": "X-Mock"}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if op, _ := store.GetOperation("op-1"); op.Forward == nil || op.Forward.Mode != models.ForwardAlways || op.Forward.MockHeader != "X-Mock" {
+		t.Errorf("Expected the forwarding policy to be stored, got %+v", op.Forward)
+	}
+	if code := do("PUT", "/operations/op-1/forward", `{"mode": "sometimes"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown mode, got %d", code)
+	}
+
+	if code := do("DELETE", "/operations/op-1/forward", ""); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if op, _ := store.GetOperation("op-1"); op.Forward != nil {
+		t.Error("Expected the forwarding policy to be removed")
+	}
+
+	if code := do("PUT", "/specs/spec-1", `{"upstream": ""}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec.Upstream != "" {
+		t.Errorf("Expected the upstream to be removed, got %q", spec.Upstream)
+	}
+}
+
+func TestDeleteOperation(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1"})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1"})
+
+	r.DELETE("/specs/:id/operations/:opId", handler.DeleteOperation)
+
+	// Wrong spec is treated as not found
+	req := httptest.NewRequest("DELETE", "/specs/spec-2/operations/op-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/specs/spec-1/operations/op-1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if _, err := store.GetOperation("op-1"); err == nil {
+		t.Error("Expected operation to be deleted")
+	}
+	if _, err := store.GetResponseConfig("resp-1"); err == nil {
+		t.Error("Expected response config to be deleted")
+	}
+}
+
+func TestDisableAndEnableOperation(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	handler.proxyEngine.ReloadRoutes()
+
+	r.PUT("/operations/:id/enable", handler.EnableOperation)
+	r.PUT("/operations/:id/disable", handler.DisableOperation)
+
+	req := httptest.NewRequest("PUT", "/operations/op-1/disable", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if op, _ := store.GetOperation("op-1"); !op.Disabled {
+		t.Error("Expected operation to be disabled")
+	}
+	if matched, _, _ := handler.proxyEngine.MatchRoute("GET", "/users"); matched != nil {
+		t.Error("Expected disabled operation not to be routed")
+	}
+
+	req = httptest.NewRequest("PUT", "/operations/op-1/enable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if op, _ := store.GetOperation("op-1"); op.Disabled {
+		t.Error("Expected operation to be enabled")
+	}
+	if matched, _, _ := handler.proxyEngine.MatchRoute("GET", "/users"); matched == nil {
+		t.Error("Expected enabled operation to be routed")
+	}
+
+	req = httptest.NewRequest("PUT", "/operations/nonexistent/disable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestTagBulkActions(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API 1", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/pets", Tags: []string{"pets"}})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/pets", Tags: []string{"pets", "admin"}})
+	store.CreateOperation(&models.Operation{ID: "op-3", SpecID: "spec-1", Method: "GET", Path: "/users", Tags: []string{"users"}})
+
+	r.GET("/specs/:id/tags", handler.ListTags)
+	r.GET("/specs/:id/operations", handler.ListOperations)
+	r.PUT("/specs/:id/tags/:tag/disable", handler.DisableTag)
+	r.PUT("/specs/:id/tags/:tag/tracing", handler.ToggleTagTracing)
+
+	req := httptest.NewRequest("GET", "/specs/spec-1/tags", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var tags []models.TagSummary
+	json.Unmarshal(w.Body.Bytes(), &tags)
+	if len(tags) != 3 || tags[1].Tag != "pets" || tags[1].OperationCount != 2 {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+
+	req = httptest.NewRequest("GET", "/specs/spec-1/operations?tag=pets", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var summaries []models.OperationSummary
+	json.Unmarshal(w.Body.Bytes(), &summaries)
+	if len(summaries) != 2 {
+		t.Errorf("Expected 2 operations tagged 'pets', got %d", len(summaries))
+	}
+
+	req = httptest.NewRequest("PUT", "/specs/spec-1/tags/pets/disable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	for id, disabled := range map[string]bool{"op-1": true, "op-2": true, "op-3": false} {
+		if op, _ := store.GetOperation(id); op.Disabled != disabled {
+			t.Errorf("Expected %s disabled=%v", id, disabled)
+		}
+	}
+
+	// Toggle without a body enables tracing
+	req = httptest.NewRequest("PUT", "/specs/spec-1/tags/admin/tracing", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if op, _ := store.GetOperation("op-2"); !op.Tracing {
+		t.Error("Expected tracing to be enabled for op-2")
+	}
+	if op, _ := store.GetOperation("op-1"); op.Tracing {
+		t.Error("Expected tracing to stay disabled for op-1")
+	}
+
+	req = httptest.NewRequest("PUT", "/specs/spec-1/tags/unknown/disable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown tag, got %d", w.Code)
+	}
+}
+
+func TestUpdateSpecContent_KeepsTagTracing(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	r.POST("/specs", handler.CreateSpec)
+	r.PUT("/specs/:id/content", handler.UpdateSpecContent)
+	r.PUT("/specs/:id/tags/:tag/tracing", handler.ToggleTagTracing)
+
+	content := `
+openapi: "3.0.0"
+info:
+  title: Test API
+  version: "1.0.0"
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      responses:
+        "200":
+          description: Success
+`
+	jsonBody, _ := json.Marshal(map[string]string{"content": content})
+	req := httptest.NewRequest("POST", "/specs", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	specID := created["id"].(string)
+
+	req = httptest.NewRequest("PUT", "/specs/"+specID+"/tags/pets/tracing", strings.NewReader(`{"enabled": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("PUT", "/specs/"+specID+"/content", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ops, _ := store.GetOperationsBySpec(specID)
+	if len(ops) != 1 || !ops[0].Tracing {
+		t.Errorf("Expected operation tracing to survive the re-upload, got %+v", ops)
+	}
+}
+
+func TestListResponseConfigs(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1"}
 	store.CreateSpec(spec)
 
 	op := &models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"}
@@ -457,35 +2486,326 @@ func TestCreateResponseConfig(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusCreated {
-		t.Errorf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		t.Errorf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result models.ResponseConfig
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if result.Name != "Success Response" {
+		t.Errorf("Expected name 'Success Response', got %q", result.Name)
+	}
+	if result.StatusCode != 200 {
+		t.Errorf("Expected status code 200, got %d", result.StatusCode)
+	}
+}
+
+func TestCreateResponseConfig_InvalidCondition(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+
+	body := map[string]interface{}{
+		"name": "Broken",
+		"conditions": []models.Condition{
+			{Source: "body", Key: "items.#(id==3.name", Operator: "exists"},
+		},
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/operations/op-1/responses", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "invalid body path") {
+		t.Errorf("Expected path error, got %s", w.Body.String())
+	}
+}
+
+func TestCreateResponseConfig_OperationNotFound(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+
+	body := map[string]interface{}{
+		"name":       "Success",
+		"statusCode": 200,
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/operations/nonexistent/responses", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetResponseConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default", StatusCode: 200}
+	store.CreateResponseConfig(config)
+
+	r.GET("/responses/:id", handler.GetResponseConfig)
+
+	req := httptest.NewRequest("GET", "/responses/config-1", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var result models.ResponseConfig
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if result.ID != "config-1" {
+		t.Errorf("Expected id 'config-1', got %q", result.ID)
+	}
+}
+
+func TestUpdateResponseConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1"}
+	store.CreateSpec(spec)
+
+	op := &models.Operation{ID: "op-1", SpecID: "spec-1"}
+	store.CreateOperation(op)
+
+	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Old Name", StatusCode: 200}
+	store.CreateResponseConfig(config)
+
+	r.PUT("/responses/:id", handler.UpdateResponseConfig)
+
+	update := map[string]interface{}{
+		"name":       "New Name",
+		"statusCode": 201,
+		"body":       `{"updated": true}`,
+	}
+	jsonBody, _ := json.Marshal(update)
+
+	req := httptest.NewRequest("PUT", "/responses/config-1", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Verify update
+	updatedConfig, _ := store.GetResponseConfig("config-1")
+	if updatedConfig.Name != "New Name" {
+		t.Errorf("Expected name 'New Name', got %q", updatedConfig.Name)
+	}
+	if updatedConfig.StatusCode != 201 {
+		t.Errorf("Expected status code 201, got %d", updatedConfig.StatusCode)
+	}
+}
+
+func TestUpdateResponseConfig_InvalidLeavesConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1"})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Old Name", StatusCode: 200})
+
+	r.PUT("/responses/:id", handler.UpdateResponseConfig)
+
+	for _, body := range []string{
+		`{"name": "New Name", "fault": {"type": "explode"}}`,
+		`{"name": "New Name", "fault": {"type": "abort"}, "events": [{"channel": ""}]}`,
+	} {
+		req := httptest.NewRequest("PUT", "/responses/config-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+
+		cfg, _ := store.GetResponseConfig("config-1")
+		if cfg.Name != "Old Name" || cfg.Fault != nil {
+			t.Errorf("Expected a rejected update to leave the config unchanged, got %+v", cfg)
+		}
+	}
+}
+
+func TestDeleteResponseConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	spec := &models.Spec{ID: "spec-1"}
+	store.CreateSpec(spec)
+
+	op := &models.Operation{ID: "op-1", SpecID: "spec-1"}
+	store.CreateOperation(op)
+
+	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default"}
+	store.CreateResponseConfig(config)
+
+	r.DELETE("/responses/:id", handler.DeleteResponseConfig)
+
+	req := httptest.NewRequest("DELETE", "/responses/config-1", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Verify deletion
+	_, err := store.GetResponseConfig("config-1")
+	if err == nil {
+		t.Error("Expected config to be deleted")
+	}
+}
+
+func TestEnableDisableResponseConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Enabled: true})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-2", OperationID: "op-1", Enabled: true, Priority: 1})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-3", OperationID: "op-1", Enabled: false, Priority: 2})
+
+	r.PUT("/responses/:id/enable", handler.EnableResponseConfig)
+	r.PUT("/responses/:id/disable", handler.DisableResponseConfig)
+	r.PUT("/operations/:id/responses/enable", handler.EnableOperationResponses)
+	r.PUT("/operations/:id/responses/disable", handler.DisableOperationResponses)
+
+	req := httptest.NewRequest("PUT", "/responses/resp-1/disable", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if cfg, _ := store.GetResponseConfig("resp-1"); cfg.Enabled {
+		t.Error("Expected resp-1 to be disabled")
+	}
+
+	req = httptest.NewRequest("PUT", "/responses/missing/enable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Bulk enable without a body affects all configs
+	req = httptest.NewRequest("PUT", "/operations/op-1/responses/enable", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var result map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result["updated"] != float64(2) {
+		t.Errorf("Expected 2 configs updated, got %v", result["updated"])
+	}
+
+	// Bulk disable limited to listed IDs
+	jsonBody, _ := json.Marshal(map[string][]string{"ids": {"resp-2", "resp-3"}})
+	req = httptest.NewRequest("PUT", "/operations/op-1/responses/disable", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	configs, _ := store.GetResponseConfigsByOperation("op-1")
+	for _, cfg := range configs {
+		if cfg.Enabled != (cfg.ID == "resp-1") {
+			t.Errorf("Unexpected enabled state for %s: %v", cfg.ID, cfg.Enabled)
+		}
+	}
+
+	// Unknown IDs are reported
+	jsonBody, _ = json.Marshal(map[string][]string{"ids": {"resp-9"}})
+	req = httptest.NewRequest("PUT", "/operations/op-1/responses/disable", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCloneResponseConfig(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/admins"})
+	source := &models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		Name:        "Premium user",
+		Priority:    3,
+		Conditions:  []models.Condition{{Source: "header", Key: "X-Tier", Operator: "eq", Value: "premium"}},
+		StatusCode:  200,
+		Headers:     map[string]string{"X-Id": "{{path.id}}"},
+		Body:        `{"tier": "premium"}`,
+		Enabled:     true,
+	}
+	store.CreateResponseConfig(source)
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-2", OperationID: "op-2", Priority: 5})
+
+	r.POST("/responses/:id/clone", handler.CloneResponseConfig)
+
+	// Clone onto the same operation without a body
+	req := httptest.NewRequest("POST", "/responses/resp-1/clone", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
 	}
 
-	var result models.ResponseConfig
-	json.Unmarshal(w.Body.Bytes(), &result)
+	var clone models.ResponseConfig
+	json.Unmarshal(w.Body.Bytes(), &clone)
 
-	if result.Name != "Success Response" {
-		t.Errorf("Expected name 'Success Response', got %q", result.Name)
+	if clone.ID == "resp-1" || clone.OperationID != "op-1" {
+		t.Errorf("Expected a new config on op-1, got %s on %s", clone.ID, clone.OperationID)
 	}
-	if result.StatusCode != 200 {
-		t.Errorf("Expected status code 200, got %d", result.StatusCode)
+	if clone.Name != "Premium user (copy)" || clone.Priority != 4 {
+		t.Errorf("Unexpected name/priority: %q/%d", clone.Name, clone.Priority)
+	}
+	if len(clone.Conditions) != 1 || clone.Headers["X-Id"] != "{{path.id}}" || clone.Body != source.Body {
+		t.Error("Expected conditions, headers and body to be copied")
 	}
-}
-
-func TestCreateResponseConfig_OperationNotFound(t *testing.T) {
-	handler, _, r := setupTestHandler(t)
 
-	r.POST("/operations/:id/responses", handler.CreateResponseConfig)
+	// Clone onto another operation with a new name
+	jsonBody, _ := json.Marshal(map[string]string{"operationId": "op-2", "name": "Admin variant"})
+	req = httptest.NewRequest("POST", "/responses/resp-1/clone", bytes.NewReader(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	body := map[string]interface{}{
-		"name":       "Success",
-		"statusCode": 200,
+	json.Unmarshal(w.Body.Bytes(), &clone)
+	if clone.OperationID != "op-2" || clone.Name != "Admin variant" || clone.Priority != 6 {
+		t.Errorf("Unexpected clone: %s %q %d", clone.OperationID, clone.Name, clone.Priority)
 	}
-	jsonBody, _ := json.Marshal(body)
 
-	req := httptest.NewRequest("POST", "/operations/nonexistent/responses", bytes.NewReader(jsonBody))
+	// Unknown target operation
+	jsonBody, _ = json.Marshal(map[string]string{"operationId": "missing"})
+	req = httptest.NewRequest("POST", "/responses/resp-1/clone", bytes.NewReader(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-
+	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusNotFound {
@@ -493,99 +2813,211 @@ func TestCreateResponseConfig_OperationNotFound(t *testing.T) {
 	}
 }
 
-func TestGetResponseConfig(t *testing.T) {
+func TestExportImportResponseConfigs(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
-	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default", StatusCode: 200}
-	store.CreateResponseConfig(config)
-
-	r.GET("/responses/:id", handler.GetResponseConfig)
-
-	req := httptest.NewRequest("GET", "/responses/config-1", nil)
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", OperationID: "listUsers"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-2", Method: "GET", Path: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		Name:        "Premium",
+		Priority:    1,
+		Conditions:  []models.Condition{{Source: "header", Key: "X-Tier", Operator: "eq", Value: "premium"}},
+		StatusCode:  200,
+		Headers:     map[string]string{"X-Tier": "premium"},
+		Body:        `{"tier": "premium"}`,
+		Enabled:     true,
+	})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-2", OperationID: "op-1", Name: "Default", StatusCode: 404, Enabled: true})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-3", OperationID: "op-2", Name: "Existing", Priority: 2})
+
+	r.GET("/operations/:id/responses/export", handler.ExportResponseConfigs)
+	r.POST("/operations/:id/responses/import", handler.ImportResponseConfigs)
+
+	req := httptest.NewRequest("GET", "/operations/op-1/responses/export", nil)
 	w := httptest.NewRecorder()
-
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "listUsers.responses.yaml") {
+		t.Errorf("Unexpected Content-Disposition: %s", w.Header().Get("Content-Disposition"))
+	}
+	exported := w.Body.Bytes()
+	if !bytes.Contains(exported, []byte("operator: eq")) {
+		t.Errorf("Expected YAML export, got:\n%s", exported)
 	}
 
-	var result models.ResponseConfig
-	json.Unmarshal(w.Body.Bytes(), &result)
+	// Append into another operation
+	req = httptest.NewRequest("POST", "/operations/op-2/responses/import", bytes.NewReader(exported))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	if result.ID != "config-1" {
-		t.Errorf("Expected id 'config-1', got %q", result.ID)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
 	}
-}
 
-func TestUpdateResponseConfig(t *testing.T) {
-	handler, store, r := setupTestHandler(t)
+	configs, _ := store.GetResponseConfigsByOperation("op-2")
+	if len(configs) != 3 {
+		t.Fatalf("Expected 3 configs after append, got %d", len(configs))
+	}
+	for _, cfg := range configs {
+		if cfg.Name == "Premium" {
+			if cfg.Priority != 4 || len(cfg.Conditions) != 1 || cfg.Headers["X-Tier"] != "premium" || !cfg.Enabled {
+				t.Errorf("Imported config not copied correctly: %+v", cfg)
+			}
+		}
+	}
 
-	spec := &models.Spec{ID: "spec-1"}
-	store.CreateSpec(spec)
+	// Replace using the JSON export
+	req = httptest.NewRequest("GET", "/operations/op-1/responses/export?format=json", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	op := &models.Operation{ID: "op-1", SpecID: "spec-1"}
-	store.CreateOperation(op)
+	req = httptest.NewRequest("POST", "/operations/op-2/responses/import?mode=replace", bytes.NewReader(w.Body.Bytes()))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Old Name", StatusCode: 200}
-	store.CreateResponseConfig(config)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	configs, _ = store.GetResponseConfigsByOperation("op-2")
+	if len(configs) != 2 {
+		t.Errorf("Expected 2 configs after replace, got %d", len(configs))
+	}
 
-	r.PUT("/responses/:id", handler.UpdateResponseConfig)
+	// Invalid document
+	req = httptest.NewRequest("POST", "/operations/op-2/responses/import", strings.NewReader("responses: ["))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	update := map[string]interface{}{
-		"name":       "New Name",
-		"statusCode": 201,
-		"body":       `{"updated": true}`,
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
 	}
-	jsonBody, _ := json.Marshal(update)
+}
 
-	req := httptest.NewRequest("PUT", "/responses/config-1", bytes.NewReader(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+func TestImportMockoon(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
 
+	r.POST("/specs/import/mockoon", handler.ImportMockoon)
+
+	env := `{
+  "name": "Shop",
+  "endpointPrefix": "shop",
+  "routes": [
+    {"type": "http", "method": "get", "endpoint": "products/:id", "responses": [
+      {"label": "Product", "statusCode": 200, "body": "{\"id\": \"{{urlParam 'id'}}\"}", "bodyType": "INLINE", "rules": [], "default": true},
+      {"label": "Bad regex", "statusCode": 400, "bodyType": "INLINE", "rules": [{"target": "query", "modifier": "q", "value": "(?<=a)b", "operator": "regex"}]}
+    ]}
+  ]
+}`
+	req := httptest.NewRequest("POST", "/specs/import/mockoon?name=Imported", strings.NewReader(env))
+	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
 	}
 
-	// Verify update
-	updatedConfig, _ := store.GetResponseConfig("config-1")
-	if updatedConfig.Name != "New Name" {
-		t.Errorf("Expected name 'New Name', got %q", updatedConfig.Name)
+	var result struct {
+		ID             string   `json:"id"`
+		Name           string   `json:"name"`
+		OperationCount int      `json:"operationCount"`
+		ResponseCount  int      `json:"responseCount"`
+		Warnings       []string `json:"warnings"`
 	}
-	if updatedConfig.StatusCode != 201 {
-		t.Errorf("Expected status code 201, got %d", updatedConfig.StatusCode)
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Name != "Imported" || result.OperationCount != 1 || result.ResponseCount != 1 || len(result.Warnings) != 1 {
+		t.Fatalf("Unexpected result: %s", w.Body.String())
+	}
+
+	spec, _ := store.GetSpec(result.ID)
+	if spec.BasePath != "/shop" {
+		t.Errorf("Expected base path /shop, got %q", spec.BasePath)
+	}
+	ops, _ := store.GetOperationsBySpec(result.ID)
+	configs, _ := store.GetResponseConfigsByOperation(ops[0].ID)
+	if len(configs) != 1 || configs[0].Body != `{"id": "{{path.id}}"}` {
+		t.Errorf("Unexpected response configs: %+v", configs)
+	}
+
+	req = httptest.NewRequest("POST", "/specs/import/mockoon", strings.NewReader(`{"name": "no routes"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
 	}
 }
 
-func TestDeleteResponseConfig(t *testing.T) {
+func TestImportWireMock(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
-	spec := &models.Spec{ID: "spec-1"}
-	store.CreateSpec(spec)
-
-	op := &models.Operation{ID: "op-1", SpecID: "spec-1"}
-	store.CreateOperation(op)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Pets", BasePath: "/api"})
+	store.CreateOperation(&models.Operation{ID: "op-get", SpecID: "spec-1", Method: "GET", Path: "/pets/{petId}"})
+	store.CreateOperation(&models.Operation{ID: "op-list", SpecID: "spec-1", Method: "GET", Path: "/pets"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-get", Name: "Existing", Priority: 3})
+
+	r.POST("/specs/:id/import/wiremock", handler.ImportWireMock)
+
+	mappings := `{
+  "mappings": [
+    {"request": {"method": "GET", "url": "/api/pets/42"}, "response": {"status": 200, "jsonBody": {"id": 42}}},
+    {"name": "Dogs", "request": {"method": "GET", "urlPath": "/api/pets", "queryParameters": {"kind": {"equalTo": "dog"}}}, "response": {"body": "[]"}},
+    {"request": {"method": "POST", "urlPath": "/api/orders"}, "response": {"status": 201}},
+    {"request": {"method": "GET", "urlPattern": "/api/things/.*"}, "response": {"status": 200}}
+  ]
+}`
+	req := httptest.NewRequest("POST", "/specs/spec-1/import/wiremock", strings.NewReader(mappings))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	config := &models.ResponseConfig{ID: "config-1", OperationID: "op-1", Name: "Default"}
-	store.CreateResponseConfig(config)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
 
-	r.DELETE("/responses/:id", handler.DeleteResponseConfig)
+	var result struct {
+		Imported   int                 `json:"imported"`
+		Operations []*models.Operation `json:"operations"`
+		Skipped    []map[string]string `json:"skipped"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Imported != 3 || len(result.Operations) != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("Unexpected result: %s", w.Body.String())
+	}
 
-	req := httptest.NewRequest("DELETE", "/responses/config-1", nil)
-	w := httptest.NewRecorder()
+	configs, _ := store.GetResponseConfigsByOperation("op-get")
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(configs))
+	}
+	imported := configs[1]
+	if imported.Priority != 4 || len(imported.Conditions) != 1 || imported.Conditions[0] != (models.Condition{Source: "path", Key: "petId", Operator: "eq", Value: "42"}) {
+		t.Errorf("Path parameter not converted: %+v", imported)
+	}
 
-	r.ServeHTTP(w, req)
+	configs, _ = store.GetResponseConfigsByOperation("op-list")
+	if len(configs) != 1 || configs[0].Name != "Dogs" || configs[0].Conditions[0].Source != "query" {
+		t.Errorf("Query matcher not converted: %+v", configs)
+	}
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
+	op := result.Operations[0]
+	if op.Method != "POST" || op.Path != "/orders" || op.FullPath != "/api/orders" || !op.Custom {
+		t.Errorf("Unexpected created operation: %+v", op)
+	}
+	configs, _ = store.GetResponseConfigsByOperation(op.ID)
+	if len(configs) != 1 || configs[0].StatusCode != 201 {
+		t.Errorf("Expected the created operation to get the stub's response, got %+v", configs)
 	}
 
-	// Verify deletion
-	_, err := store.GetResponseConfig("config-1")
-	if err == nil {
-		t.Error("Expected config to be deleted")
+	// Invalid document
+	req = httptest.NewRequest("POST", "/specs/spec-1/import/wiremock", strings.NewReader("{"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
 	}
 }
 
@@ -682,6 +3114,67 @@ func TestHealthCheck(t *testing.T) {
 	}
 }
 
+func TestReadinessCheck(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+
+	r.GET("/health/ready", handler.ReadinessCheck)
+
+	check := func(expected int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+		if w.Code != expected {
+			t.Errorf("Expected status %d, got %d", expected, w.Code)
+		}
+	}
+
+	// Not ready until the server says so
+	check(http.StatusServiceUnavailable)
+
+	handler.SetReady(true)
+	check(http.StatusOK)
+
+	handler.SetReady(false)
+	check(http.StatusServiceUnavailable)
+}
+
+func TestDrainEndpoints(t *testing.T) {
+	handler, _, r := setupTestHandler(t)
+	handler.SetReady(true)
+
+	r.GET("/health/ready", handler.ReadinessCheck)
+	r.GET("/drain", handler.GetDrainStatus)
+	r.POST("/drain", handler.StartDrain)
+	r.DELETE("/drain", handler.CancelDrain)
+
+	do := func(method, target string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var result map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	if code, _ := do("POST", "/drain?gracePeriod=soon"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid grace period, got %d", code)
+	}
+
+	code, result := do("POST", "/drain?gracePeriod=1h")
+	if code != http.StatusOK || result["draining"] != true || result["rejecting"] != false {
+		t.Errorf("Expected draining within the grace period, got %d %v", code, result)
+	}
+	if code, result := do("GET", "/health/ready"); code != http.StatusServiceUnavailable || result["status"] != "draining" {
+		t.Errorf("Expected readiness to fail while draining, got %d %v", code, result)
+	}
+
+	if code, result := do("DELETE", "/drain"); code != http.StatusOK || result["draining"] != false {
+		t.Errorf("Expected the drain to be cancelled, got %d %v", code, result)
+	}
+	if code, _ := do("GET", "/health/ready"); code != http.StatusOK {
+		t.Errorf("Expected ready after cancelling the drain, got %d", code)
+	}
+}
+
 func TestGetRoutes(t *testing.T) {
 	handler, store, r := setupTestHandler(t)
 
@@ -812,3 +3305,283 @@ func TestToggleExampleFallback(t *testing.T) {
 		t.Error("Expected example fallback to be disabled")
 	}
 }
+
+func TestExportSpec(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users", Content: "openapi: 3.0.0"})
+
+	r.GET("/export", handler.ExportAll)
+	r.GET("/specs/:id/export", handler.ExportSpec)
+
+	for _, path := range []string{"/export", "/specs/spec-1/export"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "application/gzip" {
+			t.Errorf("GET %s: unexpected Content-Type %q", path, w.Header().Get("Content-Type"))
+		}
+		if !strings.HasSuffix(w.Header().Get("Content-Disposition"), `.tar.gz"`) {
+			t.Errorf("GET %s: unexpected Content-Disposition %q", path, w.Header().Get("Content-Disposition"))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/specs/missing/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestExportSpec_Formats(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users"})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Name: "All", StatusCode: 200, Body: "[]", Enabled: true})
+
+	r.GET("/specs/:id/export", handler.ExportSpec)
+
+	req := httptest.NewRequest("GET", "/specs/spec-1/export?format=wiremock", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var mappings struct {
+		Mappings []map[string]interface{} `json:"mappings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &mappings)
+	if w.Code != http.StatusOK || len(mappings.Mappings) != 1 {
+		t.Fatalf("Expected one WireMock mapping, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasSuffix(w.Header().Get("Content-Disposition"), `.wiremock.json"`) {
+		t.Errorf("Unexpected Content-Disposition %q", w.Header().Get("Content-Disposition"))
+	}
+
+	req = httptest.NewRequest("GET", "/specs/spec-1/export?format=mockoon", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var env struct {
+		Name   string                   `json:"name"`
+		Routes []map[string]interface{} `json:"routes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &env)
+	if w.Code != http.StatusOK || env.Name != "Users" || len(env.Routes) != 1 || env.Routes[0]["endpoint"] != "users" {
+		t.Fatalf("Unexpected Mockoon environment (%d): %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/specs/spec-1/export?format=postman", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", w.Code)
+	}
+}
+
+func TestExportOpenAPI(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+
+	content := "openapi: 3.0.0\ninfo:\n  title: Users\n  version: 1.0.0\npaths:\n  /users:\n    get:\n      responses:\n        '200':\n          description: Users\n"
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users", Content: content})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Name: "Empty", StatusCode: 200, Body: "[]", Enabled: true})
+
+	r.GET("/specs/:id/export-openapi", handler.ExportOpenAPI)
+
+	req := httptest.NewRequest("GET", "/specs/spec-1/export-openapi", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-yaml" {
+		t.Fatalf("Expected a YAML document, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "x-mock:") || !strings.Contains(w.Body.String(), "empty:") {
+		t.Errorf("Expected the response config in the document, got:\n%s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/specs/spec-1/export-openapi?format=json", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc["openapi"] != "3.0.0" {
+		t.Errorf("Expected a JSON document, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/specs/missing/export-openapi":           http.StatusNotFound,
+		"/specs/spec-1/export-openapi?format=xml": http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestOrphansEndpoints(t *testing.T) {
+	handler, store, r := setupTestHandler(t)
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "rc-orphan", OperationID: "missing-op"})
+
+	r.GET("/storage/orphans", handler.GetOrphans)
+	r.DELETE("/storage/orphans", handler.DeleteOrphans)
+
+	for _, method := range []string{"GET", "DELETE"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/storage/orphans", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", method, w.Code)
+		}
+		var report storage.OrphanReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if len(report.ResponseConfigs) != 1 || report.Removed != (method == "DELETE") {
+			t.Errorf("%s: unexpected report %+v", method, report)
+		}
+	}
+
+	if _, err := store.GetResponseConfig("rc-orphan"); err == nil {
+		t.Error("Expected orphaned response config to be deleted")
+	}
+}
+
+func TestClusterNotifier(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	collector := stats.NewCollector()
+	tracingSvc := tracing.NewService(100)
+	router := NewRouter(store, collector, tracingSvc, proxy.NewEngine(store, collector, tracingSvc))
+
+	node, err := cluster.New(t.TempDir(), "node-a", time.Second, func() error { return nil })
+	if err != nil {
+		t.Fatalf("cluster.New failed: %v", err)
+	}
+	router.SetCluster(node)
+
+	do := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	do("GET", "/_api/specs", "")
+	do("PUT", "/_api/specs/missing", `{"name": "x"}`) // Fails with 404
+	do("POST", "/_api/stats/reset", "")
+	if node.Status().LastChange != nil {
+		t.Fatal("Expected reads, failures and local changes not to be announced")
+	}
+
+	store.CreateSpec(&models.Spec{ID: "spec-1"})
+	do("PUT", "/_api/specs/spec-1", `{"name": "renamed"}`)
+	if change := node.Status().LastChange; change == nil || change.NodeID != "node-a" {
+		t.Errorf("Expected the spec change to be announced, got %+v", change)
+	}
+}
+
+// recordingBroadcaster collects the purges passed on to other nodes
+type recordingBroadcaster struct {
+	purges []*models.PurgeRequest
+	err    error
+}
+
+func (b *recordingBroadcaster) BroadcastPurge(req *models.PurgeRequest) error {
+	b.purges = append(b.purges, req)
+	return b.err
+}
+
+func TestPurgeBroadcast(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	collector := stats.NewCollector()
+	tracingSvc := tracing.NewService(100)
+	router := NewRouter(store, collector, tracingSvc, proxy.NewEngine(store, collector, tracingSvc))
+
+	broadcaster := &recordingBroadcaster{}
+	router.SetPurgeBroadcaster(broadcaster)
+
+	purge := func() models.PurgeResult {
+		req := httptest.NewRequest("POST", "/_api/purge", strings.NewReader(`{"scopes": ["traces"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.Handler().ServeHTTP(w, req)
+		var result models.PurgeResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	tracingSvc.RecordTrace(&models.Trace{ID: "t1"})
+	if result := purge(); !result.Broadcast || result.Traces != 1 || len(broadcaster.purges) != 1 {
+		t.Errorf("Expected the purge to be passed on, got %+v", result)
+	}
+
+	broadcaster.err = errors.New("redis down")
+	if result := purge(); result.Broadcast || result.BroadcastError != "redis down" {
+		t.Errorf("Expected the broadcast failure to be reported, got %+v", result)
+	}
+
+	// Purges from other nodes apply to this node's data
+	tracingSvc.RecordRemoteTrace(&models.Trace{ID: "t2", Node: "node-b"})
+	router.PurgeRemote(&models.PurgeRequest{Scopes: []string{models.PurgeAll}})
+	if traces := tracingSvc.GetTraces(nil); len(traces) != 0 {
+		t.Errorf("Expected the remote purge to remove the traces, got %d", len(traces))
+	}
+	router.PurgeRemote(&models.PurgeRequest{Scopes: []string{"recordings"}}) // Ignored
+	if len(broadcaster.purges) != 2 {
+		t.Errorf("Expected remote purges not to be passed on again, got %d", len(broadcaster.purges))
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	collector := stats.NewCollector()
+	tracingSvc := tracing.NewService(100)
+	router := NewRouter(store, collector, tracingSvc, proxy.NewEngine(store, collector, tracingSvc))
+	router.SetReadOnly(true)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "original"})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tc := range []struct{ method, target string }{
+		{"POST", "/_api/specs"},
+		{"PUT", "/_api/specs/spec-1"},
+		{"DELETE", "/_api/specs/spec-1"},
+		{"POST", "/_api/stats/reset"},
+		{"DELETE", "/_api/traces"},
+		{"POST", "/_api/purge"},
+	} {
+		w := do(tc.method, tc.target, `{"name": "changed"}`)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected status 405, got %d", tc.method, tc.target, w.Code)
+		}
+	}
+	if spec, _ := store.GetSpec("spec-1"); spec == nil || spec.Name != "original" {
+		t.Errorf("Expected spec to be unchanged, got %+v", spec)
+	}
+
+	if w := do("GET", "/_api/specs", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected reads to be allowed, got %d", w.Code)
+	}
+	if w := do("POST", "/_api/specs/validate", `{"content": "openapi: 3.0.0"}`); w.Code == http.StatusMethodNotAllowed {
+		t.Error("Expected validation to be allowed")
+	}
+	if w := do("POST", "/anything", ""); w.Code == http.StatusMethodNotAllowed {
+		t.Error("Expected mock traffic to be unaffected")
+	}
+
+	router.SetReadOnly(false)
+	if w := do("PUT", "/_api/specs/spec-1", `{"name": "changed"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected changes to be allowed again, got %d", w.Code)
+	}
+}
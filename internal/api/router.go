@@ -2,13 +2,21 @@ package api
 
 import (
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prasenjit/go-virtual/internal/cluster"
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/oauth"
 	"github.com/prasenjit/go-virtual/internal/proxy"
+	"github.com/prasenjit/go-virtual/internal/requestid"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
 	"github.com/prasenjit/go-virtual/internal/tracing"
@@ -22,6 +30,7 @@ type Router struct {
 	tracingService *tracing.Service
 	proxyEngine    *proxy.Engine
 	handler        *Handler
+	readOnly       atomic.Bool
 }
 
 // NewRouter creates a new router
@@ -41,8 +50,10 @@ func NewRouter(store storage.Storage, statsCollector *stats.Collector, tracingSe
 
 	// Setup middleware
 	r.engine.Use(gin.Recovery())
+	r.engine.Use(requestLogger())
 	r.engine.Use(corsMiddleware())
-	r.engine.Use(gin.Logger())
+	r.engine.Use(r.readOnlyGuard())
+	r.engine.Use(r.clusterNotifier())
 
 	// Setup routes
 	r.setupRoutes()
@@ -58,29 +69,89 @@ func (r *Router) setupRoutes() {
 		// Specs
 		api.GET("/specs", r.handler.ListSpecs)
 		api.POST("/specs", r.handler.CreateSpec)
+		api.POST("/specs/validate", r.handler.ValidateSpec)
+		api.POST("/specs/import/mockoon", r.handler.ImportMockoon)
 		api.GET("/specs/:id", r.handler.GetSpec)
+		api.GET("/specs/:id/export", r.handler.ExportSpec)
+		api.GET("/specs/:id/export-openapi", r.handler.ExportOpenAPI)
 		api.PUT("/specs/:id", r.handler.UpdateSpec)
+		api.PUT("/specs/:id/content", r.handler.UpdateSpecContent)
 		api.DELETE("/specs/:id", r.handler.DeleteSpec)
 		api.PUT("/specs/:id/enable", r.handler.EnableSpec)
 		api.PUT("/specs/:id/disable", r.handler.DisableSpec)
 		api.PUT("/specs/:id/tracing", r.handler.ToggleTracing)
 		api.PUT("/specs/:id/example-fallback", r.handler.ToggleExampleFallback)
+		api.GET("/specs/:id/snippets", r.handler.ListSnippets)
+		api.PUT("/specs/:id/snippets/:name", r.handler.SetSnippet)
+		api.DELETE("/specs/:id/snippets/:name", r.handler.DeleteSnippet)
+		api.GET("/specs/:id/files", r.handler.ListSpecFiles)
+		api.PUT("/specs/:id/files/:name", r.handler.UploadSpecFile)
+		api.GET("/specs/:id/files/:name", r.handler.DownloadSpecFile)
+		api.DELETE("/specs/:id/files/:name", r.handler.DeleteSpecFile)
+		api.PUT("/specs/:id/asyncapi", r.handler.SetAsyncAPI)
+		api.DELETE("/specs/:id/asyncapi", r.handler.DeleteAsyncAPI)
+		api.GET("/specs/:id/channels", r.handler.ListChannels)
+		api.GET("/specs/:id/state", r.handler.GetState)
+		api.POST("/specs/:id/state/reset", r.handler.ResetState)
+		api.GET("/specs/:id/coverage", r.handler.GetCoverage)
+		api.GET("/specs/:id/violations", r.handler.GetContractViolations)
+		api.DELETE("/specs/:id/violations", r.handler.ClearContractViolations)
+		api.GET("/specs/:id/variables", r.handler.ListVariables)
+		api.DELETE("/specs/:id/variables", r.handler.ClearVariables)
+		api.PUT("/specs/:id/variables/:key", r.handler.SetVariable)
+		api.DELETE("/specs/:id/variables/:key", r.handler.DeleteVariable)
+		api.POST("/specs/:id/generate-responses", r.handler.GenerateResponses)
+		api.POST("/specs/:id/import/wiremock", r.handler.ImportWireMock)
+
+		// Groups
+		api.GET("/groups", r.handler.ListGroups)
+		api.PUT("/groups/:group/enable", r.handler.EnableGroup)
+		api.PUT("/groups/:group/disable", r.handler.DisableGroup)
+
+		// Tags
+		api.GET("/specs/:id/tags", r.handler.ListTags)
+		api.PUT("/specs/:id/tags/:tag/enable", r.handler.EnableTag)
+		api.PUT("/specs/:id/tags/:tag/disable", r.handler.DisableTag)
+		api.PUT("/specs/:id/tags/:tag/tracing", r.handler.ToggleTagTracing)
+		api.POST("/specs/:id/tags/:tag/generate-responses", r.handler.GenerateTagResponses)
 
 		// Operations
 		api.GET("/specs/:id/operations", r.handler.ListOperations)
+		api.POST("/specs/:id/operations", r.handler.CreateOperation)
+		api.PUT("/specs/:id/operations/:opId", r.handler.UpdateOperation)
+		api.DELETE("/specs/:id/operations/:opId", r.handler.DeleteOperation)
 		api.GET("/operations/:id", r.handler.GetOperation)
+		api.PUT("/operations/:id/enable", r.handler.EnableOperation)
+		api.PUT("/operations/:id/disable", r.handler.DisableOperation)
+		api.PUT("/operations/:id/rate-limit", r.handler.SetRateLimit)
+		api.DELETE("/operations/:id/rate-limit", r.handler.DeleteRateLimit)
+		api.PUT("/operations/:id/forward", r.handler.SetForward)
+		api.DELETE("/operations/:id/forward", r.handler.DeleteForward)
+		api.PUT("/operations/:id/echo", r.handler.EnableEcho)
+		api.DELETE("/operations/:id/echo", r.handler.DisableEcho)
 
 		// Response Configs
 		api.GET("/operations/:id/responses", r.handler.ListResponseConfigs)
 		api.POST("/operations/:id/responses", r.handler.CreateResponseConfig)
+		api.GET("/operations/:id/responses/export", r.handler.ExportResponseConfigs)
+		api.POST("/operations/:id/responses/import", r.handler.ImportResponseConfigs)
+		api.PUT("/operations/:id/responses/enable", r.handler.EnableOperationResponses)
+		api.PUT("/operations/:id/responses/disable", r.handler.DisableOperationResponses)
 		api.GET("/responses/:id", r.handler.GetResponseConfig)
 		api.PUT("/responses/:id", r.handler.UpdateResponseConfig)
 		api.DELETE("/responses/:id", r.handler.DeleteResponseConfig)
 		api.PUT("/responses/:id/priority", r.handler.UpdateResponsePriority)
+		api.PUT("/responses/:id/enable", r.handler.EnableResponseConfig)
+		api.PUT("/responses/:id/disable", r.handler.DisableResponseConfig)
+		api.POST("/responses/:id/clone", r.handler.CloneResponseConfig)
 
 		// Statistics
 		api.GET("/stats", r.handler.GetGlobalStats)
+		api.GET("/stats/snapshot", r.handler.GetStatsSnapshot)
+		api.GET("/stats/stream", r.handler.StreamStats)
+		api.GET("/stats/alerts", r.handler.ListAlerts)
 		api.GET("/stats/specs/:id", r.handler.GetSpecStats)
+		api.GET("/stats/groups/:group", r.handler.GetGroupStats)
 		api.GET("/stats/operations/:id", r.handler.GetOperationStats)
 		api.POST("/stats/reset", r.handler.ResetStats)
 
@@ -89,11 +160,34 @@ func (r *Router) setupRoutes() {
 		api.GET("/traces/:id", r.handler.GetTrace)
 		api.DELETE("/traces", r.handler.ClearTraces)
 
+		// Purge
+		api.POST("/purge", r.handler.Purge)
+
+		// Search
+		api.GET("/search", r.handler.Search)
+
 		// Routes info
 		api.GET("/routes", r.handler.GetRoutes)
 
+		// Export
+		api.GET("/export", r.handler.ExportAll)
+
+		// Storage maintenance
+		api.GET("/storage/orphans", r.handler.GetOrphans)
+		api.DELETE("/storage/orphans", r.handler.DeleteOrphans)
+
 		// Health
 		api.GET("/health", r.handler.HealthCheck)
+		api.GET("/health/live", r.handler.HealthCheck)
+		api.GET("/health/ready", r.handler.ReadinessCheck)
+
+		// Cluster
+		api.GET("/cluster", r.handler.GetClusterStatus)
+
+		// Drain
+		api.GET("/drain", r.handler.GetDrainStatus)
+		api.POST("/drain", r.handler.StartDrain)
+		api.DELETE("/drain", r.handler.CancelDrain)
 	}
 
 	// WebSocket for live tracing
@@ -180,9 +274,174 @@ func (r *Router) Handler() http.Handler {
 	return r.engine
 }
 
-// corsMiddleware adds CORS headers
+// SetReady sets what /_api/health/ready reports
+func (r *Router) SetReady(ready bool) {
+	r.handler.SetReady(ready)
+}
+
+// CloseStreams ends the open event streams of the admin API
+func (r *Router) CloseStreams() {
+	r.handler.CloseStreams()
+}
+
+// SpecEvicted stops serving a spec the storage evicted to make room and clears what was
+// recorded for it, as deleting it through the admin API does
+func (r *Router) SpecEvicted(specID string) {
+	slog.Warn("spec evicted to stay within the storage limits", "specId", specID)
+	r.handler.forgetSpec(specID)
+}
+
+// SetPurgeBroadcaster passes purges on to the other nodes, or nil to keep them local
+func (r *Router) SetPurgeBroadcaster(b PurgeBroadcaster) {
+	r.handler.purges = b
+}
+
+// PurgeRemote applies a purge made on another node to the data captured on this node
+func (r *Router) PurgeRemote(req *models.PurgeRequest) {
+	if err := validatePurge(req); err != nil {
+		slog.Warn("ignoring invalid purge from another node", "error", err)
+		return
+	}
+	result := r.handler.purge(req)
+	slog.Info("purged captured data for another node", "scopes", result.Scopes, "from", req.From, "to", req.To,
+		"traces", result.Traces, "bodies", result.Bodies, "errors", result.Errors, "statsReset", result.StatsReset)
+}
+
+// SetCluster enables cluster mode: successful admin API changes are announced to the other nodes
+func (r *Router) SetCluster(node *cluster.Node) {
+	r.handler.cluster = node
+}
+
+// SetOAuth mounts a mock authorization server at oauth.PathPrefix
+func (r *Router) SetOAuth(server *oauth.Server) {
+	r.engine.Any(oauth.PathPrefix+"/*path", gin.WrapH(server))
+}
+
+// SetReadOnly rejects admin API changes with 405 Method Not Allowed when enabled
+// Mock traffic, reads and draining are unaffected
+func (r *Router) SetReadOnly(readOnly bool) {
+	r.readOnly.Store(readOnly)
+}
+
+// readOnlyAllowed are admin API routes that stay available in read-only mode
+// besides reads; none of them changes specs, operations or response configs
+var readOnlyAllowed = []string{
+	"/_api/specs/validate",
+	"/_api/drain",
+}
+
+// readOnlyGuard rejects admin API changes in read-only mode
+func (r *Router) readOnlyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if !r.readOnly.Load() || !strings.HasPrefix(route, "/_api/") {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		for _, allowed := range readOnlyAllowed {
+			if route == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("Allow", "GET, HEAD")
+		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{"error": "Server is in read-only mode"})
+	}
+}
+
+// localOnlyRoutes are admin API route prefixes whose changes don't touch the shared storage
+var localOnlyRoutes = []string{
+	"/_api/specs/validate",
+	"/_api/specs/:id/variables",
+	"/_api/specs/:id/state",
+	"/_api/specs/:id/violations",
+	"/_api/stats",
+	"/_api/traces",
+	"/_api/purge",
+	"/_api/drain",
+}
+
+// clusterNotifier announces successful admin API changes to the other cluster nodes
+func (r *Router) clusterNotifier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		node := r.handler.cluster
+		route := c.FullPath()
+		if node == nil || !strings.HasPrefix(route, "/_api/") || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		for _, prefix := range localOnlyRoutes {
+			if strings.HasPrefix(route, prefix) {
+				return
+			}
+		}
+
+		if err := node.Changed(); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to announce cluster change", "error", err)
+		}
+	}
+}
+
+// requestLogger assigns a request ID, attaches a request-scoped logger to the request context and logs each
+// request when it completes. Errors recorded with c.Error are included and raise the level
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// Honor an incoming request ID, or assign one, and echo it in the response
+		id := requestid.FromHeader(c.Request.Header)
+		c.Header(requestid.Header, id)
+
+		logger := slog.Default().With(
+			"requestId", id,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"remoteAddr", c.ClientIP(),
+		)
+		ctx := requestid.WithID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"status", status,
+			"duration", time.Since(start),
+			"bytes", c.Writer.Size(),
+		}
+
+		level := slog.LevelInfo
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", strings.Join(c.Errors.Errors(), "; "))
+			level = slog.LevelError
+		} else if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// corsMiddleware adds CORS headers to the admin API
+// Mocked endpoints get the CORS policy of their spec from the proxy engine
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, "/_api") {
+			c.Next()
+			return
+		}
+
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/storage"
+)
+
+// FormatVersion is the version of the archive layout written by Write
+const FormatVersion = 1
+
+// Manifest describes the contents of an export archive
+//
+// Archive layout:
+//
+//	manifest.json
+//	specs/<id>/spec.json          spec settings and raw content
+//	specs/<id>/openapi.yaml|json  raw OpenAPI document, for reading only
+//	specs/<id>/operations.json
+//	specs/<id>/responses.json
+//	specs/<id>/files/<name>       files stored with the spec
+type Manifest struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Specs      []ManifestSpec `json:"specs"`
+}
+
+// ManifestSpec lists an exported spec
+type ManifestSpec struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	OperationCount int    `json:"operationCount"`
+	ResponseCount  int    `json:"responseCount"`
+}
+
+// Write writes a gzipped tar archive of the given specs with their operations and response configs
+// An empty specIDs exports every spec
+func Write(w io.Writer, store storage.Storage, specIDs []string) error {
+	specs, err := selectSpecs(store, specIDs)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	manifest := Manifest{
+		Version:    FormatVersion,
+		ExportedAt: now,
+		Specs:      make([]ManifestSpec, 0, len(specs)),
+	}
+
+	for _, spec := range specs {
+		ops, err := store.GetOperationsBySpec(spec.ID)
+		if err != nil {
+			return err
+		}
+
+		responses := make([]*models.ResponseConfig, 0)
+		for _, op := range ops {
+			configs, err := store.GetResponseConfigsByOperation(op.ID)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, configs...)
+		}
+
+		dir := "specs/" + spec.ID + "/"
+		if err := writeJSON(tw, dir+"spec.json", spec, now); err != nil {
+			return err
+		}
+		if err := writeFile(tw, dir+"openapi."+contentExtension(spec.Content), []byte(spec.Content), now); err != nil {
+			return err
+		}
+		if err := writeJSON(tw, dir+"operations.json", ops, now); err != nil {
+			return err
+		}
+		if err := writeJSON(tw, dir+"responses.json", responses, now); err != nil {
+			return err
+		}
+		if err := writeSpecFiles(tw, store, spec.ID, dir+"files/"); err != nil {
+			return err
+		}
+
+		manifest.Specs = append(manifest.Specs, ManifestSpec{
+			ID:             spec.ID,
+			Name:           spec.Name,
+			Version:        spec.Version,
+			OperationCount: len(ops),
+			ResponseCount:  len(responses),
+		})
+	}
+
+	if err := writeJSON(tw, "manifest.json", manifest, now); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// selectSpecs returns the specs to export, in the requested order
+func selectSpecs(store storage.Storage, specIDs []string) ([]*models.Spec, error) {
+	if len(specIDs) == 0 {
+		specs, err := store.GetAllSpecs()
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(specs, func(i, j int) bool {
+			return specs[i].CreatedAt.Before(specs[j].CreatedAt)
+		})
+		return specs, nil
+	}
+
+	specs := make([]*models.Spec, 0, len(specIDs))
+	for _, id := range specIDs {
+		spec, err := store.GetSpec(id)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// contentExtension guesses the file extension of a raw OpenAPI document
+func contentExtension(content string) string {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return "json"
+	}
+	return "yaml"
+}
+
+// writeJSON writes an indented JSON file to the archive
+func writeJSON(tw *tar.Writer, name string, v interface{}, modTime time.Time) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(tw, name, data, modTime)
+}
+
+// writeSpecFiles writes the files stored with a spec to a directory of the archive
+func writeSpecFiles(tw *tar.Writer, store storage.Storage, specID, dir string) error {
+	files, err := store.GetSpecFiles(specID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		reader, info, err := store.OpenSpecFile(specID, file.Name)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if err := writeFile(tw, dir+file.Name, data, info.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes a regular file to the archive
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/storage"
+)
+
+// readArchive returns the files of a tar.gz archive by name
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[header.Name] = content
+	}
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users", Content: "openapi: 3.0.0"})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Orders", Content: `{"openapi": "3.0.0"}`})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200})
+	store.SaveSpecFile("spec-1", "avatar.png", []byte("png"))
+
+	var buf bytes.Buffer
+	if err := Write(&buf, store, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	files := readArchive(t, buf.Bytes())
+
+	for _, name := range []string{
+		"manifest.json",
+		"specs/spec-1/spec.json",
+		"specs/spec-1/openapi.yaml",
+		"specs/spec-1/operations.json",
+		"specs/spec-1/responses.json",
+		"specs/spec-2/openapi.json",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Missing %s in archive", name)
+		}
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if manifest.Version != FormatVersion || len(manifest.Specs) != 2 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	var responses []models.ResponseConfig
+	json.Unmarshal(files["specs/spec-1/responses.json"], &responses)
+	if len(responses) != 1 || responses[0].ID != "resp-1" {
+		t.Errorf("Unexpected responses: %+v", responses)
+	}
+	if string(files["specs/spec-1/files/avatar.png"]) != "png" {
+		t.Errorf("Expected the spec's file in the archive, got %q", files["specs/spec-1/files/avatar.png"])
+	}
+}
+
+func TestWrite_SelectedSpecs(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users"})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Orders"})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, store, []string{"spec-2"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	files := readArchive(t, buf.Bytes())
+
+	if _, ok := files["specs/spec-1/spec.json"]; ok {
+		t.Error("Expected spec-1 to be excluded")
+	}
+	if _, ok := files["specs/spec-2/spec.json"]; !ok {
+		t.Error("Expected spec-2 to be included")
+	}
+
+	if err := Write(io.Discard, store, []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unknown spec")
+	}
+}
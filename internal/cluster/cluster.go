@@ -0,0 +1,333 @@
+// Package cluster keeps several instances that share a storage directory consistent
+//
+// Every node writes its own change marker to the shared directory after it modifies specs or
+// response configs. The other nodes poll the markers and reload their storage and routes
+// when any of them changes, so a load-balanced farm serves the same mocks. Each node only
+// ever writes its own marker, so concurrent changes on several nodes are never lost. Nodes
+// can also be told to poll right away through a Redis channel, and they publish their
+// statistics to the shared directory, so any node can report statistics for the whole cluster.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prasenjit/go-virtual/internal/stats"
+)
+
+// markersDir is the directory in the shared directory where nodes write their change markers
+const markersDir = "cluster-changes"
+
+// statsDir is the directory in the shared directory where nodes publish statistics
+const statsDir = "cluster-stats"
+
+// minStatsTTL is the minimum age after which the statistics of a node that stopped publishing are ignored
+const minStatsTTL = 10 * time.Second
+
+// Marker records the latest change made by a node
+type Marker struct {
+	Version string    `json:"version"` // Unique per change
+	NodeID  string    `json:"nodeId"`  // Node that made the change
+	Time    time.Time `json:"time"`
+}
+
+// Status describes a node's view of the cluster
+type Status struct {
+	Enabled      bool       `json:"enabled"`
+	NodeID       string     `json:"nodeId"`
+	PollInterval string     `json:"pollInterval"`
+	LastChange   *Marker    `json:"lastChange,omitempty"`
+	LastReload   *time.Time `json:"lastReload,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+}
+
+// Notifier tells the other nodes right away that a node changed the shared data
+type Notifier interface {
+	Notify(marker *Marker)
+}
+
+// Node notifies other nodes of local changes and applies theirs
+type Node struct {
+	id       string
+	dir      string
+	path     string // This node's marker
+	interval time.Duration
+	reload   func() error
+	stats    *stats.Collector // Published every interval when set
+	notifier Notifier         // Optional, in addition to the markers
+
+	mu         sync.Mutex
+	seen       map[string]string // Node ID -> version of the latest marker seen
+	last       *Marker           // Latest marker seen or written
+	lastReload time.Time
+	lastErr    error
+}
+
+// New creates a node sharing the directory dir
+// reload is called when another node changed the shared data
+func New(dir, id string, interval time.Duration, reload func() error) (*Node, error) {
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		id = host
+	}
+	if interval <= 0 {
+		return nil, errors.New("cluster poll interval must be positive")
+	}
+
+	n := &Node{
+		id:       id,
+		dir:      dir,
+		path:     filepath.Join(dir, markersDir, id+".json"),
+		interval: interval,
+		reload:   reload,
+		seen:     make(map[string]string),
+	}
+	// Changes made before this node started are already in the data it loaded
+	markers, _ := n.readMarkers()
+	for _, marker := range markers {
+		n.see(marker)
+	}
+	return n, nil
+}
+
+// ID returns the node ID
+func (n *Node) ID() string {
+	return n.id
+}
+
+// SetStats sets the collector whose statistics the node publishes to the other nodes
+func (n *Node) SetStats(collector *stats.Collector) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats = collector
+}
+
+// SetNotifier sets how the node tells the other nodes about its changes right away
+func (n *Node) SetNotifier(notifier Notifier) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifier = notifier
+}
+
+// Changed tells the other nodes that this node modified the shared data
+func (n *Node) Changed() error {
+	marker := &Marker{Version: uuid.New().String(), NodeID: n.id, Time: time.Now()}
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+
+	// Write and rename so pollers never read a partial marker
+	if err := os.MkdirAll(filepath.Dir(n.path), 0755); err != nil {
+		return err
+	}
+	tmp := n.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, n.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	n.mu.Lock()
+	n.see(marker)
+	notifier := n.notifier
+	n.mu.Unlock()
+
+	if notifier != nil {
+		notifier.Notify(marker)
+	}
+	return nil
+}
+
+// Run polls for changes made by other nodes and publishes statistics until ctx is done
+func (n *Node) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Poll()
+			if err := n.PublishStats(); err != nil {
+				slog.Warn("failed to publish cluster statistics", "error", err)
+			}
+		}
+	}
+}
+
+// PublishStats writes the node's statistics to the shared directory
+func (n *Node) PublishStats() error {
+	n.mu.Lock()
+	collector := n.stats
+	n.mu.Unlock()
+	if collector == nil {
+		return nil
+	}
+
+	snap := collector.Snapshot()
+	snap.NodeID = n.id
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(n.dir, statsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, n.id+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ClusterStats returns the statistics of all nodes, merged
+// The local node's statistics are current; other nodes' are as of their last publish, and nodes
+// that stopped publishing are left out. It also returns the IDs of the nodes included
+func (n *Node) ClusterStats() (*stats.Collector, []string) {
+	n.mu.Lock()
+	collector := n.stats
+	n.mu.Unlock()
+
+	var snapshots []*stats.Snapshot
+	nodes := []string{}
+	if collector != nil {
+		snapshots = append(snapshots, collector.Snapshot())
+		nodes = append(nodes, n.id)
+	}
+
+	ttl := 3 * n.interval
+	if ttl < minStatsTTL {
+		ttl = minStatsTTL
+	}
+
+	files, _ := filepath.Glob(filepath.Join(n.dir, statsDir, "*.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var snap stats.Snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			continue
+		}
+		if snap.NodeID == n.id || time.Since(snap.Time) > ttl {
+			continue
+		}
+		snapshots = append(snapshots, &snap)
+		nodes = append(nodes, snap.NodeID)
+	}
+
+	return stats.Merge(snapshots), nodes
+}
+
+// Poll reloads the shared data if other nodes changed it since the last poll
+// Changes of several nodes since the last poll are applied with a single reload
+func (n *Node) Poll() {
+	markers, err := n.readMarkers()
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	var changed *Marker
+	for _, marker := range markers {
+		if marker.NodeID == n.id || n.seen[marker.NodeID] == marker.Version {
+			continue
+		}
+		n.see(marker)
+		if changed == nil || marker.Time.After(changed.Time) {
+			changed = marker
+		}
+	}
+	n.mu.Unlock()
+	if changed == nil {
+		return
+	}
+	marker := changed
+
+	err = n.reload()
+
+	n.mu.Lock()
+	n.lastReload = time.Now()
+	n.lastErr = err
+	n.mu.Unlock()
+
+	if err != nil {
+		slog.Error("failed to apply cluster change", "node", marker.NodeID, "error", err)
+		return
+	}
+	slog.Info("applied cluster change", "node", marker.NodeID, "version", marker.Version)
+}
+
+// Status returns the node's view of the cluster
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := Status{
+		Enabled:      true,
+		NodeID:       n.id,
+		PollInterval: n.interval.String(),
+		LastChange:   n.last,
+	}
+	if !n.lastReload.IsZero() {
+		reloaded := n.lastReload
+		status.LastReload = &reloaded
+	}
+	if n.lastErr != nil {
+		status.LastError = n.lastErr.Error()
+	}
+	return status
+}
+
+// see records a marker as seen; the caller must hold n.mu
+func (n *Node) see(marker *Marker) {
+	n.seen[marker.NodeID] = marker.Version
+	if n.last == nil || marker.Time.After(n.last.Time) {
+		n.last = marker
+	}
+}
+
+// readMarkers reads the change markers of all nodes
+// Markers that can't be read, e.g. while being replaced, are skipped until the next poll
+func (n *Node) readMarkers() ([]*Marker, error) {
+	files, err := filepath.Glob(filepath.Join(n.dir, markersDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	markers := make([]*Marker, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var marker Marker
+		if err := json.Unmarshal(data, &marker); err != nil || marker.NodeID == "" {
+			continue
+		}
+		markers = append(markers, &marker)
+	}
+	return markers, nil
+}
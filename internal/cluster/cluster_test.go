@@ -0,0 +1,172 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/stats"
+)
+
+func TestNode_PollAppliesOtherNodesChanges(t *testing.T) {
+	dir := t.TempDir()
+
+	reloads := map[string]int{}
+	newNode := func(id string) *Node {
+		n, err := New(dir, id, time.Second, func() error {
+			reloads[id]++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		return n
+	}
+	a, b := newNode("a"), newNode("b")
+
+	// Nothing changed yet
+	b.Poll()
+	if reloads["b"] != 0 {
+		t.Errorf("Expected no reload without changes, got %d", reloads["b"])
+	}
+
+	if err := a.Changed(); err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	a.Poll()
+	b.Poll()
+	b.Poll()
+	if reloads["a"] != 0 {
+		t.Errorf("Expected a node to ignore its own change, got %d reloads", reloads["a"])
+	}
+	if reloads["b"] != 1 {
+		t.Errorf("Expected one reload on the other node, got %d", reloads["b"])
+	}
+
+	status := b.Status()
+	if !status.Enabled || status.LastChange == nil || status.LastChange.NodeID != "a" || status.LastReload == nil {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// A node started after a change has already loaded it
+	c := newNode("c")
+	c.Poll()
+	if reloads["c"] != 0 {
+		t.Errorf("Expected a new node to skip earlier changes, got %d reloads", reloads["c"])
+	}
+}
+
+func TestNode_PollKeepsConcurrentChanges(t *testing.T) {
+	dir := t.TempDir()
+	noop := func() error { return nil }
+	a, _ := New(dir, "a", time.Second, noop)
+	b, _ := New(dir, "b", time.Second, noop)
+
+	reloads := 0
+	c, _ := New(dir, "c", time.Second, func() error {
+		reloads++
+		return nil
+	})
+
+	// Both changes land before c polls, and b's change doesn't hide a's from b
+	if err := a.Changed(); err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	if err := b.Changed(); err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+
+	bReloads := 0
+	b.reload = func() error {
+		bReloads++
+		return nil
+	}
+	b.Poll()
+	if bReloads != 1 {
+		t.Errorf("Expected b to apply a's change, got %d reloads", bReloads)
+	}
+
+	c.Poll()
+	c.Poll()
+	if reloads != 1 {
+		t.Errorf("Expected one reload for both changes, got %d", reloads)
+	}
+
+	// A later change of one node is applied again
+	a.Changed()
+	c.Poll()
+	if reloads != 2 {
+		t.Errorf("Expected a reload for the new change, got %d", reloads)
+	}
+}
+
+type recordingNotifier struct {
+	markers []*Marker
+}
+
+func (r *recordingNotifier) Notify(marker *Marker) {
+	r.markers = append(r.markers, marker)
+}
+
+func TestNode_Notifier(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := New(dir, "a", time.Second, func() error { return nil })
+
+	reloads := 0
+	b, _ := New(dir, "b", time.Second, func() error {
+		reloads++
+		return nil
+	})
+
+	notifier := &recordingNotifier{}
+	a.SetNotifier(notifier)
+	if err := a.Changed(); err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	if len(notifier.markers) != 1 || notifier.markers[0].NodeID != "a" {
+		t.Fatalf("Expected the change to be announced, got %+v", notifier.markers)
+	}
+
+	// An announcement from another node makes b poll right away
+	payload, _ := json.Marshal(notifier.markers[0])
+	(&RedisNotifier{node: b}).handleMessage(string(payload))
+	if reloads != 1 {
+		t.Errorf("Expected the announcement to trigger a reload, got %d", reloads)
+	}
+
+	// Its own announcements are ignored
+	(&RedisNotifier{node: a}).handleMessage(string(payload))
+}
+
+func TestNew_InvalidInterval(t *testing.T) {
+	if _, err := New(t.TempDir(), "a", 0, func() error { return nil }); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+}
+
+func TestNode_ClusterStats(t *testing.T) {
+	dir := t.TempDir()
+	noop := func() error { return nil }
+	a, _ := New(dir, "a", time.Second, noop)
+	b, _ := New(dir, "b", time.Second, noop)
+
+	statsA, statsB := stats.NewCollector(), stats.NewCollector()
+	a.SetStats(statsA)
+	b.SetStats(statsB)
+	statsA.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	statsB.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+
+	if err := b.PublishStats(); err != nil {
+		t.Fatalf("PublishStats failed: %v", err)
+	}
+	// Changes on the local node count without publishing
+	statsA.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+
+	merged, nodes := a.ClusterStats()
+	if len(nodes) != 2 {
+		t.Errorf("Expected 2 nodes, got %v", nodes)
+	}
+	if total := merged.GetGlobalStats(0, 0).TotalRequests; total != 3 {
+		t.Errorf("Expected 3 requests across the cluster, got %d", total)
+	}
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// notifyTimeout bounds how long connecting to Redis or publishing one change may take
+const notifyTimeout = 2 * time.Second
+
+// RedisNotifier tells the other nodes about changes through a Redis pub/sub channel, so
+// they apply them right away instead of at their next poll. The change markers in the shared
+// directory stay the source of truth: a node that misses a message catches up when it polls
+type RedisNotifier struct {
+	client  *redis.Client
+	channel string
+	node    *Node
+}
+
+// NewRedisNotifier connects to Redis at url (e.g. redis://localhost:6379/0)
+// Changes are published as JSON markers on channel
+func NewRedisNotifier(node *Node, url, channel string) (*RedisNotifier, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisNotifier{client: client, channel: channel, node: node}, nil
+}
+
+// Notify publishes a change made on this node
+// It doesn't wait for Redis, so admin API changes never block on it
+func (r *RedisNotifier) Notify(marker *Marker) {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := r.client.Publish(ctx, r.channel, data).Err(); err != nil {
+			slog.Warn("failed to publish cluster change", "channel", r.channel, "error", err)
+		}
+	}()
+}
+
+// Run polls the shared directory whenever another node announces a change, until ctx is done
+func (r *RedisNotifier) Run(ctx context.Context) {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			r.handleMessage(msg.Payload)
+		}
+	}
+}
+
+// handleMessage applies a change announced by another node
+func (r *RedisNotifier) handleMessage(payload string) {
+	var marker Marker
+	if err := json.Unmarshal([]byte(payload), &marker); err != nil {
+		slog.Warn("invalid cluster change on channel", "channel", r.channel, "error", err)
+		return
+	}
+	if marker.NodeID == r.node.ID() {
+		return
+	}
+	r.node.Poll()
+}
+
+// Close closes the Redis connection
+func (r *RedisNotifier) Close() error {
+	return r.client.Close()
+}
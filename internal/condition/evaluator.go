@@ -1,16 +1,22 @@
 package condition
 
 import (
+	"mime"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/variables"
 	"github.com/tidwall/gjson"
 )
 
 // Evaluator evaluates conditions against request data
-type Evaluator struct{}
+type Evaluator struct {
+	mu  sync.RWMutex
+	jwt JWTOptions
+}
 
 // NewEvaluator creates a new condition evaluator
 func NewEvaluator() *Evaluator {
@@ -23,6 +29,10 @@ type RequestData struct {
 	QueryParams map[string][]string
 	Headers     map[string][]string
 	Body        string
+	Variables   *variables.Scope
+
+	jwtParsed bool   // jwtClaims holds the claims of the bearer token, decoded on first use
+	jwtClaims []byte // nil without a usable token
 }
 
 // EvaluateAll evaluates all conditions against request data
@@ -41,9 +51,22 @@ func (e *Evaluator) EvaluateAll(conditions []models.Condition, data *RequestData
 	return true
 }
 
+// Matches reports whether a group of conditions selects the request
+// With negate set the group matches when the conditions don't all hold;
+// an empty group always matches
+func (e *Evaluator) Matches(conditions []models.Condition, negate bool, data *RequestData) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+	return e.EvaluateAll(conditions, data) != negate
+}
+
 // Evaluate evaluates a single condition against request data
 func (e *Evaluator) Evaluate(cond models.Condition, data *RequestData) bool {
 	value := e.extractValue(cond.Source, cond.Key, data)
+	if cond.Operator == models.OpHMAC {
+		return verifyHMAC(value, cond.Value, data.Body)
+	}
 	return e.compare(value, cond.Operator, cond.Value)
 }
 
@@ -58,13 +81,7 @@ func (e *Evaluator) extractValue(source, key string, data *RequestData) string {
 		}
 		return ""
 	case models.SourceHeader:
-		// Headers are case-insensitive
-		for k, vals := range data.Headers {
-			if strings.EqualFold(k, key) && len(vals) > 0 {
-				return vals[0]
-			}
-		}
-		return ""
+		return headerValue(data.Headers, key)
 	case models.SourceBody:
 		// Use JSONPath to extract value from body
 		result := gjson.Get(data.Body, key)
@@ -72,11 +89,44 @@ func (e *Evaluator) extractValue(source, key string, data *RequestData) string {
 			return result.String()
 		}
 		return ""
+	case models.SourceRawBody:
+		return data.Body
+	case models.SourceContentLength:
+		return strconv.Itoa(len(data.Body))
+	case models.SourceContentType:
+		return mediaType(headerValue(data.Headers, "Content-Type"))
+	case models.SourceVar:
+		value, _ := data.Variables.Get(key)
+		return value
+	case models.SourceJWT:
+		return e.jwtClaim(key, data)
 	default:
 		return ""
 	}
 }
 
+// headerValue returns the first value of a header, matching the name case-insensitively
+func headerValue(headers map[string][]string, name string) string {
+	for k, vals := range headers {
+		if strings.EqualFold(k, name) && len(vals) > 0 {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+// mediaType normalizes a Content-Type value to its lowercased media type, dropping parameters like charset
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
 // compare compares a value against an expected value using the specified operator
 func (e *Evaluator) compare(actual, operator, expected string) bool {
 	switch operator {
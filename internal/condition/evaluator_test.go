@@ -1,9 +1,11 @@
 package condition
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/variables"
 )
 
 func TestNewEvaluator(t *testing.T) {
@@ -307,6 +309,91 @@ func TestEvaluate_Body(t *testing.T) {
 	}
 }
 
+func TestEvaluate_RawBody(t *testing.T) {
+	e := NewEvaluator()
+
+	csv := "id,name\n1,Alice\n2,Bob\n"
+	xml := `<order><id>42</id><status>paid</status></order>`
+
+	tests := []struct {
+		name     string
+		cond     models.Condition
+		body     string
+		expected bool
+	}{
+		{"contains csv row", models.Condition{Source: models.SourceRawBody, Operator: models.OpContains, Value: "2,Bob"}, csv, true},
+		{"regex multiline", models.Condition{Source: models.SourceRawBody, Operator: models.OpRegex, Value: `(?m)^1,\w+$`}, csv, true},
+		{"regex xml element", models.Condition{Source: models.SourceRawBody, Operator: models.OpRegex, Value: `<status>paid</status>`}, xml, true},
+		{"regex no match", models.Condition{Source: models.SourceRawBody, Operator: models.OpRegex, Value: `<status>refunded</status>`}, xml, false},
+		{"key is ignored", models.Condition{Source: models.SourceRawBody, Key: "order.id", Operator: models.OpStartsWith, Value: "<order>"}, xml, true},
+		{"not exists on empty body", models.Condition{Source: models.SourceRawBody, Operator: models.OpNotExists}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := e.Evaluate(tt.cond, &RequestData{Body: tt.body}); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluate_ContentLengthAndType(t *testing.T) {
+	e := NewEvaluator()
+
+	large := &RequestData{
+		Headers: map[string][]string{"content-type": {"Application/JSON; charset=UTF-8"}},
+		Body:    strings.Repeat("x", 2048),
+	}
+	small := &RequestData{
+		Headers: map[string][]string{"Content-Type": {"text/csv"}},
+		Body:    "a,b",
+	}
+
+	tests := []struct {
+		name     string
+		cond     models.Condition
+		data     *RequestData
+		expected bool
+	}{
+		{"large upload", models.Condition{Source: models.SourceContentLength, Operator: models.OpGreaterThan, Value: "1024"}, large, true},
+		{"small upload", models.Condition{Source: models.SourceContentLength, Operator: models.OpLTE, Value: "1024"}, small, true},
+		{"exact length", models.Condition{Source: models.SourceContentLength, Operator: models.OpEquals, Value: "3"}, small, true},
+		{"content type ignores charset and case", models.Condition{Source: models.SourceContentType, Operator: models.OpEquals, Value: "application/json"}, large, true},
+		{"content type mismatch", models.Condition{Source: models.SourceContentType, Operator: models.OpEquals, Value: "application/json"}, small, false},
+		{"missing content type", models.Condition{Source: models.SourceContentType, Operator: models.OpNotExists}, &RequestData{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := e.Evaluate(tt.cond, tt.data); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluate_Variable(t *testing.T) {
+	e := NewEvaluator()
+
+	store := variables.NewStore()
+	store.Set("spec-1", "orderId", "42")
+
+	cond := models.Condition{Source: models.SourceVar, Key: "orderId", Operator: models.OpEquals, Value: "42"}
+
+	if !e.Evaluate(cond, &RequestData{Variables: store.Scope("spec-1")}) {
+		t.Error("Expected variable condition to match")
+	}
+	if e.Evaluate(cond, &RequestData{Variables: store.Scope("spec-2")}) {
+		t.Error("Expected variable of another spec not to match")
+	}
+
+	exists := models.Condition{Source: models.SourceVar, Key: "orderId", Operator: models.OpExists}
+	if e.Evaluate(exists, &RequestData{}) {
+		t.Error("Expected no variables without a scope")
+	}
+}
+
 func TestEvaluate_Operators(t *testing.T) {
 	e := NewEvaluator()
 
@@ -602,6 +689,33 @@ func TestEvaluateAll(t *testing.T) {
 	}
 }
 
+func TestMatches(t *testing.T) {
+	e := NewEvaluator()
+
+	admin := []models.Condition{
+		{Source: models.SourceHeader, Key: "X-Role", Operator: models.OpEquals, Value: "admin"},
+		{Source: models.SourceQuery, Key: "scope", Operator: models.OpEquals, Value: "all"},
+	}
+	adminData := &RequestData{
+		Headers:     map[string][]string{"X-Role": {"admin"}},
+		QueryParams: map[string][]string{"scope": {"all"}},
+	}
+	userData := &RequestData{
+		Headers:     map[string][]string{"X-Role": {"user"}},
+		QueryParams: map[string][]string{"scope": {"all"}},
+	}
+
+	if !e.Matches(admin, false, adminData) || e.Matches(admin, false, userData) {
+		t.Error("Expected plain group to match only admins")
+	}
+	if e.Matches(admin, true, adminData) || !e.Matches(admin, true, userData) {
+		t.Error("Expected negated group to match everyone except admins")
+	}
+	if !e.Matches(nil, true, userData) {
+		t.Error("Expected empty negated group to match")
+	}
+}
+
 func TestHasValue(t *testing.T) {
 	e := NewEvaluator()
 
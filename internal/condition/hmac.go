@@ -0,0 +1,72 @@
+package condition
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// hmacAlgorithms are the hash functions signatures can be made with, by name
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// hmacBySize picks the hash function of an unprefixed signature from its length
+var hmacBySize = map[int]string{
+	sha1.Size:   "sha1",
+	sha256.Size: "sha256",
+	sha512.Size: "sha512",
+}
+
+// verifyHMAC reports whether signature is an HMAC of body made with secret
+// The signature may carry an algorithm prefix as in GitHub's "sha256=<hex>"; without one the
+// algorithm follows from its length. Hex and base64 encodings are accepted
+func verifyHMAC(signature, secret, body string) bool {
+	signature = strings.TrimSpace(signature)
+	if signature == "" || secret == "" {
+		return false
+	}
+
+	algorithm := ""
+	if name, rest, found := strings.Cut(signature, "="); found {
+		if _, known := hmacAlgorithms[strings.ToLower(name)]; known {
+			algorithm, signature = strings.ToLower(name), rest
+		}
+	}
+
+	sum := decodeSignature(signature)
+	if sum == nil {
+		return false
+	}
+	if algorithm == "" {
+		algorithm = hmacBySize[len(sum)]
+	}
+	newHash, ok := hmacAlgorithms[algorithm]
+	if !ok {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return hmac.Equal(mac.Sum(nil), sum)
+}
+
+// decodeSignature decodes a hex or base64 signature, returning nil if it is neither
+func decodeSignature(signature string) []byte {
+	if sum, err := hex.DecodeString(signature); err == nil {
+		return sum
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if sum, err := enc.DecodeString(signature); err == nil {
+			return sum
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package condition
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestHMACOperator(t *testing.T) {
+	body := `{"action":"opened"}`
+	sign := func(secret string) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return mac.Sum(nil)
+	}
+	sha1Mac := hmac.New(sha1.New, []byte("s3cret"))
+	sha1Mac.Write([]byte(body))
+
+	tests := []struct {
+		name      string
+		signature string
+		expected  bool
+	}{
+		{"github style", "sha256=" + hex.EncodeToString(sign("s3cret")), true},
+		{"plain hex", hex.EncodeToString(sign("s3cret")), true},
+		{"base64", base64.StdEncoding.EncodeToString(sign("s3cret")), true},
+		{"sha1 prefix", "sha1=" + hex.EncodeToString(sha1Mac.Sum(nil)), true},
+		{"wrong secret", "sha256=" + hex.EncodeToString(sign("other")), false},
+		{"wrong algorithm", "sha1=" + hex.EncodeToString(sign("s3cret")), false},
+		{"garbage", "not a signature", false},
+		{"missing", "", false},
+	}
+
+	e := NewEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &RequestData{
+				Headers: map[string][]string{"X-Hub-Signature-256": {tt.signature}},
+				Body:    body,
+			}
+			cond := models.Condition{Source: "header", Key: "X-Hub-Signature-256", Operator: "hmac", Value: "s3cret"}
+			if got := e.Evaluate(cond, data); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if err := Validate(models.Condition{Source: "header", Key: "X-Signature", Operator: "hmac"}); err == nil {
+		t.Error("Expected an hmac condition without a secret to be invalid")
+	}
+}
@@ -0,0 +1,78 @@
+package condition
+
+import (
+	"crypto/rsa"
+	"strings"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/oauth"
+	"github.com/tidwall/gjson"
+)
+
+// JWTOptions configures how the jwt source treats bearer tokens
+type JWTOptions struct {
+	Verify  bool             // Ignore tokens without a valid signature or outside their validity period
+	Keys    []*rsa.PublicKey // Keys RS256 tokens are verified with
+	Secrets [][]byte         // Secrets HS256 tokens are verified with
+}
+
+// SetJWTOptions configures the verification of tokens read by the jwt source
+func (e *Evaluator) SetJWTOptions(opts JWTOptions) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.jwt = opts
+}
+
+// jwtClaim returns a claim of the request's bearer token, selected by a gjson path
+// Requests without a usable token have no claims
+func (e *Evaluator) jwtClaim(key string, data *RequestData) string {
+	if !data.jwtParsed {
+		data.jwtParsed = true
+		data.jwtClaims = e.bearerClaims(headerValue(data.Headers, "Authorization"))
+	}
+	if data.jwtClaims == nil {
+		return ""
+	}
+
+	result := gjson.GetBytes(data.jwtClaims, key)
+	if result.Exists() {
+		return result.String()
+	}
+	return ""
+}
+
+// bearerClaims decodes the claims of an "Authorization: Bearer" token, verifying it if configured
+func (e *Evaluator) bearerClaims(authorization string) []byte {
+	scheme, raw, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil
+	}
+	token, err := oauth.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	opts := e.jwt
+	e.mu.RUnlock()
+
+	if opts.Verify && (!verifyToken(token, opts) || token.CheckTime(time.Now()) != nil) {
+		return nil
+	}
+	return token.Payload
+}
+
+// verifyToken reports whether any configured key or secret verifies the token's signature
+func verifyToken(token *oauth.Token, opts JWTOptions) bool {
+	for _, key := range opts.Keys {
+		if token.Verify(key) == nil {
+			return true
+		}
+	}
+	for _, secret := range opts.Secrets {
+		if token.VerifyHMAC(secret) == nil {
+			return true
+		}
+	}
+	return false
+}
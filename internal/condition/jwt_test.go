@@ -0,0 +1,91 @@
+package condition
+
+import (
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/oauth"
+)
+
+func bearerData(token string) *RequestData {
+	return &RequestData{Headers: map[string][]string{"Authorization": {"Bearer " + token}}}
+}
+
+func TestJWTSource(t *testing.T) {
+	key, err := oauth.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	token, _ := key.Sign(map[string]interface{}{
+		"sub":    "alice",
+		"scope":  "orders:read orders:write",
+		"tenant": map[string]interface{}{"id": "acme"},
+		"roles":  []string{"admin", "user"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+
+	e := NewEvaluator()
+	tests := []struct {
+		cond     models.Condition
+		expected bool
+	}{
+		{models.Condition{Source: "jwt", Key: "sub", Operator: "eq", Value: "alice"}, true},
+		{models.Condition{Source: "jwt", Key: "scope", Operator: "contains", Value: "orders:write"}, true},
+		{models.Condition{Source: "jwt", Key: "tenant.id", Operator: "eq", Value: "acme"}, true},
+		{models.Condition{Source: "jwt", Key: "roles", Operator: "contains", Value: `"admin"`}, true},
+		{models.Condition{Source: "jwt", Key: "email", Operator: "notExists"}, true},
+		{models.Condition{Source: "jwt", Key: "sub", Operator: "eq", Value: "bob"}, false},
+	}
+	for _, tt := range tests {
+		if got := e.Evaluate(tt.cond, bearerData(token)); got != tt.expected {
+			t.Errorf("%s %s %q: expected %v, got %v", tt.cond.Key, tt.cond.Operator, tt.cond.Value, tt.expected, got)
+		}
+	}
+
+	// Requests without a bearer token have no claims
+	data := &RequestData{Headers: map[string][]string{"Authorization": {"Basic dXNlcjpwYXNz"}}}
+	if !e.Evaluate(models.Condition{Source: "jwt", Key: "sub", Operator: "notExists"}, data) {
+		t.Error("Expected no claims without a bearer token")
+	}
+}
+
+func TestJWTSource_Verify(t *testing.T) {
+	key, _ := oauth.GenerateKey()
+	other, _ := oauth.GenerateKey()
+	cond := models.Condition{Source: "jwt", Key: "sub", Operator: "eq", Value: "alice"}
+
+	valid, _ := key.Sign(map[string]interface{}{"sub": "alice"})
+	forged, _ := other.Sign(map[string]interface{}{"sub": "alice"})
+	expired, _ := key.Sign(map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	// Without verification any well-formed token is read
+	e := NewEvaluator()
+	if !e.Evaluate(cond, bearerData(forged)) {
+		t.Error("Expected unverified tokens to be read when verification is off")
+	}
+
+	e.SetJWTOptions(JWTOptions{Verify: true, Keys: []*rsa.PublicKey{key.Public()}, Secrets: [][]byte{[]byte("s3cret")}})
+	if !e.Evaluate(cond, bearerData(valid)) {
+		t.Error("Expected a token signed with a configured key to be read")
+	}
+	if e.Evaluate(cond, bearerData(forged)) {
+		t.Error("Expected a token signed with another key to be ignored")
+	}
+	if e.Evaluate(cond, bearerData(expired)) {
+		t.Error("Expected an expired token to be ignored")
+	}
+
+	// HS256 tokens are verified with the shared secrets
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(`{"sub":"alice"}`))
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(signed))
+	if !e.Evaluate(cond, bearerData(signed+"."+enc.EncodeToString(mac.Sum(nil)))) {
+		t.Error("Expected an HS256 token signed with a configured secret to be read")
+	}
+}
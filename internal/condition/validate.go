@@ -0,0 +1,114 @@
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// sourcesWithoutKey are condition sources that don't use the key
+var sourcesWithoutKey = []string{models.SourceRawBody, models.SourceContentLength, models.SourceContentType}
+
+// Validate checks that a condition can be evaluated
+// It catches mistakes such as unknown sources, invalid regular expressions and
+// malformed body paths when a config is saved rather than as silent non-matches
+func Validate(cond models.Condition) error {
+	if !slices.Contains(models.ValidSources(), cond.Source) {
+		return fmt.Errorf("unknown source %q", cond.Source)
+	}
+	if !slices.Contains(models.ValidOperators(), cond.Operator) {
+		return fmt.Errorf("unknown operator %q", cond.Operator)
+	}
+	if cond.Key == "" && !slices.Contains(sourcesWithoutKey, cond.Source) {
+		return fmt.Errorf("key is required for source %q", cond.Source)
+	}
+	if cond.Source == models.SourceBody || cond.Source == models.SourceJWT {
+		if err := ValidatePath(cond.Key); err != nil {
+			return fmt.Errorf("invalid %s path %q: %w", cond.Source, cond.Key, err)
+		}
+	}
+	if cond.Operator == models.OpHMAC && cond.Value == "" {
+		return fmt.Errorf("hmac needs the secret as value")
+	}
+	if cond.Operator == models.OpRegex {
+		if _, err := regexp.Compile(cond.Value); err != nil {
+			return fmt.Errorf("invalid regex %q: %w", cond.Value, err)
+		}
+	}
+	return nil
+}
+
+// ValidateAll validates a list of conditions, reporting the position of the first invalid one
+func ValidateAll(conditions []models.Condition) error {
+	for i, cond := range conditions {
+		if err := Validate(cond); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// ValidatePath checks the structure of a gjson path such as "items.#(id==3).name"
+// Brackets, parentheses and braces must balance, quoted strings must be terminated
+// and path segments must not be empty
+func ValidatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+
+	closers := map[byte]byte{'(': ')', '[': ']', '{': '}'}
+	var stack []byte
+	var quote byte
+	prevSeparator := true // An empty first segment is an error too
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+
+		if c == '\\' {
+			i++
+			prevSeparator = false
+			continue
+		}
+
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			quote = c
+		case '(', '[', '{':
+			stack = append(stack, closers[c])
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return fmt.Errorf("unexpected %q at position %d", c, i+1)
+			}
+			stack = stack[:len(stack)-1]
+		case '.', '|':
+			if len(stack) == 0 {
+				if prevSeparator {
+					return fmt.Errorf("empty path segment at position %d", i+1)
+				}
+				prevSeparator = true
+				continue
+			}
+		}
+		prevSeparator = false
+	}
+
+	if quote != 0 {
+		return fmt.Errorf("unterminated string")
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("missing %q", stack[len(stack)-1])
+	}
+	if prevSeparator {
+		return fmt.Errorf("path ends with a separator")
+	}
+	return nil
+}
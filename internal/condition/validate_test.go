@@ -0,0 +1,81 @@
+package condition
+
+import (
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestValidatePath(t *testing.T) {
+	valid := []string{
+		"name",
+		"user.address.city",
+		"items.0.id",
+		"items.#",
+		"items.#(id==3).name",
+		`friends.#(last=="Murphy")#.first`,
+		`friends.#(nets.#(=="fb"))#.first`,
+		"children|@reverse|0",
+		`fav\.movie`,
+		"{name,age}",
+		`items.#(name%"D*")`,
+	}
+	for _, path := range valid {
+		if err := ValidatePath(path); err != nil {
+			t.Errorf("ValidatePath(%q) returned error: %v", path, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"user..name",
+		".name",
+		"name.",
+		"items.#(id==3.name",
+		"items.#(id==3)).name",
+		`items.#(name=="Bob).id`,
+		"{name,age",
+	}
+	for _, path := range invalid {
+		if err := ValidatePath(path); err == nil {
+			t.Errorf("ValidatePath(%q) expected an error", path)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    models.Condition
+		wantErr bool
+	}{
+		{"valid header", models.Condition{Source: "header", Key: "X-Tier", Operator: "eq", Value: "gold"}, false},
+		{"valid body query", models.Condition{Source: "body", Key: "items.#(id==3).name", Operator: "exists"}, false},
+		{"raw body without key", models.Condition{Source: "rawBody", Operator: "contains", Value: "x"}, false},
+		{"unknown source", models.Condition{Source: "cookie", Key: "a", Operator: "eq"}, true},
+		{"unknown operator", models.Condition{Source: "query", Key: "a", Operator: "like"}, true},
+		{"missing key", models.Condition{Source: "query", Operator: "eq"}, true},
+		{"bad body path", models.Condition{Source: "body", Key: "items.#(id==3", Operator: "exists"}, true},
+		{"bad regex", models.Condition{Source: "path", Key: "id", Operator: "regex", Value: "(["}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate_BodyQuery(t *testing.T) {
+	e := NewEvaluator()
+
+	data := &RequestData{Body: `{"items": [{"id": 1, "name": "pen"}, {"id": 3, "name": "book"}]}`}
+	cond := models.Condition{Source: models.SourceBody, Key: "items.#(id==3).name", Operator: models.OpEquals, Value: "book"}
+
+	if !e.Evaluate(cond, data) {
+		t.Error("Expected gjson query to match")
+	}
+}
@@ -5,22 +5,60 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/oauth"
+	"github.com/prasenjit/go-virtual/internal/tracing"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server  ServerConfig  `yaml:"server"`
-	Storage StorageConfig `yaml:"storage"`
-	Tracing TracingConfig `yaml:"tracing"`
-	Logging LoggingConfig `yaml:"logging"`
+	Server     ServerConfig     `yaml:"server"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Cluster    ClusterConfig    `yaml:"cluster"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Logging    LoggingConfig    `yaml:"logging"`
+	Templates  TemplatesConfig  `yaml:"templates"`
+	Stateful   StatefulConfig   `yaml:"stateful"`
+	Conditions ConditionsConfig `yaml:"conditions"`
+	OAuth      oauth.Config     `yaml:"oauth"`  // Mock authorization server
+	Events     EventsConfig     `yaml:"events"` // Brokers of response config event actions
+
+	// Fallback holds the server-level default responses used when no response config applies
+	Fallback models.FallbackResponses `yaml:"fallback"`
+}
+
+// EventsConfig holds the brokers event actions publish to
+type EventsConfig struct {
+	Kafka events.KafkaConfig `yaml:"kafka"`
+	MQTT  events.MQTTConfig  `yaml:"mqtt"`
+	AMQP  events.AMQPConfig  `yaml:"amqp"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port int       `yaml:"port"`
-	Host string    `yaml:"host"`
-	TLS  TLSConfig `yaml:"tls"`
+	Port             int           `yaml:"port"`
+	Host             string        `yaml:"host"`
+	TLS              TLSConfig     `yaml:"tls"`
+	MaxConcurrent    int           `yaml:"maxConcurrent"`    // Maximum mock requests served at the same time; 0 is unlimited
+	ResponseTimeout  time.Duration `yaml:"responseTimeout"`  // Maximum handling time of operations without their own timeout; 0 is none
+	BasePathPrefixes []string      `yaml:"basePathPrefixes"` // Spec base paths must be under one of these; empty allows any
+	ReadOnly         bool          `yaml:"readOnly"`         // Reject admin API changes
+	Drain            DrainConfig   `yaml:"drain"`
+	Utilities        UtilityConfig `yaml:"utilities"`
+}
+
+// UtilityConfig holds the settings of the httpbin-style utility endpoints
+type UtilityConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Prefix  string `yaml:"prefix"` // Path the endpoints are served below
+}
+
+// DrainConfig holds graceful shutdown configuration
+type DrainConfig struct {
+	GracePeriod time.Duration `yaml:"gracePeriod"` // Keep accepting mock requests this long after readiness fails
+	Timeout     time.Duration `yaml:"timeout"`     // Wait this long for in-flight mock requests
 }
 
 // TLSConfig holds TLS configuration
@@ -34,20 +72,88 @@ type TLSConfig struct {
 
 // StorageConfig holds storage configuration
 type StorageConfig struct {
-	Type string `yaml:"type"` // "memory" or "file"
-	Path string `yaml:"path"` // Path for file storage
+	Type   string             `yaml:"type"`   // "memory" or "file"
+	Path   string             `yaml:"path"`   // Path for file storage
+	Fsync  bool               `yaml:"fsync"`  // Sync file storage writes to disk before they return
+	Memory MemoryLimitsConfig `yaml:"memory"` // Limits of memory storage
+}
+
+// MemoryLimitsConfig holds the capacity limits of memory storage; 0 is unlimited
+type MemoryLimitsConfig struct {
+	MaxSpecs           int    `yaml:"maxSpecs"`
+	MaxResponseConfigs int    `yaml:"maxResponseConfigs"`
+	MaxBodyBytes       int64  `yaml:"maxBodyBytes"` // Total bytes of spec contents and response bodies
+	Eviction           string `yaml:"eviction"`     // "none" rejects writes over a limit, "oldest" deletes the oldest specs
+}
+
+// ClusterConfig holds the configuration of instances sharing file storage
+type ClusterConfig struct {
+	Enabled      bool               `yaml:"enabled"`
+	NodeID       string             `yaml:"nodeId"`       // Defaults to the hostname
+	PollInterval time.Duration      `yaml:"pollInterval"` // How often to check for changes made by other instances
+	Redis        ClusterRedisConfig `yaml:"redis"`
+}
+
+// ClusterRedisConfig holds the Redis pub/sub channel nodes announce changes through
+type ClusterRedisConfig struct {
+	URL     string `yaml:"url"` // Empty leaves change detection to polling
+	Channel string `yaml:"channel"`
 }
 
 // TracingConfig holds tracing configuration
 type TracingConfig struct {
-	MaxTraces int           `yaml:"maxTraces"`
-	Retention time.Duration `yaml:"retention"`
+	MaxTraces     int                `yaml:"maxTraces"`
+	MaxBodySize   int                `yaml:"maxBodySize"`   // Bytes of each request and response body kept; 0 keeps them whole
+	RedactHeaders []string           `yaml:"redactHeaders"` // Headers whose values traces hide
+	MaskRules     []tracing.MaskRule `yaml:"maskRules"`     // Fields of JSON bodies whose values traces hide
+	Retention     time.Duration      `yaml:"retention"`
+	Redis         TracingRedisConfig `yaml:"redis"`
+}
+
+// TracingRedisConfig holds the Redis pub/sub channel traces are shared through
+type TracingRedisConfig struct {
+	URL     string `yaml:"url"` // Empty disables trace fan-out
+	Channel string `yaml:"channel"`
+}
+
+// TemplatesConfig holds response template configuration
+type TemplatesConfig struct {
+	EnvAllowlist []string `yaml:"envAllowlist"` // Environment variables readable with {{env.NAME}}; "PREFIX_*" allows a prefix
+}
+
+// StatefulConfig holds the configuration of stateful specs
+type StatefulConfig struct {
+	SeedDir string `yaml:"seedDir"` // Seed files that aren't spec files are read from here; empty allows only spec files
+}
+
+// ConditionsConfig holds response condition configuration
+type ConditionsConfig struct {
+	JWT JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig holds the keys bearer tokens of jwt conditions are verified with
+type JWTConfig struct {
+	Verify   bool     `yaml:"verify"`   // Ignore tokens with an invalid signature or outside their validity period
+	KeyFiles []string `yaml:"keyFiles"` // PEM public keys, certificates or JWKS files for RS256 tokens
+	Secrets  []string `yaml:"secrets"`  // Shared secrets for HS256 tokens
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string `yaml:"level"`
-	Format string `yaml:"format"`
+	Level      string          `yaml:"level"`
+	Format     string          `yaml:"format"`
+	File       string          `yaml:"file"`       // Log to this file instead of stderr
+	MaxSize    int             `yaml:"maxSize"`    // Megabytes before a log file is rotated
+	MaxAge     int             `yaml:"maxAge"`     // Days to keep rotated files, 0 keeps them
+	MaxBackups int             `yaml:"maxBackups"` // Rotated files to keep, 0 keeps all
+	AccessLog  AccessLogConfig `yaml:"accessLog"`
+}
+
+// AccessLogConfig holds the access log configuration for mocked traffic
+type AccessLogConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Format  string `yaml:"format"` // "combined" or "json"
+	File    string `yaml:"file"`   // Empty or "-" writes to stdout
 }
 
 // Default returns the default configuration
@@ -64,6 +170,12 @@ func Default() *Config {
 		Server: ServerConfig{
 			Port: 8080,
 			Host: "0.0.0.0",
+			Drain: DrainConfig{
+				Timeout: 30 * time.Second,
+			},
+			Utilities: UtilityConfig{
+				Prefix: "/_util",
+			},
 			TLS: TLSConfig{
 				Enabled:      false,
 				AutoGenerate: true,
@@ -73,14 +185,52 @@ func Default() *Config {
 		Storage: StorageConfig{
 			Type: "file",
 			Path: defaultDataPath,
+			Memory: MemoryLimitsConfig{
+				Eviction: "none",
+			},
+		},
+		Cluster: ClusterConfig{
+			PollInterval: 2 * time.Second,
+			Redis: ClusterRedisConfig{
+				Channel: "go-virtual:changes",
+			},
 		},
 		Tracing: TracingConfig{
-			MaxTraces: 1000,
-			Retention: 24 * time.Hour,
+			MaxTraces:     1000,
+			MaxBodySize:   65536,
+			RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"},
+			Retention:     24 * time.Hour,
+			Redis: TracingRedisConfig{
+				Channel: "go-virtual:traces",
+			},
 		},
 		Logging: LoggingConfig{
-			Level:  "info",
-			Format: "json",
+			Level:      "info",
+			Format:     "json",
+			MaxSize:    100,
+			MaxAge:     28,
+			MaxBackups: 5,
+			AccessLog: AccessLogConfig{
+				Format: "combined",
+			},
+		},
+		OAuth: oauth.Config{
+			TokenTTL:        oauth.DefaultTokenTTL,
+			RefreshTokenTTL: oauth.DefaultRefreshTokenTTL,
+		},
+		Events: EventsConfig{
+			Kafka: events.KafkaConfig{
+				ClientID: "go-virtual",
+				Acks:     "one",
+				Timeout:  events.DefaultKafkaTimeout,
+			},
+			MQTT: events.MQTTConfig{
+				ClientID: "go-virtual",
+				Timeout:  events.DefaultMQTTTimeout,
+			},
+			AMQP: events.AMQPConfig{
+				Timeout: events.DefaultAMQPTimeout,
+			},
 		},
 	}
 }
@@ -0,0 +1,100 @@
+// Package events publishes messages to brokers after mocked responses, so consumers of the
+// mocked API's events can be tested alongside it
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Message is an event ready to publish
+// Fields a broker kind has no use for are ignored, e.g. MQTT messages have no key or headers
+type Message struct {
+	Topic    string // Topic, or routing key of amqp messages
+	Key      string
+	Headers  map[string]string
+	Payload  []byte
+	Broker   string // Broker URL overriding the configured one (mqtt)
+	QoS      byte   // Delivery guarantee: 0 at most once, 1 at least once, 2 exactly once (mqtt)
+	Retain   bool   // Keep the message for new subscribers of the topic (mqtt)
+	Exchange string // Exchange to publish to; empty is the default exchange (amqp)
+}
+
+// Publisher sends messages to one kind of broker
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// ErrNoPublisher is returned for messages of a broker kind that isn't configured
+var ErrNoPublisher = errors.New("no publisher configured")
+
+// Dispatcher routes messages to the publisher of their broker kind
+type Dispatcher struct {
+	mu         sync.RWMutex
+	publishers map[string]Publisher // broker kind -> publisher
+}
+
+// NewDispatcher creates a dispatcher without publishers
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{publishers: make(map[string]Publisher)}
+}
+
+// SetPublisher sets the publisher of a broker kind, closing the one it replaces
+// A nil publisher removes the broker kind
+func (d *Dispatcher) SetPublisher(kind string, p Publisher) {
+	d.mu.Lock()
+	old := d.publishers[kind]
+	if p == nil {
+		delete(d.publishers, kind)
+	} else {
+		d.publishers[kind] = p
+	}
+	d.mu.Unlock()
+
+	if old != nil && old != p {
+		if err := old.Close(); err != nil {
+			slog.Warn("failed to close event publisher", "type", kind, "error", err)
+		}
+	}
+}
+
+// Publish sends a message with the publisher of a broker kind
+func (d *Dispatcher) Publish(ctx context.Context, kind string, msg Message) error {
+	d.mu.RLock()
+	p := d.publishers[kind]
+	d.mu.RUnlock()
+
+	if p == nil {
+		return fmt.Errorf("%w for %s", ErrNoPublisher, kind)
+	}
+	return p.Publish(ctx, msg)
+}
+
+// Close closes all publishers
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	publishers := d.publishers
+	d.publishers = make(map[string]Publisher)
+	d.mu.Unlock()
+
+	for kind, p := range publishers {
+		if err := p.Close(); err != nil {
+			slog.Warn("failed to close event publisher", "type", kind, "error", err)
+		}
+	}
+}
+
+// sortedHeaders returns the header names of a message in order, so messages are reproducible
+func sortedHeaders(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
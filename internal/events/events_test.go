@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingPublisher struct {
+	messages []Message
+	closed   bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, msg Message) error {
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	d := NewDispatcher()
+
+	if err := d.Publish(context.Background(), "kafka", Message{Topic: "orders"}); !errors.Is(err, ErrNoPublisher) {
+		t.Errorf("Expected ErrNoPublisher, got %v", err)
+	}
+
+	first := &recordingPublisher{}
+	d.SetPublisher("kafka", first)
+	if err := d.Publish(context.Background(), "kafka", Message{Topic: "orders"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(first.messages) != 1 || first.messages[0].Topic != "orders" {
+		t.Errorf("Unexpected messages: %+v", first.messages)
+	}
+
+	second := &recordingPublisher{}
+	d.SetPublisher("kafka", second)
+	if !first.closed {
+		t.Error("Expected the replaced publisher to be closed")
+	}
+
+	d.Close()
+	if !second.closed {
+		t.Error("Expected Close to close the publishers")
+	}
+	if err := d.Publish(context.Background(), "kafka", Message{}); !errors.Is(err, ErrNoPublisher) {
+		t.Errorf("Expected no publishers after Close, got %v", err)
+	}
+}
+
+func TestNewKafkaPublisher(t *testing.T) {
+	if _, err := NewKafkaPublisher(KafkaConfig{}); err == nil {
+		t.Error("Expected an error without brokers")
+	}
+	if _, err := NewKafkaPublisher(KafkaConfig{Brokers: []string{"localhost:9092"}, Acks: "some"}); err == nil {
+		t.Error("Expected an error for invalid acks")
+	}
+
+	p, err := NewKafkaPublisher(KafkaConfig{Brokers: []string{"localhost:9092"}, Acks: "all"})
+	if err != nil {
+		t.Fatalf("NewKafkaPublisher failed: %v", err)
+	}
+	if p.timeout != DefaultKafkaTimeout {
+		t.Errorf("Expected the default timeout, got %v", p.timeout)
+	}
+	p.Close()
+}
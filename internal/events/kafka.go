@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaConfig configures the brokers Kafka events are published to
+type KafkaConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Brokers  []string      `yaml:"brokers"`  // host:port of the bootstrap brokers
+	ClientID string        `yaml:"clientId"` // Reported to the brokers; default go-virtual
+	Acks     string        `yaml:"acks"`     // "none", "one" or "all"; default "one"
+	Timeout  time.Duration `yaml:"timeout"`  // Maximum time to deliver a message; default 10s
+}
+
+// DefaultKafkaTimeout is the delivery timeout of Kafka messages without a configured one
+const DefaultKafkaTimeout = 10 * time.Second
+
+// KafkaPublisher publishes messages to Kafka topics, creating topics the brokers allow
+type KafkaPublisher struct {
+	writer  *kafka.Writer
+	timeout time.Duration
+}
+
+// NewKafkaPublisher creates a publisher for the configured brokers
+// Connections are opened on the first message
+func NewKafkaPublisher(cfg KafkaConfig) (*KafkaPublisher, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka needs at least one broker")
+	}
+
+	acks := kafka.RequireOne
+	switch cfg.Acks {
+	case "", "one":
+	case "none":
+		acks = kafka.RequireNone
+	case "all":
+		acks = kafka.RequireAll
+	default:
+		return nil, errors.New(`kafka acks must be "none", "one" or "all"`)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultKafkaTimeout
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "go-virtual"
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Balancer:               &kafka.Hash{}, // Same key, same partition
+		RequiredAcks:           acks,
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           10 * time.Millisecond, // Events are sent one by one
+		WriteTimeout:           timeout,
+		Transport:              &kafka.Transport{ClientID: clientID},
+	}
+	return &KafkaPublisher{writer: writer, timeout: timeout}, nil
+}
+
+// Publish sends a message to its topic
+func (p *KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	message := kafka.Message{Topic: msg.Topic, Value: msg.Payload}
+	if msg.Key != "" {
+		message.Key = []byte(msg.Key)
+	}
+	for _, name := range sortedHeaders(msg.Headers) {
+		message.Headers = append(message.Headers, kafka.Header{Key: name, Value: []byte(msg.Headers[name])})
+	}
+	return p.writer.WriteMessages(ctx, message)
+}
+
+// Close flushes pending messages and closes the connections
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// MQTTConfig configures how MQTT events are published
+type MQTTConfig struct {
+	Broker   string        `yaml:"broker"`   // Default broker URL, e.g. tcp://localhost:1883; actions can name their own
+	ClientID string        `yaml:"clientId"` // Prefix of the client IDs; default go-virtual
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	Timeout  time.Duration `yaml:"timeout"` // Maximum time to connect or deliver a message; default 10s
+}
+
+// DefaultMQTTTimeout is the connect and delivery timeout of MQTT messages without a configured one
+const DefaultMQTTTimeout = 10 * time.Second
+
+// MQTTBrokerSchemes are the URL schemes of MQTT brokers
+var MQTTBrokerSchemes = []string{"tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss"}
+
+// MQTTPublisher publishes messages to MQTT brokers, keeping one connection per broker
+type MQTTPublisher struct {
+	cfg     MQTTConfig
+	mu      sync.Mutex
+	clients map[string]mqtt.Client // broker URL -> connected client
+}
+
+// NewMQTTPublisher creates a publisher; connections are opened on the first message to a broker
+func NewMQTTPublisher(cfg MQTTConfig) *MQTTPublisher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultMQTTTimeout
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "go-virtual"
+	}
+	return &MQTTPublisher{cfg: cfg, clients: make(map[string]mqtt.Client)}
+}
+
+// Publish sends a message to its topic on the message's broker or the configured one
+func (p *MQTTPublisher) Publish(ctx context.Context, msg Message) error {
+	broker := msg.Broker
+	if broker == "" {
+		broker = p.cfg.Broker
+	}
+	if broker == "" {
+		return errors.New("no MQTT broker configured")
+	}
+
+	client, err := p.client(broker)
+	if err != nil {
+		return err
+	}
+	token := client.Publish(msg.Topic, msg.QoS, msg.Retain, msg.Payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-time.After(p.cfg.Timeout):
+		return fmt.Errorf("timed out publishing to %s", broker)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// client returns the connected client of a broker, connecting on first use
+// Failed connections aren't kept, so the next message retries
+func (p *MQTTPublisher) client(broker string) (mqtt.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[broker]; ok {
+		return client, nil
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(p.cfg.ClientID + "-" + uuid.New().String()[:8]).
+		SetUsername(p.cfg.Username).
+		SetPassword(p.cfg.Password).
+		SetConnectTimeout(p.cfg.Timeout).
+		SetAutoReconnect(true)
+	client := mqtt.NewClient(opts)
+
+	token := client.Connect()
+	if !token.WaitTimeout(p.cfg.Timeout) {
+		return nil, fmt.Errorf("timed out connecting to MQTT broker %s", broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", broker, err)
+	}
+	p.clients[broker] = client
+	return client, nil
+}
+
+// Close disconnects from all brokers, waiting briefly for messages in flight
+func (p *MQTTPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for broker, client := range p.clients {
+		client.Disconnect(250)
+		delete(p.clients, broker)
+	}
+	return nil
+}
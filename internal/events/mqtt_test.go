@@ -0,0 +1,133 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// publishedPacket is a PUBLISH packet received by fakeBroker
+type publishedPacket struct {
+	topic   string
+	qos     byte
+	retain  bool
+	payload string
+}
+
+// fakeBroker accepts MQTT 3.1.1 connections and records published messages
+// It implements just enough of the protocol for a publishing client
+func fakeBroker(t *testing.T) (string, <-chan publishedPacket) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	published := make(chan publishedPacket, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeBroker(conn, published)
+		}
+	}()
+	return "tcp://" + listener.Addr().String(), published
+}
+
+func serveFakeBroker(conn net.Conn, published chan<- publishedPacket) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case 1: // CONNECT
+			conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		case 3: // PUBLISH
+			qos := (header >> 1) & 0x03
+			topicLen := int(binary.BigEndian.Uint16(body))
+			packet := publishedPacket{topic: string(body[2 : 2+topicLen]), qos: qos, retain: header&0x01 == 1}
+			rest := body[2+topicLen:]
+			if qos > 0 {
+				conn.Write([]byte{0x40, 0x02, rest[0], rest[1]})
+				rest = rest[2:]
+			}
+			packet.payload = string(rest)
+			published <- packet
+		case 12: // PINGREQ
+			conn.Write([]byte{0xD0, 0x00})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	broker, published := fakeBroker(t)
+
+	p := NewMQTTPublisher(MQTTConfig{Broker: broker, Timeout: 2 * time.Second})
+	defer p.Close()
+
+	if err := p.Publish(context.Background(), Message{Topic: "devices/7/commands", Payload: []byte(`{"cmd":"reboot"}`), QoS: 1, Retain: true}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case packet := <-published:
+		want := publishedPacket{topic: "devices/7/commands", qos: 1, retain: true, payload: `{"cmd":"reboot"}`}
+		if packet != want {
+			t.Errorf("Expected %+v, got %+v", want, packet)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the broker to receive the message")
+	}
+
+	// Messages can name their own broker
+	other, otherPublished := fakeBroker(t)
+	if err := p.Publish(context.Background(), Message{Topic: "alerts", Payload: []byte("on"), Broker: other}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case packet := <-otherPublished:
+		if packet.topic != "alerts" || packet.qos != 0 {
+			t.Errorf("Unexpected message: %+v", packet)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the message's broker to receive it")
+	}
+	if len(p.clients) != 2 {
+		t.Errorf("Expected one connection per broker, got %d", len(p.clients))
+	}
+}
+
+func TestMQTTPublisher_Errors(t *testing.T) {
+	p := NewMQTTPublisher(MQTTConfig{Timeout: time.Second})
+	defer p.Close()
+
+	if err := p.Publish(context.Background(), Message{Topic: "alerts"}); err == nil || !strings.Contains(err.Error(), "no MQTT broker") {
+		t.Errorf("Expected an error without a broker, got %v", err)
+	}
+	if err := p.Publish(context.Background(), Message{Topic: "alerts", Broker: "tcp://127.0.0.1:1"}); err == nil {
+		t.Error("Expected an error for an unreachable broker")
+	}
+	if len(p.clients) != 0 {
+		t.Error("Expected failed connections not to be kept")
+	}
+}
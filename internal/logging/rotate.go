@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp inserted into the names of rotated files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOptions limits the size and number of log files; zero values disable a limit
+type RotateOptions struct {
+	MaxSizeMB  int // Rotate when the file would grow past this size
+	MaxAgeDays int // Delete rotated files older than this
+	MaxBackups int // Keep at most this many rotated files
+}
+
+// RotatingFile is a log file that is renamed and replaced when it grows too large
+// Rotated files are named <name>-<timestamp><ext> in the same directory
+type RotatingFile struct {
+	mu   sync.Mutex
+	path string
+	opts RotateOptions
+	file *os.File
+	size int64
+	now  func() time.Time
+}
+
+// OpenRotatingFile opens a log file for appending, creating its directory if needed
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SetOptions changes the rotation limits, applying them on the next write
+func (f *RotatingFile) SetOptions(opts RotateOptions) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opts = opts
+}
+
+// Write appends to the file, rotating it first if the write would exceed the size limit
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	maxSize := int64(f.opts.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the current file, remembering its size
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate renames the current file to a timestamped backup, starts a new one and prunes old backups
+// On failure the current path is reopened for appending, so later writes still reach the log
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return f.reopen(err)
+	}
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		return f.reopen(fmt.Errorf("failed to rotate log file: %w", err))
+	}
+	if err := f.open(); err != nil {
+		return f.reopen(err)
+	}
+
+	f.prune()
+	return nil
+}
+
+// reopen appends to the current path again after a failed rotation and returns its error
+// The next write that exceeds the size limit retries the rotation
+func (f *RotatingFile) reopen(err error) error {
+	if openErr := f.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// backupName returns the name of a backup rotated at t
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	return base + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// prune deletes backups beyond MaxBackups or older than MaxAgeDays
+// Errors are ignored; a leftover backup is retried on the next rotation
+func (f *RotatingFile) prune() {
+	if f.opts.MaxBackups <= 0 && f.opts.MaxAgeDays <= 0 {
+		return
+	}
+
+	backups := f.backups()
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	cutoff := f.now().Add(-time.Duration(f.opts.MaxAgeDays) * 24 * time.Hour)
+	for i, backup := range backups {
+		tooMany := f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups
+		tooOld := f.opts.MaxAgeDays > 0 && backup.time.Before(cutoff)
+		if tooMany || tooOld {
+			os.Remove(backup.path)
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups lists the rotated files of this log
+func (f *RotatingFile) backups() []backupFile {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	return backups
+}
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go-virtual.log")
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// Each chunk after the first starts a new file; only the two newest backups are kept
+	backups := f.backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(backups))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go-virtual-2024-01-01T00-01-00.000.log")); !os.IsNotExist(err) {
+		t.Error("Expected the oldest backup to be deleted")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(chunk)) {
+		t.Errorf("Expected the current file to hold one chunk, got %d bytes", info.Size())
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	old := filepath.Join(dir, "app-2020-01-01T00-00-00.000.log")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(dir, "app-notes.log")
+	if err := os.WriteFile(unrelated, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSizeMB: 1, MaxAgeDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	f.Write(chunk)
+	f.Write(chunk)
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the expired backup to be deleted")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("Expected files that are not backups to be kept")
+	}
+	if len(f.backups()) != 1 {
+		t.Errorf("Expected 1 backup, got %d", len(f.backups()))
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	for i := 0; i < 2; i++ {
+		f, err := OpenRotatingFile(path, RotateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("line\n"))
+		f.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "line\nline\n" {
+		t.Errorf("Expected appended lines, got %q", data)
+	}
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go-virtual.log")
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	// A non-empty directory in place of the backup makes the rename fail
+	backup := f.backupName(f.now())
+	os.MkdirAll(filepath.Join(backup, "taken"), 0755)
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	f.Write(chunk)
+	if _, err := f.Write(chunk); err == nil {
+		t.Fatal("Expected the failed rotation to be reported")
+	}
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Expected writes to continue after a failed rotation, got %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() <= int64(len(chunk)) {
+		t.Errorf("Expected later writes appended to the original file, got %d bytes", info.Size())
+	}
+}
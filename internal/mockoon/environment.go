@@ -0,0 +1,115 @@
+// Package mockoon converts between Mockoon environment files and go-virtual specs
+package mockoon
+
+// lastMigration is the Mockoon data migration environments are written with
+// Mockoon upgrades files with older migrations when it opens them
+const lastMigration = 32
+
+// Environment is a Mockoon environment file
+type Environment struct {
+	UUID              string        `json:"uuid"`
+	LastMigration     int           `json:"lastMigration"`
+	Name              string        `json:"name"`
+	EndpointPrefix    string        `json:"endpointPrefix"`
+	Latency           int           `json:"latency"`
+	Port              int           `json:"port"`
+	Hostname          string        `json:"hostname"`
+	Folders           []interface{} `json:"folders"`
+	Routes            []Route       `json:"routes"`
+	RootChildren      []Child       `json:"rootChildren"`
+	ProxyMode         bool          `json:"proxyMode"`
+	ProxyHost         string        `json:"proxyHost"`
+	ProxyRemovePrefix bool          `json:"proxyRemovePrefix"`
+	TLSOptions        TLSOptions    `json:"tlsOptions"`
+	CORS              bool          `json:"cors"`
+	Headers           []Header      `json:"headers"`
+	ProxyReqHeaders   []Header      `json:"proxyReqHeaders"`
+	ProxyResHeaders   []Header      `json:"proxyResHeaders"`
+	Data              []interface{} `json:"data"`
+	Callbacks         []interface{} `json:"callbacks"`
+}
+
+// Route is an endpoint of an environment with its responses
+type Route struct {
+	UUID              string     `json:"uuid"`
+	Type              string     `json:"type"`
+	Documentation     string     `json:"documentation"`
+	Method            string     `json:"method"`
+	Endpoint          string     `json:"endpoint"`
+	Responses         []Response `json:"responses"`
+	ResponseMode      *string    `json:"responseMode"`
+	StreamingMode     *string    `json:"streamingMode"`
+	StreamingInterval int        `json:"streamingInterval"`
+}
+
+// Response is a response of a route, served when its rules match
+type Response struct {
+	UUID              string        `json:"uuid"`
+	Body              string        `json:"body"`
+	Latency           int           `json:"latency"`
+	StatusCode        int           `json:"statusCode"`
+	Label             string        `json:"label"`
+	Headers           []Header      `json:"headers"`
+	BodyType          string        `json:"bodyType"`
+	FilePath          string        `json:"filePath"`
+	DatabucketID      string        `json:"databucketID"`
+	SendFileAsBody    bool          `json:"sendFileAsBody"`
+	Rules             []Rule        `json:"rules"`
+	RulesOperator     string        `json:"rulesOperator"`
+	DisableTemplating bool          `json:"disableTemplating"`
+	FallbackTo404     bool          `json:"fallbackTo404"`
+	Default           bool          `json:"default"`
+	CrudKey           string        `json:"crudKey"`
+	Callbacks         []interface{} `json:"callbacks"`
+}
+
+// Rule matches a part of the request
+type Rule struct {
+	Target   string `json:"target"`
+	Modifier string `json:"modifier"`
+	Value    string `json:"value"`
+	Invert   bool   `json:"invert"`
+	Operator string `json:"operator"`
+}
+
+// Header is a header of a response or environment
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Child places a route or folder in the environment's tree
+type Child struct {
+	Type string `json:"type"`
+	UUID string `json:"uuid"`
+}
+
+// TLSOptions holds the TLS settings of an environment
+type TLSOptions struct {
+	Enabled    bool   `json:"enabled"`
+	Type       string `json:"type"`
+	PfxPath    string `json:"pfxPath"`
+	CertPath   string `json:"certPath"`
+	KeyPath    string `json:"keyPath"`
+	CaPath     string `json:"caPath"`
+	Passphrase string `json:"passphrase"`
+}
+
+// Values of Mockoon enumerations
+const (
+	bodyTypeInline = "INLINE"
+	bodyTypeFile   = "FILE"
+
+	rulesAnd = "AND"
+	rulesOr  = "OR"
+
+	ruleEquals = "equals"
+	ruleRegex  = "regex"
+	ruleNull   = "null"
+
+	targetBody      = "body"
+	targetQuery     = "query"
+	targetHeader    = "header"
+	targetParams    = "params"
+	targetGlobalVar = "global_var"
+)
@@ -0,0 +1,251 @@
+package mockoon
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// defaultPort is the port Mockoon gives new environments
+const defaultPort = 3000
+
+// pathParam matches the parameters of an operation path
+var pathParam = regexp.MustCompile(`\{([^/{}]+)\}`)
+
+// Export converts a spec with the enabled response configs of its operations into an
+// environment. Operations without enabled configs are left out, since Mockoon can't fall back
+// to spec examples. Parts Mockoon can't express are listed in the documentation of their route.
+func Export(spec *models.Spec, ops []*models.Operation, configs map[string][]*models.ResponseConfig) *Environment {
+	env := &Environment{
+		UUID:            exportID(spec.ID),
+		LastMigration:   lastMigration,
+		Name:            spec.Name,
+		Port:            defaultPort,
+		Folders:         make([]interface{}, 0),
+		Routes:          make([]Route, 0, len(ops)),
+		RootChildren:    make([]Child, 0, len(ops)),
+		TLSOptions:      TLSOptions{Type: "CERT"},
+		CORS:            true,
+		Headers:         make([]Header, 0),
+		ProxyReqHeaders: make([]Header, 0),
+		ProxyResHeaders: make([]Header, 0),
+		Data:            make([]interface{}, 0),
+		Callbacks:       make([]interface{}, 0),
+		ProxyHost:       spec.Upstream,
+	}
+
+	for _, op := range ops {
+		route, ok := exportRoute(op, configs[op.ID])
+		if !ok {
+			continue
+		}
+		env.Routes = append(env.Routes, route)
+		env.RootChildren = append(env.RootChildren, Child{Type: "route", UUID: route.UUID})
+	}
+	return env
+}
+
+// exportRoute converts an operation with its response configs, which are in match order
+func exportRoute(op *models.Operation, configs []*models.ResponseConfig) (Route, bool) {
+	fullPath := op.FullPath
+	if fullPath == "" {
+		fullPath = op.Path
+	}
+
+	route := Route{
+		UUID:          exportID(op.ID),
+		Type:          "http",
+		Documentation: op.Summary,
+		Method:        strings.ToLower(op.Method),
+		Endpoint:      strings.TrimPrefix(pathParam.ReplaceAllString(fullPath, ":$1"), "/"),
+		Responses:     make([]Response, 0, len(configs)),
+	}
+
+	var warnings []string
+	defaultIndex := -1
+	for _, cfg := range configs {
+		if !cfg.Enabled {
+			continue
+		}
+		resp, notes := exportResponse(cfg)
+		if defaultIndex < 0 && len(resp.Rules) == 0 {
+			defaultIndex = len(route.Responses)
+		}
+		route.Responses = append(route.Responses, resp)
+		for _, note := range notes {
+			warnings = append(warnings, strconv.Quote(cfg.Name)+": "+note)
+		}
+	}
+	if len(route.Responses) == 0 {
+		return route, false
+	}
+
+	// Mockoon serves the default response when no rules match
+	route.Responses[max(defaultIndex, 0)].Default = true
+
+	if len(warnings) > 0 {
+		if route.Documentation != "" {
+			route.Documentation += "\n\n"
+		}
+		route.Documentation += "Not exported from go-virtual: " + strings.Join(warnings, "; ")
+	}
+	return route, true
+}
+
+// exportResponse converts a response config, returning notes on the parts left out
+func exportResponse(cfg *models.ResponseConfig) (Response, []string) {
+	resp := Response{
+		UUID:          exportID(cfg.ID),
+		Latency:       cfg.Delay,
+		StatusCode:    cfg.StatusCode,
+		Label:         cfg.Name,
+		Headers:       make([]Header, 0, len(cfg.Headers)),
+		BodyType:      bodyTypeInline,
+		Rules:         make([]Rule, 0, len(cfg.Conditions)),
+		RulesOperator: rulesAnd,
+		Callbacks:     make([]interface{}, 0),
+	}
+
+	names := make([]string, 0, len(cfg.Headers))
+	for name := range cfg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		resp.Headers = append(resp.Headers, Header{Key: name, Value: exportTemplate(cfg.Headers[name])})
+	}
+
+	if cfg.BodyFile != "" {
+		resp.BodyType = bodyTypeFile
+		resp.FilePath = cfg.BodyFile
+		resp.SendFileAsBody = true
+	} else {
+		resp.Body = exportTemplate(cfg.Body)
+	}
+
+	var notes []string
+	for _, cond := range cfg.Conditions {
+		rule, ok := exportRule(cond)
+		if !ok {
+			notes = append(notes, "condition on "+cond.Source+" with "+cond.Operator)
+			continue
+		}
+		resp.Rules = append(resp.Rules, rule)
+	}
+	if cfg.NegateConditions && len(resp.Rules) > 0 {
+		// not (a and b) is (not a) or (not b)
+		for i := range resp.Rules {
+			resp.Rules[i].Invert = !resp.Rules[i].Invert
+		}
+		resp.RulesOperator = rulesOr
+	}
+
+	if cfg.BodyFile != "" {
+		notes = append(notes, "copy the body file "+cfg.BodyFile+" next to the environment")
+	}
+	if cfg.Fault != nil {
+		notes = append(notes, cfg.Fault.Type+" fault")
+	}
+	if cfg.Pagination != nil {
+		notes = append(notes, "pagination")
+	}
+	if cfg.SOAPFault != nil {
+		notes = append(notes, "SOAP fault")
+	}
+	if len(cfg.Events) > 0 {
+		notes = append(notes, "events")
+	}
+	return resp, notes
+}
+
+// exportRule converts a condition into a rule
+func exportRule(cond models.Condition) (Rule, bool) {
+	rule := Rule{Modifier: cond.Key}
+	switch cond.Source {
+	case models.SourceQuery:
+		rule.Target = targetQuery
+	case models.SourceHeader:
+		rule.Target = targetHeader
+	case models.SourcePath:
+		rule.Target = targetParams
+	case models.SourceVar:
+		rule.Target = targetGlobalVar
+	case models.SourceBody:
+		if strings.ContainsAny(cond.Key, "#@|*?\\") {
+			return rule, false
+		}
+		rule.Target = targetBody
+	case models.SourceRawBody:
+		rule.Target, rule.Modifier = targetBody, ""
+	case models.SourceContentType:
+		rule.Target, rule.Modifier = targetHeader, "Content-Type"
+		if cond.Operator == models.OpEquals {
+			// The condition ignores parameters such as charset
+			return Rule{Target: targetHeader, Modifier: "Content-Type", Operator: ruleRegex, Value: "^" + regexp.QuoteMeta(cond.Value) + "(;|$)"}, true
+		}
+	default:
+		return rule, false
+	}
+
+	switch cond.Operator {
+	case models.OpEquals:
+		rule.Operator, rule.Value = ruleEquals, cond.Value
+	case models.OpNotEquals:
+		rule.Operator, rule.Value, rule.Invert = ruleEquals, cond.Value, true
+	case models.OpContains:
+		rule.Operator, rule.Value = ruleRegex, regexp.QuoteMeta(cond.Value)
+	case models.OpNotContains:
+		rule.Operator, rule.Value, rule.Invert = ruleRegex, regexp.QuoteMeta(cond.Value), true
+	case models.OpRegex:
+		rule.Operator, rule.Value = ruleRegex, cond.Value
+	case models.OpStartsWith:
+		rule.Operator, rule.Value = ruleRegex, "^"+regexp.QuoteMeta(cond.Value)
+	case models.OpEndsWith:
+		rule.Operator, rule.Value = ruleRegex, regexp.QuoteMeta(cond.Value)+"$"
+	case models.OpExists:
+		rule.Operator, rule.Invert = ruleNull, true
+	case models.OpNotExists:
+		rule.Operator = ruleNull
+	default:
+		return rule, false
+	}
+	return rule, true
+}
+
+// exportReplacements rewrite go-virtual template variables into Mockoon's Handlebars helpers
+var exportReplacements = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`\{\{\s*query\.([\w-]+)\s*\}\}`), "{{queryParam '$1'}}"},
+	{regexp.MustCompile(`\{\{\s*header\.([\w-]+)\s*\}\}`), "{{header '$1'}}"},
+	{regexp.MustCompile(`\{\{\s*path\.([\w-]+)\s*\}\}`), "{{urlParam '$1'}}"},
+	{regexp.MustCompile(`\{\{\s*body\.([\w.]+)\s*\}\}`), "{{body '$1'}}"},
+	{regexp.MustCompile(`\{\{\s*body\s*\}\}`), "{{bodyRaw}}"},
+	{regexp.MustCompile(`\{\{\s*request\.method\s*\}\}`), "{{method}}"},
+	{regexp.MustCompile(`\{\{\s*random\.uuid\s*\}\}`), "{{faker 'string.uuid'}}"},
+	{regexp.MustCompile(`\{\{\s*random\.int\((-?\d+),\s*(-?\d+)\)\s*\}\}`), "{{int $1 $2}}"},
+	{regexp.MustCompile(`\{\{\s*timestamp\.iso\s*\}\}`), "{{now}}"},
+}
+
+// exportTemplate rewrites the template variables of a body or header that have a Mockoon
+// equivalent; others are left as they are
+func exportTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	for _, r := range exportReplacements {
+		s = r.pattern.ReplaceAllString(s, r.replace)
+	}
+	return s
+}
+
+// exportID derives a stable UUID from a go-virtual ID, so repeated exports keep their UUIDs
+func exportID(id string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("go-virtual:"+id)).String()
+}
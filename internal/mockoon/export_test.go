@@ -0,0 +1,110 @@
+package mockoon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestExport(t *testing.T) {
+	spec := &models.Spec{ID: "spec-1", Name: "Pets", Upstream: "https://pets.example.com"}
+	ops := []*models.Operation{
+		{ID: "op-1", Method: "GET", Path: "/pets/{petId}", FullPath: "/api/pets/{petId}", Summary: "Get a pet"},
+		{ID: "op-2", Method: "DELETE", Path: "/pets/{petId}", FullPath: "/api/pets/{petId}"},
+	}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {
+			{
+				ID:         "resp-1",
+				Name:       "Missing pet",
+				Conditions: []models.Condition{{Source: "path", Key: "petId", Operator: "eq", Value: "0"}},
+				StatusCode: 404,
+				Enabled:    true,
+			},
+			{
+				ID:         "resp-2",
+				Name:       "Pet",
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"id": "{{path.petId}}", "tag": "{{query.tag}}", "ref": "{{random.uuid}}"}`,
+				Delay:      50,
+				Enabled:    true,
+				Events:     []models.EventAction{{Channel: "pets"}},
+			},
+		},
+		"op-2": {{ID: "resp-3", Name: "Disabled", Enabled: false}},
+	}
+
+	env := Export(spec, ops, configs)
+
+	if env.Name != "Pets" || env.UUID != exportID("spec-1") || env.LastMigration != lastMigration || env.ProxyHost != "https://pets.example.com" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+	if len(env.Routes) != 1 || len(env.RootChildren) != 1 || env.RootChildren[0].UUID != env.Routes[0].UUID {
+		t.Fatalf("Expected one route, got %+v", env.Routes)
+	}
+
+	route := env.Routes[0]
+	if route.Method != "get" || route.Endpoint != "api/pets/:petId" {
+		t.Errorf("Unexpected route: %s %s", route.Method, route.Endpoint)
+	}
+	if !strings.HasPrefix(route.Documentation, "Get a pet\n\n") || !strings.Contains(route.Documentation, `"Pet": events`) {
+		t.Errorf("Expected the left out events in the documentation, got %q", route.Documentation)
+	}
+
+	missing, pet := route.Responses[0], route.Responses[1]
+	if missing.Default || !pet.Default {
+		t.Error("Expected the response without rules to be the default")
+	}
+	if !reflect.DeepEqual(missing.Rules, []Rule{{Target: "params", Modifier: "petId", Value: "0", Operator: "equals"}}) || missing.StatusCode != 404 {
+		t.Errorf("Unexpected rules: %+v", missing.Rules)
+	}
+	if pet.Body != `{"id": "{{urlParam 'petId'}}", "tag": "{{queryParam 'tag'}}", "ref": "{{faker 'string.uuid'}}"}` {
+		t.Errorf("Templates not converted: %s", pet.Body)
+	}
+	if pet.Latency != 50 || pet.Label != "Pet" || !reflect.DeepEqual(pet.Headers, []Header{{Key: "Content-Type", Value: "application/json"}}) {
+		t.Errorf("Unexpected response: %+v", pet)
+	}
+}
+
+func TestExportRule(t *testing.T) {
+	tests := []struct {
+		cond models.Condition
+		want Rule
+		ok   bool
+	}{
+		{models.Condition{Source: "query", Key: "q", Operator: "contains", Value: "a.b"}, Rule{Target: "query", Modifier: "q", Operator: "regex", Value: `a\.b`}, true},
+		{models.Condition{Source: "header", Key: "X-Debug", Operator: "exists"}, Rule{Target: "header", Modifier: "X-Debug", Operator: "null", Invert: true}, true},
+		{models.Condition{Source: "body", Key: "user.role", Operator: "ne", Value: "admin"}, Rule{Target: "body", Modifier: "user.role", Operator: "equals", Value: "admin", Invert: true}, true},
+		{models.Condition{Source: "rawBody", Operator: "regex", Value: "^<xml"}, Rule{Target: "body", Operator: "regex", Value: "^<xml"}, true},
+		{models.Condition{Source: "contentType", Operator: "eq", Value: "application/json"}, Rule{Target: "header", Modifier: "Content-Type", Operator: "regex", Value: `^application/json(;|$)`}, true},
+		{models.Condition{Source: "jwt", Key: "sub", Operator: "eq", Value: "alice"}, Rule{}, false},
+		{models.Condition{Source: "query", Key: "n", Operator: "gt", Value: "3"}, Rule{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := exportRule(tt.cond)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("exportRule(%+v) = %+v, %v; want %+v, %v", tt.cond, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExport_NegatedConditions(t *testing.T) {
+	cfg := &models.ResponseConfig{
+		ID:               "resp-1",
+		NegateConditions: true,
+		Conditions: []models.Condition{
+			{Source: "header", Key: "X-Role", Operator: "eq", Value: "admin"},
+			{Source: "query", Key: "debug", Operator: "notExists"},
+		},
+		Enabled: true,
+	}
+
+	resp, _ := exportResponse(cfg)
+	if resp.RulesOperator != "OR" || !resp.Rules[0].Invert || !resp.Rules[1].Invert {
+		t.Errorf("Expected inverted rules joined with OR, got %+v", resp)
+	}
+}
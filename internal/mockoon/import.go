@@ -0,0 +1,415 @@
+package mockoon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// importMethods are the methods a route of any method ("all") is imported as
+var importMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// Import is a Mockoon environment converted into a spec
+type Import struct {
+	Name     string
+	BasePath string // From the endpoint prefix
+	Upstream string // Proxy host of an environment in proxy mode
+	Content  string // OpenAPI document describing the routes
+	Routes   []ImportedRoute
+	Warnings []string // Parts of the environment that could not be converted
+}
+
+// ImportedRoute is an operation with its response configs, in match order
+type ImportedRoute struct {
+	Method    string
+	Path      string
+	Responses []models.ResponseConfigInput
+}
+
+// endpointParam matches the parameters of a Mockoon endpoint
+var endpointParam = regexp.MustCompile(`^:(\w+)$`)
+
+// Parse converts a Mockoon environment file into a spec with an OpenAPI document, operations
+// and response configs. Rules become conditions and templated bodies are rewritten into
+// go-virtual template variables where an equivalent exists.
+func Parse(data []byte) (*Import, error) {
+	var env Environment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Routes == nil {
+		return nil, errors.New("not a Mockoon environment: no routes")
+	}
+
+	imp := &Import{Name: env.Name}
+	if imp.Name == "" {
+		imp.Name = "Mockoon environment"
+	}
+	if prefix := strings.Trim(env.EndpointPrefix, "/"); prefix != "" {
+		imp.BasePath = "/" + prefix
+	}
+	if env.ProxyMode {
+		imp.Upstream = env.ProxyHost
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for i := range env.Routes {
+		route := &env.Routes[i]
+		if route.Type != "" && route.Type != "http" {
+			imp.warn(route, "routes of type "+route.Type+" are not supported")
+			continue
+		}
+
+		opPath, params, ok := convertEndpoint(route.Endpoint)
+		if !ok {
+			imp.warn(route, "wildcards and patterns in endpoints are not supported")
+			continue
+		}
+
+		methods := []string{strings.ToUpper(route.Method)}
+		if methods[0] == "ALL" {
+			methods = importMethods
+		}
+		responses := imp.convertResponses(route, env.Headers, env.Latency)
+
+		for _, method := range methods {
+			if paths[opPath] == nil {
+				paths[opPath] = make(map[string]interface{})
+			}
+			if _, exists := paths[opPath][strings.ToLower(method)]; exists {
+				imp.warn(route, "an earlier route already serves "+method+" "+opPath)
+				continue
+			}
+			paths[opPath][strings.ToLower(method)] = openAPIOperation(route, params, responses)
+			imp.Routes = append(imp.Routes, ImportedRoute{Method: method, Path: opPath, Responses: responses})
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       imp.Name,
+			"version":     "1.0.0",
+			"description": "Imported from a Mockoon environment",
+		},
+		"paths": paths,
+	}
+	content, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	imp.Content = string(content)
+	return imp, nil
+}
+
+// convertEndpoint turns an endpoint like users/:id into an OpenAPI path and its parameters
+func convertEndpoint(endpoint string) (string, []string, bool) {
+	var params []string
+	segments := strings.Split(strings.Trim(endpoint, "/"), "/")
+	for i, segment := range segments {
+		if m := endpointParam.FindStringSubmatch(segment); m != nil {
+			segments[i] = "{" + m[1] + "}"
+			params = append(params, m[1])
+			continue
+		}
+		if strings.ContainsAny(segment, ":*?+()[]{}") {
+			return "", nil, false
+		}
+	}
+	return path.Clean("/" + strings.Join(segments, "/")), params, true
+}
+
+// openAPIOperation describes a route in the generated OpenAPI document
+func openAPIOperation(route *Route, params []string, responses []models.ResponseConfigInput) map[string]interface{} {
+	op := map[string]interface{}{}
+	if summary, _, _ := strings.Cut(strings.TrimSpace(route.Documentation), "\n"); summary != "" {
+		op["summary"] = summary
+	}
+
+	if len(params) > 0 {
+		parameters := make([]interface{}, 0, len(params))
+		for _, name := range params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		op["parameters"] = parameters
+	}
+
+	described := map[string]interface{}{}
+	for _, resp := range responses {
+		code := strconv.Itoa(resp.StatusCode)
+		if _, ok := described[code]; !ok {
+			described[code] = map[string]interface{}{"description": resp.Name}
+		}
+	}
+	if len(described) == 0 {
+		described["default"] = map[string]interface{}{"description": "Response"}
+	}
+	op["responses"] = described
+	return op
+}
+
+// convertResponses converts the responses of a route into response configs in match order
+// Mockoon serves its default response when no rules match, so it is placed last without
+// conditions. Responses with OR rules become a config per rule.
+func (imp *Import) convertResponses(route *Route, envHeaders []Header, envLatency int) []models.ResponseConfigInput {
+	if route.ResponseMode != nil && *route.ResponseMode != "" {
+		imp.warn(route, "response mode "+*route.ResponseMode+" is not supported; responses are chosen by their rules")
+	}
+
+	defaultIndex := 0
+	for i, resp := range route.Responses {
+		if resp.Default {
+			defaultIndex = i
+			break
+		}
+	}
+
+	var inputs []models.ResponseConfigInput
+	var fallback *models.ResponseConfigInput
+	for i := range route.Responses {
+		resp := &route.Responses[i]
+		input := imp.convertResponse(route, resp, i, envHeaders, envLatency)
+
+		if i == defaultIndex {
+			input.Conditions = make([]models.Condition, 0)
+			fallback = &input
+			continue
+		}
+		if len(resp.Rules) == 0 {
+			imp.warn(route, strconv.Quote(input.Name)+" has no rules and isn't the default, so Mockoon never serves it")
+			continue
+		}
+
+		conditions, ok := imp.convertRules(route, input.Name, resp.Rules)
+		if !ok {
+			input.Enabled = false
+		}
+		if resp.RulesOperator == rulesOr && len(conditions) > 1 {
+			for _, cond := range conditions {
+				alternative := input
+				alternative.Conditions = []models.Condition{cond}
+				inputs = append(inputs, alternative)
+			}
+			continue
+		}
+		input.Conditions = conditions
+		inputs = append(inputs, input)
+	}
+	if fallback != nil {
+		inputs = append(inputs, *fallback)
+	}
+	for i := range inputs {
+		inputs[i].Priority = i
+	}
+	return inputs
+}
+
+// convertResponse converts the parts of a response other than its rules
+func (imp *Import) convertResponse(route *Route, resp *Response, index int, envHeaders []Header, envLatency int) models.ResponseConfigInput {
+	input := models.ResponseConfigInput{
+		Name:       resp.Label,
+		StatusCode: resp.StatusCode,
+		Headers:    make(map[string]string),
+		Delay:      envLatency + resp.Latency,
+		Enabled:    true,
+	}
+	if input.Name == "" {
+		input.Name = "Response " + strconv.Itoa(index+1)
+	}
+	if input.StatusCode == 0 {
+		input.StatusCode = 200
+	}
+	for _, h := range envHeaders {
+		if h.Key != "" {
+			input.Headers[h.Key] = h.Value
+		}
+	}
+	for _, h := range resp.Headers {
+		if h.Key != "" {
+			input.Headers[h.Key] = h.Value
+		}
+	}
+
+	switch resp.BodyType {
+	case bodyTypeFile:
+		input.BodyFile = path.Base(strings.ReplaceAll(resp.FilePath, "\\", "/"))
+		imp.warn(route, "upload the body file "+input.BodyFile+" of "+strconv.Quote(input.Name)+" to the spec's files")
+	case "DATABUCKET":
+		imp.warn(route, "the data bucket body of "+strconv.Quote(input.Name)+" is not supported")
+	default:
+		input.Body = resp.Body
+	}
+
+	if resp.DisableTemplating {
+		if strings.Contains(input.Body, "{{") {
+			imp.warn(route, strconv.Quote(input.Name)+" disables templating, but go-virtual renders {{ in its body")
+		}
+	} else {
+		input.Body = importTemplate(input.Body)
+		for key, value := range input.Headers {
+			input.Headers[key] = importTemplate(value)
+		}
+		if strings.Contains(input.Body, "{{#") {
+			imp.warn(route, "block helpers in the body of "+strconv.Quote(input.Name)+" are not supported")
+		}
+	}
+	if len(resp.Callbacks) > 0 {
+		imp.warn(route, "callbacks of "+strconv.Quote(input.Name)+" are not supported")
+	}
+	return input
+}
+
+// convertRules converts the rules of a response into conditions
+// It reports false when a rule has no equivalent; that rule is left out and the response disabled
+func (imp *Import) convertRules(route *Route, name string, rules []Rule) ([]models.Condition, bool) {
+	conditions := make([]models.Condition, 0, len(rules))
+	ok := true
+	for _, rule := range rules {
+		cond, err := importRule(rule)
+		if err != nil {
+			imp.warn(route, strconv.Quote(name)+" is disabled: "+err.Error())
+			ok = false
+			continue
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, ok
+}
+
+// importRule converts a rule into a condition
+func importRule(rule Rule) (models.Condition, error) {
+	cond := models.Condition{Key: rule.Modifier, Value: rule.Value}
+	switch rule.Target {
+	case targetQuery:
+		cond.Source = models.SourceQuery
+	case targetHeader:
+		cond.Source = models.SourceHeader
+	case targetParams:
+		cond.Source = models.SourcePath
+	case targetGlobalVar:
+		cond.Source = models.SourceVar
+	case targetBody:
+		cond.Source = models.SourceBody
+		if rule.Modifier == "" {
+			cond.Source = models.SourceRawBody
+			break
+		}
+		key, ok := objectPath(rule.Modifier)
+		if !ok {
+			return cond, fmt.Errorf("body path %q is not supported", rule.Modifier)
+		}
+		cond.Key = key
+	default:
+		return cond, fmt.Errorf("rules on %s are not supported", rule.Target)
+	}
+
+	switch {
+	case rule.Operator == ruleEquals && !rule.Invert:
+		cond.Operator = models.OpEquals
+	case rule.Operator == ruleEquals:
+		cond.Operator = models.OpNotEquals
+	case rule.Operator == ruleRegex && !rule.Invert:
+		cond.Operator = models.OpRegex
+	case rule.Operator == "regex_i" && !rule.Invert:
+		cond.Operator, cond.Value = models.OpRegex, "(?i)"+rule.Value
+	case rule.Operator == ruleNull && !rule.Invert:
+		cond.Operator, cond.Value = models.OpNotExists, ""
+	case rule.Operator == ruleNull:
+		cond.Operator, cond.Value = models.OpExists, ""
+	default:
+		operator := rule.Operator
+		if rule.Invert {
+			operator = "inverted " + operator
+		}
+		return cond, fmt.Errorf("%s rules are not supported", operator)
+	}
+	return cond, nil
+}
+
+// objectPath converts a Mockoon body path, either object-path like items.0.name or a simple
+// JSON path like $.items[0].name, into gjson syntax
+func objectPath(modifier string) (string, bool) {
+	if !strings.HasPrefix(modifier, "$") {
+		return modifier, !strings.ContainsAny(modifier, "*?#@|")
+	}
+	if strings.Contains(modifier, "..") || strings.ContainsAny(modifier, "?*@()") {
+		return "", false
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(modifier, "$"), ".")
+	key = jsonPathIndex.ReplaceAllString(key, ".$1")
+	key = jsonPathQuote.ReplaceAllString(key, ".$1")
+	key = strings.TrimPrefix(key, ".")
+	return key, key != "" && !strings.ContainsAny(key, "[]")
+}
+
+var (
+	jsonPathIndex = regexp.MustCompile(`\[(\d+)\]`)
+	jsonPathQuote = regexp.MustCompile(`\['([^']*)'\]`)
+)
+
+// fakerFields map Mockoon's Faker.js methods onto {{fake.*}} fields
+var fakerFields = map[string]string{
+	"person.firstName":       "firstName",
+	"person.lastName":        "lastName",
+	"person.fullName":        "name",
+	"phone.number":           "phone",
+	"location.city":          "city",
+	"location.street":        "street",
+	"location.streetAddress": "address",
+	"location.zipCode":       "postcode",
+	"location.country":       "country",
+}
+
+// importReplacements rewrite Mockoon's Handlebars helpers into go-virtual template variables
+var importReplacements = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`\{\{\s*queryParam\s+'([\w-]+)'\s*\}\}`), "{{query.$1}}"},
+	{regexp.MustCompile(`\{\{\s*header\s+'([\w-]+)'\s*\}\}`), "{{header.$1}}"},
+	{regexp.MustCompile(`\{\{\s*urlParam\s+'([\w-]+)'\s*\}\}`), "{{path.$1}}"},
+	{regexp.MustCompile(`\{\{\s*(?:body|bodyRaw)\s+'([\w.]+)'\s*\}\}`), "{{body.$1}}"},
+	{regexp.MustCompile(`\{\{\s*(?:body|bodyRaw)\s*\}\}`), "{{body}}"},
+	{regexp.MustCompile(`\{\{\s*method\s*\}\}`), "{{request.method}}"},
+	{regexp.MustCompile(`\{\{\s*faker\s+'(?:string|datatype)\.uuid'\s*\}\}`), "{{random.uuid}}"},
+	{regexp.MustCompile(`\{\{\s*int\s+(-?\d+)\s+(-?\d+)\s*\}\}`), "{{random.int($1,$2)}}"},
+	{regexp.MustCompile(`\{\{\s*now\s*\}\}`), "{{timestamp.iso}}"},
+}
+
+// fakerCall matches a Faker.js helper such as {{faker 'person.firstName'}}
+var fakerCall = regexp.MustCompile(`\{\{\s*faker\s+'([\w.]+)'\s*\}\}`)
+
+// importTemplate rewrites the helpers of a body or header that have a go-virtual equivalent;
+// others are left as they are
+func importTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	for _, r := range importReplacements {
+		s = r.pattern.ReplaceAllString(s, r.replace)
+	}
+	return fakerCall.ReplaceAllStringFunc(s, func(call string) string {
+		if field, ok := fakerFields[fakerCall.FindStringSubmatch(call)[1]]; ok {
+			return "{{fake." + field + "}}"
+		}
+		return call
+	})
+}
+
+// warn records a part of a route that could not be converted
+func (imp *Import) warn(route *Route, message string) {
+	imp.Warnings = append(imp.Warnings, strings.ToUpper(route.Method)+" /"+strings.TrimPrefix(route.Endpoint, "/")+": "+message)
+}
@@ -0,0 +1,224 @@
+package mockoon
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+const environment = `{
+  "uuid": "b4c1",
+  "lastMigration": 32,
+  "name": "Shop",
+  "endpointPrefix": "api/v1",
+  "latency": 10,
+  "port": 3001,
+  "headers": [{"key": "Content-Type", "value": "application/json"}],
+  "proxyMode": true,
+  "proxyHost": "https://shop.example.com",
+  "routes": [
+    {
+      "uuid": "r1",
+      "type": "http",
+      "documentation": "Get a product",
+      "method": "get",
+      "endpoint": "products/:id",
+      "responses": [
+        {
+          "uuid": "a",
+          "label": "Product",
+          "statusCode": 200,
+          "body": "{\"id\": \"{{urlParam 'id'}}\", \"name\": \"{{faker 'person.firstName'}}\", \"q\": \"{{queryParam 'q'}}\"}",
+          "latency": 5,
+          "headers": [],
+          "bodyType": "INLINE",
+          "rules": [],
+          "rulesOperator": "AND",
+          "default": true
+        },
+        {
+          "uuid": "b",
+          "label": "Missing",
+          "statusCode": 404,
+          "body": "",
+          "headers": [{"key": "Content-Type", "value": "text/plain"}],
+          "bodyType": "INLINE",
+          "rules": [
+            {"target": "params", "modifier": "id", "value": "0", "invert": false, "operator": "equals"},
+            {"target": "header", "modifier": "X-Debug", "value": "", "invert": true, "operator": "null"}
+          ],
+          "rulesOperator": "AND",
+          "default": false
+        },
+        {
+          "uuid": "c",
+          "label": "Legacy",
+          "statusCode": 410,
+          "body": "",
+          "headers": [],
+          "bodyType": "INLINE",
+          "rules": [
+            {"target": "query", "modifier": "version", "value": "1", "invert": false, "operator": "equals"},
+            {"target": "body", "modifier": "$.legacy", "value": "true", "invert": false, "operator": "equals"}
+          ],
+          "rulesOperator": "OR",
+          "default": false
+        }
+      ]
+    },
+    {
+      "uuid": "r2",
+      "type": "http",
+      "method": "all",
+      "endpoint": "health",
+      "responses": [{"uuid": "d", "statusCode": 204, "bodyType": "INLINE", "rules": [], "default": true}]
+    },
+    {
+      "uuid": "r3",
+      "type": "http",
+      "method": "post",
+      "endpoint": "orders",
+      "responses": [
+        {"uuid": "e", "label": "Created", "statusCode": 201, "bodyType": "FILE", "filePath": "./files/order.json", "rules": [], "default": true},
+        {"uuid": "f", "label": "Cookie", "statusCode": 401, "bodyType": "INLINE", "rules": [{"target": "cookie", "modifier": "session", "value": "", "invert": false, "operator": "null"}], "default": false}
+      ]
+    },
+    {"uuid": "r4", "type": "http", "method": "get", "endpoint": "files/*", "responses": []},
+    {"uuid": "r5", "type": "crud", "method": "", "endpoint": "users", "responses": []}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	imp, err := Parse([]byte(environment))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if imp.Name != "Shop" || imp.BasePath != "/api/v1" || imp.Upstream != "https://shop.example.com" {
+		t.Errorf("Unexpected environment settings: %+v", imp)
+	}
+	// One GET, five methods for "all" and one POST
+	if len(imp.Routes) != 7 {
+		t.Fatalf("Expected 7 routes, got %d", len(imp.Routes))
+	}
+
+	product := imp.Routes[0]
+	if product.Method != "GET" || product.Path != "/products/{id}" {
+		t.Errorf("Unexpected route: %s %s", product.Method, product.Path)
+	}
+
+	var names []string
+	for _, resp := range product.Responses {
+		names = append(names, resp.Name)
+	}
+	if want := []string{"Missing", "Legacy", "Legacy", "Product"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected responses %v, got %v", want, names)
+	}
+
+	missing := product.Responses[0]
+	wantConditions := []models.Condition{
+		{Source: "path", Key: "id", Operator: "eq", Value: "0"},
+		{Source: "header", Key: "X-Debug", Operator: "exists"},
+	}
+	if !reflect.DeepEqual(missing.Conditions, wantConditions) || missing.Headers["Content-Type"] != "text/plain" || missing.Delay != 10 {
+		t.Errorf("Unexpected response: %+v", missing)
+	}
+
+	if product.Responses[2].Conditions[0] != (models.Condition{Source: "body", Key: "legacy", Operator: "eq", Value: "true"}) {
+		t.Errorf("Expected a config per OR rule, got %+v", product.Responses[1:3])
+	}
+
+	fallback := product.Responses[3]
+	if len(fallback.Conditions) != 0 || fallback.Priority != 3 || fallback.Delay != 15 {
+		t.Errorf("Expected the default response last without conditions, got %+v", fallback)
+	}
+	if fallback.Body != `{"id": "{{path.id}}", "name": "{{fake.firstName}}", "q": "{{query.q}}"}` {
+		t.Errorf("Templates not converted: %s", fallback.Body)
+	}
+
+	orders := imp.Routes[6]
+	if orders.Responses[0].Name != "Cookie" || orders.Responses[0].Enabled || orders.Responses[1].BodyFile != "order.json" {
+		t.Errorf("Unexpected order responses: %+v", orders.Responses)
+	}
+
+	warnings := strings.Join(imp.Warnings, "\n")
+	for _, want := range []string{"GET /files/*: wildcards", "type crud", "rules on cookie", "order.json"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Expected a warning containing %q, got:\n%s", want, warnings)
+		}
+	}
+
+	// The generated document must be accepted by the spec parser
+	result, err := parser.NewParser().Parse(imp.Content, imp.BasePath)
+	if err != nil {
+		t.Fatalf("Generated OpenAPI document is invalid: %v\n%s", err, imp.Content)
+	}
+	if len(result.Operations) != 7 {
+		t.Errorf("Expected 7 operations, got %d", len(result.Operations))
+	}
+}
+
+func TestParse_NotAnEnvironment(t *testing.T) {
+	for _, data := range []string{`{"mappings": []}`, `[`} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+}
+
+func TestImportRule(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want models.Condition
+		ok   bool
+	}{
+		{Rule{Target: "query", Modifier: "q", Operator: "regex", Value: "^a"}, models.Condition{Source: "query", Key: "q", Operator: "regex", Value: "^a"}, true},
+		{Rule{Target: "header", Modifier: "X-Id", Operator: "regex_i", Value: "abc"}, models.Condition{Source: "header", Key: "X-Id", Operator: "regex", Value: "(?i)abc"}, true},
+		{Rule{Target: "body", Modifier: "user.roles.0", Operator: "equals", Value: "admin", Invert: true}, models.Condition{Source: "body", Key: "user.roles.0", Operator: "ne", Value: "admin"}, true},
+		{Rule{Target: "body", Operator: "regex", Value: "<xml"}, models.Condition{Source: "rawBody", Operator: "regex", Value: "<xml"}, true},
+		{Rule{Target: "global_var", Modifier: "mode", Operator: "null"}, models.Condition{Source: "var", Key: "mode", Operator: "notExists"}, true},
+		{Rule{Target: "query", Modifier: "q", Operator: "regex", Value: "a", Invert: true}, models.Condition{}, false},
+		{Rule{Target: "request_number", Operator: "equals", Value: "1"}, models.Condition{}, false},
+		{Rule{Target: "body", Modifier: "$.items[?(@.id)]", Operator: "equals"}, models.Condition{}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := importRule(tt.rule)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("importRule(%+v) = %+v, %v; want %+v", tt.rule, got, err, tt.want)
+		}
+	}
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	spec := &models.Spec{ID: "spec-1", Name: "Pets"}
+	ops := []*models.Operation{{ID: "op-1", Method: "GET", Path: "/pets/{id}", FullPath: "/pets/{id}"}}
+	conditions := []models.Condition{
+		{Source: "path", Key: "id", Operator: "eq", Value: "7"},
+		{Source: "query", Key: "expand", Operator: "exists"},
+	}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {
+			{ID: "resp-1", Name: "Seven", Conditions: conditions, StatusCode: 200, Body: `{"id": "{{path.id}}"}`, Enabled: true},
+			{ID: "resp-2", Name: "Other", StatusCode: 404, Enabled: true},
+		},
+	}
+
+	data, _ := json.Marshal(Export(spec, ops, configs))
+	imp, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	responses := imp.Routes[0].Responses
+	if len(responses) != 2 || !reflect.DeepEqual(responses[0].Conditions, conditions) || responses[0].Body != `{"id": "{{path.id}}"}` {
+		t.Errorf("Responses changed in the round trip: %+v", responses)
+	}
+	if responses[1].Name != "Other" || len(responses[1].Conditions) != 0 {
+		t.Errorf("Expected the default response last, got %+v", responses[1])
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+// Actions of a channel operation, from the point of view of the mocked application
+const (
+	ChannelSend    = "send"    // The application publishes messages to the channel
+	ChannelReceive = "receive" // The application consumes messages from the channel
+)
+
+// Channel is a message channel of an AsyncAPI document attached to a spec
+type Channel struct {
+	Name        string             `json:"name"`                  // Channel ID in the document
+	Address     string             `json:"address"`               // Topic, routing key or queue name
+	Description string             `json:"description,omitempty"` // From the document
+	Protocol    string             `json:"protocol,omitempty"`    // kafka, mqtt, amqp, ... from bindings or servers
+	Operations  []ChannelOperation `json:"operations,omitempty"`
+	Messages    []ChannelMessage   `json:"messages,omitempty"`
+}
+
+// ChannelOperation is an operation of the mocked application on a channel
+type ChannelOperation struct {
+	ID      string `json:"id"`
+	Action  string `json:"action"` // send or receive
+	Summary string `json:"summary,omitempty"`
+}
+
+// ChannelMessage is a message that can be sent on a channel
+type ChannelMessage struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType,omitempty"`
+	Payload     string `json:"payload,omitempty"` // JSON schema of the payload
+	Example     string `json:"example,omitempty"` // Example or generated payload, usable as an event body template
+}
+
+// Channel returns the channel of a spec with a name or address, or nil
+func (s *Spec) Channel(name string) *Channel {
+	for i := range s.Channels {
+		if s.Channels[i].Name == name || s.Channels[i].Address == name {
+			return &s.Channels[i]
+		}
+	}
+	return nil
+}
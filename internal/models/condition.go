@@ -2,18 +2,23 @@ package models
 
 // Condition represents a condition for matching requests
 type Condition struct {
-	Source   string `json:"source"`   // path, query, header, body
-	Key      string `json:"key"`      // Parameter name or JSONPath for body
-	Operator string `json:"operator"` // eq, ne, contains, regex, exists, notExists, gt, lt, gte, lte
-	Value    string `json:"value"`    // Expected value (can be template)
+	Source   string `json:"source" yaml:"source"`     // path, query, header, body, var, rawBody, contentLength, contentType, jwt
+	Key      string `json:"key" yaml:"key"`           // Parameter name or JSONPath for body
+	Operator string `json:"operator" yaml:"operator"` // eq, ne, contains, regex, exists, notExists, gt, lt, gte, lte, hmac
+	Value    string `json:"value" yaml:"value"`       // Expected value (can be template)
 }
 
 // Supported condition sources
 const (
-	SourcePath   = "path"
-	SourceQuery  = "query"
-	SourceHeader = "header"
-	SourceBody   = "body"
+	SourcePath          = "path"
+	SourceQuery         = "query"
+	SourceHeader        = "header"
+	SourceBody          = "body"
+	SourceVar           = "var"           // Shared spec variable
+	SourceRawBody       = "rawBody"       // Unparsed request body; the key is ignored
+	SourceContentLength = "contentLength" // Request body size in bytes; the key is ignored
+	SourceContentType   = "contentType"   // Media type without parameters, lowercased; the key is ignored
+	SourceJWT           = "jwt"           // Claim of the bearer token; the key is a JSON path into its claims
 )
 
 // Supported condition operators
@@ -31,11 +36,12 @@ const (
 	OpLTE         = "lte"
 	OpStartsWith  = "startsWith"
 	OpEndsWith    = "endsWith"
+	OpHMAC        = "hmac" // The value is the secret; the source holds an HMAC signature of the request body
 )
 
 // ValidSources returns all valid condition sources
 func ValidSources() []string {
-	return []string{SourcePath, SourceQuery, SourceHeader, SourceBody}
+	return []string{SourcePath, SourceQuery, SourceHeader, SourceBody, SourceVar, SourceRawBody, SourceContentLength, SourceContentType, SourceJWT}
 }
 
 // ValidOperators returns all valid condition operators
@@ -44,5 +50,6 @@ func ValidOperators() []string {
 		OpEquals, OpNotEquals, OpContains, OpNotContains,
 		OpRegex, OpExists, OpNotExists, OpGreaterThan,
 		OpLessThan, OpGTE, OpLTE, OpStartsWith, OpEndsWith,
+		OpHMAC,
 	}
 }
@@ -18,6 +18,9 @@ func TestConditionConstants(t *testing.T) {
 	if SourceBody != "body" {
 		t.Errorf("Expected SourceBody to be 'body', got %q", SourceBody)
 	}
+	if SourceVar != "var" {
+		t.Errorf("Expected SourceVar to be 'var', got %q", SourceVar)
+	}
 }
 
 func TestOperatorConstants(t *testing.T) {
@@ -38,6 +41,7 @@ func TestOperatorConstants(t *testing.T) {
 		{OpLTE, "lte"},
 		{OpStartsWith, "startsWith"},
 		{OpEndsWith, "endsWith"},
+		{OpHMAC, "hmac"},
 	}
 
 	for _, op := range operators {
@@ -50,7 +54,7 @@ func TestOperatorConstants(t *testing.T) {
 func TestValidSources(t *testing.T) {
 	sources := ValidSources()
 
-	expected := []string{"path", "query", "header", "body"}
+	expected := []string{"path", "query", "header", "body", "var", "rawBody", "contentLength", "contentType", "jwt"}
 	if len(sources) != len(expected) {
 		t.Errorf("Expected %d sources, got %d", len(expected), len(sources))
 	}
@@ -65,8 +69,8 @@ func TestValidSources(t *testing.T) {
 func TestValidOperators(t *testing.T) {
 	operators := ValidOperators()
 
-	if len(operators) != 13 {
-		t.Errorf("Expected 13 operators, got %d", len(operators))
+	if len(operators) != 14 {
+		t.Errorf("Expected 14 operators, got %d", len(operators))
 	}
 
 	// Check that key operators are included
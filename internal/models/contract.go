@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Kinds of contract violations
+const (
+	ViolationUnknownPath      = "unknownPath"      // No operation of the spec has the method and path
+	ViolationInvalidParameter = "invalidParameter" // A path, query, header or cookie parameter is missing or invalid
+	ViolationInvalidBody      = "invalidBody"      // The request body is missing or doesn't match its schema
+	ViolationInvalidRequest   = "invalidRequest"   // Any other way the request breaks the operation
+)
+
+// ContractViolation is a way consumers broke the contract of a spec, with how often it happened
+// Requests with the same kind, operation and message count towards the same violation
+type ContractViolation struct {
+	Kind          string    `json:"kind"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"` // Operation path, or the request path of unknown paths
+	OperationID   string    `json:"operationId,omitempty"`
+	Parameter     string    `json:"parameter,omitempty"` // Location and name of an invalid parameter, e.g. query:limit
+	Message       string    `json:"message"`
+	Count         int64     `json:"count"`
+	FirstSeen     time.Time `json:"firstSeen"`
+	LastSeen      time.Time `json:"lastSeen"`
+	LastRequestID string    `json:"lastRequestId,omitempty"`
+}
+
+// ContractReport lists the contract violations of a spec, most frequent first
+type ContractReport struct {
+	SpecID     string              `json:"specId"`
+	Enabled    bool                `json:"enabled"` // Whether the spec checks its contract
+	Total      int64               `json:"total"`   // Violating requests since the report was cleared
+	Violations []ContractViolation `json:"violations"`
+}
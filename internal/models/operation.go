@@ -2,17 +2,51 @@ package models
 
 // Operation represents an API operation from an OpenAPI spec
 type Operation struct {
-	ID              string            `json:"id"`
-	SpecID          string            `json:"specId"`
-	Method          string            `json:"method"`      // GET, POST, PUT, DELETE, PATCH, etc.
-	Path            string            `json:"path"`        // Path pattern e.g., /users/{id}
-	FullPath        string            `json:"fullPath"`    // BasePath + Path
-	OperationID     string            `json:"operationId"` // From OpenAPI spec
-	Summary         string            `json:"summary"`
-	Description     string            `json:"description"`
-	Tags            []string          `json:"tags"`
-	Responses       []ResponseConfig  `json:"responses,omitempty"`
-	ExampleResponse *ExampleResponse  `json:"exampleResponse,omitempty"` // From OpenAPI spec
+	ID              string           `json:"id"`
+	SpecID          string           `json:"specId"`
+	Method          string           `json:"method"`      // GET, POST, PUT, DELETE, PATCH, etc.
+	Path            string           `json:"path"`        // Path pattern e.g., /users/{id}
+	FullPath        string           `json:"fullPath"`    // BasePath + Path
+	OperationID     string           `json:"operationId"` // From OpenAPI spec
+	Summary         string           `json:"summary"`
+	Description     string           `json:"description"`
+	Tags            []string         `json:"tags"`
+	Responses       []ResponseConfig `json:"responses,omitempty"`
+	ExampleResponse *ExampleResponse `json:"exampleResponse,omitempty"` // From OpenAPI spec
+	Custom          bool             `json:"custom"`                    // Added or modified via the admin API
+	Disabled        bool             `json:"disabled"`                  // Excluded from routing; zero value keeps operations served
+	Tracing         bool             `json:"tracing"`                   // Trace requests even when spec tracing is off
+	Timeout         int              `json:"timeout,omitempty"`         // Maximum handling time in milliseconds; 0 uses the server default
+	RateLimit       *RateLimitPolicy `json:"rateLimit,omitempty"`       // Simulated throttling
+	Forward         *ForwardPolicy   `json:"forward,omitempty"`         // Forwarding to the upstream of the spec
+	Echo            bool             `json:"echo,omitempty"`            // Answer with a JSON description of the request instead of response configs
+}
+
+// Forwarding modes of a ForwardPolicy
+const (
+	ForwardUnmatched = "unmatched" // Serve the mock when a response config matches, forward otherwise
+	ForwardAlways    = "always"    // Forward unless the request carries the mock header
+)
+
+// DefaultForceMockHeader is the header that makes operations forwarding always serve the mock
+const DefaultForceMockHeader = "X-Force-Mock"
+
+// ForwardPolicy forwards requests of an operation to the upstream of its spec, virtualizing
+// only part of an API. It has no effect while the spec has no upstream
+type ForwardPolicy struct {
+	Mode       string `json:"mode"`                 // unmatched or always
+	MockHeader string `json:"mockHeader,omitempty"` // Header serving the mock in always mode; default X-Force-Mock
+	Learn      bool   `json:"learn,omitempty"`      // Save distinct upstream responses as disabled response configs
+}
+
+// RateLimitPolicy simulates throttling of an operation with a fixed window
+// Once Limit requests arrived in a window, requests get the throttled response until it ends
+type RateLimitPolicy struct {
+	Limit    int               `json:"limit"`              // Requests allowed per window
+	Window   int               `json:"window"`             // Window length in seconds
+	Key      string            `json:"key,omitempty"`      // Counted per "ip" or "header:<name>"; empty counts all clients together
+	Headers  bool              `json:"headers"`            // Add X-RateLimit-Limit, -Remaining and -Reset headers to every response
+	Response *FallbackResponse `json:"response,omitempty"` // Throttled response; default 429 with a Retry-After header
 }
 
 // ExampleResponse holds example response data from the OpenAPI spec
@@ -24,13 +58,44 @@ type ExampleResponse struct {
 
 // OperationSummary is a lightweight version for listings
 type OperationSummary struct {
-	ID                 string `json:"id"`
-	SpecID             string `json:"specId"`
-	Method             string `json:"method"`
-	Path               string `json:"path"`
-	FullPath           string `json:"fullPath"`
-	OperationID        string `json:"operationId"`
-	Summary            string `json:"summary"`
-	ResponseCount      int    `json:"responseCount"`
-	HasExampleResponse bool   `json:"hasExampleResponse"`
+	ID                 string   `json:"id"`
+	SpecID             string   `json:"specId"`
+	Method             string   `json:"method"`
+	Path               string   `json:"path"`
+	FullPath           string   `json:"fullPath"`
+	OperationID        string   `json:"operationId"`
+	Summary            string   `json:"summary"`
+	Tags               []string `json:"tags"`
+	ResponseCount      int      `json:"responseCount"`
+	HasExampleResponse bool     `json:"hasExampleResponse"`
+	Custom             bool     `json:"custom"`
+	Disabled           bool     `json:"disabled"`
+	Tracing            bool     `json:"tracing"`
+}
+
+// TagSummary describes a tag and how many operations of a spec carry it
+type TagSummary struct {
+	Tag            string `json:"tag"`
+	OperationCount int    `json:"operationCount"`
+}
+
+// OperationInput represents input for manually adding an operation to a spec
+type OperationInput struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	OperationID string   `json:"operationId"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// OperationUpdate represents input for updating an operation
+type OperationUpdate struct {
+	Method      *string   `json:"method,omitempty"`
+	Path        *string   `json:"path,omitempty"`
+	OperationID *string   `json:"operationId,omitempty"`
+	Summary     *string   `json:"summary,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Timeout     *int      `json:"timeout,omitempty"`
 }
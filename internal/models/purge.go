@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Scopes of a purge
+const (
+	PurgeTraces = "traces" // Whole traces
+	PurgeBodies = "bodies" // Request and response bodies of traces, keeping the rest of them
+	PurgeStats  = "stats"  // Statistics and recent errors
+	PurgeAll    = "all"    // All of the above
+)
+
+// PurgeRequest describes what a purge removes. Without From and To it removes everything in its scopes
+type PurgeRequest struct {
+	Scopes []string   `json:"scopes"`
+	From   *time.Time `json:"from,omitempty"` // Only data captured at or after this time
+	To     *time.Time `json:"to,omitempty"`   // Only data captured at or before this time
+}
+
+// InTimeRange reports whether t lies between from and to, either of which may be zero
+func InTimeRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+// PurgeResult reports what a purge removed
+type PurgeResult struct {
+	Scopes      []string   `json:"scopes"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	Traces      int        `json:"traces"`      // Traces removed
+	Bodies      int        `json:"bodies"`      // Traces whose bodies were removed
+	Errors      int        `json:"errors"`      // Recent errors removed from the statistics
+	StatsReset  bool       `json:"statsReset"`  // All statistics were reset
+	HoursPurged int        `json:"hoursPurged"` // Hourly request counts removed
+	// Broadcast is set when the purge was passed on to the other nodes sharing traces
+	Broadcast      bool   `json:"broadcast"`
+	BroadcastError string `json:"broadcastError,omitempty"` // Why passing it on failed; only this node was purged
+}
@@ -2,41 +2,204 @@ package models
 
 // ResponseConfig represents a configured response for an operation
 type ResponseConfig struct {
-	ID          string            `json:"id"`
-	OperationID string            `json:"operationId"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Priority    int               `json:"priority"` // Lower = higher priority (0 is highest)
-	Conditions  []Condition       `json:"conditions"`
-	StatusCode  int               `json:"statusCode"`
-	Headers     map[string]string `json:"headers"` // Can contain template variables
-	Body        string            `json:"body"`    // Can contain template variables
-	Delay       int               `json:"delay"`   // Response delay in milliseconds
-	Enabled     bool              `json:"enabled"`
+	ID               string            `json:"id"`
+	OperationID      string            `json:"operationId"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Priority         int               `json:"priority"` // Lower = higher priority (0 is highest)
+	Conditions       []Condition       `json:"conditions"`
+	NegateConditions bool              `json:"negateConditions"` // Match when the conditions don't all hold
+	StatusCode       int               `json:"statusCode"`
+	Headers          map[string]string `json:"headers"`            // Can contain template variables
+	Body             string            `json:"body"`               // Can contain template variables
+	BodyFile         string            `json:"bodyFile,omitempty"` // File of the spec streamed as the body instead
+	Delay            int               `json:"delay"`              // Response delay in milliseconds
+	Seed             string            `json:"seed,omitempty"`     // Template whose value seeds {{random.*}}, e.g. {{path.id}}
+	Enabled          bool              `json:"enabled"`
+	Pagination       *Pagination       `json:"pagination,omitempty"` // Serve a page of a dataset; the body renders the page
+	Events           []EventAction     `json:"events,omitempty"`     // Messages published after the response was sent
+	SOAPFault        *SOAPFault        `json:"soapFault,omitempty"`  // Respond with a SOAP Fault envelope instead of the body
+	Fault            *ResponseFault    `json:"fault,omitempty"`      // Break the delivery of the response
 }
 
 // ResponseConfigInput represents input for creating/updating a response config
 type ResponseConfigInput struct {
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Priority    int               `json:"priority"`
-	Conditions  []Condition       `json:"conditions"`
-	StatusCode  int               `json:"statusCode"`
-	Headers     map[string]string `json:"headers"`
-	Body        string            `json:"body"`
-	Delay       int               `json:"delay"`
-	Enabled     bool              `json:"enabled"`
+	Name             string            `json:"name" yaml:"name"`
+	Description      string            `json:"description" yaml:"description,omitempty"`
+	Priority         int               `json:"priority" yaml:"priority"`
+	Conditions       []Condition       `json:"conditions" yaml:"conditions,omitempty"`
+	NegateConditions bool              `json:"negateConditions" yaml:"negateConditions,omitempty"`
+	StatusCode       int               `json:"statusCode" yaml:"statusCode"`
+	Headers          map[string]string `json:"headers" yaml:"headers,omitempty"`
+	Body             string            `json:"body" yaml:"body,omitempty"`
+	BodyFile         string            `json:"bodyFile,omitempty" yaml:"bodyFile,omitempty"`
+	Delay            int               `json:"delay" yaml:"delay,omitempty"`
+	Seed             string            `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Enabled          bool              `json:"enabled" yaml:"enabled"`
+	Pagination       *Pagination       `json:"pagination,omitempty" yaml:"pagination,omitempty"`
+	Events           []EventAction     `json:"events,omitempty" yaml:"events,omitempty"`
+	SOAPFault        *SOAPFault        `json:"soapFault,omitempty" yaml:"soapFault,omitempty"`
+	Fault            *ResponseFault    `json:"fault,omitempty" yaml:"fault,omitempty"`
 }
 
 // ResponseConfigUpdate represents input for updating a response config
 type ResponseConfigUpdate struct {
-	Name        *string            `json:"name,omitempty"`
-	Description *string            `json:"description,omitempty"`
-	Priority    *int               `json:"priority,omitempty"`
-	Conditions  *[]Condition       `json:"conditions,omitempty"`
-	StatusCode  *int               `json:"statusCode,omitempty"`
-	Headers     *map[string]string `json:"headers,omitempty"`
-	Body        *string            `json:"body,omitempty"`
-	Delay       *int               `json:"delay,omitempty"`
-	Enabled     *bool              `json:"enabled,omitempty"`
+	Name             *string            `json:"name,omitempty"`
+	Description      *string            `json:"description,omitempty"`
+	Priority         *int               `json:"priority,omitempty"`
+	Conditions       *[]Condition       `json:"conditions,omitempty"`
+	NegateConditions *bool              `json:"negateConditions,omitempty"`
+	StatusCode       *int               `json:"statusCode,omitempty"`
+	Headers          *map[string]string `json:"headers,omitempty"`
+	Body             *string            `json:"body,omitempty"`
+	BodyFile         *string            `json:"bodyFile,omitempty"`
+	Delay            *int               `json:"delay,omitempty"`
+	Seed             *string            `json:"seed,omitempty"`
+	Enabled          *bool              `json:"enabled,omitempty"`
+	Pagination       *Pagination        `json:"pagination,omitempty"`
+	Events           *[]EventAction     `json:"events,omitempty"`
+	SOAPFault        *SOAPFault         `json:"soapFault,omitempty"`
+	Fault            *ResponseFault     `json:"fault,omitempty"`
+}
+
+// ResponseConfigExport is a portable document holding the response configs of an operation
+// It can be imported into another operation or server
+type ResponseConfigExport struct {
+	Version   int                   `json:"version" yaml:"version"`
+	Operation ExportedOperation     `json:"operation" yaml:"operation"`
+	Responses []ResponseConfigInput `json:"responses" yaml:"responses"`
+}
+
+// ExportedOperation identifies the operation a response config export was taken from
+type ExportedOperation struct {
+	Method      string `json:"method" yaml:"method"`
+	Path        string `json:"path" yaml:"path"`
+	OperationID string `json:"operationId" yaml:"operationId"`
+}
+
+// ResponseConfigExportVersion is the current version of the response config export format
+const ResponseConfigExportVersion = 1
+
+// ToInput converts a response config to its portable input form
+func (r *ResponseConfig) ToInput() ResponseConfigInput {
+	return ResponseConfigInput{
+		Name:             r.Name,
+		Description:      r.Description,
+		Priority:         r.Priority,
+		Conditions:       r.Conditions,
+		NegateConditions: r.NegateConditions,
+		StatusCode:       r.StatusCode,
+		Headers:          r.Headers,
+		Body:             r.Body,
+		BodyFile:         r.BodyFile,
+		Delay:            r.Delay,
+		Seed:             r.Seed,
+		Enabled:          r.Enabled,
+		Pagination:       r.Pagination,
+		Events:           r.Events,
+		SOAPFault:        r.SOAPFault,
+		Fault:            r.Fault,
+	}
+}
+
+// FallbackResponse is a default response returned when no response config applies
+// Headers and body can contain template variables
+type FallbackResponse struct {
+	StatusCode int               `json:"statusCode" yaml:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       string            `json:"body" yaml:"body"`
+}
+
+// FallbackResponses holds the configurable default responses of the server or a spec
+type FallbackResponses struct {
+	NotFound *FallbackResponse `json:"notFound,omitempty" yaml:"notFound,omitempty"` // No route matches the request
+	NoMatch  *FallbackResponse `json:"noMatch,omitempty" yaml:"noMatch,omitempty"`   // Route matched but no response config or example applies
+	Error    *FallbackResponse `json:"error,omitempty" yaml:"error,omitempty"`       // Internal error while building the response
+	Timeout  *FallbackResponse `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // Response not ready within the operation timeout
+}
+
+// Supported pagination modes
+const (
+	PaginationPage   = "page"   // ?page=2&limit=10, pages counted from 1
+	PaginationOffset = "offset" // ?offset=10&limit=10
+	PaginationCursor = "cursor" // ?cursor=<opaque>&limit=10
+)
+
+// Pagination serves a dataset page by page, with X-Total-Count and Link headers
+// The dataset is a JSON array, given literally or generated from an item template
+type Pagination struct {
+	Mode         string `json:"mode" yaml:"mode"`                                     // page, offset or cursor
+	Dataset      string `json:"dataset,omitempty" yaml:"dataset,omitempty"`           // JSON array of all items; can contain template variables
+	Generate     int    `json:"generate,omitempty" yaml:"generate,omitempty"`         // Number of items to generate from ItemTemplate instead
+	ItemTemplate string `json:"itemTemplate,omitempty" yaml:"itemTemplate,omitempty"` // JSON of a generated item; {{item.index}} and {{item.number}} count from 0 and 1
+	DefaultLimit int    `json:"defaultLimit,omitempty" yaml:"defaultLimit,omitempty"` // Page size without a limit parameter; default 10
+	MaxLimit     int    `json:"maxLimit,omitempty" yaml:"maxLimit,omitempty"`         // Largest accepted page size; 0 is unlimited
+	PageParam    string `json:"pageParam,omitempty" yaml:"pageParam,omitempty"`       // Default "page"
+	LimitParam   string `json:"limitParam,omitempty" yaml:"limitParam,omitempty"`     // Default "limit"
+	OffsetParam  string `json:"offsetParam,omitempty" yaml:"offsetParam,omitempty"`   // Default "offset"
+	CursorParam  string `json:"cursorParam,omitempty" yaml:"cursorParam,omitempty"`   // Default "cursor"
+}
+
+// Supported brokers of an EventAction
+const (
+	EventKafka = "kafka" // Kafka topic, brokers configured under events.kafka
+	EventMQTT  = "mqtt"  // MQTT topic on the action's broker or events.mqtt.broker
+	EventAMQP  = "amqp"  // AMQP exchange, broker configured under events.amqp
+)
+
+// EventAction publishes a templated message after a response was sent, so consumers
+// downstream of the mocked API receive events. Topic, routing key, exchange, key, headers and
+// payload can contain template variables of the request
+type EventAction struct {
+	Type       string            `json:"type" yaml:"type"`                                 // Broker kind: kafka, mqtt or amqp
+	Channel    string            `json:"channel,omitempty" yaml:"channel,omitempty"`       // AsyncAPI channel of the spec supplying the topic and payload
+	Topic      string            `json:"topic,omitempty" yaml:"topic,omitempty"`           // Default: the channel's address (kafka, mqtt)
+	Key        string            `json:"key,omitempty" yaml:"key,omitempty"`               // Message key (kafka)
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`       // Message headers (kafka, amqp)
+	Payload    string            `json:"payload,omitempty" yaml:"payload,omitempty"`       // Default: the example of the channel's first message
+	Delay      int               `json:"delay,omitempty" yaml:"delay,omitempty"`           // Milliseconds to wait after the response
+	Broker     string            `json:"broker,omitempty" yaml:"broker,omitempty"`         // Broker URL, e.g. tcp://localhost:1883 (mqtt)
+	QoS        int               `json:"qos,omitempty" yaml:"qos,omitempty"`               // 0, 1 or 2 (mqtt)
+	Retain     bool              `json:"retain,omitempty" yaml:"retain,omitempty"`         // Retain the message on the broker (mqtt)
+	Exchange   string            `json:"exchange,omitempty" yaml:"exchange,omitempty"`     // Default: the default exchange (amqp)
+	RoutingKey string            `json:"routingKey,omitempty" yaml:"routingKey,omitempty"` // Default: the channel's address (amqp)
+}
+
+// Supported SOAP versions of a SOAPFault
+const (
+	SOAP11 = "1.1" // text/xml envelope with faultcode and faultstring
+	SOAP12 = "1.2" // application/soap+xml envelope with Code and Reason
+)
+
+// SOAPFault renders a SOAP Fault envelope as the response, for exercising the error paths of
+// SOAP clients. Codes can be given in either version's spelling: Client and Sender, Server and
+// Receiver are translated to the names of the fault's version
+type SOAPFault struct {
+	Version string `json:"version,omitempty" yaml:"version,omitempty"` // 1.1 (default) or 1.2
+	Code    string `json:"code,omitempty" yaml:"code,omitempty"`       // Server (default), Client, VersionMismatch, MustUnderstand or DataEncodingUnknown (1.2)
+	String  string `json:"string,omitempty" yaml:"string,omitempty"`   // Human-readable reason; can contain template variables
+	Detail  string `json:"detail,omitempty" yaml:"detail,omitempty"`   // XML content of the detail element; can contain template variables
+}
+
+// StatusCode returns the HTTP status of the fault's binding: 400 for sender faults of SOAP 1.2,
+// 500 for all others
+func (f *SOAPFault) StatusCode() int {
+	if f.Version == SOAP12 && (f.Code == "Client" || f.Code == "Sender") {
+		return 400
+	}
+	return 500
+}
+
+// Supported types of a ResponseFault
+const (
+	FaultAbort   = "abort"   // Close the connection part way through the body
+	FaultTrickle = "trickle" // Send the body one byte at a time
+)
+
+// ResponseFault breaks the delivery of a response, to test how clients handle broken transfers
+type ResponseFault struct {
+	Type     string  `json:"type" yaml:"type"`                             // abort or trickle
+	Fraction float64 `json:"fraction,omitempty" yaml:"fraction,omitempty"` // Share of the body written before aborting, 0-1; 0 sends only the headers
+	Interval int     `json:"interval,omitempty" yaml:"interval,omitempty"` // Milliseconds between trickled bytes, default 1000
+	MaxBytes int64   `json:"maxBytes,omitempty" yaml:"maxBytes,omitempty"` // Bytes trickled before closing the connection; 0 sends the whole body
 }
@@ -0,0 +1,28 @@
+package models
+
+// Types of search hits
+const (
+	SearchSpec      = "spec"
+	SearchOperation = "operation"
+	SearchResponse  = "response"
+)
+
+// SearchHit is a spec, operation or response config matching a search query
+type SearchHit struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	SpecID      string `json:"specId"`
+	OperationID string `json:"operationId,omitempty"` // Operation of a response config
+	Title       string `json:"title"`                 // Name of the spec or response config, or method and path of the operation
+	Field       string `json:"field"`                 // Field the query matched, e.g. name or body
+	Snippet     string `json:"snippet"`               // Text around the match
+	Link        string `json:"link"`                  // Admin API URL of the hit
+	UILink      string `json:"uiLink"`                // Page of the hit in the web UI
+}
+
+// SearchResult is the hits of a search query, specs first, then operations and response configs
+type SearchResult struct {
+	Query     string      `json:"query"`
+	Hits      []SearchHit `json:"hits"`
+	Truncated bool        `json:"truncated"` // More hits than the limit matched
+}
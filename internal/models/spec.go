@@ -6,18 +6,36 @@ import (
 
 // Spec represents an uploaded OpenAPI specification
 type Spec struct {
-	ID                 string      `json:"id"`
-	Name               string      `json:"name"`
-	Version            string      `json:"version"`
-	Description        string      `json:"description"`
-	Content            string      `json:"content"`  // Raw OpenAPI spec (YAML or JSON)
-	BasePath           string      `json:"basePath"` // Mounted path prefix for this spec
-	Enabled            bool        `json:"enabled"`
-	Tracing            bool        `json:"tracing"`            // Enable request tracing
-	UseExampleFallback bool        `json:"useExampleFallback"` // Use spec examples as fallback responses
-	CreatedAt          time.Time   `json:"createdAt"`
-	UpdatedAt          time.Time   `json:"updatedAt"`
-	Operations         []Operation `json:"operations,omitempty"`
+	ID                 string             `json:"id"`
+	Name               string             `json:"name"`
+	Version            string             `json:"version"`
+	Description        string             `json:"description"`
+	Content            string             `json:"content"`  // Raw OpenAPI spec (YAML or JSON)
+	BasePath           string             `json:"basePath"` // Mounted path prefix for this spec
+	Enabled            bool               `json:"enabled"`
+	Group              string             `json:"group,omitempty"`           // Folder the spec is listed under; empty is ungrouped
+	Labels             map[string]string  `json:"labels,omitempty"`          // Free-form key/value pairs specs can be filtered by
+	Tracing            bool               `json:"tracing"`                   // Enable request tracing
+	UseExampleFallback bool               `json:"useExampleFallback"`        // Use spec examples as fallback responses
+	Fallbacks          *FallbackResponses `json:"fallbacks,omitempty"`       // Overrides the server fallback responses
+	CORS               *CORSPolicy        `json:"cors,omitempty"`            // CORS policy for mocked endpoints; nil uses the permissive default
+	DisableAutoOptions bool               `json:"disableAutoOptions"`        // Don't answer OPTIONS automatically for paths of this spec
+	Snippets           map[string]string  `json:"snippets,omitempty"`        // Named body fragments, included with {{include "name"}}
+	MaxConcurrent      int                `json:"maxConcurrent"`             // Maximum mock requests served at the same time; 0 is unlimited
+	Auth               *AuthPolicy        `json:"auth,omitempty"`            // Simulated authentication of mocked endpoints
+	Stateful           *StatefulPolicy    `json:"stateful,omitempty"`        // Serve CRUD operations from an in-memory store
+	AsyncAPI           string             `json:"asyncapi,omitempty"`        // Raw AsyncAPI document describing the spec's events
+	Channels           []Channel          `json:"channels,omitempty"`        // Message channels of the AsyncAPI document
+	Upstream           string             `json:"upstream,omitempty"`        // Base URL of the real API that operations can forward to
+	UpstreamRewrite    *UpstreamRewrite   `json:"upstreamRewrite,omitempty"` // Transformations of forwarded requests and their responses
+	HealthCheck        *HealthCheck       `json:"healthCheck,omitempty"`     // Periodic probe of the upstream
+	Chaos              *ChaosPolicy       `json:"chaos,omitempty"`           // Random errors and delays injected into requests
+	Locale             string             `json:"locale,omitempty"`          // Locale of {{fake.*}} values, e.g. de_DE
+	ContractCheck      bool               `json:"contractCheck"`             // Record requests that violate the OpenAPI document
+	Alerts             *AlertPolicy       `json:"alerts,omitempty"`          // Error rate and latency thresholds that raise alerts
+	CreatedAt          time.Time          `json:"createdAt"`
+	UpdatedAt          time.Time          `json:"updatedAt"`
+	Operations         []Operation        `json:"operations,omitempty"`
 }
 
 // SpecInput represents input for creating/updating a spec
@@ -30,10 +48,165 @@ type SpecInput struct {
 
 // SpecUpdate represents input for updating spec settings
 type SpecUpdate struct {
-	Name               *string `json:"name,omitempty"`
-	BasePath           *string `json:"basePath,omitempty"`
-	Description        *string `json:"description,omitempty"`
-	Enabled            *bool   `json:"enabled,omitempty"`
-	Tracing            *bool   `json:"tracing,omitempty"`
-	UseExampleFallback *bool   `json:"useExampleFallback,omitempty"`
+	Name               *string            `json:"name,omitempty"`
+	BasePath           *string            `json:"basePath,omitempty"`
+	Description        *string            `json:"description,omitempty"`
+	Group              *string            `json:"group,omitempty"`  // Empty removes the spec from its group
+	Labels             map[string]string  `json:"labels,omitempty"` // Replaces the labels; an empty object removes them
+	Enabled            *bool              `json:"enabled,omitempty"`
+	Tracing            *bool              `json:"tracing,omitempty"`
+	UseExampleFallback *bool              `json:"useExampleFallback,omitempty"`
+	Fallbacks          *FallbackResponses `json:"fallbacks,omitempty"`
+	CORS               *CORSPolicy        `json:"cors,omitempty"`
+	DisableAutoOptions *bool              `json:"disableAutoOptions,omitempty"`
+	MaxConcurrent      *int               `json:"maxConcurrent,omitempty"`
+	Auth               *AuthPolicy        `json:"auth,omitempty"`
+	Stateful           *StatefulPolicy    `json:"stateful,omitempty"`
+	Upstream           *string            `json:"upstream,omitempty"`        // Empty removes the upstream
+	UpstreamRewrite    *UpstreamRewrite   `json:"upstreamRewrite,omitempty"` // An empty object removes the transformations
+	HealthCheck        *HealthCheck       `json:"healthCheck,omitempty"`
+	Chaos              *ChaosPolicy       `json:"chaos,omitempty"`
+	Locale             *string            `json:"locale,omitempty"` // Empty uses the default locale
+	ContractCheck      *bool              `json:"contractCheck,omitempty"`
+	Alerts             *AlertPolicy       `json:"alerts,omitempty"`
+}
+
+// GroupSummary describes a spec group and how many of its specs are enabled
+type GroupSummary struct {
+	Group        string `json:"group"`
+	SpecCount    int    `json:"specCount"`
+	EnabledCount int    `json:"enabledCount"`
+}
+
+// SnippetInput represents input for creating/updating a named snippet
+type SnippetInput struct {
+	Content string `json:"content"`
+}
+
+// SpecContentInput represents input for replacing the OpenAPI content of a spec
+type SpecContentInput struct {
+	Content string `json:"content"`
+}
+
+// CORSPolicy configures the CORS headers added to the mocked endpoints of a spec
+type CORSPolicy struct {
+	Enabled          bool     `json:"enabled"`
+	AllowedOrigins   []string `json:"allowedOrigins"` // "*" allows any origin
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"` // Empty allows the headers requested by the preflight
+	ExposedHeaders   []string `json:"exposedHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAge           int      `json:"maxAge"` // Preflight cache duration in seconds
+}
+
+// Supported authentication schemes of an AuthPolicy
+const (
+	AuthAPIKey = "apiKey" // API key in a request header
+	AuthBearer = "bearer" // Token in an "Authorization: Bearer" header
+)
+
+// AuthPolicy simulates API authentication on the mocked endpoints of a spec
+// Requests without credentials get the unauthorized response, requests whose credentials
+// match neither the keys nor the pattern get the forbidden response
+type AuthPolicy struct {
+	Enabled      bool              `json:"enabled"`
+	Type         string            `json:"type"`                   // apiKey or bearer
+	Header       string            `json:"header,omitempty"`       // Header holding the API key; default X-API-Key
+	Keys         []string          `json:"keys,omitempty"`         // Accepted API keys or tokens
+	Pattern      string            `json:"pattern,omitempty"`      // Regular expression accepted credentials match as a whole
+	Unauthorized *FallbackResponse `json:"unauthorized,omitempty"` // Response to missing credentials; default 401
+	Forbidden    *FallbackResponse `json:"forbidden,omitempty"`    // Response to rejected credentials; default 403
+}
+
+// ChaosPolicy injects errors and delays into a random share of the requests of a spec, across
+// all its operations, for resilience game days. Requests are delayed first and may then fail
+type ChaosPolicy struct {
+	Enabled      bool              `json:"enabled"`
+	ErrorPercent float64           `json:"errorPercent"`       // Share of requests answered with the error response, 0-100
+	DelayPercent float64           `json:"delayPercent"`       // Share of requests delayed, 0-100
+	MinDelay     int               `json:"minDelay"`           // Shortest injected delay in milliseconds
+	MaxDelay     int               `json:"maxDelay"`           // Longest injected delay in milliseconds; default minDelay
+	Seed         int64             `json:"seed,omitempty"`     // Makes the sequence of injections reproducible; 0 picks a random one
+	Response     *FallbackResponse `json:"response,omitempty"` // Injected error; default 503
+}
+
+// AlertPolicy raises an alert when the error rate or 95th percentile latency of a spec's
+// requests over a sliding window breaches a threshold, and resolves it once both are back
+type AlertPolicy struct {
+	Enabled     bool    `json:"enabled"`
+	ErrorRate   float64 `json:"errorRate,omitempty"`   // Percentage of error responses, 0-100; 0 doesn't alert
+	P95Latency  int     `json:"p95Latency,omitempty"`  // Milliseconds; 0 doesn't alert
+	Window      int     `json:"window,omitempty"`      // Seconds of requests the thresholds apply to; default 300
+	MinRequests int     `json:"minRequests,omitempty"` // Requests the window needs before alerting; default 10
+	Webhook     string  `json:"webhook,omitempty"`     // URL receiving a POST of the alert when it fires or resolves
+}
+
+// HealthCheck probes the upstream of a spec periodically. The upstream is up while it answers
+// with a status below 500 and down after FailureThreshold probes in a row failed
+type HealthCheck struct {
+	Enabled          bool   `json:"enabled"`
+	Path             string `json:"path,omitempty"`             // Probed path below the upstream URL; default the upstream URL itself
+	Interval         int    `json:"interval,omitempty"`         // Seconds between probes; default 30
+	Timeout          int    `json:"timeout,omitempty"`          // Milliseconds a probe may take; default 5000
+	FailureThreshold int    `json:"failureThreshold,omitempty"` // Failed probes in a row marking the upstream down; default 2
+	Fallback         bool   `json:"fallback"`                   // Serve mocks instead of forwarding while the upstream is down
+}
+
+// States of an upstream
+const (
+	UpstreamUnknown = "unknown" // Not probed yet
+	UpstreamUp      = "up"
+	UpstreamDown    = "down"
+)
+
+// UpstreamHealth is the state of a spec's upstream as last probed
+type UpstreamHealth struct {
+	Status      string     `json:"status"`
+	URL         string     `json:"url"` // Probed URL
+	StatusCode  int        `json:"statusCode,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	LatencyMs   float64    `json:"latencyMs"`
+	Failures    int        `json:"failures"` // Failed probes in a row
+	CheckedAt   *time.Time `json:"checkedAt,omitempty"`
+	Since       *time.Time `json:"since,omitempty"` // When the status last changed
+	FallingBack bool       `json:"fallingBack"`     // Operations serve mocks instead of forwarding
+}
+
+// UpstreamRewrite transforms the requests a spec forwards to its upstream and the responses
+// coming back, e.g. to add credentials or strip cookies
+type UpstreamRewrite struct {
+	Request  *MessageRewrite `json:"request,omitempty"`
+	Response *MessageRewrite `json:"response,omitempty"`
+}
+
+// MessageRewrite changes the headers and JSON body of a forwarded message. Headers and fields are
+// removed before others are set; values are templates rendered against the client's request
+type MessageRewrite struct {
+	SetHeaders    map[string]string `json:"setHeaders,omitempty"`    // Setting Host changes the host sent upstream
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"` // e.g. Cookie or Set-Cookie
+	SetBody       map[string]string `json:"setBody,omitempty"`       // Field path, as in body conditions -> value; JSON values are set as is, others as strings
+	RemoveBody    []string          `json:"removeBody,omitempty"`    // Field paths to remove
+}
+
+// DefaultStatefulIDField is the field identifying the items of stateful collections
+const DefaultStatefulIDField = "id"
+
+// StatefulPolicy serves the CRUD operations of a spec from an in-memory store instead of
+// response configs. Collections are named after the last static segment of operation paths,
+// so /users and /users/{id} share the "users" collection
+type StatefulPolicy struct {
+	Enabled          bool               `json:"enabled"`
+	IDField          string             `json:"idField,omitempty"`   // Field identifying items; default "id"
+	SeedFromExamples bool               `json:"seedFromExamples"`    // Seed collections from the array examples of their GET operations
+	Seed             map[string]string  `json:"seed,omitempty"`      // Collection name -> JSON or CSV file of its initial items
+	Relations        []StatefulRelation `json:"relations,omitempty"` // Foreign keys between collections
+}
+
+// StatefulRelation links the items of a collection to a parent collection by a foreign key,
+// e.g. orders.customerId -> customers. Writes must reference an existing parent
+type StatefulRelation struct {
+	Collection string `json:"collection"` // Child collection, e.g. orders
+	Field      string `json:"field"`      // Foreign key field, e.g. customerId
+	References string `json:"references"` // Parent collection, e.g. customers
+	Cascade    bool   `json:"cascade"`    // Delete the children of deleted parents
 }
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// SpecFile is a file stored with a spec, e.g. a document served as a response body
+type SpecFile struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty"` // From the file extension; empty when unknown
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -15,9 +15,14 @@ type GlobalStats struct {
 	RequestsPerSecond float64         `json:"requestsPerSecond"`
 	StartTime         time.Time       `json:"startTime"`
 	Uptime            string          `json:"uptime"`
+	TotalBytesIn      int64           `json:"totalBytesIn"`  // Bytes of request bodies
+	TotalBytesOut     int64           `json:"totalBytesOut"` // Bytes of response bodies
 	TopOperations     []OperationStat `json:"topOperations"`
+	TopBandwidth      []OperationStat `json:"topBandwidth"` // Operations with the most bytes in and out
 	RecentErrors      []ErrorStat     `json:"recentErrors"`
 	RequestsByHour    []HourlyStat    `json:"requestsByHour"`
+	Nodes             []string        `json:"nodes,omitempty"` // Cluster nodes included, in cluster mode
+	Alerts            []Alert         `json:"alerts"`          // Alerts of this node currently firing
 }
 
 // SpecStats represents statistics for a specific spec
@@ -27,7 +32,46 @@ type SpecStats struct {
 	TotalRequests     int64           `json:"totalRequests"`
 	TotalErrors       int64           `json:"totalErrors"`
 	AvgResponseTimeMs float64         `json:"avgResponseTimeMs"`
+	TotalBytesIn      int64           `json:"totalBytesIn"`
+	TotalBytesOut     int64           `json:"totalBytesOut"`
 	Operations        []OperationStat `json:"operations"`
+	Alerts            []Alert         `json:"alerts"` // Alerts of the spec currently firing on this node
+}
+
+// StatsUpdate is an event of the live statistics stream: the totals of a node and what changed
+// since the previous event
+type StatsUpdate struct {
+	Time              time.Time   `json:"time"`
+	TotalRequests     int64       `json:"totalRequests"`
+	TotalErrors       int64       `json:"totalErrors"`
+	Requests          int64       `json:"requests"`          // Requests since the previous event
+	Errors            int64       `json:"errors"`            // Errors since the previous event
+	RequestsPerSecond float64     `json:"requestsPerSecond"` // Rate since the previous event
+	AvgResponseTimeMs float64     `json:"avgResponseTimeMs"` // Of the requests since the previous event
+	NewErrors         []ErrorStat `json:"newErrors"`         // Errors recorded since the previous event
+}
+
+// GroupStats rolls up the statistics of the specs of a group
+type GroupStats struct {
+	Group             string            `json:"group"`
+	TotalRequests     int64             `json:"totalRequests"`
+	TotalErrors       int64             `json:"totalErrors"`
+	AvgResponseTimeMs float64           `json:"avgResponseTimeMs"`
+	TotalBytesIn      int64             `json:"totalBytesIn"`
+	TotalBytesOut     int64             `json:"totalBytesOut"`
+	Specs             []SpecStatSummary `json:"specs"`
+}
+
+// SpecStatSummary represents the totals of a spec, without its operations
+type SpecStatSummary struct {
+	SpecID            string  `json:"specId"`
+	SpecName          string  `json:"specName"`
+	Enabled           bool    `json:"enabled"`
+	TotalRequests     int64   `json:"totalRequests"`
+	TotalErrors       int64   `json:"totalErrors"`
+	AvgResponseTimeMs float64 `json:"avgResponseTimeMs"`
+	TotalBytesIn      int64   `json:"totalBytesIn"`
+	TotalBytesOut     int64   `json:"totalBytesOut"`
 }
 
 // OperationStat represents statistics for a specific operation
@@ -41,6 +85,8 @@ type OperationStat struct {
 	AvgResponseTimeMs float64 `json:"avgResponseTimeMs"`
 	MinResponseTimeMs float64 `json:"minResponseTimeMs"`
 	MaxResponseTimeMs float64 `json:"maxResponseTimeMs"`
+	BytesIn           int64   `json:"bytesIn"`  // Bytes of request bodies
+	BytesOut          int64   `json:"bytesOut"` // Bytes of response bodies
 	LastRequestTime   string  `json:"lastRequestTime,omitempty"`
 }
 
@@ -53,6 +99,29 @@ type ErrorStat struct {
 	Method      string    `json:"method"`
 	StatusCode  int       `json:"statusCode"`
 	Error       string    `json:"error"`
+	RequestID   string    `json:"requestId,omitempty"`
+}
+
+// Metrics and states of alerts
+const (
+	AlertErrorRate  = "errorRate"
+	AlertP95Latency = "p95Latency"
+
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is a breach of a threshold of a spec's alert policy
+type Alert struct {
+	SpecID     string     `json:"specId"`
+	SpecName   string     `json:"specName"`
+	Metric     string     `json:"metric"`
+	Status     string     `json:"status"`
+	Value      float64    `json:"value"` // Error rate in percent or latency in milliseconds, as last evaluated
+	Threshold  float64    `json:"threshold"`
+	Requests   int        `json:"requests"` // Requests in the window, as last evaluated
+	Since      time.Time  `json:"since"`    // When the alert fired
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
 }
 
 // HourlyStat represents hourly request statistics
@@ -73,6 +142,8 @@ type AtomicOperationStat struct {
 	TotalTimeNs     atomic.Int64
 	MinTimeNs       atomic.Int64
 	MaxTimeNs       atomic.Int64
+	BytesIn         atomic.Int64
+	BytesOut        atomic.Int64
 	LastRequestTime atomic.Value // stores time.Time
 }
 
@@ -100,6 +171,44 @@ func (a *AtomicOperationStat) ToOperationStat() OperationStat {
 		AvgResponseTimeMs: avgMs,
 		MinResponseTimeMs: float64(a.MinTimeNs.Load()) / 1e6,
 		MaxResponseTimeMs: float64(a.MaxTimeNs.Load()) / 1e6,
+		BytesIn:           a.BytesIn.Load(),
+		BytesOut:          a.BytesOut.Load(),
 		LastRequestTime:   lastReqTime,
 	}
 }
+
+// SpecCoverage reports which operations of a spec received requests and which of their
+// response configs matched one. Disabled operations and response configs are listed but don't
+// count towards the totals
+type SpecCoverage struct {
+	SpecID             string              `json:"specId"`
+	SpecName           string              `json:"specName"`
+	TotalOperations    int                 `json:"totalOperations"`
+	CoveredOperations  int                 `json:"coveredOperations"`
+	OperationCoverage  float64             `json:"operationCoverage"` // Percentage of operations covered
+	TotalResponses     int                 `json:"totalResponses"`
+	CoveredResponses   int                 `json:"coveredResponses"`
+	ResponseCoverage   float64             `json:"responseCoverage"` // Percentage of response configs covered
+	Operations         []OperationCoverage `json:"operations"`
+	UncoveredResponses []ResponseCoverage  `json:"uncoveredResponses"` // Enabled response configs that never matched
+}
+
+// OperationCoverage reports the requests an operation and its response configs received
+type OperationCoverage struct {
+	OperationID string             `json:"operationId"`
+	Method      string             `json:"method"`
+	Path        string             `json:"path"`
+	Disabled    bool               `json:"disabled,omitempty"`
+	Requests    int64              `json:"requests"`
+	Covered     bool               `json:"covered"`
+	Responses   []ResponseCoverage `json:"responses"`
+}
+
+// ResponseCoverage reports the requests a response config matched
+type ResponseCoverage struct {
+	ResponseID  string `json:"responseId"`
+	OperationID string `json:"operationId"`
+	Name        string `json:"name"`
+	Enabled     bool   `json:"enabled"`
+	Hits        int64  `json:"hits"`
+}
@@ -6,17 +6,31 @@ import (
 
 // Trace represents a captured request/response trace
 type Trace struct {
-	ID              string        `json:"id"`
-	SpecID          string        `json:"specId"`
-	SpecName        string        `json:"specName"`
-	OperationID     string        `json:"operationId"`
-	OperationPath   string        `json:"operationPath"`
-	Timestamp       time.Time     `json:"timestamp"`
-	Duration        int64         `json:"duration"` // Duration in nanoseconds
-	Request         TraceRequest  `json:"request"`
-	Response        TraceResponse `json:"response"`
-	MatchedConfigID string        `json:"matchedConfigId,omitempty"`
-	MatchedConfig   string        `json:"matchedConfig,omitempty"` // Name of matched response config
+	ID              string         `json:"id"`
+	SpecID          string         `json:"specId"`
+	SpecName        string         `json:"specName"`
+	OperationID     string         `json:"operationId"`
+	OperationPath   string         `json:"operationPath"`
+	Timestamp       time.Time      `json:"timestamp"`
+	Duration        int64          `json:"duration"` // Duration in nanoseconds
+	RequestID       string         `json:"requestId,omitempty"`
+	Request         TraceRequest   `json:"request"`
+	Response        TraceResponse  `json:"response"`
+	MatchedConfigID string         `json:"matchedConfigId,omitempty"`
+	MatchedConfig   string         `json:"matchedConfig,omitempty"` // Name of matched response config
+	Error           string         `json:"error,omitempty"`         // Panic message and stack, if serving the request panicked
+	Node            string         `json:"node,omitempty"`          // Node that served the request, when traces are fanned out
+	Upstream        *TraceUpstream `json:"upstream,omitempty"`      // Upstream the request was forwarded to
+}
+
+// TraceUpstream records where a forwarded request went and how long the upstream took, so slow
+// responses can be told apart from slow mocks. Durations are in nanoseconds
+type TraceUpstream struct {
+	URL     string `json:"url"`
+	Connect int64  `json:"connect"`         // Getting a connection, including DNS, TCP and TLS; near 0 when reused
+	TTFB    int64  `json:"ttfb"`            // Until the first response byte, including the connect time
+	Total   int64  `json:"total"`           // Until the response body was copied
+	Error   string `json:"error,omitempty"` // Why the request failed, if it did
 }
 
 // TraceRequest represents the captured request
@@ -27,6 +41,9 @@ type TraceRequest struct {
 	Query   map[string][]string `json:"query"`
 	Headers map[string][]string `json:"headers"`
 	Body    string              `json:"body"`
+	// Set when the body was cut to the maximum capture size
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+	BodySize      int  `json:"bodySize,omitempty"` // Bytes of the whole body
 }
 
 // TraceResponse represents the captured response
@@ -34,6 +51,9 @@ type TraceResponse struct {
 	StatusCode int                 `json:"statusCode"`
 	Headers    map[string][]string `json:"headers"`
 	Body       string              `json:"body"`
+	// Set when the body was cut to the maximum capture size
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+	BodySize      int  `json:"bodySize,omitempty"` // Bytes of the whole body
 }
 
 // TraceFilter represents filters for querying traces
@@ -43,6 +63,7 @@ type TraceFilter struct {
 	Method      string    `json:"method,omitempty"`
 	Path        string    `json:"path,omitempty"`
 	StatusCode  int       `json:"statusCode,omitempty"`
+	StatusClass int       `json:"statusClass,omitempty"` // First digit of the status code, e.g. 5 for 5xx
 	StartTime   time.Time `json:"startTime,omitempty"`
 	EndTime     time.Time `json:"endTime,omitempty"`
 	Limit       int       `json:"limit,omitempty"`
@@ -0,0 +1,304 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// SigningKey is an RSA key that signs tokens with RS256
+type SigningKey struct {
+	ID      string // Key ID (kid) published in the JWKS
+	private *rsa.PrivateKey
+}
+
+// GenerateKey creates a new 2048-bit signing key
+func GenerateKey() (*SigningKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return newSigningKey(private), nil
+}
+
+// LoadKey reads an RSA private key from a PEM file in PKCS#1 or PKCS#8 form
+func LoadKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	if private, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newSigningKey(private), nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an RSA key", path)
+	}
+	return newSigningKey(private), nil
+}
+
+// newSigningKey derives the key ID from the public modulus, so it is stable for a key file
+func newSigningKey(private *rsa.PrivateKey) *SigningKey {
+	sum := sha256.Sum256(private.PublicKey.N.Bytes())
+	return &SigningKey{ID: hex.EncodeToString(sum[:8]), private: private}
+}
+
+// Public returns the public half of the key
+func (k *SigningKey) Public() *rsa.PublicKey {
+	return &k.private.PublicKey
+}
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWK returns the public key for publishing in a JWKS
+func (k *SigningKey) JWK() JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.ID,
+		N:   base64.RawURLEncoding.EncodeToString(k.private.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.private.PublicKey.E)).Bytes()),
+	}
+}
+
+// PublicKey converts a JWK back into an RSA public key
+func (j JWK) PublicKey() (*rsa.PublicKey, error) {
+	if j.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// Sign encodes claims as a compact JWT signed with RS256
+func (k *SigningKey) Sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ErrMalformedToken is returned for strings that are not compact JWTs
+var ErrMalformedToken = errors.New("malformed token")
+
+// Token is a decoded, not necessarily verified, JWT
+type Token struct {
+	Header    map[string]interface{}
+	Claims    map[string]interface{}
+	Payload   []byte // Claims as JSON
+	signed    string // header.payload, the input of the signature
+	signature []byte
+}
+
+// Parse decodes a compact JWT without verifying its signature
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	token := &Token{signed: parts[0] + "." + parts[1]}
+	if err := decodeSegment(parts[0], &token.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	if err := json.Unmarshal(payload, &token.Claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	token.Payload = payload
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformedToken, err)
+	}
+	token.signature = signature
+	return token, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// KeyID returns the kid header of the token, if any
+func (t *Token) KeyID() string {
+	kid, _ := t.Header["kid"].(string)
+	return kid
+}
+
+// Verify checks the token's RS256 signature against a public key
+func (t *Token) Verify(key *rsa.PublicKey) error {
+	if alg, _ := t.Header["alg"].(string); alg != "RS256" {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	digest := sha256.Sum256([]byte(t.signed))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], t.signature); err != nil {
+		return errors.New("invalid token signature")
+	}
+	return nil
+}
+
+// VerifyHMAC checks the token's HS256 signature against a shared secret
+func (t *Token) VerifyHMAC(secret []byte) error {
+	if alg, _ := t.Header["alg"].(string); alg != "HS256" {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(t.signed))
+	if !hmac.Equal(mac.Sum(nil), t.signature) {
+		return errors.New("invalid token signature")
+	}
+	return nil
+}
+
+// CheckTime checks the exp and nbf claims of the token, if present, against now
+func (t *Token) CheckTime(now time.Time) error {
+	if exp, ok := t.Claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := t.Claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return errors.New("token not valid yet")
+	}
+	return nil
+}
+
+// LoadPublicKeys reads RSA public keys from a file holding a JWKS or PEM blocks
+// PEM files may contain public keys, certificates or private keys
+func LoadPublicKeys(path string) ([]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var jwks struct {
+			Keys []JWK `json:"keys"`
+		}
+		if err := json.Unmarshal(data, &jwks); err != nil {
+			return nil, fmt.Errorf("invalid JWKS in %s: %w", path, err)
+		}
+		var keys []*rsa.PublicKey
+		for _, jwk := range jwks.Keys {
+			if jwk.Kty != "RSA" {
+				continue
+			}
+			key, err := jwk.PublicKey()
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %q in %s: %w", jwk.Kid, path, err)
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+
+	var keys []*rsa.PublicKey
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := parsePublicKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in %s", path)
+	}
+	return keys, nil
+}
+
+// parsePublicKey extracts the RSA public key of a PEM block
+func parsePublicKey(block *pem.Block) (*rsa.PublicKey, error) {
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			parsed = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		var private *rsa.PrivateKey
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			parsed = &private.PublicKey
+		}
+	case "PRIVATE KEY":
+		if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			if private, ok := parsed.(*rsa.PrivateKey); ok {
+				parsed = &private.PublicKey
+			}
+		}
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
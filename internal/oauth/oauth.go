@@ -0,0 +1,489 @@
+// Package oauth implements a mock OAuth2/OIDC authorization server, so clients can obtain
+// tokens from go-virtual alongside the mocked APIs
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PathPrefix is where the authorization server is mounted; it is also the path of the default issuer
+const PathPrefix = "/_oauth"
+
+// Paths of the endpoints below PathPrefix
+const (
+	DiscoveryPath = "/.well-known/openid-configuration"
+	JWKSPath      = "/jwks.json"
+	TokenPath     = "/token"
+)
+
+// Default token lifetimes
+const (
+	DefaultTokenTTL        = time.Hour
+	DefaultRefreshTokenTTL = 24 * time.Hour
+)
+
+// Client is an OAuth2 client allowed to request tokens
+type Client struct {
+	ID     string                 `yaml:"id"`
+	Secret string                 `yaml:"secret"`
+	Scopes []string               `yaml:"scopes"` // Scopes the client may request; empty allows any
+	Claims map[string]interface{} `yaml:"claims"` // Extra claims of its access tokens
+}
+
+// User is a resource owner for the password grant
+type User struct {
+	Username string                 `yaml:"username"`
+	Password string                 `yaml:"password"`
+	Claims   map[string]interface{} `yaml:"claims"` // Extra claims of the user's tokens
+}
+
+// Config configures the authorization server
+type Config struct {
+	Enabled         bool          `yaml:"enabled"`
+	Issuer          string        `yaml:"issuer"`   // Default: the request's scheme and host followed by PathPrefix
+	Audience        string        `yaml:"audience"` // aud claim of access tokens; default the client ID
+	TokenTTL        time.Duration `yaml:"tokenTTL"`
+	RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL"`
+	KeyFile         string        `yaml:"keyFile"` // PEM RSA key; default a key generated at startup
+	Clients         []Client      `yaml:"clients"` // Empty accepts any client ID and secret
+	Users           []User        `yaml:"users"`
+}
+
+// refreshGrant is what a refresh token stands for
+type refreshGrant struct {
+	clientID string
+	subject  string
+	scopes   []string
+	claims   map[string]interface{}
+	expires  time.Time
+}
+
+// Server is a mock authorization server with a token endpoint, a JWKS and a discovery document
+type Server struct {
+	mu            sync.RWMutex
+	config        Config
+	key           *SigningKey
+	keyFile       string // file key was loaded from, "" for a generated key
+	generated     *SigningKey
+	refreshTokens map[string]*refreshGrant
+	now           func() time.Time
+}
+
+// NewServer creates a disabled authorization server with a freshly generated signing key
+func NewServer() (*Server, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		key:           key,
+		generated:     key,
+		refreshTokens: make(map[string]*refreshGrant),
+		now:           time.Now,
+	}, nil
+}
+
+// Configure replaces the server's configuration
+// Tokens already issued stay valid as long as the signing key doesn't change
+func (s *Server) Configure(config Config) error {
+	key := s.generated
+	if config.KeyFile != "" {
+		s.mu.RLock()
+		current, currentFile := s.key, s.keyFile
+		s.mu.RUnlock()
+
+		if config.KeyFile == currentFile {
+			key = current
+		} else {
+			var err error
+			if key, err = LoadKey(config.KeyFile); err != nil {
+				return err
+			}
+		}
+	}
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = DefaultTokenTTL
+	}
+	if config.RefreshTokenTTL <= 0 {
+		config.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	s.key = key
+	s.keyFile = config.KeyFile
+	return nil
+}
+
+// Key returns the key tokens are currently signed with
+func (s *Server) Key() *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.key
+}
+
+// Enabled reports whether the server answers requests
+func (s *Server) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Enabled
+}
+
+// ServeHTTP serves the endpoints below PathPrefix
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.Enabled() {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "OAuth server is disabled"})
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, PathPrefix) {
+	case DiscoveryPath:
+		s.serveDiscovery(w, r)
+	case JWKSPath:
+		s.serveJWKS(w, r)
+	case TokenPath:
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+			return
+		}
+		s.serveToken(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+}
+
+// issuer returns the configured issuer or one derived from the request
+func (s *Server) issuer(r *http.Request) string {
+	s.mu.RLock()
+	issuer := s.config.Issuer
+	s.mu.RUnlock()
+	if issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + PathPrefix
+}
+
+// serveDiscovery answers with the OpenID Connect discovery document
+func (s *Server) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := s.issuer(r)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"token_endpoint":                        issuer + TokenPath,
+		"jwks_uri":                              issuer + JWKSPath,
+		"grant_types_supported":                 []string{"client_credentials", "password", "refresh_token"},
+		"response_types_supported":              []string{"token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"scopes_supported":                      s.scopes(),
+	})
+}
+
+// scopes returns the scopes of all configured clients
+func (s *Server) scopes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scopes := []string{"openid"}
+	seen := map[string]bool{"openid": true}
+	for _, client := range s.config.Clients {
+		for _, scope := range client.Scopes {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// serveJWKS answers with the public signing key
+func (s *Server) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []JWK{s.Key().JWK()}})
+}
+
+// tokenError is an error response of the token endpoint, as defined by RFC 6749 section 5.2
+type tokenError struct {
+	status      int
+	code        string
+	description string
+}
+
+// serveToken issues tokens for the client_credentials, password and refresh_token grants
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, &tokenError{http.StatusBadRequest, "invalid_request", "Invalid form body"})
+		return
+	}
+
+	client, tErr := s.authenticateClient(r)
+	if tErr != nil {
+		writeTokenError(w, tErr)
+		return
+	}
+
+	var grant *refreshGrant
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "client_credentials":
+		grant, tErr = s.clientCredentialsGrant(client, r)
+	case "password":
+		grant, tErr = s.passwordGrant(client, r)
+	case "refresh_token":
+		grant, tErr = s.refreshTokenGrant(client, r)
+	case "":
+		tErr = &tokenError{http.StatusBadRequest, "invalid_request", "Missing grant_type"}
+	default:
+		tErr = &tokenError{http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type: " + grantType}
+	}
+	if tErr != nil {
+		writeTokenError(w, tErr)
+		return
+	}
+
+	response, err := s.issue(r, grant)
+	if err != nil {
+		writeTokenError(w, &tokenError{http.StatusInternalServerError, "server_error", err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, response)
+}
+
+// authenticateClient checks client credentials from HTTP Basic auth or the form
+func (s *Server) authenticateClient(r *http.Request) (*Client, *tokenError) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id == "" {
+		return nil, &tokenError{http.StatusUnauthorized, "invalid_client", "Missing client credentials"}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.config.Clients) == 0 {
+		return &Client{ID: id}, nil
+	}
+	for i := range s.config.Clients {
+		client := &s.config.Clients[i]
+		if client.ID == id && client.Secret == secret {
+			return client, nil
+		}
+	}
+	return nil, &tokenError{http.StatusUnauthorized, "invalid_client", "Unknown client or wrong secret"}
+}
+
+// requestedScopes returns the scopes requested by the form, checked against the client's
+func requestedScopes(client *Client, r *http.Request) ([]string, *tokenError) {
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if len(client.Scopes) == 0 {
+		return scopes, nil
+	}
+	if len(scopes) == 0 {
+		return client.Scopes, nil
+	}
+	for _, scope := range scopes {
+		if scope != "openid" && !contains(client.Scopes, scope) {
+			return nil, &tokenError{http.StatusBadRequest, "invalid_scope", "Scope not allowed: " + scope}
+		}
+	}
+	return scopes, nil
+}
+
+// clientCredentialsGrant issues tokens to the client itself
+func (s *Server) clientCredentialsGrant(client *Client, r *http.Request) (*refreshGrant, *tokenError) {
+	scopes, tErr := requestedScopes(client, r)
+	if tErr != nil {
+		return nil, tErr
+	}
+	return &refreshGrant{clientID: client.ID, subject: client.ID, scopes: scopes, claims: client.Claims}, nil
+}
+
+// passwordGrant issues tokens to a configured user
+func (s *Server) passwordGrant(client *Client, r *http.Request) (*refreshGrant, *tokenError) {
+	scopes, tErr := requestedScopes(client, r)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	username, password := r.PostForm.Get("username"), r.PostForm.Get("password")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.config.Users {
+		if user.Username == username && user.Password == password {
+			return &refreshGrant{
+				clientID: client.ID,
+				subject:  user.Username,
+				scopes:   scopes,
+				claims:   mergeClaims(client.Claims, user.Claims),
+			}, nil
+		}
+	}
+	return nil, &tokenError{http.StatusBadRequest, "invalid_grant", "Invalid username or password"}
+}
+
+// refreshTokenGrant exchanges a refresh token for new tokens
+// Refresh tokens are rotated: the one presented can't be used again
+func (s *Server) refreshTokenGrant(client *Client, r *http.Request) (*refreshGrant, *tokenError) {
+	token := r.PostForm.Get("refresh_token")
+	if token == "" {
+		return nil, &tokenError{http.StatusBadRequest, "invalid_request", "Missing refresh_token"}
+	}
+
+	s.mu.Lock()
+	grant, ok := s.refreshTokens[token]
+	delete(s.refreshTokens, token)
+	s.mu.Unlock()
+
+	if !ok || grant.clientID != client.ID {
+		return nil, &tokenError{http.StatusBadRequest, "invalid_grant", "Unknown refresh token"}
+	}
+	if s.now().After(grant.expires) {
+		return nil, &tokenError{http.StatusBadRequest, "invalid_grant", "Refresh token expired"}
+	}
+
+	// A refresh may narrow the scopes of the original grant
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !contains(grant.scopes, scope) {
+				return nil, &tokenError{http.StatusBadRequest, "invalid_scope", "Scope not granted: " + scope}
+			}
+		}
+		grant.scopes = requested
+	}
+	return grant, nil
+}
+
+// issue signs an access token, and an ID token for the openid scope, and stores a new refresh token
+func (s *Server) issue(r *http.Request, grant *refreshGrant) (map[string]interface{}, error) {
+	s.mu.RLock()
+	config := s.config
+	key := s.key
+	s.mu.RUnlock()
+
+	now := s.now()
+	issuer := s.issuer(r)
+	audience := config.Audience
+	if audience == "" {
+		audience = grant.clientID
+	}
+
+	claims := mergeClaims(grant.claims, map[string]interface{}{
+		"iss":       issuer,
+		"sub":       grant.subject,
+		"aud":       audience,
+		"client_id": grant.clientID,
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       now.Add(config.TokenTTL).Unix(),
+		"jti":       randomToken(),
+	})
+	if len(grant.scopes) > 0 {
+		claims["scope"] = strings.Join(grant.scopes, " ")
+	}
+	accessToken, err := key.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := randomToken()
+	grant.expires = now.Add(config.RefreshTokenTTL)
+	s.mu.Lock()
+	s.pruneRefreshTokens(now)
+	s.refreshTokens[refreshToken] = grant
+	s.mu.Unlock()
+
+	response := map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(config.TokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+	}
+	if len(grant.scopes) > 0 {
+		response["scope"] = strings.Join(grant.scopes, " ")
+	}
+
+	if contains(grant.scopes, "openid") {
+		idClaims := mergeClaims(grant.claims, map[string]interface{}{
+			"iss": issuer,
+			"sub": grant.subject,
+			"aud": grant.clientID,
+			"iat": now.Unix(),
+			"exp": now.Add(config.TokenTTL).Unix(),
+		})
+		idToken, err := key.Sign(idClaims)
+		if err != nil {
+			return nil, err
+		}
+		response["id_token"] = idToken
+	}
+	return response, nil
+}
+
+// pruneRefreshTokens forgets expired refresh tokens. Must be called with s.mu held
+func (s *Server) pruneRefreshTokens(now time.Time) {
+	for token, grant := range s.refreshTokens {
+		if now.After(grant.expires) {
+			delete(s.refreshTokens, token)
+		}
+	}
+}
+
+// mergeClaims returns the claims of base overridden by those of override
+func mergeClaims(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// randomToken returns a random opaque token
+func randomToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTokenError writes an RFC 6749 error response
+func writeTokenError(w http.ResponseWriter, err *tokenError) {
+	if err.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="go-virtual"`)
+	}
+	writeJSON(w, err.status, map[string]string{"error": err.code, "error_description": err.description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
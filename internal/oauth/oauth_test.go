@@ -0,0 +1,169 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupServer(t *testing.T, config Config) *Server {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	config.Enabled = true
+	if err := server.Configure(config); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	return server
+}
+
+func requestToken(server *Server, form url.Values, clientID, secret string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest("POST", PathPrefix+TokenPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w, body
+}
+
+func TestClientCredentials(t *testing.T) {
+	server := setupServer(t, Config{
+		Clients: []Client{{ID: "app", Secret: "s3cret", Scopes: []string{"read", "write"}, Claims: map[string]interface{}{"tenant": "acme"}}},
+	})
+
+	w, body := requestToken(server, url.Values{"grant_type": {"client_credentials"}, "scope": {"read"}}, "app", "s3cret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body["token_type"] != "Bearer" || body["scope"] != "read" || body["refresh_token"] == "" {
+		t.Errorf("Unexpected token response: %v", body)
+	}
+
+	token, err := Parse(body["access_token"].(string))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := token.Verify(server.Key().Public()); err != nil {
+		t.Errorf("Expected a valid signature: %v", err)
+	}
+	if token.KeyID() != server.Key().ID {
+		t.Errorf("Expected kid %q, got %q", server.Key().ID, token.KeyID())
+	}
+	if token.Claims["sub"] != "app" || token.Claims["tenant"] != "acme" || token.Claims["iss"] != "http://example.com/_oauth" {
+		t.Errorf("Unexpected claims: %v", token.Claims)
+	}
+
+	if w, body := requestToken(server, url.Values{"grant_type": {"client_credentials"}}, "app", "wrong"); w.Code != http.StatusUnauthorized || body["error"] != "invalid_client" {
+		t.Errorf("Expected invalid_client, got %d %v", w.Code, body)
+	}
+	if w, body := requestToken(server, url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}, "app", "s3cret"); w.Code != http.StatusBadRequest || body["error"] != "invalid_scope" {
+		t.Errorf("Expected invalid_scope, got %d %v", w.Code, body)
+	}
+	if w, body := requestToken(server, url.Values{"grant_type": {"implicit"}}, "app", "s3cret"); body["error"] != "unsupported_grant_type" {
+		t.Errorf("Expected unsupported_grant_type, got %d %v", w.Code, body)
+	}
+}
+
+func TestPasswordAndRefresh(t *testing.T) {
+	server := setupServer(t, Config{
+		Users: []User{{Username: "alice", Password: "pw", Claims: map[string]interface{}{"role": "admin"}}},
+	})
+
+	form := url.Values{
+		"grant_type":    {"password"},
+		"username":      {"alice"},
+		"password":      {"pw"},
+		"scope":         {"openid profile"},
+		"client_id":     {"any-client"},
+		"client_secret": {"any-secret"},
+	}
+	w, body := requestToken(server, form, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body["id_token"] == nil {
+		t.Error("Expected an ID token for the openid scope")
+	}
+
+	refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {body["refresh_token"].(string)}, "scope": {"profile"}}
+	w, refreshed := requestToken(server, refresh, "any-client", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected refresh to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	token, _ := Parse(refreshed["access_token"].(string))
+	if token.Claims["sub"] != "alice" || token.Claims["role"] != "admin" || token.Claims["scope"] != "profile" {
+		t.Errorf("Unexpected refreshed claims: %v", token.Claims)
+	}
+
+	// Refresh tokens are single use
+	if w, body := requestToken(server, refresh, "any-client", ""); body["error"] != "invalid_grant" {
+		t.Errorf("Expected invalid_grant for a used refresh token, got %d %v", w.Code, body)
+	}
+
+	// Expired refresh tokens are rejected
+	refresh.Set("refresh_token", refreshed["refresh_token"].(string))
+	server.now = func() time.Time { return time.Now().Add(DefaultRefreshTokenTTL + time.Minute) }
+	if w, body := requestToken(server, refresh, "any-client", ""); body["error"] != "invalid_grant" {
+		t.Errorf("Expected invalid_grant for an expired refresh token, got %d %v", w.Code, body)
+	}
+
+	form.Set("password", "wrong")
+	if w, body := requestToken(server, form, "", ""); body["error"] != "invalid_grant" {
+		t.Errorf("Expected invalid_grant for a wrong password, got %d %v", w.Code, body)
+	}
+}
+
+func TestDiscoveryAndJWKS(t *testing.T) {
+	server := setupServer(t, Config{Issuer: "https://auth.test/"})
+
+	req := httptest.NewRequest("GET", PathPrefix+DiscoveryPath, nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	var discovery map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &discovery)
+	if discovery["issuer"] != "https://auth.test" || discovery["jwks_uri"] != "https://auth.test/jwks.json" {
+		t.Errorf("Unexpected discovery document: %v", discovery)
+	}
+
+	req = httptest.NewRequest("GET", PathPrefix+JWKSPath, nil)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &jwks)
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("Expected one key, got %d", len(jwks.Keys))
+	}
+	public, err := jwks.Keys[0].PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+	if public.N.Cmp(server.Key().Public().N) != 0 || public.E != server.Key().Public().E {
+		t.Error("Expected the JWKS to publish the signing key")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	server, _ := NewServer()
+
+	req := httptest.NewRequest("GET", PathPrefix+DiscoveryPath, nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
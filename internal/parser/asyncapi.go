@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// maxRefDepth bounds $ref resolution in (possibly cyclic) AsyncAPI documents
+const maxRefDepth = 16
+
+// asyncAPIDoc is a decoded AsyncAPI document with its local $refs resolvable
+type asyncAPIDoc struct {
+	root               map[string]interface{}
+	defaultContentType string
+	defaultProtocol    string // Protocol of the servers when they all use the same one
+}
+
+// ParseAsyncAPI parses an AsyncAPI 2.x or 3.x document (YAML or JSON) into message channels
+// Only local $refs are resolved; payload examples are generated from schemas when missing
+func (p *Parser) ParseAsyncAPI(content string) ([]models.Channel, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse AsyncAPI document: %w", err)
+	}
+	version, _ := root["asyncapi"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("not an AsyncAPI document: missing asyncapi version")
+	}
+
+	doc := &asyncAPIDoc{root: root}
+	doc.defaultContentType, _ = root["defaultContentType"].(string)
+	doc.defaultProtocol = doc.serverProtocol()
+
+	var channels []models.Channel
+	switch {
+	case strings.HasPrefix(version, "2."):
+		channels = doc.channelsV2()
+	case strings.HasPrefix(version, "3."):
+		channels = doc.channelsV3()
+	default:
+		return nil, fmt.Errorf("unsupported AsyncAPI version %q (expected 2.x or 3.x)", version)
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("AsyncAPI document has no channels")
+	}
+	return channels, nil
+}
+
+// channelsV2 reads channels whose publish and subscribe operations hold their messages
+// In 2.x, subscribe is what the application sends and publish what it receives
+func (d *asyncAPIDoc) channelsV2() []models.Channel {
+	var channels []models.Channel
+	for _, name := range sortedKeys(asMap(d.root["channels"])) {
+		node, _ := d.deref(asMap(d.root["channels"])[name], 0)
+		channel := d.newChannel(name, name, node)
+
+		for _, key := range []string{"subscribe", "publish"} {
+			op, _ := d.deref(node[key], 0)
+			if op == nil {
+				continue
+			}
+			action := models.ChannelSend
+			if key == "publish" {
+				action = models.ChannelReceive
+			}
+			id, _ := op["operationId"].(string)
+			if id == "" {
+				id = name + "." + key
+			}
+			summary, _ := op["summary"].(string)
+			channel.Operations = append(channel.Operations, models.ChannelOperation{ID: id, Action: action, Summary: summary})
+
+			message, ref := d.deref(op["message"], 0)
+			if oneOf, ok := message["oneOf"].([]interface{}); ok {
+				for _, m := range oneOf {
+					msg, ref := d.deref(m, 0)
+					addMessage(&channel, d.message(ref, msg))
+				}
+			} else if message != nil {
+				addMessage(&channel, d.message(ref, message))
+			}
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// channelsV3 reads channels holding their messages and attaches the document's operations
+func (d *asyncAPIDoc) channelsV3() []models.Channel {
+	var channels []models.Channel
+	index := make(map[string]int)
+	for _, name := range sortedKeys(asMap(d.root["channels"])) {
+		node, _ := d.deref(asMap(d.root["channels"])[name], 0)
+		address, _ := node["address"].(string)
+		if address == "" {
+			address = name
+		}
+		channel := d.newChannel(name, address, node)
+
+		messages := asMap(node["messages"])
+		for _, key := range sortedKeys(messages) {
+			msg, _ := d.deref(messages[key], 0)
+			addMessage(&channel, d.message(key, msg))
+		}
+		index[name] = len(channels)
+		channels = append(channels, channel)
+	}
+
+	operations := asMap(d.root["operations"])
+	for _, id := range sortedKeys(operations) {
+		op, _ := d.deref(operations[id], 0)
+		ref, _ := asMap(op["channel"])["$ref"].(string)
+		i, ok := index[unescapePointer(strings.TrimPrefix(ref, "#/channels/"))]
+		if !ok {
+			continue
+		}
+		action, _ := op["action"].(string)
+		summary, _ := op["summary"].(string)
+		channels[i].Operations = append(channels[i].Operations, models.ChannelOperation{ID: id, Action: action, Summary: summary})
+	}
+	return channels
+}
+
+// newChannel builds a channel without operations and messages
+func (d *asyncAPIDoc) newChannel(name, address string, node map[string]interface{}) models.Channel {
+	description, _ := node["description"].(string)
+	protocol := d.defaultProtocol
+	if bindings := sortedKeys(asMap(node["bindings"])); len(bindings) > 0 {
+		protocol = bindings[0]
+	}
+	return models.Channel{Name: name, Address: address, Description: description, Protocol: protocol}
+}
+
+// addMessage adds a message unless the channel already has one with the same name
+func addMessage(channel *models.Channel, message models.ChannelMessage) {
+	for _, m := range channel.Messages {
+		if m.Name == message.Name {
+			return
+		}
+	}
+	channel.Messages = append(channel.Messages, message)
+}
+
+// message builds a channel message, preferring the document's examples over generated payloads
+func (d *asyncAPIDoc) message(fallbackName string, node map[string]interface{}) models.ChannelMessage {
+	message := models.ChannelMessage{Name: fallbackName, ContentType: d.defaultContentType}
+	if name, ok := node["name"].(string); ok && name != "" {
+		message.Name = name
+	}
+	if contentType, ok := node["contentType"].(string); ok && contentType != "" {
+		message.ContentType = contentType
+	}
+
+	payload := d.resolveAll(node["payload"], 0)
+	// Multi-format schemas of 3.x wrap the schema with its format
+	if wrapped := asMap(payload); wrapped["schemaFormat"] != nil && wrapped["schema"] != nil {
+		payload = wrapped["schema"]
+	}
+	if payload != nil {
+		if data, err := json.Marshal(payload); err == nil {
+			message.Payload = string(data)
+		}
+	}
+
+	if examples, ok := node["examples"].([]interface{}); ok && len(examples) > 0 {
+		if example, ok := asMap(d.resolveAll(examples[0], 0))["payload"]; ok {
+			message.Example = formatExample(example)
+			return message
+		}
+	}
+	if message.Payload != "" {
+		var schema openapi3.Schema
+		if err := json.Unmarshal([]byte(message.Payload), &schema); err == nil {
+			message.Example = generateExampleFromSchema(&schema)
+		}
+	}
+	return message
+}
+
+// serverProtocol returns the protocol of the document's servers when they all use the same one
+func (d *asyncAPIDoc) serverProtocol() string {
+	protocol := ""
+	for _, server := range asMap(d.root["servers"]) {
+		node, _ := d.deref(server, 0)
+		p, _ := node["protocol"].(string)
+		if protocol != "" && p != protocol {
+			return ""
+		}
+		protocol = p
+	}
+	return protocol
+}
+
+// deref follows a local $ref and returns the referenced object with the last segment of the
+// reference, which names components
+func (d *asyncAPIDoc) deref(node interface{}, depth int) (map[string]interface{}, string) {
+	m := asMap(node)
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") || depth > maxRefDepth {
+		return m, ""
+	}
+
+	var target interface{} = d.root
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		target = asMap(target)[unescapePointer(segment)]
+	}
+	resolved, _ := d.deref(target, depth+1)
+	segments := strings.Split(ref, "/")
+	return resolved, unescapePointer(segments[len(segments)-1])
+}
+
+// resolveAll returns a copy of a node with every local $ref replaced by its target
+func (d *asyncAPIDoc) resolveAll(node interface{}, depth int) interface{} {
+	if depth > maxRefDepth {
+		return nil
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if _, ok := v["$ref"].(string); ok {
+			resolved, _ := d.deref(v, 0)
+			if resolved == nil {
+				return v
+			}
+			return d.resolveAll(resolved, depth+1)
+		}
+		copied := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			copied[key] = d.resolveAll(value, depth+1)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, value := range v {
+			copied[i] = d.resolveAll(value, depth+1)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// asMap returns a node as an object, or nil
+func asMap(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	return m
+}
+
+// sortedKeys returns the keys of an object in order, for stable channel lists
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unescapePointer decodes a JSON pointer segment
+func unescapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
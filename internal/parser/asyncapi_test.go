@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+const asyncAPIv2 = `
+asyncapi: "2.6.0"
+info:
+  title: Orders
+  version: "1.0"
+defaultContentType: application/json
+servers:
+  production:
+    url: broker:9092
+    protocol: kafka
+channels:
+  order.created:
+    description: Orders placed by customers
+    subscribe:
+      operationId: publishOrderCreated
+      message:
+        $ref: "#/components/messages/OrderCreated"
+  order.cancel:
+    publish:
+      message:
+        oneOf:
+          - $ref: "#/components/messages/CancelOrder"
+components:
+  messages:
+    OrderCreated:
+      payload:
+        $ref: "#/components/schemas/Order"
+      examples:
+        - payload:
+            id: 42
+            status: created
+    CancelOrder:
+      contentType: application/vnd.cancel+json
+      payload:
+        type: object
+        properties:
+          id:
+            type: integer
+  schemas:
+    Order:
+      type: object
+      properties:
+        id:
+          type: integer
+        status:
+          type: string
+`
+
+func TestParseAsyncAPI_V2(t *testing.T) {
+	channels, err := NewParser().ParseAsyncAPI(asyncAPIv2)
+	if err != nil {
+		t.Fatalf("ParseAsyncAPI failed: %v", err)
+	}
+	if len(channels) != 2 {
+		t.Fatalf("Expected 2 channels, got %d", len(channels))
+	}
+
+	cancel, created := channels[0], channels[1]
+	if created.Name != "order.created" || created.Address != "order.created" || created.Protocol != "kafka" || created.Description == "" {
+		t.Errorf("Unexpected channel: %+v", created)
+	}
+	if len(created.Operations) != 1 || created.Operations[0] != (models.ChannelOperation{ID: "publishOrderCreated", Action: models.ChannelSend}) {
+		t.Errorf("Unexpected operations: %+v", created.Operations)
+	}
+	if len(created.Messages) != 1 {
+		t.Fatalf("Expected one message, got %+v", created.Messages)
+	}
+	msg := created.Messages[0]
+	if msg.Name != "OrderCreated" || msg.ContentType != "application/json" || msg.Example != `{"id":42,"status":"created"}` {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if !strings.Contains(msg.Payload, `"status":{"type":"string"}`) {
+		t.Errorf("Expected the payload schema to be resolved, got %s", msg.Payload)
+	}
+
+	if cancel.Operations[0].Action != models.ChannelReceive || cancel.Operations[0].ID != "order.cancel.publish" {
+		t.Errorf("Unexpected operation: %+v", cancel.Operations[0])
+	}
+	if m := cancel.Messages[0]; m.Name != "CancelOrder" || m.ContentType != "application/vnd.cancel+json" || m.Example != `{"id":0}` {
+		t.Errorf("Expected a generated example, got %+v", m)
+	}
+}
+
+func TestParseAsyncAPI_V3(t *testing.T) {
+	content := `{
+  "asyncapi": "3.0.0",
+  "info": {"title": "Devices", "version": "1.0"},
+  "channels": {
+    "commands": {
+      "address": "devices/{deviceId}/commands",
+      "bindings": {"mqtt": {}},
+      "messages": {"reboot": {"payload": {"type": "object", "properties": {"delay": {"type": "integer", "example": 5}}}}}
+    }
+  },
+  "operations": {
+    "sendReboot": {"action": "send", "channel": {"$ref": "#/channels/commands"}, "summary": "Reboot a device"}
+  }
+}`
+	channels, err := NewParser().ParseAsyncAPI(content)
+	if err != nil {
+		t.Fatalf("ParseAsyncAPI failed: %v", err)
+	}
+
+	ch := channels[0]
+	if ch.Address != "devices/{deviceId}/commands" || ch.Protocol != "mqtt" {
+		t.Errorf("Unexpected channel: %+v", ch)
+	}
+	if len(ch.Operations) != 1 || ch.Operations[0].ID != "sendReboot" || ch.Operations[0].Action != "send" {
+		t.Errorf("Unexpected operations: %+v", ch.Operations)
+	}
+	if len(ch.Messages) != 1 || ch.Messages[0].Name != "reboot" || ch.Messages[0].Example != `{"delay":5}` {
+		t.Errorf("Unexpected messages: %+v", ch.Messages)
+	}
+}
+
+func TestParseAsyncAPI_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"openapi: 3.0.0",
+		`asyncapi: "1.2.0"`,
+		`asyncapi: "2.6.0"`,
+		"not: [valid",
+	} {
+		if _, err := NewParser().ParseAsyncAPI(content); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
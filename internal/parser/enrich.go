@@ -0,0 +1,363 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// MockExtension is the x-mock extension Enrich adds to each operation
+type MockExtension struct {
+	Disabled  bool                 `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Echo      bool                 `json:"echo,omitempty" yaml:"echo,omitempty"`
+	Forward   string               `json:"forward,omitempty" yaml:"forward,omitempty"` // Forwarding mode
+	RateLimit *MockRateLimit       `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	Responses []MockResponseConfig `json:"responses" yaml:"responses"`
+}
+
+// MockRateLimit describes the simulated rate limit of an operation
+type MockRateLimit struct {
+	Limit  int `json:"limit" yaml:"limit"`
+	Window int `json:"window" yaml:"window"` // Seconds
+}
+
+// MockResponseConfig describes a response config in the x-mock extension
+type MockResponseConfig struct {
+	Name             string             `json:"name" yaml:"name"`
+	Priority         int                `json:"priority" yaml:"priority"`
+	Status           int                `json:"status" yaml:"status"`
+	Conditions       []models.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	NegateConditions bool               `json:"negateConditions,omitempty" yaml:"negateConditions,omitempty"`
+	Delay            int                `json:"delay,omitempty" yaml:"delay,omitempty"`
+	Enabled          bool               `json:"enabled" yaml:"enabled"`
+	Templated        bool               `json:"templated,omitempty" yaml:"templated,omitempty"` // The body is rendered per request
+	BodyFile         string             `json:"bodyFile,omitempty" yaml:"bodyFile,omitempty"`
+	Example          string             `json:"example,omitempty" yaml:"example,omitempty"` // Name of the example holding the body
+}
+
+// Enrich merges the response configs of a spec's operations into its OpenAPI document, so it
+// describes what the mock actually returns. Each enabled config becomes a named example of its
+// status code and media type, and an x-mock extension on the operation lists all configs with
+// their conditions. Operations missing from the document, such as custom ones, are added.
+// The order and formatting of the original document are kept where possible.
+func Enrich(spec *models.Spec, ops []*models.Operation, configs map[string][]*models.ResponseConfig) (*yaml.Node, error) {
+	var root yaml.Node
+	if strings.TrimSpace(spec.Content) != "" {
+		if err := yaml.Unmarshal([]byte(spec.Content), &root); err != nil {
+			return nil, fmt.Errorf("failed to parse spec content: %w", err)
+		}
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		doc, err := encodeNode(map[string]interface{}{
+			"openapi": "3.0.3",
+			"info":    map[string]string{"title": spec.Name, "version": firstNonEmpty(spec.Version, "1.0.0")},
+		})
+		if err != nil {
+			return nil, err
+		}
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{doc}}
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("spec content is not an OpenAPI document")
+	}
+
+	sorted := append([]*models.Operation(nil), ops...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	paths := mapEnsure(doc, "paths")
+	for _, op := range sorted {
+		pathItem := mapEnsure(paths, op.Path)
+		method := strings.ToLower(op.Method)
+		operation := mapGet(pathItem, method)
+		if operation == nil {
+			operation = newMapping()
+			if op.OperationID != "" {
+				mapSet(operation, "operationId", scalar(op.OperationID))
+			}
+			if op.Summary != "" {
+				mapSet(operation, "summary", scalar(op.Summary))
+			}
+			mapSet(pathItem, method, operation)
+		}
+		if err := enrichOperation(operation, op, configs[op.ID]); err != nil {
+			return nil, err
+		}
+	}
+	return &root, nil
+}
+
+// enrichOperation adds the examples and x-mock extension of an operation's response configs
+func enrichOperation(operation *yaml.Node, op *models.Operation, configs []*models.ResponseConfig) error {
+	ext := MockExtension{
+		Disabled:  op.Disabled,
+		Echo:      op.Echo,
+		Responses: make([]MockResponseConfig, 0, len(configs)),
+	}
+	if op.Forward != nil {
+		ext.Forward = op.Forward.Mode
+	}
+	if op.RateLimit != nil {
+		ext.RateLimit = &MockRateLimit{Limit: op.RateLimit.Limit, Window: op.RateLimit.Window}
+	}
+
+	responses := mapEnsure(operation, "responses")
+	for _, cfg := range configs {
+		entry := MockResponseConfig{
+			Name:             cfg.Name,
+			Priority:         cfg.Priority,
+			Status:           cfg.StatusCode,
+			Conditions:       cfg.Conditions,
+			NegateConditions: cfg.NegateConditions,
+			Delay:            cfg.Delay,
+			Enabled:          cfg.Enabled,
+			Templated:        strings.Contains(cfg.Body, "{{"),
+			BodyFile:         cfg.BodyFile,
+		}
+		if cfg.Enabled && cfg.BodyFile == "" && cfg.Body != "" {
+			name, err := addExample(responses, cfg)
+			if err != nil {
+				return err
+			}
+			entry.Example = name
+		}
+		ext.Responses = append(ext.Responses, entry)
+	}
+
+	node, err := encodeNode(ext)
+	if err != nil {
+		return err
+	}
+	mapSet(operation, "x-mock", node)
+	return nil
+}
+
+// addExample adds the body of a response config as a named example of its response and
+// returns the example's name. Responses defined by reference are left alone.
+func addExample(responses *yaml.Node, cfg *models.ResponseConfig) (string, error) {
+	code := strconv.Itoa(cfg.StatusCode)
+	response := mapGet(responses, code)
+	if response == nil {
+		response = newMapping()
+		mapSet(response, "description", scalar(cfg.Name))
+		mapSet(responses, code, response)
+	}
+	if mapGet(response, "$ref") != nil {
+		return "", nil
+	}
+
+	content := mapEnsure(response, "content")
+	mediaType := exampleMediaType(cfg, content)
+	media := mapEnsure(content, mediaType)
+
+	examples := mapEnsure(media, "examples")
+	// OpenAPI doesn't allow example next to examples, so the spec's own example moves over
+	if example := mapGet(media, "example"); example != nil {
+		specExample := newMapping()
+		mapSet(specExample, "value", example)
+		mapSet(examples, uniqueKey(examples, "spec"), specExample)
+		mapDelete(media, "example")
+	}
+
+	var value interface{} = cfg.Body
+	if strings.Contains(mediaType, "json") {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(cfg.Body), &decoded); err == nil {
+			value = decoded
+		}
+	}
+	valueNode, err := encodeNode(value)
+	if err != nil {
+		return "", err
+	}
+
+	example := newMapping()
+	mapSet(example, "summary", scalar(cfg.Name))
+	mapSet(example, "value", valueNode)
+	name := uniqueKey(examples, slug(cfg.Name))
+	mapSet(examples, name, example)
+	return name, nil
+}
+
+// exampleMediaType picks the media type of a response config's example: its Content-Type
+// header, the only media type the spec defines, or a guess from the body
+func exampleMediaType(cfg *models.ResponseConfig, content *yaml.Node) string {
+	for name, value := range cfg.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+				return mediaType
+			}
+		}
+	}
+	if len(content.Content) == 2 {
+		return content.Content[0].Value
+	}
+	if json.Valid([]byte(cfg.Body)) {
+		return "application/json"
+	}
+	return "text/plain"
+}
+
+// RenderJSON writes a YAML document as indented JSON, keeping the order of its keys
+func RenderJSON(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, node); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// writeJSON writes a YAML node as compact JSON
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// mapGet returns the value of a key of a mapping node, or nil
+func mapGet(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mapSet sets the value of a key of a mapping node, appending new keys
+func mapSet(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, scalar(key), value)
+}
+
+// mapDelete removes a key of a mapping node
+func mapDelete(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// mapEnsure returns the mapping under a key of a mapping node, creating it when missing
+func mapEnsure(m *yaml.Node, key string) *yaml.Node {
+	value := mapGet(m, key)
+	if value == nil || value.Kind != yaml.MappingNode {
+		value = newMapping()
+		mapSet(m, key, value)
+	}
+	return value
+}
+
+func newMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// encodeNode converts a value into a YAML node
+func encodeNode(v interface{}) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns a response config name into an example name, e.g. "Premium user" into premium-user
+func slug(name string) string {
+	s := strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if s == "" {
+		return "example"
+	}
+	return s
+}
+
+// uniqueKey returns key, or key with a number appended if the mapping already has it
+func uniqueKey(m *yaml.Node, key string) string {
+	candidate := key
+	for n := 2; mapGet(m, candidate) != nil; n++ {
+		candidate = key + "-" + strconv.Itoa(n)
+	}
+	return candidate
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+const enrichSpec = `openapi: 3.0.0
+info:
+  title: Users
+  version: 1.0.0
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        200:
+          description: A user
+          content:
+            application/json:
+              example:
+                id: "1"
+        404:
+          $ref: '#/components/responses/NotFound'
+components:
+  responses:
+    NotFound:
+      description: Not found
+`
+
+func TestEnrich(t *testing.T) {
+	spec := &models.Spec{ID: "spec-1", Name: "Users", Content: enrichSpec}
+	ops := []*models.Operation{
+		{ID: "op-1", Method: "GET", Path: "/users/{id}", OperationID: "getUser", RateLimit: &models.RateLimitPolicy{Limit: 5, Window: 60}},
+		{ID: "op-2", Method: "POST", Path: "/users", OperationID: "createUser", Summary: "Create a user", Custom: true},
+	}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {
+			{
+				Name:       "Admin",
+				Priority:   0,
+				Conditions: []models.Condition{{Source: "path", Key: "id", Operator: "eq", Value: "42"}},
+				StatusCode: 200,
+				Body:       `{"id": "42", "role": "admin"}`,
+				Enabled:    true,
+			},
+			{Name: "Missing", Priority: 1, StatusCode: 404, Body: `{"error": "not found"}`, Enabled: true},
+			{Name: "Echo", Priority: 2, StatusCode: 200, Body: `{"id": "{{path.id}}"}`, Enabled: false},
+		},
+		"op-2": {
+			{Name: "Created", StatusCode: 201, Headers: map[string]string{"Content-Type": "text/plain; charset=utf-8"}, Body: "ok", Enabled: true},
+		},
+	}
+
+	node, err := Enrich(spec, ops, configs)
+	if err != nil {
+		t.Fatalf("Enrich failed: %v", err)
+	}
+	data, _ := yaml.Marshal(node)
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `yaml:"operationId"`
+			Summary     string `yaml:"summary"`
+			Responses   map[string]struct {
+				Ref     string `yaml:"$ref"`
+				Content map[string]struct {
+					Example  interface{}                       `yaml:"example"`
+					Examples map[string]map[string]interface{} `yaml:"examples"`
+				} `yaml:"content"`
+			} `yaml:"responses"`
+			XMock MockExtension `yaml:"x-mock"`
+		} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Enriched document is invalid YAML: %v", err)
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+	media := get.Responses["200"].Content["application/json"]
+	if media.Example != nil || len(media.Examples) != 2 {
+		t.Fatalf("Expected the spec example and one config example, got %+v", media)
+	}
+	admin := media.Examples["admin"]
+	if admin["summary"] != "Admin" || admin["value"].(map[string]interface{})["role"] != "admin" {
+		t.Errorf("Unexpected example: %+v", admin)
+	}
+	if media.Examples["spec"]["value"].(map[string]interface{})["id"] != "1" {
+		t.Errorf("Expected the spec's example to be kept, got %+v", media.Examples["spec"])
+	}
+	if get.Responses["404"].Ref == "" || len(get.Responses["404"].Content) != 0 {
+		t.Errorf("Expected the referenced response to be left alone, got %+v", get.Responses["404"])
+	}
+
+	mock := get.XMock
+	if mock.RateLimit == nil || mock.RateLimit.Limit != 5 || len(mock.Responses) != 3 {
+		t.Fatalf("Unexpected x-mock extension: %+v", mock)
+	}
+	if mock.Responses[0].Example != "admin" || len(mock.Responses[0].Conditions) != 1 {
+		t.Errorf("Unexpected x-mock entry: %+v", mock.Responses[0])
+	}
+	if disabled := mock.Responses[2]; disabled.Enabled || !disabled.Templated || disabled.Example != "" {
+		t.Errorf("Expected the disabled config without an example, got %+v", disabled)
+	}
+
+	created := doc.Paths["/users"]["post"]
+	if created.OperationID != "createUser" || created.Summary != "Create a user" {
+		t.Errorf("Expected the custom operation to be added, got %+v", created)
+	}
+	if created.Responses["201"].Content["text/plain"].Examples["created"]["value"] != "ok" {
+		t.Errorf("Unexpected custom operation responses: %+v", created.Responses)
+	}
+
+	// The enriched document is still a valid spec
+	if _, err := NewParser().Parse(string(data), ""); err != nil {
+		t.Errorf("Enriched document failed validation: %v\n%s", err, data)
+	}
+}
+
+func TestEnrich_WithoutContent(t *testing.T) {
+	spec := &models.Spec{ID: "spec-1", Name: "Scratch"}
+	ops := []*models.Operation{{ID: "op-1", Method: "GET", Path: "/ping"}}
+	configs := map[string][]*models.ResponseConfig{"op-1": {{Name: "Pong", StatusCode: 200, Body: "pong", Enabled: true}}}
+
+	node, err := Enrich(spec, ops, configs)
+	if err != nil {
+		t.Fatalf("Enrich failed: %v", err)
+	}
+	data, _ := yaml.Marshal(node)
+	if _, err := NewParser().Parse(string(data), ""); err != nil {
+		t.Errorf("Generated document failed validation: %v\n%s", err, data)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var node yaml.Node
+	yaml.Unmarshal([]byte("openapi: 3.0.0\ninfo:\n  title: T\n  version: 1.0.0\npaths:\n  /a:\n    get:\n      responses:\n        200:\n          description: ok\n"), &node)
+
+	data, err := RenderJSON(&node)
+	if err != nil {
+		t.Fatalf("RenderJSON failed: %v", err)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("Invalid JSON: %s", data)
+	}
+	if strings.Index(string(data), `"openapi"`) > strings.Index(string(data), `"info"`) {
+		t.Errorf("Expected keys in document order, got %s", data)
+	}
+	if !strings.Contains(string(data), `"200": {`) {
+		t.Errorf("Expected status codes as keys, got %s", data)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +32,13 @@ type ParseResult struct {
 
 // Parse parses an OpenAPI 3 specification
 func (p *Parser) Parse(content string, basePath string) (*ParseResult, error) {
+	return p.ParseForSpec(content, uuid.New().String(), basePath)
+}
+
+// ParseForSpec parses and validates an OpenAPI 3 specification using an existing spec ID
+// Operation IDs are derived from the spec ID, so re-parsing new content for the same
+// spec yields the same IDs for operations that still exist
+func (p *Parser) ParseForSpec(content string, specID string, basePath string) (*ParseResult, error) {
 	// Load the OpenAPI document
 	loader := openapi3.NewLoader()
 	loader.IsExternalRefsAllowed = true
@@ -45,7 +54,6 @@ func (p *Parser) Parse(content string, basePath string) (*ParseResult, error) {
 	}
 
 	// Extract spec info
-	specID := uuid.New().String()
 	now := time.Now()
 
 	spec := &models.Spec{
@@ -95,17 +103,7 @@ func (p *Parser) extractOperations(doc *openapi3.T, specID, basePath string) []*
 		}
 
 		// Process each HTTP method
-		methods := map[string]*openapi3.Operation{
-			"GET":     pathItem.Get,
-			"POST":    pathItem.Post,
-			"PUT":     pathItem.Put,
-			"DELETE":  pathItem.Delete,
-			"PATCH":   pathItem.Patch,
-			"HEAD":    pathItem.Head,
-			"OPTIONS": pathItem.Options,
-		}
-
-		for method, op := range methods {
+		for method, op := range pathOperations(pathItem) {
 			if op == nil {
 				continue
 			}
@@ -116,7 +114,7 @@ func (p *Parser) extractOperations(doc *openapi3.T, specID, basePath string) []*
 			operationID := op.OperationID
 			if operationID == "" {
 				// Generate operation ID if not provided
-				operationID = fmt.Sprintf("%s_%s", strings.ToLower(method), sanitizePath(pathPattern))
+				operationID = DefaultOperationID(method, pathPattern)
 			}
 
 			operation := &models.Operation{
@@ -169,30 +167,11 @@ func extractExampleResponseFromOp(op *openapi3.Operation) *models.ExampleRespons
 		}
 
 		// Extract body example from JSON content
-		for mediaType, content := range response.Value.Content {
-			if strings.Contains(mediaType, "json") {
-				example.Headers["Content-Type"] = mediaType
-				
-				if content.Example != nil {
-					// Direct example
-					example.Body = formatExample(content.Example)
-				} else if len(content.Examples) > 0 {
-					// Named examples - use first one
-					for _, ex := range content.Examples {
-						if ex.Value != nil && ex.Value.Value != nil {
-							example.Body = formatExample(ex.Value.Value)
-							break
-						}
-					}
-				} else if content.Schema != nil && content.Schema.Value != nil {
-					// Generate from schema
-					example.Body = generateExampleFromSchema(content.Schema.Value)
-				}
-				
-				if example.Body != "" {
-					return example
-				}
-				break
+		if mediaType, body, ok := extractContentExample(response.Value.Content); ok {
+			example.Headers["Content-Type"] = mediaType
+			example.Body = body
+			if example.Body != "" {
+				return example
 			}
 		}
 
@@ -205,6 +184,136 @@ func extractExampleResponseFromOp(op *openapi3.Operation) *models.ExampleRespons
 	return nil
 }
 
+// extractContentExample returns the media type and example body of the first JSON content entry
+// The body comes from the direct example, the first named example, or is generated from the schema
+func extractContentExample(content openapi3.Content) (string, string, bool) {
+	for mediaType, mt := range content {
+		if !strings.Contains(mediaType, "json") {
+			continue
+		}
+
+		var body string
+		if mt.Example != nil {
+			// Direct example
+			body = formatExample(mt.Example)
+		} else if len(mt.Examples) > 0 {
+			// Named examples - use first one
+			for _, ex := range mt.Examples {
+				if ex.Value != nil && ex.Value.Value != nil {
+					body = formatExample(ex.Value.Value)
+					break
+				}
+			}
+		} else if mt.Schema != nil && mt.Schema.Value != nil {
+			// Generate from schema
+			body = generateExampleFromSchema(mt.Schema.Value)
+		}
+
+		return mediaType, body, true
+	}
+
+	return "", "", false
+}
+
+// ResponseExample holds an example response for one documented status code of an operation
+type ResponseExample struct {
+	StatusCode  int
+	Description string
+	Headers     map[string]string
+	Body        string
+}
+
+// ExtractResponseExamples extracts an example for every documented status code of every operation
+// Results are keyed by the deterministic operation ID for the given spec ID and sorted by status code.
+// Range keys such as 4XX map to the first code of the range; the default response is skipped.
+func (p *Parser) ExtractResponseExamples(content string, specID string) (map[string][]ResponseExample, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+
+	doc, err := loader.LoadFromData([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+
+	result := make(map[string][]ResponseExample)
+	for pathPattern, pathItem := range doc.Paths.Map() {
+		if pathItem == nil {
+			continue
+		}
+
+		for method, op := range pathOperations(pathItem) {
+			if op == nil || op.Responses == nil {
+				continue
+			}
+
+			var examples []ResponseExample
+			for key, response := range op.Responses.Map() {
+				statusCode, ok := parseStatusKey(key)
+				if !ok || response == nil || response.Value == nil {
+					continue
+				}
+
+				example := ResponseExample{
+					StatusCode: statusCode,
+					Headers:    make(map[string]string),
+				}
+				if response.Value.Description != nil {
+					example.Description = *response.Value.Description
+				}
+				for name, header := range response.Value.Headers {
+					if header.Value != nil && header.Value.Example != nil {
+						example.Headers[name] = fmt.Sprintf("%v", header.Value.Example)
+					}
+				}
+				if mediaType, body, ok := extractContentExample(response.Value.Content); ok {
+					example.Headers["Content-Type"] = mediaType
+					example.Body = body
+				}
+
+				examples = append(examples, example)
+			}
+
+			sort.Slice(examples, func(i, j int) bool {
+				return examples[i].StatusCode < examples[j].StatusCode
+			})
+			result[generateOperationID(specID, method, pathPattern)] = examples
+		}
+	}
+
+	return result, nil
+}
+
+// parseStatusKey converts a responses map key to a status code
+// Accepts exact codes ("404") and ranges ("4XX"); rejects "default"
+func parseStatusKey(key string) (int, bool) {
+	if len(key) == 3 && strings.HasSuffix(strings.ToUpper(key), "XX") {
+		digit, err := strconv.Atoi(key[:1])
+		if err != nil || digit < 1 || digit > 5 {
+			return 0, false
+		}
+		return digit * 100, true
+	}
+
+	code, err := strconv.Atoi(key)
+	if err != nil || code < 100 || code > 599 {
+		return 0, false
+	}
+	return code, true
+}
+
+// pathOperations returns the operations of a path item keyed by HTTP method
+func pathOperations(pathItem *openapi3.PathItem) map[string]*openapi3.Operation {
+	return map[string]*openapi3.Operation{
+		"GET":     pathItem.Get,
+		"POST":    pathItem.Post,
+		"PUT":     pathItem.Put,
+		"DELETE":  pathItem.Delete,
+		"PATCH":   pathItem.Patch,
+		"HEAD":    pathItem.Head,
+		"OPTIONS": pathItem.Options,
+	}
+}
+
 // formatExample converts an example value to a JSON string
 func formatExample(v interface{}) string {
 	switch val := v.(type) {
@@ -238,6 +347,11 @@ func normalizeBasePath(basePath string) string {
 	return basePath
 }
 
+// DefaultOperationID builds the operationId used when an operation doesn't declare one
+func DefaultOperationID(method, pathPattern string) string {
+	return fmt.Sprintf("%s_%s", strings.ToLower(method), sanitizePath(pathPattern))
+}
+
 // sanitizePath converts a path to a valid identifier
 func sanitizePath(pathPattern string) string {
 	// Replace path parameters
@@ -325,6 +439,12 @@ func (p *Parser) ExtractExampleResponse(content string, method, pathPattern stri
 	return headers, body, nil
 }
 
+// GenerateOperationID returns the deterministic operation ID used for the given spec, method and path
+// Manually added operations use it too, so a later spec upload defining the same endpoint maps onto them
+func GenerateOperationID(specID, method, path string) string {
+	return generateOperationID(specID, method, path)
+}
+
 // generateOperationID generates a deterministic operation ID based on spec, method, and path
 // This allows operations to be regenerated from spec while maintaining stable IDs
 // that response configs can reference
@@ -336,26 +456,176 @@ func generateOperationID(specID, method, path string) string {
 	return hex.EncodeToString(hash[:16])
 }
 
-// generateExampleFromSchema generates a basic example from an OpenAPI schema
+// maxExampleDepth bounds recursion when generating examples from (possibly cyclic) schemas
+const maxExampleDepth = 8
+
+// generateExampleFromSchema generates an example JSON document from an OpenAPI schema
 func generateExampleFromSchema(schema *openapi3.Schema) string {
+	// Top-level examples are passed through as-is (string examples are often raw JSON)
 	if schema.Example != nil {
-		return fmt.Sprintf("%v", schema.Example)
+		return formatExample(schema.Example)
+	}
+
+	data, err := json.Marshal(exampleValue(schema, 0))
+	if err != nil {
+		return "null"
 	}
+	return string(data)
+}
 
-	switch schema.Type.Slice()[0] {
+// exampleValue builds an example value for a schema, recursing into properties,
+// array items and allOf/oneOf/anyOf composition
+func exampleValue(schema *openapi3.Schema, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	// allOf merges the properties of every sub-schema
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		var last interface{}
+		for _, ref := range schema.AllOf {
+			if ref == nil {
+				continue
+			}
+			last = exampleValue(ref.Value, depth+1)
+			if obj, ok := last.(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		if obj, ok := exampleValue(withoutComposition(schema), depth+1).(map[string]interface{}); ok {
+			for k, v := range obj {
+				merged[k] = v
+			}
+		}
+		if len(merged) > 0 {
+			return merged
+		}
+		return last
+	}
+
+	// oneOf/anyOf use the first alternative
+	for _, alternatives := range []openapi3.SchemaRefs{schema.OneOf, schema.AnyOf} {
+		for _, ref := range alternatives {
+			if ref != nil && ref.Value != nil {
+				return exampleValue(ref.Value, depth+1)
+			}
+		}
+	}
+
+	schemaType := ""
+	if types := schema.Type.Slice(); len(types) > 0 {
+		schemaType = types[0]
+	} else if len(schema.Properties) > 0 {
+		schemaType = "object"
+	} else if schema.Items != nil {
+		schemaType = "array"
+	}
+
+	switch schemaType {
 	case "object":
-		return "{}"
+		return objectExample(schema, depth)
 	case "array":
-		return "[]"
+		count := int(schema.MinItems)
+		if count < 1 {
+			count = 1
+		}
+		var item interface{}
+		if schema.Items != nil {
+			item = exampleValue(schema.Items.Value, depth+1)
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i] = item
+		}
+		return items
 	case "string":
-		return `"string"`
+		return stringExample(schema.Format)
 	case "integer":
-		return "0"
+		if schema.Min != nil {
+			return int64(*schema.Min)
+		}
+		return 0
 	case "number":
-		return "0.0"
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0.0
 	case "boolean":
-		return "false"
+		return false
 	default:
-		return "null"
+		return nil
+	}
+}
+
+// objectExample builds an example object from the schema properties
+// When the schema lists required properties only those are included
+func objectExample(schema *openapi3.Schema, depth int) map[string]interface{} {
+	obj := make(map[string]interface{})
+
+	names := schema.Required
+	if len(names) == 0 {
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok || prop == nil {
+			continue
+		}
+		obj[name] = exampleValue(prop.Value, depth+1)
 	}
+
+	return obj
+}
+
+// stringExample returns a sample string for a schema format
+func stringExample(format string) string {
+	switch format {
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "c3RyaW5n"
+	default:
+		return "string"
+	}
+}
+
+// withoutComposition returns a shallow copy of a schema with composition keywords removed
+func withoutComposition(schema *openapi3.Schema) *openapi3.Schema {
+	copied := *schema
+	copied.AllOf = nil
+	copied.OneOf = nil
+	copied.AnyOf = nil
+	return &copied
 }
@@ -3,6 +3,8 @@ package parser
 import (
 	"strings"
 	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
 )
 
 func TestNewParser(t *testing.T) {
@@ -625,3 +627,170 @@ func TestParse_JSONSpec(t *testing.T) {
 		t.Errorf("Expected 1 operation, got %d", len(result.Operations))
 	}
 }
+
+func TestExtractResponseExamples(t *testing.T) {
+	p := NewParser()
+
+	spec := `
+openapi: 3.0.0
+info:
+  title: Test API
+  version: 1.0.0
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '404':
+          description: Not found
+          content:
+            application/json:
+              example:
+                error: not found
+        '200':
+          description: Success
+          content:
+            application/json:
+              schema:
+                type: object
+        5XX:
+          description: Server error
+        default:
+          description: Unexpected error
+`
+
+	result, err := p.ExtractResponseExamples(spec, "spec-1")
+	if err != nil {
+		t.Fatalf("ExtractResponseExamples failed: %v", err)
+	}
+
+	examples := result[generateOperationID("spec-1", "GET", "/users/{id}")]
+	if len(examples) != 3 {
+		t.Fatalf("Expected 3 examples, got %d", len(examples))
+	}
+
+	expectedCodes := []int{200, 404, 500}
+	for i, code := range expectedCodes {
+		if examples[i].StatusCode != code {
+			t.Errorf("Expected status %d at index %d, got %d", code, i, examples[i].StatusCode)
+		}
+	}
+
+	if examples[0].Body != "{}" {
+		t.Errorf("Expected schema-derived body '{}', got %q", examples[0].Body)
+	}
+	if examples[1].Body != `{"error":"not found"}` {
+		t.Errorf("Expected example body, got %q", examples[1].Body)
+	}
+	if examples[1].Description != "Not found" {
+		t.Errorf("Expected description 'Not found', got %q", examples[1].Description)
+	}
+	if examples[1].Headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type header, got %q", examples[1].Headers["Content-Type"])
+	}
+}
+
+func TestGenerateExampleFromSchema(t *testing.T) {
+	p := NewParser()
+
+	spec := `
+openapi: 3.0.0
+info:
+  title: Test API
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: Success
+          content:
+            application/json:
+              schema:
+                type: array
+                minItems: 2
+                items:
+                  $ref: '#/components/schemas/Pet'
+components:
+  schemas:
+    Base:
+      type: object
+      required: [id, createdAt]
+      properties:
+        id:
+          type: string
+          format: uuid
+        createdAt:
+          type: string
+          format: date-time
+        internal:
+          type: string
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            status:
+              type: string
+              enum: [available, sold]
+            owner:
+              oneOf:
+                - type: object
+                  properties:
+                    email:
+                      type: string
+                      format: email
+                - type: string
+            tags:
+              type: array
+              items:
+                type: integer
+`
+
+	result, err := p.Parse(spec, "")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	example := result.Operations[0].ExampleResponse
+	if example == nil {
+		t.Fatal("Expected example response")
+	}
+
+	expected := `[{"createdAt":"2024-01-01T00:00:00Z","id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","owner":{"email":"user@example.com"},"status":"available","tags":[0]},` +
+		`{"createdAt":"2024-01-01T00:00:00Z","id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","owner":{"email":"user@example.com"},"status":"available","tags":[0]}]`
+	if example.Body != expected {
+		t.Errorf("Unexpected example body:\n got: %s\nwant: %s", example.Body, expected)
+	}
+}
+
+func TestGenerateExampleFromSchema_Primitives(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   *openapi3.Schema
+		expected string
+	}{
+		{"string", openapi3.NewStringSchema(), `"string"`},
+		{"email", openapi3.NewStringSchema().WithFormat("email"), `"user@example.com"`},
+		{"integer", openapi3.NewIntegerSchema(), "0"},
+		{"integer with minimum", openapi3.NewIntegerSchema().WithMin(5), "5"},
+		{"boolean", openapi3.NewBoolSchema(), "false"},
+		{"enum", openapi3.NewStringSchema().WithEnum("a", "b"), `"a"`},
+		{"empty object", openapi3.NewObjectSchema(), "{}"},
+		{"untyped", &openapi3.Schema{}, "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateExampleFromSchema(tt.schema)
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// DefaultAPIKeyHeader is the header checked by API key policies that don't name one
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// authResult is the outcome of the simulated authentication of a request
+type authResult string
+
+const (
+	authOK           authResult = ""
+	authUnauthorized authResult = "unauthorized" // No credentials
+	authForbidden    authResult = "forbidden"    // Credentials not accepted
+)
+
+// defaultAuthResponses are used when a policy doesn't configure its own
+var defaultAuthResponses = map[authResult]models.FallbackResponse{
+	authUnauthorized: {
+		StatusCode: http.StatusUnauthorized,
+		Body:       `{"error": "Unauthorized"}`,
+	},
+	authForbidden: {
+		StatusCode: http.StatusForbidden,
+		Body:       `{"error": "Forbidden"}`,
+	},
+}
+
+// compileAuthPattern compiles the credential pattern of an auth policy once, when routes load
+// The pattern must match the whole credential, so "test-[a-z]+" doesn't accept "xtest-a!"
+// It returns nil without a pattern; an invalid one, which the admin API rejects, accepts nothing
+func compileAuthPattern(policy *models.AuthPolicy) *regexp.Regexp {
+	if policy == nil || policy.Pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + policy.Pattern + ")$")
+	if err != nil {
+		slog.Warn("invalid auth pattern, accepting no credential by pattern", "pattern", policy.Pattern, "error", err)
+		return nil
+	}
+	return re
+}
+
+// checkAuth checks the credentials of a request against a spec's auth policy and its compiled
+// pattern. Requests pass when the policy is nil or disabled
+func checkAuth(r *http.Request, policy *models.AuthPolicy, pattern *regexp.Regexp) authResult {
+	if policy == nil || !policy.Enabled {
+		return authOK
+	}
+
+	credential := authCredential(r, policy)
+	if credential == "" {
+		return authUnauthorized
+	}
+	if acceptsCredential(policy, pattern, credential) {
+		return authOK
+	}
+	return authForbidden
+}
+
+// authCredential returns the API key or bearer token of a request, or "" if it has none
+func authCredential(r *http.Request, policy *models.AuthPolicy) string {
+	if policy.Type == models.AuthBearer {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	header := policy.Header
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	return strings.TrimSpace(r.Header.Get(header))
+}
+
+// acceptsCredential reports whether a credential is one of the policy's keys or matches its pattern
+// A policy with neither accepts any credential
+func acceptsCredential(policy *models.AuthPolicy, pattern *regexp.Regexp, credential string) bool {
+	if len(policy.Keys) == 0 && policy.Pattern == "" {
+		return true
+	}
+	for _, key := range policy.Keys {
+		if key == credential {
+			return true
+		}
+	}
+	return pattern != nil && pattern.MatchString(credential)
+}
+
+// writeAuthError writes the policy's response to a request that failed authentication
+// It returns the status code and rendered body for tracing
+func (e *Engine) writeAuthError(w http.ResponseWriter, r *http.Request, result authResult, spec *models.Spec, pathParams map[string]string, requestBody string) (int, string) {
+	response := spec.Auth.Unauthorized
+	if result == authForbidden {
+		response = spec.Auth.Forbidden
+	}
+	if response == nil {
+		builtin := defaultAuthResponses[result]
+		response = &builtin
+	}
+
+	// Tell clients how to authenticate, as real servers do
+	if result == authUnauthorized && spec.Auth.Type == models.AuthBearer {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="go-virtual"`)
+	}
+
+	templateCtx := e.newTemplateContext(r, spec, pathParams, requestBody)
+	return e.writeTemplated(w, response, defaultAuthResponses[result].StatusCode, templateCtx)
+}
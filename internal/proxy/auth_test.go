@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_AuthAPIKey(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Auth: &models.AuthPolicy{
+		Enabled: true,
+		Type:    models.AuthAPIKey,
+		Keys:    []string{"secret"},
+		Pattern: `^test-[a-z]+$`,
+	}}, nil, &models.ResponseConfig{})
+
+	tests := []struct {
+		name     string
+		key      string
+		expected int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"listed key", "secret", http.StatusOK},
+		{"pattern", "test-abc", http.StatusOK},
+		{"rejected", "wrong", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tt.key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_AuthBearer(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Auth: &models.AuthPolicy{
+		Enabled: true,
+		Type:    models.AuthBearer,
+		Unauthorized: &models.FallbackResponse{
+			Body: `{"error": "login required", "path": "{{request.path}}"}`,
+		},
+	}}, nil, &models.ResponseConfig{})
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected a WWW-Authenticate header")
+	}
+	if w.Body.String() != `{"error": "login required", "path": "/api/users"}` {
+		t.Errorf("Unexpected body: %s", w.Body.String())
+	}
+
+	// Without keys or a pattern any token is accepted
+	req = httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Authorization", "bearer anything")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_AuthDisabled(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Auth: &models.AuthPolicy{Enabled: false, Type: models.AuthBearer}}, nil, &models.ResponseConfig{})
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestReloadRoutes_CompilesAuthPattern(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Auth: &models.AuthPolicy{Enabled: true, Type: models.AuthAPIKey, Pattern: `test-[a-z]+`}}, nil, &models.ResponseConfig{})
+
+	routes := engine.routes["GET"]
+	if len(routes) != 1 || routes[0].authPattern == nil || routes[0].authPattern.String() != `^(?:test-[a-z]+)$` {
+		t.Fatalf("Expected the auth pattern to be compiled with the route, got %+v", routes)
+	}
+
+	// The pattern must match the whole credential
+	for key, expected := range map[string]int{"test-abc": http.StatusOK, "xtest-abc": http.StatusForbidden, "test-abc!": http.StatusForbidden} {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set(DefaultAPIKeyHeader, key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("Expected status %d for key %q, got %d", expected, key, w.Code)
+		}
+	}
+
+	// A pattern stored before validation accepts nothing, but listed keys still work
+	engine = setupSpecEngine(t, &models.Spec{Auth: &models.AuthPolicy{Enabled: true, Type: models.AuthAPIKey, Keys: []string{"secret"}, Pattern: `(`}}, nil, &models.ResponseConfig{})
+	for key, expected := range map[string]int{"secret": http.StatusOK, "(": http.StatusForbidden} {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set(DefaultAPIKeyHeader, key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("Expected status %d for key %q, got %d", expected, key, w.Code)
+		}
+	}
+}
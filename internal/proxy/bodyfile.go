@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// openBodyFile opens a file of a spec served as a response body and sets its content type,
+// taken from the file extension or else sniffed from its first bytes. Headers of the response
+// config can still override it
+func (e *Engine) openBodyFile(w http.ResponseWriter, spec *models.Spec, name string) (io.ReadSeekCloser, *models.SpecFile, error) {
+	file, info, err := e.store.OpenSpecFile(spec.ID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	return file, info, nil
+}
+
+// writeBodyFile streams an opened body file as the response with its length, stopping once
+// ctx is done. It returns a description of the body for traces, which don't hold file contents
+func writeBodyFile(ctx context.Context, w http.ResponseWriter, statusCode int, file io.Reader, info *models.SpecFile) (string, error) {
+	defer interruptWrites(ctx, w)()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.WriteHeader(statusCode)
+	_, err := io.Copy(w, file)
+	return fmt.Sprintf("[file %s, %d bytes]", info.Name, info.Size), err
+}
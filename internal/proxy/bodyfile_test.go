@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_BodyFile(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Reports", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/reports/{id}", FullPath: "/reports/{id}"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, BodyFile: "report.pdf", Body: "ignored", Enabled: true,
+		Headers: map[string]string{"Content-Disposition": `attachment; filename="report-{{path.id}}.pdf"`}})
+	store.SaveSpecFile("spec-1", "report.pdf", []byte("%PDF-1.4 report"))
+	engine.ReloadRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/reports/7", nil))
+	if w.Code != 200 || w.Body.String() != "%PDF-1.4 report" {
+		t.Fatalf("Expected the file, got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Expected application/pdf, got %q", ct)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "15" {
+		t.Errorf("Expected Content-Length 15, got %q", cl)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="report-7.pdf"` {
+		t.Errorf("Expected the templated header, got %q", cd)
+	}
+
+	// Files without a known extension have their content type sniffed
+	store.SaveSpecFile("spec-1", "page", []byte("<!DOCTYPE html><html></html>"))
+	cfg, _ := store.GetResponseConfig("resp-1")
+	cfg.BodyFile = "page"
+	cfg.Headers = nil
+	store.UpdateResponseConfig(cfg)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/reports/7", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" || w.Body.String() != "<!DOCTYPE html><html></html>" {
+		t.Errorf("Expected the sniffed HTML file, got %q %q", ct, w.Body.String())
+	}
+
+	// A missing file is an internal error
+	store.DeleteSpecFile("spec-1", "page")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/reports/7", nil))
+	if w.Code != 500 {
+		t.Errorf("Expected status 500 for a missing file, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_BodyFileTimeout(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Exports", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/export", FullPath: "/export", Timeout: 100})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, BodyFile: "export.bin", Enabled: true})
+	store.SaveSpecFile("spec-1", "export.bin", bytes.Repeat([]byte("x"), 64<<20))
+	engine.ReloadRoutes()
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	// A client too slow to take the file before the timeout gets it cut off
+	resp, err := http.Get(server.URL + "/export")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(300 * time.Millisecond)
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err == nil || n >= 64<<20 {
+		t.Errorf("Expected the body to be cut off, got %d bytes and %v", n, err)
+	}
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// defaultChaosResponse is the injected error of chaos policies without their own
+var defaultChaosResponse = models.FallbackResponse{
+	StatusCode: http.StatusServiceUnavailable,
+	Body:       `{"error": "Service unavailable"}`,
+}
+
+// chaosState holds the random source of each spec's chaos policy
+type chaosState struct {
+	mu      sync.Mutex
+	sources map[string]*rand.Rand // spec ID -> source seeded from the policy
+}
+
+// chaosDecision is what a chaos policy does to one request
+type chaosDecision struct {
+	delay time.Duration
+	fail  bool
+}
+
+// decide draws the injections for the next request of a spec
+func (c *chaosState) decide(specID string, policy *models.ChaosPolicy) chaosDecision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rng, ok := c.sources[specID]
+	if !ok {
+		seed := policy.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng = rand.New(rand.NewSource(seed))
+		if c.sources == nil {
+			c.sources = make(map[string]*rand.Rand)
+		}
+		c.sources[specID] = rng
+	}
+
+	// Every request draws the same numbers, so a seed reproduces the whole sequence
+	delayRoll, delayFraction, errorRoll := rng.Float64()*100, rng.Float64(), rng.Float64()*100
+
+	var d chaosDecision
+	if delayRoll < policy.DelayPercent {
+		maxDelay := max(policy.MaxDelay, policy.MinDelay)
+		ms := policy.MinDelay + int(delayFraction*float64(maxDelay-policy.MinDelay+1))
+		d.delay = time.Duration(ms) * time.Millisecond
+	}
+	d.fail = errorRoll < policy.ErrorPercent
+	return d
+}
+
+// ResetChaos starts the injections of a spec's chaos policy over from its seed
+func (e *Engine) ResetChaos(specID string) {
+	e.chaos.mu.Lock()
+	defer e.chaos.mu.Unlock()
+	delete(e.chaos.sources, specID)
+}
+
+// injectChaos delays or fails a request as the chaos policy of its spec draws it
+// It reports whether the request was answered
+func (e *Engine) injectChaos(ctx context.Context, w http.ResponseWriter, r *http.Request, rt *route, pathParams map[string]string, requestBody string, startTime time.Time, logger *slog.Logger) bool {
+	policy := rt.spec.Chaos
+	if policy == nil || !policy.Enabled {
+		return false
+	}
+	d := e.chaos.decide(rt.spec.ID, policy)
+
+	if d.delay > 0 {
+		logger.Debug("chaos: injecting delay", "delay", d.delay)
+		timeout := e.operationTimeout(rt.operation)
+		if !e.sleep(ctx, d.delay) {
+			if r.Context().Err() != nil {
+				logger.Debug("client went away during chaos delay")
+				return true
+			}
+			logger.Warn("response timed out", "timeout", timeout, "chaosDelay", d.delay)
+			statusCode, responseBody := e.writeFallback(w, r, fallbackTimeout, rt.spec, pathParams, requestBody)
+			e.recordFallback(rt, r, requestBody, startTime, w, "timeout", statusCode, responseBody)
+			return true
+		}
+	}
+
+	if !d.fail {
+		return false
+	}
+	logger.Debug("chaos: injecting error")
+	response := policy.Response
+	if response == nil {
+		response = &defaultChaosResponse
+	}
+	templateCtx := e.newTemplateContext(r, rt.spec, pathParams, requestBody)
+	statusCode, responseBody := e.writeTemplated(w, response, http.StatusServiceUnavailable, templateCtx)
+	e.recordFallback(rt, r, requestBody, startTime, w, "chaos", statusCode, responseBody)
+	return true
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// chaosSequence returns the status codes of n requests
+func chaosSequence(engine *Engine, n int) []int {
+	codes := make([]int, n)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+		codes[i] = w.Code
+	}
+	return codes
+}
+
+func TestServeHTTP_ChaosErrors(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: &models.ChaosPolicy{
+		Enabled:      true,
+		ErrorPercent: 100,
+		Response:     &models.FallbackResponse{StatusCode: 500, Body: `{"error": "chaos on {{request.path}}"}`},
+	}}, nil, &models.ResponseConfig{})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"error": "chaos on /api/users"}` {
+		t.Fatalf("Expected the chaos response, got %d %s", w.Code, w.Body.String())
+	}
+	if traces := engine.tracingService.GetTraces(nil); len(traces) != 1 || traces[0].MatchedConfig != "chaos" {
+		t.Errorf("Expected a chaos trace, got %+v", traces)
+	}
+
+	engine = setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: &models.ChaosPolicy{Enabled: false, ErrorPercent: 100}}, nil, &models.ResponseConfig{})
+	if codes := chaosSequence(engine, 3); codes[0] != 200 || codes[1] != 200 || codes[2] != 200 {
+		t.Errorf("Expected a disabled policy to inject nothing, got %v", codes)
+	}
+}
+
+func TestServeHTTP_ChaosSeed(t *testing.T) {
+	policy := &models.ChaosPolicy{Enabled: true, ErrorPercent: 50, Seed: 42}
+
+	first := chaosSequence(setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: policy}, nil, &models.ResponseConfig{}), 40)
+	second := chaosSequence(setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: policy}, nil, &models.ResponseConfig{}), 40)
+
+	failures := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same sequence from the same seed, got %v and %v", first, second)
+		}
+		if first[i] == http.StatusServiceUnavailable {
+			failures++
+		}
+	}
+	if failures == 0 || failures == len(first) {
+		t.Errorf("Expected some of the requests to fail, got %v", first)
+	}
+
+	// Resetting starts the sequence over
+	engine := setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: policy}, nil, &models.ResponseConfig{})
+	chaosSequence(engine, 5)
+	engine.ResetChaos("spec-1")
+	if again := chaosSequence(engine, 5); again[0] != first[0] || again[4] != first[4] {
+		t.Errorf("Expected a reset to start over, got %v after %v", again, first[:5])
+	}
+}
+
+func TestServeHTTP_ChaosDelay(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Tracing: true, Chaos: &models.ChaosPolicy{Enabled: true, DelayPercent: 100, MinDelay: 30, MaxDelay: 40}}, nil, &models.ResponseConfig{})
+
+	start := time.Now()
+	if codes := chaosSequence(engine, 1); codes[0] != http.StatusOK {
+		t.Fatalf("Expected status 200 after the delay, got %d", codes[0])
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected a delay of at least 30ms, took %v", elapsed)
+	}
+
+	engine.SetResponseTimeout(10 * time.Millisecond)
+	if codes := chaosSequence(engine, 1); codes[0] != StatusTimeout {
+		t.Errorf("Expected status %d when the delay passes the timeout, got %d", StatusTimeout, codes[0])
+	}
+}
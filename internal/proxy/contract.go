@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/requestid"
+)
+
+// maxContractViolations bounds the distinct violations kept per spec; the least recently seen
+// one makes room for a new one
+const maxContractViolations = 200
+
+// contractOptions validate requests without authenticating them, since the spec's simulated
+// authentication does that, and report schema errors without dumping the schema
+var contractOptions = func() *openapi3filter.Options {
+	opts := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+	opts.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		if pointer := err.JSONPointer(); len(pointer) > 0 {
+			return "/" + strings.Join(pointer, "/") + ": " + err.Reason
+		}
+		return err.Reason
+	})
+	return opts
+}()
+
+// contractState holds the documents of specs checking their contract and the violations found
+type contractState struct {
+	mu      sync.Mutex
+	docs    map[string]*contractDoc    // spec ID -> document of specs checking their contract
+	reports map[string]*contractReport // spec ID -> violations
+}
+
+// contractDoc is a parsed OpenAPI document with the content it was parsed from
+type contractDoc struct {
+	content string
+	doc     *openapi3.T
+}
+
+// contractReport holds the violations of a spec by their key
+type contractReport struct {
+	total      int64
+	violations map[string]*models.ContractViolation
+}
+
+// sync parses the documents of specs that started checking their contract or changed their
+// content, and drops those of specs that stopped. Reports are kept until cleared
+func (c *contractState) sync(specs []*models.Spec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := make(map[string]*contractDoc)
+	for _, spec := range specs {
+		if !spec.ContractCheck || strings.TrimSpace(spec.Content) == "" {
+			continue
+		}
+		if cached, ok := c.docs[spec.ID]; ok && cached.content == spec.Content {
+			docs[spec.ID] = cached
+			continue
+		}
+		loader := openapi3.NewLoader()
+		loader.IsExternalRefsAllowed = true
+		doc, err := loader.LoadFromData([]byte(spec.Content))
+		if err != nil {
+			slog.Warn("contract check disabled: failed to parse spec content", "specId", spec.ID, "error", err)
+			continue
+		}
+		docs[spec.ID] = &contractDoc{content: spec.Content, doc: doc}
+	}
+	c.docs = docs
+}
+
+// document returns the document of a spec checking its contract, or nil
+func (c *contractState) document(specID string) *openapi3.T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.docs[specID]; ok {
+		return cached.doc
+	}
+	return nil
+}
+
+// record counts a violation of a spec's contract
+func (c *contractState) record(specID string, v models.ContractViolation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reports == nil {
+		c.reports = make(map[string]*contractReport)
+	}
+	report, ok := c.reports[specID]
+	if !ok {
+		report = &contractReport{violations: make(map[string]*models.ContractViolation)}
+		c.reports[specID] = report
+	}
+	report.total++
+
+	key := strings.Join([]string{v.Kind, v.Method, v.Path, v.Parameter, v.Message}, "\x00")
+	if existing, ok := report.violations[key]; ok {
+		existing.Count++
+		existing.LastSeen = v.LastSeen
+		existing.LastRequestID = v.LastRequestID
+		return
+	}
+
+	if len(report.violations) >= maxContractViolations {
+		var oldestKey string
+		var oldest time.Time
+		for k, existing := range report.violations {
+			if oldestKey == "" || existing.LastSeen.Before(oldest) {
+				oldestKey, oldest = k, existing.LastSeen
+			}
+		}
+		delete(report.violations, oldestKey)
+	}
+	v.Count = 1
+	v.FirstSeen = v.LastSeen
+	report.violations[key] = &v
+}
+
+// ContractReport returns the contract violations recorded for a spec, most frequent first
+func (e *Engine) ContractReport(spec *models.Spec) models.ContractReport {
+	e.contracts.mu.Lock()
+	defer e.contracts.mu.Unlock()
+
+	result := models.ContractReport{
+		SpecID:     spec.ID,
+		Enabled:    spec.ContractCheck,
+		Violations: make([]models.ContractViolation, 0),
+	}
+	report, ok := e.contracts.reports[spec.ID]
+	if !ok {
+		return result
+	}
+	result.Total = report.total
+	for _, v := range report.violations {
+		result.Violations = append(result.Violations, *v)
+	}
+	sort.Slice(result.Violations, func(i, j int) bool {
+		a, b := result.Violations[i], result.Violations[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+	return result
+}
+
+// ClearContractReport removes the contract violations recorded for a spec
+func (e *Engine) ClearContractReport(specID string) {
+	e.contracts.mu.Lock()
+	defer e.contracts.mu.Unlock()
+	delete(e.contracts.reports, specID)
+}
+
+// checkContract validates a request against the operation it matched in the spec's document and
+// records how it violates it. Operations missing from the document, such as custom ones, are
+// not checked
+func (e *Engine) checkContract(r *http.Request, rt *route, pathParams map[string]string, requestBody string) {
+	if !rt.spec.ContractCheck {
+		return
+	}
+	doc := e.contracts.document(rt.spec.ID)
+	if doc == nil || doc.Paths == nil {
+		return
+	}
+	pathItem := doc.Paths.Value(rt.operation.Path)
+	if pathItem == nil {
+		return
+	}
+	operation := pathItem.GetOperation(rt.operation.Method)
+	if operation == nil {
+		return
+	}
+
+	// The validator reads the body, which the engine already did
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(strings.NewReader(requestBody))
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route: &routers.Route{
+			Spec:      doc,
+			Path:      rt.operation.Path,
+			PathItem:  pathItem,
+			Method:    rt.operation.Method,
+			Operation: operation,
+		},
+		Options: contractOptions,
+	}
+	err := openapi3filter.ValidateRequest(req.Context(), input)
+	if err == nil {
+		return
+	}
+
+	v := models.ContractViolation{
+		Kind:          models.ViolationInvalidRequest,
+		Method:        rt.operation.Method,
+		Path:          rt.operation.Path,
+		OperationID:   rt.operation.ID,
+		Message:       err.Error(),
+		LastSeen:      time.Now(),
+		LastRequestID: requestid.FromContext(r.Context()),
+	}
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		switch {
+		case reqErr.Parameter != nil:
+			v.Kind = models.ViolationInvalidParameter
+			v.Parameter = reqErr.Parameter.In + ":" + reqErr.Parameter.Name
+		case reqErr.RequestBody != nil:
+			v.Kind = models.ViolationInvalidBody
+		}
+	}
+	e.contracts.record(rt.spec.ID, v)
+}
+
+// recordUnknownPath records a request under the base path of a spec checking its contract that
+// matched none of its operations
+func (e *Engine) recordUnknownPath(r *http.Request, spec *models.Spec) {
+	if spec == nil || !spec.ContractCheck {
+		return
+	}
+	e.contracts.record(spec.ID, models.ContractViolation{
+		Kind:          models.ViolationUnknownPath,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Message:       "no operation for " + r.Method + " " + r.URL.Path,
+		LastSeen:      time.Now(),
+		LastRequestID: requestid.FromContext(r.Context()),
+	})
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+const contractYAML = `openapi: 3.0.0
+info:
+  title: Pets
+  version: "1.0"
+paths:
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+            enum: [name, age]
+      responses:
+        200:
+          description: OK
+  /pets:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        201:
+          description: Created
+`
+
+func sendContractRequests(engine *Engine) {
+	requests := []struct{ method, path, body string }{
+		{"GET", "/api/pets/1", ""},
+		{"GET", "/api/pets/abc", ""},
+		{"GET", "/api/pets/abc", ""},
+		{"GET", "/api/pets/1?fields=color", ""},
+		{"POST", "/api/pets", `{"name": "Rex"}`},
+		{"POST", "/api/pets", `{"age": 3}`},
+		{"GET", "/api/owners", ""},
+		{"GET", "/api/custom?anything=goes", ""},
+	}
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		if req.body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+// contractOperations serves the pets of contractYAML and a route it lacks
+func contractOperations() []*models.Operation {
+	return []*models.Operation{
+		{ID: "op-get", Path: "/pets/{id}"},
+		{Method: "POST", Path: "/pets"},
+		{Path: "/custom"},
+	}
+}
+
+func TestServeHTTP_ContractViolations(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Content: contractYAML, ContractCheck: true}, contractOperations())
+	sendContractRequests(engine)
+
+	spec := &models.Spec{ID: "spec-1", ContractCheck: true}
+	report := engine.ContractReport(spec)
+	if report.Total != 5 || len(report.Violations) != 4 {
+		t.Fatalf("Expected 5 violations of 4 kinds, got %+v", report)
+	}
+
+	first := report.Violations[0]
+	if first.Kind != models.ViolationInvalidParameter || first.Parameter != "path:id" || first.Count != 2 || first.OperationID != "op-get" {
+		t.Errorf("Expected the invalid path parameter first, got %+v", first)
+	}
+	byKind := map[string]models.ContractViolation{}
+	for _, v := range report.Violations[1:] {
+		byKind[v.Kind+" "+v.Parameter] = v
+	}
+	if v, ok := byKind["invalidParameter query:fields"]; !ok || v.Path != "/pets/{id}" {
+		t.Errorf("Expected the invalid query parameter, got %+v", report.Violations)
+	}
+	if v, ok := byKind["invalidBody "]; !ok || !strings.Contains(v.Message, "name") {
+		t.Errorf("Expected the body missing its name, got %+v", report.Violations)
+	}
+	if v, ok := byKind["unknownPath "]; !ok || v.Method != "GET" || v.Path != "/api/owners" {
+		t.Errorf("Expected the unknown path, got %+v", report.Violations)
+	}
+
+	engine.ClearContractReport("spec-1")
+	if report := engine.ContractReport(spec); report.Total != 0 || len(report.Violations) != 0 {
+		t.Errorf("Expected a cleared report, got %+v", report)
+	}
+}
+
+func TestServeHTTP_ContractCheckDisabled(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Content: contractYAML}, contractOperations())
+	sendContractRequests(engine)
+
+	if report := engine.ContractReport(&models.Spec{ID: "spec-1"}); report.Total != 0 || report.Enabled {
+		t.Errorf("Expected no violations without the contract check, got %+v", report)
+	}
+}
+
+func TestContractState_Bounded(t *testing.T) {
+	var c contractState
+	for i := 0; i < maxContractViolations+10; i++ {
+		c.record("spec-1", models.ContractViolation{Kind: models.ViolationUnknownPath, Path: "/x/" + strings.Repeat("a", i)})
+	}
+	if n := len(c.reports["spec-1"].violations); n != maxContractViolations {
+		t.Errorf("Expected %d violations, got %d", maxContractViolations, n)
+	}
+	if c.reports["spec-1"].total != maxContractViolations+10 {
+		t.Errorf("Expected every request counted, got %d", c.reports["spec-1"].total)
+	}
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Default CORS headers, used for specs without a CORS policy
+const (
+	defaultCORSMethods = "GET, POST, PUT, DELETE, OPTIONS, PATCH"
+	defaultCORSHeaders = "Origin, Content-Type, Accept, Authorization"
+	defaultCORSMaxAge  = "86400"
+)
+
+// isPreflight reports whether the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// applyCORS adds the CORS headers of the spec's policy to a response
+// A nil spec or a spec without a policy gets the permissive default headers.
+// Origins the policy doesn't allow get no CORS headers, so browsers block the response
+func applyCORS(w http.ResponseWriter, r *http.Request, spec *models.Spec) {
+	var policy *models.CORSPolicy
+	if spec != nil {
+		policy = spec.CORS
+	}
+
+	if policy == nil {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", defaultCORSMethods)
+		w.Header().Set("Access-Control-Allow-Headers", defaultCORSHeaders)
+		w.Header().Set("Access-Control-Max-Age", defaultCORSMaxAge)
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	if !policy.Enabled || origin == "" {
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	allowOrigin := matchOrigin(policy.AllowedOrigins, origin)
+	if allowOrigin == "" {
+		return
+	}
+	// Browsers reject a wildcard origin on credentialed requests
+	if allowOrigin == "*" && policy.AllowCredentials {
+		allowOrigin = origin
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+	if policy.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+	}
+
+	if isPreflight(r) {
+		methods := defaultCORSMethods
+		if len(policy.AllowedMethods) > 0 {
+			methods = strings.Join(policy.AllowedMethods, ", ")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", methods)
+
+		if len(policy.AllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+		} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			w.Header().Set("Access-Control-Allow-Headers", requested)
+		}
+
+		if policy.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+		}
+	}
+}
+
+// matchOrigin returns the Access-Control-Allow-Origin value for origin, or "" if it is not allowed
+func matchOrigin(allowed []string, origin string) string {
+	for _, o := range allowed {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_DefaultCORS(t *testing.T) {
+	engine := setupSpecEngine(t, nil, nil, &models.ResponseConfig{})
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected wildcard origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestServeHTTP_CORSPolicy(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{CORS: &models.CORSPolicy{
+		Enabled:          true,
+		AllowedOrigins:   []string{"http://localhost:3000"},
+		AllowedMethods:   []string{"GET", "POST"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}}, nil, &models.ResponseConfig{})
+
+	// Allowed origin
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("Expected origin to be allowed, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Expected credentials to be allowed")
+	}
+	if w.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" {
+		t.Errorf("Unexpected exposed headers: %q", w.Header().Get("Access-Control-Expose-Headers"))
+	}
+
+	// Disallowed origin still gets the response, without CORS headers
+	req = httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no allowed origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Preflight for an existing route
+	req = httptest.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-Api-Key")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" {
+		t.Errorf("Unexpected allowed methods: %q", w.Header().Get("Access-Control-Allow-Methods"))
+	}
+	if w.Header().Get("Access-Control-Allow-Headers") != "X-Api-Key" {
+		t.Errorf("Expected requested headers to be allowed, got %q", w.Header().Get("Access-Control-Allow-Headers"))
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Unexpected max age: %q", w.Header().Get("Access-Control-Max-Age"))
+	}
+
+	// Preflight for a method without a route is answered with the path's methods
+	req = httptest.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("Unexpected Allow header: %q", w.Header().Get("Allow"))
+	}
+}
+
+func TestServeHTTP_CORSPolicyDisabled(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{CORS: &models.CORSPolicy{Enabled: false}}, nil, &models.ResponseConfig{})
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		allowed  []string
+		origin   string
+		expected string
+	}{
+		{[]string{"*"}, "http://a.example", "*"},
+		{[]string{"http://a.example/"}, "http://a.example", "http://a.example"},
+		{[]string{"http://a.example"}, "http://b.example", ""},
+		{nil, "http://a.example", ""},
+	}
+
+	for _, tt := range tests {
+		if got := matchOrigin(tt.allowed, tt.origin); got != tt.expected {
+			t.Errorf("matchOrigin(%v, %q) = %q, want %q", tt.allowed, tt.origin, got, tt.expected)
+		}
+	}
+}
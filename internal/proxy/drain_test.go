@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestDrain(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/slow", FullPath: "/api/slow"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        `{}`,
+		Delay:       200,
+		Enabled:     true,
+	})
+	engine.ReloadRoutes()
+
+	// Start a delayed request, then drain with a grace period
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/slow", nil))
+		done <- w.Code
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	engine.StartDrain(50 * time.Millisecond)
+	status := engine.DrainStatus()
+	if !status.Draining || status.Rejecting || status.InFlight != 1 {
+		t.Fatalf("Expected draining with 1 in-flight request, got %+v", status)
+	}
+
+	// Requests are still accepted during the grace period
+	if engine.rejectDraining(httptest.NewRecorder()) {
+		t.Fatal("Expected requests to be accepted during the grace period")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After after the grace period, got %d", w.Code)
+	}
+
+	// In-flight requests complete
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := engine.WaitIdle(ctx); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected the in-flight request to succeed, got %d", code)
+	}
+
+	// Cancelling accepts requests again
+	engine.CancelDrain()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/slow", nil))
+	if w.Code != http.StatusOK || engine.Draining() {
+		t.Errorf("Expected requests to be served after CancelDrain, got %d", w.Code)
+	}
+}
+
+func TestWaitIdleTimeout(t *testing.T) {
+	engine, _ := setupTestEngine(t)
+
+	engine.drain.inFlight.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := engine.WaitIdle(ctx); err == nil {
+		t.Error("Expected WaitIdle to time out with a request in flight")
+	}
+}
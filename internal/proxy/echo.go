@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// echoResponse describes the request an echo operation answers
+type echoResponse struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	URL     string              `json:"url"`
+	Query   map[string][]string `json:"query"`
+	Headers map[string]string   `json:"headers"` // Repeated values are joined with ", "
+	Body    string              `json:"body"`
+	JSON    json.RawMessage     `json:"json,omitempty"` // The body, when it is JSON
+}
+
+// writeEcho answers a request with a JSON description of itself
+// It returns the status code and body written
+func writeEcho(w http.ResponseWriter, r *http.Request, requestBody string) (int, string) {
+	echo := echoResponse{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		URL:     r.URL.String(),
+		Query:   r.URL.Query(),
+		Headers: make(map[string]string, len(r.Header)),
+		Body:    requestBody,
+	}
+	for key, values := range r.Header {
+		echo.Headers[key] = strings.Join(values, ", ")
+	}
+	if requestBody != "" && json.Valid([]byte(requestBody)) {
+		echo.JSON = json.RawMessage(requestBody)
+	}
+
+	data, err := json.Marshal(echo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return http.StatusInternalServerError, err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+	return http.StatusOK, string(data)
+}
@@ -1,39 +1,75 @@
 package proxy
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"path"
 	"regexp"
+	"runtime/debug"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/prasenjit/go-virtual/internal/accesslog"
+	"github.com/prasenjit/go-virtual/internal/alerts"
 	"github.com/prasenjit/go-virtual/internal/condition"
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/logging"
 	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/requestid"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
 	"github.com/prasenjit/go-virtual/internal/template"
 	"github.com/prasenjit/go-virtual/internal/tracing"
+	"github.com/prasenjit/go-virtual/internal/variables"
 )
 
 // Engine handles proxying requests to virtual API endpoints
 type Engine struct {
-	store           storage.Storage
-	statsCollector  *stats.Collector
-	tracingService  *tracing.Service
-	condEvaluator   *condition.Evaluator
-	templateEngine  *template.Engine
-	mu              sync.RWMutex
-	routes          map[string][]*route // method -> routes
+	store            storage.Storage
+	statsCollector   *stats.Collector
+	tracingService   *tracing.Service
+	condEvaluator    *condition.Evaluator
+	templateEngine   *template.Engine
+	mu               sync.RWMutex
+	routes           map[string][]*route // method -> routes
+	specs            []*models.Spec      // Enabled specs, whose documents are served under their base path
+	fallbacks        models.FallbackResponses
+	accessLog        *accesslog.Logger
+	responseTimeout  time.Duration
+	basePathPrefixes []string // Allowed spec base path prefixes; empty allows any
+	utilities        utilitySettings
+	drain            drainState
+	limiter          concurrencyLimiter
+	rateLimits       rateLimiter
+	chaos            chaosState
+	contracts        contractState
+	health           healthState
+	alerts           *alerts.Monitor
+	state            stateStore
+	events           *events.Dispatcher
+	variables        *variables.Store
+	upstream         *http.Client // Forwards requests to the upstreams of specs
+	learning         learnState
 }
 
 // route represents a registered route
 type route struct {
-	spec      *models.Spec
-	operation *models.Operation
-	pattern   *regexp.Regexp
-	paramKeys []string
+	spec        *models.Spec
+	operation   *models.Operation
+	pattern     *regexp.Regexp
+	paramKeys   []string
+	authPattern *regexp.Regexp // Compiled credential pattern of the spec's auth policy
+}
+
+// tracing reports whether requests on this route should be traced
+func (r *route) tracing() bool {
+	return r.spec.Tracing || r.operation.Tracing
 }
 
 // NewEngine creates a new proxy engine
@@ -45,6 +81,10 @@ func NewEngine(store storage.Storage, statsCollector *stats.Collector, tracingSe
 		condEvaluator:  condition.NewEvaluator(),
 		templateEngine: template.NewEngine(),
 		routes:         make(map[string][]*route),
+		variables:      variables.NewStore(),
+		events:         events.NewDispatcher(),
+		upstream:       newUpstreamClient(),
+		alerts:         alerts.NewMonitor(),
 	}
 
 	// Load initial routes
@@ -53,7 +93,82 @@ func NewEngine(store storage.Storage, statsCollector *stats.Collector, tracingSe
 	return e
 }
 
-// ReloadRoutes reloads all routes from enabled specs
+// Alerts returns the monitor of the specs' alert policies
+func (e *Engine) Alerts() *alerts.Monitor {
+	return e.alerts
+}
+
+// Variables returns the store of shared spec variables
+func (e *Engine) Variables() *variables.Store {
+	return e.variables
+}
+
+// SetTemplateEnvAllowlist sets the environment variables response templates may read
+func (e *Engine) SetTemplateEnvAllowlist(names []string) {
+	e.templateEngine.SetEnvAllowlist(names)
+}
+
+// SetJWTOptions configures the verification of bearer tokens read by jwt conditions
+func (e *Engine) SetJWTOptions(opts condition.JWTOptions) {
+	e.condEvaluator.SetJWTOptions(opts)
+}
+
+// SetFallbackResponses sets the server-level fallback responses
+// Specs can override each of them individually
+func (e *Engine) SetFallbackResponses(fallbacks models.FallbackResponses) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fallbacks = fallbacks
+}
+
+// SetResponseTimeout sets the maximum handling time of operations without their own timeout
+func (e *Engine) SetResponseTimeout(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responseTimeout = d
+}
+
+// operationTimeout returns the maximum handling time of an operation, or 0 for none
+func (e *Engine) operationTimeout(op *models.Operation) time.Duration {
+	if op.Timeout > 0 {
+		return time.Duration(op.Timeout) * time.Millisecond
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.responseTimeout
+}
+
+// responseContext bounds the handling of a request by its operation's timeout, counted from start
+// Delays, forwarding and writing the response all stop once it is done
+func (e *Engine) responseContext(ctx context.Context, op *models.Operation, start time.Time) (context.Context, context.CancelFunc) {
+	if timeout := e.operationTimeout(op); timeout > 0 {
+		return context.WithDeadline(ctx, start.Add(timeout))
+	}
+	return context.WithCancel(ctx)
+}
+
+// sleep waits for d unless ctx is done first, because the request was cancelled or timed out
+// It reports whether the full wait completed
+func (e *Engine) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SetAccessLog sets the logger that records every request served by the engine, or nil to disable it
+func (e *Engine) SetAccessLog(logger *accesslog.Logger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.accessLog = logger
+}
+
+// ReloadRoutes reloads all routes from enabled specs, skipping disabled operations
 func (e *Engine) ReloadRoutes() error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -67,16 +182,36 @@ func (e *Engine) ReloadRoutes() error {
 		return err
 	}
 
+	// Specs stored before the base path prefixes were restricted aren't served
+	allowed := make([]*models.Spec, 0, len(specs))
+	for _, spec := range specs {
+		if err := checkBasePath(e.basePathPrefixes, spec.BasePath); err != nil {
+			slog.Warn("skipping spec outside the allowed base path prefixes", "specId", spec.ID, "error", err)
+			continue
+		}
+		allowed = append(allowed, spec)
+	}
+	specs = allowed
+
+	specOps := make(map[string][]*models.Operation, len(specs))
 	for _, spec := range specs {
 		ops, err := e.store.GetOperationsBySpec(spec.ID)
 		if err != nil {
+			slog.Warn("skipping spec while loading routes", "specId", spec.ID, "error", err)
 			continue
 		}
+		specOps[spec.ID] = ops
+		authPattern := compileAuthPattern(spec.Auth)
 
 		for _, op := range ops {
+			if op.Disabled {
+				continue
+			}
+
 			r := &route{
-				spec:      spec,
-				operation: op,
+				spec:        spec,
+				operation:   op,
+				authPattern: authPattern,
 			}
 
 			// Build regex pattern from path
@@ -86,11 +221,25 @@ func (e *Engine) ReloadRoutes() error {
 		}
 	}
 
+	e.specs = specs
+
 	// Sort routes by specificity (more specific patterns first)
 	for method := range e.routes {
 		sortRoutes(e.routes[method])
 	}
 
+	// Seed the stores of new stateful specs
+	e.state.sync(specs, specOps, e.LoadSeed)
+
+	// Parse the documents of specs checking their contract
+	e.contracts.sync(specs)
+
+	// Watch the specs with an alert policy
+	e.alerts.SetSpecs(specs)
+
+	// Probe the upstreams of specs with a health check
+	e.health.sync(specs, e.upstream)
+
 	return nil
 }
 
@@ -145,8 +294,64 @@ func (e *Engine) Handler() http.Handler {
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	// Read request body early for tracing (we need it even for unmatched requests)
+	e.mu.RLock()
+	accessLog := e.accessLog
+	e.mu.RUnlock()
+
+	// Assign a request ID unless the admin router already did
+	requestID := requestid.FromContext(r.Context())
+	if requestID == "" {
+		requestID = requestid.FromHeader(r.Header)
+		ctx := requestid.WithID(r.Context(), requestID)
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("requestId", requestID))
+		r = r.WithContext(ctx)
+	}
+	w.Header().Set(requestid.Header, requestID)
+
+	var access *accesslog.Recorder
+	if accessLog != nil {
+		access = accesslog.NewRecorder(w)
+		w = access
+		defer func() {
+			accessLog.Log(access.Entry(r, startTime))
+		}()
+	}
+
+	// Reject new requests once a drain's grace period is over
+	if e.rejectDraining(w) {
+		return
+	}
+	e.drain.inFlight.Add(1)
+	defer e.drain.inFlight.Add(-1)
+
+	if !e.limiter.acquire() {
+		logging.FromContext(r.Context()).Warn("rejected request: server concurrency limit reached")
+		writeSaturated(w)
+		return
+	}
+	defer e.limiter.release()
+
+	var matchedRoute *route
 	var requestBody string
+
+	// Count the body bytes of requests to operations and of their responses, including
+	// responses to panics since this runs last
+	counter := &byteCounter{ResponseWriter: w}
+	w = counter
+	defer func() {
+		if matchedRoute != nil {
+			e.statsCollector.RecordBytes(matchedRoute.operation.ID, int64(len(requestBody)), counter.bytes)
+		}
+	}()
+
+	// Record panics with the request context before answering 500
+	defer func() {
+		if p := recover(); p != nil {
+			e.recoverPanic(w, r, p, matchedRoute, requestBody, startTime)
+		}
+	}()
+
+	// Read request body early for tracing (we need it even for unmatched requests)
 	if r.Body != nil {
 		bodyBytes, _ := io.ReadAll(r.Body)
 		requestBody = string(bodyBytes)
@@ -157,37 +362,197 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	matchedRoute, pathParams := e.matchRoute(r.Method, r.URL.Path)
 	e.mu.RUnlock()
 
+	// Serve HEAD from the GET operation when the path has no HEAD operation
+	if matchedRoute == nil && r.Method == http.MethodHead {
+		e.mu.RLock()
+		matchedRoute, pathParams = e.matchRoute(http.MethodGet, r.URL.Path)
+		e.mu.RUnlock()
+
+		if matchedRoute != nil {
+			head := &headResponseWriter{ResponseWriter: w}
+			w = head
+			defer head.finish()
+		}
+	}
+
+	// Serve the utility endpoints unless an operation has their path
+	if matchedRoute == nil && e.serveUtility(w, r, requestBody) {
+		return
+	}
+
+	// Serve the documents of specs unless an operation has their path
+	if matchedRoute == nil && (r.Method == http.MethodGet || r.Method == http.MethodHead) && e.serveSpecDocument(w, r) {
+		return
+	}
+
+	// Answer CORS preflight requests for routes that exist for the requested method
+	if matchedRoute == nil && isPreflight(r) {
+		e.mu.RLock()
+		preflightRoute, _ := e.matchRoute(r.Header.Get("Access-Control-Request-Method"), r.URL.Path)
+		e.mu.RUnlock()
+
+		if preflightRoute != nil {
+			applyCORS(w, r, preflightRoute.spec)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	// Answer OPTIONS for paths that have operations for any method
+	if matchedRoute == nil && r.Method == http.MethodOptions {
+		e.mu.RLock()
+		spec, methods := e.pathMethods(r.URL.Path)
+		e.mu.RUnlock()
+
+		if spec != nil {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			applyCORS(w, r, spec)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
 	if matchedRoute == nil {
+		e.mu.RLock()
+		spec := e.specForPath(r.URL.Path)
+		e.mu.RUnlock()
+
+		applyCORS(w, r, spec)
+		e.recordUnknownPath(r, spec)
+
+		statusCode, responseBody := e.writeFallback(w, r, fallbackNotFound, spec, nil, requestBody)
+
 		// Record trace for unmatched request if any spec has tracing enabled
-		e.recordUnmatchedTrace(r, requestBody, startTime)
-		http.NotFound(w, r)
+		e.recordUnmatchedTrace(w, r, requestBody, startTime, statusCode, responseBody)
+		return
+	}
+
+	applyCORS(w, r, matchedRoute.spec)
+	e.checkContract(r, matchedRoute, pathParams, requestBody)
+
+	if access != nil {
+		access.SpecID = matchedRoute.spec.ID
+		access.OperationID = matchedRoute.operation.OperationID
+	}
+
+	logger := logging.FromContext(r.Context()).With(
+		"specId", matchedRoute.spec.ID,
+		"operationId", matchedRoute.operation.OperationID,
+	)
+
+	// The operation timeout bounds the whole response, not just its delay
+	ctx, cancel := e.responseContext(r.Context(), matchedRoute.operation, startTime)
+	defer cancel()
+
+	if !e.limiter.acquireSpec(matchedRoute.spec.ID, matchedRoute.spec.MaxConcurrent) {
+		logger.Warn("rejected request: spec concurrency limit reached", "maxConcurrent", matchedRoute.spec.MaxConcurrent)
+		writeSaturated(w)
+		e.recordError(matchedRoute, r, http.StatusServiceUnavailable, "concurrency limit reached")
+		return
+	}
+	defer e.limiter.releaseSpec(matchedRoute.spec.ID)
+
+	// Delay or fail the request as the spec's chaos policy draws it
+	if e.injectChaos(ctx, w, r, matchedRoute, pathParams, requestBody, startTime, logger) {
+		return
+	}
+
+	// Operations forwarding always go upstream unless the request asks for the mock or the
+	// spec falls back to mocks while its upstream is down
+	if forwardsFirst(matchedRoute, r) && !e.upstreamDown(matchedRoute.spec) {
+		e.serveUpstream(ctx, w, r, matchedRoute, pathParams, requestBody, startTime, logger)
+		return
+	}
+
+	// Simulate the spec's authentication before any response config applies
+	if result := checkAuth(r, matchedRoute.spec.Auth, matchedRoute.authPattern); result != authOK {
+		logger.Debug("rejected request: simulated authentication failed", "result", string(result))
+		statusCode, responseBody := e.writeAuthError(w, r, result, matchedRoute.spec, pathParams, requestBody)
+		e.recordFallback(matchedRoute, r, requestBody, startTime, w, string(result), statusCode, responseBody)
 		return
 	}
 
+	// Count the request against the operation's simulated rate limit
+	var rateLimit map[string]string
+	if policy := matchedRoute.operation.RateLimit; policy != nil {
+		now := time.Now()
+		state := e.rateLimits.take(matchedRoute.operation.ID, rateLimitClient(r, policy), policy, now)
+		rateLimit = state.templateVars(now)
+		if policy.Headers {
+			state.setHeaders(w)
+		}
+		if !state.allowed {
+			logger.Debug("rejected request: simulated rate limit reached", "limit", policy.Limit, "window", policy.Window)
+			response := policy.Response
+			if response == nil {
+				response = &defaultThrottledResponse
+			}
+			templateCtx := e.newTemplateContext(r, matchedRoute.spec, pathParams, requestBody)
+			templateCtx.RateLimit = rateLimit
+			statusCode, responseBody := e.writeTemplated(w, response, http.StatusTooManyRequests, templateCtx)
+			e.recordFallback(matchedRoute, r, requestBody, startTime, w, "rate-limited", statusCode, responseBody)
+			return
+		}
+	}
+
+	// Echo operations describe the request instead of serving response configs
+	if matchedRoute.operation.Echo {
+		statusCode, responseBody := writeEcho(w, r, requestBody)
+		e.recordFallback(matchedRoute, r, requestBody, startTime, w, "echo", statusCode, responseBody)
+		return
+	}
+
+	// Serve CRUD operations of stateful specs from their store
+	if matchedRoute.spec.Stateful != nil && matchedRoute.spec.Stateful.Enabled {
+		if statusCode, responseBody, ok := e.serveStateful(w, r, matchedRoute, pathParams, requestBody); ok {
+			e.recordFallback(matchedRoute, r, requestBody, startTime, w, "stateful", statusCode, responseBody)
+			return
+		}
+	}
+
 	// Build request data for condition evaluation
 	reqData := &condition.RequestData{
 		PathParams:  pathParams,
 		QueryParams: r.URL.Query(),
 		Headers:     r.Header,
 		Body:        requestBody,
+		Variables:   e.variables.Scope(matchedRoute.spec.ID),
 	}
 
 	// Get response configs for the operation
 	responseConfigs, err := e.store.GetResponseConfigsByOperation(matchedRoute.operation.ID)
-	
+	if err != nil {
+		logger.Error("failed to load response configs", "error", err)
+		statusCode, responseBody := e.writeFallback(w, r, fallbackError, matchedRoute.spec, pathParams, requestBody)
+		e.recordFallback(matchedRoute, r, requestBody, startTime, w, "error", statusCode, responseBody)
+		return
+	}
+
 	// Find matching response config by priority (only if configs exist)
 	var matchedConfig *models.ResponseConfig
-	if err == nil && len(responseConfigs) > 0 {
+	if len(responseConfigs) > 0 {
 		for _, cfg := range responseConfigs {
 			if !cfg.Enabled {
 				continue
 			}
-			if e.condEvaluator.EvaluateAll(cfg.Conditions, reqData) {
+			if e.condEvaluator.Matches(cfg.Conditions, cfg.NegateConditions, reqData) {
 				matchedConfig = cfg
 				break
 			}
 		}
 	}
+	if matchedConfig != nil {
+		logger.Debug("matched response config", "responseId", matchedConfig.ID, "responseName", matchedConfig.Name)
+		e.statsCollector.RecordResponseHit(matchedConfig.ID)
+	} else {
+		logger.Debug("no response config matched", "candidates", len(responseConfigs))
+	}
+
+	// Forward unmatched requests of operations that fall back to their upstream
+	if matchedConfig == nil && forwardsUnmatched(matchedRoute) && !e.upstreamDown(matchedRoute.spec) {
+		e.serveUpstream(ctx, w, r, matchedRoute, pathParams, requestBody, startTime, logger)
+		return
+	}
 
 	// If no matching config found, try to use example response from OpenAPI spec
 	// Only if UseExampleFallback is enabled for the spec
@@ -213,17 +578,13 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Calculate duration and record stats
 		duration := time.Since(startTime)
 		isError := example.StatusCode >= 400
-		e.statsCollector.RecordRequest(
-			matchedRoute.spec.ID,
-			matchedRoute.operation.ID,
-			matchedRoute.operation.Method,
-			matchedRoute.operation.Path,
-			duration,
-			isError,
-		)
+		e.recordRequest(matchedRoute, duration, isError)
+		if isError {
+			e.recordError(matchedRoute, r, example.StatusCode, "example response")
+		}
 		
 		// Record trace if enabled
-		if matchedRoute.spec.Tracing {
+		if matchedRoute.tracing() {
 			trace := &models.Trace{
 				SpecID:        matchedRoute.spec.ID,
 				SpecName:      matchedRoute.spec.Name,
@@ -231,6 +592,7 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				OperationPath: matchedRoute.operation.Path,
 				Timestamp:     startTime,
 				Duration:      duration.Nanoseconds(),
+				RequestID:     requestid.FromContext(r.Context()),
 				MatchedConfig: "spec-example",
 				Request: models.TraceRequest{
 					Method:  r.Method,
@@ -251,24 +613,65 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If still no match and no example, return error
+	// If still no match and no example, return the no-match fallback
 	if matchedConfig == nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(`{"error": "No matching response configuration and no example in spec"}`))
+		statusCode, responseBody := e.writeFallback(w, r, fallbackNoMatch, matchedRoute.spec, pathParams, requestBody)
+		e.recordFallback(matchedRoute, r, requestBody, startTime, w, "no-match", statusCode, responseBody)
 		return
 	}
 
-	// Apply delay if configured
+	// Apply delay if configured, giving up when the operation timeout passes first
 	if matchedConfig.Delay > 0 {
-		time.Sleep(time.Duration(matchedConfig.Delay) * time.Millisecond)
+		timeout := e.operationTimeout(matchedRoute.operation)
+		if !e.sleep(ctx, time.Duration(matchedConfig.Delay)*time.Millisecond) {
+			if r.Context().Err() != nil {
+				logger.Debug("client went away during response delay")
+				return
+			}
+			logger.Warn("response timed out", "timeout", timeout, "delay", matchedConfig.Delay)
+			statusCode, responseBody := e.writeFallback(w, r, fallbackTimeout, matchedRoute.spec, pathParams, requestBody)
+			e.recordFallback(matchedRoute, r, requestBody, startTime, w, "timeout", statusCode, responseBody)
+			return
+		}
 	}
 
 	// Build template context
-	templateCtx := &template.Context{
-		PathParams:  pathParams,
-		QueryParams: r.URL.Query(),
-		Headers:     r.Header,
-		Body:        requestBody,
+	templateCtx := e.newTemplateContext(r, matchedRoute.spec, pathParams, requestBody)
+	templateCtx.RateLimit = rateLimit
+	templateCtx.Sequence = e.sequence(matchedRoute.operation.ID)
+
+	// A seed makes the random values of the response repeat for the same input
+	if matchedConfig.Seed != "" {
+		templateCtx.Random = template.NewSeededRand(e.templateEngine.Process(matchedConfig.Seed, templateCtx))
+	}
+
+	// Select the requested page; without a body the response is the page's items
+	body := matchedConfig.Body
+	if matchedConfig.Pagination != nil {
+		e.paginate(w, r, matchedConfig.Pagination, templateCtx, logger)
+		if body == "" {
+			body = "{{page.items}}"
+		}
+	}
+
+	// SOAP faults render their envelope instead of the body
+	var responseBody string
+	if matchedConfig.SOAPFault != nil {
+		responseBody = e.soapFault(w, matchedConfig.SOAPFault, templateCtx)
+	}
+
+	// Body files are streamed from storage instead of the body
+	var bodyFile io.ReadSeekCloser
+	var bodyFileInfo *models.SpecFile
+	if matchedConfig.BodyFile != "" && matchedConfig.SOAPFault == nil {
+		bodyFile, bodyFileInfo, err = e.openBodyFile(w, matchedRoute.spec, matchedConfig.BodyFile)
+		if err != nil {
+			logger.Error("failed to open body file", "file", matchedConfig.BodyFile, "error", err)
+			statusCode, responseBody := e.writeFallback(w, r, fallbackError, matchedRoute.spec, pathParams, requestBody)
+			e.recordFallback(matchedRoute, r, requestBody, startTime, w, "error", statusCode, responseBody)
+			return
+		}
+		defer bodyFile.Close()
 	}
 
 	// Process headers
@@ -283,28 +686,47 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process body
-	responseBody := e.templateEngine.Process(matchedConfig.Body, templateCtx)
+	if matchedConfig.SOAPFault == nil && bodyFile == nil {
+		responseBody = e.templateEngine.Process(body, templateCtx)
+	}
 
 	// Write response
-	w.WriteHeader(matchedConfig.StatusCode)
-	w.Write([]byte(responseBody))
+	switch fault := matchedConfig.Fault; {
+	case fault != nil:
+		var n int64
+		if bodyFile != nil {
+			n, err = writeFaulty(ctx, w, matchedConfig.StatusCode, bodyFile, bodyFileInfo.Size, fault)
+			responseBody = fmt.Sprintf("[file %s, %d of %d bytes]", bodyFileInfo.Name, n, bodyFileInfo.Size)
+		} else {
+			n, err = writeFaulty(ctx, w, matchedConfig.StatusCode, strings.NewReader(responseBody), int64(len(responseBody)), fault)
+			responseBody = responseBody[:n]
+		}
+		logger.Debug("faulty response", "fault", fault.Type, "written", n, "error", err)
+	case bodyFile != nil:
+		responseBody, err = writeBodyFile(ctx, w, matchedConfig.StatusCode, bodyFile, bodyFileInfo)
+		if err != nil {
+			logger.Debug("failed to stream body file", "file", matchedConfig.BodyFile, "error", err)
+		}
+	default:
+		w.WriteHeader(matchedConfig.StatusCode)
+		w.Write([]byte(responseBody))
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Warn("response timed out while being written", "timeout", e.operationTimeout(matchedRoute.operation))
+	}
 
 	// Calculate duration
 	duration := time.Since(startTime)
 
 	// Record statistics
 	isError := matchedConfig.StatusCode >= 400
-	e.statsCollector.RecordRequest(
-		matchedRoute.spec.ID,
-		matchedRoute.operation.ID,
-		matchedRoute.operation.Method,
-		matchedRoute.operation.Path,
-		duration,
-		isError,
-	)
+	e.recordRequest(matchedRoute, duration, isError)
+	if isError {
+		e.recordError(matchedRoute, r, matchedConfig.StatusCode, fmt.Sprintf("response config %q", matchedConfig.Name))
+	}
 
 	// Record trace if tracing is enabled
-	if matchedRoute.spec.Tracing {
+	if matchedRoute.tracing() {
 		trace := &models.Trace{
 			SpecID:          matchedRoute.spec.ID,
 			SpecName:        matchedRoute.spec.Name,
@@ -312,6 +734,7 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			OperationPath:   matchedRoute.operation.Path,
 			Timestamp:       startTime,
 			Duration:        duration.Nanoseconds(),
+			RequestID:       requestid.FromContext(r.Context()),
 			MatchedConfigID: matchedConfig.ID,
 			MatchedConfig:   matchedConfig.Name,
 			Request: models.TraceRequest{
@@ -330,6 +753,37 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		e.tracingService.RecordTrace(trace)
 	}
+
+	// Publish the response config's events once the response is complete
+	if len(matchedConfig.Events) > 0 {
+		e.emitEvents(matchedRoute.spec, matchedConfig.Events, pathParams, templateCtx, logger)
+	}
+}
+
+// newTemplateContext builds the template context for a request on a spec, which may be nil
+func (e *Engine) newTemplateContext(r *http.Request, spec *models.Spec, pathParams map[string]string, requestBody string) *template.Context {
+	var snippets map[string]string
+	var vars *variables.Scope
+	var locale string
+	if spec != nil {
+		snippets = spec.Snippets
+		vars = e.variables.Scope(spec.ID)
+		locale = spec.Locale
+	}
+
+	return &template.Context{
+		PathParams:  pathParams,
+		QueryParams: r.URL.Query(),
+		Headers:     r.Header,
+		Body:        requestBody,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		URL:         r.URL.String(),
+		RequestID:   requestid.FromContext(r.Context()),
+		Snippets:    snippets,
+		Variables:   vars,
+		Locale:      locale,
+	}
 }
 
 // matchRoute finds a matching route for the given method and path
@@ -363,6 +817,125 @@ func (e *Engine) matchRoute(method, requestPath string) (*route, map[string]stri
 	return nil, nil
 }
 
+// pathMethods returns the methods that have routes for the request path, including OPTIONS
+// and HEAD when GET is present, and the spec of the first matching route.
+// Specs with automatic OPTIONS disabled are skipped. Must be called with e.mu held
+func (e *Engine) pathMethods(requestPath string) (*models.Spec, []string) {
+	methods := make([]string, 0, len(e.routes))
+	for method := range e.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var spec *models.Spec
+	allowed := make(map[string]bool)
+	for _, method := range methods {
+		for _, rt := range e.routes[method] {
+			if rt.pattern == nil || rt.spec.DisableAutoOptions || !rt.pattern.MatchString(requestPath) {
+				continue
+			}
+			if spec == nil {
+				spec = rt.spec
+			}
+			allowed[method] = true
+			break
+		}
+	}
+
+	if spec == nil {
+		return nil, nil
+	}
+
+	// HEAD is served from GET, OPTIONS automatically
+	if allowed[http.MethodGet] {
+		allowed[http.MethodHead] = true
+	}
+	allowed[http.MethodOptions] = true
+
+	result := make([]string, 0, len(allowed))
+	for method := range allowed {
+		result = append(result, method)
+	}
+	sort.Strings(result)
+
+	return spec, result
+}
+
+// headResponseWriter discards the body of a response, to answer HEAD requests from GET operations
+// The header is held back until the response is complete, so it can carry the body's length
+type headResponseWriter struct {
+	http.ResponseWriter
+	status   int   // Status of the held back header, 0 before one is written
+	bytes    int64 // Body bytes discarded
+	finished bool
+}
+
+// WriteHeader holds the status back until finish
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if w.finished {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+// Write discards the body while reporting it as written
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 && !w.finished {
+		w.status = http.StatusOK
+	}
+	w.bytes += int64(len(b))
+	return len(b), nil
+}
+
+// Flush does nothing, since the header waits for the Content-Length
+func (w *headResponseWriter) Flush() {}
+
+// finish sends the held back header with the length of the discarded body, unless the response
+// set its own Content-Length. Later writes, such as the answer to a panic, pass through
+func (w *headResponseWriter) finish() {
+	w.finished = true
+	if w.status == 0 {
+		return
+	}
+	if w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.FormatInt(w.bytes, 10))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// byteCounter counts the bytes of a response body that reach the client
+type byteCounter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+// Write counts the bytes written
+func (w *byteCounter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom counts the bytes copied, keeping the wrapped writer's sendfile path for body files
+func (w *byteCounter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *byteCounter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // headersToMap converts http.Header to map[string][]string
 func headersToMap(h http.Header) map[string][]string {
 	result := make(map[string][]string)
@@ -399,9 +972,307 @@ func (e *Engine) GetRegisteredRoutes() map[string][]string {
 	return result
 }
 
+// RouteConflict describes an operation whose route collides with an already registered route
+type RouteConflict struct {
+	Method              string `json:"method"`
+	Path                string `json:"path"`
+	ExistingSpecID      string `json:"existingSpecId"`
+	ExistingSpecName    string `json:"existingSpecName"`
+	ExistingOperationID string `json:"existingOperationId"`
+	ExistingPath        string `json:"existingPath"`
+}
+
+// FindConflicts returns the registered routes that would collide with the given operations
+// Two routes collide when they share a method and their full paths only differ in parameter names
+func (e *Engine) FindConflicts(ops []*models.Operation) []RouteConflict {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	conflicts := make([]RouteConflict, 0)
+	for _, op := range ops {
+		shape := routeShape(op.FullPath)
+		for _, r := range e.routes[op.Method] {
+			if r.spec.ID == op.SpecID {
+				continue
+			}
+			existingPath := path.Join(r.spec.BasePath, r.operation.Path)
+			if routeShape(existingPath) != shape {
+				continue
+			}
+			conflicts = append(conflicts, RouteConflict{
+				Method:              op.Method,
+				Path:                op.FullPath,
+				ExistingSpecID:      r.spec.ID,
+				ExistingSpecName:    r.spec.Name,
+				ExistingOperationID: r.operation.ID,
+				ExistingPath:        existingPath,
+			})
+		}
+	}
+
+	return conflicts
+}
+
+// routeParamPattern matches OpenAPI path parameters like {id}
+var routeParamPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+// routeShape normalizes a path pattern by blanking out parameter names
+func routeShape(pathPattern string) string {
+	return routeParamPattern.ReplaceAllString(pathPattern, "{}")
+}
+
+// fallbackKind identifies which fallback response applies
+type fallbackKind int
+
+const (
+	fallbackNotFound fallbackKind = iota
+	fallbackNoMatch
+	fallbackError
+	fallbackTimeout
+)
+
+// StatusTimeout is the default status of responses aborted by an operation timeout,
+// after the status proxies such as Cloudflare use for origin timeouts
+const StatusTimeout = 524
+
+// defaultFallbacks are the built-in responses used when neither the spec nor the server configures one
+var defaultFallbacks = map[fallbackKind]models.FallbackResponse{
+	fallbackNotFound: {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Body:       "404 page not found\n",
+	},
+	fallbackNoMatch: {
+		StatusCode: http.StatusNotFound,
+		Body:       `{"error": "No matching response configuration and no example in spec"}`,
+	},
+	fallbackError: {
+		StatusCode: http.StatusInternalServerError,
+		Body:       `{"error": "Internal server error"}`,
+	},
+	fallbackTimeout: {
+		StatusCode: StatusTimeout,
+		Body:       `{"error": "Response timed out"}`,
+	},
+}
+
+// pickFallback returns the fallback response of the given kind, if configured
+func pickFallback(fallbacks *models.FallbackResponses, kind fallbackKind) *models.FallbackResponse {
+	if fallbacks == nil {
+		return nil
+	}
+	switch kind {
+	case fallbackNotFound:
+		return fallbacks.NotFound
+	case fallbackNoMatch:
+		return fallbacks.NoMatch
+	case fallbackError:
+		return fallbacks.Error
+	case fallbackTimeout:
+		return fallbacks.Timeout
+	}
+	return nil
+}
+
+// writeFallback writes the fallback response of the given kind, preferring the spec's over the server's
+// It returns the status code and rendered body for tracing
+func (e *Engine) writeFallback(w http.ResponseWriter, r *http.Request, kind fallbackKind, spec *models.Spec, pathParams map[string]string, requestBody string) (int, string) {
+	var fallback *models.FallbackResponse
+	if spec != nil {
+		fallback = pickFallback(spec.Fallbacks, kind)
+	}
+	if fallback == nil {
+		e.mu.RLock()
+		fallback = pickFallback(&e.fallbacks, kind)
+		e.mu.RUnlock()
+	}
+	if fallback == nil {
+		builtin := defaultFallbacks[kind]
+		fallback = &builtin
+	}
+
+	templateCtx := e.newTemplateContext(r, spec, pathParams, requestBody)
+	return e.writeTemplated(w, fallback, defaultFallbacks[kind].StatusCode, templateCtx)
+}
+
+// writeTemplated renders and writes a response whose headers and body can contain template variables
+// A zero status code is replaced by defaultStatus. It returns the status code and rendered body for tracing
+func (e *Engine) writeTemplated(w http.ResponseWriter, response *models.FallbackResponse, defaultStatus int, templateCtx *template.Context) (int, string) {
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = defaultStatus
+	}
+
+	for key, value := range e.templateEngine.ProcessHeaders(response.Headers, templateCtx) {
+		w.Header().Set(key, value)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	responseBody := e.templateEngine.Process(response.Body, templateCtx)
+
+	w.WriteHeader(statusCode)
+	w.Write([]byte(responseBody))
+
+	return statusCode, responseBody
+}
+
+// specForPath returns the enabled spec whose base path contains the request path
+// The longest base path wins; specs mounted at the root are not considered. Must be called with e.mu held
+func (e *Engine) specForPath(requestPath string) *models.Spec {
+	var best *models.Spec
+	for _, routes := range e.routes {
+		for _, rt := range routes {
+			basePath := strings.TrimSuffix(rt.spec.BasePath, "/")
+			if basePath == "" || (requestPath != basePath && !strings.HasPrefix(requestPath, basePath+"/")) {
+				continue
+			}
+			if best == nil || len(basePath) > len(strings.TrimSuffix(best.BasePath, "/")) {
+				best = rt.spec
+			}
+		}
+	}
+	return best
+}
+
+// recordRequest counts a request of an operation in the statistics and its spec's alert window
+func (e *Engine) recordRequest(matchedRoute *route, duration time.Duration, isError bool) {
+	e.statsCollector.RecordRequest(
+		matchedRoute.spec.ID,
+		matchedRoute.operation.ID,
+		matchedRoute.operation.Method,
+		matchedRoute.operation.Path,
+		duration,
+		isError,
+	)
+	e.alerts.Record(matchedRoute.spec.ID, duration, isError)
+}
+
+// recordError adds an error response to the recent errors of the stats
+func (e *Engine) recordError(matchedRoute *route, r *http.Request, statusCode int, message string) {
+	e.statsCollector.RecordError(
+		matchedRoute.spec.ID,
+		matchedRoute.operation.ID,
+		matchedRoute.operation.Path,
+		matchedRoute.operation.Method,
+		statusCode,
+		message,
+		requestid.FromContext(r.Context()),
+	)
+}
+
+// recoverPanic answers a request whose handling panicked with the error fallback and records
+// the panic, with its stack, in the log, the recent errors of the stats and a trace
+func (e *Engine) recoverPanic(w http.ResponseWriter, r *http.Request, p interface{}, matchedRoute *route, requestBody string, startTime time.Time) {
+	// Let net/http handle deliberate aborts
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+
+	stack := string(debug.Stack())
+	message := fmt.Sprintf("panic: %v", p)
+	requestID := requestid.FromContext(r.Context())
+
+	var spec *models.Spec
+	specID, operationID, operationPath, method := "", "", "", r.Method
+	if matchedRoute != nil {
+		spec = matchedRoute.spec
+		specID, operationID = matchedRoute.spec.ID, matchedRoute.operation.ID
+		operationPath, method = matchedRoute.operation.Path, matchedRoute.operation.Method
+	}
+
+	logging.FromContext(r.Context()).Error("panic while serving request",
+		"panic", fmt.Sprint(p), "specId", specID, "operationId", operationID, "stack", stack)
+
+	statusCode, responseBody := e.writeFallback(w, r, fallbackError, spec, nil, requestBody)
+	duration := time.Since(startTime)
+
+	errorPath := r.URL.Path
+	if matchedRoute != nil {
+		errorPath = operationPath
+		e.recordRequest(matchedRoute, duration, true)
+	}
+	e.statsCollector.RecordError(specID, operationID, errorPath, method, statusCode, message, requestID)
+
+	// Panics are traced even when tracing is off for the spec
+	specName := "[Unmatched]"
+	if spec != nil {
+		specName = spec.Name
+	}
+	e.tracingService.RecordTrace(&models.Trace{
+		SpecID:        specID,
+		SpecName:      specName,
+		OperationID:   operationID,
+		OperationPath: operationPath,
+		Timestamp:     startTime,
+		Duration:      duration.Nanoseconds(),
+		RequestID:     requestID,
+		MatchedConfig: "panic",
+		Error:         message + "\n\n" + stack,
+		Request: models.TraceRequest{
+			Method:  r.Method,
+			URL:     r.URL.String(),
+			Path:    r.URL.Path,
+			Query:   r.URL.Query(),
+			Headers: r.Header,
+			Body:    requestBody,
+		},
+		Response: models.TraceResponse{
+			StatusCode: statusCode,
+			Headers:    headersToMap(w.Header()),
+			Body:       responseBody,
+		},
+	})
+}
+
+// recordFallback records stats and, if enabled, a trace for a fallback response on a matched route
+func (e *Engine) recordFallback(matchedRoute *route, r *http.Request, requestBody string, startTime time.Time, w http.ResponseWriter, matched string, statusCode int, responseBody string) {
+	e.recordResponse(matchedRoute, r, requestBody, startTime, w, matched, statusCode, responseBody, nil)
+}
+
+// recordResponse is recordFallback for responses that may come from an upstream
+func (e *Engine) recordResponse(matchedRoute *route, r *http.Request, requestBody string, startTime time.Time, w http.ResponseWriter, matched string, statusCode int, responseBody string, upstream *models.TraceUpstream) {
+	duration := time.Since(startTime)
+	e.recordRequest(matchedRoute, duration, statusCode >= 400)
+	if statusCode >= 400 {
+		e.recordError(matchedRoute, r, statusCode, matched+" fallback")
+	}
+
+	if !matchedRoute.tracing() {
+		return
+	}
+
+	trace := &models.Trace{
+		SpecID:        matchedRoute.spec.ID,
+		SpecName:      matchedRoute.spec.Name,
+		OperationID:   matchedRoute.operation.ID,
+		OperationPath: matchedRoute.operation.Path,
+		Timestamp:     startTime,
+		Duration:      duration.Nanoseconds(),
+		RequestID:     requestid.FromContext(r.Context()),
+		MatchedConfig: matched,
+		Upstream:      upstream,
+		Request: models.TraceRequest{
+			Method:  r.Method,
+			URL:     r.URL.String(),
+			Path:    r.URL.Path,
+			Query:   r.URL.Query(),
+			Headers: r.Header,
+			Body:    requestBody,
+		},
+		Response: models.TraceResponse{
+			StatusCode: statusCode,
+			Headers:    headersToMap(w.Header()),
+			Body:       responseBody,
+		},
+	}
+	e.tracingService.RecordTrace(trace)
+}
+
 // recordUnmatchedTrace records a trace for requests that don't match any operation
 // This helps debug requests that are failing to match
-func (e *Engine) recordUnmatchedTrace(r *http.Request, requestBody string, startTime time.Time) {
+func (e *Engine) recordUnmatchedTrace(w http.ResponseWriter, r *http.Request, requestBody string, startTime time.Time, statusCode int, responseBody string) {
 	// Check if any spec has tracing enabled
 	specs, err := e.store.GetEnabledSpecs()
 	if err != nil {
@@ -429,6 +1300,7 @@ func (e *Engine) recordUnmatchedTrace(r *http.Request, requestBody string, start
 		OperationPath: "",
 		Timestamp:     startTime,
 		Duration:      duration.Nanoseconds(),
+		RequestID:     requestid.FromContext(r.Context()),
 		MatchedConfig: "no-match",
 		Request: models.TraceRequest{
 			Method:  r.Method,
@@ -439,9 +1311,9 @@ func (e *Engine) recordUnmatchedTrace(r *http.Request, requestBody string, start
 			Body:    requestBody,
 		},
 		Response: models.TraceResponse{
-			StatusCode: http.StatusNotFound,
-			Headers:    map[string][]string{"Content-Type": {"text/plain; charset=utf-8"}},
-			Body:       "404 page not found\n",
+			StatusCode: statusCode,
+			Headers:    headersToMap(w.Header()),
+			Body:       responseBody,
 		},
 	}
 	e.tracingService.RecordTrace(trace)
@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prasenjit/go-virtual/internal/accesslog"
 	"github.com/prasenjit/go-virtual/internal/models"
 	"github.com/prasenjit/go-virtual/internal/stats"
 	"github.com/prasenjit/go-virtual/internal/storage"
@@ -23,6 +26,63 @@ func setupTestEngine(t *testing.T) (*Engine, storage.Storage) {
 	return engine, store
 }
 
+// setupSpecEngine serves spec with ops and configs, filling in the fields a
+// test leaves empty. Nil ops serve a single GET /users operation, and configs
+// belong to the first operation unless they name another one.
+func setupSpecEngine(t *testing.T, spec *models.Spec, ops []*models.Operation, configs ...*models.ResponseConfig) *Engine {
+	engine, store := setupTestEngine(t)
+
+	if spec == nil {
+		spec = &models.Spec{}
+	}
+	if spec.ID == "" {
+		spec.ID = "spec-1"
+	}
+	if spec.Name == "" {
+		spec.Name = "API"
+	}
+	if spec.BasePath == "" {
+		spec.BasePath = "/api"
+	}
+	spec.Enabled = true
+	store.CreateSpec(spec)
+
+	if ops == nil {
+		ops = []*models.Operation{{}}
+	}
+	for i, op := range ops {
+		if op.ID == "" {
+			op.ID = fmt.Sprintf("op-%d", i+1)
+		}
+		if op.Method == "" {
+			op.Method = "GET"
+		}
+		if op.Path == "" {
+			op.Path = "/users"
+		}
+		op.SpecID = spec.ID
+		op.FullPath = spec.BasePath + op.Path
+		store.CreateOperation(op)
+	}
+
+	for i, config := range configs {
+		if config.ID == "" {
+			config.ID = fmt.Sprintf("resp-%d", i+1)
+		}
+		if config.OperationID == "" {
+			config.OperationID = ops[0].ID
+		}
+		if config.StatusCode == 0 {
+			config.StatusCode = 200
+		}
+		config.Enabled = true
+		store.CreateResponseConfig(config)
+	}
+	engine.ReloadRoutes()
+
+	return engine
+}
+
 func TestNewEngine(t *testing.T) {
 	engine, _ := setupTestEngine(t)
 	
@@ -927,3 +987,518 @@ func TestGetRegisteredRoutes(t *testing.T) {
 		t.Errorf("Expected 1 POST route, got %d", len(routes["POST"]))
 	}
 }
+
+func TestFindConflicts(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	spec := &models.Spec{ID: "spec-1", Name: "Test API", BasePath: "/api", Enabled: true}
+	store.CreateSpec(spec)
+	store.CreateOperation(&models.Operation{
+		ID:       "op-1",
+		SpecID:   "spec-1",
+		Method:   "GET",
+		Path:     "/users/{id}",
+		FullPath: "/api/users/{id}",
+	})
+	engine.ReloadRoutes()
+
+	candidates := []*models.Operation{
+		{SpecID: "spec-2", Method: "GET", Path: "/users/{userId}", FullPath: "/api/users/{userId}"},
+		{SpecID: "spec-2", Method: "POST", Path: "/users/{userId}", FullPath: "/api/users/{userId}"},
+		{SpecID: "spec-2", Method: "GET", Path: "/users", FullPath: "/api/users"},
+	}
+
+	conflicts := engine.FindConflicts(candidates)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(conflicts))
+	}
+	if conflicts[0].ExistingOperationID != "op-1" {
+		t.Errorf("Expected conflict with op-1, got %q", conflicts[0].ExistingOperationID)
+	}
+	if conflicts[0].ExistingPath != "/api/users/{id}" {
+		t.Errorf("Expected existing path '/api/users/{id}', got %q", conflicts[0].ExistingPath)
+	}
+
+	// Operations of the same spec never conflict with themselves
+	own := []*models.Operation{{SpecID: "spec-1", Method: "GET", FullPath: "/api/users/{id}"}}
+	if len(engine.FindConflicts(own)) != 0 {
+		t.Error("Expected no conflicts for operations of the same spec")
+	}
+}
+
+func TestServeHTTP_OperationTracing(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	collector := stats.NewCollector()
+	tracingSvc := tracing.NewService(100)
+	engine := NewEngine(store, collector, tracingSvc)
+
+	// Spec tracing is off, only one operation is traced
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Test API", Enabled: true, UseExampleFallback: true})
+	example := &models.ExampleResponse{StatusCode: 200, Body: `{}`}
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", ExampleResponse: example, Tracing: true})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/orders", ExampleResponse: example})
+	engine.ReloadRoutes()
+
+	for _, path := range []string{"/users", "/orders"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+	}
+
+	traces := tracingSvc.GetTraces(nil)
+	if len(traces) != 1 {
+		t.Fatalf("Expected 1 trace, got %d", len(traces))
+	}
+	if traces[0].OperationID != "op-1" {
+		t.Errorf("Expected trace for op-1, got %q", traces[0].OperationID)
+	}
+}
+
+func TestServeHTTP_FallbackResponses(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users", BasePath: "/api", Enabled: true})
+	store.CreateSpec(&models.Spec{
+		ID:       "spec-2",
+		Name:     "Orders",
+		BasePath: "/orders",
+		Enabled:  true,
+		Fallbacks: &models.FallbackResponses{
+			NotFound: &models.FallbackResponse{StatusCode: 404, Body: `{"error": "unknown order route"}`},
+			NoMatch:  &models.FallbackResponse{StatusCode: 501, Body: `{"error": "not mocked"}`},
+		},
+	})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-2", Method: "GET", Path: "/list", FullPath: "/orders/list"})
+	engine.ReloadRoutes()
+
+	engine.SetFallbackResponses(models.FallbackResponses{
+		NotFound: &models.FallbackResponse{
+			StatusCode: 404,
+			Headers:    map[string]string{"X-Mock": "missing"},
+			Body:       `{"error": "no route", "tier": "{{query.tier}}"}`,
+		},
+		NoMatch: &models.FallbackResponse{StatusCode: 418, Body: `{"error": "server no match"}`},
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"server not found", "/unknown?tier=gold", 404, `{"error": "no route", "tier": "gold"}`},
+		{"spec not found", "/orders/1/items", 404, `{"error": "unknown order route"}`},
+		{"server no match", "/api/users", 418, `{"error": "server no match"}`},
+		{"spec no match", "/orders/list", 501, `{"error": "not mocked"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	// Headers are applied to the server fallback
+	req := httptest.NewRequest("GET", "/unknown", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Header().Get("X-Mock") != "missing" || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected fallback headers: %v", w.Header())
+	}
+}
+
+func TestServeHTTP_AutoOptions(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Users", BasePath: "/api", Enabled: true})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Orders", BasePath: "/orders", Enabled: true, DisableAutoOptions: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "DELETE", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateOperation(&models.Operation{ID: "op-3", SpecID: "spec-2", Method: "GET", Path: "/list", FullPath: "/orders/list"})
+	engine.ReloadRoutes()
+
+	req := httptest.NewRequest("OPTIONS", "/api/users/42", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if w.Header().Get("Allow") != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("Unexpected Allow header: %q", w.Header().Get("Allow"))
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("Expected CORS headers on the OPTIONS response")
+	}
+
+	// Unknown path
+	req = httptest.NewRequest("OPTIONS", "/api/unknown", nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown path, got %d", w.Code)
+	}
+
+	// Spec with automatic OPTIONS disabled
+	req = httptest.NewRequest("OPTIONS", "/orders/list", nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when auto OPTIONS is disabled, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_HeadFromGet(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/orders", FullPath: "/api/orders"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Headers:     map[string]string{"X-User-Id": "{{path.id}}"},
+		Body:        `{"id": "{{path.id}}"}`,
+		Enabled:     true,
+	})
+	engine.ReloadRoutes()
+
+	req := httptest.NewRequest("HEAD", "/api/users/7", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-User-Id") != "7" || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected GET headers, got %v", w.Header())
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+	if length := w.Header().Get("Content-Length"); length != "11" {
+		t.Errorf("Expected the Content-Length of the GET body, got %q", length)
+	}
+
+	// Only GET operations are used for HEAD
+	req = httptest.NewRequest("HEAD", "/api/orders", nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_AccessLog(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", OperationID: "getUser", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        `{"id": "{{path.id}}"}`,
+		Enabled:     true,
+	})
+	engine.ReloadRoutes()
+
+	var buf strings.Builder
+	logger, err := accesslog.New(&buf, accesslog.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.SetAccessLog(logger)
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/7?x=1", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 access log lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], `"uri":"/api/users/7?x=1"`) || !strings.Contains(lines[0], `"status":200`) ||
+		!strings.Contains(lines[0], `"specId":"spec-1"`) || !strings.Contains(lines[0], `"operationId":"getUser"`) {
+		t.Errorf("Unexpected entry for matched request: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"status":404`) || strings.Contains(lines[1], "specId") {
+		t.Errorf("Unexpected entry for unmatched request: %s", lines[1])
+	}
+
+	// Disabling stops logging
+	engine.SetAccessLog(nil)
+	buf.Reset()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/7", nil))
+	if buf.Len() != 0 {
+		t.Errorf("Expected no access log output, got %q", buf.String())
+	}
+}
+
+func TestServeHTTP_RequestID(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Tracing: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		Name:        "Missing",
+		StatusCode:  404,
+		Body:        `{"requestId": "{{request.id}}"}`,
+		Enabled:     true,
+	})
+	engine.ReloadRoutes()
+
+	// An incoming ID is honored everywhere
+	req := httptest.NewRequest("GET", "/api/users/7", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Errorf("Expected response header abc-123, got %q", got)
+	}
+	if w.Body.String() != `{"requestId": "abc-123"}` {
+		t.Errorf("Expected the ID in the body, got %s", w.Body.String())
+	}
+
+	traces := engine.tracingService.GetTraces(&models.TraceFilter{})
+	if len(traces) != 1 || traces[0].RequestID != "abc-123" {
+		t.Errorf("Expected a trace with the request ID, got %+v", traces)
+	}
+
+	errors := engine.statsCollector.GetGlobalStats(1, 1).RecentErrors
+	if len(errors) != 1 || errors[0].RequestID != "abc-123" {
+		t.Errorf("Expected a recent error with the request ID, got %+v", errors)
+	}
+
+	// Without one, an ID is generated
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users/7", nil))
+
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "abc-123" {
+		t.Errorf("Expected a generated request ID, got %q", got)
+	}
+}
+
+// panickingStorage panics when response configs are loaded
+type panickingStorage struct {
+	storage.Storage
+}
+
+func (s *panickingStorage) GetResponseConfigsByOperation(operationID string) ([]*models.ResponseConfig, error) {
+	panic("boom")
+}
+
+func TestServeHTTP_PanicRecovery(t *testing.T) {
+	store := &panickingStorage{Storage: storage.NewMemoryStorage()}
+	collector := stats.NewCollector()
+	tracingSvc := tracing.NewService(100)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users"})
+	engine := NewEngine(store, collector, tracingSvc)
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("X-Request-ID", "req-panic")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+
+	errors := collector.GetGlobalStats(1, 1).RecentErrors
+	if len(errors) != 1 || errors[0].Error != "panic: boom" || errors[0].RequestID != "req-panic" || errors[0].OperationID != "op-1" {
+		t.Errorf("Expected the panic in the recent errors, got %+v", errors)
+	}
+
+	// Traced although tracing is off for the spec
+	traces := tracingSvc.GetTraces(&models.TraceFilter{})
+	if len(traces) != 1 {
+		t.Fatalf("Expected 1 trace, got %d", len(traces))
+	}
+	if traces[0].MatchedConfig != "panic" || !strings.Contains(traces[0].Error, "panic: boom") || !strings.Contains(traces[0].Error, "GetResponseConfigsByOperation") {
+		t.Errorf("Expected the panic and its stack in the trace, got %q", traces[0].Error)
+	}
+}
+
+func TestServeHTTP_OperationTimeout(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/slow", FullPath: "/api/slow", Timeout: 50})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/default", FullPath: "/api/default"})
+	for _, opID := range []string{"op-1", "op-2"} {
+		store.CreateResponseConfig(&models.ResponseConfig{
+			ID:          opID + "-resp",
+			OperationID: opID,
+			StatusCode:  200,
+			Body:        `{}`,
+			Delay:       10000,
+			Enabled:     true,
+		})
+	}
+	engine.ReloadRoutes()
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/slow", nil))
+
+	if w.Code != StatusTimeout {
+		t.Errorf("Expected status %d, got %d", StatusTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the delay to be cut short, took %v", elapsed)
+	}
+
+	// Operations without their own timeout use the server default
+	engine.SetResponseTimeout(50 * time.Millisecond)
+	engine.SetFallbackResponses(models.FallbackResponses{
+		Timeout: &models.FallbackResponse{StatusCode: 504, Body: `{"error": "too slow"}`},
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/default", nil))
+
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != `{"error": "too slow"}` {
+		t.Errorf("Expected the configured timeout fallback, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServeHTTP_Sequence(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/users", FullPath: "/api/users"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "POST", Path: "/orders", FullPath: "/api/orders"})
+	for _, opID := range []string{"op-1", "op-2"} {
+		store.CreateResponseConfig(&models.ResponseConfig{
+			ID:          opID + "-resp",
+			OperationID: opID,
+			StatusCode:  201,
+			Headers:     map[string]string{"Location": "/items/{{sequence}}"},
+			Body:        `{"id": {{sequence}}}`,
+			Enabled:     true,
+		})
+	}
+	engine.ReloadRoutes()
+
+	// Each operation counts on its own; headers and body share the value
+	for _, tc := range []struct{ path, expected string }{
+		{"/api/users", "1"},
+		{"/api/users", "2"},
+		{"/api/orders", "1"},
+		{"/api/users", "3"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", tc.path, nil))
+		if w.Body.String() != `{"id": `+tc.expected+`}` || w.Header().Get("Location") != "/items/"+tc.expected {
+			t.Errorf("Expected id %s from %s, got %s and %s", tc.expected, tc.path, w.Body.String(), w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestServeHTTP_Seed(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users/{id}", FullPath: "/api/users/{id}"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        `{"id": "{{path.id}}", "name": "{{random.name}} {{random.string(8)}}", "ref": "{{random.uuid}}"}`,
+		Seed:        "{{path.id}}",
+		Enabled:     true,
+	})
+	engine.ReloadRoutes()
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	first := get("/api/users/1")
+	if again := get("/api/users/1"); again != first {
+		t.Errorf("Expected the same body for the same id, got %s and %s", first, again)
+	}
+	if other := get("/api/users/2"); other == strings.Replace(first, `"1"`, `"2"`, 1) {
+		t.Errorf("Expected other random values for another id, got %s", other)
+	}
+}
+
+func TestServeHTTP_Echo(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/debug/{id}", FullPath: "/api/debug/{id}", Echo: true})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 201, Body: `{}`, Enabled: true})
+	engine.ReloadRoutes()
+
+	req := httptest.NewRequest("POST", "/api/debug/7?q=a&q=b", strings.NewReader(`{"name": "x"}`))
+	req.Header.Add("X-Tag", "one")
+	req.Header.Add("X-Tag", "two")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON echo instead of the response config, got %d %s", w.Code, w.Body.String())
+	}
+	var echo struct {
+		Method  string              `json:"method"`
+		Path    string              `json:"path"`
+		Query   map[string][]string `json:"query"`
+		Headers map[string]string   `json:"headers"`
+		Body    string              `json:"body"`
+		JSON    map[string]string   `json:"json"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &echo); err != nil {
+		t.Fatalf("Expected JSON, got %s", w.Body.String())
+	}
+	if echo.Method != "POST" || echo.Path != "/api/debug/7" || len(echo.Query["q"]) != 2 {
+		t.Errorf("Expected the method, path and query, got %+v", echo)
+	}
+	if echo.Headers["X-Tag"] != "one, two" || echo.Body != `{"name": "x"}` || echo.JSON["name"] != "x" {
+		t.Errorf("Expected the headers and body, got %+v", echo)
+	}
+}
+
+func TestServeHTTP_ByteStats(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/users", FullPath: "/api/users"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", Name: "Created", StatusCode: 201, Body: `{"id": 1}`, Enabled: true})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-2", OperationID: "op-2", Name: "List", StatusCode: 200, Body: `[]`, Enabled: true})
+	engine.ReloadRoutes()
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name": "Ann"}`)))
+	if op := engine.statsCollector.GetOperationStats("op-1"); op == nil || op.BytesIn != 15 || op.BytesOut != 9 {
+		t.Errorf("Expected 15 bytes in and 9 out, got %+v", op)
+	}
+
+	// HEAD responses have no body
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/api/users", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+	if op := engine.statsCollector.GetOperationStats("op-2"); op == nil || op.TotalRequests != 2 || op.BytesOut != 2 {
+		t.Errorf("Expected 2 bytes out for 2 requests, got %+v", op)
+	}
+}
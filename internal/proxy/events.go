@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/template"
+)
+
+// Events returns the dispatcher publishing the event actions of response configs
+func (e *Engine) Events() *events.Dispatcher {
+	return e.events
+}
+
+// emitEvents renders the event actions of a response config and publishes them in the
+// background, so they never delay the response. Failures are logged
+func (e *Engine) emitEvents(spec *models.Spec, actions []models.EventAction, pathParams map[string]string, templateCtx *template.Context, logger *slog.Logger) {
+	for _, action := range actions {
+		msg, ok := e.renderEvent(spec, action, pathParams, templateCtx)
+		if !ok {
+			logger.Warn("skipping event without a destination", "type", action.Type, "channel", action.Channel)
+			continue
+		}
+
+		go func(kind string, delay time.Duration, msg events.Message) {
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+			if err := e.events.Publish(context.Background(), kind, msg); err != nil {
+				logger.Warn("failed to publish event", "type", kind, "topic", msg.Topic, "error", err)
+				return
+			}
+			logger.Debug("published event", "type", kind, "topic", msg.Topic)
+		}(action.Type, time.Duration(action.Delay)*time.Millisecond, msg)
+	}
+}
+
+// renderEvent builds the message of an event action, filling the topic and payload from its
+// AsyncAPI channel when they are empty. AMQP routing keys are carried as the topic
+// It reports false when the message has nowhere to go
+func (e *Engine) renderEvent(spec *models.Spec, action models.EventAction, pathParams map[string]string, templateCtx *template.Context) (events.Message, bool) {
+	topic, payload := action.Topic, action.Payload
+	if action.Type == models.EventAMQP {
+		topic = action.RoutingKey
+	}
+	if channel := spec.Channel(action.Channel); action.Channel != "" && channel != nil {
+		if topic == "" {
+			topic = channelAddress(channel.Address, pathParams)
+		}
+		if payload == "" && len(channel.Messages) > 0 {
+			payload = channel.Messages[0].Example
+		}
+	}
+
+	msg := events.Message{
+		Topic:   e.templateEngine.Process(topic, templateCtx),
+		Key:     e.templateEngine.Process(action.Key, templateCtx),
+		Headers: e.templateEngine.ProcessHeaders(action.Headers, templateCtx),
+		Payload: []byte(e.templateEngine.Process(payload, templateCtx)),
+		Broker:  action.Broker,
+		QoS:     byte(action.QoS),
+		Retain:  action.Retain,
+	}
+	if action.Type == models.EventAMQP {
+		// Fanout exchanges ignore routing keys
+		msg.Exchange = e.templateEngine.Process(action.Exchange, templateCtx)
+		return msg, msg.Topic != "" || msg.Exchange != ""
+	}
+	return msg, msg.Topic != ""
+}
+
+// channelAddress fills the {name} parameters of a channel address with the request's path
+// parameters of the same name, e.g. devices/{deviceId}/commands
+func channelAddress(address string, pathParams map[string]string) string {
+	for name, value := range pathParams {
+		address = strings.ReplaceAll(address, "{"+name+"}", value)
+	}
+	return address
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/events"
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages []events.Message
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, msg events.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+// wait returns the published messages once there are n of them
+func (p *recordingPublisher) wait(t *testing.T, n int) []events.Message {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		if len(p.messages) >= n {
+			defer p.mu.Unlock()
+			return p.messages
+		}
+		p.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d published messages", n)
+	return nil
+}
+
+func TestServeHTTP_Events(t *testing.T) {
+	engine, store := setupTestEngine(t)
+	publisher := &recordingPublisher{}
+	engine.Events().SetPublisher(models.EventKafka, publisher)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Shop", BasePath: "/shop", Enabled: true, Channels: []models.Channel{{
+		Name:     "orderShipped",
+		Address:  "orders.{orderId}.shipped",
+		Messages: []models.ChannelMessage{{Name: "shipped", Example: `{"order": "{{path.orderId}}"}`}},
+	}}})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/orders/{orderId}/ship", FullPath: "/shop/orders/{orderId}/ship"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 202, Enabled: true, Events: []models.EventAction{
+		{Type: models.EventKafka, Topic: "orders", Key: "{{path.orderId}}", Headers: map[string]string{"source": "{{header.X-Source}}"}, Payload: `{"id": "{{path.orderId}}", "status": "{{body.status}}"}`},
+		{Type: models.EventKafka, Channel: "orderShipped"},
+	}})
+	engine.ReloadRoutes()
+
+	req := httptest.NewRequest("POST", "/shop/orders/7/ship", strings.NewReader(`{"status": "shipped"}`))
+	req.Header.Set("X-Source", "web")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != 202 {
+		t.Fatalf("Expected status 202, got %d", w.Code)
+	}
+
+	messages := publisher.wait(t, 2)
+	var direct, channel events.Message
+	for _, msg := range messages {
+		if msg.Topic == "orders" {
+			direct = msg
+		} else {
+			channel = msg
+		}
+	}
+	if direct.Key != "7" || direct.Headers["source"] != "web" || string(direct.Payload) != `{"id": "7", "status": "shipped"}` {
+		t.Errorf("Unexpected message: %+v", direct)
+	}
+	if channel.Topic != "orders.7.shipped" || string(channel.Payload) != `{"order": "7"}` {
+		t.Errorf("Expected the topic and payload of the channel, got %s %s", channel.Topic, channel.Payload)
+	}
+}
+
+func TestServeHTTP_AMQPEvents(t *testing.T) {
+	engine, store := setupTestEngine(t)
+	publisher := &recordingPublisher{}
+	engine.Events().SetPublisher(models.EventAMQP, publisher)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Shop", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/orders/{orderId}", FullPath: "/orders/{orderId}"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 201, Enabled: true, Events: []models.EventAction{
+		{Type: models.EventAMQP, Exchange: "orders", RoutingKey: "order.{{path.orderId}}.created", Headers: map[string]string{"source": "mock"}, Payload: `{"id": "{{path.orderId}}"}`},
+		{Type: models.EventAMQP, Exchange: "audit"},
+	}})
+	engine.ReloadRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/orders/7", nil))
+	if w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	messages := publisher.wait(t, 2)
+	var routed, fanout events.Message
+	for _, msg := range messages {
+		if msg.Exchange == "orders" {
+			routed = msg
+		} else {
+			fanout = msg
+		}
+	}
+	if routed.Topic != "order.7.created" || routed.Headers["source"] != "mock" || string(routed.Payload) != `{"id": "7"}` {
+		t.Errorf("Unexpected message: %+v", routed)
+	}
+	if fanout.Exchange != "audit" || fanout.Topic != "" {
+		t.Errorf("Expected a message to the audit exchange without routing key, got %+v", fanout)
+	}
+}
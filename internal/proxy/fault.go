@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// defaultTrickleInterval is the time between trickled bytes of faults without their own
+const defaultTrickleInterval = time.Second
+
+// writeFaulty writes a response broken as its fault describes, stopping once ctx is done
+// It returns the number of body bytes written
+func writeFaulty(ctx context.Context, w http.ResponseWriter, statusCode int, body io.Reader, size int64, fault *models.ResponseFault) (int64, error) {
+	if fault.Type == models.FaultTrickle {
+		interval := time.Duration(fault.Interval) * time.Millisecond
+		if interval <= 0 {
+			interval = defaultTrickleInterval
+		}
+		return writeTrickled(ctx, w, statusCode, body, size, interval, fault.MaxBytes)
+	}
+	return writeAborted(ctx, w, statusCode, body, size, fault.Fraction)
+}
+
+// writeAborted writes the status and headers of a response announcing the full body length,
+// then the first fraction of the body, and closes the connection. It returns the number of
+// body bytes written
+func writeAborted(ctx context.Context, w http.ResponseWriter, statusCode int, body io.Reader, size int64, fraction float64) (int64, error) {
+	defer interruptWrites(ctx, w)()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(statusCode)
+
+	n, err := io.CopyN(w, body, int64(float64(size)*fraction))
+	if closeErr := closeConnection(w); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// writeTrickled writes the status and headers of a response announcing the full body length,
+// then the body one byte per interval. After maxBytes bytes, when set, the connection is closed.
+// The server's write timeout doesn't apply, so trickles can outlast it, but the operation timeout
+// in ctx does. It returns the number of body bytes written
+func writeTrickled(ctx context.Context, w http.ResponseWriter, statusCode int, body io.Reader, size int64, interval time.Duration, maxBytes int64) (int64, error) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	defer interruptWrites(ctx, w)()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(statusCode)
+	rc.Flush()
+
+	limit := size
+	if maxBytes > 0 && maxBytes < size {
+		limit = maxBytes
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	var n int64
+	b := make([]byte, 1)
+	for n < limit {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+		if _, err := io.ReadFull(body, b); err != nil {
+			return n, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return n, err
+		}
+		n++
+		if err := rc.Flush(); err != nil {
+			return n, err
+		}
+		timer.Reset(interval)
+	}
+
+	if n < size {
+		return n, closeConnection(w)
+	}
+	return n, nil
+}
+
+// interruptWrites makes blocked and later writes to w fail once ctx is done, so a slow client
+// can't hold a response past the operation timeout. The returned function stops it; call it
+// before the handler returns, as net/http still writes the end of the response afterwards
+func interruptWrites(ctx context.Context, w http.ResponseWriter) func() bool {
+	rc := http.NewResponseController(w)
+	return context.AfterFunc(ctx, func() {
+		rc.SetWriteDeadline(time.Now())
+	})
+}
+
+// closeConnection flushes what was written of a response and abruptly closes its connection
+// Wrappers refusing to hijack once a response was written, like gin's, are unwrapped. Writers
+// that can't be hijacked at all, like HTTP/2 streams, are left short of their Content-Length,
+// which makes net/http close the connection or reset the stream once the handler returns
+func closeConnection(w http.ResponseWriter) error {
+	http.NewResponseController(w).Flush()
+
+	var err error = http.ErrNotSupported
+	for {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			var conn net.Conn
+			if conn, _, err = hijacker.Hijack(); err == nil {
+				return conn.Close()
+			}
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return err
+		}
+		w = wrapper.Unwrap()
+	}
+}
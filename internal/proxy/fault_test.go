@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_AbortFault(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	body := strings.Repeat("0123456789", 100)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Tracing: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/download", FullPath: "/api/download"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/report", FullPath: "/api/report"})
+	store.SaveSpecFile("spec-1", "report.csv", []byte(body))
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        body,
+		Enabled:     true,
+		Fault:       &models.ResponseFault{Type: models.FaultAbort, Fraction: 0.25},
+	})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-2",
+		OperationID: "op-2",
+		StatusCode:  200,
+		BodyFile:    "report.csv",
+		Enabled:     true,
+		Fault:       &models.ResponseFault{Type: models.FaultAbort, Fraction: 0.5},
+	})
+	engine.ReloadRoutes()
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	for path, expected := range map[string]int{"/api/download": 250, "/api/report": 500} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Expected the headers of %s, got %v", path, err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(body)) {
+			t.Errorf("Expected status 200 announcing %d bytes, got %d and %d", len(body), resp.StatusCode, resp.ContentLength)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Expected an unexpected EOF reading %s, got %v", path, err)
+		}
+		if len(data) != expected || string(data) != body[:expected] {
+			t.Errorf("Expected the first %d bytes of %s, got %d", expected, path, len(data))
+		}
+	}
+
+	traces := engine.tracingService.GetTraces(&models.TraceFilter{OperationID: "op-1"})
+	if len(traces) != 1 || traces[0].Response.Body != body[:250] {
+		t.Errorf("Expected the written part in the trace, got %+v", traces)
+	}
+}
+
+func TestServeHTTP_TrickleFault(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Tracing: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/slow", FullPath: "/api/slow"})
+	store.CreateOperation(&models.Operation{ID: "op-2", SpecID: "spec-1", Method: "GET", Path: "/stalled", FullPath: "/api/stalled"})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        "abcd",
+		Enabled:     true,
+		Fault:       &models.ResponseFault{Type: models.FaultTrickle, Interval: 20},
+	})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-2",
+		OperationID: "op-2",
+		StatusCode:  200,
+		Body:        "abcdefgh",
+		Enabled:     true,
+		Fault:       &models.ResponseFault{Type: models.FaultTrickle, Interval: 10, MaxBytes: 3},
+	})
+	engine.ReloadRoutes()
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	// The whole body arrives, one byte per interval
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/api/slow")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(data) != "abcd" {
+		t.Errorf("Expected the whole body, got %q and %v", data, err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected the body to take 4 intervals, took %v", elapsed)
+	}
+
+	// The connection closes once the cap is reached
+	resp, err = http.Get(server.URL + "/api/stalled")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	data, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || string(data) != "abc" {
+		t.Errorf("Expected 3 bytes and an unexpected EOF, got %q and %v", data, err)
+	}
+
+	traces := engine.tracingService.GetTraces(&models.TraceFilter{OperationID: "op-2"})
+	if len(traces) != 1 || traces[0].Response.Body != "abc" {
+		t.Errorf("Expected the trickled part in the trace, got %+v", traces)
+	}
+}
+
+func TestServeHTTP_TrickleFaultTimeout(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/slow", FullPath: "/api/slow", Timeout: 100})
+	store.CreateResponseConfig(&models.ResponseConfig{
+		ID:          "resp-1",
+		OperationID: "op-1",
+		StatusCode:  200,
+		Body:        strings.Repeat("a", 100),
+		Enabled:     true,
+		Fault:       &models.ResponseFault{Type: models.FaultTrickle, Interval: 20},
+	})
+	engine.ReloadRoutes()
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	// The operation timeout cuts the trickle short
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/api/slow")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || len(data) >= 100 {
+		t.Errorf("Expected part of the body and an unexpected EOF, got %d bytes and %v", len(data), err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the timeout to end the trickle, took %v", elapsed)
+	}
+}
@@ -0,0 +1,261 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/logging"
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/template"
+)
+
+// hopHeaders apply to a single connection and are never forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// defaultBadGatewayResponse answers forwarded requests whose upstream can't be reached
+var defaultBadGatewayResponse = models.FallbackResponse{
+	StatusCode: http.StatusBadGateway,
+	Body:       `{"error": "Upstream request failed"}`,
+}
+
+// newUpstreamClient creates the client forwarding requests
+// Redirects are passed on to the caller rather than followed
+func newUpstreamClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// forwardsFirst reports whether a request goes upstream without considering response configs
+func forwardsFirst(rt *route, r *http.Request) bool {
+	policy := rt.operation.Forward
+	if rt.spec.Upstream == "" || policy == nil || policy.Mode != models.ForwardAlways {
+		return false
+	}
+	header := policy.MockHeader
+	if header == "" {
+		header = models.DefaultForceMockHeader
+	}
+	return len(r.Header.Values(header)) == 0
+}
+
+// forwardsUnmatched reports whether requests no response config matches go upstream
+func forwardsUnmatched(rt *route) bool {
+	policy := rt.operation.Forward
+	return rt.spec.Upstream != "" && policy != nil && policy.Mode == models.ForwardUnmatched
+}
+
+// serveUpstream forwards a request to the upstream of its spec and records the response,
+// learning it when the forwarding policy asks to. Unreachable upstreams get a 502 response,
+// upstreams slower than the operation timeout the timeout fallback
+// ctx carries the operation timeout
+func (e *Engine) serveUpstream(ctx context.Context, w http.ResponseWriter, r *http.Request, rt *route, pathParams map[string]string, requestBody string, startTime time.Time, logger *slog.Logger) {
+	templateCtx := e.newTemplateContext(r, rt.spec, pathParams, requestBody)
+	statusCode, responseBody, upstream, err := e.forward(ctx, w, r, rt.spec, requestBody, templateCtx, rt.tracing() || rt.learns())
+	switch {
+	case err == nil:
+		e.recordResponse(rt, r, requestBody, startTime, w, "upstream", statusCode, responseBody, upstream)
+		// Responses that broke off are incomplete and not worth learning
+		if rt.learns() && upstream.Error == "" {
+			e.learnResponse(rt, r, statusCode, w.Header(), responseBody, logger)
+		}
+	case r.Context().Err() != nil:
+		logger.Debug("client went away while forwarding")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("upstream timed out", "upstream", rt.spec.Upstream, "timeout", e.operationTimeout(rt.operation))
+		statusCode, responseBody = e.writeFallback(w, r, fallbackTimeout, rt.spec, pathParams, requestBody)
+		e.recordResponse(rt, r, requestBody, startTime, w, "timeout", statusCode, responseBody, upstream)
+	default:
+		logger.Warn("upstream request failed", "upstream", rt.spec.Upstream, "error", err)
+		statusCode, responseBody = e.writeTemplated(w, &defaultBadGatewayResponse, http.StatusBadGateway, templateCtx)
+		e.recordResponse(rt, r, requestBody, startTime, w, "upstream-error", statusCode, responseBody, upstream)
+	}
+}
+
+// forward sends a request to the upstream of a spec and copies back the upstream response,
+// applying the spec's rewrites to both. It returns the status code, the body for tracing when
+// capture is set, and the upstream URL with its timings, which failed requests have too.
+// Errors are only returned before anything was written
+func (e *Engine) forward(ctx context.Context, w http.ResponseWriter, r *http.Request, spec *models.Spec, requestBody string, templateCtx *template.Context, capture bool) (int, string, *models.TraceUpstream, error) {
+	target, err := upstreamURL(spec.Upstream, spec.BasePath, r.URL)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	upstream := &models.TraceUpstream{URL: target}
+	start := time.Now()
+	var connStart time.Time
+	var ttfb atomic.Int64 // Set on the transport's read goroutine
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			connStart = time.Now()
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			upstream.Connect = time.Since(connStart).Nanoseconds()
+		},
+		GotFirstResponseByte: func() {
+			ttfb.Store(time.Since(start).Nanoseconds())
+		},
+	})
+	// The timings are complete whichever way forwarding ends
+	defer func() {
+		upstream.TTFB = ttfb.Load()
+		upstream.Total = time.Since(start).Nanoseconds()
+	}()
+
+	statusCode, responseBody, err := e.exchange(ctx, w, r, spec, target, requestBody, templateCtx, capture, upstream)
+	if err != nil {
+		upstream.Error = err.Error()
+	}
+	return statusCode, responseBody, upstream, err
+}
+
+// exchange sends the request of forward to its target and copies back the response
+// A response that breaks off while being copied sets the upstream error but returns no error
+func (e *Engine) exchange(ctx context.Context, w http.ResponseWriter, r *http.Request, spec *models.Spec, target, requestBody string, templateCtx *template.Context, capture bool, upstream *models.TraceUpstream) (int, string, error) {
+	rules := requestRewrite(spec)
+	if rewritesBody(rules, r.Header) {
+		requestBody = e.rewriteBody(requestBody, rules, templateCtx)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, strings.NewReader(requestBody))
+	if err != nil {
+		return 0, "", err
+	}
+	copyHeaders(req.Header, r.Header)
+	setForwardedHeaders(req.Header, r)
+	if rules != nil {
+		e.rewriteHeaders(req.Header, rules, templateCtx)
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+			req.Header.Del("Host")
+		}
+	}
+
+	resp, err := e.upstream.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	copyHeaders(w.Header(), resp.Header)
+	rules = responseRewrite(spec)
+	if rules != nil {
+		e.rewriteHeaders(w.Header(), rules, templateCtx)
+	}
+
+	// Rewritten bodies are buffered; others stream through
+	if rewritesBody(rules, resp.Header) {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return 0, "", err
+		}
+		body := e.rewriteBody(string(data), rules, templateCtx)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(resp.StatusCode)
+		w.Write([]byte(body))
+		if !capture {
+			body = ""
+		}
+		return resp.StatusCode, body, nil
+	}
+
+	w.WriteHeader(resp.StatusCode)
+
+	var body strings.Builder
+	dst := io.Writer(w)
+	if capture {
+		dst = io.MultiWriter(w, &body)
+	}
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		logging.FromContext(r.Context()).Debug("failed to copy upstream response", "upstream", spec.Upstream, "error", err)
+		upstream.Error = err.Error()
+	}
+	return resp.StatusCode, body.String(), nil
+}
+
+// upstreamURL maps a request URL below a spec's base path onto the spec's upstream URL
+func upstreamURL(upstream, basePath string, u *url.URL) (string, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return "", err
+	}
+	target.Path = strings.TrimSuffix(target.Path, "/") + strings.TrimPrefix(u.Path, strings.TrimSuffix(basePath, "/"))
+	target.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+		target.RawQuery = u.RawQuery
+	case u.RawQuery != "":
+		target.RawQuery += "&" + u.RawQuery
+	}
+	return target.String(), nil
+}
+
+// copyHeaders copies the end-to-end headers of src, replacing their values in dst
+func copyHeaders(dst, src http.Header) {
+	connection := src.Values("Connection")
+	for key, values := range src {
+		if isHopHeader(key, connection) {
+			continue
+		}
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// isHopHeader reports whether a header is hop-by-hop, including those a Connection header names
+func isHopHeader(key string, connection []string) bool {
+	for _, h := range hopHeaders {
+		if strings.EqualFold(key, h) {
+			return true
+		}
+	}
+	for _, value := range connection {
+		for _, name := range strings.Split(value, ",") {
+			if strings.EqualFold(key, strings.TrimSpace(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setForwardedHeaders tells the upstream about the original client, host and protocol
+func setForwardedHeaders(h http.Header, r *http.Request) {
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := r.Header.Get("X-Forwarded-For"); prior != "" {
+			clientIP = prior + ", " + clientIP
+		}
+		h.Set("X-Forwarded-For", clientIP)
+	}
+	if h.Get("X-Forwarded-Host") == "" {
+		h.Set("X-Forwarded-Host", r.Host)
+	}
+	if h.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if r.TLS != nil {
+			proto = "https"
+		}
+		h.Set("X-Forwarded-Proto", proto)
+	}
+}
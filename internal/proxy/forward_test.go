@@ -0,0 +1,276 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// newUpstream starts an upstream that echoes what it received
+func newUpstream(t *testing.T) *httptest.Server {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Upstream-Path", r.URL.RequestURI())
+		w.Header().Set("X-Upstream-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Upstream-Token", r.Header.Get("X-Token"))
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("upstream:" + string(body)))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// forwardOperation serves POST /users/{id}, forwarding as policy says
+func forwardOperation(policy *models.ForwardPolicy) []*models.Operation {
+	return []*models.Operation{{Method: "POST", Path: "/users/{id}", Forward: policy}}
+}
+
+// mockUserOne mocks user 1 and leaves the other users unmatched
+func mockUserOne() *models.ResponseConfig {
+	return &models.ResponseConfig{
+		Body:       `{"source": "mock"}`,
+		Conditions: []models.Condition{{Source: models.SourcePath, Key: "id", Operator: models.OpEquals, Value: "1"}},
+	}
+}
+
+func TestServeHTTP_ForwardUnmatched(t *testing.T) {
+	upstream := newUpstream(t)
+	engine := setupSpecEngine(t, &models.Spec{Tracing: true, Upstream: upstream.URL + "/v1"}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardUnmatched}), mockUserOne())
+
+	// Matching configs are served from the mock
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/1", strings.NewReader("hi")))
+	if w.Code != http.StatusOK || w.Body.String() != `{"source": "mock"}` {
+		t.Fatalf("Expected the mock response, got %d %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("POST", "/api/users/2?expand=true", strings.NewReader("hello"))
+	req.Header.Set("X-Token", "secret")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated || w.Body.String() != "upstream:hello" {
+		t.Fatalf("Expected the upstream response, got %d %s", w.Code, w.Body.String())
+	}
+	if path := w.Header().Get("X-Upstream-Path"); path != "/v1/users/2?expand=true" {
+		t.Errorf("Expected the upstream to get /v1/users/2?expand=true, got %q", path)
+	}
+	if token := w.Header().Get("X-Upstream-Token"); token != "secret" {
+		t.Errorf("Expected request headers to be forwarded, got %q", token)
+	}
+	if forwardedFor := w.Header().Get("X-Upstream-Forwarded-For"); forwardedFor != "192.0.2.1" {
+		t.Errorf("Expected X-Forwarded-For 192.0.2.1, got %q", forwardedFor)
+	}
+	if connection := w.Header().Get("Connection"); connection != "" {
+		t.Errorf("Expected hop-by-hop headers to be dropped, got Connection %q", connection)
+	}
+
+	traces := engine.tracingService.GetTraces(nil)
+	if len(traces) != 2 || traces[0].MatchedConfig != "upstream" || traces[0].Response.Body != "upstream:hello" {
+		t.Errorf("Expected an upstream trace, got %+v", traces[0])
+	}
+	timing := traces[0].Upstream
+	if timing == nil || timing.URL != upstream.URL+"/v1/users/2?expand=true" {
+		t.Fatalf("Expected the upstream URL in the trace, got %+v", timing)
+	}
+	if timing.TTFB <= 0 || timing.Total < timing.TTFB || timing.Connect > timing.TTFB || timing.Total > traces[0].Duration {
+		t.Errorf("Unexpected upstream timings: %+v (request took %d)", timing, traces[0].Duration)
+	}
+	if traces[1].Upstream != nil {
+		t.Errorf("Expected no upstream for the mocked request, got %+v", traces[1].Upstream)
+	}
+}
+
+func TestServeHTTP_ForwardAlways(t *testing.T) {
+	upstream := newUpstream(t)
+	engine := setupSpecEngine(t, &models.Spec{Upstream: upstream.URL}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardAlways}), mockUserOne())
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/1", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "upstream:" {
+		t.Fatalf("Expected the upstream response, got %d %s", w.Code, w.Body.String())
+	}
+
+	// The mock header serves the mock
+	req := httptest.NewRequest("POST", "/api/users/1", nil)
+	req.Header.Set(models.DefaultForceMockHeader, "1")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != `{"source": "mock"}` {
+		t.Fatalf("Expected the mock response, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServeHTTP_ForwardWithoutUpstream(t *testing.T) {
+	engine := setupSpecEngine(t, nil, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardAlways}), mockUserOne())
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the mock without an upstream, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_ForwardErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	engine := setupSpecEngine(t, &models.Spec{Tracing: true, Upstream: closed.URL}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardUnmatched}), mockUserOne())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/2", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502 for an unreachable upstream, got %d", w.Code)
+	}
+	if traces := engine.tracingService.GetTraces(nil); len(traces) != 1 || traces[0].Upstream == nil || traces[0].Upstream.Error == "" {
+		t.Errorf("Expected the upstream error in the trace, got %+v", traces)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	engine = setupSpecEngine(t, &models.Spec{Tracing: true, Upstream: slow.URL}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardUnmatched}), mockUserOne())
+	engine.SetResponseTimeout(50 * time.Millisecond)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/2", nil))
+	if w.Code != StatusTimeout {
+		t.Errorf("Expected status %d for a slow upstream, got %d", StatusTimeout, w.Code)
+	}
+}
+
+func TestServeHTTP_ForwardLearn(t *testing.T) {
+	upstream := newUpstream(t)
+	engine := setupSpecEngine(t, &models.Spec{Upstream: upstream.URL}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardAlways, Learn: true}), mockUserOne())
+
+	for _, body := range []string{"a", "b", "a"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", "/api/users/2", strings.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected the upstream response, got %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Mocked requests teach nothing
+	req := httptest.NewRequest("POST", "/api/users/1", strings.NewReader("c"))
+	req.Header.Set(models.DefaultForceMockHeader, "true")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	configs, _ := engine.store.GetResponseConfigsByOperation("op-1")
+	var learned []*models.ResponseConfig
+	for _, cfg := range configs {
+		if cfg.ID != "resp-1" {
+			learned = append(learned, cfg)
+		}
+	}
+	if len(learned) != 2 {
+		t.Fatalf("Expected 2 distinct learned responses, got %d", len(learned))
+	}
+	bodies := map[string]bool{}
+	for _, cfg := range learned {
+		if cfg.Enabled || cfg.StatusCode != http.StatusCreated || cfg.Headers["Content-Type"] != "text/plain" {
+			t.Errorf("Expected a disabled 201 text/plain draft, got %+v", cfg)
+		}
+		bodies[cfg.Body] = true
+	}
+	if !bodies["upstream:a"] || !bodies["upstream:b"] {
+		t.Errorf("Expected the upstream bodies to be learned, got %v", bodies)
+	}
+
+	// Without learn nothing is saved
+	engine = setupSpecEngine(t, &models.Spec{Upstream: upstream.URL}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardAlways}), mockUserOne())
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/users/2", strings.NewReader("a")))
+	if configs, _ := engine.store.GetResponseConfigsByOperation("op-1"); len(configs) != 1 {
+		t.Errorf("Expected no learned responses, got %d configs", len(configs))
+	}
+}
+
+func TestUpstreamURL(t *testing.T) {
+	tests := []struct {
+		upstream, basePath, request, expected string
+	}{
+		{"http://real:8080", "/api", "/api/users?page=2", "http://real:8080/users?page=2"},
+		{"http://real:8080/v1/", "/api/", "/api/users", "http://real:8080/v1/users"},
+		{"https://real/v1?key=k", "/", "/users?page=2", "https://real/v1/users?key=k&page=2"},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.request)
+		got, err := upstreamURL(tt.upstream, tt.basePath, u)
+		if err != nil || got != tt.expected {
+			t.Errorf("upstreamURL(%q, %q, %q) = %q, %v; expected %q", tt.upstream, tt.basePath, tt.request, got, err, tt.expected)
+		}
+	}
+}
+
+func TestServeHTTP_ForwardRewrite(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, receivedBody = r, string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=upstream")
+		w.Write([]byte(`{"id": 2, "secret": "s3cr3t", "owner": {"name": "real"}}`))
+	}))
+	defer upstream.Close()
+
+	engine := setupSpecEngine(t, &models.Spec{
+		Upstream: upstream.URL,
+		UpstreamRewrite: &models.UpstreamRewrite{
+			Request: &models.MessageRewrite{
+				SetHeaders:    map[string]string{"Authorization": "Bearer {{header.X-User}}", "Host": "api.internal"},
+				RemoveHeaders: []string{"Cookie"},
+				SetBody:       map[string]string{"user.id": "{{path.id}}", "source": "virtual"},
+				RemoveBody:    []string{"password"},
+			},
+			Response: &models.MessageRewrite{
+				SetHeaders:    map[string]string{"X-Served-By": "go-virtual"},
+				RemoveHeaders: []string{"Set-Cookie"},
+				SetBody:       map[string]string{"owner.name": "{{header.X-User}}"},
+				RemoveBody:    []string{"secret"},
+			},
+		},
+	}, forwardOperation(&models.ForwardPolicy{Mode: models.ForwardAlways}))
+
+	req := httptest.NewRequest("POST", "/api/users/2", strings.NewReader(`{"name": "Jane", "password": "hunter2"}`))
+	req.Header.Set("X-User", "jane")
+	req.Header.Set("Cookie", "session=client")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if auth := received.Header.Get("Authorization"); auth != "Bearer jane" {
+		t.Errorf("Expected Authorization %q, got %q", "Bearer jane", auth)
+	}
+	if received.Host != "api.internal" {
+		t.Errorf("Expected host api.internal, got %q", received.Host)
+	}
+	if cookie := received.Header.Get("Cookie"); cookie != "" {
+		t.Errorf("Expected the cookie to be stripped, got %q", cookie)
+	}
+	if expected := `{"name": "Jane","source":"virtual","user":{"id":2}}`; receivedBody != expected {
+		t.Errorf("Expected request body %s, got %s", expected, receivedBody)
+	}
+
+	if w.Header().Get("Set-Cookie") != "" || w.Header().Get("X-Served-By") != "go-virtual" {
+		t.Errorf("Unexpected response headers: %v", w.Header())
+	}
+	if expected := `{"id": 2, "owner": {"name": "jane"}}`; w.Body.String() != expected {
+		t.Errorf("Expected response body %s, got %s", expected, w.Body.String())
+	}
+	if length := w.Header().Get("Content-Length"); length != strconv.Itoa(w.Body.Len()) {
+		t.Errorf("Expected Content-Length %d, got %s", w.Body.Len(), length)
+	}
+}
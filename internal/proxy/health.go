@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Defaults of health checks
+const (
+	defaultHealthInterval  = 30 * time.Second
+	defaultHealthTimeout   = 5 * time.Second
+	defaultHealthThreshold = 2
+)
+
+// healthState holds the running health checks of spec upstreams
+type healthState struct {
+	mu     sync.Mutex
+	checks map[string]*healthCheck // spec ID -> check
+}
+
+// healthCheck probes one upstream until stopped
+type healthCheck struct {
+	target string
+	policy models.HealthCheck
+	stop   context.CancelFunc
+
+	mu     sync.Mutex
+	health models.UpstreamHealth
+}
+
+// sync starts the health checks of specs with an upstream and an enabled policy, restarting
+// those whose upstream or policy changed and stopping the rest
+func (h *healthState) sync(specs []*models.Spec, client *http.Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	checks := make(map[string]*healthCheck)
+	for _, spec := range specs {
+		if spec.Upstream == "" || spec.HealthCheck == nil || !spec.HealthCheck.Enabled {
+			continue
+		}
+		target := healthTarget(spec.Upstream, spec.HealthCheck.Path)
+		if existing, ok := h.checks[spec.ID]; ok && existing.target == target && existing.policy == *spec.HealthCheck {
+			checks[spec.ID] = existing
+			continue
+		}
+
+		ctx, stop := context.WithCancel(context.Background())
+		check := &healthCheck{
+			target: target,
+			policy: *spec.HealthCheck,
+			stop:   stop,
+			health: models.UpstreamHealth{Status: models.UpstreamUnknown, URL: target},
+		}
+		checks[spec.ID] = check
+		go check.run(ctx, spec.ID, client)
+	}
+
+	for id, check := range h.checks {
+		if checks[id] != check {
+			check.stop()
+		}
+	}
+	h.checks = checks
+}
+
+// get returns the health check of a spec, or nil
+func (h *healthState) get(specID string) *healthCheck {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.checks[specID]
+}
+
+// UpstreamHealth returns the state of a spec's upstream, or nil when it isn't health checked
+func (e *Engine) UpstreamHealth(specID string) *models.UpstreamHealth {
+	check := e.health.get(specID)
+	if check == nil {
+		return nil
+	}
+	check.mu.Lock()
+	defer check.mu.Unlock()
+	health := check.health
+	health.FallingBack = check.policy.Fallback && health.Status == models.UpstreamDown
+	return &health
+}
+
+// upstreamDown reports whether a spec serves mocks because its upstream is down
+func (e *Engine) upstreamDown(spec *models.Spec) bool {
+	health := e.UpstreamHealth(spec.ID)
+	return health != nil && health.FallingBack
+}
+
+// run probes the upstream right away and then at the policy's interval until ctx is done
+func (c *healthCheck) run(ctx context.Context, specID string, client *http.Client) {
+	interval := time.Duration(c.policy.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.probe(ctx, specID, client)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe sends one request to the upstream and updates its state
+func (c *healthCheck) probe(ctx context.Context, specID string, client *http.Client) {
+	timeout := time.Duration(c.policy.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var statusCode int
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.target, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			statusCode = resp.StatusCode
+			if statusCode >= 500 {
+				err = fmt.Errorf("upstream answered %d", statusCode)
+			}
+		}
+	}
+	// A stopped check doesn't record the probe it abandoned; timed out probes failed
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	threshold := c.policy.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultHealthThreshold
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	previous := c.health.Status
+	c.health.CheckedAt = &now
+	c.health.StatusCode = statusCode
+	c.health.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		c.health.Error = err.Error()
+		c.health.Failures++
+		if c.health.Failures >= threshold {
+			c.health.Status = models.UpstreamDown
+		}
+	} else {
+		c.health.Error = ""
+		c.health.Failures = 0
+		c.health.Status = models.UpstreamUp
+	}
+
+	if c.health.Status != previous {
+		c.health.Since = &now
+		switch c.health.Status {
+		case models.UpstreamDown:
+			slog.Warn("upstream is down", "specId", specID, "url", c.target, "error", c.health.Error, "fallback", c.policy.Fallback)
+		case models.UpstreamUp:
+			slog.Info("upstream is up", "specId", specID, "url", c.target)
+		}
+	}
+}
+
+// healthTarget returns the URL probed for an upstream and health check path
+func healthTarget(upstream, path string) string {
+	if path == "" {
+		return upstream
+	}
+	return strings.TrimSuffix(upstream, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// waitForHealth waits until the upstream of spec-1 was probed
+func waitForHealth(t *testing.T, engine *Engine) *models.UpstreamHealth {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if health := engine.UpstreamHealth("spec-1"); health != nil && health.CheckedAt != nil {
+			return health
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected the upstream to be probed")
+	return nil
+}
+
+func TestHealthCheck_Fallback(t *testing.T) {
+	var healthy atomic.Bool
+	var probed atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/health" {
+			probed.Store(r.URL.Path)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+
+	engine, store := setupTestEngine(t)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Upstream: upstream.URL + "/v1",
+		HealthCheck: &models.HealthCheck{Enabled: true, Path: "/health", Interval: 3600, FailureThreshold: 1, Fallback: true}})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users",
+		Forward: &models.ForwardPolicy{Mode: models.ForwardAlways}})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, Body: "mock", Enabled: true})
+	engine.ReloadRoutes()
+	t.Cleanup(func() { engine.health.sync(nil, nil) })
+
+	health := waitForHealth(t, engine)
+	if health.Status != models.UpstreamDown || !health.FallingBack || health.StatusCode != 503 || probed.Load() != "/v1/health" {
+		t.Fatalf("Expected the upstream down, got %+v", health)
+	}
+
+	serve := func() string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+		return w.Body.String()
+	}
+	if body := serve(); body != "mock" {
+		t.Errorf("Expected the mock while the upstream is down, got %q", body)
+	}
+
+	healthy.Store(true)
+	engine.health.get("spec-1").probe(context.Background(), "spec-1", engine.upstream)
+	if health := engine.UpstreamHealth("spec-1"); health.Status != models.UpstreamUp || health.FallingBack || health.Failures != 0 {
+		t.Fatalf("Expected the upstream up, got %+v", health)
+	}
+	if body := serve(); body != "upstream" {
+		t.Errorf("Expected the upstream once it is up, got %q", body)
+	}
+}
+
+func TestHealthCheck_Threshold(t *testing.T) {
+	engine, store := setupTestEngine(t)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true, Upstream: "http://127.0.0.1:1",
+		HealthCheck: &models.HealthCheck{Enabled: true, Interval: 3600, Timeout: 500}})
+	engine.ReloadRoutes()
+	t.Cleanup(func() { engine.health.sync(nil, nil) })
+
+	// One failure isn't enough to mark the upstream down
+	health := waitForHealth(t, engine)
+	if health.Status != models.UpstreamUnknown || health.Failures != 1 || health.Error == "" || health.FallingBack {
+		t.Fatalf("Expected one failure, got %+v", health)
+	}
+	engine.health.get("spec-1").probe(context.Background(), "spec-1", engine.upstream)
+	if health := engine.UpstreamHealth("spec-1"); health.Status != models.UpstreamDown || health.FallingBack {
+		t.Errorf("Expected the upstream down without fallback, got %+v", health)
+	}
+
+	// Disabling the check stops it
+	spec, _ := store.GetSpec("spec-1")
+	spec.HealthCheck.Enabled = false
+	store.UpdateSpec(spec)
+	engine.ReloadRoutes()
+	if health := engine.UpstreamHealth("spec-1"); health != nil {
+		t.Errorf("Expected no health without a check, got %+v", health)
+	}
+}
+
+func TestHealthTarget(t *testing.T) {
+	tests := map[string][2]string{
+		"https://api.example.com/v1":        {"https://api.example.com/v1", ""},
+		"https://api.example.com/v1/health": {"https://api.example.com/v1/", "/health"},
+		"https://api.example.com/status":    {"https://api.example.com", "status"},
+	}
+	for want, in := range tests {
+		if got := healthTarget(in[0], in[1]); got != want {
+			t.Errorf("healthTarget(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// learnState serializes learning, so identical responses forwarded at once are saved once
+type learnState struct {
+	mu sync.Mutex
+}
+
+// learns reports whether forwarded responses of a route are saved as draft response configs
+func (r *route) learns() bool {
+	return r.operation.Forward != nil && r.operation.Forward.Learn
+}
+
+// learnResponse saves a forwarded response as a disabled response config of the operation,
+// unless one of its configs already has the same status code, content type and body
+// Compressed responses are skipped, as their bodies can't be replayed as mocks
+func (e *Engine) learnResponse(rt *route, r *http.Request, statusCode int, header http.Header, body string, logger *slog.Logger) {
+	if header.Get("Content-Encoding") != "" {
+		logger.Debug("not learning encoded upstream response", "encoding", header.Get("Content-Encoding"))
+		return
+	}
+	contentType := header.Get("Content-Type")
+
+	e.learning.mu.Lock()
+	defer e.learning.mu.Unlock()
+
+	configs, err := e.store.GetResponseConfigsByOperation(rt.operation.ID)
+	if err != nil {
+		logger.Warn("failed to load response configs to learn from upstream", "error", err)
+		return
+	}
+	priority := 0
+	for _, cfg := range configs {
+		if cfg.StatusCode == statusCode && cfg.BodyFile == "" && cfg.Body == body && cfg.Headers["Content-Type"] == contentType {
+			return
+		}
+		if cfg.Priority >= priority {
+			priority = cfg.Priority + 1
+		}
+	}
+
+	cfg := &models.ResponseConfig{
+		ID:          uuid.New().String(),
+		OperationID: rt.operation.ID,
+		Name:        fmt.Sprintf("Learned %d", statusCode),
+		Description: fmt.Sprintf("Learned from %s %s at %s", r.Method, r.URL.RequestURI(), time.Now().UTC().Format(time.RFC3339)),
+		Priority:    priority,
+		StatusCode:  statusCode,
+		Body:        body,
+	}
+	if contentType != "" {
+		cfg.Headers = map[string]string{"Content-Type": contentType}
+	}
+	if err := e.store.CreateResponseConfig(cfg); err != nil {
+		logger.Warn("failed to save learned response", "error", err)
+		return
+	}
+	logger.Info("learned upstream response", "config", cfg.ID, "status", statusCode)
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+)
+
+// concurrencyLimiter caps the number of mock requests served at the same time,
+// in total and per spec; a limit of 0 means unlimited
+type concurrencyLimiter struct {
+	mu      sync.Mutex
+	max     int
+	total   int
+	perSpec map[string]int
+}
+
+// SetMaxConcurrent sets the maximum number of mock requests served at the same time
+func (e *Engine) SetMaxConcurrent(n int) {
+	e.limiter.mu.Lock()
+	defer e.limiter.mu.Unlock()
+	e.limiter.max = n
+}
+
+// acquire reserves a slot for a request, reporting false when the server is saturated
+func (l *concurrencyLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.total >= l.max {
+		return false
+	}
+	l.total++
+	return true
+}
+
+// release frees a slot reserved with acquire
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+}
+
+// acquireSpec reserves a slot for a request on a spec, reporting false when the spec is saturated
+func (l *concurrencyLimiter) acquireSpec(specID string, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if max > 0 && l.perSpec[specID] >= max {
+		return false
+	}
+	if l.perSpec == nil {
+		l.perSpec = make(map[string]int)
+	}
+	l.perSpec[specID]++
+	return true
+}
+
+// releaseSpec frees a slot reserved with acquireSpec
+func (l *concurrencyLimiter) releaseSpec(specID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perSpec[specID] <= 1 {
+		delete(l.perSpec, specID)
+		return
+	}
+	l.perSpec[specID]--
+}
+
+// writeSaturated answers 503 when a concurrency limit is reached
+func writeSaturated(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(`{"error": "Too many concurrent requests"}`))
+}
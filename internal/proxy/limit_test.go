@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// serveAsync serves a request in the background and returns a channel with its status code
+func serveAsync(engine *Engine, target string) <-chan int {
+	done := make(chan int, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		done <- w.Code
+	}()
+	return done
+}
+
+func setupSlowSpecs(t *testing.T, maxConcurrent int) *Engine {
+	engine, store := setupTestEngine(t)
+
+	for _, id := range []string{"a", "b"} {
+		store.CreateSpec(&models.Spec{ID: id, Name: id, BasePath: "/" + id, Enabled: true, MaxConcurrent: maxConcurrent})
+		store.CreateOperation(&models.Operation{ID: id + "-op", SpecID: id, Method: "GET", Path: "/slow", FullPath: "/" + id + "/slow"})
+		store.CreateResponseConfig(&models.ResponseConfig{
+			ID:          id + "-resp",
+			OperationID: id + "-op",
+			StatusCode:  200,
+			Body:        `{}`,
+			Delay:       200,
+			Enabled:     true,
+		})
+	}
+	engine.ReloadRoutes()
+	return engine
+}
+
+func TestConcurrencyLimit_Global(t *testing.T) {
+	engine := setupSlowSpecs(t, 0)
+	engine.SetMaxConcurrent(1)
+
+	first := serveAsync(engine, "/a/slow")
+	time.Sleep(50 * time.Millisecond)
+
+	// The limit applies across specs
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/b/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 503 with Retry-After while saturated, got %d", w.Code)
+	}
+
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("Expected the first request to succeed, got %d", code)
+	}
+
+	// A slot is free again
+	if code := <-serveAsync(engine, "/b/slow"); code != http.StatusOK {
+		t.Errorf("Expected 200 after the first request finished, got %d", code)
+	}
+}
+
+func TestConcurrencyLimit_PerSpec(t *testing.T) {
+	engine := setupSlowSpecs(t, 1)
+
+	first := serveAsync(engine, "/a/slow")
+	time.Sleep(50 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/a/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for the saturated spec, got %d", w.Code)
+	}
+
+	// Other specs are unaffected
+	if code := <-serveAsync(engine, "/b/slow"); code != http.StatusOK {
+		t.Errorf("Expected 200 for another spec, got %d", code)
+	}
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("Expected the first request to succeed, got %d", code)
+	}
+
+	errors := engine.statsCollector.GetGlobalStats(1, 1).RecentErrors
+	if len(errors) != 1 || errors[0].SpecID != "a" || errors[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the rejection in the recent errors, got %+v", errors)
+	}
+	if len(engine.limiter.perSpec) != 0 || engine.limiter.total != 0 {
+		t.Errorf("Expected all slots to be released, got %d total and %v per spec", engine.limiter.total, engine.limiter.perSpec)
+	}
+}
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/template"
+)
+
+// defaultPageLimit is the page size of paginations without a default limit
+const defaultPageLimit = 10
+
+// pageRequest is the page a client asked for
+type pageRequest struct {
+	offset int
+	limit  int
+}
+
+// end returns the offset after the page, capped at total without overflowing
+func (req pageRequest) end(total int) int {
+	if req.limit >= total-req.offset {
+		return total
+	}
+	return req.offset + req.limit
+}
+
+// paginate renders the dataset of a pagination, selects the requested page and sets the
+// X-Total-Count and Link headers. The page is exposed to the body template as {{page.name}}
+func (e *Engine) paginate(w http.ResponseWriter, r *http.Request, p *models.Pagination, ctx *template.Context, logger *slog.Logger) {
+	items := e.paginationDataset(p, ctx, logger)
+	total := len(items)
+	req := parsePageRequest(r, p, total)
+
+	page, _ := json.Marshal(items[req.offset:req.end(total)])
+
+	vars := map[string]string{
+		"items":  string(page),
+		"total":  strconv.Itoa(total),
+		"limit":  strconv.Itoa(req.limit),
+		"offset": strconv.Itoa(req.offset),
+		"number": strconv.Itoa(req.offset/req.limit + 1),
+		"pages":  strconv.Itoa(pageCount(total, req.limit)),
+	}
+
+	links := pageLinks(r, p, req, total)
+	var linkHeader []string
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		vars[rel] = links[rel]
+		if links[rel] != "" {
+			linkHeader = append(linkHeader, "<"+links[rel]+`>; rel="`+rel+`"`)
+		}
+	}
+	if p.Mode == models.PaginationCursor {
+		if req.end(total) < total {
+			vars["nextCursor"] = encodeCursor(req.end(total))
+		}
+		if req.offset > 0 {
+			vars["prevCursor"] = encodeCursor(max(req.offset-req.limit, 0))
+		}
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if len(linkHeader) > 0 {
+		w.Header().Set("Link", strings.Join(linkHeader, ", "))
+	}
+	ctx.Page = vars
+}
+
+// paginationDataset renders the literal dataset or generates the items of a pagination
+// A dataset that isn't a JSON array is logged and treated as empty
+func (e *Engine) paginationDataset(p *models.Pagination, ctx *template.Context, logger *slog.Logger) []json.RawMessage {
+	if p.Generate > 0 {
+		items := make([]json.RawMessage, 0, p.Generate)
+		for i := 0; i < p.Generate; i++ {
+			ctx.Item = map[string]string{"index": strconv.Itoa(i), "number": strconv.Itoa(i + 1)}
+			item := e.templateEngine.Process(p.ItemTemplate, ctx)
+			if !json.Valid([]byte(item)) {
+				// Plain text items become JSON strings
+				quoted, _ := json.Marshal(item)
+				item = string(quoted)
+			}
+			items = append(items, json.RawMessage(item))
+		}
+		ctx.Item = nil
+		return items
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(e.templateEngine.Process(p.Dataset, ctx)), &items); err != nil {
+		logger.Warn("pagination dataset is not a JSON array", "error", err)
+		return []json.RawMessage{}
+	}
+	return items
+}
+
+// parsePageRequest reads the page and page size from the query, applying defaults and limits
+// The offset is clamped to [0,total]; page numbers past the last page select the empty page at total
+func parsePageRequest(r *http.Request, p *models.Pagination, total int) pageRequest {
+	query := r.URL.Query()
+
+	limit := p.DefaultLimit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if n, err := strconv.Atoi(query.Get(paramName(p.LimitParam, "limit"))); err == nil && n > 0 {
+		limit = n
+	}
+	if p.MaxLimit > 0 && limit > p.MaxLimit {
+		limit = p.MaxLimit
+	}
+
+	offset := 0
+	switch p.Mode {
+	case models.PaginationPage:
+		if n, err := strconv.Atoi(query.Get(paramName(p.PageParam, "page"))); err == nil && n > 1 {
+			if n-1 > total/limit {
+				offset = total
+			} else {
+				offset = (n - 1) * limit
+			}
+		}
+	case models.PaginationOffset:
+		if n, err := strconv.Atoi(query.Get(paramName(p.OffsetParam, "offset"))); err == nil && n > 0 {
+			offset = n
+		}
+	case models.PaginationCursor:
+		offset = decodeCursor(query.Get(paramName(p.CursorParam, "cursor")))
+	}
+	return pageRequest{offset: min(offset, total), limit: limit}
+}
+
+// pageCount returns the number of pages of size limit needed for total items
+func pageCount(total, limit int) int {
+	pages := total / limit
+	if total%limit != 0 {
+		pages++
+	}
+	return pages
+}
+
+// pageLinks returns the URLs of the first, previous, next and last pages; missing pages are ""
+// Cursor paginations have no last page
+func pageLinks(r *http.Request, p *models.Pagination, req pageRequest, total int) map[string]string {
+	links := make(map[string]string, 4)
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = (total - 1) / req.limit * req.limit
+	}
+	hasPrev := req.offset > 0
+	hasNext := req.end(total) < total
+	prevOffset := max(min(req.offset-req.limit, lastOffset), 0)
+
+	switch p.Mode {
+	case models.PaginationPage:
+		param := paramName(p.PageParam, "page")
+		number := func(offset int) string { return strconv.Itoa(offset/req.limit + 1) }
+		links["first"] = pageURL(r, param, "1")
+		links["last"] = pageURL(r, param, number(lastOffset))
+		if hasPrev {
+			links["prev"] = pageURL(r, param, number(prevOffset))
+		}
+		if hasNext {
+			links["next"] = pageURL(r, param, number(req.end(total)))
+		}
+	case models.PaginationOffset:
+		param := paramName(p.OffsetParam, "offset")
+		links["first"] = pageURL(r, param, "0")
+		links["last"] = pageURL(r, param, strconv.Itoa(lastOffset))
+		if hasPrev {
+			links["prev"] = pageURL(r, param, strconv.Itoa(prevOffset))
+		}
+		if hasNext {
+			links["next"] = pageURL(r, param, strconv.Itoa(req.end(total)))
+		}
+	case models.PaginationCursor:
+		param := paramName(p.CursorParam, "cursor")
+		links["first"] = pageURL(r, param, "")
+		if hasPrev {
+			links["prev"] = pageURL(r, param, encodeCursor(max(req.offset-req.limit, 0)))
+		}
+		if hasNext {
+			links["next"] = pageURL(r, param, encodeCursor(req.end(total)))
+		}
+	}
+	return links
+}
+
+// pageURL returns the absolute URL of the request with a query parameter replaced
+// An empty value removes the parameter
+func pageURL(r *http.Request, param, value string) string {
+	query := r.URL.Query()
+	if value == "" {
+		query.Del(param)
+	} else {
+		query.Set(param, value)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// paramName returns the configured name of a query parameter or its default
+func paramName(configured, fallback string) string {
+	if configured != "" {
+		return configured
+	}
+	return fallback
+}
+
+// encodeCursor returns the opaque cursor of an offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset of a cursor; missing or invalid cursors start at 0
+func decodeCursor(cursor string) int {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(string(data), "offset:"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_PaginationPage(t *testing.T) {
+	engine := setupSpecEngine(t, nil, nil, &models.ResponseConfig{Pagination: &models.Pagination{
+		Mode:         models.PaginationPage,
+		Generate:     5,
+		ItemTemplate: `{"id": {{item.number}}}`,
+		DefaultLimit: 2,
+	}})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/api/users?page=2&sort=name", nil))
+
+	if body := w.Body.String(); body != `[{"id":3},{"id":4}]` {
+		t.Errorf("Unexpected page: %s", body)
+	}
+	if total := w.Header().Get("X-Total-Count"); total != "5" {
+		t.Errorf("Expected X-Total-Count 5, got %q", total)
+	}
+	link := w.Header().Get("Link")
+	for _, want := range []string{
+		`<http://example.com/api/users?page=1&sort=name>; rel="first"`,
+		`<http://example.com/api/users?page=1&sort=name>; rel="prev"`,
+		`<http://example.com/api/users?page=3&sort=name>; rel="next"`,
+		`<http://example.com/api/users?page=3&sort=name>; rel="last"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("Expected Link to contain %s, got %s", want, link)
+		}
+	}
+
+	// The last page has no next link
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/api/users?page=3", nil))
+	if w.Body.String() != `[{"id":5}]` || strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("Unexpected last page: %s %s", w.Body.String(), w.Header().Get("Link"))
+	}
+}
+
+func TestServeHTTP_PaginationOffset(t *testing.T) {
+	engine := setupSpecEngine(t, nil, nil, &models.ResponseConfig{
+		Body: `{"data": {{page.items}}, "total": {{page.total}}, "next": "{{page.next}}"}`,
+		Pagination: &models.Pagination{
+			Mode:     models.PaginationOffset,
+			Dataset:  `["a", "b", "c", "d"]`,
+			MaxLimit: 3,
+		},
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/api/users?offset=1&limit=50", nil))
+
+	want := `{"data": ["b","c","d"], "total": 4, "next": ""}`
+	if body := w.Body.String(); body != want {
+		t.Errorf("Expected %s, got %s", want, body)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `<http://example.com/api/users?limit=50&offset=0>; rel="prev"`) {
+		t.Errorf("Unexpected Link: %s", link)
+	}
+}
+
+func TestServeHTTP_PaginationOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		mode  string
+		query string
+	}{
+		{models.PaginationOffset, "offset=9223372036854775807"},
+		{models.PaginationOffset, "offset=3&limit=9223372036854775807"},
+		{models.PaginationPage, "page=9223372036854775807"},
+		{models.PaginationPage, "page=4611686018427387905&limit=2"},
+		{models.PaginationCursor, "cursor=" + encodeCursor(9223372036854775807)},
+	} {
+		engine := setupSpecEngine(t, nil, nil, &models.ResponseConfig{
+			Body: `{{page.items}} {{page.total}} {{page.pages}}`,
+			Pagination: &models.Pagination{
+				Mode:    tc.mode,
+				Dataset: `["a", "b", "c", "d"]`,
+			},
+		})
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/api/users?"+tc.query, nil))
+		if w.Code != 200 {
+			t.Errorf("%s: expected 200, got %d", tc.query, w.Code)
+		}
+		if body := w.Body.String(); !strings.HasPrefix(body, "[") || !strings.Contains(body, " 4 ") {
+			t.Errorf("%s: unexpected body %s", tc.query, body)
+		}
+		if strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+			t.Errorf("%s: expected no next link, got %s", tc.query, w.Header().Get("Link"))
+		}
+	}
+}
+
+func TestServeHTTP_PaginationCursor(t *testing.T) {
+	engine := setupSpecEngine(t, nil, nil, &models.ResponseConfig{
+		Body: `{"items": {{page.items}}, "cursor": "{{page.nextCursor}}"}`,
+		Pagination: &models.Pagination{
+			Mode:         models.PaginationCursor,
+			Generate:     3,
+			ItemTemplate: `item-{{item.index}}`,
+			DefaultLimit: 2,
+		},
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if !strings.HasPrefix(w.Body.String(), `{"items": ["item-0","item-1"], "cursor": "`) {
+		t.Fatalf("Unexpected first page: %s", w.Body.String())
+	}
+
+	cursor := encodeCursor(2)
+	if !strings.Contains(w.Body.String(), cursor) {
+		t.Fatalf("Expected next cursor %s in %s", cursor, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users?cursor="+cursor, nil))
+	if body := w.Body.String(); body != `{"items": ["item-2"], "cursor": ""}` {
+		t.Errorf("Unexpected second page: %s", body)
+	}
+
+	// Invalid cursors start from the beginning
+	if decodeCursor("not a cursor") != 0 {
+		t.Error("Expected an invalid cursor to decode to 0")
+	}
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SetBasePathPrefixes restricts spec base paths to the given prefixes, e.g. "/team-a"
+// With no prefixes, or a "/" prefix, any base path is allowed
+// Routes are reloaded when the prefixes change, dropping specs outside the new ones
+func (e *Engine) SetBasePathPrefixes(prefixes []string) {
+	normalized := normalizePrefixes(prefixes)
+
+	e.mu.Lock()
+	changed := !slices.Equal(e.basePathPrefixes, normalized)
+	e.basePathPrefixes = normalized
+	e.mu.Unlock()
+
+	if changed {
+		e.ReloadRoutes()
+	}
+}
+
+// BasePathPrefixes returns the prefixes spec base paths are restricted to
+func (e *Engine) BasePathPrefixes() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]string(nil), e.basePathPrefixes...)
+}
+
+// CheckBasePath returns an error if a spec base path is outside the allowed prefixes
+// A prefix matches whole path segments: "/team-a" allows "/team-a/users" but not "/team-ab"
+func (e *Engine) CheckBasePath(basePath string) error {
+	return checkBasePath(e.BasePathPrefixes(), basePath)
+}
+
+// CheckBasePathPrefixes returns an error if a spec base path is outside the given prefixes,
+// for tools that write specs without a running engine
+func CheckBasePathPrefixes(prefixes []string, basePath string) error {
+	return checkBasePath(normalizePrefixes(prefixes), basePath)
+}
+
+// checkBasePath checks a base path against normalized prefixes
+func checkBasePath(prefixes []string, basePath string) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	basePath = normalizePrefix(basePath)
+	for _, prefix := range prefixes {
+		if prefix == "" || basePath == prefix || strings.HasPrefix(basePath, prefix+"/") {
+			return nil
+		}
+	}
+	if basePath == "" {
+		basePath = "/"
+	}
+	return fmt.Errorf("base path %q must be under one of %s", basePath, strings.Join(prefixes, ", "))
+}
+
+// normalizePrefixes normalizes each of a list of prefixes
+func normalizePrefixes(prefixes []string) []string {
+	normalized := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		normalized[i] = normalizePrefix(prefix)
+	}
+	return normalized
+}
+
+// normalizePrefix adds a leading slash and removes trailing ones; "/" becomes ""
+func normalizePrefix(p string) string {
+	p = strings.TrimRight(strings.TrimSpace(p), "/")
+	if p != "" && !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestCheckBasePath(t *testing.T) {
+	engine, _ := setupTestEngine(t)
+
+	// Without prefixes any base path is allowed
+	if err := engine.CheckBasePath("/anything"); err != nil {
+		t.Errorf("Expected no restriction without prefixes, got %v", err)
+	}
+
+	engine.SetBasePathPrefixes([]string{"/team-a/", "team-b"})
+
+	tests := []struct {
+		basePath string
+		allowed  bool
+	}{
+		{"/team-a", true},
+		{"/team-a/orders", true},
+		{"team-b/users/", true},
+		{"/team-ab", false},
+		{"/team-c/orders", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := engine.CheckBasePath(tt.basePath)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckBasePath(%q): expected allowed=%v, got %v", tt.basePath, tt.allowed, err)
+		}
+	}
+
+	// A "/" prefix allows everything
+	engine.SetBasePathPrefixes([]string{"/"})
+	if err := engine.CheckBasePath("/team-c"); err != nil {
+		t.Errorf("Expected \"/\" to allow any base path, got %v", err)
+	}
+}
+
+func TestReloadRoutes_SkipsSpecsOutsidePrefixes(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-a", Name: "A", BasePath: "/team-a", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-a", SpecID: "spec-a", Method: "GET", Path: "/users", FullPath: "/team-a/users"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-a", OperationID: "op-a", StatusCode: 200, Enabled: true})
+	store.CreateSpec(&models.Spec{ID: "spec-c", Name: "C", BasePath: "/team-c", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-c", SpecID: "spec-c", Method: "GET", Path: "/users", FullPath: "/team-c/users"})
+	engine.ReloadRoutes()
+
+	// Restricting the prefixes drops the routes of specs stored earlier outside them
+	engine.SetBasePathPrefixes([]string{"/team-a"})
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+	if code := serve("/team-a/users"); code != http.StatusOK {
+		t.Error("Expected the spec under an allowed prefix to be served")
+	}
+	if code := serve("/team-c/users"); code != http.StatusNotFound {
+		t.Errorf("Expected the spec outside the prefixes to be skipped, got %d", code)
+	}
+}
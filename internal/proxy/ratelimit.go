@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// rateWindow counts the requests of one client of an operation in the current window
+type rateWindow struct {
+	start time.Time
+	end   time.Time // Start plus the window length of the policy that opened it
+	count int
+}
+
+// rateLimiter keeps the fixed windows of operations with a rate limit policy
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow // by operation ID and client key
+	pruned  time.Time
+}
+
+// rateState is the outcome of counting a request against a policy
+type rateState struct {
+	allowed   bool
+	limit     int
+	remaining int
+	reset     time.Time // End of the current window
+}
+
+// ResetRateLimit forgets the request counts of an operation, starting fresh windows
+func (e *Engine) ResetRateLimit(operationID string) {
+	e.rateLimits.mu.Lock()
+	defer e.rateLimits.mu.Unlock()
+
+	for key := range e.rateLimits.windows {
+		if strings.HasPrefix(key, operationID+"\x00") {
+			delete(e.rateLimits.windows, key)
+		}
+	}
+}
+
+// take counts a request of a client against an operation's policy
+func (l *rateLimiter) take(operationID, client string, policy *models.RateLimitPolicy, now time.Time) rateState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window := time.Duration(policy.Window) * time.Second
+	if l.windows == nil {
+		l.windows = make(map[string]*rateWindow)
+	}
+	l.prune(now, window)
+
+	key := operationID + "\x00" + client
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= window {
+		w = &rateWindow{start: now, end: now.Add(window)}
+		l.windows[key] = w
+	}
+
+	state := rateState{limit: policy.Limit, reset: w.start.Add(window)}
+	if w.count >= policy.Limit {
+		return state
+	}
+	w.count++
+	state.allowed = true
+	state.remaining = policy.Limit - w.count
+	return state
+}
+
+// prune drops windows that ended, at most once per window length of the calling policy
+// Each window expires by its own end, so operations with longer windows keep their counts
+// Must be called with l.mu held
+func (l *rateLimiter) prune(now time.Time, window time.Duration) {
+	if now.Sub(l.pruned) < window {
+		return
+	}
+	l.pruned = now
+	for key, w := range l.windows {
+		if !now.Before(w.end) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// rateLimitClient returns the key a request is counted under for a policy
+func rateLimitClient(r *http.Request, policy *models.RateLimitPolicy) string {
+	switch {
+	case policy.Key == "ip":
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	case strings.HasPrefix(policy.Key, "header:"):
+		return r.Header.Get(strings.TrimPrefix(policy.Key, "header:"))
+	}
+	return ""
+}
+
+// templateVars exposes the state to templates as {{rateLimit.limit}}, {{rateLimit.remaining}},
+// {{rateLimit.reset}} (seconds until the window ends) and {{rateLimit.resetAt}} (Unix time)
+func (s rateState) templateVars(now time.Time) map[string]string {
+	return map[string]string{
+		"limit":     strconv.Itoa(s.limit),
+		"remaining": strconv.Itoa(s.remaining),
+		"reset":     strconv.Itoa(s.retryAfter(now)),
+		"resetAt":   strconv.FormatInt(s.reset.Unix(), 10),
+	}
+}
+
+// retryAfter returns the whole seconds until the window ends, at least 1
+func (s rateState) retryAfter(now time.Time) int {
+	seconds := int((s.reset.Sub(now) + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// setHeaders adds the conventional X-RateLimit headers to a response
+func (s rateState) setHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+}
+
+// defaultThrottledResponse is used when a policy doesn't configure its own
+var defaultThrottledResponse = models.FallbackResponse{
+	StatusCode: http.StatusTooManyRequests,
+	Headers:    map[string]string{"Retry-After": "{{rateLimit.reset}}"},
+	Body:       `{"error": "Too many requests"}`,
+}
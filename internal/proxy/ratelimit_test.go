@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_RateLimit(t *testing.T) {
+	engine := setupSpecEngine(t, nil, []*models.Operation{{RateLimit: &models.RateLimitPolicy{Limit: 2, Window: 60, Headers: true}}}, &models.ResponseConfig{Body: `{"remaining": {{rateLimit.remaining}}}`})
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusOK || w.Body.String() != `{"remaining": 1}` || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("Unexpected first response: %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+	get()
+
+	w := get()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", retry)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected rate limit headers: %v", w.Header())
+	}
+
+	engine.ResetRateLimit("op-1")
+	if w := get(); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after a reset, got %d", w.Code)
+	}
+}
+
+func TestServeHTTP_RateLimitPerKey(t *testing.T) {
+	engine := setupSpecEngine(t, nil, []*models.Operation{{RateLimit: &models.RateLimitPolicy{
+		Limit:    1,
+		Window:   60,
+		Key:      "header:X-API-Key",
+		Response: &models.FallbackResponse{StatusCode: 503, Body: `{"retryIn": {{rateLimit.reset}}}`},
+	}}}, &models.ResponseConfig{})
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	get("a")
+	if w := get("b"); w.Code != http.StatusOK {
+		t.Errorf("Expected clients to be counted separately, got %d", w.Code)
+	}
+	w := get("a")
+	if w.Code != 503 || w.Body.String() != `{"retryIn": 60}` {
+		t.Errorf("Expected the configured throttled response, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRateLimiter_WindowReset(t *testing.T) {
+	var limiter rateLimiter
+	policy := &models.RateLimitPolicy{Limit: 1, Window: 10}
+	now := time.Now()
+
+	if !limiter.take("op-1", "", policy, now).allowed {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	state := limiter.take("op-1", "", policy, now.Add(4*time.Second))
+	if state.allowed || state.retryAfter(now.Add(4*time.Second)) != 6 {
+		t.Errorf("Expected a throttled request retrying in 6s, got %+v", state)
+	}
+	if !limiter.take("op-1", "", policy, now.Add(10*time.Second)).allowed {
+		t.Error("Expected a new window to allow requests again")
+	}
+}
+
+func TestRateLimiter_PruneKeepsLongerWindows(t *testing.T) {
+	var limiter rateLimiter
+	long := &models.RateLimitPolicy{Limit: 1, Window: 60}
+	short := &models.RateLimitPolicy{Limit: 1, Window: 1}
+	now := time.Now()
+
+	if !limiter.take("op-long", "", long, now).allowed {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	// A request to the short window operation prunes after its own window ended
+	limiter.take("op-short", "", short, now.Add(2*time.Second))
+
+	if limiter.take("op-long", "", long, now.Add(3*time.Second)).allowed {
+		t.Error("Expected the 60s window to still throttle after pruning with a 1s window")
+	}
+	limiter.take("op-short", "", short, now.Add(5*time.Second))
+	if _, ok := limiter.windows["op-short\x00"]; !ok {
+		t.Error("Expected the current short window to be kept")
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/template"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// requestRewrite returns the transformations of requests a spec forwards, or nil
+func requestRewrite(spec *models.Spec) *models.MessageRewrite {
+	if spec.UpstreamRewrite == nil {
+		return nil
+	}
+	return spec.UpstreamRewrite.Request
+}
+
+// responseRewrite returns the transformations of the upstream responses of a spec, or nil
+func responseRewrite(spec *models.Spec) *models.MessageRewrite {
+	if spec.UpstreamRewrite == nil {
+		return nil
+	}
+	return spec.UpstreamRewrite.Response
+}
+
+// rewriteHeaders removes and then sets the headers of a forwarded message
+func (e *Engine) rewriteHeaders(h http.Header, rules *models.MessageRewrite, ctx *template.Context) {
+	for _, name := range rules.RemoveHeaders {
+		h.Del(name)
+	}
+	for name, value := range e.templateEngine.ProcessHeaders(rules.SetHeaders, ctx) {
+		h.Set(name, value)
+	}
+}
+
+// rewritesBody reports whether rules change the body of messages with the given headers
+// Only uncompressed bodies can be rewritten
+func rewritesBody(rules *models.MessageRewrite, h http.Header) bool {
+	if rules == nil || (len(rules.SetBody) == 0 && len(rules.RemoveBody) == 0) {
+		return false
+	}
+	encoding := h.Get("Content-Encoding")
+	return encoding == "" || encoding == "identity"
+}
+
+// rewriteBody removes and then sets fields of a JSON body; other bodies are returned unchanged
+// Rendered values that are valid JSON are set as is, anything else as a string
+func (e *Engine) rewriteBody(body string, rules *models.MessageRewrite, ctx *template.Context) string {
+	if !gjson.Valid(body) {
+		return body
+	}
+	for _, path := range rules.RemoveBody {
+		if updated, err := sjson.Delete(body, path); err == nil {
+			body = updated
+		}
+	}
+
+	paths := make([]string, 0, len(rules.SetBody))
+	for path := range rules.SetBody {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		value := e.templateEngine.Process(rules.SetBody[path], ctx)
+		var updated string
+		var err error
+		if gjson.Valid(value) {
+			updated, err = sjson.SetRaw(body, path, value)
+		} else {
+			updated, err = sjson.Set(body, path, value)
+		}
+		if err == nil {
+			body = updated
+		}
+	}
+	return body
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"log/slog"
+
+	"github.com/prasenjit/go-virtual/internal/storage"
+	"github.com/prasenjit/go-virtual/internal/template"
+)
+
+// operationSequence is the {{sequence}} counter of an operation, kept by the storage
+type operationSequence struct {
+	store storage.Sequencer
+	opID  string
+}
+
+// sequence returns the counter of an operation, or nil if the storage keeps no counters
+func (e *Engine) sequence(opID string) template.Sequence {
+	store, ok := e.store.(storage.Sequencer)
+	if !ok {
+		return nil
+	}
+	return &operationSequence{store: store, opID: opID}
+}
+
+// Next increments the counter; values the storage failed to persist are still used
+func (s *operationSequence) Next() int64 {
+	value, err := s.store.NextSequence(s.opID)
+	if err != nil {
+		slog.Warn("failed to persist sequence", "operationId", s.opID, "error", err)
+	}
+	return value
+}
+
+// Reset starts the counter over
+func (s *operationSequence) Reset() {
+	if err := s.store.ResetSequence(s.opID); err != nil {
+		slog.Warn("failed to reset sequence", "operationId", s.opID, "error", err)
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/template"
+)
+
+// Envelope namespaces of the SOAP versions
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// soap12Codes translates SOAP 1.1 fault codes to their SOAP 1.2 names
+var soap12Codes = map[string]string{"Client": "Sender", "Server": "Receiver"}
+
+// soapFault renders the envelope of a SOAP fault and sets its content type, leaving headers of
+// the response config to override it. The reason and detail are rendered as templates
+func (e *Engine) soapFault(w http.ResponseWriter, f *models.SOAPFault, ctx *template.Context) string {
+	reason := e.templateEngine.Process(f.String, ctx)
+	detail := e.templateEngine.Process(f.Detail, ctx)
+
+	if f.Version == models.SOAP12 {
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		return soap12Fault(f.Code, reason, detail)
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	return soap11Fault(f.Code, reason, detail)
+}
+
+// soap11Fault renders a SOAP 1.1 Fault envelope
+func soap11Fault(code, reason, detail string) string {
+	switch code {
+	case "":
+		code = "Server"
+	case "Sender":
+		code = "Client"
+	case "Receiver":
+		code = "Server"
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<soap:Envelope xmlns:soap="` + soap11Namespace + `">`)
+	b.WriteString("<soap:Body><soap:Fault>")
+	b.WriteString("<faultcode>soap:" + code + "</faultcode>")
+	b.WriteString("<faultstring>" + escapeXML(reason) + "</faultstring>")
+	if detail != "" {
+		b.WriteString("<detail>" + xmlContent(detail) + "</detail>")
+	}
+	b.WriteString("</soap:Fault></soap:Body></soap:Envelope>")
+	return b.String()
+}
+
+// soap12Fault renders a SOAP 1.2 Fault envelope
+func soap12Fault(code, reason, detail string) string {
+	if code == "" {
+		code = "Receiver"
+	}
+	if translated, ok := soap12Codes[code]; ok {
+		code = translated
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<env:Envelope xmlns:env="` + soap12Namespace + `">`)
+	b.WriteString("<env:Body><env:Fault>")
+	b.WriteString("<env:Code><env:Value>env:" + code + "</env:Value></env:Code>")
+	b.WriteString(`<env:Reason><env:Text xml:lang="en">` + escapeXML(reason) + "</env:Text></env:Reason>")
+	if detail != "" {
+		b.WriteString("<env:Detail>" + xmlContent(detail) + "</env:Detail>")
+	}
+	b.WriteString("</env:Fault></env:Body></env:Envelope>")
+	return b.String()
+}
+
+// xmlContent returns well-formed XML content as is and escapes anything else as text, so a
+// detail can hold either elements or a plain message
+func xmlContent(content string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<detail>" + content + "</detail>"))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return content
+		}
+		if err != nil {
+			return escapeXML(content)
+		}
+	}
+}
+
+// escapeXML escapes text for XML character data
+func escapeXML(text string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
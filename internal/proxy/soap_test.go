@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func serveSOAPFault(t *testing.T, fault *models.SOAPFault, headers map[string]string) *httptest.ResponseRecorder {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Orders", BasePath: "/ws", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "POST", Path: "/orders/{id}", FullPath: "/ws/orders/{id}"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 500, Headers: headers, Body: "ignored", SOAPFault: fault, Enabled: true})
+	engine.ReloadRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/ws/orders/<7>", nil))
+	if w.Code != 500 {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), new(struct{})); err != nil {
+		t.Fatalf("Expected a well-formed envelope, got %v: %s", err, w.Body.String())
+	}
+	return w
+}
+
+func TestServeHTTP_SOAP11Fault(t *testing.T) {
+	w := serveSOAPFault(t, &models.SOAPFault{
+		Code:   "Sender",
+		String: "Order {{path.id}} not found",
+		Detail: `<err:code xmlns:err="urn:orders">404</err:code>`,
+	}, nil)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/xml; charset=utf-8" {
+		t.Errorf("Expected text/xml, got %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">`,
+		`<faultcode>soap:Client</faultcode>`,
+		`<faultstring>Order &lt;7&gt; not found</faultstring>`,
+		`<detail><err:code xmlns:err="urn:orders">404</err:code></detail>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the envelope to contain %s, got %s", want, body)
+		}
+	}
+}
+
+func TestServeHTTP_SOAP12Fault(t *testing.T) {
+	w := serveSOAPFault(t, &models.SOAPFault{
+		Version: models.SOAP12,
+		String:  "Database unavailable",
+		Detail:  "retry < 5s",
+	}, map[string]string{"Content-Type": "application/soap+xml; charset=utf-8; action=\"urn:GetOrder\""})
+
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, `action="urn:GetOrder"`) {
+		t.Errorf("Expected the configured content type, got %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">`,
+		`<env:Code><env:Value>env:Receiver</env:Value></env:Code>`,
+		`<env:Text xml:lang="en">Database unavailable</env:Text>`,
+		`<env:Detail>retry &lt; 5s</env:Detail>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the envelope to contain %s, got %s", want, body)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serveSpecDocument answers requests for the documents of enabled specs under their base path:
+// openapi.json and openapi.yaml serve the OpenAPI content, asyncapi.json and asyncapi.yaml the
+// AsyncAPI document. Documents are converted to the requested format. It reports whether the
+// request was for a document
+func (e *Engine) serveSpecDocument(w http.ResponseWriter, r *http.Request) bool {
+	e.mu.RLock()
+	specs := e.specs
+	e.mu.RUnlock()
+
+	for _, spec := range specs {
+		name, ok := strings.CutPrefix(r.URL.Path, strings.TrimSuffix(spec.BasePath, "/")+"/")
+		if !ok {
+			continue
+		}
+
+		var content string
+		switch name {
+		case "openapi.json", "openapi.yaml":
+			content = spec.Content
+		case "asyncapi.json", "asyncapi.yaml":
+			content = spec.AsyncAPI
+		default:
+			continue
+		}
+		if content == "" {
+			continue
+		}
+
+		doc, contentType, err := convertDocument(content, path.Ext(name))
+		if err != nil {
+			slog.Warn("failed to convert spec document", "specId", spec.ID, "document", name, "error", err)
+			continue
+		}
+		applyCORS(w, r, spec)
+		w.Header().Set("Content-Type", contentType)
+		http.ServeContent(w, r, name, spec.UpdatedAt, strings.NewReader(doc))
+		return true
+	}
+	return false
+}
+
+// convertDocument returns a YAML or JSON document in the format of an extension, with its
+// content type. Documents already in that format are returned unchanged
+func convertDocument(content, ext string) (string, string, error) {
+	isJSON := json.Valid([]byte(content))
+
+	if ext == ".json" {
+		if isJSON {
+			return content, "application/json", nil
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return "", "", err
+		}
+		data, err := json.MarshalIndent(jsonCompatible(doc), "", "  ")
+		if err != nil {
+			return "", "", err
+		}
+		return string(data), "application/json", nil
+	}
+
+	if !isJSON {
+		return content, "application/yaml", nil
+	}
+	// JSON is YAML; dropping the flow style of its nodes keeps the key order in block style
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		return "", "", err
+	}
+	blockStyle(&node)
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", "", err
+	}
+	return b.String(), "application/yaml", nil
+}
+
+// jsonCompatible converts the maps of a decoded YAML document to string keys, since YAML keys
+// like response codes decode as numbers
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = jsonCompatible(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonCompatible(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// blockStyle clears the flow and quoting styles of a node tree
+// The encoder still quotes strings that would otherwise read as other types
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+const specDocYAML = `openapi: 3.0.0
+info:
+  title: Pets
+  version: "1.0"
+paths:
+  /pets:
+    get:
+      responses:
+        200:
+          description: OK
+`
+
+func TestServeHTTP_SpecDocuments(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Pets", BasePath: "/pets-api", Content: specDocYAML, Enabled: true})
+	store.CreateSpec(&models.Spec{ID: "spec-2", Name: "Orders", BasePath: "/orders-api", Content: `{"openapi": "3.0.0", "info": {"title": "Orders", "version": "007"}}`, AsyncAPI: "asyncapi: 3.0.0", Enabled: true})
+	store.CreateSpec(&models.Spec{ID: "spec-3", Name: "Hidden", BasePath: "/hidden", Content: specDocYAML})
+	engine.ReloadRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	// The stored format is served unchanged
+	w := get("/pets-api/openapi.yaml")
+	if w.Code != 200 || w.Body.String() != specDocYAML || w.Header().Get("Content-Type") != "application/yaml" {
+		t.Errorf("Unexpected document: %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// YAML is converted to JSON, with numeric keys as strings
+	w = get("/pets-api/openapi.json")
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON document, got %v: %s", err, w.Body.String())
+	}
+	if _, ok := doc["paths"].(map[string]interface{})["/pets"].(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})["200"]; !ok {
+		t.Errorf("Expected the 200 response in the JSON document, got %s", w.Body.String())
+	}
+
+	// JSON is converted to YAML keeping the key order and string types
+	w = get("/orders-api/openapi.yaml")
+	if want := "openapi: 3.0.0\ninfo:\n  title: Orders\n  version: \"007\"\n"; w.Body.String() != want {
+		t.Errorf("Expected %q, got %q", want, w.Body.String())
+	}
+	if w := get("/orders-api/asyncapi.yaml"); w.Code != 200 || w.Body.String() != "asyncapi: 3.0.0" {
+		t.Errorf("Expected the AsyncAPI document, got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/pets-api/asyncapi.json", "/hidden/openapi.json", "/pets-api/swagger.json"} {
+		if w := get(path); w.Code != 404 {
+			t.Errorf("Expected status 404 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestServeHTTP_SpecDocumentOperationWins(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Pets", Content: specDocYAML, Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/openapi.json", FullPath: "/openapi.json"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, Body: `{"mocked": true}`, Enabled: true})
+	engine.ReloadRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if !strings.Contains(w.Body.String(), "mocked") {
+		t.Errorf("Expected the operation's response, got %s", w.Body.String())
+	}
+
+	// HEAD requests get the headers of the document
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("HEAD", "/openapi.yaml", nil))
+	if w.Code != 200 || w.Body.Len() != 0 || w.Header().Get("Content-Length") != strconv.Itoa(len(specDocYAML)) {
+		t.Errorf("Unexpected HEAD response: %d %q %s", w.Code, w.Header().Get("Content-Length"), w.Body.String())
+	}
+}
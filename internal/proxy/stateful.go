@@ -0,0 +1,572 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/storage"
+)
+
+// item is a resource of a stateful collection
+type item = map[string]interface{}
+
+// stateStore holds the collections of stateful specs
+type stateStore struct {
+	mu      sync.Mutex
+	specs   map[string]*specState  // spec ID -> state
+	seedDir atomic.Pointer[string] // Directory of seed files that aren't spec files; nil for none
+}
+
+// specState is the data of one stateful spec and the seed it was built from
+type specState struct {
+	policy      models.StatefulPolicy
+	seed        map[string][]item
+	collections map[string]*collection
+}
+
+// collection is an ordered list of items with a counter for generated IDs
+type collection struct {
+	items  []item
+	nextID int64
+}
+
+// crudPath is the collection an operation works on
+type crudPath struct {
+	collection  string // e.g. "orders"
+	idParam     string // Path parameter of the item ID; empty for collection operations
+	parent      string // Parent collection of nested paths like /customers/{id}/orders
+	parentParam string // Path parameter of the parent ID
+}
+
+// crudTarget returns the collection an operation works on. The collection is the last static
+// segment of the path; a parameter after it is the item ID and a static segment followed by a
+// parameter before it names the parent. Paths without a static segment have no collection
+func crudTarget(path string) (crudPath, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var target crudPath
+
+	last := len(segments) - 1
+	if isPathParam(segments[last]) {
+		target.idParam = paramKey(segments[last])
+		last--
+	}
+	if last < 0 || segments[last] == "" || isPathParam(segments[last]) {
+		return crudPath{}, false
+	}
+	target.collection = segments[last]
+
+	if last >= 2 && isPathParam(segments[last-1]) && !isPathParam(segments[last-2]) {
+		target.parent = segments[last-2]
+		target.parentParam = paramKey(segments[last-1])
+	}
+	return target, true
+}
+
+// isPathParam reports whether a path segment is a parameter like {id}
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// paramKey returns the name of a path parameter segment
+func paramKey(segment string) string {
+	return strings.Trim(segment, "{}")
+}
+
+// sync builds the state of stateful specs that are new or whose policy changed and drops the
+// state of specs that are no longer stateful. Callers hold e.mu
+func (s *stateStore) sync(specs []*models.Spec, ops map[string][]*models.Operation, load seedLoader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := make(map[string]bool)
+	for _, spec := range specs {
+		if spec.Stateful == nil || !spec.Stateful.Enabled {
+			continue
+		}
+		active[spec.ID] = true
+		if state, ok := s.specs[spec.ID]; ok && reflect.DeepEqual(state.policy, *spec.Stateful) {
+			continue
+		}
+		if s.specs == nil {
+			s.specs = make(map[string]*specState)
+		}
+		state := &specState{policy: *spec.Stateful, seed: buildSeed(spec, ops[spec.ID], load)}
+		state.reset()
+		s.specs[spec.ID] = state
+	}
+
+	for id := range s.specs {
+		if !active[id] {
+			delete(s.specs, id)
+		}
+	}
+}
+
+// seedLoader reads the items of a seed file of a spec
+type seedLoader func(specID, path string) ([]item, error)
+
+// buildSeed collects the initial items of a spec from its examples and seed files
+// Seed files win over examples of the same collection; unreadable files are logged and skipped
+func buildSeed(spec *models.Spec, ops []*models.Operation, load seedLoader) map[string][]item {
+	seed := make(map[string][]item)
+
+	if spec.Stateful.SeedFromExamples {
+		for _, op := range ops {
+			target, ok := crudTarget(op.Path)
+			if !ok || target.idParam != "" || target.parent != "" || op.Method != http.MethodGet || op.ExampleResponse == nil {
+				continue
+			}
+			var items []item
+			if err := decodeJSON([]byte(op.ExampleResponse.Body), &items); err != nil {
+				continue
+			}
+			seed[target.collection] = items
+		}
+	}
+
+	for name, path := range spec.Stateful.Seed {
+		items, err := load(spec.ID, path)
+		if err != nil {
+			slog.Warn("skipping stateful seed file", "specId", spec.ID, "collection", name, "error", err)
+			continue
+		}
+		seed[name] = items
+	}
+	return seed
+}
+
+// SetSeedDir sets the directory seed paths that aren't files of the spec are read from
+// Empty allows only spec files
+func (e *Engine) SetSeedDir(dir string) {
+	if dir == "" {
+		e.state.seedDir.Store(nil)
+		return
+	}
+	e.state.seedDir.Store(&dir)
+}
+
+// LoadSeed reads the items of a seed file of a spec. The path names a file stored with the spec
+// or, when a seed directory is configured, a relative path inside it; absolute paths and ".."
+// are rejected so policies can't read arbitrary server files
+func (e *Engine) LoadSeed(specID, path string) ([]map[string]interface{}, error) {
+	if !filepath.IsLocal(path) || slices.Contains(strings.FieldsFunc(path, isPathSeparator), "..") {
+		return nil, fmt.Errorf("seed file %s must be a spec file or a relative path inside the seed directory", strconv.Quote(path))
+	}
+
+	if storage.ValidSpecFileName(path) {
+		if file, _, err := e.store.OpenSpecFile(specID, path); err == nil {
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read seed file: %w", err)
+			}
+			return parseSeed(path, data)
+		}
+	}
+
+	dir := e.state.seedDir.Load()
+	if dir == nil {
+		return nil, fmt.Errorf("spec has no file %s and no stateful.seedDir is configured", strconv.Quote(path))
+	}
+	// OpenInRoot also keeps symbolic links from leaving the directory
+	file, err := os.OpenInRoot(*dir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+	return parseSeed(path, data)
+}
+
+// isPathSeparator reports whether a rune separates path elements on any platform
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+// parseSeed decodes a seed file: a JSON array of objects or a CSV file whose header row names
+// the fields. CSV numbers and booleans become JSON numbers and booleans
+func parseSeed(path string, data []byte) ([]item, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		var items []item
+		if err := decodeJSON(data, &items); err != nil {
+			return nil, fmt.Errorf("seed file %s is not a JSON array of objects: %w", path, err)
+		}
+		return items, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV in %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("seed file %s has no header row", path)
+	}
+	items := make([]item, 0, len(records)-1)
+	for _, record := range records[1:] {
+		it := make(item, len(record))
+		for i, field := range records[0] {
+			it[field] = csvValue(record[i])
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// csvValue converts a CSV cell to the JSON value it looks like
+func csvValue(cell string) interface{} {
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return json.Number(cell)
+	}
+	if b, err := strconv.ParseBool(cell); err == nil && (cell == "true" || cell == "false") {
+		return b
+	}
+	return cell
+}
+
+// decodeJSON decodes JSON keeping numbers as json.Number, so IDs compare and print unchanged
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// reset replaces the collections with copies of the seed
+func (s *specState) reset() {
+	s.collections = make(map[string]*collection, len(s.seed))
+	for name, items := range s.seed {
+		c := &collection{items: make([]item, 0, len(items))}
+		for _, it := range items {
+			c.add(copyItem(it), s.idField())
+		}
+		s.collections[name] = c
+	}
+}
+
+// idField returns the field identifying items
+func (s *specState) idField() string {
+	if s.policy.IDField != "" {
+		return s.policy.IDField
+	}
+	return models.DefaultStatefulIDField
+}
+
+// collection returns a collection, creating it when it doesn't exist yet
+func (s *specState) collection(name string) *collection {
+	c, ok := s.collections[name]
+	if !ok {
+		c = &collection{}
+		s.collections[name] = c
+	}
+	return c
+}
+
+// add appends an item, assigning the next numeric ID when it has none
+func (c *collection) add(it item, idField string) {
+	if _, ok := it[idField]; !ok {
+		c.nextID++
+		it[idField] = c.nextID
+	} else if n, err := strconv.ParseInt(itemID(it, idField), 10, 64); err == nil && n > c.nextID {
+		c.nextID = n
+	}
+	c.items = append(c.items, it)
+}
+
+// find returns the index of the item with an ID, or -1
+func (c *collection) find(id, idField string) int {
+	for i, it := range c.items {
+		if itemID(it, idField) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemID returns the ID of an item as it appears in paths
+func itemID(it item, idField string) string {
+	if id, ok := it[idField]; ok && id != nil {
+		return fmt.Sprint(id)
+	}
+	return ""
+}
+
+// copyItem returns a deep copy of an item, so seeds survive changes to the collections
+func copyItem(it item) item {
+	data, _ := json.Marshal(it)
+	var copied item
+	decodeJSON(data, &copied)
+	return copied
+}
+
+// relation returns the relation linking a collection to a parent collection
+// Nested paths without a configured relation use the singular parent name plus "Id" as the
+// foreign key, e.g. customerId for /customers/{id}/orders
+func (s *specState) relation(collection, parent string) models.StatefulRelation {
+	for _, rel := range s.policy.Relations {
+		if rel.Collection == collection && rel.References == parent {
+			return rel
+		}
+	}
+	return models.StatefulRelation{Collection: collection, Field: singular(parent) + "Id", References: parent}
+}
+
+// singular strips the plural ending of a collection name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses"), strings.HasSuffix(name, "xes"):
+		return strings.TrimSuffix(name, "es")
+	default:
+		return strings.TrimSuffix(name, "s")
+	}
+}
+
+// checkReferences returns an error message when a foreign key of an item names a parent that
+// doesn't exist. Only configured relations are enforced; empty foreign keys are allowed
+func (s *specState) checkReferences(collection string, it item) string {
+	for _, rel := range s.policy.Relations {
+		if rel.Collection != collection {
+			continue
+		}
+		ref := itemID(it, rel.Field)
+		if ref == "" {
+			continue
+		}
+		if s.collection(rel.References).find(ref, s.idField()) < 0 {
+			return rel.Field + " " + ref + " does not reference an existing " + rel.References + " item"
+		}
+	}
+	return ""
+}
+
+// delete removes an item and, for cascading relations, the items referencing it
+func (s *specState) delete(collection string, index int) {
+	c := s.collection(collection)
+	id := itemID(c.items[index], s.idField())
+	c.items = append(c.items[:index], c.items[index+1:]...)
+
+	for _, rel := range s.policy.Relations {
+		if !rel.Cascade || rel.References != collection {
+			continue
+		}
+		children := s.collection(rel.Collection)
+		for i := len(children.items) - 1; i >= 0; i-- {
+			if i < len(children.items) && itemID(children.items[i], rel.Field) == id {
+				s.delete(rel.Collection, i)
+			}
+		}
+	}
+}
+
+// statefulError builds the JSON error response of a stateful request
+func statefulError(statusCode int, message string) (int, interface{}) {
+	return statusCode, map[string]string{"error": message}
+}
+
+// errNotJSONObject is returned for stateful writes whose body isn't a JSON object
+var errNotJSONObject = errors.New("request body must be a JSON object")
+
+// serveStateful answers a CRUD request from the spec's store
+// It returns false for operations that aren't list, create, read, update or delete
+func (e *Engine) serveStateful(w http.ResponseWriter, r *http.Request, matchedRoute *route, pathParams map[string]string, requestBody string) (int, string, bool) {
+	target, ok := crudTarget(matchedRoute.operation.Path)
+	if !ok {
+		return 0, "", false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return 0, "", false
+	}
+	if r.Method == http.MethodPost && target.idParam != "" {
+		return 0, "", false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && target.idParam == "" {
+		return 0, "", false
+	}
+
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+
+	state := e.state.specs[matchedRoute.spec.ID]
+	if state == nil {
+		return 0, "", false
+	}
+	statusCode, response := state.serve(w, r, target, pathParams, requestBody)
+
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return statusCode, "", true
+	}
+	body, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+	return statusCode, string(body), true
+}
+
+// serve applies a CRUD request to the collections and returns the response status and value
+// Nested paths only see the items linked to their parent, which must exist
+func (s *specState) serve(w http.ResponseWriter, r *http.Request, target crudPath, pathParams map[string]string, requestBody string) (int, interface{}) {
+	idField := s.idField()
+	c := s.collection(target.collection)
+
+	// belongs reports whether an item is linked to the parent of a nested path
+	belongs := func(item) bool { return true }
+	var rel models.StatefulRelation
+	var parentID interface{}
+	if target.parent != "" {
+		parents := s.collection(target.parent)
+		id := pathParams[target.parentParam]
+		index := parents.find(id, idField)
+		if index < 0 {
+			return statefulError(http.StatusNotFound, target.parent+" item "+id+" not found")
+		}
+		rel = s.relation(target.collection, target.parent)
+		parentID = parents.items[index][idField]
+		belongs = func(it item) bool { return itemID(it, rel.Field) == id }
+	}
+
+	if target.idParam == "" {
+		switch r.Method {
+		case http.MethodGet:
+			items := make([]item, 0, len(c.items))
+			for _, it := range c.items {
+				if belongs(it) {
+					items = append(items, it)
+				}
+			}
+			return http.StatusOK, items
+		default: // POST
+			it, err := decodeItem(requestBody)
+			if err != nil {
+				return statefulError(http.StatusBadRequest, err.Error())
+			}
+			if parentID != nil {
+				it[rel.Field] = parentID
+			}
+			if id := itemID(it, idField); id != "" && c.find(id, idField) >= 0 {
+				return statefulError(http.StatusConflict, "Item "+id+" already exists")
+			}
+			if msg := s.checkReferences(target.collection, it); msg != "" {
+				return statefulError(http.StatusUnprocessableEntity, msg)
+			}
+			c.add(it, idField)
+			w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+itemID(it, idField))
+			return http.StatusCreated, it
+		}
+	}
+
+	id := pathParams[target.idParam]
+	index := c.find(id, idField)
+	if index < 0 || !belongs(c.items[index]) {
+		return statefulError(http.StatusNotFound, "Item "+id+" not found")
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, c.items[index]
+	case http.MethodDelete:
+		s.delete(target.collection, index)
+		return http.StatusNoContent, nil
+	default: // PUT, PATCH
+		it, err := decodeItem(requestBody)
+		if err != nil {
+			return statefulError(http.StatusBadRequest, err.Error())
+		}
+		updated := it
+		if r.Method == http.MethodPatch {
+			updated = copyItem(c.items[index])
+			for k, v := range it {
+				updated[k] = v
+			}
+		}
+		// Items keep their stored ID and, on nested paths, their parent
+		updated[idField] = c.items[index][idField]
+		if parentID != nil {
+			updated[rel.Field] = parentID
+		}
+		if msg := s.checkReferences(target.collection, updated); msg != "" {
+			return statefulError(http.StatusUnprocessableEntity, msg)
+		}
+		c.items[index] = updated
+		return http.StatusOK, updated
+	}
+}
+
+// decodeItem parses the JSON object of a stateful write
+func decodeItem(body string) (item, error) {
+	var it item
+	if err := decodeJSON([]byte(body), &it); err != nil || it == nil {
+		return nil, errNotJSONObject
+	}
+	return it, nil
+}
+
+// State returns a copy of the collections of a stateful spec, or nil when it isn't stateful
+func (e *Engine) State(specID string) map[string][]map[string]interface{} {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+
+	state := e.state.specs[specID]
+	if state == nil {
+		return nil
+	}
+	collections := make(map[string][]map[string]interface{}, len(state.collections))
+	for name, c := range state.collections {
+		items := make([]map[string]interface{}, 0, len(c.items))
+		for _, it := range c.items {
+			items = append(items, copyItem(it))
+		}
+		collections[name] = items
+	}
+	return collections
+}
+
+// ResetState restores the collections of a stateful spec to its seed, reloading the seed files
+// It reports false when the spec isn't stateful
+func (e *Engine) ResetState(specID string) bool {
+	e.mu.RLock()
+	var spec *models.Spec
+	var ops []*models.Operation
+	for _, routes := range e.routes {
+		for _, rt := range routes {
+			if rt.spec.ID == specID {
+				spec = rt.spec
+				ops = append(ops, rt.operation)
+			}
+		}
+	}
+	e.mu.RUnlock()
+
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+
+	state := e.state.specs[specID]
+	if state == nil {
+		return false
+	}
+	if spec != nil {
+		state.seed = buildSeed(spec, ops, e.LoadSeed)
+	}
+	state.reset()
+	return true
+}
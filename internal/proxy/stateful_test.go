@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func serveStatefulRequest(engine *Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestServeHTTP_Stateful(t *testing.T) {
+	engine := setupSpecEngine(t, &models.Spec{Stateful: &models.StatefulPolicy{Enabled: true, SeedFromExamples: true}}, []*models.Operation{
+		{ExampleResponse: &models.ExampleResponse{StatusCode: 200, Body: `[{"id": 1, "name": "Ann"}, {"id": 2, "name": "Bob"}]`}},
+		{Method: "POST"},
+		{Path: "/users/{userId}"},
+		{Method: "PATCH", Path: "/users/{userId}"},
+		{Method: "DELETE", Path: "/users/{userId}"},
+	})
+
+	if w := serveStatefulRequest(engine, "GET", "/api/users/2", ""); w.Code != http.StatusOK || w.Body.String() != `{"id":2,"name":"Bob"}` {
+		t.Errorf("Unexpected seeded item: %d %s", w.Code, w.Body.String())
+	}
+
+	w := serveStatefulRequest(engine, "POST", "/api/users", `{"name": "Cy"}`)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":3,"name":"Cy"}` || w.Header().Get("Location") != "/api/users/3" {
+		t.Errorf("Unexpected create response: %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := serveStatefulRequest(engine, "POST", "/api/users", `{"id": 1}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate ID, got %d", w.Code)
+	}
+	if w := serveStatefulRequest(engine, "POST", "/api/users", `[]`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a non-object body, got %d", w.Code)
+	}
+
+	if w := serveStatefulRequest(engine, "PATCH", "/api/users/1", `{"id": 9, "name": "Anna"}`); w.Body.String() != `{"id":1,"name":"Anna"}` {
+		t.Errorf("Unexpected patched item: %s", w.Body.String())
+	}
+	if w := serveStatefulRequest(engine, "DELETE", "/api/users/2", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := serveStatefulRequest(engine, "GET", "/api/users/2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a deleted item, got %d", w.Code)
+	}
+	if w := serveStatefulRequest(engine, "GET", "/api/users", ""); w.Body.String() != `[{"id":1,"name":"Anna"},{"id":3,"name":"Cy"}]` {
+		t.Errorf("Unexpected list: %s", w.Body.String())
+	}
+
+	if !engine.ResetState("spec-1") {
+		t.Fatal("Expected the spec to be stateful")
+	}
+	if w := serveStatefulRequest(engine, "GET", "/api/users", ""); w.Body.String() != `[{"id":1,"name":"Ann"},{"id":2,"name":"Bob"}]` {
+		t.Errorf("Expected the seed after a reset, got %s", w.Body.String())
+	}
+}
+
+func TestServeHTTP_StatefulSeedFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "seed"), 0755)
+	os.WriteFile(filepath.Join(dir, "seed", "users.csv"), []byte("id,name,active\nu1,Dee,true\nu2,Eve,false\n"), 0644)
+
+	engine, store := setupTestEngine(t)
+	engine.SetSeedDir(dir)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api", Enabled: true,
+		Stateful: &models.StatefulPolicy{Enabled: true, SeedFromExamples: true, Seed: map[string]string{"users": "seed/users.csv"}}})
+	store.CreateOperation(&models.Operation{ID: "op-list", SpecID: "spec-1", Method: "GET", Path: "/users", FullPath: "/api/users",
+		ExampleResponse: &models.ExampleResponse{StatusCode: 200, Body: `[{"id": 1, "name": "Ann"}]`}})
+	engine.ReloadRoutes()
+
+	if w := serveStatefulRequest(engine, "GET", "/api/users", ""); w.Body.String() != `[{"active":true,"id":"u1","name":"Dee"},{"active":false,"id":"u2","name":"Eve"}]` {
+		t.Errorf("Expected the seed file to win over examples, got %s", w.Body.String())
+	}
+	if state := engine.State("spec-1"); len(state["users"]) != 2 {
+		t.Errorf("Unexpected state: %v", state)
+	}
+	if engine.State("missing") != nil || engine.ResetState("missing") {
+		t.Error("Expected no state for a spec that isn't stateful")
+	}
+}
+
+func TestLoadSeed(t *testing.T) {
+	engine, store := setupTestEngine(t)
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/api"})
+	store.SaveSpecFile("spec-1", "items.json", []byte(`[{"id": 10, "price": 2.5}]`))
+
+	items, err := engine.LoadSeed("spec-1", "items.json")
+	if err != nil {
+		t.Fatalf("LoadSeed failed: %v", err)
+	}
+	if len(items) != 1 || itemID(items[0], "id") != "10" {
+		t.Errorf("Unexpected items: %v", items)
+	}
+
+	store.SaveSpecFile("spec-1", "items.json", []byte(`{"id": 10}`))
+	if _, err := engine.LoadSeed("spec-1", "items.json"); err == nil {
+		t.Error("Expected an error for a JSON object")
+	}
+	if _, err := engine.LoadSeed("spec-1", "missing.csv"); err == nil {
+		t.Error("Expected an error for a missing file without a seed directory")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "items.csv"), []byte("id\n1\n"), 0644)
+	engine.SetSeedDir(filepath.Join(dir, "seed"))
+	os.MkdirAll(filepath.Join(dir, "seed"), 0755)
+	os.Symlink(filepath.Join(dir, "items.csv"), filepath.Join(dir, "seed", "link.csv"))
+	for _, path := range []string{filepath.Join(dir, "items.csv"), "../items.csv", "a/../../items.csv", "link.csv", "/etc/passwd"} {
+		if _, err := engine.LoadSeed("spec-1", path); err == nil {
+			t.Errorf("Expected %s outside the seed directory to be rejected", path)
+		}
+	}
+	engine.SetSeedDir(dir)
+	if items, err := engine.LoadSeed("spec-1", "items.csv"); err != nil || len(items) != 1 {
+		t.Errorf("Expected the seed directory file, got %v %v", items, err)
+	}
+}
+
+func TestCrudTarget(t *testing.T) {
+	tests := []struct {
+		path string
+		want crudPath
+		ok   bool
+	}{
+		{"/users", crudPath{collection: "users"}, true},
+		{"/users/{id}", crudPath{collection: "users", idParam: "id"}, true},
+		{"/customers/{id}/orders", crudPath{collection: "orders", parent: "customers", parentParam: "id"}, true},
+		{"/customers/{cid}/orders/{oid}", crudPath{collection: "orders", idParam: "oid", parent: "customers", parentParam: "cid"}, true},
+		{"/{tenant}", crudPath{}, false},
+		{"/", crudPath{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := crudTarget(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("crudTarget(%q) = %+v, %v", tt.path, got, ok)
+		}
+	}
+}
+
+func TestServeHTTP_StatefulRelations(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "Shop", BasePath: "/shop", Enabled: true, Stateful: &models.StatefulPolicy{
+		Enabled:   true,
+		Relations: []models.StatefulRelation{{Collection: "orders", Field: "customerId", References: "customers", Cascade: true}},
+	}})
+	for i, op := range []struct{ method, path string }{
+		{"POST", "/customers"},
+		{"DELETE", "/customers/{id}"},
+		{"GET", "/orders"},
+		{"POST", "/orders"},
+		{"GET", "/customers/{id}/orders"},
+		{"POST", "/customers/{id}/orders"},
+		{"GET", "/customers/{customerId}/orders/{orderId}"},
+	} {
+		store.CreateOperation(&models.Operation{ID: "op-" + string(rune('a'+i)), SpecID: "spec-1", Method: op.method, Path: op.path, FullPath: "/shop" + op.path})
+	}
+	engine.ReloadRoutes()
+
+	serveStatefulRequest(engine, "POST", "/shop/customers", `{"name": "Ann"}`)
+	serveStatefulRequest(engine, "POST", "/shop/customers", `{"name": "Bob"}`)
+
+	if w := serveStatefulRequest(engine, "POST", "/shop/customers/1/orders", `{"total": 5, "customerId": 2}`); w.Code != http.StatusCreated || w.Body.String() != `{"customerId":1,"id":1,"total":5}` {
+		t.Errorf("Expected the order to be linked to its parent, got %d %s", w.Code, w.Body.String())
+	}
+	serveStatefulRequest(engine, "POST", "/shop/orders", `{"total": 7, "customerId": 2}`)
+	if w := serveStatefulRequest(engine, "POST", "/shop/orders", `{"total": 9, "customerId": 3}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a dangling reference, got %d", w.Code)
+	}
+
+	if w := serveStatefulRequest(engine, "GET", "/shop/customers/2/orders", ""); w.Body.String() != `[{"customerId":2,"id":2,"total":7}]` {
+		t.Errorf("Unexpected nested list: %s", w.Body.String())
+	}
+	if w := serveStatefulRequest(engine, "GET", "/shop/customers/1/orders/2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another customer's order, got %d", w.Code)
+	}
+	if w := serveStatefulRequest(engine, "GET", "/shop/customers/9/orders", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing parent, got %d", w.Code)
+	}
+
+	serveStatefulRequest(engine, "DELETE", "/shop/customers/2", "")
+	if w := serveStatefulRequest(engine, "GET", "/shop/orders", ""); w.Body.String() != `[{"customerId":1,"id":1,"total":5}]` {
+		t.Errorf("Expected orders of the deleted customer to cascade, got %s", w.Body.String())
+	}
+}
+
+func TestStatefulRelationDefaultField(t *testing.T) {
+	state := &specState{}
+	if rel := state.relation("orders", "categories"); rel.Field != "categoryId" {
+		t.Errorf("Expected the categoryId default foreign key, got %q", rel.Field)
+	}
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of the utility endpoints
+const (
+	maxUtilityDelay = time.Minute      // Longest wait of /delay
+	maxUtilityBytes = 10 * 1024 * 1024 // Largest body of /bytes
+)
+
+// utilitySettings enables the built-in utility endpoints below a prefix
+type utilitySettings struct {
+	enabled bool
+	prefix  string
+}
+
+// SetUtilities enables or disables the httpbin-style utility endpoints served below prefix
+// Operations of specs take precedence over them
+func (e *Engine) SetUtilities(enabled bool, prefix string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.utilities = utilitySettings{enabled: enabled, prefix: normalizePrefix(prefix)}
+}
+
+// serveUtility answers requests for the utility endpoints, reporting whether it did
+func (e *Engine) serveUtility(w http.ResponseWriter, r *http.Request, requestBody string) bool {
+	e.mu.RLock()
+	settings := e.utilities
+	e.mu.RUnlock()
+
+	if !settings.enabled {
+		return false
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, settings.prefix+"/")
+	if !ok {
+		return false
+	}
+	name, arg, _ := strings.Cut(rest, "/")
+
+	switch {
+	case name == "status" && arg != "":
+		code, err := strconv.Atoi(arg)
+		if err != nil || code < 200 || code > 599 {
+			writeUtilityError(w, "status code must be between 200 and 599")
+			return true
+		}
+		w.WriteHeader(code)
+	case name == "delay" && arg != "":
+		ms, err := strconv.Atoi(arg)
+		if err != nil || ms < 0 {
+			writeUtilityError(w, "delay must be a number of milliseconds")
+			return true
+		}
+		timer := time.NewTimer(min(time.Duration(ms)*time.Millisecond, maxUtilityDelay))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			writeEcho(w, r, requestBody)
+		case <-r.Context().Done():
+		}
+	case name == "headers" && arg == "":
+		headers := make(map[string]string, len(r.Header))
+		for key, values := range r.Header {
+			headers[key] = strings.Join(values, ", ")
+		}
+		writeUtilityJSON(w, map[string]interface{}{"headers": headers})
+	case name == "ip" && arg == "":
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		writeUtilityJSON(w, map[string]string{"origin": ip})
+	case name == "bytes" && arg != "":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n > maxUtilityBytes {
+			writeUtilityError(w, "bytes must be a number up to "+strconv.Itoa(maxUtilityBytes))
+			return true
+		}
+		data := make([]byte, n)
+		rand.Read(data)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(n))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeUtilityJSON writes a JSON response of a utility endpoint
+func writeUtilityJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeUtilityError rejects an invalid request for a utility endpoint
+func writeUtilityError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestServeHTTP_Utilities(t *testing.T) {
+	engine, store := setupTestEngine(t)
+
+	store.CreateSpec(&models.Spec{ID: "spec-1", Name: "API", BasePath: "/tools", Enabled: true})
+	store.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1", Method: "GET", Path: "/ip", FullPath: "/tools/ip"})
+	store.CreateResponseConfig(&models.ResponseConfig{ID: "resp-1", OperationID: "op-1", StatusCode: 200, Body: `{"mocked": true}`, Enabled: true})
+	engine.ReloadRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.1.2.3:5555"
+		req.Header.Set("X-Probe", "1")
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Disabled by default
+	if w := get("/_util/status/418"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while disabled, got %d", w.Code)
+	}
+
+	engine.SetUtilities(true, "_util/")
+
+	if w := get("/_util/status/418"); w.Code != http.StatusTeapot {
+		t.Errorf("Expected 418, got %d", w.Code)
+	}
+	if w := get("/_util/status/42"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid status, got %d", w.Code)
+	}
+
+	w := get("/_util/headers")
+	var headers struct {
+		Headers map[string]string `json:"headers"`
+	}
+	if json.Unmarshal(w.Body.Bytes(), &headers); headers.Headers["X-Probe"] != "1" {
+		t.Errorf("Expected the request headers, got %s", w.Body.String())
+	}
+
+	if w := get("/_util/ip"); w.Body.String() != `{"origin":"10.1.2.3"}`+"\n" {
+		t.Errorf("Expected the client IP, got %s", w.Body.String())
+	}
+
+	w = get("/_util/bytes/1024")
+	if w.Code != http.StatusOK || w.Body.Len() != 1024 || w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("Expected 1024 bytes, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	start := time.Now()
+	if w := get("/_util/delay/50"); w.Code != http.StatusOK || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("Expected a response after 50ms, got %d after %v", w.Code, time.Since(start))
+	}
+
+	if w := get("/_util/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown endpoint, got %d", w.Code)
+	}
+
+	// Operations take precedence
+	engine.SetUtilities(true, "/tools")
+	if w := get("/tools/ip"); w.Body.String() != `{"mocked": true}` {
+		t.Errorf("Expected the operation's response, got %s", w.Body.String())
+	}
+	if w := get("/tools/status/204"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+}
@@ -0,0 +1,54 @@
+// Package requestid assigns request IDs used to correlate logs, traces and stats
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the header carrying the request ID in requests and responses
+const Header = "X-Request-ID"
+
+// maxLength bounds incoming IDs so clients cannot bloat logs and traces
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates a request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// FromHeader returns the incoming request ID if it is usable, or a new one
+func FromHeader(h http.Header) string {
+	if id := h.Get(Header); valid(id) {
+		return id
+	}
+	return New()
+}
+
+// valid reports whether an incoming ID is non-empty, short and printable ASCII
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a context carrying a request ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of a context, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,35 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(Header, "abc-123")
+	if id := FromHeader(h); id != "abc-123" {
+		t.Errorf("Expected the incoming ID, got %q", id)
+	}
+
+	for _, incoming := range []string{"", "has space", "bad\nline", strings.Repeat("x", 200)} {
+		h.Set(Header, incoming)
+		id := FromHeader(h)
+		if id == incoming || len(id) != 36 {
+			t.Errorf("Expected a generated ID for %q, got %q", incoming, id)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("Expected no ID, got %q", id)
+	}
+
+	ctx := WithID(context.Background(), "abc")
+	if id := FromContext(ctx); id != "abc" {
+		t.Errorf("Expected abc, got %q", id)
+	}
+}
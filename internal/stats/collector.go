@@ -13,6 +13,7 @@ type Collector struct {
 	mu            sync.RWMutex
 	startTime     time.Time
 	operations    map[string]*models.AtomicOperationStat // operationID -> stats
+	responses     map[string]int64                       // response config ID -> requests it matched
 	recentErrors  []models.ErrorStat
 	hourlyStats   map[string]*hourlyCounter // "YYYY-MM-DD-HH" -> counter
 	maxErrors     int
@@ -30,6 +31,7 @@ func NewCollector() *Collector {
 	return &Collector{
 		startTime:      time.Now(),
 		operations:     make(map[string]*models.AtomicOperationStat),
+		responses:      make(map[string]int64),
 		recentErrors:   make([]models.ErrorStat, 0),
 		hourlyStats:    make(map[string]*hourlyCounter),
 		maxErrors:      100,
@@ -93,8 +95,27 @@ func (c *Collector) RecordRequest(specID, operationID, method, path string, dura
 	}
 }
 
-// RecordError records an error
-func (c *Collector) RecordError(specID, operationID, path, method string, statusCode int, err string) {
+// RecordResponseHit records that a response config matched a request
+func (c *Collector) RecordResponseHit(responseID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[responseID]++
+}
+
+// RecordBytes adds the body sizes of a request and its response to an operation's statistics
+// Operations without a recorded request are ignored
+func (c *Collector) RecordBytes(operationID string, bytesIn, bytesOut int64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if opStats, ok := c.operations[operationID]; ok {
+		opStats.BytesIn.Add(bytesIn)
+		opStats.BytesOut.Add(bytesOut)
+	}
+}
+
+// RecordError records an error response, with the ID of the request that caused it
+func (c *Collector) RecordError(specID, operationID, path, method string, statusCode int, err, requestID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -106,6 +127,7 @@ func (c *Collector) RecordError(specID, operationID, path, method string, status
 		Method:      method,
 		StatusCode:  statusCode,
 		Error:       err,
+		RequestID:   requestID,
 	}
 
 	c.recentErrors = append(c.recentErrors, errorStat)
@@ -139,7 +161,7 @@ func (c *Collector) GetGlobalStats(activeSpecs, totalOperations int) *models.Glo
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var totalRequests, totalErrors, totalTimeNs int64
+	var totalRequests, totalErrors, totalTimeNs, totalBytesIn, totalBytesOut int64
 
 	opStats := make([]models.OperationStat, 0, len(c.operations))
 	for _, op := range c.operations {
@@ -148,6 +170,8 @@ func (c *Collector) GetGlobalStats(activeSpecs, totalOperations int) *models.Glo
 		totalRequests += stat.TotalRequests
 		totalErrors += stat.TotalErrors
 		totalTimeNs += op.TotalTimeNs.Load()
+		totalBytesIn += stat.BytesIn
+		totalBytesOut += stat.BytesOut
 	}
 
 	// Sort by total requests (descending)
@@ -161,6 +185,20 @@ func (c *Collector) GetGlobalStats(activeSpecs, totalOperations int) *models.Glo
 		topOps = topOps[:10]
 	}
 
+	// Top 10 operations by bytes in and out
+	topBandwidth := make([]models.OperationStat, 0, len(opStats))
+	for _, stat := range opStats {
+		if stat.BytesIn+stat.BytesOut > 0 {
+			topBandwidth = append(topBandwidth, stat)
+		}
+	}
+	sort.SliceStable(topBandwidth, func(i, j int) bool {
+		return topBandwidth[i].BytesIn+topBandwidth[i].BytesOut > topBandwidth[j].BytesIn+topBandwidth[j].BytesOut
+	})
+	if len(topBandwidth) > 10 {
+		topBandwidth = topBandwidth[:10]
+	}
+
 	// Calculate average response time
 	var avgResponseTimeMs float64
 	if totalRequests > 0 {
@@ -186,7 +224,10 @@ func (c *Collector) GetGlobalStats(activeSpecs, totalOperations int) *models.Glo
 		RequestsPerSecond: requestsPerSecond,
 		StartTime:         c.startTime,
 		Uptime:            formatDuration(time.Since(c.startTime)),
+		TotalBytesIn:      totalBytesIn,
+		TotalBytesOut:     totalBytesOut,
 		TopOperations:     topOps,
+		TopBandwidth:      topBandwidth,
 		RecentErrors:      c.recentErrors,
 		RequestsByHour:    hourlyStats,
 	}
@@ -197,7 +238,7 @@ func (c *Collector) GetSpecStats(specID, specName string) *models.SpecStats {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var totalRequests, totalErrors, totalTimeNs int64
+	var totalRequests, totalErrors, totalTimeNs, totalBytesIn, totalBytesOut int64
 	opStats := make([]models.OperationStat, 0)
 
 	for _, op := range c.operations {
@@ -210,6 +251,8 @@ func (c *Collector) GetSpecStats(specID, specName string) *models.SpecStats {
 		totalRequests += stat.TotalRequests
 		totalErrors += stat.TotalErrors
 		totalTimeNs += op.TotalTimeNs.Load()
+		totalBytesIn += stat.BytesIn
+		totalBytesOut += stat.BytesOut
 	}
 
 	var avgResponseTimeMs float64
@@ -223,6 +266,8 @@ func (c *Collector) GetSpecStats(specID, specName string) *models.SpecStats {
 		TotalRequests:     totalRequests,
 		TotalErrors:       totalErrors,
 		AvgResponseTimeMs: avgResponseTimeMs,
+		TotalBytesIn:      totalBytesIn,
+		TotalBytesOut:     totalBytesOut,
 		Operations:        opStats,
 	}
 }
@@ -269,9 +314,33 @@ func (c *Collector) buildHourlyStats() []models.HourlyStat {
 func (c *Collector) Reset() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.reset()
+}
+
+// ResetSpec removes the statistics of a spec's operations and its recent errors
+func (c *Collector) ResetSpec(specID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, op := range c.operations {
+		if op.SpecID == specID {
+			delete(c.operations, id)
+		}
+	}
+	kept := make([]models.ErrorStat, 0, len(c.recentErrors))
+	for _, e := range c.recentErrors {
+		if e.SpecID != specID {
+			kept = append(kept, e)
+		}
+	}
+	c.recentErrors = kept
+}
 
+// reset resets all statistics; the caller holds the lock
+func (c *Collector) reset() {
 	c.startTime = time.Now()
 	c.operations = make(map[string]*models.AtomicOperationStat)
+	c.responses = make(map[string]int64)
 	c.recentErrors = make([]models.ErrorStat, 0)
 	c.hourlyStats = make(map[string]*hourlyCounter)
 }
@@ -98,7 +98,7 @@ func TestRecordRequest_MinMaxTime(t *testing.T) {
 func TestRecordError(t *testing.T) {
 	c := NewCollector()
 
-	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Internal Server Error")
+	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Internal Server Error", "req-1")
 
 	stats := c.GetGlobalStats(1, 1)
 	if len(stats.RecentErrors) != 1 {
@@ -110,6 +110,9 @@ func TestRecordError(t *testing.T) {
 	if stats.RecentErrors[0].Error != "Internal Server Error" {
 		t.Errorf("Expected error message 'Internal Server Error', got %q", stats.RecentErrors[0].Error)
 	}
+	if stats.RecentErrors[0].RequestID != "req-1" {
+		t.Errorf("Expected request ID req-1, got %q", stats.RecentErrors[0].RequestID)
+	}
 }
 
 func TestRecordError_MaxLimit(t *testing.T) {
@@ -118,7 +121,7 @@ func TestRecordError_MaxLimit(t *testing.T) {
 
 	// Record more than max errors
 	for i := 0; i < 10; i++ {
-		c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error")
+		c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error", "")
 	}
 
 	stats := c.GetGlobalStats(1, 1)
@@ -271,7 +274,7 @@ func TestReset(t *testing.T) {
 
 	// Add some data
 	c.RecordRequest("spec-1", "op-1", "GET", "/users", 100*time.Millisecond, false)
-	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error")
+	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error", "")
 
 	// Verify data exists
 	stats := c.GetGlobalStats(1, 1)
@@ -334,7 +337,7 @@ func TestConcurrentStatsAccess(t *testing.T) {
 
 	go func() {
 		for i := 0; i < 100; i++ {
-			c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error")
+			c.RecordError("spec-1", "op-1", "/users", "GET", 500, "Error", "")
 		}
 		done <- true
 	}()
@@ -386,3 +389,64 @@ func TestFormatDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeSnapshots(t *testing.T) {
+	a := NewCollector()
+	a.RecordRequest("spec-1", "op-1", "GET", "/users", 10*time.Millisecond, false)
+	a.RecordRequest("spec-1", "op-1", "GET", "/users", 30*time.Millisecond, true)
+	a.RecordError("spec-1", "op-1", "/users", "GET", 500, "boom", "req-1")
+
+	b := NewCollector()
+	b.RecordRequest("spec-1", "op-1", "GET", "/users", 5*time.Millisecond, false)
+	b.RecordRequest("spec-1", "op-2", "POST", "/users", 50*time.Millisecond, false)
+
+	merged := Merge([]*Snapshot{a.Snapshot(), b.Snapshot()})
+
+	global := merged.GetGlobalStats(1, 2)
+	if global.TotalRequests != 4 || global.TotalErrors != 1 {
+		t.Errorf("Expected 4 requests and 1 error, got %d and %d", global.TotalRequests, global.TotalErrors)
+	}
+	if len(global.RecentErrors) != 1 || global.RecentErrors[0].RequestID != "req-1" {
+		t.Errorf("Expected merged recent errors, got %+v", global.RecentErrors)
+	}
+	if hour := global.RequestsByHour[len(global.RequestsByHour)-1]; hour.Requests != 4 {
+		t.Errorf("Expected 4 requests in the current hour, got %d", hour.Requests)
+	}
+
+	op := merged.GetOperationStats("op-1")
+	if op.TotalRequests != 3 || op.MinResponseTimeMs != 5 || op.MaxResponseTimeMs != 30 {
+		t.Errorf("Unexpected merged operation stats %+v", op)
+	}
+	if !merged.Snapshot().StartTime.Equal(a.Snapshot().StartTime) {
+		t.Error("Expected the earliest start time")
+	}
+}
+
+func TestRecordBytes(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("spec-1", "op-1", "POST", "/users", time.Millisecond, false)
+	c.RecordBytes("op-1", 100, 2000)
+	c.RecordRequest("spec-1", "op-2", "GET", "/users", time.Millisecond, false)
+	c.RecordBytes("op-2", 0, 50)
+	c.RecordRequest("spec-2", "op-3", "GET", "/orders", time.Millisecond, false)
+	// Operations without a recorded request are ignored
+	c.RecordBytes("op-unknown", 10, 10)
+
+	global := c.GetGlobalStats(2, 3)
+	if global.TotalBytesIn != 100 || global.TotalBytesOut != 2050 {
+		t.Errorf("Expected 100 bytes in and 2050 out, got %d and %d", global.TotalBytesIn, global.TotalBytesOut)
+	}
+	if len(global.TopBandwidth) != 2 || global.TopBandwidth[0].OperationID != "op-1" || global.TopBandwidth[0].BytesOut != 2000 {
+		t.Errorf("Expected op-1 first and op-3 left out, got %+v", global.TopBandwidth)
+	}
+
+	spec := c.GetSpecStats("spec-1", "Users")
+	if spec.TotalBytesIn != 100 || spec.TotalBytesOut != 2050 {
+		t.Errorf("Expected the spec's bytes, got %d in and %d out", spec.TotalBytesIn, spec.TotalBytesOut)
+	}
+
+	merged := Merge([]*Snapshot{c.Snapshot(), c.Snapshot()})
+	if op := merged.GetOperationStats("op-1"); op.BytesIn != 200 || op.BytesOut != 4000 {
+		t.Errorf("Expected merged bytes, got %+v", op)
+	}
+}
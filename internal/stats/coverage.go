@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// GetCoverage reports which of a spec's operations received requests and which of their
+// response configs matched one, with configs in match order
+func (c *Collector) GetCoverage(spec *models.Spec, ops []*models.Operation, configs map[string][]*models.ResponseConfig) *models.SpecCoverage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	coverage := &models.SpecCoverage{
+		SpecID:             spec.ID,
+		SpecName:           spec.Name,
+		Operations:         make([]models.OperationCoverage, 0, len(ops)),
+		UncoveredResponses: make([]models.ResponseCoverage, 0),
+	}
+
+	for _, op := range ops {
+		opCoverage := models.OperationCoverage{
+			OperationID: op.ID,
+			Method:      op.Method,
+			Path:        op.Path,
+			Disabled:    op.Disabled,
+			Responses:   make([]models.ResponseCoverage, 0, len(configs[op.ID])),
+		}
+		if stat, ok := c.operations[op.ID]; ok {
+			opCoverage.Requests = stat.TotalRequests.Load()
+		}
+		opCoverage.Covered = opCoverage.Requests > 0
+		if !op.Disabled {
+			coverage.TotalOperations++
+			if opCoverage.Covered {
+				coverage.CoveredOperations++
+			}
+		}
+
+		for _, cfg := range configs[op.ID] {
+			respCoverage := models.ResponseCoverage{
+				ResponseID:  cfg.ID,
+				OperationID: op.ID,
+				Name:        cfg.Name,
+				Enabled:     cfg.Enabled,
+				Hits:        c.responses[cfg.ID],
+			}
+			opCoverage.Responses = append(opCoverage.Responses, respCoverage)
+			if op.Disabled || !cfg.Enabled {
+				continue
+			}
+			coverage.TotalResponses++
+			if respCoverage.Hits > 0 {
+				coverage.CoveredResponses++
+			} else {
+				coverage.UncoveredResponses = append(coverage.UncoveredResponses, respCoverage)
+			}
+		}
+
+		coverage.Operations = append(coverage.Operations, opCoverage)
+	}
+
+	coverage.OperationCoverage = percentage(coverage.CoveredOperations, coverage.TotalOperations)
+	coverage.ResponseCoverage = percentage(coverage.CoveredResponses, coverage.TotalResponses)
+	return coverage
+}
+
+// percentage returns part of total in percent, or 100 when there is nothing to cover
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestGetCoverage(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	c.RecordResponseHit("resp-1")
+	c.RecordResponseHit("resp-1")
+
+	spec := &models.Spec{ID: "spec-1", Name: "API"}
+	ops := []*models.Operation{
+		{ID: "op-1", Method: "GET", Path: "/users"},
+		{ID: "op-2", Method: "POST", Path: "/users"},
+		{ID: "op-3", Method: "DELETE", Path: "/users", Disabled: true},
+	}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {
+			{ID: "resp-1", Name: "Found", Enabled: true},
+			{ID: "resp-2", Name: "Empty", Enabled: true},
+			{ID: "resp-3", Name: "Off", Enabled: false},
+		},
+		"op-3": {{ID: "resp-4", Name: "Deleted", Enabled: true}},
+	}
+
+	coverage := c.GetCoverage(spec, ops, configs)
+	if coverage.TotalOperations != 2 || coverage.CoveredOperations != 1 || coverage.OperationCoverage != 50 {
+		t.Errorf("Expected 1 of 2 operations covered, got %+v", coverage)
+	}
+	if coverage.TotalResponses != 2 || coverage.CoveredResponses != 1 || coverage.ResponseCoverage != 50 {
+		t.Errorf("Expected 1 of 2 response configs covered, got %+v", coverage)
+	}
+	if len(coverage.UncoveredResponses) != 1 || coverage.UncoveredResponses[0].ResponseID != "resp-2" {
+		t.Errorf("Expected resp-2 uncovered, got %+v", coverage.UncoveredResponses)
+	}
+	op := coverage.Operations[0]
+	if !op.Covered || op.Requests != 2 || len(op.Responses) != 3 || op.Responses[0].Hits != 2 {
+		t.Errorf("Unexpected coverage of op-1: %+v", op)
+	}
+	if coverage.Operations[1].Covered || !coverage.Operations[2].Disabled {
+		t.Errorf("Unexpected coverage of op-2 and op-3: %+v", coverage.Operations[1:])
+	}
+
+	// Nothing to cover counts as fully covered
+	if empty := c.GetCoverage(&models.Spec{ID: "spec-2"}, nil, nil); empty.OperationCoverage != 100 || empty.ResponseCoverage != 100 {
+		t.Errorf("Expected full coverage of an empty spec, got %+v", empty)
+	}
+}
+
+func TestMerge_ResponseHits(t *testing.T) {
+	a, b := NewCollector(), NewCollector()
+	a.RecordResponseHit("resp-1")
+	b.RecordResponseHit("resp-1")
+	b.RecordResponseHit("resp-2")
+
+	merged := Merge([]*Snapshot{a.Snapshot(), b.Snapshot()})
+	if merged.responses["resp-1"] != 2 || merged.responses["resp-2"] != 1 {
+		t.Errorf("Expected summed response hits, got %v", merged.responses)
+	}
+
+	a.Reset()
+	if len(a.responses) != 0 {
+		t.Errorf("Expected reset to clear response hits, got %v", a.responses)
+	}
+}
@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// GetGroupStats rolls up the statistics of the specs of a group, listing the specs in the
+// given order
+func (c *Collector) GetGroupStats(group string, specs []*models.Spec) *models.GroupStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	totals := make(map[string]*specTotals, len(specs))
+	for _, spec := range specs {
+		totals[spec.ID] = &specTotals{}
+	}
+	for _, op := range c.operations {
+		if t, ok := totals[op.SpecID]; ok {
+			t.requests += op.TotalRequests.Load()
+			t.errors += op.TotalErrors.Load()
+			t.timeNs += op.TotalTimeNs.Load()
+			t.bytesIn += op.BytesIn.Load()
+			t.bytesOut += op.BytesOut.Load()
+		}
+	}
+
+	result := &models.GroupStats{
+		Group: group,
+		Specs: make([]models.SpecStatSummary, 0, len(specs)),
+	}
+	var groupTimeNs int64
+	for _, spec := range specs {
+		t := totals[spec.ID]
+		result.Specs = append(result.Specs, models.SpecStatSummary{
+			SpecID:            spec.ID,
+			SpecName:          spec.Name,
+			Enabled:           spec.Enabled,
+			TotalRequests:     t.requests,
+			TotalErrors:       t.errors,
+			AvgResponseTimeMs: averageMs(t.timeNs, t.requests),
+			TotalBytesIn:      t.bytesIn,
+			TotalBytesOut:     t.bytesOut,
+		})
+		result.TotalRequests += t.requests
+		result.TotalErrors += t.errors
+		result.TotalBytesIn += t.bytesIn
+		result.TotalBytesOut += t.bytesOut
+		groupTimeNs += t.timeNs
+	}
+	result.AvgResponseTimeMs = averageMs(groupTimeNs, result.TotalRequests)
+	return result
+}
+
+// specTotals sums the counters of a spec's operations
+type specTotals struct {
+	requests, errors, timeNs int64
+	bytesIn, bytesOut        int64
+}
+
+// averageMs returns the average of a total duration in nanoseconds over requests in milliseconds
+func averageMs(totalNs, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(totalNs) / float64(requests) / 1e6
+}
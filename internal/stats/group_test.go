@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestGetGroupStats(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", 10*time.Millisecond, false)
+	c.RecordRequest("spec-1", "op-2", "POST", "/users", 20*time.Millisecond, true)
+	c.RecordRequest("spec-2", "op-3", "GET", "/orders", 30*time.Millisecond, false)
+	c.RecordRequest("spec-3", "op-4", "GET", "/other", time.Second, true)
+
+	specs := []*models.Spec{
+		{ID: "spec-1", Name: "Users", Enabled: true},
+		{ID: "spec-2", Name: "Orders"},
+		{ID: "spec-4", Name: "Idle", Enabled: true},
+	}
+	group := c.GetGroupStats("payments", specs)
+
+	if group.Group != "payments" || group.TotalRequests != 3 || group.TotalErrors != 1 || group.AvgResponseTimeMs != 20 {
+		t.Errorf("Expected 3 requests with 1 error averaging 20ms, got %+v", group)
+	}
+	if len(group.Specs) != 3 {
+		t.Fatalf("Expected 3 specs, got %d", len(group.Specs))
+	}
+	if s := group.Specs[0]; s.SpecID != "spec-1" || !s.Enabled || s.TotalRequests != 2 || s.AvgResponseTimeMs != 15 {
+		t.Errorf("Unexpected stats of spec-1: %+v", s)
+	}
+	if s := group.Specs[2]; s.TotalRequests != 0 || s.AvgResponseTimeMs != 0 {
+		t.Errorf("Expected no requests for spec-4, got %+v", s)
+	}
+}
@@ -0,0 +1,45 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Purge removes the recent errors and hourly request counts captured between from and to, and
+// returns how many of each it removed. A zero time leaves that end of the range open. The totals
+// of operations and response configs can't be split by time, so only an open range removes them,
+// by resetting all statistics
+func (c *Collector) Purge(from, to time.Time) (errors, hours int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if from.IsZero() && to.IsZero() {
+		errors, hours = len(c.recentErrors), len(c.hourlyStats)
+		c.reset()
+		return errors, hours
+	}
+
+	kept := make([]models.ErrorStat, 0, len(c.recentErrors))
+	for _, e := range c.recentErrors {
+		if models.InTimeRange(e.Timestamp, from, to) {
+			errors++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	c.recentErrors = kept
+
+	// An hour is removed when all of it lies in the range
+	for key := range c.hourlyStats {
+		start, err := time.ParseInLocation("2006-01-02-15", key, time.Local)
+		if err != nil {
+			continue
+		}
+		if models.InTimeRange(start, from, to) && models.InTimeRange(start.Add(time.Hour-time.Nanosecond), from, to) {
+			delete(c.hourlyStats, key)
+			hours++
+		}
+	}
+	return errors, hours
+}
@@ -0,0 +1,38 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurge_Range(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, true)
+	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "boom", "req-1")
+
+	// A range ending before now removes nothing
+	if errors, hours := c.Purge(time.Time{}, time.Now().Add(-2*time.Hour)); errors != 0 || hours != 0 {
+		t.Errorf("Expected nothing purged, got %d errors and %d hours", errors, hours)
+	}
+
+	// A range covering the current hour removes its count and errors but keeps the totals
+	hour := time.Now().Truncate(time.Hour)
+	if errors, hours := c.Purge(hour.Add(-time.Hour), hour.Add(2*time.Hour)); errors != 1 || hours != 1 {
+		t.Errorf("Expected 1 error and 1 hour purged, got %d and %d", errors, hours)
+	}
+	stats := c.GetGlobalStats(0, 0)
+	if stats.TotalRequests != 1 || len(stats.RecentErrors) != 0 {
+		t.Errorf("Expected the totals kept and the errors removed, got %+v", stats)
+	}
+	for _, h := range stats.RequestsByHour {
+		if h.Requests != 0 {
+			t.Errorf("Expected no hourly requests left, got %+v", h)
+		}
+	}
+
+	// An open range resets everything
+	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "boom", "req-2")
+	if errors, _ := c.Purge(time.Time{}, time.Time{}); errors != 1 || c.GetGlobalStats(0, 0).TotalRequests != 0 {
+		t.Error("Expected an open range to reset the statistics")
+	}
+}
@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Snapshot holds the raw counters of a collector, so the statistics of several nodes can be merged
+type Snapshot struct {
+	NodeID       string              `json:"nodeId,omitempty"`
+	Time         time.Time           `json:"time"` // When the snapshot was taken
+	StartTime    time.Time           `json:"startTime"`
+	Operations   []OperationSnapshot `json:"operations"`
+	Responses    map[string]int64    `json:"responses,omitempty"` // Response config ID -> requests it matched
+	RecentErrors []models.ErrorStat  `json:"recentErrors"`
+	Hourly       map[string]Counts   `json:"hourly"` // "YYYY-MM-DD-HH" -> counts
+}
+
+// OperationSnapshot holds the raw counters of one operation
+type OperationSnapshot struct {
+	OperationID     string    `json:"operationId"`
+	SpecID          string    `json:"specId"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	TotalRequests   int64     `json:"totalRequests"`
+	TotalErrors     int64     `json:"totalErrors"`
+	TotalTimeNs     int64     `json:"totalTimeNs"`
+	MinTimeNs       int64     `json:"minTimeNs"`
+	MaxTimeNs       int64     `json:"maxTimeNs"`
+	BytesIn         int64     `json:"bytesIn"`
+	BytesOut        int64     `json:"bytesOut"`
+	LastRequestTime time.Time `json:"lastRequestTime"`
+}
+
+// Counts holds request and error counts
+type Counts struct {
+	Requests int64 `json:"requests"`
+	Errors   int64 `json:"errors"`
+}
+
+// Snapshot returns the collector's current counters
+func (c *Collector) Snapshot() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := &Snapshot{
+		Time:         time.Now(),
+		StartTime:    c.startTime,
+		Operations:   make([]OperationSnapshot, 0, len(c.operations)),
+		RecentErrors: append([]models.ErrorStat(nil), c.recentErrors...),
+		Hourly:       make(map[string]Counts, len(c.hourlyStats)),
+		Responses:    make(map[string]int64, len(c.responses)),
+	}
+	for _, op := range c.operations {
+		last, _ := op.LastRequestTime.Load().(time.Time)
+		snap.Operations = append(snap.Operations, OperationSnapshot{
+			OperationID:     op.OperationID,
+			SpecID:          op.SpecID,
+			Method:          op.Method,
+			Path:            op.Path,
+			TotalRequests:   op.TotalRequests.Load(),
+			TotalErrors:     op.TotalErrors.Load(),
+			TotalTimeNs:     op.TotalTimeNs.Load(),
+			MinTimeNs:       op.MinTimeNs.Load(),
+			MaxTimeNs:       op.MaxTimeNs.Load(),
+			BytesIn:         op.BytesIn.Load(),
+			BytesOut:        op.BytesOut.Load(),
+			LastRequestTime: last,
+		})
+	}
+	for id, hits := range c.responses {
+		snap.Responses[id] = hits
+	}
+	for key, hourly := range c.hourlyStats {
+		snap.Hourly[key] = Counts{Requests: hourly.Requests, Errors: hourly.Errors}
+	}
+	return snap
+}
+
+// Merge combines snapshots into a collector whose statistics cover all of them
+// Counts are summed, response time extremes and the start time span all snapshots, and the
+// most recent errors are kept
+func Merge(snapshots []*Snapshot) *Collector {
+	merged := NewCollector()
+	for i, snap := range snapshots {
+		if i == 0 || snap.StartTime.Before(merged.startTime) {
+			merged.startTime = snap.StartTime
+		}
+
+		for _, op := range snap.Operations {
+			stat, ok := merged.operations[op.OperationID]
+			if !ok {
+				stat = &models.AtomicOperationStat{
+					OperationID: op.OperationID,
+					SpecID:      op.SpecID,
+					Method:      op.Method,
+					Path:        op.Path,
+				}
+				stat.MinTimeNs.Store(op.MinTimeNs)
+				merged.operations[op.OperationID] = stat
+			}
+			stat.TotalRequests.Add(op.TotalRequests)
+			stat.TotalErrors.Add(op.TotalErrors)
+			stat.TotalTimeNs.Add(op.TotalTimeNs)
+			stat.BytesIn.Add(op.BytesIn)
+			stat.BytesOut.Add(op.BytesOut)
+			if op.MinTimeNs < stat.MinTimeNs.Load() {
+				stat.MinTimeNs.Store(op.MinTimeNs)
+			}
+			if op.MaxTimeNs > stat.MaxTimeNs.Load() {
+				stat.MaxTimeNs.Store(op.MaxTimeNs)
+			}
+			if last, ok := stat.LastRequestTime.Load().(time.Time); !ok || op.LastRequestTime.After(last) {
+				stat.LastRequestTime.Store(op.LastRequestTime)
+			}
+		}
+
+		for id, hits := range snap.Responses {
+			merged.responses[id] += hits
+		}
+
+		merged.recentErrors = append(merged.recentErrors, snap.RecentErrors...)
+
+		for key, counts := range snap.Hourly {
+			hourly, ok := merged.hourlyStats[key]
+			if !ok {
+				hourly = &hourlyCounter{Hour: key}
+				merged.hourlyStats[key] = hourly
+			}
+			hourly.Requests += counts.Requests
+			hourly.Errors += counts.Errors
+		}
+	}
+
+	sort.SliceStable(merged.recentErrors, func(i, j int) bool {
+		return merged.recentErrors[i].Timestamp.Before(merged.recentErrors[j].Timestamp)
+	})
+	if excess := len(merged.recentErrors) - merged.maxErrors; excess > 0 {
+		merged.recentErrors = merged.recentErrors[excess:]
+	}
+	merged.cleanupOldHourlyStats()
+	return merged
+}
@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Stream follows a collector for a live statistics feed, reporting what changed between updates
+// rather than the full statistics
+type Stream struct {
+	c         *Collector
+	start     time.Time // Start time of the collector, which a reset changes
+	at        time.Time // When the previous update was taken
+	requests  int64
+	errors    int64
+	timeNs    int64
+	lastError time.Time // Timestamp of the newest error reported
+}
+
+// NewStream starts a stream at the collector's current totals
+func (c *Collector) NewStream() *Stream {
+	s := &Stream{c: c}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s.start = c.startTime
+	s.at = time.Now()
+	s.requests, s.errors, s.timeNs = c.totals()
+	return s
+}
+
+// Next returns the totals and what changed since the previous update, or since the stream
+// started. The first update carries all recent errors, later ones only the errors recorded since
+func (s *Stream) Next() *models.StatsUpdate {
+	s.c.mu.RLock()
+	defer s.c.mu.RUnlock()
+
+	now := time.Now()
+	requests, errors, timeNs := s.c.totals()
+	// After a reset the counters start over from zero
+	if !s.c.startTime.Equal(s.start) {
+		s.start = s.c.startTime
+		s.requests, s.errors, s.timeNs = 0, 0, 0
+	}
+
+	update := &models.StatsUpdate{
+		Time:          now,
+		TotalRequests: requests,
+		TotalErrors:   errors,
+		Requests:      requests - s.requests,
+		Errors:        errors - s.errors,
+		NewErrors:     make([]models.ErrorStat, 0),
+	}
+	if elapsed := now.Sub(s.at).Seconds(); elapsed > 0 {
+		update.RequestsPerSecond = float64(update.Requests) / elapsed
+	}
+	if update.Requests > 0 {
+		update.AvgResponseTimeMs = float64(timeNs-s.timeNs) / float64(update.Requests) / 1e6
+	}
+	for _, e := range s.c.recentErrors {
+		if e.Timestamp.After(s.lastError) {
+			update.NewErrors = append(update.NewErrors, e)
+			s.lastError = e.Timestamp
+		}
+	}
+
+	s.at = now
+	s.requests, s.errors, s.timeNs = requests, errors, timeNs
+	return update
+}
+
+// totals sums the counters of all operations; the caller holds the lock
+func (c *Collector) totals() (requests, errors, timeNs int64) {
+	for _, op := range c.operations {
+		requests += op.TotalRequests.Load()
+		errors += op.TotalErrors.Load()
+		timeNs += op.TotalTimeNs.Load()
+	}
+	return requests, errors, timeNs
+}
@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", 10*time.Millisecond, false)
+	c.RecordError("spec-1", "op-1", "/users", "GET", 500, "boom", "req-1")
+
+	stream := c.NewStream()
+	first := stream.Next()
+	if first.TotalRequests != 1 || first.Requests != 0 || len(first.NewErrors) != 1 {
+		t.Errorf("Expected the totals and recent errors without changes, got %+v", first)
+	}
+
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", 20*time.Millisecond, true)
+	c.RecordRequest("spec-1", "op-2", "POST", "/users", 40*time.Millisecond, false)
+	c.RecordError("spec-1", "op-1", "/users", "GET", 503, "unavailable", "req-2")
+
+	update := stream.Next()
+	if update.TotalRequests != 3 || update.Requests != 2 || update.Errors != 1 || update.AvgResponseTimeMs != 30 || update.RequestsPerSecond <= 0 {
+		t.Errorf("Expected 2 new requests averaging 30ms, got %+v", update)
+	}
+	if len(update.NewErrors) != 1 || update.NewErrors[0].RequestID != "req-2" {
+		t.Errorf("Expected only the new error, got %+v", update.NewErrors)
+	}
+
+	if idle := stream.Next(); idle.Requests != 0 || idle.AvgResponseTimeMs != 0 || len(idle.NewErrors) != 0 {
+		t.Errorf("Expected no changes, got %+v", idle)
+	}
+
+	// Counters start over after a reset
+	time.Sleep(time.Millisecond)
+	c.Reset()
+	c.RecordRequest("spec-1", "op-1", "GET", "/users", time.Millisecond, false)
+	if update := stream.Next(); update.TotalRequests != 1 || update.Requests != 1 {
+		t.Errorf("Expected 1 request after the reset, got %+v", update)
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// tempPattern is the name pattern of files being written by writeFileAtomic
+const tempPattern = ".*.tmp-*"
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so readers and a crash never see a partially written file
+// With fsync the file and its directory are synced to disk before returning
+func writeFileAtomic(path string, data []byte, fsync bool) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if fsync {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	if fsync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// syncDir flushes a directory so a rename in it survives a crash
+// Windows cannot sync directories and some file systems refuse to; the rename is still atomic there
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
+
+// removeTempFiles deletes temporary files left behind by writes interrupted by a crash
+func removeTempFiles(dir string) {
+	matches, _ := filepath.Glob(filepath.Join(dir, tempPattern))
+	for _, path := range matches {
+		os.Remove(path)
+	}
+}
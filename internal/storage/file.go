@@ -3,30 +3,37 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prasenjit/go-virtual/internal/models"
 	"github.com/prasenjit/go-virtual/internal/parser"
 )
 
 // FileStorage implements Storage interface with file-based persistence
+// Files are written to a temporary file and renamed into place, so a crash never leaves a
+// partially written file; entries that fail to load are moved to the quarantine directory
 type FileStorage struct {
 	mu       sync.RWMutex
 	basePath string
 	memory   *MemoryStorage
+	fsync    bool // Sync files to disk before a write returns
 }
 
 // NewFileStorage creates a new file-based storage
 func NewFileStorage(basePath string) (*FileStorage, error) {
 	// Create directories if they don't exist
-	// Note: operations are in-memory only (derived from specs), no directory needed
+	// Note: operations are derived from specs; only custom or disabled operations
+	// and removals of spec-derived operations are persisted
 	dirs := []string{
 		basePath,
 		filepath.Join(basePath, "specs"),
+		filepath.Join(basePath, "operations"),
 		filepath.Join(basePath, "responses"),
 	}
 
@@ -49,8 +56,84 @@ func NewFileStorage(basePath string) (*FileStorage, error) {
 	return fs, nil
 }
 
+// SetFsync sets whether writes are synced to disk before they return
+// Syncing survives power loss at the cost of slower writes
+func (f *FileStorage) SetFsync(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fsync = enabled
+}
+
+// writeFile writes a file atomically, syncing it when fsync is enabled
+func (f *FileStorage) writeFile(path string, data []byte) error {
+	return writeFileAtomic(path, data, f.fsync)
+}
+
+// quarantine moves a file that failed to load, and the files stored alongside it, to
+// quarantine/<dir> so it is neither loaded nor overwritten and can be inspected
+func (f *FileStorage) quarantine(dir, name string, companions []string, reason error) {
+	destDir := filepath.Join(f.basePath, "quarantine", dir)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		slog.Error("failed to create quarantine directory", "path", destDir, "error", err)
+		return
+	}
+
+	// Keep earlier quarantined copies of the same file
+	suffix := ""
+	if _, err := os.Stat(filepath.Join(destDir, name)); err == nil {
+		suffix = "." + time.Now().Format("20060102T150405.000")
+	}
+
+	for _, file := range append([]string{name}, companions...) {
+		src := filepath.Join(f.basePath, dir, file)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.Rename(src, filepath.Join(destDir, file+suffix)); err != nil {
+			slog.Error("failed to quarantine file", "file", src, "error", err)
+		}
+	}
+	slog.Warn("quarantined corrupt storage file", "file", filepath.Join(dir, name), "error", reason)
+}
+
+// Reload replaces the loaded data with what is on disk, picking up changes made by other
+// instances sharing the directory
+// Tracing flags are kept, since they are not persisted
+func (f *FileStorage) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fresh := &FileStorage{basePath: f.basePath, memory: NewMemoryStorage(), fsync: f.fsync}
+	if err := fresh.loadAll(); err != nil {
+		return err
+	}
+
+	f.memory.mu.Lock()
+	defer f.memory.mu.Unlock()
+	for id, spec := range fresh.memory.specs {
+		if old, ok := f.memory.specs[id]; ok {
+			spec.Tracing = old.Tracing
+		}
+	}
+	for id, op := range fresh.memory.operations {
+		if old, ok := f.memory.operations[id]; ok {
+			op.Tracing = old.Tracing
+		}
+	}
+	f.memory.specs = fresh.memory.specs
+	f.memory.operations = fresh.memory.operations
+	f.memory.responseConfigs = fresh.memory.responseConfigs
+	f.memory.contentSizes = fresh.memory.contentSizes
+	f.memory.bodySizes = fresh.memory.bodySizes
+	return nil
+}
+
 // loadAll loads all data from disk
 func (f *FileStorage) loadAll() error {
+	for _, dir := range []string{"specs", "operations", "responses"} {
+		removeTempFiles(filepath.Join(f.basePath, dir))
+	}
+
 	// Load specs
 	specsDir := filepath.Join(f.basePath, "specs")
 	entries, err := os.ReadDir(specsDir)
@@ -60,6 +143,9 @@ func (f *FileStorage) loadAll() error {
 
 	var specsToMigrate []*models.Spec
 	p := parser.NewParser()
+	index := f.loadIndex()
+	indexChanged := false
+	loaded := make(map[string]bool)
 
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
@@ -71,13 +157,14 @@ func (f *FileStorage) loadAll() error {
 			continue
 		}
 
+		specID := strings.TrimSuffix(entry.Name(), ".json")
 		var spec models.Spec
-		if err := json.Unmarshal(data, &spec); err != nil {
+		if err := decodeEntry(data, &spec, &spec.ID, specID); err != nil {
+			f.quarantine("specs", entry.Name(), specContentFiles(specID), err)
 			continue
 		}
 
 		// Load spec content from separate file if it exists
-		specID := strings.TrimSuffix(entry.Name(), ".json")
 		content, err := f.loadSpecContent(specID)
 		if err == nil && content != "" {
 			spec.Content = content
@@ -91,17 +178,36 @@ func (f *FileStorage) loadAll() error {
 
 		f.memory.specs[spec.ID] = &spec
 
-		// Regenerate operations from spec content (operations are not persisted)
+		// Regenerate operations from spec content (spec-derived operations are not persisted)
+		// The index skips parsing specs that haven't changed since the last start
 		if spec.Content != "" {
-			operations, err := p.ParseOperations(spec.Content, spec.ID, spec.BasePath)
-			if err == nil {
-				for _, op := range operations {
-					f.memory.operations[op.ID] = op
-				}
+			operations, changed := specOperations(p, index, &spec)
+			indexChanged = indexChanged || changed
+			loaded[spec.ID] = true
+			for _, op := range operations {
+				f.memory.operations[op.ID] = op
 			}
 		}
 	}
 
+	// Drop index entries of deleted specs
+	for id := range index.Specs {
+		if !loaded[id] {
+			delete(index.Specs, id)
+			indexChanged = true
+		}
+	}
+	if indexChanged {
+		if err := f.saveIndex(index); err != nil {
+			slog.Warn("failed to save operations index", "error", err)
+		}
+	}
+
+	// Apply persisted operation changes on top of the spec-derived operations
+	if err := f.loadOperations(); err != nil {
+		return err
+	}
+
 	// Load response configs
 	respDir := filepath.Join(f.basePath, "responses")
 	entries, err = os.ReadDir(respDir)
@@ -121,13 +227,14 @@ func (f *FileStorage) loadAll() error {
 			continue
 		}
 
+		cfgID := strings.TrimSuffix(entry.Name(), ".json")
 		var cfg models.ResponseConfig
-		if err := json.Unmarshal(data, &cfg); err != nil {
+		if err := decodeEntry(data, &cfg, &cfg.ID, cfgID); err != nil {
+			f.quarantine("responses", entry.Name(), []string{cfgID + ".body"}, err)
 			continue
 		}
 
 		// Load response body from separate file if it exists
-		cfgID := strings.TrimSuffix(entry.Name(), ".json")
 		body, err := f.loadResponseBody(cfgID)
 		if err == nil && body != "" {
 			cfg.Body = body
@@ -143,7 +250,7 @@ func (f *FileStorage) loadAll() error {
 	for _, spec := range specsToMigrate {
 		if err := f.saveSpec(spec); err != nil {
 			// Log but don't fail - data is still in memory
-			fmt.Printf("Warning: failed to migrate spec %s to new format: %v\n", spec.ID, err)
+			slog.Warn("failed to migrate spec to new format", "spec", spec.ID, "error", err)
 		}
 	}
 
@@ -151,20 +258,94 @@ func (f *FileStorage) loadAll() error {
 	for _, cfg := range configsToMigrate {
 		if err := f.saveResponseConfig(cfg); err != nil {
 			// Log but don't fail - data is still in memory
-			fmt.Printf("Warning: failed to migrate response config %s to new format: %v\n", cfg.ID, err)
+			slog.Warn("failed to migrate response config to new format", "responseConfig", cfg.ID, "error", err)
+		}
+	}
+
+	f.loadSequences()
+
+	return nil
+}
+
+// loadOperations loads persisted operations and removes spec-derived operations that were deleted
+// Tombstones are applied first so a persisted operation file always wins
+func (f *FileStorage) loadOperations() error {
+	opsDir := filepath.Join(f.basePath, "operations")
+	entries, err := os.ReadDir(opsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".deleted" {
+			continue
+		}
+		// Tombstone names are <specID>.<operationID>.deleted
+		parts := strings.Split(strings.TrimSuffix(entry.Name(), ".deleted"), ".")
+		if len(parts) == 2 {
+			delete(f.memory.operations, parts[1])
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
 		}
+
+		data, err := os.ReadFile(filepath.Join(opsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var op models.Operation
+		if err := decodeEntry(data, &op, &op.ID, strings.TrimSuffix(entry.Name(), ".json")); err != nil {
+			f.quarantine("operations", entry.Name(), nil, err)
+			continue
+		}
+
+		// Skip operations whose spec no longer exists
+		if _, ok := f.memory.specs[op.SpecID]; !ok {
+			continue
+		}
+
+		// Like spec tracing, operation tracing does not persist across restarts
+		op.Tracing = false
+
+		f.memory.operations[op.ID] = &op
 	}
 
 	return nil
 }
 
+// decodeEntry unmarshals a stored JSON entry and checks that its ID matches the file name
+func decodeEntry(data []byte, v interface{}, id *string, fileID string) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if *id != fileID {
+		return fmt.Errorf("ID %q does not match the file name", *id)
+	}
+	return nil
+}
+
+// specContentExtensions are the extensions of spec content files, in load order
+var specContentExtensions = []string{".yaml", ".yml", ".spec.json"}
+
+// specContentFiles returns the possible names of a spec's content file
+func specContentFiles(specID string) []string {
+	files := make([]string, len(specContentExtensions))
+	for i, ext := range specContentExtensions {
+		files[i] = specID + ext
+	}
+	return files
+}
+
 // loadSpecContent loads the OpenAPI spec content from a separate file
 func (f *FileStorage) loadSpecContent(specID string) (string, error) {
 	// Try .yaml first, then .yml, then .json
-	extensions := []string{".yaml", ".yml", ".spec.json"}
 	specsDir := filepath.Join(f.basePath, "specs")
 
-	for _, ext := range extensions {
+	for _, ext := range specContentExtensions {
 		path := filepath.Join(specsDir, specID+ext)
 		data, err := os.ReadFile(path)
 		if err == nil {
@@ -198,9 +379,17 @@ func (f *FileStorage) saveSpec(spec *models.Spec) error {
 			ext = ".spec.json"
 		}
 		contentPath := filepath.Join(specsDir, spec.ID+ext)
-		if err := os.WriteFile(contentPath, []byte(content), 0644); err != nil {
+		if err := f.writeFile(contentPath, []byte(content)); err != nil {
 			return err
 		}
+
+		// Remove content files with other extensions so a format change on
+		// re-upload doesn't leave stale content that would be loaded first
+		for _, other := range specContentExtensions {
+			if other != ext {
+				os.Remove(filepath.Join(specsDir, spec.ID+other))
+			}
+		}
 	}
 
 	// Save metadata without content
@@ -213,10 +402,10 @@ func (f *FileStorage) saveSpec(spec *models.Spec) error {
 	}
 
 	path := filepath.Join(specsDir, spec.ID+".json")
-	return os.WriteFile(path, data, 0644)
+	return f.writeFile(path, data)
 }
 
-// deleteSpecFile deletes a spec file and its content file from disk
+// deleteSpecFile deletes a spec file, its content file and the files stored with the spec from disk
 func (f *FileStorage) deleteSpecFile(id string) error {
 	specsDir := filepath.Join(f.basePath, "specs")
 	
@@ -225,25 +414,57 @@ func (f *FileStorage) deleteSpecFile(id string) error {
 	os.Remove(jsonPath) // Ignore error if doesn't exist
 	
 	// Delete content files (try all extensions)
-	extensions := []string{".yaml", ".yml", ".spec.json"}
-	for _, ext := range extensions {
+	for _, ext := range specContentExtensions {
 		os.Remove(filepath.Join(specsDir, id+ext))
 	}
+	os.RemoveAll(f.specFilesDir(id))
 	
 	return nil
 }
 
+// saveOperation saves an operation to disk
+func (f *FileStorage) saveOperation(op *models.Operation) error {
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(f.basePath, "operations", op.ID+".json")
+	return f.writeFile(path, data)
+}
+
+// persistOperation saves an operation that differs from its spec-derived form
+// Operations that match the spec again have their saved copy removed
+func (f *FileStorage) persistOperation(op *models.Operation) error {
+	if op.Custom || op.Disabled || op.RateLimit != nil || op.Forward != nil || op.Echo {
+		return f.saveOperation(op)
+	}
+
+	err := os.Remove(filepath.Join(f.basePath, "operations", op.ID+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// operationTombstonePath returns the path of the marker recording a deleted operation
+func (f *FileStorage) operationTombstonePath(specID, opID string) string {
+	return filepath.Join(f.basePath, "operations", specID+"."+opID+".deleted")
+}
+
 // saveResponseConfig saves a response config to disk (metadata in JSON, body in separate file)
 func (f *FileStorage) saveResponseConfig(cfg *models.ResponseConfig) error {
 	respDir := filepath.Join(f.basePath, "responses")
 
-	// Save body to separate file if not empty
+	// Save body to separate file if not empty, removing an old one otherwise
 	body := cfg.Body
+	bodyPath := filepath.Join(respDir, cfg.ID+".body")
 	if body != "" {
-		bodyPath := filepath.Join(respDir, cfg.ID+".body")
-		if err := os.WriteFile(bodyPath, []byte(body), 0644); err != nil {
+		if err := f.writeFile(bodyPath, []byte(body)); err != nil {
 			return err
 		}
+	} else if err := os.Remove(bodyPath); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
 	// Save metadata without body
@@ -256,7 +477,7 @@ func (f *FileStorage) saveResponseConfig(cfg *models.ResponseConfig) error {
 	}
 
 	path := filepath.Join(respDir, cfg.ID+".json")
-	return os.WriteFile(path, data, 0644)
+	return f.writeFile(path, data)
 }
 
 // deleteResponseConfigFile deletes a response config file and its body file from disk
@@ -325,12 +546,19 @@ func (f *FileStorage) DeleteSpec(id string) error {
 	return f.deleteSpecFile(id)
 }
 
-// CreateOperation creates a new operation (in-memory only, not persisted)
+// CreateOperation creates a new operation (only custom or disabled operations are persisted)
 func (f *FileStorage) CreateOperation(op *models.Operation) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	return f.memory.CreateOperation(op)
+	if err := f.memory.CreateOperation(op); err != nil {
+		return err
+	}
+
+	// The operation may have been deleted before; it exists again now
+	os.Remove(f.operationTombstonePath(op.SpecID, op.ID))
+
+	return f.persistOperation(op)
 }
 
 // GetOperation retrieves an operation by ID
@@ -348,28 +576,58 @@ func (f *FileStorage) GetAllOperations() ([]*models.Operation, error) {
 	return f.memory.GetAllOperations()
 }
 
-// UpdateOperation updates an operation (in-memory only, not persisted)
+// UpdateOperation updates an operation (only custom or disabled operations are persisted)
 func (f *FileStorage) UpdateOperation(op *models.Operation) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	return f.memory.UpdateOperation(op)
+	if err := f.memory.UpdateOperation(op); err != nil {
+		return err
+	}
+
+	return f.persistOperation(op)
 }
 
-// DeleteOperation deletes an operation (in-memory only)
+// DeleteOperation deletes an operation
+// A tombstone is written so a spec-derived operation is not regenerated on the next load
 func (f *FileStorage) DeleteOperation(id string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	return f.memory.DeleteOperation(id)
+	op, err := f.memory.GetOperation(id)
+	if err != nil {
+		return err
+	}
+
+	if err := f.memory.DeleteOperation(id); err != nil {
+		return err
+	}
+
+	os.Remove(filepath.Join(f.basePath, "operations", id+".json"))
+	return f.writeFile(f.operationTombstonePath(op.SpecID, id), nil)
 }
 
-// DeleteOperationsBySpec deletes all operations for a spec (in-memory only)
+// DeleteOperationsBySpec deletes all operations for a spec along with their persisted files
 func (f *FileStorage) DeleteOperationsBySpec(specID string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	return f.memory.DeleteOperationsBySpec(specID)
+	ops, _ := f.memory.GetOperationsBySpec(specID)
+
+	if err := f.memory.DeleteOperationsBySpec(specID); err != nil {
+		return err
+	}
+
+	opsDir := filepath.Join(f.basePath, "operations")
+	for _, op := range ops {
+		os.Remove(filepath.Join(opsDir, op.ID+".json"))
+	}
+	tombstones, _ := filepath.Glob(filepath.Join(opsDir, specID+".*.deleted"))
+	for _, path := range tombstones {
+		os.Remove(path)
+	}
+
+	return nil
 }
 
 // CreateResponseConfig creates a new response config
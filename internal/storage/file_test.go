@@ -0,0 +1,395 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+const testSpecContent = `
+openapi: 3.0.0
+info:
+  title: Test API
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      responses:
+        '200':
+          description: Success
+  /orders:
+    get:
+      responses:
+        '200':
+          description: Success
+`
+
+// newTestFileStorage creates a file storage with one spec whose operations are derived from testSpecContent
+func newTestFileStorage(t *testing.T) (*FileStorage, string) {
+	dir := t.TempDir()
+
+	f, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	result, err := parser.NewParser().ParseForSpec(testSpecContent, "spec-1", "")
+	if err != nil {
+		t.Fatalf("ParseForSpec failed: %v", err)
+	}
+	if err := f.CreateSpec(result.Spec); err != nil {
+		t.Fatalf("CreateSpec failed: %v", err)
+	}
+	for _, op := range result.Operations {
+		f.CreateOperation(op)
+	}
+
+	return f, dir
+}
+
+func TestFileStorage_CustomOperationsPersist(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+
+	custom := &models.Operation{
+		ID:       "custom-op",
+		SpecID:   "spec-1",
+		Method:   "POST",
+		Path:     "/users/import",
+		FullPath: "/users/import",
+		Custom:   true,
+	}
+	if err := f.CreateOperation(custom); err != nil {
+		t.Fatalf("CreateOperation failed: %v", err)
+	}
+
+	// Modify a spec-derived operation
+	usersID := parser.GenerateOperationID("spec-1", "GET", "/users")
+	users, _ := f.GetOperation(usersID)
+	modified := *users
+	modified.Summary = "Modified"
+	modified.Custom = true
+	f.UpdateOperation(&modified)
+
+	// Delete a spec-derived operation
+	ordersID := parser.GenerateOperationID("spec-1", "GET", "/orders")
+	if err := f.DeleteOperation(ordersID); err != nil {
+		t.Fatalf("DeleteOperation failed: %v", err)
+	}
+
+	reloaded, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	if _, err := reloaded.GetOperation("custom-op"); err != nil {
+		t.Error("Expected custom operation to be reloaded")
+	}
+	if op, err := reloaded.GetOperation(usersID); err != nil || op.Summary != "Modified" {
+		t.Error("Expected modified operation to override the spec-derived one")
+	}
+	if _, err := reloaded.GetOperation(ordersID); err == nil {
+		t.Error("Expected deleted operation to stay deleted")
+	}
+}
+
+func TestFileStorage_DeleteOperationsBySpec(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+
+	f.CreateOperation(&models.Operation{ID: "custom-op", SpecID: "spec-1", Method: "POST", Path: "/x", Custom: true})
+	f.DeleteOperation(parser.GenerateOperationID("spec-1", "GET", "/orders"))
+
+	f.DeleteOperationsBySpec("spec-1")
+	f.DeleteSpec("spec-1")
+
+	reloaded, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	ops, _ := reloaded.GetAllOperations()
+	if len(ops) != 0 {
+		t.Errorf("Expected no operations after deleting the spec, got %d", len(ops))
+	}
+}
+
+func TestFileStorage_DisabledOperationPersists(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+
+	usersID := parser.GenerateOperationID("spec-1", "GET", "/users")
+	op, _ := f.GetOperation(usersID)
+	op.Disabled = true
+	f.UpdateOperation(op)
+
+	reloaded, _ := NewFileStorage(dir)
+	if op, err := reloaded.GetOperation(usersID); err != nil || !op.Disabled {
+		t.Fatal("Expected operation to stay disabled after reload")
+	}
+
+	// Re-enabling drops the saved copy so the spec-derived operation is used again
+	op, _ = reloaded.GetOperation(usersID)
+	op.Disabled = false
+	reloaded.UpdateOperation(op)
+
+	again, _ := NewFileStorage(dir)
+	if op, err := again.GetOperation(usersID); err != nil || op.Disabled {
+		t.Error("Expected operation to be enabled after reload")
+	}
+}
+
+func TestFileStorage_AtomicWritesLeaveNoTempFiles(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+	f.SetFsync(true)
+
+	f.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", OperationID: "op-1", Body: `{"ok": true}`})
+	spec, _ := f.GetSpec("spec-1")
+	spec.Description = "Updated"
+	if err := f.UpdateSpec(spec); err != nil {
+		t.Fatalf("UpdateSpec failed: %v", err)
+	}
+
+	for _, sub := range []string{"specs", "operations", "responses"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, sub, tempPattern))
+		if len(matches) != 0 {
+			t.Errorf("Expected no temporary files in %s, got %v", sub, matches)
+		}
+	}
+
+	// A temporary file left by a crash is removed on load
+	stale := filepath.Join(dir, "specs", ".spec-1.json.tmp-123")
+	os.WriteFile(stale, []byte("{"), 0644)
+	if _, err := NewFileStorage(dir); err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected stale temporary file to be removed")
+	}
+}
+
+func TestFileStorage_QuarantinesCorruptEntries(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+	f.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", OperationID: "op-1", Body: "hello"})
+	f.CreateResponseConfig(&models.ResponseConfig{ID: "rc-2", OperationID: "op-1"})
+
+	// Truncated JSON and an entry whose ID doesn't match its file name
+	os.WriteFile(filepath.Join(dir, "responses", "rc-1.json"), []byte(`{"id": "rc-1", "oper`), 0644)
+	os.WriteFile(filepath.Join(dir, "responses", "rc-2.json"), []byte(`{"id": "other"}`), 0644)
+
+	reloaded, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	if _, err := reloaded.GetSpec("spec-1"); err != nil {
+		t.Error("Expected valid spec to load")
+	}
+	for _, id := range []string{"rc-1", "rc-2", "other"} {
+		if _, err := reloaded.GetResponseConfig(id); err == nil {
+			t.Errorf("Expected corrupt response config %s to be skipped", id)
+		}
+	}
+
+	for _, name := range []string{"rc-1.json", "rc-1.body", "rc-2.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "quarantine", "responses", name)); err != nil {
+			t.Errorf("Expected %s to be quarantined: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "responses", name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be moved out of the responses directory", name)
+		}
+	}
+}
+
+func TestFileStorage_OperationsIndex(t *testing.T) {
+	_, dir := newTestFileStorage(t)
+
+	// The first load builds the index
+	if _, err := NewFileStorage(dir); err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	indexPath := filepath.Join(dir, indexFile)
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("Expected index file to be written: %v", err)
+	}
+
+	// Operations come from the index while the content hash matches
+	index := (&FileStorage{basePath: dir}).loadIndex()
+	entry := index.Specs["spec-1"]
+	if entry == nil || len(entry.Operations) != 2 {
+		t.Fatalf("Expected 2 indexed operations, got %+v", entry)
+	}
+	entry.Operations[0].Summary = "From index"
+	(&FileStorage{basePath: dir}).saveIndex(index)
+
+	reloaded, _ := NewFileStorage(dir)
+	op, err := reloaded.GetOperation(entry.Operations[0].ID)
+	if err != nil || op.Summary != "From index" {
+		t.Errorf("Expected operation to be loaded from the index, got %+v", op)
+	}
+
+	// Changed content is re-parsed
+	spec, _ := reloaded.GetSpec("spec-1")
+	spec.Content = testSpecContent + "\n"
+	reloaded.UpdateSpec(spec)
+
+	again, _ := NewFileStorage(dir)
+	op, err = again.GetOperation(entry.Operations[0].ID)
+	if err != nil || op.Summary == "From index" {
+		t.Errorf("Expected changed spec to be re-parsed, got %+v", op)
+	}
+}
+
+func TestFileStorage_Orphans(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+	usersID := parser.GenerateOperationID("spec-1", "GET", "/users")
+	f.CreateResponseConfig(&models.ResponseConfig{ID: "rc-kept", OperationID: usersID, Body: "kept"})
+	f.CreateResponseConfig(&models.ResponseConfig{ID: "rc-orphan", OperationID: "missing-op", Body: "orphan"})
+
+	os.WriteFile(filepath.Join(dir, "responses", "gone.body"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "specs", "gone.yaml"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "specs", "spec-1.yml"), []byte("x"), 0644) // Shadowed by spec-1.yaml
+
+	report, err := f.Orphans(false)
+	if err != nil {
+		t.Fatalf("Orphans failed: %v", err)
+	}
+	if len(report.ResponseConfigs) != 1 || report.ResponseConfigs[0] != "rc-orphan" {
+		t.Errorf("Expected rc-orphan, got %v", report.ResponseConfigs)
+	}
+	if len(report.BodyFiles) != 1 || report.BodyFiles[0] != "responses/gone.body" {
+		t.Errorf("Expected responses/gone.body, got %v", report.BodyFiles)
+	}
+	if len(report.ContentFiles) != 2 {
+		t.Errorf("Expected 2 stale content files, got %v", report.ContentFiles)
+	}
+	if _, err := f.GetResponseConfig("rc-orphan"); err != nil {
+		t.Error("Expected report-only run to keep orphans")
+	}
+
+	report, _ = f.Orphans(true)
+	if !report.Removed || report.Count() != 4 {
+		t.Errorf("Expected 4 removed orphans, got %+v", report)
+	}
+	for _, name := range []string{"responses/gone.body", "responses/rc-orphan.json", "responses/rc-orphan.body", "specs/gone.yaml", "specs/spec-1.yml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "responses", "rc-kept.body")); err != nil {
+		t.Error("Expected body of a valid response config to be kept")
+	}
+
+	if report, _ := f.Orphans(false); report.Count() != 0 {
+		t.Errorf("Expected no orphans after cleanup, got %+v", report)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	src, _ := newTestFileStorage(t)
+	usersID := parser.GenerateOperationID("spec-1", "GET", "/users")
+	ordersID := parser.GenerateOperationID("spec-1", "GET", "/orders")
+	src.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", OperationID: usersID, Body: `{"id": 1}`})
+	src.DeleteOperation(ordersID)
+	src.SaveSpecFile("spec-1", "report.pdf", []byte("%PDF-1.4"))
+
+	dir := t.TempDir()
+	dst, err := Open("file:" + dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	copied, err := Migrate(src, dst)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	expected := Counts{Specs: 1, Operations: 1, ResponseConfigs: 1, Files: 1}
+	if copied != expected {
+		t.Errorf("Expected %+v copied, got %+v", expected, copied)
+	}
+
+	// The reopened target has the same data, without the deleted operation
+	reopened, _ := Open("file:" + dir)
+	if counts, _ := CountAll(reopened); counts != expected {
+		t.Errorf("Expected %+v after reopening, got %+v", expected, counts)
+	}
+	if cfg, err := reopened.GetResponseConfig("rc-1"); err != nil || cfg.Body != `{"id": 1}` {
+		t.Errorf("Expected response config with its body, got %+v", cfg)
+	}
+
+	if _, err := Migrate(src, reopened); err == nil {
+		t.Error("Expected error migrating into a non-empty storage")
+	}
+	if _, err := Open("sqlite:./data.db"); err == nil {
+		t.Error("Expected error for an unsupported storage type")
+	}
+}
+
+func TestFileStorage_Reload(t *testing.T) {
+	a, dir := newTestFileStorage(t)
+	b, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	spec, _ := b.GetSpec("spec-1")
+	spec.Tracing = true
+
+	usersID := parser.GenerateOperationID("spec-1", "GET", "/users")
+	a.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", OperationID: usersID, Body: "hi"})
+
+	if err := b.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if cfg, err := b.GetResponseConfig("rc-1"); err != nil || cfg.Body != "hi" {
+		t.Errorf("Expected response config written by another instance, got %+v", cfg)
+	}
+	if spec, _ := b.GetSpec("spec-1"); !spec.Tracing {
+		t.Error("Expected tracing flag to survive a reload")
+	}
+}
+
+func TestFileStorage_SequencesPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if value, err := f.NextSequence("op-1"); err != nil || value != int64(i) {
+			t.Fatalf("Expected %d, got %d and %v", i, value, err)
+		}
+	}
+	f.NextSequence("op-2")
+	f.ResetSequence("op-2")
+
+	reopened, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	if value, _ := reopened.NextSequence("op-1"); value != 4 {
+		t.Errorf("Expected the counter to continue at 4, got %d", value)
+	}
+	if value, _ := reopened.NextSequence("op-2"); value != 1 {
+		t.Errorf("Expected the reset counter to start at 1, got %d", value)
+	}
+}
+
+func TestFileStorage_OperationSettingsPersist(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+
+	ops, _ := f.GetOperationsBySpec("spec-1")
+	op := ops[0]
+	op.Echo = true
+	if err := f.UpdateOperation(op); err != nil {
+		t.Fatalf("UpdateOperation failed: %v", err)
+	}
+
+	reopened, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	if loaded, err := reopened.GetOperation(op.ID); err != nil || !loaded.Echo {
+		t.Errorf("Expected the echo setting to survive a restart, got %+v and %v", loaded, err)
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+// indexVersion is bumped when parsed operations change shape, invalidating saved indexes
+const indexVersion = 1
+
+// indexFile is the name of the operations index in the storage directory
+const indexFile = "index.json"
+
+// operationIndex caches the operations parsed from each spec so startup only re-parses
+// specs whose content or base path changed
+type operationIndex struct {
+	Version int                    `json:"version"`
+	Specs   map[string]*indexEntry `json:"specs"`
+}
+
+// indexEntry holds the operations parsed from one spec
+type indexEntry struct {
+	Hash       string              `json:"hash"` // contentHash of the parsed spec
+	Operations []*models.Operation `json:"operations"`
+}
+
+// contentHash identifies the input operations are parsed from
+func contentHash(spec *models.Spec) string {
+	h := sha256.New()
+	h.Write([]byte(spec.BasePath))
+	h.Write([]byte{0})
+	h.Write([]byte(spec.Content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadIndex reads the operations index, returning an empty one if it is missing or outdated
+func (f *FileStorage) loadIndex() *operationIndex {
+	index := &operationIndex{Version: indexVersion, Specs: make(map[string]*indexEntry)}
+
+	data, err := os.ReadFile(filepath.Join(f.basePath, indexFile))
+	if err != nil {
+		return index
+	}
+	var saved operationIndex
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Version != indexVersion || saved.Specs == nil {
+		return index
+	}
+	return &saved
+}
+
+// saveIndex writes the operations index
+func (f *FileStorage) saveIndex(index *operationIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return f.writeFile(filepath.Join(f.basePath, indexFile), data)
+}
+
+// specOperations returns the operations derived from a spec, from the index when its
+// content is unchanged and by parsing it otherwise
+// It reports whether the index was updated
+func specOperations(p *parser.Parser, index *operationIndex, spec *models.Spec) ([]*models.Operation, bool) {
+	hash := contentHash(spec)
+	if entry, ok := index.Specs[spec.ID]; ok && entry.Hash == hash {
+		return entry.Operations, false
+	}
+
+	operations, err := p.ParseOperations(spec.Content, spec.ID, spec.BasePath)
+	if err != nil {
+		// Don't cache failures; the spec may parse once referenced files are available
+		delete(index.Specs, spec.ID)
+		return nil, true
+	}
+	index.Specs[spec.ID] = &indexEntry{Hash: hash, Operations: operations}
+	return operations, true
+}
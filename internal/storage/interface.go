@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"io"
+
 	"github.com/prasenjit/go-virtual/internal/models"
 )
 
@@ -31,6 +33,12 @@ type Storage interface {
 	DeleteResponseConfig(id string) error
 	DeleteResponseConfigsByOperation(opID string) error
 
+	// Spec file operations
+	SaveSpecFile(specID, name string, data []byte) error
+	OpenSpecFile(specID, name string) (io.ReadSeekCloser, *models.SpecFile, error)
+	GetSpecFiles(specID string) ([]*models.SpecFile, error)
+	DeleteSpecFile(specID, name string) error
+
 	// Utility
 	Close() error
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -8,12 +9,40 @@ import (
 	"github.com/prasenjit/go-virtual/internal/models"
 )
 
+// ErrCapacityExceeded is returned when a write would exceed the storage limits
+var ErrCapacityExceeded = errors.New("storage capacity exceeded")
+
+// Eviction policies of MemoryStorage when a limit is reached
+const (
+	EvictNone   = "none"   // Reject the write
+	EvictOldest = "oldest" // Delete the oldest specs, with their operations and response configs
+)
+
+// MemoryLimits bounds what MemoryStorage holds; zero values mean unlimited
+type MemoryLimits struct {
+	MaxSpecs           int    // Maximum number of specs
+	MaxResponseConfigs int    // Maximum number of response configs
+	MaxBodyBytes       int64  // Maximum total size of spec contents, response bodies and spec files
+	Eviction           string // EvictNone (default) or EvictOldest
+}
+
 // MemoryStorage implements Storage interface with in-memory storage
 type MemoryStorage struct {
 	mu              sync.RWMutex
 	specs           map[string]*models.Spec
 	operations      map[string]*models.Operation
 	responseConfigs map[string]*models.ResponseConfig
+	files           map[string]map[string]*memoryFile // spec ID -> file name -> file
+	sequences       map[string]int64                  // operation ID -> last value of {{sequence}}
+	limits          MemoryLimits
+
+	// Sizes of spec contents and response bodies when they were stored, since callers may
+	// change a stored item before updating it
+	contentSizes map[string]int64 // spec ID -> bytes
+	bodySizes    map[string]int64 // response config ID -> bytes
+
+	onEvict func(specID string) // Told about each spec evicted to make room
+	evicted []string            // Specs evicted by the current write, not yet reported
 }
 
 // NewMemoryStorage creates a new in-memory storage
@@ -22,11 +51,164 @@ func NewMemoryStorage() *MemoryStorage {
 		specs:           make(map[string]*models.Spec),
 		operations:      make(map[string]*models.Operation),
 		responseConfigs: make(map[string]*models.ResponseConfig),
+		files:           make(map[string]map[string]*memoryFile),
+		contentSizes:    make(map[string]int64),
+		bodySizes:       make(map[string]int64),
+	}
+}
+
+// SetLimits sets the capacity limits, validating the eviction policy
+// Limits apply to later writes; existing data is kept
+func (m *MemoryStorage) SetLimits(limits MemoryLimits) error {
+	switch limits.Eviction {
+	case "":
+		limits.Eviction = EvictNone
+	case EvictNone, EvictOldest:
+	default:
+		return fmt.Errorf("unknown eviction policy %q (use %s or %s)", limits.Eviction, EvictNone, EvictOldest)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+	return nil
+}
+
+// SetEvictHandler sets a function told about each spec evicted to make room, with its
+// operations and response configs, so whatever serves or records the spec can forget it.
+// It is called once the write that evicted the spec has released the storage
+func (m *MemoryStorage) SetEvictHandler(handler func(specID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvict = handler
+}
+
+// notifyEvicted reports the specs evicted by a write; writes that may evict defer it before
+// taking the lock, so the handler can use the storage
+func (m *MemoryStorage) notifyEvicted() {
+	m.mu.Lock()
+	evicted, handler := m.evicted, m.onEvict
+	m.evicted = nil
+	m.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for _, id := range evicted {
+		handler(id)
+	}
+}
+
+// contentSize returns the size of a stored spec's content when it was stored
+func (m *MemoryStorage) contentSize(spec *models.Spec) int64 {
+	if size, ok := m.contentSizes[spec.ID]; ok {
+		return size
+	}
+	return int64(len(spec.Content))
+}
+
+// bodySize returns the size of a stored response config's body when it was stored
+func (m *MemoryStorage) bodySize(cfg *models.ResponseConfig) int64 {
+	if size, ok := m.bodySizes[cfg.ID]; ok {
+		return size
+	}
+	return int64(len(cfg.Body))
+}
+
+// bodyBytes returns the stored spec contents, response bodies and spec files in bytes
+func (m *MemoryStorage) bodyBytes() int64 {
+	var total int64
+	for _, spec := range m.specs {
+		total += m.contentSize(spec)
+	}
+	for _, cfg := range m.responseConfigs {
+		total += m.bodySize(cfg)
+	}
+	for _, files := range m.files {
+		for _, file := range files {
+			total += int64(len(file.data))
+		}
+	}
+	return total
+}
+
+// capacityError reports which limit a write would exceed, or nil if it fits
+// newSpecs and newConfigs are the items the write adds; bytes is the resulting body size
+func (m *MemoryStorage) capacityError(newSpecs, newConfigs int, bytes int64) error {
+	if l := m.limits.MaxSpecs; l > 0 && newSpecs > 0 && len(m.specs)+newSpecs > l {
+		return fmt.Errorf("%w: at most %d specs", ErrCapacityExceeded, l)
+	}
+	if l := m.limits.MaxResponseConfigs; l > 0 && newConfigs > 0 && len(m.responseConfigs)+newConfigs > l {
+		return fmt.Errorf("%w: at most %d response configs", ErrCapacityExceeded, l)
 	}
+	if l := m.limits.MaxBodyBytes; l > 0 && bytes > l {
+		return fmt.Errorf("%w: at most %d bytes of spec contents, response bodies and files", ErrCapacityExceeded, l)
+	}
+	return nil
+}
+
+// ensureCapacity checks a write against the limits, evicting the oldest specs other than
+// keepSpecID when the eviction policy allows it
+// bytes returns the resulting body size, which changes as specs are evicted
+func (m *MemoryStorage) ensureCapacity(newSpecs, newConfigs int, bytes func() int64, keepSpecID string) error {
+	for {
+		err := m.capacityError(newSpecs, newConfigs, bytes())
+		if err == nil || m.limits.Eviction != EvictOldest {
+			return err
+		}
+		if !m.evictOldestSpec(keepSpecID) {
+			return err
+		}
+	}
+}
+
+// evictOldestSpec deletes the oldest spec other than keepSpecID, with its operations and
+// response configs, reporting false if there is none
+func (m *MemoryStorage) evictOldestSpec(keepSpecID string) bool {
+	var oldest *models.Spec
+	for id, spec := range m.specs {
+		if id == keepSpecID {
+			continue
+		}
+		if oldest == nil || spec.CreatedAt.Before(oldest.CreatedAt) ||
+			(spec.CreatedAt.Equal(oldest.CreatedAt) && spec.ID < oldest.ID) {
+			oldest = spec
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+
+	for opID, op := range m.operations {
+		if op.SpecID != oldest.ID {
+			continue
+		}
+		for cfgID, cfg := range m.responseConfigs {
+			if cfg.OperationID == opID {
+				delete(m.responseConfigs, cfgID)
+				delete(m.bodySizes, cfgID)
+			}
+		}
+		delete(m.operations, opID)
+	}
+	delete(m.specs, oldest.ID)
+	delete(m.contentSizes, oldest.ID)
+	delete(m.files, oldest.ID)
+	m.evicted = append(m.evicted, oldest.ID)
+	return true
+}
+
+// specOfOperation returns the spec ID of an operation, or an empty string
+func (m *MemoryStorage) specOfOperation(opID string) string {
+	if op, ok := m.operations[opID]; ok {
+		return op.SpecID
+	}
+	return ""
 }
 
 // CreateSpec creates a new spec
 func (m *MemoryStorage) CreateSpec(spec *models.Spec) error {
+	defer m.notifyEvicted()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -34,7 +216,14 @@ func (m *MemoryStorage) CreateSpec(spec *models.Spec) error {
 		return fmt.Errorf("spec with ID %s already exists", spec.ID)
 	}
 
+	size := int64(len(spec.Content))
+	bytes := func() int64 { return m.bodyBytes() + size }
+	if err := m.ensureCapacity(1, 0, bytes, spec.ID); err != nil {
+		return err
+	}
+
 	m.specs[spec.ID] = spec
+	m.contentSizes[spec.ID] = size
 	return nil
 }
 
@@ -85,15 +274,27 @@ func (m *MemoryStorage) GetEnabledSpecs() ([]*models.Spec, error) {
 }
 
 // UpdateSpec updates a spec
+// Only a larger content is checked against the limits
 func (m *MemoryStorage) UpdateSpec(spec *models.Spec) error {
+	defer m.notifyEvicted()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.specs[spec.ID]; !exists {
+	stored, exists := m.specs[spec.ID]
+	if !exists {
 		return fmt.Errorf("spec not found: %s", spec.ID)
 	}
 
+	size := int64(len(spec.Content))
+	if growth := size - m.contentSize(stored); growth > 0 {
+		bytes := func() int64 { return m.bodyBytes() + growth }
+		if err := m.ensureCapacity(0, 0, bytes, spec.ID); err != nil {
+			return err
+		}
+	}
+
 	m.specs[spec.ID] = spec
+	m.contentSizes[spec.ID] = size
 	return nil
 }
 
@@ -107,6 +308,8 @@ func (m *MemoryStorage) DeleteSpec(id string) error {
 	}
 
 	delete(m.specs, id)
+	delete(m.contentSizes, id)
+	delete(m.files, id)
 	return nil
 }
 
@@ -214,6 +417,7 @@ func (m *MemoryStorage) DeleteOperationsBySpec(specID string) error {
 
 // CreateResponseConfig creates a new response config
 func (m *MemoryStorage) CreateResponseConfig(cfg *models.ResponseConfig) error {
+	defer m.notifyEvicted()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -221,7 +425,14 @@ func (m *MemoryStorage) CreateResponseConfig(cfg *models.ResponseConfig) error {
 		return fmt.Errorf("response config with ID %s already exists", cfg.ID)
 	}
 
+	size := int64(len(cfg.Body))
+	bytes := func() int64 { return m.bodyBytes() + size }
+	if err := m.ensureCapacity(0, 1, bytes, m.specOfOperation(cfg.OperationID)); err != nil {
+		return err
+	}
+
 	m.responseConfigs[cfg.ID] = cfg
+	m.bodySizes[cfg.ID] = size
 	return nil
 }
 
@@ -259,15 +470,27 @@ func (m *MemoryStorage) GetResponseConfigsByOperation(opID string) ([]*models.Re
 }
 
 // UpdateResponseConfig updates a response config
+// Only a larger body is checked against the limits
 func (m *MemoryStorage) UpdateResponseConfig(cfg *models.ResponseConfig) error {
+	defer m.notifyEvicted()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.responseConfigs[cfg.ID]; !exists {
+	stored, exists := m.responseConfigs[cfg.ID]
+	if !exists {
 		return fmt.Errorf("response config not found: %s", cfg.ID)
 	}
 
+	size := int64(len(cfg.Body))
+	if growth := size - m.bodySize(stored); growth > 0 {
+		bytes := func() int64 { return m.bodyBytes() + growth }
+		if err := m.ensureCapacity(0, 0, bytes, m.specOfOperation(cfg.OperationID)); err != nil {
+			return err
+		}
+	}
+
 	m.responseConfigs[cfg.ID] = cfg
+	m.bodySizes[cfg.ID] = size
 	return nil
 }
 
@@ -281,6 +504,7 @@ func (m *MemoryStorage) DeleteResponseConfig(id string) error {
 	}
 
 	delete(m.responseConfigs, id)
+	delete(m.bodySizes, id)
 	return nil
 }
 
@@ -292,6 +516,7 @@ func (m *MemoryStorage) DeleteResponseConfigsByOperation(opID string) error {
 	for id, cfg := range m.responseConfigs {
 		if cfg.OperationID == opID {
 			delete(m.responseConfigs, id)
+			delete(m.bodySizes, id)
 		}
 	}
 
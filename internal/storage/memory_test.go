@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -560,3 +561,121 @@ func TestConcurrentResponseConfigAccess(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestMemoryLimits_Reject(t *testing.T) {
+	s := NewMemoryStorage()
+	if err := s.SetLimits(MemoryLimits{MaxSpecs: 1, MaxResponseConfigs: 1, MaxBodyBytes: 10}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+
+	if err := s.CreateSpec(&models.Spec{ID: "spec-1", Content: "12345"}); err != nil {
+		t.Fatalf("CreateSpec failed: %v", err)
+	}
+	if err := s.CreateSpec(&models.Spec{ID: "spec-2"}); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded for a second spec, got %v", err)
+	}
+
+	if err := s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", Body: "123456"}); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded for body bytes, got %v", err)
+	}
+	if err := s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", Body: "12345"}); err != nil {
+		t.Fatalf("CreateResponseConfig failed: %v", err)
+	}
+	if err := s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-2"}); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded for a second response config, got %v", err)
+	}
+
+	// Updates only count the new size of the item
+	if err := s.UpdateResponseConfig(&models.ResponseConfig{ID: "rc-1", Body: "1234"}); err != nil {
+		t.Errorf("UpdateResponseConfig failed: %v", err)
+	}
+	if err := s.UpdateSpec(&models.Spec{ID: "spec-1", Content: "1234567"}); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded for a larger spec, got %v", err)
+	}
+}
+
+func TestMemoryLimits_EvictOldest(t *testing.T) {
+	s := NewMemoryStorage()
+	if err := s.SetLimits(MemoryLimits{MaxSpecs: 2, Eviction: EvictOldest}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+
+	base := time.Now()
+	for i, id := range []string{"spec-1", "spec-2"} {
+		_ = s.CreateSpec(&models.Spec{ID: id, CreatedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+	_ = s.CreateOperation(&models.Operation{ID: "op-1", SpecID: "spec-1"})
+	_ = s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", OperationID: "op-1"})
+
+	if err := s.CreateSpec(&models.Spec{ID: "spec-3", CreatedAt: base.Add(2 * time.Second)}); err != nil {
+		t.Fatalf("CreateSpec failed: %v", err)
+	}
+
+	if _, err := s.GetSpec("spec-1"); err == nil {
+		t.Error("Expected the oldest spec to be evicted")
+	}
+	if _, err := s.GetOperation("op-1"); err == nil {
+		t.Error("Expected the operations of the evicted spec to be deleted")
+	}
+	if _, err := s.GetResponseConfig("rc-1"); err == nil {
+		t.Error("Expected the response configs of the evicted spec to be deleted")
+	}
+	if _, err := s.GetSpec("spec-2"); err != nil {
+		t.Errorf("Expected spec-2 to be kept: %v", err)
+	}
+}
+
+func TestMemoryLimits_ChangedBeforeUpdate(t *testing.T) {
+	s := NewMemoryStorage()
+	if err := s.SetLimits(MemoryLimits{MaxBodyBytes: 10}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+	_ = s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-1", Body: "12345"})
+	_ = s.CreateResponseConfig(&models.ResponseConfig{ID: "rc-2", Body: "12345"})
+
+	// The size stored with the config counts, not the one of the changed stored item
+	cfg, _ := s.GetResponseConfig("rc-1")
+	cfg.Body = "123456"
+	if err := s.UpdateResponseConfig(cfg); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded for a larger body, got %v", err)
+	}
+
+	// Updates that don't grow are accepted even over the limit
+	if err := s.SetLimits(MemoryLimits{MaxBodyBytes: 5}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+	if err := s.UpdateResponseConfig(&models.ResponseConfig{ID: "rc-2", Body: "1234", Enabled: true}); err != nil {
+		t.Errorf("Expected a smaller body accepted, got %v", err)
+	}
+}
+
+func TestMemoryLimits_EvictHandler(t *testing.T) {
+	s := NewMemoryStorage()
+	if err := s.SetLimits(MemoryLimits{MaxSpecs: 1, Eviction: EvictOldest}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+
+	var evicted []string
+	s.SetEvictHandler(func(specID string) {
+		// The handler runs once the storage is unlocked
+		if _, err := s.GetSpec(specID); err == nil {
+			t.Errorf("Expected %s gone when reported", specID)
+		}
+		evicted = append(evicted, specID)
+	})
+
+	_ = s.CreateSpec(&models.Spec{ID: "spec-1", CreatedAt: time.Now()})
+	if err := s.CreateSpec(&models.Spec{ID: "spec-2", CreatedAt: time.Now().Add(time.Second)}); err != nil {
+		t.Fatalf("CreateSpec failed: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0] != "spec-1" {
+		t.Errorf("Expected spec-1 reported as evicted, got %v", evicted)
+	}
+}
+
+func TestMemoryLimits_InvalidEviction(t *testing.T) {
+	s := NewMemoryStorage()
+	if err := s.SetLimits(MemoryLimits{Eviction: "random"}); err == nil {
+		t.Error("Expected error for an unknown eviction policy")
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/parser"
+)
+
+// Counts holds the number of items in a storage
+type Counts struct {
+	Specs           int `json:"specs"`
+	Operations      int `json:"operations"`
+	ResponseConfigs int `json:"responseConfigs"`
+	Files           int `json:"files"`
+}
+
+// CountAll counts the specs, operations and response configs in a storage
+func CountAll(s Storage) (Counts, error) {
+	var counts Counts
+
+	specs, err := s.GetAllSpecs()
+	if err != nil {
+		return counts, err
+	}
+	counts.Specs = len(specs)
+
+	for _, spec := range specs {
+		files, err := s.GetSpecFiles(spec.ID)
+		if err != nil {
+			return counts, err
+		}
+		counts.Files += len(files)
+	}
+
+	ops, err := s.GetAllOperations()
+	if err != nil {
+		return counts, err
+	}
+	counts.Operations = len(ops)
+
+	for _, op := range ops {
+		cfgs, err := s.GetResponseConfigsByOperation(op.ID)
+		if err != nil {
+			return counts, err
+		}
+		counts.ResponseConfigs += len(cfgs)
+	}
+	return counts, nil
+}
+
+// Open opens a persistent storage from a "<type>:<location>" string such as "file:./data"
+// File storage is the only persistent backend, so "file" is the only type
+func Open(location string) (Storage, error) {
+	kind, path, _ := strings.Cut(location, ":")
+	switch kind {
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file storage needs a directory, e.g. file:./data")
+		}
+		return NewFileStorage(path)
+	default:
+		return nil, fmt.Errorf("unsupported storage %q: file storage (file:<dir>) is the only persistent backend", location)
+	}
+}
+
+// Migrate copies all specs with their files, operations and response configs from one storage
+// to another
+// The target must be empty; it returns the counts copied
+// Response configs of operations that no longer exist are not copied
+func Migrate(from, to Storage) (Counts, error) {
+	var copied Counts
+
+	existing, err := to.GetAllSpecs()
+	if err != nil {
+		return copied, err
+	}
+	if len(existing) > 0 {
+		return copied, fmt.Errorf("target storage is not empty (%d specs)", len(existing))
+	}
+
+	specs, err := from.GetAllSpecs()
+	if err != nil {
+		return copied, err
+	}
+
+	p := parser.NewParser()
+	for _, spec := range specs {
+		specCopy := *spec
+		if err := to.CreateSpec(&specCopy); err != nil {
+			return copied, fmt.Errorf("failed to copy spec %s: %w", spec.ID, err)
+		}
+		copied.Specs++
+
+		files, err := copySpecFiles(from, to, spec.ID)
+		copied.Files += files
+		if err != nil {
+			return copied, err
+		}
+
+		ops, err := from.GetOperationsBySpec(spec.ID)
+		if err != nil {
+			return copied, err
+		}
+		kept := make(map[string]bool, len(ops))
+		for _, op := range ops {
+			opCopy := *op
+			if err := to.CreateOperation(&opCopy); err != nil {
+				return copied, fmt.Errorf("failed to copy operation %s: %w", op.ID, err)
+			}
+			kept[op.ID] = true
+			copied.Operations++
+
+			cfgs, err := from.GetResponseConfigsByOperation(op.ID)
+			if err != nil {
+				return copied, err
+			}
+			for _, cfg := range cfgs {
+				cfgCopy := *cfg
+				if err := to.CreateResponseConfig(&cfgCopy); err != nil {
+					return copied, fmt.Errorf("failed to copy response config %s: %w", cfg.ID, err)
+				}
+				copied.ResponseConfigs++
+			}
+		}
+
+		// Operations deleted from the spec are deleted in the target too, so storages that
+		// regenerate operations from spec content don't bring them back
+		if spec.Content == "" {
+			continue
+		}
+		derived, err := p.ParseOperations(spec.Content, spec.ID, spec.BasePath)
+		if err != nil {
+			continue
+		}
+		for _, op := range derived {
+			if kept[op.ID] {
+				continue
+			}
+			if err := to.CreateOperation(op); err == nil {
+				to.DeleteOperation(op.ID)
+			}
+		}
+	}
+
+	return copied, nil
+}
+
+// copySpecFiles copies the files stored with a spec, returning how many were copied
+func copySpecFiles(from, to Storage, specID string) (int, error) {
+	files, err := from.GetSpecFiles(specID)
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range files {
+		reader, _, err := from.OpenSpecFile(specID, file.Name)
+		if err != nil {
+			return i, err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return i, err
+		}
+		if err := to.SaveSpecFile(specID, file.Name, data); err != nil {
+			return i, fmt.Errorf("failed to copy file %s of spec %s: %w", file.Name, specID, err)
+		}
+	}
+	return len(files), nil
+}
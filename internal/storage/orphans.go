@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OrphanReport lists data left behind in storage that nothing refers to
+// Paths are relative to the storage directory
+type OrphanReport struct {
+	ResponseConfigs []string `json:"responseConfigs"` // IDs of response configs whose operation no longer exists
+	BodyFiles       []string `json:"bodyFiles"`       // Response body files without a response config using them
+	ContentFiles    []string `json:"contentFiles"`    // Spec content files that are not loaded for any spec
+	Removed         bool     `json:"removed"`         // Whether the orphans were deleted
+}
+
+// Count returns the number of orphans in the report
+func (r *OrphanReport) Count() int {
+	return len(r.ResponseConfigs) + len(r.BodyFiles) + len(r.ContentFiles)
+}
+
+// Maintainer is implemented by storages that can find and remove orphaned data
+type Maintainer interface {
+	// Orphans reports orphaned data, deleting it when remove is set
+	Orphans(remove bool) (*OrphanReport, error)
+}
+
+// newOrphanReport creates an empty report, so lists encode as [] rather than null
+func newOrphanReport(remove bool) *OrphanReport {
+	return &OrphanReport{
+		ResponseConfigs: []string{},
+		BodyFiles:       []string{},
+		ContentFiles:    []string{},
+		Removed:         remove,
+	}
+}
+
+// Orphans reports response configs whose operation no longer exists, deleting them when remove is set
+func (m *MemoryStorage) Orphans(remove bool) (*OrphanReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := newOrphanReport(remove)
+	for id, cfg := range m.responseConfigs {
+		if _, ok := m.operations[cfg.OperationID]; ok {
+			continue
+		}
+		report.ResponseConfigs = append(report.ResponseConfigs, id)
+		if remove {
+			delete(m.responseConfigs, id)
+		}
+	}
+	sort.Strings(report.ResponseConfigs)
+	return report, nil
+}
+
+// Orphans reports response configs whose operation no longer exists, body files without a
+// response config and spec content files that are not loaded, deleting them when remove is set
+// Deleting also compacts the operations index to the current specs
+func (f *FileStorage) Orphans(remove bool) (*OrphanReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	report, err := f.memory.Orphans(remove)
+	if err != nil {
+		return nil, err
+	}
+	if remove {
+		for _, id := range report.ResponseConfigs {
+			f.deleteResponseConfigFile(id)
+		}
+	}
+
+	f.memory.mu.RLock()
+	defer f.memory.mu.RUnlock()
+
+	// A body file is stale when its config is gone or no longer has a body;
+	// loading it would bring back an old body
+	respDir := filepath.Join(f.basePath, "responses")
+	bodies, _ := filepath.Glob(filepath.Join(respDir, "*.body"))
+	for _, path := range bodies {
+		id := strings.TrimSuffix(filepath.Base(path), ".body")
+		if cfg, ok := f.memory.responseConfigs[id]; ok && cfg.Body != "" {
+			continue
+		}
+		report.BodyFiles = append(report.BodyFiles, filepath.ToSlash(filepath.Join("responses", filepath.Base(path))))
+		if remove {
+			os.Remove(path)
+		}
+	}
+
+	// Only the first content file in load order is used for a spec
+	specsDir := filepath.Join(f.basePath, "specs")
+	entries, err := os.ReadDir(specsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		present[entry.Name()] = true
+	}
+	for _, entry := range entries {
+		id, ext, ok := splitContentFile(entry.Name())
+		if !ok {
+			continue
+		}
+		if _, exists := f.memory.specs[id]; exists && loadedContentExt(present, id) == ext {
+			continue
+		}
+		report.ContentFiles = append(report.ContentFiles, filepath.ToSlash(filepath.Join("specs", entry.Name())))
+		if remove {
+			os.Remove(filepath.Join(specsDir, entry.Name()))
+		}
+	}
+
+	if remove {
+		f.compactIndex()
+	}
+
+	sort.Strings(report.BodyFiles)
+	sort.Strings(report.ContentFiles)
+	return report, nil
+}
+
+// splitContentFile splits a spec content file name into the spec ID and extension
+func splitContentFile(name string) (string, string, bool) {
+	// .spec.json comes before .json metadata files, which also end in .json
+	for _, ext := range specContentExtensions {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return strings.TrimSuffix(name, ext), ext, true
+		}
+	}
+	return "", "", false
+}
+
+// loadedContentExt returns the extension of the content file loaded for a spec
+func loadedContentExt(present map[string]bool, specID string) string {
+	for _, ext := range specContentExtensions {
+		if present[specID+ext] {
+			return ext
+		}
+	}
+	return ""
+}
+
+// compactIndex drops index entries of specs that no longer exist
+func (f *FileStorage) compactIndex() {
+	index := f.loadIndex()
+	changed := false
+	for id := range index.Specs {
+		if _, ok := f.memory.specs[id]; !ok {
+			delete(index.Specs, id)
+			changed = true
+		}
+	}
+	if changed {
+		f.saveIndex(index)
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// sequencesFile holds the sequence counters of all operations of a file storage
+const sequencesFile = "sequences.json"
+
+// Sequencer is implemented by storages that keep a sequence counter per operation
+type Sequencer interface {
+	// NextSequence increments the counter of an operation and returns its new value, starting at 1
+	NextSequence(opID string) (int64, error)
+	// ResetSequence starts the counter of an operation over
+	ResetSequence(opID string) error
+}
+
+// NextSequence increments the sequence counter of an operation
+func (m *MemoryStorage) NextSequence(opID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sequences == nil {
+		m.sequences = make(map[string]int64)
+	}
+	m.sequences[opID]++
+	return m.sequences[opID], nil
+}
+
+// ResetSequence starts the sequence counter of an operation over
+func (m *MemoryStorage) ResetSequence(opID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sequences, opID)
+	return nil
+}
+
+// sequencesJSON encodes the sequence counters of all operations
+func (m *MemoryStorage) sequencesJSON() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return json.Marshal(m.sequences)
+}
+
+// NextSequence increments the sequence counter of an operation and persists it
+// The counter is incremented even if persisting it fails
+func (f *FileStorage) NextSequence(opID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, _ := f.memory.NextSequence(opID)
+	return value, f.saveSequences()
+}
+
+// ResetSequence starts the sequence counter of an operation over and persists it
+func (f *FileStorage) ResetSequence(opID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.memory.ResetSequence(opID)
+	return f.saveSequences()
+}
+
+// saveSequences writes the sequence counters of all operations
+func (f *FileStorage) saveSequences() error {
+	data, err := f.memory.sequencesJSON()
+	if err != nil {
+		return err
+	}
+	return f.writeFile(filepath.Join(f.basePath, sequencesFile), data)
+}
+
+// loadSequences reads the persisted sequence counters; unreadable counters start over
+func (f *FileStorage) loadSequences() {
+	path := filepath.Join(f.basePath, sequencesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read sequence counters", "path", path, "error", err)
+		}
+		return
+	}
+
+	var sequences map[string]int64
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		slog.Warn("ignoring invalid sequence counters", "path", path, "error", err)
+		return
+	}
+	f.memory.sequences = sequences
+}
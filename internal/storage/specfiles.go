@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// ErrInvalidFileName is returned for spec file names that could escape the spec's files
+var ErrInvalidFileName = errors.New("invalid file name")
+
+// specFileNamePattern matches names of spec files; they can't start with a dot
+var specFileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// ValidSpecFileName reports whether a name can be used for a spec file
+func ValidSpecFileName(name string) bool {
+	return len(name) <= 255 && specFileNamePattern.MatchString(name)
+}
+
+// newSpecFile describes a stored spec file, with the content type of its extension
+func newSpecFile(name string, size int64, updatedAt time.Time) *models.SpecFile {
+	return &models.SpecFile{
+		Name:        name,
+		Size:        size,
+		ContentType: mime.TypeByExtension(filepath.Ext(name)),
+		UpdatedAt:   updatedAt,
+	}
+}
+
+// sortSpecFiles sorts spec files by name
+func sortSpecFiles(files []*models.SpecFile) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+}
+
+// memoryFile is the content of a spec file held by MemoryStorage
+type memoryFile struct {
+	data      []byte
+	updatedAt time.Time
+}
+
+// SaveSpecFile stores a file with a spec, replacing a file of the same name
+func (m *MemoryStorage) SaveSpecFile(specID, name string, data []byte) error {
+	if !ValidSpecFileName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidFileName, name)
+	}
+
+	defer m.notifyEvicted()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.specs[specID]; !exists {
+		return fmt.Errorf("spec not found: %s", specID)
+	}
+
+	bytes := func() int64 {
+		total := m.bodyBytes() + int64(len(data))
+		if old, ok := m.files[specID][name]; ok {
+			total -= int64(len(old.data))
+		}
+		return total
+	}
+	if err := m.ensureCapacity(0, 0, bytes, specID); err != nil {
+		return err
+	}
+
+	if m.files[specID] == nil {
+		m.files[specID] = make(map[string]*memoryFile)
+	}
+	m.files[specID][name] = &memoryFile{data: append([]byte(nil), data...), updatedAt: time.Now()}
+	return nil
+}
+
+// OpenSpecFile opens a file stored with a spec
+func (m *MemoryStorage) OpenSpecFile(specID, name string) (io.ReadSeekCloser, *models.SpecFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, exists := m.files[specID][name]
+	if !exists {
+		return nil, nil, fmt.Errorf("file not found: %s", name)
+	}
+
+	// Stored data is never modified in place, so readers can share it
+	reader := nopCloser{bytes.NewReader(file.data)}
+	return reader, newSpecFile(name, int64(len(file.data)), file.updatedAt), nil
+}
+
+// GetSpecFiles lists the files stored with a spec
+func (m *MemoryStorage) GetSpecFiles(specID string) ([]*models.SpecFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := make([]*models.SpecFile, 0, len(m.files[specID]))
+	for name, file := range m.files[specID] {
+		files = append(files, newSpecFile(name, int64(len(file.data)), file.updatedAt))
+	}
+	sortSpecFiles(files)
+	return files, nil
+}
+
+// DeleteSpecFile deletes a file stored with a spec
+func (m *MemoryStorage) DeleteSpecFile(specID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.files[specID][name]; !exists {
+		return fmt.Errorf("file not found: %s", name)
+	}
+	delete(m.files[specID], name)
+	if len(m.files[specID]) == 0 {
+		delete(m.files, specID)
+	}
+	return nil
+}
+
+// nopCloser adds a no-op Close to a reader of in-memory data
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// specFilesDir returns the directory holding the files of a spec
+func (f *FileStorage) specFilesDir(specID string) string {
+	return filepath.Join(f.basePath, "files", specID)
+}
+
+// SaveSpecFile stores a file with a spec, replacing a file of the same name
+func (f *FileStorage) SaveSpecFile(specID, name string, data []byte) error {
+	if !ValidSpecFileName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidFileName, name)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.memory.GetSpec(specID); err != nil {
+		return err
+	}
+
+	dir := f.specFilesDir(specID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return f.writeFile(filepath.Join(dir, name), data)
+}
+
+// OpenSpecFile opens a file stored with a spec
+func (f *FileStorage) OpenSpecFile(specID, name string) (io.ReadSeekCloser, *models.SpecFile, error) {
+	if !ValidSpecFileName(name) {
+		return nil, nil, fmt.Errorf("file not found: %s", name)
+	}
+
+	file, err := os.Open(filepath.Join(f.specFilesDir(specID), name))
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("file not found: %s", name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, newSpecFile(name, info.Size(), info.ModTime()), nil
+}
+
+// GetSpecFiles lists the files stored with a spec
+func (f *FileStorage) GetSpecFiles(specID string) ([]*models.SpecFile, error) {
+	entries, err := os.ReadDir(f.specFilesDir(specID))
+	if os.IsNotExist(err) {
+		return []*models.SpecFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*models.SpecFile, 0, len(entries))
+	for _, entry := range entries {
+		// Leftover temporary files of interrupted writes start with a dot
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, newSpecFile(entry.Name(), info.Size(), info.ModTime()))
+	}
+	sortSpecFiles(files)
+	return files, nil
+}
+
+// DeleteSpecFile deletes a file stored with a spec
+func (f *FileStorage) DeleteSpecFile(specID, name string) error {
+	if !ValidSpecFileName(name) {
+		return fmt.Errorf("file not found: %s", name)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(filepath.Join(f.specFilesDir(specID), name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", name)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func readSpecFile(t *testing.T, s Storage, specID, name string) (string, *models.SpecFile) {
+	file, info, err := s.OpenSpecFile(specID, name)
+	if err != nil {
+		t.Fatalf("OpenSpecFile failed: %v", err)
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	return string(data), info
+}
+
+func TestSpecFiles(t *testing.T) {
+	fileStore, _ := newTestFileStorage(t)
+	memory := NewMemoryStorage()
+	memory.CreateSpec(&models.Spec{ID: "spec-1", Name: "API"})
+
+	for name, s := range map[string]Storage{"memory": memory, "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			if err := s.SaveSpecFile("spec-1", "report.pdf", []byte("%PDF-1.4 v1")); err != nil {
+				t.Fatalf("SaveSpecFile failed: %v", err)
+			}
+			if err := s.SaveSpecFile("spec-1", "report.pdf", []byte("%PDF-1.4 v2")); err != nil {
+				t.Fatalf("SaveSpecFile failed: %v", err)
+			}
+			s.SaveSpecFile("spec-1", "data", []byte("raw"))
+
+			data, info := readSpecFile(t, s, "spec-1", "report.pdf")
+			if data != "%PDF-1.4 v2" || info.Size != 11 || info.ContentType != "application/pdf" {
+				t.Errorf("Unexpected file: %q %+v", data, info)
+			}
+
+			files, _ := s.GetSpecFiles("spec-1")
+			if len(files) != 2 || files[0].Name != "data" || files[0].ContentType != "" || files[1].Name != "report.pdf" {
+				t.Errorf("Unexpected files: %+v", files)
+			}
+
+			for _, name := range []string{"../escape", ".hidden", "a/b", ""} {
+				if err := s.SaveSpecFile("spec-1", name, nil); !errors.Is(err, ErrInvalidFileName) {
+					t.Errorf("Expected ErrInvalidFileName for %q, got %v", name, err)
+				}
+			}
+			if err := s.SaveSpecFile("missing", "report.pdf", nil); err == nil {
+				t.Error("Expected an error for a missing spec")
+			}
+
+			if err := s.DeleteSpecFile("spec-1", "data"); err != nil {
+				t.Fatalf("DeleteSpecFile failed: %v", err)
+			}
+			if _, _, err := s.OpenSpecFile("spec-1", "data"); err == nil {
+				t.Error("Expected the file to be deleted")
+			}
+			if err := s.DeleteSpecFile("spec-1", "data"); err == nil {
+				t.Error("Expected an error deleting a missing file")
+			}
+
+			// Files are deleted with their spec
+			s.DeleteSpec("spec-1")
+			if files, _ := s.GetSpecFiles("spec-1"); len(files) != 0 {
+				t.Errorf("Expected no files after deleting the spec, got %+v", files)
+			}
+		})
+	}
+}
+
+func TestFileStorage_SpecFilesPersist(t *testing.T) {
+	f, dir := newTestFileStorage(t)
+	f.SaveSpecFile("spec-1", "logo.png", []byte("png"))
+
+	reopened, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	if data, _ := readSpecFile(t, reopened, "spec-1", "logo.png"); data != "png" {
+		t.Errorf("Expected the file after reopening, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "files", "spec-1", "logo.png")); err != nil {
+		t.Errorf("Expected the file in the spec's files directory: %v", err)
+	}
+}
+
+func TestMemoryStorage_SpecFileLimits(t *testing.T) {
+	m := NewMemoryStorage()
+	m.SetLimits(MemoryLimits{MaxBodyBytes: 10})
+	m.CreateSpec(&models.Spec{ID: "spec-1", Content: "12345"})
+
+	if err := m.SaveSpecFile("spec-1", "a.txt", []byte("12345")); err != nil {
+		t.Fatalf("Expected the file to fit, got %v", err)
+	}
+	// Replacing a file only counts the new content
+	if err := m.SaveSpecFile("spec-1", "a.txt", []byte("54321")); err != nil {
+		t.Fatalf("Expected the replacement to fit, got %v", err)
+	}
+	if err := m.SaveSpecFile("spec-1", "b.txt", []byte("1")); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+	}
+}
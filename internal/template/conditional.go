@@ -0,0 +1,97 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+)
+
+// conditionPattern matches a comparison like `path.id == "1"`
+var conditionPattern = regexp.MustCompile(`^(.+?)\s*(==|!=)\s*(.+)$`)
+
+// conditionalFrame tracks an open {{if}} block while processing conditionals
+type conditionalFrame struct {
+	parentActive bool // Whether the enclosing block is being rendered
+	taken        bool // Whether a branch of this block already matched
+	active       bool // Whether the current branch is being rendered
+}
+
+// processConditionals renders {{if cond}}...{{else if cond}}...{{else}}...{{end}} blocks
+// Other variables are left in place for substitution. Blocks can be nested; an unclosed
+// block runs to the end of the template
+func (e *Engine) processConditionals(template string, ctx *Context) string {
+	if !strings.Contains(template, "{{if ") {
+		return template
+	}
+
+	var sb strings.Builder
+	var stack []*conditionalFrame
+
+	active := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].active
+	}
+
+	last := 0
+	for _, loc := range templateVarPattern.FindAllStringSubmatchIndex(template, -1) {
+		if active() {
+			sb.WriteString(template[last:loc[0]])
+		}
+		last = loc[1]
+
+		directive := strings.TrimSpace(template[loc[2]:loc[3]])
+		switch {
+		case strings.HasPrefix(directive, "if "):
+			parentActive := active()
+			matched := parentActive && e.evaluateCondition(strings.TrimPrefix(directive, "if "), ctx)
+			stack = append(stack, &conditionalFrame{parentActive: parentActive, taken: matched, active: matched})
+			continue
+		case len(stack) > 0 && strings.HasPrefix(directive, "else if "):
+			frame := stack[len(stack)-1]
+			frame.active = false
+			if frame.parentActive && !frame.taken && e.evaluateCondition(strings.TrimPrefix(directive, "else if "), ctx) {
+				frame.active = true
+				frame.taken = true
+			}
+			continue
+		case len(stack) > 0 && directive == "else":
+			frame := stack[len(stack)-1]
+			frame.active = frame.parentActive && !frame.taken
+			frame.taken = true
+			continue
+		case len(stack) > 0 && directive == "end":
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if active() {
+			sb.WriteString(template[loc[0]:loc[1]])
+		}
+	}
+
+	if active() {
+		sb.WriteString(template[last:])
+	}
+
+	return sb.String()
+}
+
+// evaluateCondition evaluates an inline condition
+// Supported forms are `a == b`, `a != b`, `value` (true when non-empty and not "false")
+// and `!value`. Operands are quoted strings, numbers or template variables
+func (e *Engine) evaluateCondition(expr string, ctx *Context) bool {
+	expr = strings.TrimSpace(expr)
+
+	if m := conditionPattern.FindStringSubmatch(expr); m != nil {
+		equal := e.resolveOperand(m[1], ctx) == e.resolveOperand(m[3], ctx)
+		if m[2] == "!=" {
+			return !equal
+		}
+		return equal
+	}
+
+	if strings.HasPrefix(expr, "!") {
+		return !e.evaluateCondition(strings.TrimPrefix(expr, "!"), ctx)
+	}
+
+	value := e.resolveOperand(expr, ctx)
+	return value != "" && value != "false"
+}
@@ -1,20 +1,32 @@
 package template
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"math/rand"
+	"net/url"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prasenjit/go-virtual/internal/variables"
 	"github.com/tidwall/gjson"
 )
 
 // Engine processes template strings with variable substitution
 type Engine struct {
 	rng *rand.Rand
+
+	mu           sync.RWMutex
+	envAllowlist []string // Environment variables readable with {{env.NAME}}
 }
 
 // NewEngine creates a new template engine
@@ -24,12 +36,57 @@ func NewEngine() *Engine {
 	}
 }
 
+// SetEnvAllowlist sets the environment variables templates may read with {{env.NAME}}
+// An entry ending in "*" allows all variables with that prefix. Nothing is readable by default
+func (e *Engine) SetEnvAllowlist(names []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.envAllowlist = append([]string(nil), names...)
+}
+
+// envAllowed reports whether an environment variable is on the allowlist
+func (e *Engine) envAllowed(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, allowed := range e.envAllowlist {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Context contains all data available for template rendering
 type Context struct {
 	PathParams  map[string]string
 	QueryParams map[string][]string
 	Headers     map[string][]string
 	Body        string
+	Method      string
+	Path        string
+	URL         string            // Request URI including the query string
+	RequestID   string            // ID correlating the request across logs and traces
+	Snippets    map[string]string // Named snippets available to {{include "name"}}
+	Variables   *variables.Scope  // Shared spec variables, read with {{vars.name}}
+	RateLimit   map[string]string // Throttling state of the operation, read with {{rateLimit.name}}
+	Page        map[string]string // Page of a paginated response, read with {{page.name}}
+	Item        map[string]string // Position of a generated dataset item, read with {{item.index}}
+	Sequence    Sequence          // Counter of the operation, read with {{sequence}}
+	Random      *rand.Rand        // Source of {{random.*}} values; the engine's own when nil
+	Locale      string            // Locale of {{fake.*}} values without their own
+
+	sequence string // Value of {{sequence}} drawn for this response
+}
+
+// Sequence is a counter of strictly increasing integers
+type Sequence interface {
+	Next() int64
+	Reset()
 }
 
 // templateVarPattern matches template variables like {{variable}}
@@ -37,6 +94,9 @@ var templateVarPattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
 // Process processes a template string and replaces all variables
 func (e *Engine) Process(template string, ctx *Context) string {
+	template = e.processIncludes(template, ctx, 0)
+	template = e.processConditionals(template, ctx)
+
 	return templateVarPattern.ReplaceAllStringFunc(template, func(match string) string {
 		// Extract variable name (remove {{ and }})
 		varName := strings.TrimSpace(match[2 : len(match)-2])
@@ -44,6 +104,29 @@ func (e *Engine) Process(template string, ctx *Context) string {
 	})
 }
 
+// includePattern matches snippet includes like {{include "address"}}
+var includePattern = regexp.MustCompile(`\{\{\s*include\s+["']([^"']+)["']\s*\}\}`)
+
+// maxIncludeDepth limits nested includes so that cyclic snippets terminate
+const maxIncludeDepth = 10
+
+// processIncludes replaces snippet includes with the snippet content, expanding nested includes
+// Unknown snippets are replaced with an empty string
+func (e *Engine) processIncludes(template string, ctx *Context, depth int) string {
+	if depth >= maxIncludeDepth || !strings.Contains(template, "include") {
+		return template
+	}
+
+	return includePattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := includePattern.FindStringSubmatch(match)[1]
+		snippet, ok := ctx.Snippets[name]
+		if !ok {
+			return ""
+		}
+		return e.processIncludes(snippet, ctx, depth+1)
+	})
+}
+
 // ProcessHeaders processes all headers and replaces template variables
 func (e *Engine) ProcessHeaders(headers map[string]string, ctx *Context) map[string]string {
 	result := make(map[string]string)
@@ -53,10 +136,37 @@ func (e *Engine) ProcessHeaders(headers map[string]string, ctx *Context) map[str
 	return result
 }
 
+// templateFuncs are helpers applied to an argument, e.g. {{base64.decode header.X-Payload}}
+var templateFuncs = map[string]func(string) string{
+	"base64.encode": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"base64.decode": decodeBase64,
+	"urlencode":     url.QueryEscape,
+	"urldecode": func(s string) string {
+		decoded, err := url.QueryUnescape(s)
+		if err != nil {
+			return ""
+		}
+		return decoded
+	},
+	"jsonescape": func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data[1 : len(data)-1])
+	},
+}
+
 // resolveVariable resolves a single variable to its value
 func (e *Engine) resolveVariable(varName string, ctx *Context) string {
 	// Handle optional leading dot (e.g., both "path.id" and ".path.id" are valid)
 	varName = strings.TrimPrefix(varName, ".")
+
+	// Apply a helper to its argument; arguments can be helpers themselves
+	if name, arg, ok := strings.Cut(varName, " "); ok {
+		if fn, ok := templateFuncs[name]; ok {
+			return fn(e.resolveOperand(arg, ctx))
+		}
+	}
 	
 	parts := strings.SplitN(varName, ".", 2)
 	if len(parts) < 1 {
@@ -91,32 +201,145 @@ func (e *Engine) resolveVariable(varName string, ctx *Context) string {
 				}
 			}
 		}
+	case "headers":
+		if key == "" {
+			return headersJSON(ctx.Headers)
+		}
+	case "request":
+		switch key {
+		case "method":
+			return ctx.Method
+		case "path":
+			return ctx.Path
+		case "url":
+			return ctx.URL
+		case "id":
+			return ctx.RequestID
+		}
 	case "body":
-		if key != "" && ctx.Body != "" {
+		if key == "" {
+			return ctx.Body
+		}
+		if ctx.Body != "" {
 			result := gjson.Get(ctx.Body, key)
 			if result.Exists() {
 				return result.String()
 			}
 		}
+	case "vars":
+		// {{vars.set name value}} stores a value and renders nothing
+		if rest, ok := strings.CutPrefix(key, "set "); ok {
+			name, valueExpr, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			if name != "" {
+				ctx.Variables.Set(name, e.resolveOperand(valueExpr, ctx))
+			}
+			return ""
+		}
+		if value, ok := ctx.Variables.Get(key); ok {
+			return value
+		}
+	case "rateLimit":
+		return ctx.RateLimit[key]
+	case "page":
+		return ctx.Page[key]
+	case "item":
+		return ctx.Item[key]
+	case "sequence":
+		return resolveSequence(key, ctx)
 	case "random":
-		return e.resolveRandom(key)
+		if ctx.Random != nil {
+			return resolveRandom(key, ctx.Random, ctx.Random)
+		}
+		return resolveRandom(key, e.rng, cryptorand.Reader)
+	case "fake":
+		return e.resolveFake(key, ctx)
 	case "timestamp":
 		return e.resolveTimestamp(key)
 	case "env":
-		// Environment variables could be added here if needed
-		return ""
+		if key != "" && e.envAllowed(key) {
+			return os.Getenv(key)
+		}
 	}
 
 	return ""
 }
 
+// resolveOperand resolves a quoted string, number, boolean or template variable to its string value
+func (e *Engine) resolveOperand(operand string, ctx *Context) string {
+	operand = strings.TrimSpace(operand)
+
+	if len(operand) >= 2 {
+		if quote := operand[0]; (quote == '"' || quote == '\'') && operand[len(operand)-1] == quote {
+			return operand[1 : len(operand)-1]
+		}
+	}
+
+	if _, err := strconv.ParseFloat(operand, 64); err == nil {
+		return operand
+	}
+	if operand == "true" || operand == "false" {
+		return operand
+	}
+
+	return e.resolveVariable(operand, ctx)
+}
+
+// headersJSON renders request headers as a JSON object, joining repeated values with ", "
+func headersJSON(headers map[string][]string) string {
+	flat := make(map[string]string, len(headers))
+	for key, values := range headers {
+		flat[key] = strings.Join(values, ", ")
+	}
+	data, _ := json.Marshal(flat)
+	return string(data)
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding
+func decodeBase64(s string) string {
+	s = strings.TrimSpace(s)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(s); err == nil {
+			return string(decoded)
+		}
+	}
+	return ""
+}
+
+// rand returns the source of random values of a context
+func (e *Engine) rand(ctx *Context) *rand.Rand {
+	if ctx.Random != nil {
+		return ctx.Random
+	}
+	return e.rng
+}
+
+// NewSeededRand creates a source of {{random.*}} values that repeats for the same seed
+func NewSeededRand(seed string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
 // resolveRandom resolves random value generators
-func (e *Engine) resolveRandom(key string) string {
+// UUIDs read their random bits from uuidSource, everything else from rng
+func resolveRandom(key string, rng *rand.Rand, uuidSource io.Reader) string {
 	switch {
 	case key == "uuid":
-		return uuid.New().String()
+		id, err := uuid.NewRandomFromReader(uuidSource)
+		if err != nil {
+			return ""
+		}
+		return id.String()
+	case key == "uuidv7":
+		id, err := uuid.NewV7FromReader(uuidSource)
+		if err != nil {
+			return ""
+		}
+		return id.String()
+	case key == "ulid":
+		return newULID(rng, time.Now())
 	case key == "int":
-		return strconv.Itoa(e.rng.Intn(1000000))
+		return strconv.Itoa(rng.Intn(1000000))
 	case strings.HasPrefix(key, "int("):
 		// Parse int(min,max)
 		params := parseParams(key, "int")
@@ -124,50 +347,69 @@ func (e *Engine) resolveRandom(key string) string {
 			min, _ := strconv.Atoi(params[0])
 			max, _ := strconv.Atoi(params[1])
 			if max > min {
-				return strconv.Itoa(min + e.rng.Intn(max-min+1))
+				return strconv.Itoa(min + rng.Intn(max-min+1))
 			}
 		}
-		return strconv.Itoa(e.rng.Intn(1000000))
+		return strconv.Itoa(rng.Intn(1000000))
 	case key == "float":
-		return fmt.Sprintf("%.2f", e.rng.Float64()*1000)
+		return fmt.Sprintf("%.2f", rng.Float64()*1000)
 	case strings.HasPrefix(key, "float("):
 		params := parseParams(key, "float")
 		if len(params) == 2 {
 			min, _ := strconv.ParseFloat(params[0], 64)
 			max, _ := strconv.ParseFloat(params[1], 64)
 			if max > min {
-				return fmt.Sprintf("%.2f", min+e.rng.Float64()*(max-min))
+				return fmt.Sprintf("%.2f", min+rng.Float64()*(max-min))
 			}
 		}
-		return fmt.Sprintf("%.2f", e.rng.Float64()*1000)
+		return fmt.Sprintf("%.2f", rng.Float64()*1000)
 	case key == "string":
-		return randomString(e.rng, 10)
+		return randomString(rng, 10)
 	case strings.HasPrefix(key, "string("):
 		params := parseParams(key, "string")
 		if len(params) == 1 {
 			length, _ := strconv.Atoi(params[0])
 			if length > 0 {
-				return randomString(e.rng, length)
+				return randomString(rng, length)
 			}
 		}
-		return randomString(e.rng, 10)
+		return randomString(rng, 10)
 	case key == "bool":
-		if e.rng.Intn(2) == 0 {
+		if rng.Intn(2) == 0 {
 			return "false"
 		}
 		return "true"
 	case key == "email":
-		return fmt.Sprintf("%s@example.com", randomString(e.rng, 8))
+		return fmt.Sprintf("%s@example.com", randomString(rng, 8))
 	case key == "name":
 		names := []string{"John", "Jane", "Bob", "Alice", "Charlie", "Diana", "Eve", "Frank"}
-		return names[e.rng.Intn(len(names))]
+		return names[rng.Intn(len(names))]
 	case key == "phone":
-		return fmt.Sprintf("+1-%03d-%03d-%04d", e.rng.Intn(1000), e.rng.Intn(1000), e.rng.Intn(10000))
+		return fmt.Sprintf("+1-%03d-%03d-%04d", rng.Intn(1000), rng.Intn(1000), rng.Intn(10000))
 	}
 
 	return ""
 }
 
+// resolveSequence resolves the operation counter; all uses in a response share one value
+// {{sequence.reset}} starts the counter over and renders nothing
+func resolveSequence(key string, ctx *Context) string {
+	if ctx.Sequence == nil {
+		return ""
+	}
+	switch key {
+	case "":
+		if ctx.sequence == "" {
+			ctx.sequence = strconv.FormatInt(ctx.Sequence.Next(), 10)
+		}
+		return ctx.sequence
+	case "reset":
+		ctx.Sequence.Reset()
+		ctx.sequence = ""
+	}
+	return ""
+}
+
 // resolveTimestamp resolves timestamp generators
 func (e *Engine) resolveTimestamp(key string) string {
 	now := time.Now()
@@ -225,6 +467,33 @@ func parseParams(key, funcName string) []string {
 	return strings.Split(paramsStr, ",")
 }
 
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID generates a ULID: a 48-bit millisecond timestamp followed by 80 random bits, encoded
+// as 26 characters of Crockford base32 that sort by time
+func newULID(rng *rand.Rand, t time.Time) string {
+	hi := uint64(t.UnixMilli())<<16 | uint64(rng.Intn(1<<16))
+	lo := rng.Uint64()
+
+	// The 128 bits are read 5 at a time from the top, so the first character holds only 3
+	result := make([]byte, 26)
+	for i := range result {
+		shift := uint(125 - 5*i)
+		var v uint64
+		switch {
+		case shift >= 64:
+			v = hi >> (shift - 64)
+		case shift > 59:
+			v = lo>>shift | hi<<(64-shift)
+		default:
+			v = lo >> shift
+		}
+		result[i] = crockford[v&31]
+	}
+	return string(result)
+}
+
 // randomString generates a random alphanumeric string
 func randomString(rng *rand.Rand, length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -1,9 +1,14 @@
 package template
 
 import (
+	"math/rand"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/variables"
 )
 
 func TestNewEngine(t *testing.T) {
@@ -274,6 +279,32 @@ func TestProcess_RandomValues(t *testing.T) {
 		}
 	})
 
+	t.Run("random uuidv7", func(t *testing.T) {
+		first := e.Process("{{random.uuidv7}}", ctx)
+		time.Sleep(2 * time.Millisecond)
+		second := e.Process("{{random.uuidv7}}", ctx)
+		uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		if !uuidPattern.MatchString(first) {
+			t.Errorf("expected version 7 UUID format, got %q", first)
+		}
+		if second <= first {
+			t.Errorf("expected later UUIDs to sort after earlier ones, got %q then %q", first, second)
+		}
+	})
+
+	t.Run("random ulid", func(t *testing.T) {
+		first := e.Process("{{random.ulid}}", ctx)
+		time.Sleep(2 * time.Millisecond)
+		second := e.Process("{{random.ulid}}", ctx)
+		ulidPattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+		if !ulidPattern.MatchString(first) {
+			t.Errorf("expected ULID format, got %q", first)
+		}
+		if second <= first {
+			t.Errorf("expected later ULIDs to sort after earlier ones, got %q then %q", first, second)
+		}
+	})
+
 	t.Run("random int", func(t *testing.T) {
 		result := e.Process("{{random.int}}", ctx)
 		if result == "" {
@@ -466,6 +497,209 @@ func TestProcess_MixedTemplate(t *testing.T) {
 	}
 }
 
+func TestProcess_Conditionals(t *testing.T) {
+	e := NewEngine()
+
+	ctx := &Context{
+		PathParams:  map[string]string{"id": "1"},
+		QueryParams: map[string][]string{"debug": {"true"}, "page": {"2"}},
+		Headers:     map[string][]string{"X-Role": {"admin"}},
+		Body:        `{"active": false}`,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"equal true", `{{if path.id == "1"}}first{{else}}other{{end}}`, "first"},
+		{"equal false", `{{if path.id == "2"}}first{{else}}other{{end}}`, "other"},
+		{"not equal", `{{if header.X-Role != 'admin'}}user{{else}}admin{{end}}`, "admin"},
+		{"number literal", `{{if query.page == 2}}page two{{end}}`, "page two"},
+		{"no else", `{{if path.id == "2"}}hidden{{end}}shown`, "shown"},
+		{"else if", `{{if path.id == "0"}}zero{{else if path.id == "1"}}one{{else}}many{{end}}`, "one"},
+		{"truthy", `{{if query.debug}}debug{{end}}`, "debug"},
+		{"falsy body value", `{{if body.active}}active{{else}}inactive{{end}}`, "inactive"},
+		{"missing value", `{{if query.missing}}yes{{else}}no{{end}}`, "no"},
+		{"negation", `{{if !query.missing}}no filter{{end}}`, "no filter"},
+		{"variables in branch", `{{if path.id == "1"}}id={{path.id}}{{end}}`, "id=1"},
+		{"nested", `{{if query.debug}}[{{if header.X-Role == "admin"}}admin{{else}}user{{end}}]{{else}}-{{end}}`, "[admin]"},
+		{"nested in inactive branch", `{{if query.missing}}{{if path.id}}a{{else}}b{{end}}{{else}}c{{end}}`, "c"},
+		{"unclosed", `{{if path.id == "2"}}hidden`, ""},
+		{"stray end", `text{{end}}`, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Process(tt.template, ctx)
+			if result != tt.expected {
+				t.Errorf("Process(%q) = %q, want %q", tt.template, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcess_EncodingFunctions(t *testing.T) {
+	e := NewEngine()
+
+	ctx := &Context{
+		QueryParams: map[string][]string{"q": {"a b&c"}},
+		Headers:     map[string][]string{"X-Payload": {"eyJpZCI6IDQyfQ=="}},
+		Body:        `{"message": "line1\nsaid \"hi\""}`,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"base64 encode", `{{base64.encode query.q}}`, "YSBiJmM="},
+		{"base64 decode", `{{base64.decode header.X-Payload}}`, `{"id": 42}`},
+		{"base64 decode unpadded", `{{base64.decode "aGk"}}`, "hi"},
+		{"base64 decode invalid", `{{base64.decode "***"}}`, ""},
+		{"urlencode", `{{urlencode query.q}}`, "a+b%26c"},
+		{"urldecode", `{{urldecode "a+b%26c"}}`, "a b&c"},
+		{"jsonescape", `{"echo": "{{jsonescape body.message}}"}`, `{"echo": "line1\nsaid \"hi\""}`},
+		{"chained", `{{urlencode base64.encode query.q}}`, "YSBiJmM%3D"},
+		{"literal", `{{base64.encode 'go'}}`, "Z28="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Process(tt.template, ctx)
+			if result != tt.expected {
+				t.Errorf("Process(%q) = %q, want %q", tt.template, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcess_RequestEcho(t *testing.T) {
+	e := NewEngine()
+
+	ctx := &Context{
+		Headers: map[string][]string{"Accept": {"application/json"}, "X-Tag": {"a", "b"}},
+		Body:      `{"name": "Test"}`,
+		Method:    "POST",
+		Path:      "/api/users",
+		URL:       "/api/users?page=2",
+		RequestID: "req-42",
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{body}}`, `{"name": "Test"}`},
+		{`{{request.method}}`, "POST"},
+		{`{{request.path}}`, "/api/users"},
+		{`{{request.url}}`, "/api/users?page=2"},
+		{`{{request.id}}`, "req-42"},
+		{`{{headers}}`, `{"Accept":"application/json","X-Tag":"a, b"}`},
+		{`{{request.unknown}}`, ""},
+	}
+
+	for _, tt := range tests {
+		if result := e.Process(tt.template, ctx); result != tt.expected {
+			t.Errorf("Process(%q) = %q, want %q", tt.template, result, tt.expected)
+		}
+	}
+}
+
+func TestProcess_Includes(t *testing.T) {
+	e := NewEngine()
+
+	ctx := &Context{
+		PathParams: map[string]string{"id": "7"},
+		Snippets: map[string]string{
+			"address": `{"city": "Springfield"}`,
+			"user":    `{"id": "{{path.id}}", "address": {{include "address"}}}`,
+			"loop":    `[{{include "loop"}}]`,
+		},
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{include "address"}}`, `{"city": "Springfield"}`},
+		{`{"user": {{ include 'user' }}}`, `{"user": {"id": "7", "address": {"city": "Springfield"}}}`},
+		{`{{include "missing"}}`, ""},
+	}
+
+	for _, tt := range tests {
+		if result := e.Process(tt.template, ctx); result != tt.expected {
+			t.Errorf("Process(%q) = %q, want %q", tt.template, result, tt.expected)
+		}
+	}
+
+	// Cyclic includes terminate
+	if result := e.Process(`{{include "loop"}}`, ctx); !strings.HasPrefix(result, "[[[") {
+		t.Errorf("Unexpected cyclic include result: %q", result)
+	}
+}
+
+func TestProcess_Variables(t *testing.T) {
+	e := NewEngine()
+	store := variables.NewStore()
+
+	ctx := &Context{
+		Body:      `{"id": "order-1"}`,
+		Variables: store.Scope("spec-1"),
+	}
+
+	result := e.Process(`{{vars.set orderId body.id}}{"stored": "{{vars.orderId}}"}`, ctx)
+	if result != `{"stored": "order-1"}` {
+		t.Errorf("Unexpected result: %q", result)
+	}
+
+	// A later request sees the stored value
+	result = e.Process(`{{vars.orderId}}`, &Context{Variables: store.Scope("spec-1")})
+	if result != "order-1" {
+		t.Errorf("Expected stored variable, got %q", result)
+	}
+
+	// Literal values, and no scope
+	e.Process(`{{vars.set status "shipped"}}`, ctx)
+	if value, _ := store.Get("spec-1", "status"); value != "shipped" {
+		t.Errorf("Expected literal to be stored, got %q", value)
+	}
+	if result := e.Process(`{{vars.set a "b"}}{{vars.a}}`, &Context{}); result != "" {
+		t.Errorf("Expected empty result without variables, got %q", result)
+	}
+}
+
+func TestProcess_EnvAllowlist(t *testing.T) {
+	t.Setenv("GOVIRTUAL_TEST_HOST", "api.internal")
+	t.Setenv("TENANT_ID", "acme")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+
+	e := NewEngine()
+	ctx := &Context{}
+
+	if result := e.Process("{{env.GOVIRTUAL_TEST_HOST}}", ctx); result != "" {
+		t.Errorf("Expected env to be blocked without allowlist, got %q", result)
+	}
+
+	e.SetEnvAllowlist([]string{"GOVIRTUAL_TEST_HOST", "TENANT_*"})
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{env.GOVIRTUAL_TEST_HOST}}", "api.internal"},
+		{"{{env.TENANT_ID}}", "acme"},
+		{"{{env.SECRET_TOKEN}}", ""},
+		{"{{env.TENANT_MISSING}}", ""},
+	}
+
+	for _, tt := range tests {
+		if result := e.Process(tt.template, ctx); result != tt.expected {
+			t.Errorf("Process(%q) = %q, want %q", tt.template, result, tt.expected)
+		}
+	}
+}
+
 func TestProcessHeaders(t *testing.T) {
 	e := NewEngine()
 
@@ -624,3 +858,131 @@ func TestRandomString(t *testing.T) {
 		}
 	})
 }
+
+// counter is an in-memory Sequence
+type counter struct {
+	value int64
+}
+
+func (c *counter) Next() int64 {
+	c.value++
+	return c.value
+}
+
+func (c *counter) Reset() {
+	c.value = 0
+}
+
+func TestProcess_Sequence(t *testing.T) {
+	e := NewEngine()
+	seq := &counter{}
+
+	// All uses in one response share a value
+	ctx := &Context{Sequence: seq}
+	if result := e.Process(`{"id": {{sequence}}, "href": "/items/{{sequence}}"}`, ctx); result != `{"id": 1, "href": "/items/1"}` {
+		t.Errorf("Expected one value per response, got %s", result)
+	}
+	if result := e.Process("{{sequence}}", &Context{Sequence: seq}); result != "2" {
+		t.Errorf("Expected the next response to get 2, got %s", result)
+	}
+
+	// Resetting starts over
+	if result := e.Process("{{sequence.reset}}{{sequence}}", &Context{Sequence: seq}); result != "1" {
+		t.Errorf("Expected 1 after a reset, got %s", result)
+	}
+
+	if result := e.Process("{{sequence}}", &Context{}); result != "" {
+		t.Errorf("Expected nothing without a sequence, got %s", result)
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// Timestamps of examples of the reference implementations
+	for ms, expected := range map[int64]string{1469918176385: "01ARYZ6S41", 1000000000: "0000XSNJG0"} {
+		if id := newULID(rng, time.UnixMilli(ms)); len(id) != 26 || id[:10] != expected {
+			t.Errorf("expected %d to encode as %s, got %q", ms, expected, id)
+		}
+	}
+
+	max := newULID(rand.New(rand.NewSource(1)), time.UnixMilli(1<<48-1))
+	if max[:10] != "7ZZZZZZZZZ" {
+		t.Errorf("expected the largest timestamp to encode as 7ZZZZZZZZZ, got %q", max)
+	}
+}
+
+func TestProcess_SeededRandom(t *testing.T) {
+	e := NewEngine()
+	template := `{"id": "{{random.uuid}}", "name": "{{random.name}}", "score": {{random.int(1,1000)}}, "code": "{{random.string(12)}}"}`
+
+	render := func(seed string) string {
+		return e.Process(template, &Context{Random: NewSeededRand(seed)})
+	}
+
+	first := render("42")
+	if second := render("42"); second != first {
+		t.Errorf("expected the same values for the same seed, got %s and %s", first, second)
+	}
+	if other := render("43"); other == first {
+		t.Errorf("expected other values for another seed, got %s twice", first)
+	}
+	if unseeded := e.Process(template, &Context{}); unseeded == first {
+		t.Errorf("expected unseeded values to differ, got %s", unseeded)
+	}
+}
+
+func TestProcess_Fake(t *testing.T) {
+	e := NewEngine()
+
+	t.Run("locale argument", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			phone := e.Process(`{{fake.phone "de_DE"}}`, &Context{})
+			if !regexp.MustCompile(`^\+49 30 \d{8}$`).MatchString(phone) {
+				t.Fatalf("expected a German phone number, got %q", phone)
+			}
+			postcode := e.Process(`{{fake.postcode "en-GB"}}`, &Context{})
+			if !regexp.MustCompile(`^[A-Z]{2}\d \d[A-Z]{2}$`).MatchString(postcode) {
+				t.Fatalf("expected a British postcode, got %q", postcode)
+			}
+		}
+	})
+
+	t.Run("context locale", func(t *testing.T) {
+		ctx := &Context{Locale: "ja"}
+		name := e.Process("{{fake.name}}", ctx)
+		last, _, _ := strings.Cut(name, " ")
+		if !slices.Contains(fakeLocales["ja_JP"].lastNames, last) {
+			t.Errorf("expected a Japanese name with the family name first, got %q", name)
+		}
+		if country := e.Process("{{fake.country}}", ctx); country != "日本" {
+			t.Errorf("expected 日本, got %q", country)
+		}
+		if country := e.Process(`{{fake.country "fr_FR"}}`, ctx); country != "France" {
+			t.Errorf("expected the argument to override the context locale, got %q", country)
+		}
+	})
+
+	t.Run("address format", func(t *testing.T) {
+		address := e.Process(`{{fake.address "de_DE"}}`, &Context{})
+		if !regexp.MustCompile(`^\D+ \d+, \d{5} .+$`).MatchString(address) {
+			t.Errorf("expected street, number, postcode and city, got %q", address)
+		}
+	})
+
+	t.Run("unknown locale", func(t *testing.T) {
+		if country := e.Process(`{{fake.country "xx_XX"}}`, &Context{}); country != "United States" {
+			t.Errorf("expected the default locale, got %q", country)
+		}
+		if result := e.Process("{{fake.unknown}}", &Context{}); result != "" {
+			t.Errorf("expected nothing for an unknown field, got %q", result)
+		}
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		first := e.Process(`{{fake.address "it_IT"}}`, &Context{Random: NewSeededRand("1")})
+		if second := e.Process(`{{fake.address "it_IT"}}`, &Context{Random: NewSeededRand("1")}); second != first {
+			t.Errorf("expected the same address for the same seed, got %q and %q", first, second)
+		}
+	})
+}
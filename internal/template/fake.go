@@ -0,0 +1,209 @@
+package template
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the locale of fake data when neither the template nor the spec names one
+const DefaultLocale = "en_US"
+
+// fakeLocale holds the data and formats fake values of a locale are drawn from
+// Formats use # for a digit and ? for an upper case letter; address formats also use
+// {number}, {street}, {city} and {postcode}
+type fakeLocale struct {
+	firstNames    []string
+	lastNames     []string
+	familyFirst   bool // Whether full names put the last name first
+	streets       []string
+	cities        []string
+	country       string
+	phoneFormat   string
+	postcodeFmt   string
+	addressFormat string
+}
+
+// fakeLocales are the locales of {{fake.*}} values, by name
+var fakeLocales = map[string]*fakeLocale{
+	"en_US": {
+		firstNames:    []string{"James", "Mary", "Robert", "Patricia", "Michael", "Jennifer", "David", "Linda", "Daniel", "Emily"},
+		lastNames:     []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller", "Davis", "Wilson", "Anderson", "Taylor"},
+		streets:       []string{"Main Street", "Oak Avenue", "Maple Drive", "Cedar Lane", "Park Road", "Washington Boulevard", "Elm Street", "Lake View Drive"},
+		cities:        []string{"Springfield", "Portland", "Austin", "Denver", "Columbus", "Madison", "Raleigh", "Sacramento"},
+		country:       "United States",
+		phoneFormat:   "+1 ###-###-####",
+		postcodeFmt:   "#####",
+		addressFormat: "{number} {street}, {city} {postcode}",
+	},
+	"en_GB": {
+		firstNames:    []string{"Oliver", "Amelia", "George", "Isla", "Harry", "Ava", "Jack", "Olivia", "Charlie", "Emily"},
+		lastNames:     []string{"Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Evans", "Thomas", "Roberts", "Walker"},
+		streets:       []string{"High Street", "Station Road", "Church Lane", "Victoria Road", "Green Lane", "Manor Road", "Kings Road", "Queens Road"},
+		cities:        []string{"London", "Manchester", "Bristol", "Leeds", "Edinburgh", "Cardiff", "Norwich", "York"},
+		country:       "United Kingdom",
+		phoneFormat:   "+44 20 #### ####",
+		postcodeFmt:   "??# #??",
+		addressFormat: "{number} {street}, {city} {postcode}",
+	},
+	"de_DE": {
+		firstNames:    []string{"Lukas", "Sophie", "Jonas", "Marie", "Leon", "Hannah", "Felix", "Lena", "Maximilian", "Jürgen"},
+		lastNames:     []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann"},
+		streets:       []string{"Hauptstraße", "Bahnhofstraße", "Gartenstraße", "Schulstraße", "Lindenweg", "Bergstraße", "Kirchplatz", "Goethestraße"},
+		cities:        []string{"Berlin", "München", "Hamburg", "Köln", "Frankfurt am Main", "Stuttgart", "Düsseldorf", "Leipzig"},
+		country:       "Deutschland",
+		phoneFormat:   "+49 30 ########",
+		postcodeFmt:   "#####",
+		addressFormat: "{street} {number}, {postcode} {city}",
+	},
+	"fr_FR": {
+		firstNames:    []string{"Gabriel", "Louise", "Léo", "Jade", "Raphaël", "Emma", "Arthur", "Chloé", "Hugo", "Léa"},
+		lastNames:     []string{"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau"},
+		streets:       []string{"rue de la Paix", "avenue Victor Hugo", "rue du Moulin", "boulevard Saint-Michel", "rue des Écoles", "place de la République", "rue Pasteur", "allée des Tilleuls"},
+		cities:        []string{"Paris", "Lyon", "Marseille", "Toulouse", "Nantes", "Bordeaux", "Lille", "Strasbourg"},
+		country:       "France",
+		phoneFormat:   "+33 1 ## ## ## ##",
+		postcodeFmt:   "#####",
+		addressFormat: "{number} {street}, {postcode} {city}",
+	},
+	"es_ES": {
+		firstNames:    []string{"Hugo", "Lucía", "Martín", "Sofía", "Pablo", "María", "Alejandro", "Paula", "Daniel", "Carmen"},
+		lastNames:     []string{"García", "Rodríguez", "González", "Fernández", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Ruiz"},
+		streets:       []string{"Calle Mayor", "Calle Real", "Avenida de la Constitución", "Calle del Sol", "Plaza de España", "Calle de Alcalá", "Paseo del Prado", "Calle Nueva"},
+		cities:        []string{"Madrid", "Barcelona", "Valencia", "Sevilla", "Zaragoza", "Málaga", "Bilbao", "Granada"},
+		country:       "España",
+		phoneFormat:   "+34 9## ### ###",
+		postcodeFmt:   "#####",
+		addressFormat: "{street}, {number}, {postcode} {city}",
+	},
+	"it_IT": {
+		firstNames:    []string{"Leonardo", "Sofia", "Francesco", "Giulia", "Alessandro", "Aurora", "Lorenzo", "Alice", "Mattia", "Chiara"},
+		lastNames:     []string{"Rossi", "Russo", "Ferrari", "Esposito", "Bianchi", "Romano", "Colombo", "Ricci", "Marino", "Greco"},
+		streets:       []string{"Via Roma", "Via Garibaldi", "Corso Italia", "Via Dante", "Piazza del Duomo", "Via Verdi", "Via Mazzini", "Viale della Libertà"},
+		cities:        []string{"Roma", "Milano", "Napoli", "Torino", "Firenze", "Bologna", "Venezia", "Genova"},
+		country:       "Italia",
+		phoneFormat:   "+39 06 #### ####",
+		postcodeFmt:   "#####",
+		addressFormat: "{street} {number}, {postcode} {city}",
+	},
+	"pt_BR": {
+		firstNames:    []string{"Miguel", "Helena", "Arthur", "Alice", "Gael", "Laura", "Heitor", "Valentina", "Davi", "Beatriz"},
+		lastNames:     []string{"Silva", "Santos", "Oliveira", "Souza", "Rodrigues", "Ferreira", "Alves", "Pereira", "Lima", "Gomes"},
+		streets:       []string{"Rua das Flores", "Avenida Paulista", "Rua Sete de Setembro", "Rua XV de Novembro", "Avenida Brasil", "Rua São João", "Rua da Consolação", "Avenida Atlântica"},
+		cities:        []string{"São Paulo", "Rio de Janeiro", "Belo Horizonte", "Salvador", "Curitiba", "Recife", "Porto Alegre", "Fortaleza"},
+		country:       "Brasil",
+		phoneFormat:   "+55 11 9####-####",
+		postcodeFmt:   "#####-###",
+		addressFormat: "{street}, {number} - {city}, {postcode}",
+	},
+	"ja_JP": {
+		firstNames:    []string{"太郎", "花子", "翔太", "陽菜", "大翔", "結衣", "蓮", "美咲", "悠真", "さくら"},
+		lastNames:     []string{"佐藤", "鈴木", "高橋", "田中", "伊藤", "渡辺", "山本", "中村", "小林", "加藤"},
+		familyFirst:   true,
+		streets:       []string{"千代田区丸の内", "中央区銀座", "港区六本木", "新宿区西新宿", "渋谷区神南", "北区梅田", "中区栄", "博多区博多駅前"},
+		cities:        []string{"東京都", "大阪府", "名古屋市", "福岡市", "札幌市", "京都市", "横浜市", "神戸市"},
+		country:       "日本",
+		phoneFormat:   "+81 3-####-####",
+		postcodeFmt:   "###-####",
+		addressFormat: "〒{postcode} {city}{street}{number}",
+	},
+}
+
+// HasLocale reports whether fake data is available for a locale
+func HasLocale(name string) bool {
+	_, ok := fakeLocales[normalizeLocale(name)]
+	return ok
+}
+
+// Locales returns the names of the locales of fake data, sorted
+func Locales() []string {
+	names := make([]string, 0, len(fakeLocales))
+	for name := range fakeLocales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeLocale maps spellings like "de-DE" and "de_de" onto "de_DE"
+// A bare language such as "de" maps onto the first of its locales
+func normalizeLocale(name string) string {
+	language, region, _ := strings.Cut(strings.ReplaceAll(name, "-", "_"), "_")
+	language = strings.ToLower(language)
+	if region != "" {
+		return language + "_" + strings.ToUpper(region)
+	}
+	for _, locale := range Locales() {
+		if strings.HasPrefix(locale, language+"_") {
+			return locale
+		}
+	}
+	return language
+}
+
+// resolveFake resolves a fake data generator such as `name "de_DE"`
+// Without an argument the locale of the context is used, then DefaultLocale
+func (e *Engine) resolveFake(key string, ctx *Context) string {
+	field, arg, _ := strings.Cut(key, " ")
+
+	localeName := ctx.Locale
+	if arg != "" {
+		localeName = e.resolveOperand(arg, ctx)
+	}
+	locale, ok := fakeLocales[normalizeLocale(localeName)]
+	if !ok {
+		locale = fakeLocales[DefaultLocale]
+	}
+
+	rng := e.rand(ctx)
+	switch field {
+	case "firstName":
+		return pick(rng, locale.firstNames)
+	case "lastName":
+		return pick(rng, locale.lastNames)
+	case "name":
+		first, last := pick(rng, locale.firstNames), pick(rng, locale.lastNames)
+		if locale.familyFirst {
+			return last + " " + first
+		}
+		return first + " " + last
+	case "phone":
+		return formatPattern(rng, locale.phoneFormat)
+	case "street":
+		return pick(rng, locale.streets)
+	case "city":
+		return pick(rng, locale.cities)
+	case "postcode":
+		return formatPattern(rng, locale.postcodeFmt)
+	case "country":
+		return locale.country
+	case "address":
+		return strings.NewReplacer(
+			"{number}", strconv.Itoa(1+rng.Intn(199)),
+			"{street}", pick(rng, locale.streets),
+			"{city}", pick(rng, locale.cities),
+			"{postcode}", formatPattern(rng, locale.postcodeFmt),
+		).Replace(locale.addressFormat)
+	}
+	return ""
+}
+
+// pick returns a random element of values
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
+
+// formatPattern replaces each # of a pattern with a random digit and each ? with a random letter
+func formatPattern(rng *rand.Rand, pattern string) string {
+	result := []byte(pattern)
+	for i, c := range result {
+		switch c {
+		case '#':
+			result[i] = byte('0' + rng.Intn(10))
+		case '?':
+			result[i] = byte('A' + rng.Intn(26))
+		}
+	}
+	return string(result)
+}
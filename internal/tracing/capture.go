@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// DefaultMaxBodySize is the default number of bytes of each body kept in a trace
+const DefaultMaxBodySize = 64 << 10
+
+// TruncatedMarker ends bodies cut to the maximum capture size
+const TruncatedMarker = "…[truncated]"
+
+// SetMaxBodySize sets the number of bytes of request and response bodies kept in traces;
+// 0 or less keeps bodies whole. Traces already recorded are left as they are
+func (s *Service) SetMaxBodySize(maxBodySize int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxBodySize = maxBodySize
+}
+
+// capture hides the redacted headers and masked body fields of a trace and bounds its bodies
+// before it is stored or streamed; the caller holds the lock
+func (s *Service) capture(trace *models.Trace) {
+	if len(s.redactHeaders) > 0 {
+		trace.Request.Headers = redactHeaders(trace.Request.Headers, s.redactHeaders)
+		trace.Response.Headers = redactHeaders(trace.Response.Headers, s.redactHeaders)
+	}
+
+	// Mask whole bodies, since truncated ones are no longer valid JSON
+	if len(s.maskRules) > 0 {
+		trace.Request.Body = maskBody(trace.Request.Body, s.maskRules)
+		trace.Response.Body = maskBody(trace.Response.Body, s.maskRules)
+	}
+
+	if s.maxBodySize <= 0 {
+		return
+	}
+	// Traces of other nodes may have been truncated already
+	if !trace.Request.BodyTruncated {
+		trace.Request.Body, trace.Request.BodyTruncated, trace.Request.BodySize = truncateBody(trace.Request.Body, s.maxBodySize)
+	}
+	if !trace.Response.BodyTruncated {
+		trace.Response.Body, trace.Response.BodyTruncated, trace.Response.BodySize = truncateBody(trace.Response.Body, s.maxBodySize)
+	}
+}
+
+// truncateBody cuts a body longer than max bytes at a character boundary and appends the
+// marker, returning whether it did and the size of the whole body
+func truncateBody(body string, max int) (string, bool, int) {
+	if len(body) <= max {
+		return body, false, 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut] + TruncatedMarker, true, len(body)
+}
+
+// DefaultRedactHeaders are the headers whose values traces hide by default
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"}
+
+// RedactedValue replaces the values of redacted headers
+const RedactedValue = "[REDACTED]"
+
+// SetRedactHeaders sets the request and response headers whose values traces hide, matched
+// case-insensitively. Traces already recorded are left as they are
+func (s *Service) SetRedactHeaders(names []string) {
+	redact := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			redact[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redactHeaders = redact
+}
+
+// redactHeaders returns headers with the values of the redacted ones replaced, copying them
+// rather than changing the headers of the request or response that was traced
+func redactHeaders(headers map[string][]string, redact map[string]bool) map[string][]string {
+	var redacted map[string][]string
+	for name, values := range headers {
+		if !redact[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		if redacted == nil {
+			redacted = make(map[string][]string, len(headers))
+			for k, v := range headers {
+				redacted[k] = v
+			}
+		}
+		masked := make([]string, len(values))
+		for i := range masked {
+			masked[i] = RedactedValue
+		}
+		redacted[name] = masked
+	}
+	if redacted == nil {
+		return headers
+	}
+	return redacted
+}
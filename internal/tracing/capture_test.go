@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestRecordTrace_TruncatesBodies(t *testing.T) {
+	s := NewService(10)
+	s.SetMaxBodySize(8)
+
+	_, live := s.Subscribe()
+	s.RecordTrace(&models.Trace{
+		Request:  models.TraceRequest{Body: "short"},
+		Response: models.TraceResponse{Body: "héllo wörld"},
+	})
+
+	trace := s.GetTraces(nil)[0]
+	if trace.Request.Body != "short" || trace.Request.BodyTruncated || trace.Request.BodySize != 0 {
+		t.Errorf("Expected the short body kept whole, got %+v", trace.Request)
+	}
+	// The cut doesn't split the two bytes of ö
+	if trace.Response.Body != "héllo w"+TruncatedMarker || !trace.Response.BodyTruncated || trace.Response.BodySize != 13 {
+		t.Errorf("Expected the body cut to 8 bytes, got %+v", trace.Response)
+	}
+	if streamed := <-live; streamed.Response.Body != trace.Response.Body {
+		t.Errorf("Expected the truncated body streamed, got %q", streamed.Response.Body)
+	}
+
+	// Traces truncated by another node aren't cut again
+	s.RecordRemoteTrace(&models.Trace{ID: "remote", Response: models.TraceResponse{Body: "0123456789" + TruncatedMarker, BodyTruncated: true, BodySize: 100}})
+	if remote := s.GetTrace("remote"); remote.Response.BodySize != 100 || strings.Count(remote.Response.Body, TruncatedMarker) != 1 {
+		t.Errorf("Expected the remote trace kept as is, got %+v", remote.Response)
+	}
+
+	s.SetMaxBodySize(0)
+	s.RecordTrace(&models.Trace{ID: "whole", Request: models.TraceRequest{Body: strings.Repeat("x", 100)}})
+	if whole := s.GetTrace("whole"); len(whole.Request.Body) != 100 || whole.Request.BodyTruncated {
+		t.Errorf("Expected the body kept whole without a limit, got %d bytes", len(whole.Request.Body))
+	}
+}
+
+func TestRecordTrace_RedactsHeaders(t *testing.T) {
+	s := NewService(10)
+
+	requestHeaders := map[string][]string{"Authorization": {"Bearer secret"}, "Accept": {"application/json"}}
+	s.RecordTrace(&models.Trace{
+		ID:       "default",
+		Request:  models.TraceRequest{Headers: requestHeaders},
+		Response: models.TraceResponse{Headers: map[string][]string{"Set-Cookie": {"a=1", "b=2"}}},
+	})
+
+	trace := s.GetTrace("default")
+	if got := trace.Request.Headers["Authorization"]; len(got) != 1 || got[0] != RedactedValue {
+		t.Errorf("Expected Authorization redacted, got %v", got)
+	}
+	if got := trace.Request.Headers["Accept"]; got[0] != "application/json" {
+		t.Errorf("Expected Accept kept, got %v", got)
+	}
+	if got := trace.Response.Headers["Set-Cookie"]; len(got) != 2 || got[1] != RedactedValue {
+		t.Errorf("Expected both cookies redacted, got %v", got)
+	}
+	// The traced request keeps its headers
+	if requestHeaders["Authorization"][0] != "Bearer secret" {
+		t.Error("Expected the request headers left unchanged")
+	}
+
+	s.SetRedactHeaders([]string{"x-tenant-token"})
+	s.RecordTrace(&models.Trace{ID: "custom", Request: models.TraceRequest{Headers: map[string][]string{"X-Tenant-Token": {"t"}, "Authorization": {"Basic a"}}}})
+	trace = s.GetTrace("custom")
+	if trace.Request.Headers["X-Tenant-Token"][0] != RedactedValue || trace.Request.Headers["Authorization"][0] != "Basic a" {
+		t.Errorf("Expected only the configured header redacted, got %v", trace.Request.Headers)
+	}
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// MaskedValue replaces the masked part of a body value
+const MaskedValue = "****"
+
+// MaskRule hides a field of JSON request and response bodies in traces, such as a card number
+type MaskRule struct {
+	Path     string `yaml:"path"`     // JSONPath like $.card.number or $.items[*].ssn, or a gjson path like items.#.ssn
+	KeepLast int    `yaml:"keepLast"` // Trailing characters left visible, e.g. 4 for ****1234
+}
+
+// maskRule is a mask rule with its path split into gjson segments
+type maskRule struct {
+	segments []string // "#" stands for every element of an array
+	keepLast int
+}
+
+// SetMaskRules sets the fields masked in the bodies of traces; an invalid path rejects all rules
+// and keeps the previous ones. Traces already recorded are left as they are
+func (s *Service) SetMaskRules(rules []MaskRule) error {
+	compiled := make([]maskRule, 0, len(rules))
+	for _, rule := range rules {
+		segments, err := parseMaskPath(rule.Path)
+		if err != nil {
+			return err
+		}
+		if rule.KeepLast < 0 {
+			return fmt.Errorf("invalid mask rule %q: keepLast can't be negative", rule.Path)
+		}
+		compiled = append(compiled, maskRule{segments: segments, keepLast: rule.KeepLast})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maskRules = compiled
+	return nil
+}
+
+// parseMaskPath splits a JSONPath or gjson path into segments. Only field names, array indexes
+// and wildcards over array elements are allowed, since masking needs the exact fields a path names
+func parseMaskPath(path string) ([]string, error) {
+	p := strings.TrimSpace(path)
+	if p == "$" || p == "" {
+		return nil, fmt.Errorf("invalid mask path %q: a field is required", path)
+	}
+	if strings.HasPrefix(p, "$") {
+		p = strings.ReplaceAll(strings.TrimPrefix(p, "$"), "[*]", ".#")
+		for {
+			open := strings.Index(p, "[")
+			if open < 0 {
+				break
+			}
+			end := strings.Index(p[open:], "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid mask path %q: unclosed [", path)
+			}
+			index := p[open+1 : open+end]
+			if _, err := strconv.Atoi(index); err != nil {
+				return nil, fmt.Errorf("invalid mask path %q: only [n] and [*] are supported", path)
+			}
+			p = p[:open] + "." + index + p[open+end+1:]
+		}
+		p = strings.TrimPrefix(p, ".")
+	}
+
+	segments := strings.Split(p, ".")
+	for _, segment := range segments {
+		if segment == "" || strings.ContainsAny(segment, "*?@|()=<>!%\\") || (strings.Contains(segment, "#") && segment != "#") {
+			return nil, fmt.Errorf("invalid mask path %q: use field names, array indexes and [*]", path)
+		}
+	}
+	return segments, nil
+}
+
+// maskBody masks the fields of a JSON body the rules name; other bodies are returned unchanged
+func maskBody(body string, rules []maskRule) string {
+	if len(rules) == 0 || !gjson.Valid(body) {
+		return body
+	}
+	for _, rule := range rules {
+		for _, path := range expandPath(body, rule.segments) {
+			value := gjson.Get(body, path)
+			if !value.Exists() || value.Type == gjson.Null || value.IsObject() || value.IsArray() {
+				continue
+			}
+			if masked, err := sjson.Set(body, path, maskValue(value.String(), rule.keepLast)); err == nil {
+				body = masked
+			}
+		}
+	}
+	return body
+}
+
+// expandPath returns the concrete paths of a body that path segments with "#" wildcards match
+func expandPath(body string, segments []string) []string {
+	paths := []string{""}
+	for _, segment := range segments {
+		var next []string
+		for _, prefix := range paths {
+			if segment != "#" {
+				next = append(next, joinPath(prefix, segment))
+				continue
+			}
+			array := gjson.Parse(body)
+			if prefix != "" {
+				array = gjson.Get(body, prefix)
+			}
+			if !array.IsArray() {
+				continue
+			}
+			for i := range array.Array() {
+				next = append(next, joinPath(prefix, strconv.Itoa(i)))
+			}
+		}
+		paths = next
+	}
+	return paths
+}
+
+func joinPath(prefix, segment string) string {
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// maskValue hides a value but for its last keepLast characters
+func maskValue(value string, keepLast int) string {
+	runes := []rune(value)
+	if keepLast <= 0 || len(runes) <= keepLast {
+		return MaskedValue
+	}
+	return MaskedValue + string(runes[len(runes)-keepLast:])
+}
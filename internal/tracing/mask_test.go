@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestParseMaskPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "$.card.number", want: "card.number"},
+		{path: "$.items[*].ssn", want: "items.#.ssn"},
+		{path: "$.items[0].ssn", want: "items.0.ssn"},
+		{path: "$[*].matrix[*][1]", want: "#.matrix.#.1"},
+		{path: "items.#.ssn", want: "items.#.ssn"},
+		{path: "$", wantErr: true},
+		{path: "", wantErr: true},
+		{path: "$..number", wantErr: true},
+		{path: "$.items[?(@.ssn)]", wantErr: true},
+		{path: "$.card.*", wantErr: true},
+		{path: "$.items[0", wantErr: true},
+	}
+	for _, tt := range tests {
+		segments, err := parseMaskPath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMaskPath(%q) expected an error, got %v", tt.path, segments)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMaskPath(%q) unexpected error: %v", tt.path, err)
+			continue
+		}
+		if got := joinSegments(segments); got != tt.want {
+			t.Errorf("parseMaskPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func joinSegments(segments []string) string {
+	path := ""
+	for _, segment := range segments {
+		path = joinPath(path, segment)
+	}
+	return path
+}
+
+func TestRecordTrace_MasksBodies(t *testing.T) {
+	s := NewService(10)
+	err := s.SetMaskRules([]MaskRule{
+		{Path: "$.card.number", KeepLast: 4},
+		{Path: "$.customers[*].ssn"},
+		{Path: "$.card"}, // Objects are left alone
+	})
+	if err != nil {
+		t.Fatalf("SetMaskRules failed: %v", err)
+	}
+
+	s.RecordTrace(&models.Trace{
+		ID:       "json",
+		Request:  models.TraceRequest{Body: `{"card":{"number":"4111111111111111","cvc":"123"}}`},
+		Response: models.TraceResponse{Body: `{"customers":[{"ssn":"123-45-6789"},{"name":"x"},{"ssn":987654321}]}`},
+	})
+	trace := s.GetTrace("json")
+	if want := `{"card":{"number":"****1111","cvc":"123"}}`; trace.Request.Body != want {
+		t.Errorf("Expected request body %s, got %s", want, trace.Request.Body)
+	}
+	if want := `{"customers":[{"ssn":"****"},{"name":"x"},{"ssn":"****"}]}`; trace.Response.Body != want {
+		t.Errorf("Expected response body %s, got %s", want, trace.Response.Body)
+	}
+
+	s.RecordTrace(&models.Trace{ID: "text", Request: models.TraceRequest{Body: "card.number=4111"}})
+	if body := s.GetTrace("text").Request.Body; body != "card.number=4111" {
+		t.Errorf("Expected a non-JSON body left unchanged, got %s", body)
+	}
+
+	// An invalid rule keeps the previous ones
+	if err := s.SetMaskRules([]MaskRule{{Path: "$..number"}}); err == nil {
+		t.Error("Expected an invalid path rejected")
+	}
+	if err := s.SetMaskRules([]MaskRule{{Path: "$.card.number", KeepLast: -1}}); err == nil {
+		t.Error("Expected a negative keepLast rejected")
+	}
+	s.RecordTrace(&models.Trace{ID: "kept", Request: models.TraceRequest{Body: `{"card":{"number":"5500"}}`}})
+	if body := s.GetTrace("kept").Request.Body; body != `{"card":{"number":"****"}}` {
+		t.Errorf("Expected the previous rules kept, got %s", body)
+	}
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// PurgeTraces removes the traces recorded between from and to and returns how many it removed.
+// A zero time leaves that end of the range open
+func (s *Service) PurgeTraces(from, to time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]*models.Trace, 0, len(s.traces))
+	for _, trace := range s.traces {
+		if !models.InTimeRange(trace.Timestamp, from, to) {
+			kept = append(kept, trace)
+		}
+	}
+	removed := len(s.traces) - len(kept)
+	s.traces = kept
+	return removed
+}
+
+// PurgeBodies removes the request and response bodies of the traces recorded between from and
+// to, keeping the rest of them, and returns how many traces had a body
+func (s *Service) PurgeBodies(from, to time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for i, trace := range s.traces {
+		if !models.InTimeRange(trace.Timestamp, from, to) || (trace.Request.Body == "" && trace.Response.Body == "") {
+			continue
+		}
+		// Callers may still hold the trace, so it is replaced rather than changed
+		stripped := *trace
+		stripped.Request.Body, stripped.Request.BodyTruncated, stripped.Request.BodySize = "", false, 0
+		stripped.Response.Body, stripped.Response.BodyTruncated, stripped.Response.BodySize = "", false, 0
+		s.traces[i] = &stripped
+		purged++
+	}
+	return purged
+}
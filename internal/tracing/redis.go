@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// publishTimeout bounds how long publishing one trace to Redis may take
+const publishTimeout = 2 * time.Second
+
+// RedisFanout shares traces between nodes through a Redis pub/sub channel, so the live trace
+// stream of every node shows requests served by any node behind a load balancer. Purges of
+// captured data travel the same channel, so the traces shared with other nodes go as well
+type RedisFanout struct {
+	client  *redis.Client
+	channel string
+	nodeID  string
+	service *Service
+	queue   chan []byte                // Traces waiting to be published
+	onPurge func(*models.PurgeRequest) // Applies purges made on other nodes
+}
+
+// fanoutMessage is a message on the channel: a trace, or a purge when Purge is set
+type fanoutMessage struct {
+	models.Trace
+	Purge *models.PurgeRequest `json:"purge,omitempty"`
+}
+
+// purgeMessage announces a purge made on a node
+type purgeMessage struct {
+	Node  string               `json:"node"`
+	Purge *models.PurgeRequest `json:"purge"`
+}
+
+// NewRedisFanout connects to Redis at url (e.g. redis://localhost:6379/0)
+// Traces are published as JSON on channel, tagged with nodeID
+func NewRedisFanout(service *Service, url, channel, nodeID string) (*RedisFanout, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisFanout{
+		client:  client,
+		channel: channel,
+		nodeID:  nodeID,
+		service: service,
+		queue:   make(chan []byte, 1000),
+	}, nil
+}
+
+// Publish queues a trace recorded on this node for publishing
+// Traces are dropped when Redis can't keep up, so serving requests never waits on it
+func (f *RedisFanout) Publish(trace *models.Trace) {
+	tagged := *trace
+	tagged.Node = f.nodeID
+	data, err := json.Marshal(&tagged)
+	if err != nil {
+		return
+	}
+
+	select {
+	case f.queue <- data:
+	default:
+		slog.Warn("trace fan-out queue full, dropping trace", "trace", trace.ID)
+	}
+}
+
+// SetPurgeHandler sets how purges made on other nodes are applied to this node
+// Set it before Run; without it such purges are ignored
+func (f *RedisFanout) SetPurgeHandler(apply func(*models.PurgeRequest)) {
+	f.onPurge = apply
+}
+
+// BroadcastPurge passes a purge made on this node on to the other nodes
+// Unlike traces it is published right away, so failures can be reported
+func (f *RedisFanout) BroadcastPurge(req *models.PurgeRequest) error {
+	data, err := json.Marshal(&purgeMessage{Node: f.nodeID, Purge: req})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	return f.client.Publish(ctx, f.channel, data).Err()
+}
+
+// Run publishes queued traces and records traces published by other nodes until ctx is done
+func (f *RedisFanout) Run(ctx context.Context) {
+	pubsub := f.client.Subscribe(ctx, f.channel)
+	defer pubsub.Close()
+
+	go f.publishLoop(ctx)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			f.handleMessage(msg.Payload)
+		}
+	}
+}
+
+// publishLoop sends queued traces to the channel
+func (f *RedisFanout) publishLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-f.queue:
+			pubCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+			err := f.client.Publish(pubCtx, f.channel, data).Err()
+			cancel()
+			if err != nil {
+				slog.Warn("failed to publish trace", "channel", f.channel, "error", err)
+			}
+		}
+	}
+}
+
+// handleMessage records a trace published by another node, or applies its purge
+func (f *RedisFanout) handleMessage(payload string) {
+	var msg fanoutMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		slog.Warn("invalid trace on fan-out channel", "channel", f.channel, "error", err)
+		return
+	}
+	if msg.Node == f.nodeID {
+		return
+	}
+	if msg.Purge != nil {
+		if f.onPurge != nil {
+			f.onPurge(msg.Purge)
+		}
+		return
+	}
+	f.service.RecordRemoteTrace(&msg.Trace)
+}
+
+// Close closes the Redis connection
+func (f *RedisFanout) Close() error {
+	return f.client.Close()
+}
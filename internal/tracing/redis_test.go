@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// recordingPublisher collects published traces
+type recordingPublisher struct {
+	traces []*models.Trace
+}
+
+func (p *recordingPublisher) Publish(trace *models.Trace) {
+	p.traces = append(p.traces, trace)
+}
+
+func TestService_PublishesLocalTracesOnly(t *testing.T) {
+	s := NewService(10)
+	pub := &recordingPublisher{}
+	s.SetPublisher(pub)
+
+	s.RecordTrace(&models.Trace{ID: "local"})
+	s.RecordRemoteTrace(&models.Trace{ID: "remote", Node: "other"})
+
+	if len(pub.traces) != 1 || pub.traces[0].ID != "local" {
+		t.Errorf("Expected only the local trace to be published, got %v", pub.traces)
+	}
+	if s.GetTrace("remote") == nil {
+		t.Error("Expected remote trace to be recorded")
+	}
+}
+
+func TestRedisFanout_HandleMessage(t *testing.T) {
+	s := NewService(10)
+	f := &RedisFanout{nodeID: "a", service: s, queue: make(chan []byte, 1)}
+
+	_, live := s.Subscribe()
+
+	own, _ := json.Marshal(&models.Trace{ID: "own", Node: "a"})
+	f.handleMessage(string(own))
+	other, _ := json.Marshal(&models.Trace{ID: "other", Node: "b"})
+	f.handleMessage(string(other))
+	f.handleMessage("not json")
+
+	if s.GetTrace("own") != nil {
+		t.Error("Expected the node's own trace to be ignored")
+	}
+	select {
+	case trace := <-live:
+		if trace.ID != "other" || trace.Node != "b" {
+			t.Errorf("Expected trace from node b on the live stream, got %+v", trace)
+		}
+	default:
+		t.Error("Expected trace from another node on the live stream")
+	}
+
+	// Published traces are tagged with the node
+	f.Publish(&models.Trace{ID: "t1"})
+	var queued models.Trace
+	json.Unmarshal(<-f.queue, &queued)
+	if queued.ID != "t1" || queued.Node != "a" {
+		t.Errorf("Expected queued trace tagged with node a, got %+v", queued)
+	}
+}
+
+func TestRedisFanout_HandlePurge(t *testing.T) {
+	s := NewService(10)
+	f := &RedisFanout{nodeID: "a", service: s, queue: make(chan []byte, 1)}
+
+	var applied []*models.PurgeRequest
+	f.SetPurgeHandler(func(req *models.PurgeRequest) {
+		applied = append(applied, req)
+	})
+
+	own, _ := json.Marshal(&purgeMessage{Node: "a", Purge: &models.PurgeRequest{Scopes: []string{models.PurgeTraces}}})
+	f.handleMessage(string(own))
+	other, _ := json.Marshal(&purgeMessage{Node: "b", Purge: &models.PurgeRequest{Scopes: []string{models.PurgeBodies}}})
+	f.handleMessage(string(other))
+
+	if len(applied) != 1 || applied[0].Scopes[0] != models.PurgeBodies {
+		t.Errorf("Expected only the purge of node b to be applied, got %+v", applied)
+	}
+	if traces := s.GetTraces(nil); len(traces) != 0 {
+		t.Errorf("Expected purges not to be recorded as traces, got %d", len(traces))
+	}
+}
@@ -1,6 +1,9 @@
 package tracing
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,16 +13,27 @@ import (
 
 // subscriber wraps a channel with closed state tracking
 type subscriber struct {
-	ch     chan *models.Trace
-	closed bool
+	ch      chan *models.Trace
+	closed  bool
+	filter  *models.TraceFilter // Traces sent to the subscriber; nil sends all
+	dropped int64               // Traces skipped since the last Dropped call because the channel was full
+}
+
+// Publisher sends traces recorded on this node to other nodes
+type Publisher interface {
+	Publish(trace *models.Trace)
 }
 
 // Service manages request/response tracing
 type Service struct {
-	mu          sync.RWMutex
-	traces      []*models.Trace
-	maxTraces   int
-	subscribers map[string]*subscriber
+	mu            sync.RWMutex
+	traces        []*models.Trace
+	maxTraces     int
+	maxBodySize   int             // Bytes of each body kept; 0 keeps bodies whole
+	redactHeaders map[string]bool // Canonical names of headers whose values are hidden
+	maskRules     []maskRule      // Fields of JSON bodies whose values are hidden
+	subscribers   map[string]*subscriber
+	publisher     Publisher // nil unless traces are fanned out to other nodes
 }
 
 // NewService creates a new tracing service
@@ -28,15 +42,42 @@ func NewService(maxTraces int) *Service {
 		maxTraces = 1000
 	}
 
-	return &Service{
+	s := &Service{
 		traces:      make([]*models.Trace, 0),
 		maxTraces:   maxTraces,
+		maxBodySize: DefaultMaxBodySize,
 		subscribers: make(map[string]*subscriber),
 	}
+	s.SetRedactHeaders(DefaultRedactHeaders)
+	return s
 }
 
-// RecordTrace records a new trace
+// SetPublisher sets where traces recorded on this node are published, or nil to stop publishing
+func (s *Service) SetPublisher(p Publisher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publisher = p
+}
+
+// RecordTrace records a new trace and publishes it to other nodes
 func (s *Service) RecordTrace(trace *models.Trace) {
+	s.record(trace)
+
+	s.mu.RLock()
+	publisher := s.publisher
+	s.mu.RUnlock()
+	if publisher != nil {
+		publisher.Publish(trace)
+	}
+}
+
+// RecordRemoteTrace records a trace served by another node, without publishing it again
+func (s *Service) RecordRemoteTrace(trace *models.Trace) {
+	s.record(trace)
+}
+
+// record stores a trace and notifies live subscribers
+func (s *Service) record(trace *models.Trace) {
 	s.mu.Lock()
 
 	// Generate ID if not set
@@ -49,6 +90,9 @@ func (s *Service) RecordTrace(trace *models.Trace) {
 		trace.Timestamp = time.Now()
 	}
 
+	// Bound the bodies before the trace is kept or sent
+	s.capture(trace)
+
 	// Add to traces
 	s.traces = append(s.traces, trace)
 
@@ -60,11 +104,12 @@ func (s *Service) RecordTrace(trace *models.Trace) {
 	// Notify subscribers (non-blocking) while holding the lock
 	// This ensures we don't send to closed channels
 	for _, sub := range s.subscribers {
-		if !sub.closed {
+		if !sub.closed && matchesFilter(sub.filter, trace) {
 			select {
 			case sub.ch <- trace:
 			default:
-				// Channel full, skip
+				// Channel full, count the trace so the subscriber learns it missed it
+				sub.dropped++
 			}
 		}
 	}
@@ -72,6 +117,21 @@ func (s *Service) RecordTrace(trace *models.Trace) {
 	s.mu.Unlock()
 }
 
+// SetMaxTraces changes the number of traces kept, dropping the oldest if over the new limit
+func (s *Service) SetMaxTraces(maxTraces int) {
+	if maxTraces <= 0 {
+		maxTraces = 1000
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxTraces = maxTraces
+	if len(s.traces) > maxTraces {
+		s.traces = s.traces[len(s.traces)-maxTraces:]
+	}
+}
+
 // GetTraces returns traces matching the filter
 func (s *Service) GetTraces(filter *models.TraceFilter) []*models.Trace {
 	s.mu.RLock()
@@ -83,25 +143,8 @@ func (s *Service) GetTraces(filter *models.TraceFilter) []*models.Trace {
 		trace := s.traces[i]
 
 		// Apply filters
-		if filter != nil {
-			if filter.SpecID != "" && trace.SpecID != filter.SpecID {
-				continue
-			}
-			if filter.OperationID != "" && trace.OperationID != filter.OperationID {
-				continue
-			}
-			if filter.Method != "" && trace.Request.Method != filter.Method {
-				continue
-			}
-			if filter.StatusCode != 0 && trace.Response.StatusCode != filter.StatusCode {
-				continue
-			}
-			if !filter.StartTime.IsZero() && trace.Timestamp.Before(filter.StartTime) {
-				continue
-			}
-			if !filter.EndTime.IsZero() && trace.Timestamp.After(filter.EndTime) {
-				continue
-			}
+		if !matchesFilter(filter, trace) {
+			continue
 		}
 
 		result = append(result, trace)
@@ -115,6 +158,49 @@ func (s *Service) GetTraces(filter *models.TraceFilter) []*models.Trace {
 	return result
 }
 
+// matchesFilter reports whether a trace passes the fields of a filter other than its limit and offset
+func matchesFilter(filter *models.TraceFilter, trace *models.Trace) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.SpecID != "" && trace.SpecID != filter.SpecID {
+		return false
+	}
+	if filter.OperationID != "" && trace.OperationID != filter.OperationID {
+		return false
+	}
+	if filter.Method != "" && trace.Request.Method != filter.Method {
+		return false
+	}
+	if filter.StatusCode != 0 && trace.Response.StatusCode != filter.StatusCode {
+		return false
+	}
+	if filter.StatusClass != 0 && trace.Response.StatusCode/100 != filter.StatusClass {
+		return false
+	}
+	if !filter.StartTime.IsZero() && trace.Timestamp.Before(filter.StartTime) {
+		return false
+	}
+	if !filter.EndTime.IsZero() && trace.Timestamp.After(filter.EndTime) {
+		return false
+	}
+	return true
+}
+
+// ParseStatus parses a status filter: a status code like 404, or a class like 5xx
+func ParseStatus(status string, filter *models.TraceFilter) error {
+	if len(status) == 3 && strings.EqualFold(status[1:], "xx") && status[0] >= '1' && status[0] <= '5' {
+		filter.StatusClass = int(status[0] - '0')
+		return nil
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil || code < 100 || code > 599 {
+		return fmt.Errorf("invalid status %q: expected a status code like 404 or a class like 5xx", status)
+	}
+	filter.StatusCode = code
+	return nil
+}
+
 // GetTrace returns a single trace by ID
 func (s *Service) GetTrace(id string) *models.Trace {
 	s.mu.RLock()
@@ -153,16 +239,36 @@ func (s *Service) ClearTracesBySpec(specID string) {
 
 // Subscribe creates a subscription for live traces
 func (s *Service) Subscribe() (string, chan *models.Trace) {
+	return s.SubscribeFiltered(nil)
+}
+
+// SubscribeFiltered creates a subscription for the live traces matching a filter
+func (s *Service) SubscribeFiltered(filter *models.TraceFilter) (string, chan *models.Trace) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	id := uuid.New().String()
 	ch := make(chan *models.Trace, 100)
-	s.subscribers[id] = &subscriber{ch: ch, closed: false}
+	s.subscribers[id] = &subscriber{ch: ch, closed: false, filter: filter}
 
 	return id, ch
 }
 
+// Dropped returns how many traces a subscription missed because it didn't keep up, since the
+// previous call
+func (s *Service) Dropped(id string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscribers[id]
+	if !ok {
+		return 0
+	}
+	dropped := sub.dropped
+	sub.dropped = 0
+	return dropped
+}
+
 // Unsubscribe removes a subscription
 func (s *Service) Unsubscribe(id string) {
 	s.mu.Lock()
@@ -1,6 +1,7 @@
 package tracing
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -85,6 +86,32 @@ func TestRecordTrace_MaxLimit(t *testing.T) {
 	}
 }
 
+func TestSetMaxTraces(t *testing.T) {
+	s := NewService(10)
+
+	for i := 0; i < 10; i++ {
+		s.RecordTrace(&models.Trace{ID: fmt.Sprintf("trace-%d", i)})
+	}
+
+	s.SetMaxTraces(3)
+
+	traces := s.GetTraces(nil)
+	if len(traces) != 3 {
+		t.Fatalf("Expected 3 traces after lowering the limit, got %d", len(traces))
+	}
+	if traces[0].ID != "trace-9" || traces[2].ID != "trace-7" {
+		t.Errorf("Expected the newest traces to be kept, got %s..%s", traces[0].ID, traces[2].ID)
+	}
+
+	s.SetMaxTraces(5)
+	for i := 10; i < 15; i++ {
+		s.RecordTrace(&models.Trace{ID: fmt.Sprintf("trace-%d", i)})
+	}
+	if got := len(s.GetTraces(nil)); got != 5 {
+		t.Errorf("Expected 5 traces after raising the limit, got %d", got)
+	}
+}
+
 func TestRecordTrace_PreservesExistingID(t *testing.T) {
 	s := NewService(100)
 
@@ -403,6 +430,62 @@ func TestSubscriberReceivesTraces(t *testing.T) {
 	}
 }
 
+func TestSubscribeFiltered(t *testing.T) {
+	s := NewService(100)
+
+	filter := &models.TraceFilter{SpecID: "spec-1"}
+	if err := ParseStatus("5xx", filter); err != nil {
+		t.Fatalf("Failed to parse the status: %v", err)
+	}
+	id, ch := s.SubscribeFiltered(filter)
+	defer s.Unsubscribe(id)
+
+	s.RecordTrace(&models.Trace{SpecID: "spec-1", Response: models.TraceResponse{StatusCode: 200}})
+	s.RecordTrace(&models.Trace{SpecID: "spec-2", Response: models.TraceResponse{StatusCode: 500}})
+	s.RecordTrace(&models.Trace{ID: "match", SpecID: "spec-1", Response: models.TraceResponse{StatusCode: 503}})
+
+	if len(ch) != 1 {
+		t.Fatalf("Expected 1 trace sent, got %d", len(ch))
+	}
+	if trace := <-ch; trace.ID != "match" {
+		t.Errorf("Expected the matching trace, got %q", trace.ID)
+	}
+
+	// Traces that don't fit the channel are counted until asked for
+	for i := 0; i < 105; i++ {
+		s.RecordTrace(&models.Trace{SpecID: "spec-1", Response: models.TraceResponse{StatusCode: 500}})
+	}
+	if dropped := s.Dropped(id); dropped != 5 {
+		t.Errorf("Expected 5 dropped traces, got %d", dropped)
+	}
+	if dropped := s.Dropped(id); dropped != 0 {
+		t.Errorf("Expected the count to start over, got %d", dropped)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		code   int
+		class  int
+		valid  bool
+	}{
+		{"404", 404, 0, true},
+		{"5xx", 0, 5, true},
+		{"2XX", 0, 2, true},
+		{"6xx", 0, 0, false},
+		{"99", 0, 0, false},
+		{"ok", 0, 0, false},
+	}
+	for _, tt := range tests {
+		var filter models.TraceFilter
+		err := ParseStatus(tt.status, &filter)
+		if (err == nil) != tt.valid || filter.StatusCode != tt.code || filter.StatusClass != tt.class {
+			t.Errorf("ParseStatus(%q) = %+v, %v", tt.status, filter, err)
+		}
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	s := NewService(500)
 
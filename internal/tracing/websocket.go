@@ -2,11 +2,14 @@ package tracing
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prasenjit/go-virtual/internal/models"
 )
 
 // WebSocketHandler handles WebSocket connections for live tracing
@@ -29,18 +32,34 @@ func NewWebSocketHandler(service *Service) *WebSocketHandler {
 	}
 }
 
+// DroppedNotice tells a live trace subscriber how many traces it missed because it didn't
+// read them fast enough
+type DroppedNotice struct {
+	Notice  string `json:"notice"` // Always "dropped"
+	Dropped int64  `json:"dropped"`
+}
+
 // ServeHTTP handles WebSocket upgrade and streaming
+// The specId, operationId, method and status query parameters select the traces sent
 func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := streamFilter(r.URL.Query())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.Warn("websocket upgrade failed", "error", err)
 		return
 	}
 	defer conn.Close()
 
 	// Subscribe to trace events
-	subID, traceChan := h.service.Subscribe()
+	subID, traceChan := h.service.SubscribeFiltered(filter)
 	defer h.service.Unsubscribe(subID)
 
 	// Set up ping/pong for keepalive
@@ -76,24 +95,58 @@ func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			// Serialize trace to JSON
 			data, err := json.Marshal(trace)
 			if err != nil {
-				log.Printf("Failed to marshal trace: %v", err)
+				slog.Error("failed to marshal trace", "traceId", trace.ID, "error", err)
 				continue
 			}
 
 			// Send to client
 			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-				log.Printf("Failed to send trace: %v", err)
+				slog.Debug("failed to send trace, closing stream", "error", err)
 				return
 			}
 
+			// Once it caught up, tell a slow client what it missed
+			if len(traceChan) == 0 {
+				if err := h.notifyDropped(conn, subID); err != nil {
+					return
+				}
+			}
+
 		case <-ticker.C:
 			// Send ping
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			if err := h.notifyDropped(conn, subID); err != nil {
+				return
+			}
 
 		case <-done:
 			return
 		}
 	}
 }
+
+// notifyDropped sends a notice if the subscription missed traces since the last one
+func (h *WebSocketHandler) notifyDropped(conn *websocket.Conn, subID string) error {
+	dropped := h.service.Dropped(subID)
+	if dropped == 0 {
+		return nil
+	}
+	return conn.WriteJSON(DroppedNotice{Notice: "dropped", Dropped: dropped})
+}
+
+// streamFilter builds the filter of a live trace stream from its query parameters
+func streamFilter(query url.Values) (*models.TraceFilter, error) {
+	filter := &models.TraceFilter{
+		SpecID:      query.Get("specId"),
+		OperationID: query.Get("operationId"),
+		Method:      strings.ToUpper(query.Get("method")),
+	}
+	if status := query.Get("status"); status != "" {
+		if err := ParseStatus(status, filter); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestWebSocketHandler_Filter(t *testing.T) {
+	s := NewService(100)
+	server := httptest.NewServer(NewWebSocketHandler(s))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"?status=abc", nil); err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid status filter, got %v", resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?specId=spec-1&method=post", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Wait for the subscription before recording
+	for deadline := time.Now().Add(time.Second); s.GetStats()["activeSubscribers"].(int) == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	s.RecordTrace(&models.Trace{ID: "other-spec", SpecID: "spec-2", Request: models.TraceRequest{Method: "POST"}})
+	s.RecordTrace(&models.Trace{ID: "other-method", SpecID: "spec-1", Request: models.TraceRequest{Method: "GET"}})
+	s.RecordTrace(&models.Trace{ID: "match", SpecID: "spec-1", Request: models.TraceRequest{Method: "POST"}})
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read a trace: %v", err)
+	}
+	var trace models.Trace
+	json.Unmarshal(data, &trace)
+	if trace.ID != "match" {
+		t.Errorf("Expected only the matching trace, got %q", trace.ID)
+	}
+}
@@ -0,0 +1,99 @@
+package variables
+
+import (
+	"sync"
+)
+
+// Store holds key/value variables shared between requests, scoped per spec
+// Variables live in memory only and are cleared on restart
+type Store struct {
+	mu   sync.RWMutex
+	vars map[string]map[string]string // specID -> key -> value
+}
+
+// NewStore creates a new variable store
+func NewStore() *Store {
+	return &Store{
+		vars: make(map[string]map[string]string),
+	}
+}
+
+// Get returns the value of a spec variable
+func (s *Store) Get(specID, key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.vars[specID][key]
+	return value, ok
+}
+
+// Set sets the value of a spec variable
+func (s *Store) Set(specID, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.vars[specID] == nil {
+		s.vars[specID] = make(map[string]string)
+	}
+	s.vars[specID][key] = value
+}
+
+// Delete removes a spec variable, reporting whether it existed
+func (s *Store) Delete(specID, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.vars[specID][key]; !ok {
+		return false
+	}
+	delete(s.vars[specID], key)
+	return true
+}
+
+// All returns a copy of all variables of a spec
+func (s *Store) All(specID string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]string, len(s.vars[specID]))
+	for key, value := range s.vars[specID] {
+		result[key] = value
+	}
+	return result
+}
+
+// Clear removes all variables of a spec
+func (s *Store) Clear(specID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.vars, specID)
+}
+
+// Scope returns a view of the store limited to one spec
+func (s *Store) Scope(specID string) *Scope {
+	return &Scope{store: s, specID: specID}
+}
+
+// Scope gives access to the variables of a single spec
+// A nil Scope has no variables and ignores writes
+type Scope struct {
+	store  *Store
+	specID string
+}
+
+// Get returns the value of a variable
+func (s *Scope) Get(key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	return s.store.Get(s.specID, key)
+}
+
+// Set sets the value of a variable
+func (s *Scope) Set(key, value string) {
+	if s == nil {
+		return
+	}
+	s.store.Set(s.specID, key, value)
+}
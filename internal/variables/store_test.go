@@ -0,0 +1,47 @@
+package variables
+
+import "testing"
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+
+	s.Set("spec-1", "orderId", "42")
+	s.Set("spec-2", "orderId", "7")
+
+	if value, ok := s.Get("spec-1", "orderId"); !ok || value != "42" {
+		t.Errorf("Expected 42, got %q (%v)", value, ok)
+	}
+	if _, ok := s.Get("spec-1", "missing"); ok {
+		t.Error("Expected missing variable")
+	}
+
+	all := s.All("spec-2")
+	if len(all) != 1 || all["orderId"] != "7" {
+		t.Errorf("Unexpected variables: %v", all)
+	}
+
+	if !s.Delete("spec-1", "orderId") || s.Delete("spec-1", "orderId") {
+		t.Error("Expected delete to succeed only once")
+	}
+
+	s.Clear("spec-2")
+	if len(s.All("spec-2")) != 0 {
+		t.Error("Expected variables to be cleared")
+	}
+}
+
+func TestScope(t *testing.T) {
+	s := NewStore()
+	scope := s.Scope("spec-1")
+
+	scope.Set("token", "abc")
+	if value, _ := s.Get("spec-1", "token"); value != "abc" {
+		t.Errorf("Expected scope write to reach the store, got %q", value)
+	}
+
+	var nilScope *Scope
+	nilScope.Set("token", "x")
+	if _, ok := nilScope.Get("token"); ok {
+		t.Error("Expected nil scope to have no variables")
+	}
+}
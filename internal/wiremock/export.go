@@ -0,0 +1,259 @@
+package wiremock
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// Document is a set of stub mappings, as read and written by WireMock's admin API
+type Document struct {
+	Mappings []ExportedMapping `json:"mappings"`
+}
+
+// ExportedMapping is a stub mapping written by Export
+type ExportedMapping struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Priority int                    `json:"priority"`
+	Request  map[string]interface{} `json:"request"`
+	Response map[string]interface{} `json:"response"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// pathParam matches the parameters of an operation path
+var pathParam = regexp.MustCompile(`\{[^/{}]+\}`)
+
+// Export converts the enabled response configs of a spec's operations into stub mappings
+// Configs are expected in match order. Parts WireMock can't express are listed as warnings in
+// the metadata of their mapping.
+func Export(ops []*models.Operation, configs map[string][]*models.ResponseConfig) *Document {
+	doc := &Document{Mappings: make([]ExportedMapping, 0)}
+	for _, op := range ops {
+		priority := 0
+		for _, cfg := range configs[op.ID] {
+			if !cfg.Enabled {
+				continue
+			}
+			priority++
+			doc.Mappings = append(doc.Mappings, exportMapping(op, cfg, priority))
+		}
+	}
+	return doc
+}
+
+// exportMapping converts a response config of an operation into a stub mapping
+func exportMapping(op *models.Operation, cfg *models.ResponseConfig, priority int) ExportedMapping {
+	var warnings []string
+	fullPath := op.FullPath
+	if fullPath == "" {
+		fullPath = op.Path
+	}
+
+	request := map[string]interface{}{"method": op.Method}
+	if pathParam.MatchString(fullPath) {
+		request["urlPathTemplate"] = fullPath
+	} else {
+		request["urlPath"] = fullPath
+	}
+
+	groups := map[string]map[string]interface{}{}
+	var bodyPatterns []interface{}
+	addMatcher := func(group, key string, matcher map[string]interface{}) {
+		if groups[group] == nil {
+			groups[group] = map[string]interface{}{}
+		}
+		groups[group][key] = matcher
+	}
+
+	if cfg.NegateConditions && len(cfg.Conditions) > 0 {
+		warnings = append(warnings, "negated conditions are not supported; the conditions were left out")
+	} else {
+		for _, cond := range cfg.Conditions {
+			matcher, ok := exportMatcher(cond)
+			if !ok {
+				warnings = append(warnings, "condition "+describeCondition(cond)+" is not supported")
+				continue
+			}
+			switch cond.Source {
+			case models.SourcePath:
+				addMatcher("pathParameters", cond.Key, matcher)
+			case models.SourceQuery:
+				addMatcher("queryParameters", cond.Key, matcher)
+			case models.SourceHeader:
+				addMatcher("headers", cond.Key, matcher)
+			case models.SourceContentType:
+				addMatcher("headers", "Content-Type", matcher)
+			case models.SourceRawBody:
+				bodyPatterns = append(bodyPatterns, matcher)
+			case models.SourceBody:
+				if strings.ContainsAny(cond.Key, "#@|*?\\") {
+					warnings = append(warnings, "condition "+describeCondition(cond)+" uses a body path WireMock can't express")
+					continue
+				}
+				if cond.Operator == models.OpExists {
+					bodyPatterns = append(bodyPatterns, map[string]interface{}{"matchesJsonPath": "$." + cond.Key})
+					continue
+				}
+				matcher["expression"] = "$." + cond.Key
+				bodyPatterns = append(bodyPatterns, map[string]interface{}{"matchesJsonPath": matcher})
+			default:
+				warnings = append(warnings, "condition "+describeCondition(cond)+" is not supported")
+			}
+		}
+	}
+	for group, matchers := range groups {
+		if group == "pathParameters" && request["urlPathTemplate"] == nil {
+			warnings = append(warnings, "path parameter conditions need a path template")
+			continue
+		}
+		request[group] = matchers
+	}
+	if len(bodyPatterns) > 0 {
+		request["bodyPatterns"] = bodyPatterns
+	}
+
+	response := map[string]interface{}{"status": cfg.StatusCode}
+	if len(cfg.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers))
+		for name, value := range cfg.Headers {
+			headers[name] = exportTemplate(value)
+		}
+		response["headers"] = headers
+	}
+	switch {
+	case cfg.BodyFile != "":
+		response["bodyFileName"] = cfg.BodyFile
+		warnings = append(warnings, "copy "+cfg.BodyFile+" from the spec's files into WireMock's __files directory")
+	case cfg.Body != "":
+		response["body"] = exportTemplate(cfg.Body)
+	}
+	if usesTemplate(cfg) {
+		response["transformers"] = []string{"response-template"}
+	}
+	if cfg.Delay > 0 {
+		response["fixedDelayMilliseconds"] = cfg.Delay
+	}
+	if fault := cfg.Fault; fault != nil {
+		switch fault.Type {
+		case models.FaultAbort:
+			response["fault"] = "CONNECTION_RESET_BY_PEER"
+		case models.FaultTrickle:
+			interval := fault.Interval
+			if interval == 0 {
+				interval = 1000
+			}
+			chunks := max(len(cfg.Body), 1)
+			response["chunkedDribbleDelay"] = map[string]int{"numberOfChunks": chunks, "totalDuration": chunks * interval}
+		}
+	}
+	if cfg.Pagination != nil {
+		warnings = append(warnings, "pagination is not supported")
+	}
+	if cfg.SOAPFault != nil {
+		warnings = append(warnings, "SOAP faults are not supported")
+	}
+	if len(cfg.Events) > 0 {
+		warnings = append(warnings, "events are not supported")
+	}
+
+	mapping := ExportedMapping{
+		ID:       exportID(cfg.ID),
+		Name:     cfg.Name,
+		Priority: priority,
+		Request:  request,
+		Response: response,
+	}
+	if len(warnings) > 0 {
+		mapping.Metadata = map[string]interface{}{"go-virtual": map[string]interface{}{"warnings": warnings}}
+	}
+	return mapping
+}
+
+// exportMatcher converts a condition into a WireMock string value matcher
+func exportMatcher(cond models.Condition) (map[string]interface{}, bool) {
+	switch cond.Operator {
+	case models.OpEquals:
+		return map[string]interface{}{"equalTo": cond.Value}, true
+	case models.OpNotEquals:
+		return map[string]interface{}{"doesNotMatch": regexp.QuoteMeta(cond.Value)}, true
+	case models.OpContains:
+		return map[string]interface{}{"contains": cond.Value}, true
+	case models.OpNotContains:
+		return map[string]interface{}{"doesNotContain": cond.Value}, true
+	case models.OpRegex:
+		// go-virtual regular expressions match anywhere in the value, WireMock's the whole value
+		return map[string]interface{}{"matches": ".*(?:" + cond.Value + ").*"}, true
+	case models.OpStartsWith:
+		return map[string]interface{}{"matches": regexp.QuoteMeta(cond.Value) + ".*"}, true
+	case models.OpEndsWith:
+		return map[string]interface{}{"matches": ".*" + regexp.QuoteMeta(cond.Value)}, true
+	case models.OpExists:
+		return map[string]interface{}{"absent": false}, true
+	case models.OpNotExists:
+		return map[string]interface{}{"absent": true}, true
+	}
+	return nil, false
+}
+
+// exportReplacements rewrite go-virtual template variables into WireMock's Handlebars helpers
+var exportReplacements = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`\{\{\s*query\.([\w-]+)\s*\}\}`), "{{request.query.$1}}"},
+	{regexp.MustCompile(`\{\{\s*header\.([\w-]+)\s*\}\}`), "{{request.headers.$1}}"},
+	{regexp.MustCompile(`\{\{\s*path\.([\w-]+)\s*\}\}`), "{{request.path.$1}}"},
+	{regexp.MustCompile(`\{\{\s*body\.([\w.]+)\s*\}\}`), "{{jsonPath request.body '$$.$1'}}"},
+	{regexp.MustCompile(`\{\{\s*body\s*\}\}`), "{{request.body}}"},
+	{regexp.MustCompile(`\{\{\s*random\.uuid\s*\}\}`), "{{randomValue type='UUID'}}"},
+	{regexp.MustCompile(`\{\{\s*timestamp\.iso\s*\}\}`), "{{now}}"},
+}
+
+// exportTemplate rewrites the template variables of a body or header that have a WireMock
+// equivalent; others are left as they are
+func exportTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	for _, r := range exportReplacements {
+		s = r.pattern.ReplaceAllString(s, r.replace)
+	}
+	return s
+}
+
+// usesTemplate reports whether the body or a header of a response config is a template
+func usesTemplate(cfg *models.ResponseConfig) bool {
+	if cfg.BodyFile == "" && strings.Contains(cfg.Body, "{{") {
+		return true
+	}
+	for _, value := range cfg.Headers {
+		if strings.Contains(value, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// exportID derives a stable stub UUID from a response config ID, so repeated exports of the
+// same config replace each other in WireMock
+func exportID(id string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("go-virtual:"+id)).String()
+}
+
+// describeCondition formats a condition for warnings, like "header:X-Tier eq gold"
+func describeCondition(cond models.Condition) string {
+	s := cond.Source
+	if cond.Key != "" {
+		s += ":" + cond.Key
+	}
+	s += " " + cond.Operator
+	if cond.Value != "" {
+		s += " " + strconv.Quote(cond.Value)
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package wiremock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestExport(t *testing.T) {
+	op := &models.Operation{ID: "op-1", Method: "GET", Path: "/pets/{petId}", FullPath: "/api/pets/{petId}"}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {
+			{
+				ID:   "resp-1",
+				Name: "Premium pet",
+				Conditions: []models.Condition{
+					{Source: "path", Key: "petId", Operator: "eq", Value: "1"},
+					{Source: "header", Key: "X-Tier", Operator: "startsWith", Value: "gold"},
+					{Source: "body", Key: "owner.id", Operator: "exists"},
+					{Source: "jwt", Key: "sub", Operator: "eq", Value: "alice"},
+				},
+				StatusCode: 200,
+				Headers:    map[string]string{"X-Request": "{{header.X-Request-Id}}"},
+				Body:       `{"id": "{{path.petId}}"}`,
+				Delay:      100,
+				Enabled:    true,
+			},
+			{ID: "resp-2", Name: "Disabled", StatusCode: 500},
+			{ID: "resp-3", Name: "Default", StatusCode: 404, Enabled: true, Fault: &models.ResponseFault{Type: models.FaultAbort}},
+		},
+	}
+
+	doc := Export([]*models.Operation{op}, configs)
+	if len(doc.Mappings) != 2 {
+		t.Fatalf("Expected 2 mappings, got %d", len(doc.Mappings))
+	}
+
+	data, _ := json.Marshal(doc.Mappings[0])
+	var premium struct {
+		ID       string `json:"id"`
+		Priority int    `json:"priority"`
+		Request  struct {
+			Method          string                       `json:"method"`
+			URLPathTemplate string                       `json:"urlPathTemplate"`
+			PathParameters  map[string]map[string]string `json:"pathParameters"`
+			Headers         map[string]map[string]string `json:"headers"`
+			BodyPatterns    []map[string]string          `json:"bodyPatterns"`
+		} `json:"request"`
+		Response struct {
+			Headers      map[string]string `json:"headers"`
+			Body         string            `json:"body"`
+			Transformers []string          `json:"transformers"`
+			Delay        int               `json:"fixedDelayMilliseconds"`
+		} `json:"response"`
+		Metadata struct {
+			GoVirtual struct {
+				Warnings []string `json:"warnings"`
+			} `json:"go-virtual"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(data, &premium)
+
+	if premium.Priority != 1 || premium.ID != exportID("resp-1") || len(premium.ID) != 36 {
+		t.Errorf("Unexpected mapping identity: %s", data)
+	}
+	if premium.Request.Method != "GET" || premium.Request.URLPathTemplate != "/api/pets/{petId}" {
+		t.Errorf("Unexpected route: %s", data)
+	}
+	if premium.Request.PathParameters["petId"]["equalTo"] != "1" || premium.Request.Headers["X-Tier"]["matches"] != "gold.*" {
+		t.Errorf("Unexpected matchers: %s", data)
+	}
+	if !reflect.DeepEqual(premium.Request.BodyPatterns, []map[string]string{{"matchesJsonPath": "$.owner.id"}}) {
+		t.Errorf("Unexpected body patterns: %s", data)
+	}
+	if premium.Response.Body != `{"id": "{{request.path.petId}}"}` || premium.Response.Headers["X-Request"] != "{{request.headers.X-Request-Id}}" {
+		t.Errorf("Templates not converted: %s", data)
+	}
+	if !reflect.DeepEqual(premium.Response.Transformers, []string{"response-template"}) || premium.Response.Delay != 100 {
+		t.Errorf("Unexpected response: %s", data)
+	}
+	if len(premium.Metadata.GoVirtual.Warnings) != 1 {
+		t.Errorf("Expected a warning for the jwt condition, got %v", premium.Metadata.GoVirtual.Warnings)
+	}
+
+	fallback := doc.Mappings[1]
+	if fallback.Priority != 2 || fallback.Response["fault"] != "CONNECTION_RESET_BY_PEER" || fallback.Request["urlPathTemplate"] != "/api/pets/{petId}" {
+		t.Errorf("Unexpected fallback mapping: %+v", fallback)
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	op := &models.Operation{ID: "op-1", Method: "POST", Path: "/orders", FullPath: "/orders"}
+	conditions := []models.Condition{
+		{Source: "query", Key: "dryRun", Operator: "eq", Value: "true"},
+		{Source: "header", Key: "X-Tenant", Operator: "notExists"},
+		{Source: "body", Key: "customer.tier", Operator: "eq", Value: "gold"},
+	}
+	configs := map[string][]*models.ResponseConfig{
+		"op-1": {{ID: "resp-1", Name: "Dry run", Conditions: conditions, StatusCode: 202, Body: "{{body}}", Enabled: true}},
+	}
+
+	data, _ := json.Marshal(Export([]*models.Operation{op}, configs))
+	stubs, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	stub := stubs[0]
+	if stub.Method != "POST" || stub.Path != "/orders" || len(stub.Warnings) != 0 {
+		t.Errorf("Unexpected stub: %+v", stub)
+	}
+	if !reflect.DeepEqual(stub.Response.Conditions, conditions) {
+		t.Errorf("Conditions changed in the round trip:\n got %+v\nwant %+v", stub.Response.Conditions, conditions)
+	}
+	if stub.Response.StatusCode != 202 || stub.Response.Body != "{{body}}" {
+		t.Errorf("Response changed in the round trip: %+v", stub.Response)
+	}
+}
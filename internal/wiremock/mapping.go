@@ -0,0 +1,573 @@
+// Package wiremock converts between WireMock stub mappings and response configs
+package wiremock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+// defaultPriority is the priority WireMock gives stubs without one
+const defaultPriority = 5
+
+// Stub is a WireMock stub mapping converted into a response config
+type Stub struct {
+	Name     string // Name of the stub, for reports
+	Method   string // Upper case method, or ANY
+	Path     string // Exact request path or path template; empty when the URL is a pattern
+	Template bool   // Whether Path is a template with {param} segments (urlPathTemplate)
+	Pattern  string // Regular expression of the URL (urlPattern, urlPathPattern)
+	Priority int    // WireMock priority, lower matches first
+
+	Response models.ResponseConfigInput
+	Warnings []string // Parts of the stub that could not be converted
+}
+
+// Mapping is a WireMock stub mapping, as found in its mappings directory and admin API
+type Mapping struct {
+	ID       string         `json:"id"`
+	UUID     string         `json:"uuid"`
+	Name     string         `json:"name"`
+	Priority int            `json:"priority"`
+	Request  RequestPattern `json:"request"`
+	Response ResponseDef    `json:"response"`
+	Scenario string         `json:"scenarioName"`
+}
+
+// RequestPattern is the request matcher of a stub mapping
+type RequestPattern struct {
+	Method               string                     `json:"method"`
+	URL                  string                     `json:"url"`
+	URLPath              string                     `json:"urlPath"`
+	URLPattern           string                     `json:"urlPattern"`
+	URLPathPattern       string                     `json:"urlPathPattern"`
+	URLPathTemplate      string                     `json:"urlPathTemplate"`
+	PathParameters       map[string]json.RawMessage `json:"pathParameters"`
+	QueryParameters      map[string]json.RawMessage `json:"queryParameters"`
+	Headers              map[string]json.RawMessage `json:"headers"`
+	Cookies              map[string]json.RawMessage `json:"cookies"`
+	BodyPatterns         []json.RawMessage          `json:"bodyPatterns"`
+	BasicAuthCredentials *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"basicAuthCredentials"`
+}
+
+// ResponseDef is the response definition of a stub mapping
+type ResponseDef struct {
+	Status            int                        `json:"status"`
+	Headers           map[string]json.RawMessage `json:"headers"`
+	Body              string                     `json:"body"`
+	JSONBody          json.RawMessage            `json:"jsonBody"`
+	Base64Body        string                     `json:"base64Body"`
+	BodyFileName      string                     `json:"bodyFileName"`
+	FixedDelay        int                        `json:"fixedDelayMilliseconds"`
+	DelayDistribution json.RawMessage            `json:"delayDistribution"`
+	ChunkedDribble    *ChunkedDribbleDelay       `json:"chunkedDribbleDelay"`
+	Fault             string                     `json:"fault"`
+	Transformers      []string                   `json:"transformers"`
+	ProxyBaseURL      string                     `json:"proxyBaseUrl"`
+}
+
+// ChunkedDribbleDelay spreads the body of a response over a duration
+type ChunkedDribbleDelay struct {
+	NumberOfChunks int `json:"numberOfChunks"`
+	TotalDuration  int `json:"totalDuration"`
+}
+
+// Parse reads WireMock stub mappings from a mappings file, which holds a single mapping,
+// a {"mappings": [...]} document as exported by the admin API, or an array of mappings.
+// Stubs are returned in the order WireMock would match them: by priority, then newest first
+func Parse(data []byte) ([]*Stub, error) {
+	data = bytes.TrimSpace(data)
+	var mappings []Mapping
+	switch {
+	case len(data) > 0 && data[0] == '[':
+		if err := json.Unmarshal(data, &mappings); err != nil {
+			return nil, err
+		}
+	default:
+		var doc struct {
+			Mappings []Mapping `json:"mappings"`
+			Request  *struct{} `json:"request"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if doc.Request != nil {
+			var m Mapping
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, err
+			}
+			mappings = []Mapping{m}
+		} else {
+			mappings = doc.Mappings
+		}
+	}
+	if len(mappings) == 0 {
+		return nil, errors.New("no stub mappings found")
+	}
+
+	stubs := make([]*Stub, 0, len(mappings))
+	for i := range mappings {
+		stub, err := Convert(&mappings[i])
+		if err != nil {
+			return nil, fmt.Errorf("mapping %d: %w", i+1, err)
+		}
+		stubs = append(stubs, stub)
+	}
+
+	// WireMock tries the most recently added of equal priority stubs first, and files list
+	// stubs oldest first
+	for i, j := 0, len(stubs)-1; i < j; i, j = i+1, j-1 {
+		stubs[i], stubs[j] = stubs[j], stubs[i]
+	}
+	sort.SliceStable(stubs, func(i, j int) bool {
+		return stubs[i].Priority < stubs[j].Priority
+	})
+	return stubs, nil
+}
+
+// Convert turns a stub mapping into a response config input and the route it applies to
+func Convert(m *Mapping) (*Stub, error) {
+	stub := &Stub{
+		Method:   strings.ToUpper(m.Request.Method),
+		Priority: m.Priority,
+	}
+	if stub.Method == "" {
+		stub.Method = "ANY"
+	}
+	if stub.Priority == 0 {
+		stub.Priority = defaultPriority
+	}
+
+	var conditions []models.Condition
+	req := &m.Request
+	switch {
+	case req.URL != "":
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url %q: %w", req.URL, err)
+		}
+		stub.Path = u.Path
+		for _, key := range sortedKeys(u.Query()) {
+			conditions = append(conditions, models.Condition{Source: models.SourceQuery, Key: key, Operator: models.OpEquals, Value: u.Query().Get(key)})
+		}
+	case req.URLPath != "":
+		stub.Path = req.URLPath
+	case req.URLPathTemplate != "":
+		stub.Path = req.URLPathTemplate
+		stub.Template = true
+	case req.URLPattern != "":
+		stub.Pattern = req.URLPattern
+	case req.URLPathPattern != "":
+		stub.Pattern = req.URLPathPattern
+	default:
+		stub.Pattern = ".*"
+	}
+
+	stub.Name = m.Name
+	if stub.Name == "" {
+		target := stub.Path
+		if target == "" {
+			target = stub.Pattern
+		}
+		stub.Name = stub.Method + " " + target
+	}
+
+	conditions = append(conditions, stub.matcherGroup(models.SourcePath, req.PathParameters)...)
+	conditions = append(conditions, stub.matcherGroup(models.SourceQuery, req.QueryParameters)...)
+	conditions = append(conditions, stub.matcherGroup(models.SourceHeader, req.Headers)...)
+	if len(req.Cookies) > 0 {
+		stub.warn("cookie matchers are not supported")
+	}
+	if creds := req.BasicAuthCredentials; creds != nil {
+		token := base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+		conditions = append(conditions, models.Condition{Source: models.SourceHeader, Key: "Authorization", Operator: models.OpEquals, Value: "Basic " + token})
+	}
+	for _, raw := range req.BodyPatterns {
+		conditions = append(conditions, stub.bodyConditions(raw)...)
+	}
+	if m.Scenario != "" {
+		stub.warn("scenario " + strconv.Quote(m.Scenario) + " is ignored; use stateful mode or variables instead")
+	}
+
+	if err := stub.convertResponse(&m.Response); err != nil {
+		return nil, err
+	}
+	stub.Response.Name = stub.Name
+	stub.Response.Conditions = conditions
+	stub.Response.Enabled = true
+	if m.ID != "" || m.UUID != "" {
+		stub.Response.Description = "Imported from WireMock stub " + firstNonEmpty(m.ID, m.UUID)
+	}
+	return stub, nil
+}
+
+// convertResponse fills the response config from a response definition
+func (s *Stub) convertResponse(def *ResponseDef) error {
+	resp := &s.Response
+	resp.StatusCode = def.Status
+	if resp.StatusCode == 0 {
+		resp.StatusCode = 200
+	}
+	resp.Delay = def.FixedDelay
+
+	resp.Headers = make(map[string]string, len(def.Headers))
+	for name, raw := range def.Headers {
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return fmt.Errorf("invalid value of header %q", name)
+			}
+			values = []string{value}
+		}
+		resp.Headers[name] = strings.Join(values, ", ")
+	}
+
+	switch {
+	case len(def.JSONBody) > 0:
+		var body bytes.Buffer
+		if err := json.Indent(&body, def.JSONBody, "", "  "); err != nil {
+			return fmt.Errorf("invalid jsonBody: %w", err)
+		}
+		resp.Body = body.String()
+		if _, ok := headerValue(resp.Headers, "Content-Type"); !ok {
+			resp.Headers["Content-Type"] = "application/json"
+		}
+	case def.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(def.Base64Body)
+		if err != nil {
+			return fmt.Errorf("invalid base64Body: %w", err)
+		}
+		resp.Body = string(body)
+	case def.BodyFileName != "":
+		resp.BodyFile = def.BodyFileName
+	default:
+		resp.Body = def.Body
+	}
+
+	if containsString(def.Transformers, "response-template") {
+		resp.Body = convertTemplate(resp.Body)
+		for name, value := range resp.Headers {
+			resp.Headers[name] = convertTemplate(value)
+		}
+	} else if strings.Contains(resp.Body, "{{") {
+		// Without the transformer WireMock returns the body verbatim, which go-virtual would
+		// treat as a template
+		s.warn("the body contains {{ which go-virtual renders as a template")
+	}
+
+	switch def.Fault {
+	case "":
+	case "CONNECTION_RESET_BY_PEER", "EMPTY_RESPONSE":
+		resp.Fault = &models.ResponseFault{Type: models.FaultAbort}
+	case "MALFORMED_RESPONSE_CHUNK":
+		resp.Fault = &models.ResponseFault{Type: models.FaultAbort, Fraction: 0.5}
+	default:
+		s.warn("fault " + def.Fault + " is not supported")
+	}
+	if dribble := def.ChunkedDribble; dribble != nil && resp.Fault == nil {
+		interval := 1
+		if n := len(resp.Body); n > 0 && dribble.TotalDuration > n {
+			interval = dribble.TotalDuration / n
+		}
+		resp.Fault = &models.ResponseFault{Type: models.FaultTrickle, Interval: interval}
+	}
+	if len(def.DelayDistribution) > 0 {
+		s.warn("delayDistribution is not supported; use a fixed delay or chaos mode instead")
+	}
+	if def.ProxyBaseURL != "" {
+		s.warn("proxyBaseUrl " + def.ProxyBaseURL + " is ignored; set an upstream to forward requests instead")
+	}
+	return nil
+}
+
+// matcherGroup converts the matchers of named request parts such as headers
+func (s *Stub) matcherGroup(source string, matchers map[string]json.RawMessage) []models.Condition {
+	var conditions []models.Condition
+	for _, key := range sortedKeys(matchers) {
+		conditions = append(conditions, s.matcherConditions(source, key, matchers[key])...)
+	}
+	return conditions
+}
+
+// matcherConditions converts a WireMock string value matcher such as {"equalTo": "x"}
+func (s *Stub) matcherConditions(source, key string, raw json.RawMessage) []models.Condition {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		s.warn(describe(source, key) + " has an invalid matcher")
+		return nil
+	}
+
+	if and, ok := m["and"]; ok {
+		var parts []json.RawMessage
+		json.Unmarshal(and, &parts)
+		var conditions []models.Condition
+		for _, part := range parts {
+			conditions = append(conditions, s.matcherConditions(source, key, part)...)
+		}
+		return conditions
+	}
+
+	var caseInsensitive bool
+	if raw, ok := m["caseInsensitive"]; ok {
+		json.Unmarshal(raw, &caseInsensitive)
+	}
+
+	cond := models.Condition{Source: source, Key: key}
+	for name, raw := range m {
+		var value string
+		json.Unmarshal(raw, &value)
+		switch name {
+		case "equalTo":
+			cond.Operator, cond.Value = models.OpEquals, value
+			if caseInsensitive {
+				cond.Operator, cond.Value = models.OpRegex, "(?i)^"+regexp.QuoteMeta(value)+"$"
+			}
+		case "contains":
+			cond.Operator, cond.Value = models.OpContains, value
+		case "doesNotContain":
+			cond.Operator, cond.Value = models.OpNotContains, value
+		case "matches":
+			cond.Operator, cond.Value = models.OpRegex, anchor(value)
+		case "absent":
+			var absent bool
+			json.Unmarshal(raw, &absent)
+			cond.Operator = models.OpExists
+			if absent {
+				cond.Operator = models.OpNotExists
+			}
+		case "caseInsensitive":
+			continue
+		default:
+			s.warn(describe(source, key) + " matcher " + name + " is not supported")
+			return nil
+		}
+	}
+	if cond.Operator == "" {
+		s.warn(describe(source, key) + " has no matcher")
+		return nil
+	}
+	return []models.Condition{cond}
+}
+
+// bodyConditions converts a body pattern
+func (s *Stub) bodyConditions(raw json.RawMessage) []models.Condition {
+	var pattern map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &pattern); err != nil {
+		s.warn("invalid body pattern")
+		return nil
+	}
+
+	if expr, ok := pattern["matchesJsonPath"]; ok {
+		return s.jsonPathConditions(expr)
+	}
+	if value, ok := pattern["equalToJson"]; ok {
+		// Accept both an embedded document and one in a string
+		var text string
+		if json.Unmarshal(value, &text) == nil {
+			value = json.RawMessage(text)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(value, &doc); err != nil {
+			s.warn("equalToJson body pattern holds invalid JSON")
+			return nil
+		}
+		// Matching every value of the document behaves like WireMock's ignoreExtraElements
+		var conditions []models.Condition
+		flattenJSON("", doc, &conditions)
+		return conditions
+	}
+
+	for _, name := range []string{"equalTo", "contains", "doesNotContain", "matches"} {
+		if _, ok := pattern[name]; ok {
+			return s.matcherConditions(models.SourceRawBody, "", raw)
+		}
+	}
+
+	for name := range pattern {
+		s.warn("body pattern " + name + " is not supported")
+		break
+	}
+	return nil
+}
+
+// jsonPathConditions converts a matchesJsonPath body pattern, which is either an expression
+// or an expression with a value matcher
+func (s *Stub) jsonPathConditions(raw json.RawMessage) []models.Condition {
+	var expression string
+	var matcher map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &expression); err != nil {
+		if err := json.Unmarshal(raw, &matcher); err != nil {
+			s.warn("invalid matchesJsonPath body pattern")
+			return nil
+		}
+		json.Unmarshal(matcher["expression"], &expression)
+		delete(matcher, "expression")
+	}
+
+	key, ok := gjsonPath(expression)
+	if !ok {
+		s.warn("JSON path " + strconv.Quote(expression) + " is not supported")
+		return nil
+	}
+	if len(matcher) == 0 {
+		return []models.Condition{{Source: models.SourceBody, Key: key, Operator: models.OpExists}}
+	}
+	m, _ := json.Marshal(matcher)
+	return s.matcherConditions(models.SourceBody, key, m)
+}
+
+var (
+	jsonPathIndex = regexp.MustCompile(`\[(\d+)\]`)
+	jsonPathQuote = regexp.MustCompile(`\['([^']*)'\]`)
+)
+
+// gjsonPath converts a simple JSON path such as $.items[0].name into gjson syntax
+// Filters, wildcards and deep scans have no equivalent and are rejected
+func gjsonPath(expression string) (string, bool) {
+	if strings.Contains(expression, "..") {
+		return "", false
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(expression, "$"), ".")
+	if path == "" || strings.ContainsAny(path, "?*@()") {
+		return "", false
+	}
+	path = jsonPathQuote.ReplaceAllString(path, ".$1")
+	path = jsonPathIndex.ReplaceAllString(path, ".$1")
+	if strings.ContainsAny(path, "[]") {
+		return "", false
+	}
+	return strings.TrimPrefix(path, "."), true
+}
+
+// flattenJSON adds an equality condition on the body for every scalar value of a document
+func flattenJSON(prefix string, value interface{}, conditions *[]models.Condition) {
+	join := func(key string) string {
+		key = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`).Replace(key)
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flattenJSON(join(key), v[key], conditions)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenJSON(join(strconv.Itoa(i)), item, conditions)
+		}
+	case nil:
+		*conditions = append(*conditions, models.Condition{Source: models.SourceBody, Key: prefix, Operator: models.OpExists})
+	case string:
+		if strings.HasPrefix(v, "${json-unit.") {
+			*conditions = append(*conditions, models.Condition{Source: models.SourceBody, Key: prefix, Operator: models.OpExists})
+			return
+		}
+		*conditions = append(*conditions, models.Condition{Source: models.SourceBody, Key: prefix, Operator: models.OpEquals, Value: v})
+	default:
+		data, _ := json.Marshal(v)
+		*conditions = append(*conditions, models.Condition{Source: models.SourceBody, Key: prefix, Operator: models.OpEquals, Value: string(data)})
+	}
+}
+
+// templateReplacements rewrite WireMock's Handlebars helpers into go-virtual template variables
+var templateReplacements = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`\{\{\s*request\.query\.([\w-]+)(?:\.\[?0\]?)?\s*\}\}`), "{{query.$1}}"},
+	{regexp.MustCompile(`\{\{\s*request\.headers\.([\w-]+)(?:\.\[?0\]?)?\s*\}\}`), "{{header.$1}}"},
+	{regexp.MustCompile(`\{\{\s*request\.(?:path|pathSegments)\.([\w-]+)\s*\}\}`), "{{path.$1}}"},
+	{regexp.MustCompile(`\{\{\s*request\.body\s*\}\}`), "{{body}}"},
+	{regexp.MustCompile(`\{\{\s*request\.(method|url|path)\s*\}\}`), "{{request.$1}}"},
+	{regexp.MustCompile(`\{\{\s*jsonPath\s+request\.body\s+'\$\.([\w.]+)'\s*\}\}`), "{{body.$1}}"},
+	{regexp.MustCompile(`\{\{\s*randomValue\s+type='UUID'\s*\}\}`), "{{random.uuid}}"},
+	{regexp.MustCompile(`\{\{\s*now\s*\}\}`), "{{timestamp.iso}}"},
+}
+
+// convertTemplate rewrites the common Handlebars helpers of a response template
+// Helpers without an equivalent are left as they are
+func convertTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	for _, r := range templateReplacements {
+		s = r.pattern.ReplaceAllString(s, r.replace)
+	}
+	return s
+}
+
+// warn records a part of the stub that could not be converted
+func (s *Stub) warn(message string) {
+	s.Warnings = append(s.Warnings, message)
+}
+
+// describe names a request part in warnings
+func describe(source, key string) string {
+	if key == "" {
+		return source
+	}
+	return source + " " + strconv.Quote(key)
+}
+
+// anchor makes a regular expression match whole values, as WireMock's matches does
+func anchor(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
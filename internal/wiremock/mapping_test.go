@@ -0,0 +1,227 @@
+package wiremock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prasenjit/go-virtual/internal/models"
+)
+
+func TestParse_Forms(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"single mapping", `{"request": {"url": "/a"}, "response": {}}`, 1},
+		{"mappings document", `{"mappings": [{"request": {"url": "/a"}}, {"request": {"url": "/b"}}]}`, 2},
+		{"array", `[{"request": {"url": "/a"}}]`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(stubs) != tt.want {
+				t.Errorf("Expected %d stubs, got %d", tt.want, len(stubs))
+			}
+		})
+	}
+
+	if _, err := Parse([]byte(`{"mappings": []}`)); err == nil {
+		t.Error("Expected an error for a document without mappings")
+	}
+}
+
+func TestParse_Order(t *testing.T) {
+	stubs, err := Parse([]byte(`[
+		{"name": "general", "request": {"url": "/a"}},
+		{"name": "specific", "request": {"url": "/a"}},
+		{"name": "fallback", "priority": 10, "request": {"url": "/a"}},
+		{"name": "first", "priority": 1, "request": {"url": "/a"}}
+	]`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var names []string
+	for _, stub := range stubs {
+		names = append(names, stub.Name)
+	}
+	want := []string{"first", "specific", "general", "fallback"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected order %v, got %v", want, names)
+	}
+}
+
+func TestConvert_Request(t *testing.T) {
+	stubs, err := Parse([]byte(`{
+		"request": {
+			"method": "post",
+			"url": "/orders?source=web",
+			"headers": {
+				"Content-Type": {"contains": "json"},
+				"X-Tenant": {"equalTo": "acme", "caseInsensitive": true},
+				"X-Debug": {"absent": true},
+				"Accept": {"matches": "text/.*"}
+			},
+			"cookies": {"session": {"equalTo": "x"}},
+			"basicAuthCredentials": {"username": "user", "password": "pass"},
+			"bodyPatterns": [
+				{"matchesJsonPath": "$.items[0].sku"},
+				{"matchesJsonPath": {"expression": "$.customer.tier", "equalTo": "gold"}},
+				{"equalToJson": {"currency": "EUR", "total": 12.5}},
+				{"matchesJsonPath": "$.items[?(@.qty > 1)]"}
+			]
+		},
+		"response": {"status": 201}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	stub := stubs[0]
+
+	if stub.Method != "POST" || stub.Path != "/orders" || stub.Name != "POST /orders" {
+		t.Errorf("Unexpected route: %s %s (%s)", stub.Method, stub.Path, stub.Name)
+	}
+
+	want := []models.Condition{
+		{Source: "query", Key: "source", Operator: "eq", Value: "web"},
+		{Source: "header", Key: "Accept", Operator: "regex", Value: "^(?:text/.*)$"},
+		{Source: "header", Key: "Content-Type", Operator: "contains", Value: "json"},
+		{Source: "header", Key: "X-Debug", Operator: "notExists"},
+		{Source: "header", Key: "X-Tenant", Operator: "regex", Value: "(?i)^acme$"},
+		{Source: "header", Key: "Authorization", Operator: "eq", Value: "Basic dXNlcjpwYXNz"},
+		{Source: "body", Key: "items.0.sku", Operator: "exists"},
+		{Source: "body", Key: "customer.tier", Operator: "eq", Value: "gold"},
+		{Source: "body", Key: "currency", Operator: "eq", Value: "EUR"},
+		{Source: "body", Key: "total", Operator: "eq", Value: "12.5"},
+	}
+	if !reflect.DeepEqual(stub.Response.Conditions, want) {
+		t.Errorf("Unexpected conditions:\n got %+v\nwant %+v", stub.Response.Conditions, want)
+	}
+	if len(stub.Warnings) != 2 {
+		t.Errorf("Expected warnings for the cookie and the JSON path filter, got %v", stub.Warnings)
+	}
+}
+
+func TestConvert_URLForms(t *testing.T) {
+	stubs, _ := Parse([]byte(`[
+		{"request": {"method": "GET", "urlPathTemplate": "/pets/{id}", "pathParameters": {"id": {"equalTo": "7"}}}},
+		{"request": {"method": "GET", "urlPathPattern": "/pets/[0-9]+"}},
+		{"request": {}}
+	]`))
+
+	// Newest first
+	if stubs[0].Pattern != ".*" || stubs[0].Method != "ANY" {
+		t.Errorf("Expected a stub without URL to match anything, got %+v", stubs[0])
+	}
+	if stubs[1].Pattern != "/pets/[0-9]+" || stubs[1].Path != "" {
+		t.Errorf("Expected a pattern stub, got %+v", stubs[1])
+	}
+	template := stubs[2]
+	if !template.Template || template.Path != "/pets/{id}" || template.Response.Conditions[0] != (models.Condition{Source: "path", Key: "id", Operator: "eq", Value: "7"}) {
+		t.Errorf("Unexpected template stub: %+v", template)
+	}
+}
+
+func TestConvert_Response(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		check    func(t *testing.T, resp models.ResponseConfigInput)
+	}{
+		{
+			name:     "json body",
+			response: `{"jsonBody": {"id": 1}, "headers": {"X-Tags": ["a", "b"]}, "fixedDelayMilliseconds": 50}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				if resp.StatusCode != 200 || resp.Body != "{\n  \"id\": 1\n}" || resp.Delay != 50 {
+					t.Errorf("Unexpected response: %+v", resp)
+				}
+				if resp.Headers["Content-Type"] != "application/json" || resp.Headers["X-Tags"] != "a, b" {
+					t.Errorf("Unexpected headers: %v", resp.Headers)
+				}
+			},
+		},
+		{
+			name:     "base64 body",
+			response: `{"status": 202, "base64Body": "aGVsbG8="}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				if resp.StatusCode != 202 || resp.Body != "hello" {
+					t.Errorf("Unexpected response: %+v", resp)
+				}
+			},
+		},
+		{
+			name:     "body file",
+			response: `{"bodyFileName": "pets.json"}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				if resp.BodyFile != "pets.json" {
+					t.Errorf("Expected body file, got %+v", resp)
+				}
+			},
+		},
+		{
+			name:     "response template",
+			response: `{"body": "{{request.query.name}} {{request.headers.X-Id}} {{jsonPath request.body '$.user.id'}} {{randomValue type='UUID'}} {{request.method}}", "transformers": ["response-template"]}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				want := "{{query.name}} {{header.X-Id}} {{body.user.id}} {{random.uuid}} {{request.method}}"
+				if resp.Body != want {
+					t.Errorf("Expected %q, got %q", want, resp.Body)
+				}
+			},
+		},
+		{
+			name:     "connection reset",
+			response: `{"fault": "CONNECTION_RESET_BY_PEER"}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				if resp.Fault == nil || resp.Fault.Type != models.FaultAbort {
+					t.Errorf("Expected abort fault, got %+v", resp.Fault)
+				}
+			},
+		},
+		{
+			name:     "chunked dribble",
+			response: `{"body": "0123456789", "chunkedDribbleDelay": {"numberOfChunks": 5, "totalDuration": 1000}}`,
+			check: func(t *testing.T, resp models.ResponseConfigInput) {
+				if resp.Fault == nil || resp.Fault.Type != models.FaultTrickle || resp.Fault.Interval != 100 {
+					t.Errorf("Expected trickle fault, got %+v", resp.Fault)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs, err := Parse([]byte(`{"request": {"url": "/a"}, "response": ` + tt.response + `}`))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			tt.check(t, stubs[0].Response)
+		})
+	}
+}
+
+func TestGJSONPath(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		ok         bool
+	}{
+		{"$.name", "name", true},
+		{"$.items[2].id", "items.2.id", true},
+		{"$['user'].name", "user.name", true},
+		{"$..name", "", false},
+		{"$.items[*].id", "", false},
+		{"$", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := gjsonPath(tt.expression)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("gjsonPath(%q) = %q, %v; want %q, %v", tt.expression, got, ok, tt.want, tt.ok)
+		}
+	}
+}
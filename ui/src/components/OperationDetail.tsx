@@ -59,6 +59,13 @@ export default function OperationDetail() {
         },
     })
 
+    const echoMutation = useMutation({
+        mutationFn: (echo: boolean) => operationsApi.setEcho(operationId!, echo),
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['operation', operationId] })
+        },
+    })
+
     const toggleMutation = useMutation({
         mutationFn: ({ id, enabled }: { id: string; enabled: boolean }) =>
             responsesApi.update(id, { enabled: !enabled }),
@@ -125,6 +132,24 @@ export default function OperationDetail() {
                     <h1 className="text-2xl font-mono font-bold text-gray-900 ml-4">
                         {operation.path}
                     </h1>
+                    <button
+                        onClick={() => echoMutation.mutate(!operation.echo)}
+                        disabled={echoMutation.isPending}
+                        className={clsx(
+                            'ml-auto flex items-center px-3 py-1.5 rounded-lg text-sm transition-colors',
+                            operation.echo
+                                ? 'bg-primary-50 text-primary-700 hover:bg-primary-100'
+                                : 'text-gray-500 hover:bg-gray-100'
+                        )}
+                        title="Answer with a JSON description of each request instead of the response configurations"
+                    >
+                        {operation.echo ? (
+                            <ToggleRight className="w-5 h-5 mr-1.5" />
+                        ) : (
+                            <ToggleLeft className="w-5 h-5 mr-1.5" />
+                        )}
+                        Echo requests
+                    </button>
                 </div>
                 {operation.summary && (
                     <p className="text-gray-500 mt-2">{operation.summary}</p>
@@ -79,8 +79,12 @@ export default function TraceViewer() {
         wsRef.current = ws
 
         ws.onmessage = (event) => {
-            const trace = JSON.parse(event.data) as Trace
-            setLiveTraces((prev) => [trace, ...prev].slice(0, 100))
+            const message = JSON.parse(event.data)
+            if (message.notice === 'dropped') {
+                console.warn(`Live traces: missed ${message.dropped} traces`)
+                return
+            }
+            setLiveTraces((prev) => [message as Trace, ...prev].slice(0, 100))
         }
 
         ws.onerror = () => {
@@ -361,6 +365,47 @@ export default function TraceViewer() {
                                     Matched config: <span className="font-medium">{selectedTrace.matchedConfig}</span>
                                 </div>
                             )}
+
+                            {/* Request ID */}
+                            {selectedTrace.requestId && (
+                                <div className="mt-2 text-sm text-gray-500">
+                                    Request ID: <span className="font-mono">{selectedTrace.requestId}</span>
+                                </div>
+                            )}
+
+                            {/* Upstream the request was forwarded to */}
+                            {selectedTrace.upstream && (
+                                <div className="mt-4">
+                                    <h4 className="text-xs font-medium text-gray-500 uppercase mb-2">Upstream</h4>
+                                    <div className="text-sm font-mono break-all">{selectedTrace.upstream.url}</div>
+                                    <div className="mt-1 text-sm text-gray-500">
+                                        Connect {formatDuration(selectedTrace.upstream.connect)} · First byte{' '}
+                                        {formatDuration(selectedTrace.upstream.ttfb)} · Total{' '}
+                                        {formatDuration(selectedTrace.upstream.total)} of{' '}
+                                        {formatDuration(selectedTrace.duration)}
+                                    </div>
+                                    {selectedTrace.upstream.error && (
+                                        <div className="mt-1 text-sm text-red-600">{selectedTrace.upstream.error}</div>
+                                    )}
+                                </div>
+                            )}
+
+                            {/* Node that served the request */}
+                            {selectedTrace.node && (
+                                <div className="mt-2 text-sm text-gray-500">
+                                    Served by: <span className="font-mono">{selectedTrace.node}</span>
+                                </div>
+                            )}
+
+                            {/* Panic */}
+                            {selectedTrace.error && (
+                                <div className="mt-4">
+                                    <h4 className="text-xs font-medium text-red-600 uppercase mb-2">Error</h4>
+                                    <pre className="bg-red-50 text-red-800 rounded p-3 text-xs overflow-x-auto">
+                                        {selectedTrace.error}
+                                    </pre>
+                                </div>
+                            )}
                         </div>
                     ) : (
                         <div className="h-full flex items-center justify-center text-gray-500">
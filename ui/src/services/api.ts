@@ -89,6 +89,13 @@ export const operationsApi = {
         const response = await fetch(`${API_BASE}/operations/${id}`);
         return handleResponse<any>(response);
     },
+
+    setEcho: async (id: string, echo: boolean) => {
+        const response = await fetch(`${API_BASE}/operations/${id}/echo`, {
+            method: echo ? 'PUT' : 'DELETE',
+        });
+        return handleResponse<any>(response);
+    },
 };
 
 // Response configs API
@@ -34,6 +34,7 @@ export interface Operation {
     tags: string[];
     responses?: ResponseConfig[];
     exampleResponse?: ExampleResponse;
+    echo?: boolean;
 }
 
 export interface ExampleResponse {
@@ -104,10 +105,22 @@ export interface Trace {
     operationPath: string;
     timestamp: string;
     duration: number;
+    requestId?: string;
     request: TraceRequest;
     response: TraceResponse;
     matchedConfigId?: string;
     matchedConfig?: string;
+    error?: string;
+    node?: string;
+    upstream?: TraceUpstream;
+}
+
+export interface TraceUpstream {
+    url: string;
+    connect: number;
+    ttfb: number;
+    total: number;
+    error?: string;
 }
 
 export interface TraceRequest {
@@ -135,9 +148,13 @@ export interface GlobalStats {
     requestsPerSecond: number;
     startTime: string;
     uptime: string;
+    totalBytesIn: number;
+    totalBytesOut: number;
     topOperations: OperationStat[];
+    topBandwidth: OperationStat[];
     recentErrors: ErrorStat[];
     requestsByHour: HourlyStat[];
+    nodes?: string[];
 }
 
 export interface SpecStats {
@@ -146,6 +163,8 @@ export interface SpecStats {
     totalRequests: number;
     totalErrors: number;
     avgResponseTimeMs: number;
+    totalBytesIn: number;
+    totalBytesOut: number;
     operations: OperationStat[];
 }
 
@@ -159,6 +178,8 @@ export interface OperationStat {
     avgResponseTimeMs: number;
     minResponseTimeMs: number;
     maxResponseTimeMs: number;
+    bytesIn: number;
+    bytesOut: number;
     lastRequestTime?: string;
 }
 
@@ -170,6 +191,7 @@ export interface ErrorStat {
     method: string;
     statusCode: number;
     error: string;
+    requestId?: string;
 }
 
 export interface HourlyStat {